@@ -16,6 +16,9 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
 )
 
 const (
@@ -35,11 +38,24 @@ type (
 var suzanne_obj []byte
 
 func main() {
-	mesh, err := load_obj(suzanne_obj)
+	ebiten.SetWindowTitle("000-simple")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, nil)
 
 	if err != nil {
 		panic(err)
 	}
+}
+
+// load makes the game, app.RunLoader shows a broken model in the window instead.
+func load() (ebiten.Game, error) {
+	mesh, err := load_obj(suzanne_obj)
+
+	if err != nil {
+		return nil, err
+	}
 
 	for t := range mesh.triangles {
 		mesh.triangles[t].rgba = vec4{
@@ -50,20 +66,10 @@ func main() {
 	white := ebiten.NewImage(1, 1)
 	white.Fill(color.White)
 
-	game := &Game{
+	return &Game{
 		white:   white,
 		suzanne: mesh,
-	}
-
-	ebiten.SetWindowTitle("000-simple")
-	ebiten.SetWindowSize(game_width, game_height)
-	ebiten.SetVsyncEnabled(false)
-
-	err = ebiten.RunGame(game)
-
-	if err != nil {
-		panic(err)
-	}
+	}, nil
 }
 
 type Game struct {
@@ -208,7 +214,7 @@ func (self *Game) Draw(screen *ebiten.Image) {
 	ctx.set_viewport(0, 0, w, h)
 
 	// we'll use the cursor to give a little control to the camera
-	cx, cy := ebiten.CursorPosition()
+	cx, cy := input.CursorPosition()
 
 	cycle := float64(self.cycle) / 200.0
 	cycle -= float64(cx) / 100
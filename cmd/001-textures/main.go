@@ -9,7 +9,6 @@ import (
 	"image"
 	_ "image/jpeg"
 	"io"
-	"log"
 	"math"
 	"os"
 	"runtime"
@@ -20,6 +19,11 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
 )
 
 const (
@@ -42,20 +46,29 @@ var wall_obj []byte
 //go:embed diffuse.jpg
 var diffuse_jpg []byte
 
+var logger = logging.Tag("main")
+
 var cpu_profile = flag.String("cpuprofile", "", "write cpu profile to `file`")
 var mem_profile = flag.String("memprofile", "", "write memory profile to `file`")
 
 func main() {
 	flag.Parse()
 
+	input.Bind("camera_look", input.Mouse(ebiten.MouseButtonLeft))
+	input.Bind("move_forward", input.Key(ebiten.KeyW), input.Key(ebiten.KeyUp), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, -1))
+	input.Bind("move_backward", input.Key(ebiten.KeyS), input.Key(ebiten.KeyDown), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, 1))
+	input.Bind("move_left", input.Key(ebiten.KeyA), input.Key(ebiten.KeyLeft), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, -1))
+	input.Bind("move_right", input.Key(ebiten.KeyD), input.Key(ebiten.KeyRight), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, 1))
+	input.Bind("uv_check", input.Key(ebiten.KeyU))
+
 	if *cpu_profile != "" {
 		f, err := os.Create(*cpu_profile)
 		if err != nil {
-			log.Fatal("could not create CPU profile: ", err)
+			logger.Fatalf("could not create CPU profile: %v", err)
 		}
 		defer f.Close()
 		if err := pprof.StartCPUProfile(f); err != nil {
-			log.Fatal("could not start CPU profile: ", err)
+			logger.Fatalf("could not start CPU profile: %v", err)
 		}
 		defer pprof.StopCPUProfile()
 	}
@@ -66,56 +79,63 @@ func main() {
 		defer func() {
 			f, err := os.Create(*mem_profile)
 			if err != nil {
-				log.Fatal("could not create memory profile:", err)
+				logger.Fatalf("could not create memory profile: %v", err)
 			}
 			defer f.Close()
 			runtime.GC() // get up-to-date statistics
 			if err := pprof.WriteHeapProfile(f); err != nil {
-				log.Fatal("could not write memory profile:", err)
+				logger.Fatalf("could not write memory profile: %v", err)
 			}
 		}()
 	}
 
-	mesh, err := load_obj(wall_obj)
+	ebiten.SetWindowTitle("001-textures")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, nil)
 
 	if err != nil {
 		panic(err)
 	}
+}
+
+// load makes the game, app.RunLoader shows a broken model or texture in the window instead.
+func load() (ebiten.Game, error) {
+	mesh, err := load_obj(wall_obj)
+
+	if err != nil {
+		return nil, err
+	}
 
 	image, _, err := image.Decode(bytes.NewReader(diffuse_jpg))
 
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	game := &game{
-		texture: ebiten.NewImageFromImage(image),
-		mesh:    mesh,
+	return &game{
+		texture:  ebiten.NewImageFromImage(image),
+		uv_check: ebiten.NewImageFromImage(texgen.UVCheck(512)),
+		mesh:     mesh,
 		camera: camera{
 			yaw: math.Pi,
 			pos: vec3{0, 10, -10},
 		},
 		context: &context{},
-	}
-
-	ebiten.SetWindowTitle("001-textures")
-	ebiten.SetWindowSize(game_width, game_height)
-	ebiten.SetVsyncEnabled(false)
-
-	err = ebiten.RunGame(game)
-
-	if err != nil {
-		panic(err)
-	}
+	}, nil
 }
 
 type game struct {
-	context   *context
-	cycle     float32
-	texture   *ebiten.Image
-	mesh      *mesh
-	frametime time.Duration
-	camera    camera
+	context *context
+	cycle   float32
+	texture *ebiten.Image
+	// uv_check is drawn instead of the texture while show_uv_check is on, to see how the model is mapped
+	uv_check      *ebiten.Image
+	show_uv_check bool
+	mesh          *mesh
+	frametime     time.Duration
+	camera        camera
 }
 
 type camera struct {
@@ -233,8 +253,12 @@ func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
 func (self *game) Update() error {
 	self.cycle++
 
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-		cx, cy := ebiten.CursorPosition()
+	if input.JustPressed("uv_check") {
+		self.show_uv_check = !self.show_uv_check
+	}
+
+	if input.Pressed("camera_look") {
+		cx, cy := input.CursorPosition()
 
 		// doing the logic in the next update ensures we don't get some crazy snapping
 		if !self.camera.dragging {
@@ -254,15 +278,15 @@ func (self *game) Update() error {
 			self.camera.up = view.Row(1).Vec3()
 			self.camera.forward = view.Row(2).Vec3().Mul(-1)
 
-			if ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyUp) {
+			if input.Pressed("move_forward") {
 				self.camera.pos = self.camera.pos.Add(self.camera.forward)
-			} else if ebiten.IsKeyPressed(ebiten.KeyS) || ebiten.IsKeyPressed(ebiten.KeyDown) {
+			} else if input.Pressed("move_backward") {
 				self.camera.pos = self.camera.pos.Sub(self.camera.forward)
 			}
 
-			if ebiten.IsKeyPressed(ebiten.KeyD) || ebiten.IsKeyPressed(ebiten.KeyRight) {
+			if input.Pressed("move_right") {
 				self.camera.pos = self.camera.pos.Add(self.camera.right)
-			} else if ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyLeft) {
+			} else if input.Pressed("move_left") {
 				self.camera.pos = self.camera.pos.Sub(self.camera.right)
 			}
 
@@ -596,7 +620,11 @@ func (self *game) Draw(screen *ebiten.Image) {
 
 	ctx.push_mesh(self.mesh)
 	ctx.sort_triangles()
-	ctx.draw_triangles(self.texture, screen)
+	texture := self.texture
+	if self.show_uv_check {
+		texture = self.uv_check
+	}
+	ctx.draw_triangles(texture, screen)
 
 	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f", ebiten.ActualTPS()), 0, 0)
 	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("FPS: %.0f (%v)", ebiten.ActualFPS(), self.frametime), 0, 14)
@@ -1,26 +1,30 @@
 package main
 
 import (
-	"bytes"
-	_ "embed"
-	"errors"
 	"flag"
 	"fmt"
 	"image/color"
-	_ "image/jpeg"
-	"io"
-	"log"
 	"math"
 	"os"
 	"runtime"
 	"runtime/pprof"
-	"slices"
 	"time"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/console"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/flags"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sky"
 )
 
 const (
@@ -33,47 +37,46 @@ type (
 	float = float32
 	vec2  = mgl32.Vec2
 	vec3  = mgl32.Vec3
-	vec4  = mgl32.Vec4
 	mat4  = mgl32.Mat4
 )
 
-var shader = `
-//kage:unit pixels
-package main
-
-func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
-	src_origin := imageSrc0Origin()
-
-	// atlas -> texture space
-	texel := src - src_origin
-
-	// perspective divide (W is stored in rgba.a)
-	texel /= rgba.a
-
-	// scale uv to pixels
-	texel *= imageSrc0Size()
-
-	// move back to atlas space
-	texel += src_origin
-	
-	return imageSrc0At(texel)
-}
-`
+var logger = logging.Tag("main")
+
+var (
+	wireframe     = cvar.Bool("r_wireframe", false, 0, "outlines every drawn triangle")
+	clear_color   = cvar.Color("r_clear_color", color.RGBA{130, 130, 130, 255}, cvar.Persist, "background color")
+	sort_buckets  = cvar.Int("r_sort_buckets", 4096, 0, "depth buckets of the painter sort").Range(1, 1<<16)
+	exact_sort    = cvar.Bool("r_exact_sort", false, 0, "compare depths exactly instead of bucketing them")
+	auto_near_far = cvar.Bool("r_auto_near_far", false, 0, "fit the near and far planes to the visible meshes")
+	reverse_z     = cvar.Bool("r_reverse_z", false, 0, "map depth from 1 at the near plane to 0 at the far plane")
+	depth_view    = cvar.Bool("r_depth_view", false, 0, "draw depth as grayscale, stretched over the depth range of the frame")
+	draw_sky      = cvar.Bool("r_sky", true, cvar.Persist, "draw the procedural sky instead of clearing to r_clear_color")
+	anti_alias    = cvar.Int("r_aa", int(render.AAEbiten), cvar.Persist, "smooths edges: 0 off, 1 by ebiten per draw call, 2 and 3 drawn at 2x and 4x the size, 4 fxaa").Range(0, float64(render.AACount-1))
+	aa_compare    = cvar.Bool("r_aa_compare", false, 0, "draws the left half of the screen without smoothing to compare r_aa against")
+)
 
 var cpu_profile = flag.String("cpuprofile", "", "write cpu profile to `file`")
 var mem_profile = flag.String("memprofile", "", "write memory profile to `file`")
+var rasterizer = flag.String("rasterizer", "gpu", "rasterize with the `gpu` (DrawTriangles) or in `software` on the CPU")
+var model_path = flag.String("model", "", "draw the wavefront obj model in `file` instead of the plane")
 
 func main() {
 	flag.Parse()
 
+	input.Bind("camera_look", input.Mouse(ebiten.MouseButtonLeft))
+	input.Bind("move_forward", input.Key(ebiten.KeyW), input.Key(ebiten.KeyUp), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, -1))
+	input.Bind("move_backward", input.Key(ebiten.KeyS), input.Key(ebiten.KeyDown), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, 1))
+	input.Bind("move_left", input.Key(ebiten.KeyA), input.Key(ebiten.KeyLeft), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, -1))
+	input.Bind("move_right", input.Key(ebiten.KeyD), input.Key(ebiten.KeyRight), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, 1))
+
 	if *cpu_profile != "" {
 		f, err := os.Create(*cpu_profile)
 		if err != nil {
-			log.Fatal("could not create CPU profile: ", err)
+			logger.Fatalf("could not create CPU profile: %v", err)
 		}
 		defer f.Close()
 		if err := pprof.StartCPUProfile(f); err != nil {
-			log.Fatal("could not start CPU profile: ", err)
+			logger.Fatalf("could not start CPU profile: %v", err)
 		}
 		defer pprof.StopCPUProfile()
 	}
@@ -84,21 +87,56 @@ func main() {
 		defer func() {
 			f, err := os.Create(*mem_profile)
 			if err != nil {
-				log.Fatal("could not create memory profile:", err)
+				logger.Fatalf("could not create memory profile: %v", err)
 			}
 			defer f.Close()
 			runtime.GC() // get up-to-date statistics
 			if err := pprof.WriteHeapProfile(f); err != nil {
-				log.Fatal("could not write memory profile:", err)
+				logger.Fatalf("could not write memory profile: %v", err)
 			}
 		}()
 	}
 
-	shader, err := ebiten.NewShader([]byte(shader))
+	ebiten.SetWindowTitle("002-textures-perspective-correct")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
 
 	if err != nil {
 		panic(err)
 	}
+}
+
+// new_renderer makes the renderer -rasterizer asks for.
+func new_renderer() (render.TriangleRenderer, error) {
+	switch *rasterizer {
+	case "gpu":
+		return render.NewRenderer()
+	case "software":
+		return render.NewSoftwareRenderer(), nil
+	}
+	return nil, fmt.Errorf("unknown rasterizer %q, expected gpu or software", *rasterizer)
+}
+
+// load makes the game, app.RunLoader shows a renderer, sky or model which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := new_renderer()
+	if err != nil {
+		return nil, err
+	}
+
+	skybox, err := sky.New()
+	if err != nil {
+		return nil, err
+	}
+
+	aa, err := render.NewAntiAliaser()
+	if err != nil {
+		return nil, err
+	}
 
 	const texture_size = 128
 	const texture_subdivisions = 16
@@ -121,58 +159,124 @@ func main() {
 	vector.StrokeRect(texture, 1, 1, texture_size-1, texture_size-1, 1, color.RGBA{255, 0, 0, 255}, false)
 
 	const plane_size = 10
-	plane := &mesh{
-		triangles: []triangle{
-			{2, 1, 0, 2, 1, 0},
-			{3, 1, 2, 3, 1, 2},
+	plane := &mesh.Mesh{
+		Triangles: []mesh.Triangle{
+			{P1: 2, P2: 1, P3: 0, T1: 2, T2: 1, T3: 0},
+			{P1: 3, P2: 1, P3: 2, T1: 3, T2: 1, T3: 2},
 		},
-		points: []vec3{
+		Points: []vec3{
 			{-plane_size, 0, -plane_size},
 			{plane_size, 0, -plane_size},
 			{-plane_size, 0, plane_size},
 			{plane_size, 0, plane_size},
 		},
-		texcoords: []vec2{
+		Texcoords: []vec2{
 			{0, 0},
 			{1, 0},
 			{0, 1},
 			{1, 1},
 		},
 	}
+	plane.ComputeBounds()
 
 	game := &game{
 		texture: texture,
+		plane:   plane,
 		mesh:    plane,
 		camera: camera{
 			pitch: 0.35,
 			yaw:   0,
 			pos:   vec3{0, 7, 19},
 		},
-		context: &context{
-			shader: shader,
-		},
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		aa:       aa,
+		sky:      skybox,
+		fov:      mgl32.DegToRad(30),
 	}
 
-	ebiten.SetWindowTitle("002-textures-perspective-correct")
-	ebiten.SetWindowSize(game_width, game_height)
-	ebiten.SetVsyncEnabled(false)
+	if *model_path != "" {
+		if err := game.load_model(*model_path); err != nil {
+			return nil, err
+		}
+	}
 
-	err = ebiten.RunGameWithOptions(game, &ebiten.RunGameOptions{
-		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	game.register_commands()
+
+	// both flags change as the demo runs, a model which fails to load leaves what's drawn alone
+	flags.Default.Live("rasterizer", func() error {
+		r, err := new_renderer()
+		if err == nil {
+			game.renderer = r
+		}
+		return err
+	})
+	flags.Default.Live("model", func() error {
+		if *model_path == "" {
+			game.mesh = game.plane
+			return nil
+		}
+		return game.load_model(*model_path)
 	})
 
-	if err != nil {
-		panic(err)
-	}
+	return game, nil
 }
 
 type game struct {
-	context   *context
+	context   *pipeline.Context
+	renderer  render.TriangleRenderer
+	aa        *render.AntiAliaser
+	sky       *sky.Sky
 	cycle     float32
 	texture   *ebiten.Image
-	mesh      *mesh
+	mesh      *mesh.Mesh
+	plane     *mesh.Mesh // what's drawn without a model
 	frametime time.Duration
 	camera    camera
+
+	// fov is passed straight to mgl32.Perspective, which takes radians
+	fov float
+}
+
+func (self *game) register_commands() {
+	console.Register("fov", "[degrees]", "prints or sets the vertical field of view", func(args console.Args) error {
+		if len(args) == 0 {
+			logger.Infof("fov is %.2f degrees", mgl32.RadToDeg(self.fov))
+			return nil
+		}
+		degrees, err := args.Float(0)
+		if err != nil {
+			return err
+		}
+		if degrees <= 0 || degrees >= 180 {
+			return fmt.Errorf("fov must be between 0 and 180 degrees")
+		}
+		self.fov = mgl32.DegToRad(float(degrees))
+		return nil
+	})
+
+	console.Register("load", "<file.obj>", "replaces the plane with a wavefront obj model", func(args console.Args) error {
+		path, err := args.String(0)
+		if err != nil {
+			return err
+		}
+		return self.load_model(path)
+	})
+}
+
+// load_model replaces what's drawn with the wavefront obj model in `path`.
+func (self *game) load_model(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m, err := mesh.LoadOBJ(src)
+	if err != nil {
+		return err
+	}
+	self.mesh = m
+	logger.Infof("loaded %s, %d triangles", path, len(m.Triangles))
+	return nil
 }
 
 type camera struct {
@@ -191,107 +295,16 @@ type camera struct {
 	view_matrix mat4
 }
 
-type triangle struct {
-	p1, p2, p3 uint16
-	t1, t2, t3 uint16
-}
-
-type mesh struct {
-	triangles []triangle
-	points    []vec3
-	texcoords []vec2
-}
-
-type vertex struct {
-	position vec4
-	texcoord vec2
-}
-
-func interpolate_vec4(v1, v2, v3 vec4, f vec3) (result vec4) {
-	result = result.Add(v1.Mul(f.X()))
-	result = result.Add(v2.Mul(f.Y()))
-	result = result.Add(v3.Mul(f.Z()))
-	return
-}
-
-func interpolate_vec2(v1, v2, v3 vec2, f vec3) (result vec2) {
-	result = result.Add(v1.Mul(f.X()))
-	result = result.Add(v2.Mul(f.Y()))
-	result = result.Add(v3.Mul(f.Z()))
-	return
-}
-
-func interpolate_vertex(v1, v2, v3 vertex, f vec3) (result vertex) {
-	result.position = interpolate_vec4(v1.position, v2.position, v3.position, f)
-	result.texcoord = interpolate_vec2(v1.texcoord, v2.texcoord, v3.texcoord, f)
-	return
-}
-
-func load_obj(src []byte) (*mesh, error) {
-	reader := bytes.NewReader(src)
-	mesh := &mesh{}
-	for {
-		var typ string
-		if _, err := fmt.Fscan(reader, &typ); err != nil {
-			if errors.Is(io.EOF, err) {
-				break
-			}
-			return nil, fmt.Errorf("bad type: %w", err)
-		}
-		switch typ {
-		default:
-			return nil, fmt.Errorf("unknown type: %s", typ)
-		case "#", "o", "s", "l":
-			fmt.Fscanln(reader)
-		case "v":
-			var x, y, z float
-			if _, err := fmt.Fscanf(reader, "%f %f %f", &x, &y, &z); err != nil {
-				return nil, fmt.Errorf("bad vertex: %w", err)
-			}
-			mesh.points = append(mesh.points, vec3{x, y, z})
-		case "vt":
-			var s, t float
-			if _, err := fmt.Fscanf(reader, "%f %f", &s, &t); err != nil {
-				return nil, fmt.Errorf("bad texcoord: %w", err)
-			}
-			mesh.texcoords = append(mesh.texcoords, vec2{s, t})
-		case "f":
-			var v1, v2, v3 uint16
-			var t1, t2, t3 uint16
-			if _, err := fmt.Fscanf(reader, "%d/%d %d/%d %d/%d", &v1, &t1, &v2, &t2, &v3, &t3); err != nil {
-				return nil, fmt.Errorf("bad face: %w", err)
-			}
-			mesh.triangles = append(mesh.triangles, triangle{
-				p1: v1 - 1,
-				p2: v2 - 1,
-				p3: v3 - 1,
-				t1: t1 - 1,
-				t2: t2 - 1,
-				t3: t3 - 1,
-			})
-		}
-	}
-	return mesh, nil
-}
-
-type viewport struct {
-	x   int
-	y   int
-	w   int
-	h   int
-	w_2 int
-	h_2 int
-}
-
 func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
 	return game_width, game_height
 }
 
 func (self *game) Update() error {
 	self.cycle++
+	self.sky.Update()
 
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-		cx, cy := ebiten.CursorPosition()
+	if input.Pressed("camera_look") {
+		cx, cy := input.CursorPosition()
 
 		// doing the logic in the next update ensures we don't get some crazy snapping
 		if !self.camera.dragging {
@@ -307,19 +320,19 @@ func (self *game) Update() error {
 			view = view.Mul4(mgl32.HomogRotate3DX(self.camera.pitch))
 			view = view.Mul4(mgl32.HomogRotate3DY(self.camera.yaw))
 
-			self.camera.right = view.Row(0).Vec3().Mul(-1)
+			self.camera.right = view.Row(0).Vec3()
 			self.camera.up = view.Row(1).Vec3()
 			self.camera.forward = view.Row(2).Vec3().Mul(-1)
 
-			if ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyUp) {
+			if input.Pressed("move_forward") {
 				self.camera.pos = self.camera.pos.Add(self.camera.forward.Mul(0.1))
-			} else if ebiten.IsKeyPressed(ebiten.KeyS) || ebiten.IsKeyPressed(ebiten.KeyDown) {
+			} else if input.Pressed("move_backward") {
 				self.camera.pos = self.camera.pos.Sub(self.camera.forward.Mul(0.1))
 			}
 
-			if ebiten.IsKeyPressed(ebiten.KeyD) || ebiten.IsKeyPressed(ebiten.KeyRight) {
+			if input.Pressed("move_right") {
 				self.camera.pos = self.camera.pos.Add(self.camera.right.Mul(0.1))
-			} else if ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyLeft) {
+			} else if input.Pressed("move_left") {
 				self.camera.pos = self.camera.pos.Sub(self.camera.right.Mul(0.1))
 			}
 
@@ -339,292 +352,9 @@ func (self *game) Update() error {
 	return nil
 }
 
-type plane struct {
-	origin vec4
-	normal vec4
-}
-
-// test determines if `v` is in front of the plane.
-func (p plane) test(v vec4) bool {
-	return v.Sub(p.origin).Dot(p.normal) > 0
-}
-
-// intersection returns the point of contact of a line segment between a->b to our plane.
-func (p plane) intersection(a, b vec4) vec4 {
-	u := b.Sub(a)
-	w := a.Sub(p.origin)
-	d := p.normal.Dot(u)
-	n := -p.normal.Dot(w)
-	return a.Add(u.Mul(n / d))
-}
-
-var clip_planes = [...]plane{
-	{origin: vec4{1, 0, 0, 1}, normal: vec4{-1, 0, 0, 1}}, // right
-	{origin: vec4{-1, 0, 0, 1}, normal: vec4{1, 0, 0, 1}}, // left
-	{origin: vec4{0, 1, 0, 1}, normal: vec4{0, -1, 0, 1}}, // bottom
-	{origin: vec4{0, -1, 0, 1}, normal: vec4{0, 1, 0, 1}}, // top
-	{origin: vec4{0, 0, 1, 1}, normal: vec4{0, 0, -1, 1}}, // front
-	{origin: vec4{0, 0, -1, 1}, normal: vec4{0, 0, 1, 1}}, // back
-}
-
-// scratch1 & 2 are temporary buffers to reduce allocations in
-// the sutherland_hodgman_3d function.
-var scratch1 = [9]vec4{} // 9 is a safe number to ensure we never
-var scratch2 = [9]vec4{} // run out of space while clipping
-
-// https://en.wikipedia.org/wiki/Sutherland-Hodgman_algorithm
-// this function is not concurrency safe since there are no mechanisms
-// to switch or protect scratch1 or scratch2.
-func sutherland_hodgman_3d(p1, p2, p3 vec4) []vec4 {
-	output := append(scratch2[:0], p1, p2, p3)
-
-	for _, plane := range clip_planes {
-		copy(scratch1[:], output)       // copy output polygon to our input
-		input := scratch1[:len(output)] //
-		output = scratch2[:0]           // clear our output polygon
-
-		if len(input) == 0 {
-			return nil
-		}
-
-		prev_point := input[len(input)-1]
-
-		for _, point := range input {
-			if plane.test(point) {
-				if !plane.test(prev_point) {
-					output = append(output, plane.intersection(prev_point, point))
-				}
-				output = append(output, point)
-			} else if plane.test(prev_point) {
-				output = append(output, plane.intersection(prev_point, point))
-			}
-			prev_point = point
-		}
-	}
-	return output
-}
-
-// https://en.wikipedia.org/wiki/Barycentric_coordinate_system
-func barycentric(p1, p2, p3, p vec3) vec3 {
-	v0 := p2.Sub(p1)
-	v1 := p3.Sub(p1)
-	v2 := p.Sub(p1)
-	d00 := v0.Dot(v0)
-	d01 := v0.Dot(v1)
-	d11 := v1.Dot(v1)
-	d20 := v2.Dot(v0)
-	d21 := v2.Dot(v1)
-	d := d00*d11 - d01*d01
-	v := (d11*d20 - d01*d21) / d
-	w := (d00*d21 - d01*d20) / d
-	u := 1 - v - w
-	return vec3{u, v, w}
-}
-
-type context struct {
-	shader      *ebiten.Shader
-	view_matrix mat4
-	proj_matrix mat4
-	// viewport is used to convert normalized device coordinates to screen coordinates
-	viewport viewport
-
-	// statistics
-	drawn_triangles int
-
-	// the following are not required to be stored here,
-	// they serve as buffers to reduce overall allocations.
-
-	clip_space_points []vec4
-	screen_triangles  []screen_triangle
-	vertices          []ebiten.Vertex
-	indices           []uint16
-}
-
-type screen_triangle struct {
-	v1, v2, v3 vertex
-	distance   float
-}
-
-func (c *context) set_viewport(x, y, w, h int) {
-	c.viewport.x = x
-	c.viewport.y = y
-	c.viewport.w = w
-	c.viewport.h = h
-	c.viewport.w_2 = w / 2
-	c.viewport.h_2 = h / 2
-}
-
-func clip_out_of_bounds(a vec4) bool {
-	x, y, z, w := a.X(), a.Y(), a.Z(), a.W()
-	return x < -w || x > w || y < -w || y > w || z < -w || z > w
-}
-
-func (c *context) clip_to_ndc(src vec4) (ndc vec4) {
-	inv_w := 1.0 / src.W()
-	ndc = vec4{
-		src.X() * inv_w,
-		src.Y() * inv_w,
-		src.Z() * inv_w,
-		src.W(), //  retain W for later
-	}
-	return
-}
-
-func (c *context) ndc_to_screen(src vec4) vec4 {
-	w_2 := float(c.viewport.w_2)
-	h_2 := float(c.viewport.h_2)
-	return vec4{
-		w_2*src.X() + w_2,
-		h_2*src.Y() + h_2,
-		src.Z(),
-		src.W(),
-	}
-}
-
-func (ctx *context) push_mesh(mesh *mesh) {
-	// save us some calculations by doing this here instead of per point
-	projection_view_matrix := ctx.proj_matrix.Mul4(ctx.view_matrix)
-
-	// transform all the mesh points into clip space
-	for _, point := range mesh.points {
-		point := projection_view_matrix.Mul4x1(point.Vec4(1))
-		ctx.clip_space_points = append(ctx.clip_space_points, point)
-	}
-
-	for _, triangle := range mesh.triangles {
-		v1 := vertex{
-			position: ctx.clip_space_points[triangle.p1],
-			texcoord: mesh.texcoords[triangle.t1],
-		}
-		v2 := vertex{
-			position: ctx.clip_space_points[triangle.p2],
-			texcoord: mesh.texcoords[triangle.t2],
-		}
-		v3 := vertex{
-			position: ctx.clip_space_points[triangle.p3],
-			texcoord: mesh.texcoords[triangle.t3],
-		}
-
-		if clip_out_of_bounds(v1.position) || clip_out_of_bounds(v2.position) || clip_out_of_bounds(v3.position) {
-			ctx.clip_triangle_and_push(v1, v2, v3)
-		} else {
-			ctx.push_triangle(v1, v2, v3)
-		}
-	}
-}
-
-func (c *context) clip_triangle_and_push(v1, v2, v3 vertex) {
-	points := sutherland_hodgman_3d(v1.position, v2.position, v3.position)
-
-	p1 := v1.position.Vec3()
-	p2 := v2.position.Vec3()
-	p3 := v3.position.Vec3()
-
-	for i := 2; i < len(points); i++ {
-		b1 := barycentric(p1, p2, p3, points[0].Vec3())
-		b2 := barycentric(p1, p2, p3, points[i-1].Vec3())
-		b3 := barycentric(p1, p2, p3, points[i].Vec3())
-
-		c.push_triangle(
-			interpolate_vertex(v1, v2, v3, b1),
-			interpolate_vertex(v1, v2, v3, b2),
-			interpolate_vertex(v1, v2, v3, b3),
-		)
-	}
-}
-
-func (c *context) push_triangle(v1, v2, v3 vertex) {
-	ndc1 := c.clip_to_ndc(v1.position)
-	ndc2 := c.clip_to_ndc(v2.position)
-	ndc3 := c.clip_to_ndc(v3.position)
-
-	// back-face culling
-	if (ndc2.X()-ndc1.X())*(ndc3.Y()-ndc1.Y())-(ndc3.X()-ndc1.X())*(ndc2.Y()-ndc1.Y()) <= 0 {
-		return
-	}
-
-	v1.position = c.ndc_to_screen(ndc1)
-	v2.position = c.ndc_to_screen(ndc2)
-	v3.position = c.ndc_to_screen(ndc3)
-
-	c.screen_triangles = append(c.screen_triangles, screen_triangle{
-		v1:       v1,
-		v2:       v2,
-		v3:       v3,
-		distance: (v1.position.Z() + v2.position.Z() + v3.position.Z()) / 3,
-	})
-}
-
-func (ctx *context) sort_triangles() {
-	slices.SortFunc(ctx.screen_triangles, func(a, b screen_triangle) int {
-		if a.distance >= b.distance {
-			return -1
-		}
-		return 1
-	})
-}
-
-func (ctx *context) draw_triangles(texture, target *ebiten.Image) {
-	for _, triangle := range ctx.screen_triangles {
-		v1 := triangle.v1
-		v2 := triangle.v2
-		v3 := triangle.v3
-
-		inv_w1 := 1.0 / v1.position.W()
-		inv_w2 := 1.0 / v2.position.W()
-		inv_w3 := 1.0 / v3.position.W()
-
-		ctx.vertices = append(ctx.vertices,
-			ebiten.Vertex{
-				SrcX:   v1.texcoord.X() * inv_w1,
-				SrcY:   v1.texcoord.Y() * inv_w1,
-				DstX:   v1.position.X(),
-				DstY:   v1.position.Y(),
-				ColorR: 1,
-				ColorG: 1,
-				ColorB: 1,
-				ColorA: inv_w1,
-			},
-			ebiten.Vertex{
-				SrcX:   v2.texcoord.X() * inv_w2,
-				SrcY:   v2.texcoord.Y() * inv_w2,
-				DstX:   v2.position.X(),
-				DstY:   v2.position.Y(),
-				ColorR: 1,
-				ColorG: 1,
-				ColorB: 1,
-				ColorA: inv_w2,
-			},
-			ebiten.Vertex{
-				SrcX:   v3.texcoord.X() * inv_w3,
-				SrcY:   v3.texcoord.Y() * inv_w3,
-				DstX:   v3.position.X(),
-				DstY:   v3.position.Y(),
-				ColorR: 1,
-				ColorG: 1,
-				ColorB: 1,
-				ColorA: inv_w3,
-			},
-		)
-
-		first_index := uint16(len(ctx.indices))
-		ctx.indices = append(ctx.indices, first_index, first_index+1, first_index+2)
-	}
-
-	target.DrawTrianglesShader(ctx.vertices, ctx.indices, ctx.shader, &ebiten.DrawTrianglesShaderOptions{
-		Images: [4]*ebiten.Image{
-			texture,
-		},
-		AntiAlias: true,
-	})
-
-	ctx.drawn_triangles = len(ctx.indices) / 3
-
-	// reset buffers
-	ctx.clip_space_points = ctx.clip_space_points[:0]
-	ctx.screen_triangles = ctx.screen_triangles[:0]
-	ctx.vertices = ctx.vertices[:0]
-	ctx.indices = ctx.indices[:0]
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
 }
 
 func (self *game) Draw(screen *ebiten.Image) {
@@ -637,38 +367,85 @@ func (self *game) Draw(screen *ebiten.Image) {
 		}
 	}(time.Now())
 
+	mode := render.AA(anti_alias.Int())
+	if aa_compare.Bool() {
+		self.aa.Compare(screen, mode, 0.5, self.draw_scene)
+		ebitenutil.DebugPrintAt(screen, "AA off", game_width/2-48, game_height-16)
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("AA %v", mode), game_width/2+8, game_height-16)
+	} else {
+		self.aa.Draw(screen, mode, self.draw_scene)
+	}
+
+	ctx := self.context
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f", ebiten.ActualTPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("FPS: %.0f (%v)", ebiten.ActualFPS(), self.frametime), 0, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Triangles: %d (%s), rejected meshes: %d", ctx.Stats.Triangles, *rasterizer, ctx.Stats.Rejected), 0, 28)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Eye: %.2f, %.2f", self.camera.pitch, self.camera.yaw), 0, 42)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Cam: %v", self.camera.pos), 0, 56)
+	near, far, _ := ctx.NearFar()
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Near/far: %.2f, %.2f", near, far), 0, 70)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("AA: %v", mode), 0, 84)
+}
+
+// draw_scene draws the scene onto `target`, which is larger than the screen when it's supersampled.
+func (self *game) draw_scene(target *ebiten.Image, mode render.AA) {
+	if r, ok := self.renderer.(*render.Renderer); ok {
+		r.AntiAlias = mode == render.AAEbiten
+	}
 	ctx := self.context
 
-	w := screen.Bounds().Dx()
-	h := screen.Bounds().Dy()
+	w := target.Bounds().Dx()
+	h := target.Bounds().Dy()
 
-	ctx.set_viewport(0, 0, w, h)
+	ctx.SetViewport(0, 0, w, h)
+	ctx.SortBuckets = sort_buckets.Int()
+	ctx.ExactSort = exact_sort.Bool()
+	ctx.AutoNearFar = auto_near_far.Bool()
+	ctx.ReverseZ = reverse_z.Bool()
 
 	// If you use orthographic then the Z axis will invert for everything.
 	// https://www.songho.ca/opengl/gl_projectionmatrix.html#perspective
 	// ctx.set_orthographic(-eye_distance*game_aspect, eye_distance*game_aspect, eye_distance, -eye_distance, 0.1, 10)
 
-	ctx.proj_matrix = mgl32.Perspective(30, game_aspect, 0.1, 100)
+	ctx.SetPerspective(self.fov, game_aspect, 0.1, 100)
 
 	// the camera view matrix is invalid until the user controls it
 	if self.camera.view_matrix.Det() == 0 {
-		ctx.view_matrix = mgl32.LookAtV(
+		ctx.SetView(mgl32.LookAtV(
 			vec3{0, 7, 19},
 			vec3{0, 0, 0},
 			vec3{0, 1, 0},
-		)
+		))
 	} else {
-		ctx.view_matrix = self.camera.view_matrix
+		ctx.SetView(self.camera.view_matrix)
 	}
 
-	screen.Fill(color.RGBA{130, 130, 130, 255})
-	ctx.push_mesh(self.mesh)
-	ctx.sort_triangles()
-	ctx.draw_triangles(self.texture, screen)
+	if draw_sky.Bool() {
+		// the sky expects the flip of the pipeline's FlipY in its matrix
+		self.sky.Draw(target, mgl32.Scale3D(1, -1, 1).Mul4(ctx.ProjectionMatrix().Mul4(ctx.ViewMatrix())))
+	} else {
+		target.Fill(clear_color.Color())
+	}
+	ctx.PushMesh(self.mesh)
+	ctx.Sort()
+	if depth_view.Bool() {
+		near, far := render.DepthRange(ctx.Triangles(), ctx.ReverseZ)
+		self.renderer.DrawDepth(target, ctx.Triangles(), near, far)
+	} else {
+		self.renderer.DrawTriangles(target, self.texture, ctx.Triangles())
+	}
+	if wireframe.Bool() {
+		draw_wireframe(target, ctx.Triangles(), float(mode.Scale()))
+	}
+	ctx.Reset()
+}
 
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f", ebiten.ActualTPS()), 0, 0)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("FPS: %.0f (%v)", ebiten.ActualFPS(), self.frametime), 0, 14)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Triangles: %d", ctx.drawn_triangles), 0, 28)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Eye: %.2f, %.2f", self.camera.pitch, self.camera.yaw), 0, 42)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Cam: %v", self.camera.pos), 0, 56)
+func draw_wireframe(screen *ebiten.Image, triangles []pipeline.Triangle, width float) {
+	clr := color.RGBA{0, 255, 0, 255}
+	for _, t := range triangles {
+		p1, p2, p3 := t.V1.Position, t.V2.Position, t.V3.Position
+		vector.StrokeLine(screen, p1.X(), p1.Y(), p2.X(), p2.Y(), width, clr, false)
+		vector.StrokeLine(screen, p2.X(), p2.Y(), p3.X(), p3.Y(), width, clr, false)
+		vector.StrokeLine(screen, p3.X(), p3.Y(), p1.X(), p1.Y(), width, clr, false)
+	}
 }
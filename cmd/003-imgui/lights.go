@@ -0,0 +1,304 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/bake"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/debugdraw"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/locale"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/scene"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/undo"
+)
+
+// light_kinds are the kinds of light in the order the kind button goes through them
+var light_kinds = []string{scene.Point, scene.Directional, scene.Ambient}
+
+// the lights window edits the lights of the scene, the nodes at its top with a light. selected_light is the one
+// edited and moved with the gizmo in the viewport.
+var (
+	selected_light *scene.Node
+	light_list     ui.List
+	light_edit     = struct {
+		color, position, rotation ui.VectorEdit
+		intensity, reach          ui.Spinbox
+	}{
+		color:     ui.VectorEdit{Color: true},
+		position:  ui.VectorEdit{Step: 0.01},
+		rotation:  ui.VectorEdit{Step: 1},
+		intensity: ui.Spinbox{Step: 0.05, Min: 0, Max: 8},
+		reach:     ui.Spinbox{Step: 0.05, Min: 0.1, Max: 20},
+	}
+)
+
+// baked draws the meshes with the light baked into them rather than lighting them every frame, for comparing the
+// two. bake_next bakes them when the scene is next drawn, where its meshes and lights are in place.
+var baked, bake_next bool
+
+// light_nodes are the lights the window edits.
+func light_nodes() []*scene.Node {
+	var lights []*scene.Node
+	for _, n := range world.Nodes {
+		if n.Light != nil {
+			lights = append(lights, n)
+		}
+	}
+	return lights
+}
+
+// draw_lights draws the buttons to add and remove lights, to bake them and to capture the reflection probes, the
+// list of the lights, and the editors of the one selected.
+func draw_lights() {
+	const name_width = 80
+	const row_height = 20
+	const list_rows = 4
+
+	lights := light_nodes()
+	if !slices.Contains(lights, selected_light) {
+		selected_light = nil
+	}
+
+	ctx.Panel()
+	area := ctx.Next().Bounds().Inset(6)
+	y := area.Min.Y
+	ctx.Push(area.Min.X, y, area.Dx(), row_height, &ui.GridLayout{Columns: 2, Rows: 1})
+	ctx.Button(ui.ButtonArgs{Text: locale.T("add"), AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: add_light}})
+	ctx.Button(ui.ButtonArgs{Text: locale.T("remove"), AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: remove_light}})
+	ctx.Pop()
+	y += row_height + 4
+	ctx.Push(area.Min.X, y, area.Dx(), row_height, &ui.GridLayout{Columns: 3, Rows: 1})
+	ctx.Button(ui.ButtonArgs{Text: locale.T("bake"), AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: func() {
+		bake_next, baked = true, true
+	}}})
+	ctx.Button(ui.ButtonArgs{
+		Text:     locale.T("baked"),
+		AlignX:   0.5,
+		AlignY:   0.5,
+		Selected: baked,
+		Behavior: ui.ButtonBehavior{OnActivate: func() { baked = !baked }},
+	})
+	ctx.Button(ui.ButtonArgs{Text: locale.T("capture"), AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: func() {
+		capture_next = true
+	}}})
+	ctx.Pop()
+	y += row_height + 4
+
+	ctx.Push(area.Min.X, y, area.Dx(), list_rows*row_height, nil)
+	ctx.List(&light_list, len(lights), row_height, func(i int) {
+		ctx.Button(ui.ButtonArgs{
+			Text:     lights[i].Name,
+			AlignX:   0.02,
+			AlignY:   0.5,
+			Selected: lights[i] == selected_light,
+			Behavior: ui.ButtonBehavior{OnActivate: func() { selected_light = lights[i] }},
+		})
+	})
+	ctx.Pop()
+	y += list_rows*row_height + 4
+
+	n := selected_light
+	if n == nil {
+		return
+	}
+	property := func(name string, edit func()) {
+		name_size := ui.Size{Min: image.Pt(name_width, 0), Preferred: image.Pt(name_width, 0)}
+		ctx.Push(area.Min.X, y, area.Dx(), min(row_height, area.Max.Y-y), &ui.StackLayout{Gap: 4, Sizes: []ui.Size{name_size, {Stretch: 1}}})
+		ctx.Label(locale.T(name), 0, 0.5)
+		edit()
+		ctx.Pop()
+		y += row_height + 4
+	}
+	l, t := n.Light, &n.Transform
+	before, transform := *l, *t
+	property("kind", func() {
+		ctx.Button(ui.ButtonArgs{
+			Text:   locale.T("light_" + l.Kind),
+			AlignX: 0.5,
+			AlignY: 0.5,
+			Behavior: ui.ButtonBehavior{Mode: ui.ActivateOnClick, OnActivate: func() {
+				next := light_kinds[(slices.Index(light_kinds, l.Kind)+1)%len(light_kinds)]
+				history.Do(&undo.Set[string]{Target: &l.Kind, From: l.Kind, To: next})
+			}},
+		})
+	})
+	property("color", func() { record(&l.Color, ctx.Vector(&light_edit.color, l.Color[:]), before.Color) })
+	property("intensity", func() { spin(&light_edit.intensity, &l.Intensity) })
+	switch l.Kind {
+	case scene.Point:
+		property("range", func() { spin(&light_edit.reach, &l.Range) })
+		property("position", func() { record(&t.Position, ctx.Vector(&light_edit.position, t.Position[:]), transform.Position) })
+	case scene.Directional:
+		property("rotation", func() { record(&t.Rotation, ctx.Vector(&light_edit.rotation, t.Rotation[:]), transform.Rotation) })
+	}
+}
+
+// spin declares the spinbox `s` of `value` and records its edits.
+func spin(s *ui.Spinbox, value *float) {
+	before := *value
+	v := float64(*value)
+	changed := ctx.Spinbox(s, &v)
+	*value = float(v)
+	record(value, changed, before)
+}
+
+// add_light adds a point light above the turntable and selects it.
+func add_light() {
+	name := ""
+	for i := 1; name == "" || world.Find(name) != nil; i++ {
+		name = fmt.Sprint("light ", i)
+	}
+	n := &scene.Node{Name: name, Transform: scene.Identity, Light: &scene.Light{Kind: scene.Point, Color: vec3{1, 1, 1}, Intensity: 1, Range: 3}}
+	n.Transform.Position = vec3{0, 1.5, 1}
+	history.Do(&undo.Set[[]*scene.Node]{Target: &world.Nodes, From: world.Nodes, To: append(slices.Clip(world.Nodes), n)})
+	selected_light = n
+}
+
+// remove_light removes the light selected.
+func remove_light() {
+	i := slices.Index(world.Nodes, selected_light)
+	if i < 0 {
+		return
+	}
+	history.Do(&undo.Set[[]*scene.Node]{Target: &world.Nodes, From: world.Nodes, To: slices.Delete(slices.Clone(world.Nodes), i, i+1)})
+	selected_light = nil
+}
+
+// scene_light is the light `l` placed by `transform` as the renderer takes it, false for kinds it doesn't know.
+func scene_light(l *scene.Light, transform mgl32.Mat4) (render.Light, bool) {
+	light := render.Light{Color: l.Color.Mul(l.Intensity), Position: transform.Col(3).Vec3(), Range: l.Range}
+	switch l.Kind {
+	case scene.Point:
+		light.Kind = render.PointLight
+	case scene.Directional:
+		light.Kind = render.DirectionalLight
+		light.Direction = transform.Mul4x1(vec4{0, 0, -1, 0}).Vec3().Normalize()
+	case scene.Ambient:
+		light.Kind = render.AmbientLight
+	default:
+		return light, false
+	}
+	return light, true
+}
+
+// bake_light is `l` as package bake takes it.
+func bake_light(l render.Light) bake.Light {
+	kind := map[render.LightKind]bake.LightKind{
+		render.AmbientLight:     bake.Ambient,
+		render.DirectionalLight: bake.Directional,
+		render.PointLight:       bake.Point,
+	}[l.Kind]
+	return bake.Light{Kind: kind, Color: l.Color, Position: l.Position, Direction: l.Direction, Range: l.Range}
+}
+
+// bake bakes the lights of the scene into its meshes as they're placed now, with ambient occlusion and shadows.
+// The meshes keep the light as they move, which the cube spinning in the light of the sun shows off.
+func (self *game) bake() {
+	var meshes []*mesh.Mesh
+	for _, m := range self.by_id {
+		meshes = append(meshes, m)
+	}
+	// the same meshes in the same order cast the same rays, baking again changes nothing
+	slices.SortFunc(meshes, func(a, b *mesh.Mesh) int { return cmp.Compare(a.ID(), b.ID()) })
+	lights := make([]bake.Light, len(self.lights))
+	for i, l := range self.lights {
+		lights[i] = bake_light(l)
+	}
+	start := time.Now()
+	bake.Bake(meshes, bake.Options{Lights: lights, Samples: 32, Distance: 1, Shadows: true})
+	logger.Infof("baked %d meshes in %v", len(meshes), time.Since(start).Round(time.Millisecond))
+}
+
+// gizmo_length is how long the arrows of the gizmo are, in world units.
+const gizmo_length = 0.5
+
+// gizmo is the drag of an arrow of the gizmo: along which axis, from where the light and the cursor were, and
+// where the arrow pointed on screen
+var gizmo struct {
+	dragging bool
+	axis     int
+	from     vec3
+	cursor   vec2
+	arrow    vec2
+}
+
+var axis_colors = [3]color.RGBA{{230, 70, 70, 255}, {70, 200, 70, 255}, {80, 120, 240, 255}}
+
+// draw_light_gizmos draws the lights over the viewport, the range of point lights as a wireframe sphere and the
+// direction of directional ones as a line, and the arrows which move the one selected when they're dragged.
+func draw_light_gizmos(dst *ebiten.Image, c *pipeline.Context) {
+	for _, n := range light_nodes() {
+		clr := color.RGBA{140, 140, 150, 255}
+		if n == selected_light {
+			clr = color.RGBA{255, 220, 120, 255}
+		}
+		l, ok := scene_light(n.Light, n.Transform.Matrix())
+		switch {
+		case !ok:
+		case l.Kind == render.PointLight:
+			debugdraw.Sphere(dst, c, l.Position, l.Range, 1, clr)
+			debugdraw.Point(dst, c, l.Position, 4, clr)
+		case l.Kind == render.DirectionalLight:
+			debugdraw.Line(dst, c, l.Position, l.Position.Add(l.Direction), 2, clr)
+			debugdraw.Point(dst, c, l.Position, 4, clr)
+		}
+	}
+	move_light(dst, c)
+}
+
+// move_light draws an arrow along each axis from the light selected, and moves the light along the one dragged.
+// The drag is recorded as it goes, it's one edit once the button is let go.
+func move_light(dst *ebiten.Image, c *pipeline.Context) {
+	if !input.MousePressed(ebiten.MouseButtonLeft) {
+		gizmo.dragging = false
+	}
+	n := selected_light
+	if n == nil || n.Light.Kind == scene.Ambient {
+		gizmo.dragging = false
+		return
+	}
+	at := n.Transform.Position
+	start, ok := c.Project(at)
+	if !ok {
+		return
+	}
+	x, y := input.CursorPosition()
+	cursor := vec2{float(x), float(y)}
+	for i := range 3 {
+		var axis vec3
+		axis[i] = 1
+		tip := at.Add(axis.Mul(gizmo_length))
+		debugdraw.Line(dst, c, at, tip, 2, axis_colors[i])
+		debugdraw.Point(dst, c, tip, 5, axis_colors[i])
+		end, ok := c.Project(tip)
+		if ok && !gizmo.dragging && input.MouseJustPressed(ebiten.MouseButtonLeft) &&
+			image.Pt(x, y).In(dst.Bounds()) && cursor.Sub(end).Len() < 8 {
+			gizmo.dragging, gizmo.axis, gizmo.from, gizmo.cursor, gizmo.arrow = true, i, at, cursor, end.Sub(start)
+		}
+	}
+	if !gizmo.dragging {
+		return
+	}
+	// how far the cursor went along the arrow on screen is how far the light goes along it in the world
+	length := gizmo.arrow.Dot(gizmo.arrow)
+	if length == 0 {
+		return
+	}
+	var axis vec3
+	axis[gizmo.axis] = 1
+	t := cursor.Sub(gizmo.cursor).Dot(gizmo.arrow) / length
+	before := n.Transform.Position
+	n.Transform.Position = gizmo.from.Add(axis.Mul(t * gizmo_length))
+	record(&n.Transform.Position, n.Transform.Position != before, before)
+}
@@ -1,58 +1,50 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"image"
 	"image/color"
+	"io/fs"
 	"log"
 	"math"
-	"runtime"
+	"slices"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/go-gl/mathgl/mgl32"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/hajimehoshi/ebiten/v2/vector"
-)
-
-func cursor_within(rect image.Rectangle) bool {
-	cx, cy := ebiten.CursorPosition()
-	return cx >= rect.Min.X && cy >= rect.Min.Y && cx < rect.Max.X && cy < rect.Max.Y
-}
 
-func draw_border(dst *ebiten.Image, inset, width float32, clr color.Color) {
-	bounds := dst.Bounds()
-	inset += width / 2
-	x := float32(bounds.Min.X) + inset
-	y := float32(bounds.Min.Y) + inset
-	w := float32(bounds.Dx()) - inset*2
-	h := float32(bounds.Dy()) - inset*2
-	vector.StrokeRect(dst, x, y, w, h, width, clr, false)
-}
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/audio"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/curve"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/dock"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/locale"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/material"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/scene"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tween"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/undo"
+)
 
-func draw_string(dst *ebiten.Image, s string, align_x, align_y float32) {
-	bounds := dst.Bounds()
-	x := float32(bounds.Min.X)
-	y := float32(bounds.Min.Y)
-	width := float32(bounds.Dx())
-	height := float32(bounds.Dy())
+var logger = logging.Tag("main")
 
-	const font_height = 16
+//go:embed strings
+var strings_fs embed.FS
 
-	n_lines := strings.Count(s, "\n") + 1
-	text_height := float32(n_lines * font_height)
-	y += (height - text_height) * align_y
+// materials are read from the materials directory when it has them, so copying a file there and editing it
+// changes the material as the demo runs, and otherwise from the ones built in
+//
+//go:embed materials
+var materials_fs embed.FS
 
-	for _, line := range strings.Split(s, "\n") {
-		const char_width = 6
-		line_width := float32(len(line) * char_width)
-		x := x + (width-line_width)*align_x
-		ebitenutil.DebugPrintAt(dst, line, int(x), int(y))
-		y += font_height
-	}
-}
+var materials = render.Materials{Library: &material.Library{Dir: "materials"}}
 
 func main() {
 	ebiten.SetWindowSize(800, 600)
@@ -60,401 +52,769 @@ func main() {
 	ebiten.SetTPS(60)
 	ebiten.SetVsyncEnabled(true)
 
-	if err := ebiten.RunGame(&game{}); err != nil {
+	// menu sounds: a tick when hovering, a lower click when pressing and a chime when a button goes off
+	audio.Default.Add("tick", audio.Tone(2400, 0.02))
+	audio.Default.Add("click", audio.Tone(700, 0.05))
+	audio.Default.Add("chime", audio.Synth(0.4, func(t float32) float32 {
+		fifth := math.Sin(2*math.Pi*880*float64(t)) + math.Sin(2*math.Pi*1320*float64(t))
+		return float32(fifth/2) * audio.Decay(t, 0.4)
+	}))
+	audio.Default.On("ui_hover", 0.15, "tick")
+	audio.Default.On("ui_press", 0.4, "click")
+	audio.Default.On("ui_activate", 0.3, "chime")
+
+	if err := app.RunLoader(load, nil); err != nil {
 		log.Panic(err)
 	}
 }
 
-var time_start = time.Now()
+// load makes the game, app.RunLoader shows broken strings or a renderer which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	if err := locale.LoadFS(strings_fs, "strings"); err != nil {
+		return nil, err
+	}
 
-func elapsed() time.Duration {
-	return time_start.Sub(time.Now())
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	g := &game{
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		texture:  ebiten.NewImage(2, 2),
+		meshes:   make(map[*scene.Node]*placed),
+		drawn:    make(map[uint32]drawing),
+		by_id:    make(map[uint32]*mesh.Mesh),
+		probes:   make(map[*scene.Node]*render.Probe),
+	}
+	g.checker = &render.Material{Images: [4]*ebiten.Image{g.texture}}
+	if fallback, err := fs.Sub(materials_fs, "materials"); err == nil {
+		materials.Library.Fallback = fallback
+	}
+	return g, nil
 }
 
-var current_frame int
-
-// we need input state synchronized with the frame due to checking inputs at the end of a frame
-var (
-	input_mu       sync.Mutex
-	mouse_pressed  = make(map[ebiten.MouseButton]int)
-	mouse_released = make(map[ebiten.MouseButton]int)
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
 )
 
-func mouse_just_pressed(button ebiten.MouseButton) bool {
-	input_mu.Lock()
-	defer input_mu.Unlock()
-	return mouse_pressed[button] == current_frame
-}
+var time_start = time.Now()
 
-func mouse_just_released(button ebiten.MouseButton) bool {
-	input_mu.Lock()
-	defer input_mu.Unlock()
-	return mouse_released[button] == current_frame
+func elapsed() time.Duration {
+	return time_start.Sub(time.Now())
 }
 
-type layout_t interface {
-	layout(src image.Rectangle) (dst image.Rectangle)
+// the windows of the dock space, the viewport is the 3D view the rest of them are arranged around
+var windows = []string{"viewport", "inspector", "lights", "easing", "buttons", "list", "timeline", "scene", "about"}
+
+// default_space is the arrangement until the windows are moved: the inspector, the lights, the easing, the buttons
+// and the list tabbed to the right of the viewport, the timeline, the scene and the about panel below it.
+func default_space() *dock.Space {
+	space := dock.NewSpace("viewport")
+	space.Dock("inspector", space.Root, dock.Right)
+	space.Dock("lights", space.Find("inspector"), dock.Center)
+	space.Dock("easing", space.Find("inspector"), dock.Center)
+	space.Dock("buttons", space.Find("inspector"), dock.Center)
+	space.Dock("list", space.Find("inspector"), dock.Center)
+	space.Dock("timeline", space.Find("viewport"), dock.Bottom)
+	space.Dock("scene", space.Find("timeline"), dock.Center)
+	space.Dock("about", space.Find("timeline"), dock.Center)
+	space.Show("timeline")
+	space.Show("inspector")
+	return space
 }
 
-// grid_layout is a rough example of how layouts might be implemented
-type grid_layout struct {
-	columns int
-	rows    int
-	current int
+type game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	// batch joins the draws of the scene into fewer draw calls
+	batch   render.Batch
+	texture *ebiten.Image
+	spin    float
+	// meshes are those of the nodes of the scene shown, which they're built for
+	meshes map[*scene.Node]*placed
+	shown  *scene.Scene
+	// checker is the material of the nodes without a material file, drawn is how the meshes pushed are drawn and
+	// by_id are those meshes, for the debug drawing of normals
+	checker *render.Material
+	drawn   map[uint32]drawing
+	by_id   map[uint32]*mesh.Mesh
+	// lights are those of the scene shown, in world space
+	lights []render.Light
+	// nodes are the nodes of the scene shown with a mesh, in the order they're walked
+	nodes []node_mesh
+	// probes are the reflection probes of the nodes of the scene shown, shown_probes those shown this frame
+	probes       map[*scene.Node]*render.Probe
+	shown_probes []node_probe
 }
 
-func (l *grid_layout) layout(src image.Rectangle) (dst image.Rectangle) {
-	if l.current == l.rows*l.columns {
-		return image.Rectangle{}
-	}
-	col := l.current % l.columns
-	row := l.current / l.columns
-	l.current++
-	cell_width := src.Dx() / l.columns
-	cell_height := src.Dy() / l.rows
-	x := src.Min.X + (col * cell_width)
-	y := src.Min.Y + (row * cell_height)
-	w := cell_width
-	h := cell_height
-	return image.Rect(x, y, x+w, y+h)
+type node_mesh struct {
+	node *scene.Node
+	mesh *mesh.Mesh
 }
 
-type game struct{}
-
-// uid_t is a unique identifier that should remain consistent between frames.
-type uid_t struct {
-	// base is typically derived from a program counter, however it can be any deterministic value that stays the same between frames
-	base uint64
-	// id is typically a value starting at 0 and incrementing for each time a `base` is reused.
-	id uint64
+// drawing is how a mesh is drawn, with its material unless it's debugged.
+type drawing struct {
+	material *render.Material
+	debug    render.Debug
 }
 
-var uid_zero uid_t
-
-type ui_context_t struct {
-	// layers tracks the clipping of the context. The last entry is always the "top" or "active" clipping area.
-	layers []*ebiten.Image
-
-	// layout affects the returned *ebiten.Image of ctx.next()
-	layout layout_t
+// debug is how the inspector debugs the meshes of nodes, they're drawn with their material when they're missing
+var debug = make(map[*scene.Node]render.Debug)
 
-	// triggers is a mapping of uid->trigger for behaviors that can happen with a delay...
-	// like pressing a button, dragging away, and then releasing
-	triggers map[uid_t]trigger_t
-
-	// uid_base_occurences is a mapping of program counters (PC) to the number of occurences on the current frame.
-	// This map gets cleared at the end of each frame
-	uid_base_occurences map[uintptr]uint64
+// placed is the mesh built from the recipe of a node, with its points in the node's space to be moved from.
+type placed struct {
+	recipe string
+	mesh   *mesh.Mesh
+	local  []vec3
+}
 
-	// uid_frame is a mapping of UIDs to the cycle
-	uid_frame map[uid_t]int
+// world is the scene the viewport shows and the inspector edits, saved and loaded in the scene window
+var world = default_scene()
+
+// speed is how fast the cube spins, it's the demo's rather than the scene's
+var speed = 0.01
+
+// default_scene is a checkered cube on a turntable, which the demo spins and hops, and four moons around it, the
+// instances of a prefab, one with a ball for a box, one striped blue and one without its ball, and a mirror ball
+// beside them.
+func default_scene() *scene.Scene {
+	s := scene.New()
+	s.Materials["checker"] = &scene.Material{Params: map[string]vec4{
+		"light": {0.78, 0.82, 0.9, 1},
+		"dark":  {0.35, 0.43, 0.63, 1},
+	}}
+	cube := &scene.Node{Name: "cube", Transform: scene.Identity, Mesh: "box 1 1 1", Material: "checker"}
+	turntable := &scene.Node{Name: "turntable", Transform: scene.Identity, Children: []*scene.Node{cube}}
+	title := &scene.Node{Name: "title", Transform: scene.Identity, Mesh: "text 0.3 0.06 Kage", Material: "checker"}
+	title.Transform.Position = vec3{0, 0.9, 0}
+	s.Nodes = []*scene.Node{turntable, title}
+
+	ball := &scene.Node{Name: "ball", Transform: scene.Identity, Mesh: "sphere 0.08 6 8", Material: "checker"}
+	ball.Transform.Position = vec3{0, 0.2, 0}
+	s.Materials["glow"] = &scene.Material{File: "glow"}
+	s.Materials["stripes"] = &scene.Material{File: "stripes"}
+	s.Prefabs = map[string]*scene.Node{"moon": {
+		Name:      "moon",
+		Transform: scene.Identity,
+		Mesh:      "box 0.2 0.2 0.2",
+		Material:  "glow",
+		Children:  []*scene.Node{ball},
+	}}
+	for i, at := range []vec3{{0.9, 0, 0}, {0, 0, 0.9}, {-0.9, 0, 0}, {0, 0, -0.9}} {
+		moon := scene.Instance(fmt.Sprint("moon ", i), "moon")
+		moon.Transform.Position = at
+		turntable.Children = append(turntable.Children, moon)
+	}
+	turntable.Children[2].Overrides = map[string]scene.Override{"": {Mesh: "sphere 0.12 6 8"}}
+	turntable.Children[3].Overrides = map[string]scene.Override{"": {Material: "stripes"}}
+	turntable.Children[4].Overrides = map[string]scene.Override{"ball": {Hidden: true}}
+
+	// a dim fill, a sun from the upper left and a warm lamp to the front right, see the lights window
+	ambient := &scene.Node{Name: "ambient", Transform: scene.Identity, Light: &scene.Light{Kind: scene.Ambient, Color: vec3{0.6, 0.65, 0.8}, Intensity: 0.5}}
+	sun := &scene.Node{Name: "sun", Transform: scene.Identity, Light: &scene.Light{Kind: scene.Directional, Color: vec3{1, 0.95, 0.85}, Intensity: 0.6}}
+	sun.Transform.Position = vec3{-1, 1.5, 0}
+	sun.Transform.Rotation = vec3{-45, -30, 0}
+	lamp := &scene.Node{Name: "lamp", Transform: scene.Identity, Light: &scene.Light{Kind: scene.Point, Color: vec3{1, 0.6, 0.3}, Intensity: 1, Range: 2.5}}
+	lamp.Transform.Position = vec3{1.2, 0.6, 1}
+	s.Nodes = append(s.Nodes, ambient, sun, lamp)
+
+	// a mirror ball to the left, reflecting the probe at its middle
+	s.Materials["mirror"] = &scene.Material{File: "mirror"}
+	mirror := &scene.Node{Name: "mirror", Transform: scene.Identity, Mesh: "sphere 0.3 12 16", Material: "mirror", Probe: &scene.Probe{Radius: 1, Size: 64}}
+	mirror.Transform.Position = vec3{-1.3, -0.5, 0.3}
+	s.Nodes = append(s.Nodes, mirror)
+	return s
+}
 
-	// frame_triggers is a per-frame tracker of triggers used for testing input against. This list should always be populated
-	// by draw-order to ensure the top level trigger is properly detected.
-	frame_triggers []trigger_t
+func (self *game) Update() error {
+	materials.Library.Reload()
+	// the turntable is moved when it's drawn, so the scene remembers where the last tick's frame had it
+	world.Tick()
+	// the spin and hop are by the tick, slowed and sped up with the rest of time
+	self.spin += float(speed) * app.TimeScale()
+	hop.Advance(float64(app.TimeScale()))
+
+	// a drag is one edit however many frames it took
+	if input.MouseJustReleased(ebiten.MouseButtonLeft) {
+		history.Seal()
+	}
+	ctrl := input.KeyPressed(ebiten.KeyControl) || input.KeyPressed(ebiten.KeyMeta)
+	shift := input.KeyPressed(ebiten.KeyShift)
+	switch {
+	case ctrl && !shift && input.KeyJustPressed(ebiten.KeyZ):
+		history.Undo()
+	case ctrl && (shift && input.KeyJustPressed(ebiten.KeyZ) || input.KeyJustPressed(ebiten.KeyY)):
+		history.Redo()
+	default:
+		return nil
+	}
+	// the cached windows don't know what changed under them
+	layers.inspector.Damage()
+	layers.easing.Damage()
+	return nil
+}
 
-	// hover_uid is a global state for which uid is hovered.
-	hover_uid uid_t
+// history is the undo history of the inspector, the easing and the scenes loaded
+var history = undo.Stack{Limit: 200}
 
-	// press_uid is the global state for which trigger is pressed. Pressed as in: mouse is currently down, not released.
-	press_uid uid_t
+// HotState keeps the scene being edited across restarts by cmd/dev.
+func (self *game) HotState() any {
+	return world
 }
 
-func new_ui_context() *ui_context_t {
-	return &ui_context_t{
-		triggers:            make(map[uid_t]trigger_t),
-		uid_base_occurences: make(map[uintptr]uint64),
-		uid_frame:           make(map[uid_t]int),
+func (self *game) RestoreHotState(data []byte) error {
+	restored, err := scene.Decode(data)
+	if err != nil {
+		return err
 	}
+	world = restored
+	return nil
 }
 
-// start_frame resets and initializes the context with a destination image
-func (ctx *ui_context_t) start_frame(dst *ebiten.Image) {
-	clear(ctx.layers)                        // we're using 'clear' to avoid holding onto references
-	ctx.layers = append(ctx.layers[:0], dst) //
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
 }
 
-// end_frame performs cleanup on per-frame state and runs logic to perform button inputs
-func (ctx *ui_context_t) end_frame() {
-	clear(ctx.uid_base_occurences)
+var (
+	ctx   *ui.Context
+	docks *ui.Dock
+	// header is described as a tree, it only changes with the language and the windows shown
+	header ui.Tree
+	// layers keep the windows which didn't change, see ui_show_redraws
+	layers struct {
+		inspector, easing, buttons, list, scene, about ui.Layer
+	}
+)
 
-	var hovered_trigger trigger_t
-	var cursor_over_trigger bool
+// the editors of the inspector, the scale linked to begin with so it scales evenly
+var inspector = struct {
+	position, rotation, scale, moons, light, dark ui.VectorEdit
+	speed                                         ui.Spinbox
+}{
+	position: ui.VectorEdit{Step: 0.01},
+	rotation: ui.VectorEdit{Step: 1},
+	scale:    ui.VectorEdit{Step: 0.01, Min: 0.05, Max: 4, Linked: true},
+	moons:    ui.VectorEdit{Step: 0.01, Min: 0.05, Max: 4, Linked: true},
+	light:    ui.VectorEdit{Color: true},
+	dark:     ui.VectorEdit{Color: true},
+	speed:    ui.Spinbox{Step: 0.005, Min: -0.2, Max: 0.2},
+}
 
-	for _, trigger := range ctx.frame_triggers {
-		if cursor_within(trigger.bounds) {
-			hovered_trigger = trigger
-			cursor_over_trigger = true
-		}
-	}
-	ctx.frame_triggers = ctx.frame_triggers[:0]
+// easing is how the cube hops up and down, a curve authored in its window, which can start from any of the eases
+// of package tween. The view leaves room for eases which overshoot.
+var easing = ui.CurveEdit{Curve: curve.FromEase(tween.InOutCubic, 5), Min: -0.25, Max: 1.25}
 
-	if next_uid := hovered_trigger.uid; next_uid != ctx.hover_uid {
-		var prev trigger_t
+// easing_keys are the keys of the easing as they were last drawn
+var easing_keys []curve.Key
 
-		if ctx.hover_uid != uid_zero {
-			prev = ctx.triggers[ctx.hover_uid]
-		}
+// hop_ticks is how long the cube takes to go up, and as long to come back down
+const hop_ticks = 90
 
-		next, ok := ctx.triggers[next_uid]
+// hop plays the hop of the cube, in ticks, and its timeline scrubs through it
+var hop = ui.Timeline{Length: hop_ticks * 2, Step: 1, Playing: true, Loop: true}
 
-		if !ok {
-			ctx.triggers[next_uid] = hovered_trigger
-			next = hovered_trigger
-		}
+// `list` is the scroll of the list window, `picked` the row clicked last and `goto_row` the field to jump to one
+var (
+	list     ui.List
+	picked   int
+	goto_row ui.TextInput
+)
 
-		cx, cy := ebiten.CursorPosition()
+const list_rows = 200_000
 
-		log.Printf("%+v -> %+v", prev.uid, next.uid)
+func (self *game) Draw(screen *ebiten.Image) {
+	if ctx == nil {
+		ctx = ui.NewContext()
+		ctx.Theme.Sounds = ui.ThemeSounds{Hover: "ui_hover", Press: "ui_press", Activate: "ui_activate"}
+		docks = ui.NewDock("imgui", default_space(), windows...)
+	}
 
-		if on_exit := prev.on_exit; on_exit != nil {
-			on_exit(cx, cy)
+	ctx.StartFrame(screen)
+
+	const header_height = 40
+
+	ctx.Push(0, 0, 800, header_height, nil)
+	ctx.Tree(&header, header_tree())
+	ctx.Pop()
+
+	// wobble the text of the buttons around to prove the alignment works
+	phase := float64(elapsed()) / 1e9
+	align_x := float32(0.5 + math.Cos(phase)/2)
+	align_y := float32(0.5 + math.Sin(phase)/2)
+
+	ctx.Push(0, header_height, 800, 600-header_height, nil)
+	ctx.DockSpace(docks, func(window string) string { return locale.T(window) }, func(window string) {
+		switch window {
+		case "viewport":
+			self.draw_viewport(ctx.Next())
+		case "inspector":
+			// the values only change through the inspector's own widgets, which damage the layer as they're used, or
+			// with the scene
+			ctx.Layer(&layers.inspector, [2]any{locale.Language(), world}, draw_inspector)
+		case "lights":
+			// the gizmo moves the lights from outside of the window, it's drawn every frame
+			draw_lights()
+		case "easing":
+			// picking an ease replaces the curve, editing it damages the layer
+			ctx.Layer(&layers.easing, [2]any{locale.Language(), easing.Curve}, draw_easing)
+		case "timeline":
+			// the playhead moves every frame, there's no keeping this one either
+			draw_timeline()
+		case "buttons":
+			// the wobble changes every frame, there's no keeping this one
+			ctx.Layer(&layers.buttons, [3]any{locale.Language(), align_x, align_y}, func() { draw_buttons(align_x, align_y) })
+		case "list":
+			ctx.Layer(&layers.list, [2]any{locale.Language(), picked}, draw_list)
+		case "scene":
+			ctx.Layer(&layers.scene, locale.Language(), draw_scene)
+		case "about":
+			ctx.Layer(&layers.about, locale.Language(), draw_about)
 		}
+	})
+	ctx.Pop()
 
-		if on_enter := next.on_enter; on_enter != nil {
-			on_enter(cx, cy)
-		}
+	ctx.EndFrame()
+}
 
-		ctx.hover_uid = next_uid
-	}
+// header_tree describes the title and a hint, the list and about buttons and a button per language, their names
+// written in themselves. The buttons are as wide as their text needs, the title takes the rest and its hint is cut
+// off first when there's too little.
+func header_tree() *ui.Node {
+	const language_width = 80
 
-	if cursor_over_trigger {
-		trigger := ctx.triggers[hovered_trigger.uid]
+	title_size := (&ui.StackLayout{Vertical: true, Sizes: []ui.Size{ui.TextSize(locale.T("title")), ui.TextSize(locale.T("hint"))}}).Measure()
+	title_size.Min.X, title_size.Stretch = 0, 1
+	row := ui.HStack(0, ui.VStack(0, ui.Label(locale.T("title")), ui.Label(locale.T("hint"))).WithSize(title_size))
 
-		if mouse_just_pressed(ebiten.MouseButtonLeft) {
-			if on_press := trigger.on_press; on_press != nil {
-				on_press(ebiten.MouseButtonLeft)
-			}
+	for _, window := range []string{"lights", "list", "about"} {
+		row.Children = append(row.Children, ui.Button(locale.T(window), func() {
+			toggle(window)
+		}).WithKey(window).WithSelected(docks.Space.Find(window) != nil))
+	}
+	for _, lang := range locale.Languages() {
+		size := ui.ButtonSize(locale.In(lang, "language"))
+		size.Preferred.X = max(size.Preferred.X, language_width)
+		row.Children = append(row.Children, ui.Button(locale.In(lang, "language"), func() {
+			locale.SetLanguage(lang)
+		}).WithKey(lang).WithSelected(lang == locale.Language()).WithSize(size))
+	}
+	return row
+}
 
-			if trigger.mode == button_mode_activate_on_click {
-				if on_activate := trigger.on_activate; on_activate != nil {
-					on_activate()
-				}
-			}
+// toggle closes `window`, or docks it back into the tabs of the viewport.
+func toggle(window string) {
+	if docks.Space.Find(window) != nil {
+		docks.Space.Remove(window)
+	} else {
+		docks.Space.Dock(window, docks.Space.Find("viewport"), dock.Center)
+	}
+	docks.Save()
+}
 
-			ctx.press_uid = hovered_trigger.uid
-		}
+// background is behind the scene, in the viewport and the probes
+var background = color.RGBA{24, 26, 32, 255}
+
+// draw_viewport draws the scene, the turntable turning and hopping, into whatever size the viewport window has.
+func (self *game) draw_viewport(dst *ebiten.Image) {
+	area := dst.Bounds()
+	dst.Fill(background)
+	if area.Dx() == 0 || area.Dy() == 0 {
+		return
+	}
+	if self.shown != world {
+		clear(self.meshes)
+		self.clear_probes()
+		clear(debug)
+		self.shown = world
 	}
 
-	if mouse_just_released(ebiten.MouseButtonLeft) {
-		if trigger := ctx.triggers[ctx.press_uid]; trigger.uid != uid_zero {
-			if on_release := trigger.on_release; on_release != nil {
-				on_release(ebiten.MouseButtonLeft)
-			}
+	// up and back down the same way, as far as the curve says
+	phase := float(hop.Time) / hop_ticks
+	if phase > 1 {
+		phase = 2 - phase
+	}
+	if turntable := world.Find("turntable"); turntable != nil {
+		turntable.Transform.Position = vec3{0, tween.Lerp(-0.5, 0.5, easing.Curve.Evaluate(phase)), 0}
+		turntable.Transform.Rotation = vec3{mgl32.RadToDeg(self.spin * 0.7), mgl32.RadToDeg(self.spin), 0}
+	}
 
-			if trigger.mode == button_mode_activate_on_release ||
-				trigger.mode == button_mode_activate_on_click_release && cursor_within(trigger.bounds) {
-				if on_activate := trigger.on_activate; on_activate != nil {
-					on_activate()
-				}
+	c := self.context
+	camera := world.Camera
+	c.SetViewport(area.Min.X, area.Min.Y, area.Dx(), area.Dy())
+	c.SetPerspective(mgl32.DegToRad(camera.Fov), float(area.Dx())/float(area.Dy()), 0.1, 100)
+	c.SetView(mgl32.LookAtV(camera.Position, camera.Target, vec3{0, 1, 0}))
+	clear(self.drawn)
+	clear(self.by_id)
+	self.lights = self.lights[:0]
+	self.nodes = self.nodes[:0]
+	self.shown_probes = self.shown_probes[:0]
+	// drawn between the last two ticks, the cube turns smoothly however fast the display is
+	world.WalkBetween(app.Interpolation(), func(n *scene.Node, transform mgl32.Mat4) {
+		if n.Light != nil {
+			if l, ok := scene_light(n.Light, transform); ok {
+				self.lights = append(self.lights, l)
 			}
-			ctx.press_uid = uid_zero
 		}
+		if n.Probe != nil {
+			self.place_probe(n, transform)
+		}
+		m := self.place(n, transform)
+		if m == nil {
+			return
+		}
+		self.drawn[m.ID()] = drawing{material: self.material(n), debug: debug[n]}
+		self.by_id[m.ID()] = m
+		self.nodes = append(self.nodes, node_mesh{n, m})
+	})
+	if bake_next {
+		bake_next = false
+		self.bake()
 	}
-
-	ctx.gc()
+	// a scene without lights is drawn unlit, the way scenes were before they had any
+	self.renderer.Lighting = nil
+	if len(self.lights) > 0 {
+		self.renderer.Lighting = &render.Lighting{Lights: self.lights, Meshes: func(id uint32) *mesh.Mesh { return self.by_id[id] }, Baked: baked}
+	}
+	paint_texture(self.texture)
+	self.capture_probes()
+	self.renderer.Reflections = self.reflections(c)
+	self.draw_meshes(dst, c, nil)
+	draw_light_gizmos(dst, c)
+	c.Reset()
 }
 
-// stale_uid_frames is how many frames need to elapse before a uid is considered 'stale'
-const stale_uid_frames = 5
-
-// gc performs garbage collection on this ui context. This may not be entirely necessary
-// since the size of a trigger in memory is barely anything at all. However, if there were
-// hundreds of thousands then this might make a difference in memory usage over time.
-//
-// This will become more important when larger state is retained.
-func (ctx *ui_context_t) gc() {
-	var stale_uids []uid_t
-	for uid, frame := range ctx.uid_frame {
-		if current_frame-frame >= stale_uid_frames {
-			stale_uids = append(stale_uids, uid)
+// draw_meshes pushes the meshes of the scene through `c`, but the mesh of `skip`, and draws them onto `dst`.
+func (self *game) draw_meshes(dst *ebiten.Image, c *pipeline.Context, skip *scene.Node) {
+	for _, nm := range self.nodes {
+		if nm.node != skip {
+			c.Cull = self.drawn[nm.mesh.ID()].material.Cull
+			c.PushMesh(nm.mesh)
 		}
 	}
-	// delete references to the uid
-	for _, uid := range stale_uids {
-		delete(ctx.uid_frame, uid)
-		delete(ctx.triggers, uid)
+	c.Cull = pipeline.CullBack
+	c.Sort()
+	// the triangles are drawn in depth order, a draw for each run of them which is drawn the same way, which the
+	// batch joins into fewer draw calls
+	triangles := c.Triangles()
+	for len(triangles) > 0 {
+		drawn := self.drawn[triangles[0].Mesh]
+		n := 1
+		for n < len(triangles) && self.drawn[triangles[n].Mesh] == drawn {
+			n++
+		}
+		if drawn.debug != render.DebugOff {
+			self.batch.Flush(self.renderer)
+			self.renderer.DrawDebug(dst, triangles[:n], drawn.debug, func(id uint32) *mesh.Mesh { return self.by_id[id] })
+		} else {
+			self.batch.Add(dst, drawn.material, triangles[:n])
+		}
+		triangles = triangles[n:]
 	}
+	self.batch.Flush(self.renderer)
 }
 
-func (ctx *ui_context_t) set_layout(layout layout_t) {
-	ctx.layout = layout
+// material is what the node is drawn with: the material file its material names, or the checkerboard when it
+// names none or the file never loaded.
+func (self *game) material(n *scene.Node) *render.Material {
+	if m := world.Materials[n.Material]; m != nil && m.File != "" {
+		if drawn := materials.Get(m.File); drawn != nil {
+			return drawn
+		}
+	}
+	return self.checker
 }
 
-// push pushes a subimage of the current image onto the layer stack, effectively making it our new working area.
-func (ctx *ui_context_t) push(x, y, w, h int, layout layout_t) {
-	if len(ctx.layers) == 0 {
-		panic("ui context not initialized")
+// place moves the mesh of `n` to where `transform` puts it and returns it, building it first when its recipe is
+// new. Nodes without a recipe, or one which doesn't build, have none.
+func (self *game) place(n *scene.Node, transform mgl32.Mat4) *mesh.Mesh {
+	if n.Mesh == "" {
+		return nil
+	}
+	p := self.meshes[n]
+	if p == nil || p.recipe != n.Mesh {
+		p = &placed{recipe: n.Mesh}
+		if m, err := scene.BuildMesh(n.Mesh); err != nil {
+			logger.Warnf("node %s: %v", n.Name, err)
+		} else {
+			p.mesh, p.local = m, slices.Clone(m.Points)
+		}
+		self.meshes[n] = p
 	}
-	top := ctx.layers[len(ctx.layers)-1]
-	ctx.layers = append(ctx.layers, top.SubImage(image.Rect(x, y, x+w, y+h)).(*ebiten.Image))
-	ctx.layout = layout
+	if p.mesh == nil {
+		return nil
+	}
+	for i, q := range p.local {
+		p.mesh.Points[i] = mgl32.TransformCoordinate(q, transform)
+	}
+	p.mesh.ComputeBounds()
+	return p.mesh
 }
 
-// push_trigger pushes a per-frame trigger for input for testing at the end of the current frame.
-func (ctx *ui_context_t) push_trigger(uid uid_t, bounds image.Rectangle, behavior button_behavior_t) {
-	ctx.frame_triggers = append(ctx.frame_triggers, trigger_t{
-		button_behavior_t: behavior,
-		uid:               uid,
-		bounds:            bounds,
+// checker_colors are the colors of the checkerboard when the scene has no checker material
+var checker_colors = map[string]vec4{"light": {1, 1, 1, 1}, "dark": {0.5, 0.5, 0.5, 1}}
+
+// paint_texture paints a checkerboard in the colors of the checker material, enough to see the cube turn.
+func paint_texture(texture *ebiten.Image) {
+	params := checker_colors
+	if m := world.Materials["checker"]; m != nil {
+		params = m.Params
+	}
+	light, dark := rgba(params["light"]), rgba(params["dark"])
+	texture.WritePixels([]byte{
+		light.R, light.G, light.B, light.A, dark.R, dark.G, dark.B, dark.A,
+		dark.R, dark.G, dark.B, dark.A, light.R, light.G, light.B, light.A,
 	})
 }
 
-// pop pops the top subimage off the layer stack.
-func (ctx *ui_context_t) pop() {
-	if len(ctx.layers) > 0 {
-		ctx.layers[len(ctx.layers)-1] = nil
-		ctx.layers = ctx.layers[:len(ctx.layers)-1]
-	}
-	ctx.layout = nil
+// rgba is a color edited from 0 to 1, premultiplied the way WritePixels wants it.
+func rgba(c vec4) color.RGBA {
+	return color.RGBAModel.Convert(color.NRGBA{
+		uint8(c[0]*255 + 0.5), uint8(c[1]*255 + 0.5), uint8(c[2]*255 + 0.5), uint8(c[3]*255 + 0.5),
+	}).(color.RGBA)
 }
 
-// next returns the working area of our context. If `ctx.layout` is not `nil`, then the next image will be
-// determined by that layout. Because this always works in the context of a subimage, a layout can never
-// escape the bounds it begins in.
-func (ctx *ui_context_t) next() *ebiten.Image {
-	if len(ctx.layers) == 0 {
-		panic("ui context not initialized")
+// draw_inspector draws a row per property of the cube and its material, its name and its editor.
+func draw_inspector() {
+	const name_width = 80
+	const row_height = 20
+
+	ctx.Panel()
+	area := ctx.Next().Bounds().Inset(6)
+	y := area.Min.Y
+	property := func(name string, edit func()) {
+		name_size := ui.Size{Min: image.Pt(name_width, 0), Preferred: image.Pt(name_width, 0)}
+		ctx.Push(area.Min.X, y, area.Dx(), min(row_height, area.Max.Y-y), &ui.StackLayout{Gap: 4, Sizes: []ui.Size{name_size, {Stretch: 1}}})
+		ctx.Label(locale.T(name), 0, 0.5)
+		edit()
+		ctx.Pop()
+		y += row_height + 4
 	}
-
-	top := ctx.layers[len(ctx.layers)-1]
-
-	if l := ctx.layout; l != nil {
-		if bounds := l.layout(top.Bounds()); !bounds.Empty() {
-			return top.SubImage(bounds).(*ebiten.Image)
-		}
+	// the widgets change the values as they're declared, what they were is taken first
+	if cube := world.Find("cube"); cube != nil {
+		t := &cube.Transform
+		before := *t
+		property("position", func() { record(&t.Position, ctx.Vector(&inspector.position, t.Position[:]), before.Position) })
+		property("rotation", func() { record(&t.Rotation, ctx.Vector(&inspector.rotation, t.Rotation[:]), before.Rotation) })
+		property("scale", func() { record(&t.Scale, ctx.Vector(&inspector.scale, t.Scale[:]), before.Scale) })
+		// how the cube is drawn isn't an edit of the scene, it's left out of the history
+		property("debug", func() {
+			ctx.Button(ui.ButtonArgs{
+				Text:   locale.T("debug_" + debug[cube].String()),
+				AlignX: 0.5,
+				AlignY: 0.5,
+				Behavior: ui.ButtonBehavior{Mode: ui.ActivateOnClick, OnActivate: func() {
+					debug[cube] = (debug[cube] + 1) % render.DebugCount
+					layers.inspector.Damage()
+				}},
+			})
+		})
+	}
+	// the moons are instances, scaling the prefab scales them all
+	if moon := world.Prefabs["moon"]; moon != nil {
+		t := &moon.Transform
+		before := *t
+		property("moons", func() { record(&t.Scale, ctx.Vector(&inspector.moons, t.Scale[:]), before.Scale) })
+	}
+	before := speed
+	property("spin", func() { record(&speed, ctx.Spinbox(&inspector.speed, &speed), before) })
+	if checker := world.Materials["checker"]; checker != nil {
+		property("light", func() { param(checker.Params, "light", &inspector.light) })
+		property("dark", func() { param(checker.Params, "dark", &inspector.dark) })
 	}
-
-	return top
 }
 
-type trigger_t struct {
-	uid    uid_t
-	bounds image.Rectangle
-	button_behavior_t
+// param declares the editor `e` of the parameter `name` in `params` and records its edits.
+func param(params map[string]vec4, name string, e *ui.VectorEdit) {
+	v := params[name]
+	if ctx.Vector(e, v[:]) {
+		history.Push(&undo.SetKey[string, vec4]{Map: params, Key: name, From: params[name], To: v})
+		params[name] = v
+	}
 }
 
-type button_mode int
-
-const (
-	button_mode_activate_on_click_release button_mode = iota
-	button_mode_activate_on_click
-	button_mode_activate_on_release
-)
-
-type button_behavior_t struct {
-	mode        button_mode
-	on_enter    func(x, y int)
-	on_press    func(btn ebiten.MouseButton)
-	on_exit     func(x, y int)
-	on_activate func()
-	on_release  func(btn ebiten.MouseButton)
+// record records that `target` was edited from `from`, when it `changed`.
+func record[T any](target *T, changed bool, from T) {
+	if changed {
+		history.Push(&undo.Set[T]{Target: target, From: from, To: *target})
+	}
 }
 
-type button_args struct {
-	text     string
-	behavior button_behavior_t
+// draw_easing draws a button per ease to start the curve from and the curve editor below them.
+func draw_easing() {
+	names := tween.Names()
+	sizes := make([]ui.Size, len(names))
+	for i, name := range names {
+		sizes[i] = ui.ButtonSize(name)
+	}
+	column := &ui.StackLayout{Vertical: true, Sizes: []ui.Size{{Min: image.Pt(0, 20), Preferred: image.Pt(0, 20)}, {Stretch: 1}}}
+	ctx.SetLayout(column)
+	row := ctx.Next().Bounds()
+	ctx.Push(row.Min.X, row.Min.Y, row.Dx(), row.Dy(), &ui.StackLayout{Sizes: sizes})
+	for _, name := range names {
+		ctx.Button(ui.ButtonArgs{
+			Text:   name,
+			AlignX: 0.5,
+			AlignY: 0.5,
+			Behavior: ui.ButtonBehavior{
+				OnActivate: func() {
+					ease, _ := tween.ByName(name)
+					history.Do(&undo.Set[*curve.Curve]{Target: &easing.Curve, From: easing.Curve, To: curve.FromEase(ease, 5)})
+				},
+			},
+		})
+	}
+	ctx.Pop()
+	ctx.SetLayout(column)
+	// keys are added as the editor is pressed, at the end of the frame before it reports them, so what they were
+	// is what they were when last drawn
+	if ctx.Curve(&easing) {
+		history.Push(&undo.Set[[]curve.Key]{Target: &easing.Curve.Keys, From: easing_keys, To: slices.Clone(easing.Curve.Keys)})
+	}
+	easing_keys = slices.Clone(easing.Curve.Keys)
 }
 
-func (ctx *ui_context_t) uid(skip int) (uid uid_t) {
-	var pcs [1]uintptr
-	runtime.Callers(2+skip, pcs[:])
-	pc := pcs[0]
-
-	uid = uid_t{
-		base: uint64(pc),
-		id:   ctx.uid_base_occurences[pc],
+// draw_timeline draws the timeline of the hop, its keys those of the easing on the way up and back down.
+func draw_timeline() {
+	ctx.Panel()
+	area := ctx.Next().Bounds().Inset(6)
+	hop.Keys = hop.Keys[:0]
+	for _, k := range easing.Curve.Keys {
+		hop.Keys = append(hop.Keys, float64(k.T)*hop_ticks, (2-float64(k.T))*hop_ticks)
 	}
-
-	ctx.uid_frame[uid] = current_frame
-	ctx.uid_base_occurences[pc]++
-	return
+	ctx.Push(area.Min.X, area.Min.Y, area.Dx(), min(area.Dy(), 24), nil)
+	ctx.Timeline(&hop)
+	ctx.Pop()
 }
 
-func (ctx *ui_context_t) button(args button_args) {
-	uid := ctx.uid(1)
-	dst := ctx.next()
-
-	if ctx.press_uid == uid {
-		dst.Fill(color.RGBA{60, 60, 60, 255})
-	} else if ctx.hover_uid == uid {
-		dst.Fill(color.RGBA{128, 128, 128, 255})
-	} else {
-		dst.Fill(color.RGBA{80, 80, 80, 255})
-	}
+// scene_files picks the file of the scene window, scene_status says what its last save or load did
+var (
+	scene_files  = ui.FileDialog{Dir: "scenes", Ext: ".json"}
+	scene_status string
+)
 
-	draw_border(dst, 1, 1, color.RGBA{127, 127, 127, 255})
-	draw_border(dst, 0, 1, color.RGBA{196, 196, 196, 255})
+// draw_scene draws the scene files, the name to save or load and the buttons to do either.
+func draw_scene() {
+	ctx.Panel()
+	area := ctx.Next().Bounds().Inset(6)
+	line := ui.Size{Min: image.Pt(0, 20), Preferred: image.Pt(0, 20)}
+	column := &ui.StackLayout{Vertical: true, Gap: 4, Sizes: []ui.Size{{Stretch: 1}, line, line}}
+	ctx.Push(area.Min.X, area.Min.Y, area.Dx(), area.Dy(), column)
+	ctx.FileDialog(&scene_files)
+	row := ctx.Next().Bounds()
+	ctx.Push(row.Min.X, row.Min.Y, row.Dx(), row.Dy(), &ui.GridLayout{Columns: 2, Rows: 1})
+	ctx.Button(ui.ButtonArgs{Text: locale.T("save"), AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: save_scene}})
+	ctx.Button(ui.ButtonArgs{Text: locale.T("load"), AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: load_scene}})
+	ctx.Pop()
+	ctx.SetLayout(column)
+	ctx.Label(scene_status, 0, 0.5)
+	ctx.Pop()
+}
 
-	if args.text != "" {
-		phase := float64(elapsed()) / 1e9
-		x := float32(0.5 + math.Cos(phase)/2)
-		y := float32(0.5 + math.Sin(phase)/2)
-		draw_string(dst, args.text, x, y)
+func save_scene() {
+	// the buttons go off after the window was drawn, it shows what they did next frame
+	defer layers.scene.Damage()
+	path := scene_files.Path()
+	if path == "" {
+		return
 	}
-
-	ctx.push_trigger(uid, dst.Bounds(), args.behavior)
-
-	return
+	if err := world.Save(path); err != nil {
+		logger.Warnf("could not save %s: %v", path, err)
+		scene_status = locale.T("scene_error", path)
+		return
+	}
+	scene_files.Refresh()
+	scene_status = locale.T("saved", path)
 }
 
-func (g *game) Update() error {
-	input_mu.Lock()
-	defer input_mu.Unlock()
-
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-		mouse_pressed[ebiten.MouseButtonLeft] = current_frame
+// load_scene replaces the scene with the one in the file named, which can be undone like any edit.
+func load_scene() {
+	defer layers.scene.Damage()
+	path := scene_files.Path()
+	if path == "" {
+		return
 	}
-	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
-		mouse_released[ebiten.MouseButtonLeft] = current_frame
+	loaded, err := scene.Load(path)
+	if err != nil {
+		logger.Warnf("could not load %s: %v", path, err)
+		scene_status = locale.T("scene_error", path)
+		return
 	}
-	return nil
+	history.Do(&undo.Set[*scene.Scene]{Target: &world, From: world, To: loaded})
+	scene_status = locale.T("loaded", path)
 }
 
-var ctx *ui_context_t
+func draw_buttons(align_x, align_y float32) {
+	const columns = 8
+	const rows = 8
 
-func (g *game) Draw(screen *ebiten.Image) {
-	if ctx == nil {
-		ctx = new_ui_context()
+	// every cell as large as the widest label needs, so the text isn't cut off until the window is too small
+	cells := make([]ui.Size, columns*rows)
+	for i := range cells {
+		cells[i] = ui.ButtonSize(fmt.Sprintf("%d,%d", i/rows, i%rows))
+		cells[i].Stretch = 1
 	}
-
-	ctx.start_frame(screen)
-
-	const columns = 16
-	const rows = 16
-
-	ctx.set_layout(&grid_layout{
-		columns: columns,
-		rows:    rows,
-	})
+	ctx.SetLayout(&ui.GridLayout{Columns: columns, Rows: rows, Cells: cells})
 
 	for i := 0; i < columns; i++ {
 		for j := 0; j < rows; j++ {
-			ctx.button(button_args{
-				text: fmt.Sprintf("%d,%d", i, j),
-				behavior: button_behavior_t{
-					on_enter: func(x, y int) {
-					},
-					on_exit: func(x, y int) {
-					},
-					on_activate: func() {
-						fmt.Println(">>> activate", i, j)
-					},
-					on_press: func(button ebiten.MouseButton) {
-					},
-					on_release: func(button ebiten.MouseButton) {
+			ctx.Button(ui.ButtonArgs{
+				Text:   fmt.Sprintf("%d,%d", i, j),
+				AlignX: align_x,
+				AlignY: align_y,
+				Behavior: ui.ButtonBehavior{
+					OnActivate: func() {
+						logger.Infof("%s", locale.T("activated", i, j))
 					},
 				},
 			})
-
 		}
 	}
+}
 
-	ctx.end_frame()
+func draw_list() {
+	// a field to jump to a row, which can be pasted into and copied from
+	ctx.SetLayout(&ui.StackLayout{Vertical: true, Sizes: []ui.Size{{Min: image.Pt(0, 20), Preferred: image.Pt(0, 20)}, {Stretch: 1}}})
+	if ctx.TextInput(&goto_row) {
+		if n, err := strconv.Atoi(strings.TrimSpace(goto_row.Text)); err == nil && n >= 1 && n <= list_rows {
+			picked = n - 1
+			list.ScrollTo(picked)
+		}
+		goto_row.SelectAll()
+	}
+	ctx.List(&list, list_rows, 20, func(i int) {
+		ctx.Button(ui.ButtonArgs{
+			Text:     locale.T("row", i+1, list_rows),
+			AlignX:   0.02,
+			AlignY:   0.5,
+			Selected: i == picked,
+			Behavior: ui.ButtonBehavior{
+				OnActivate: func() {
+					picked = i
+				},
+			},
+		})
+	})
+}
 
-	current_frame++
+func draw_about() {
+	ctx.Panel()
+	area := ctx.Next().Bounds().Inset(12)
+	ctx.Push(area.Min.X, area.Min.Y, area.Dx(), area.Dy(), nil)
+	ctx.RichText(locale.T("about_text"), func(target string) {
+		if lang, ok := strings.CutPrefix(target, "lang:"); ok {
+			locale.SetLanguage(lang)
+		} else if target == "close" {
+			toggle("about")
+		}
+	})
+	ctx.Pop()
 }
 
-func (g *game) Layout(outer_width, outer_height int) (width, height int) {
+func (self *game) Layout(outer_width, outer_height int) (width, height int) {
 	return 800, 600
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/scene"
+)
+
+// capture_next captures the probes when the scene is next drawn. Probes are captured when they're made and when
+// the capture button of the lights window is pressed, they don't follow the scene as it moves or is edited.
+var capture_next = true
+
+type node_probe struct {
+	node  *scene.Node
+	probe *render.Probe
+}
+
+// place_probe moves the probe of `n` to where `transform` puts it, making it first when it's new or its size
+// changed.
+func (self *game) place_probe(n *scene.Node, transform mgl32.Mat4) {
+	size := max(n.Probe.Size, 1)
+	p := self.probes[n]
+	if p == nil || p.Size() != size {
+		if p != nil {
+			p.Deallocate()
+		}
+		p = render.NewProbe(size)
+		self.probes[n] = p
+		capture_next = true
+	}
+	p.Position = transform.Col(3).Vec3()
+	p.Radius = n.Probe.Radius
+	self.shown_probes = append(self.shown_probes, node_probe{n, p})
+}
+
+// clear_probes deallocates the probes, for a scene which isn't shown anymore.
+func (self *game) clear_probes() {
+	for n, p := range self.probes {
+		p.Deallocate()
+		delete(self.probes, n)
+	}
+}
+
+// capture_probes draws the scene into the probes shown, when they're to be captured. A probe leaves out the mesh of
+// its own node, which would be all it saw, and reflective meshes reflect nothing in them.
+func (self *game) capture_probes() {
+	if !capture_next {
+		return
+	}
+	capture_next = false
+	self.renderer.Reflections = nil
+	for _, np := range self.shown_probes {
+		np.probe.Capture(func(target *ebiten.Image, c *pipeline.Context) {
+			target.Fill(background)
+			self.draw_meshes(target, c, np.node)
+		})
+	}
+}
+
+// reflections are the probes shown as the renderer takes them, for meshes pushed through `c`.
+func (self *game) reflections(c *pipeline.Context) *render.Reflections {
+	r := &render.Reflections{Meshes: func(id uint32) *mesh.Mesh { return self.by_id[id] }, Context: c}
+	for _, np := range self.shown_probes {
+		r.Probes = append(r.Probes, np.probe)
+	}
+	return r
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/bench"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+)
+
+var (
+	bench_path   = flag.String("bench", "", "benchmark the pipeline on generated scenes instead and write the report to `file`, csv when it ends in .csv and markdown otherwise")
+	bench_frames = flag.Int("bench-frames", 120, "frames each scene is benchmarked for under each configuration")
+)
+
+// bench_warmup is how many frames of each run aren't counted, the pipeline's buffers grow during the first ones.
+const bench_warmup = 10
+
+// bench_game flies the camera along its path through every scene under every configuration and records how long
+// each frame took, then writes the report and quits.
+type bench_game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	texture  *ebiten.Image
+
+	configs []bench.Config
+	results []*bench.Result
+	// run is the index of the scene and configuration being benchmarked, size * configs + config
+	run   int
+	frame int
+	scene []*mesh.Mesh
+	size  int
+}
+
+// load_bench makes the benchmark the way load makes the game.
+func load_bench() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	return new_bench_game(renderer, new_checkerboard()), nil
+}
+
+func new_bench_game(renderer *render.Renderer, texture *ebiten.Image) *bench_game {
+	return &bench_game{
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		texture:  texture,
+		configs:  bench.Configs(),
+	}
+}
+
+func (self *bench_game) runs() int {
+	return len(bench.Sizes) * len(self.configs)
+}
+
+func (self *bench_game) Update() error {
+	if self.run < self.runs() {
+		return nil
+	}
+	if err := bench.Write(*bench_path, self.results); err != nil {
+		return err
+	}
+	logger.Infof("wrote the benchmark to %s", *bench_path)
+	return ebiten.Termination
+}
+
+func (self *bench_game) Draw(screen *ebiten.Image) {
+	if self.run >= self.runs() {
+		return
+	}
+	size := bench.Sizes[self.run/len(self.configs)]
+	config := self.configs[self.run%len(self.configs)]
+	if self.scene == nil || self.size != size {
+		self.scene, self.size = bench.Scene(size), size
+	}
+	if self.frame == 0 {
+		self.results = append(self.results, &bench.Result{Config: config, Size: bench.Triangles(self.scene)})
+	}
+	result := self.results[len(self.results)-1]
+
+	ctx := self.context
+	config.Apply(ctx)
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(mgl32.DegToRad(60), float(game_width)/float(game_height), near, 200)
+
+	frames := *bench_frames + bench_warmup
+	start := time.Now()
+	bench.Frame(ctx, self.scene, float(self.frame)/float(frames))
+	pipelined := time.Now()
+	self.renderer.DrawTriangles(screen, self.texture, ctx.Triangles())
+	drawn := time.Now()
+	if self.frame >= bench_warmup {
+		result.Add(pipelined.Sub(start), drawn.Sub(pipelined), len(ctx.Triangles()))
+	}
+	ctx.Reset()
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("benchmark %d/%d: %d triangles, %v\nframe %d/%d",
+		self.run+1, self.runs(), result.Size, config, self.frame+1, frames), 0, 0)
+
+	self.frame++
+	if self.frame == frames {
+		self.frame = 0
+		self.run++
+	}
+}
+
+func (self *bench_game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *bench_game) Pipeline() *pipeline.Context {
+	return self.context
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	// each half of the screen shows the same view
+	half_width  = game_width / 2
+	half_aspect = float(half_width) / float(game_height)
+
+	fov  = 1.2 // radians
+	near = 0.1
+	far  = 100
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	mat4  = mgl32.Mat4
+)
+
+var logger = logging.Tag("main")
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{130, 130, 130, 255}, cvar.Persist, "background color")
+	spin        = cvar.Bool("r_spin", true, 0, "rotates the cube")
+)
+
+var cpu_profile = flag.String("cpuprofile", "", "write cpu profile to `file`")
+var mem_profile = flag.String("memprofile", "", "write memory profile to `file`")
+
+// flip_y turns the picture upright. The pipeline maps NDC Y straight to screen Y, which points down, and the other
+// demos only look upright because they pass mgl32.Perspective a field of view in degrees where it expects
+// radians. The flip mirrors the winding too, see new_cube.
+var flip_y = mgl32.Scale3D(1, -1, 1)
+
+// max_vertices must match MaxVertices in the shader, it's the size of the uniform arrays
+const max_vertices = 36
+
+// kage_shader rasterizes every triangle of the mesh for every pixel of the rectangle it's drawn over. The mesh is
+// passed in uniforms, there are no vertex shaders in Kage. The screen space math mirrors the pipeline: the same
+// viewport mapping, the same back-face culling and perspective-correct texture coordinates. Instead of sorting,
+// each pixel keeps the nearest triangle, so it's exact where the painter's algorithm isn't. Triangles crossing the
+// near plane are dropped rather than clipped.
+var kage_shader = `
+//kage:unit pixels
+package main
+
+const MaxVertices = 36
+
+var MVP mat4
+var Positions [MaxVertices]vec3
+var Texcoords [MaxVertices]vec2
+var Count int
+
+// Viewport is x, y, width and height in pixels of the target.
+var Viewport vec4
+
+func edge(a, b, p vec2) float {
+	return (b.x-a.x)*(p.y-a.y) - (b.y-a.y)*(p.x-a.x)
+}
+
+// screen maps clip space to pixels like the pipeline's ndc_to_screen, keeping NDC depth in z.
+func screen(clip vec4) vec3 {
+	ndc := clip.xyz / clip.w
+	half := Viewport.zw / 2
+	return vec3(ndc.xy*half+half+Viewport.xy, ndc.z)
+}
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	p := dst.xy
+	depth := 1.0
+	result := vec4(0)
+
+	for i := 0; i < MaxVertices; i += 3 {
+		if i >= Count {
+			break
+		}
+
+		c1 := MVP * vec4(Positions[i], 1)
+		c2 := MVP * vec4(Positions[i+1], 1)
+		c3 := MVP * vec4(Positions[i+2], 1)
+		if c1.w <= 0 || c2.w <= 0 || c3.w <= 0 {
+			continue
+		}
+
+		s1 := screen(c1)
+		s2 := screen(c2)
+		s3 := screen(c3)
+
+		// back-face culling, the viewport mapping doesn't flip so the sign matches NDC
+		area := edge(s1.xy, s2.xy, s3.xy)
+		if area <= 0 {
+			continue
+		}
+
+		w1 := edge(s2.xy, s3.xy, p) / area
+		w2 := edge(s3.xy, s1.xy, p) / area
+		w3 := edge(s1.xy, s2.xy, p) / area
+		if w1 < 0 || w2 < 0 || w3 < 0 {
+			continue
+		}
+
+		z := w1*s1.z + w2*s2.z + w3*s3.z
+		if z < -1 || z >= depth {
+			continue
+		}
+		depth = z
+
+		// perspective correction, see the texture shader in the render package
+		inv_w := w1/c1.w + w2/c2.w + w3/c3.w
+		uv := (w1*Texcoords[i]/c1.w + w2*Texcoords[i+1]/c2.w + w3*Texcoords[i+2]/c3.w) / inv_w
+		result = imageSrc0At(uv*imageSrc0Size() + imageSrc0Origin())
+	}
+
+	return result
+}
+`
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+
+	if *cpu_profile != "" {
+		f, err := os.Create(*cpu_profile)
+		if err != nil {
+			logger.Fatalf("could not create CPU profile: %v", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			logger.Fatalf("could not start CPU profile: %v", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	if *mem_profile != "" {
+		_ = pprof.Lookup("heap")
+
+		defer func() {
+			f, err := os.Create(*mem_profile)
+			if err != nil {
+				logger.Fatalf("could not create memory profile: %v", err)
+			}
+			defer f.Close()
+			runtime.GC() // get up-to-date statistics
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				logger.Fatalf("could not write memory profile: %v", err)
+			}
+		}()
+	}
+
+	if *bench_path != "" {
+		ebiten.SetWindowTitle("004-gpu-vs-cpu benchmark")
+		ebiten.SetWindowSize(game_width, game_height)
+		ebiten.SetVsyncEnabled(false)
+		err := app.RunLoader(load_bench, &ebiten.RunGameOptions{
+			GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+		})
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	ebiten.SetWindowTitle("004-gpu-vs-cpu")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a renderer or shader which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	shader, err := ebiten.NewShader([]byte(kage_shader))
+	if err != nil {
+		return nil, err
+	}
+
+	cube := new_cube()
+	positions, texcoords := pack_uniforms(cube)
+
+	return &game{
+		context:   &pipeline.Context{},
+		renderer:  renderer,
+		shader:    shader,
+		texture:   new_checkerboard(),
+		mesh:      cube,
+		positions: positions,
+		texcoords: texcoords,
+		camera:    camera.New(vec3{3, 2.5, 4}, vec3{}),
+	}, nil
+}
+
+func new_checkerboard() *ebiten.Image {
+	const texture_size = 128
+	const texture_subdivisions = 8
+	const tile_size = texture_size / texture_subdivisions
+
+	texture := ebiten.NewImage(texture_size, texture_size)
+	texture.Fill(color.Black)
+
+	for row := 0; row < texture_subdivisions; row++ {
+		for col := 0; col < texture_subdivisions; col++ {
+			if (row+col)%2 == 0 {
+				continue
+			}
+			x := float(col * tile_size)
+			y := float(row * tile_size)
+			vector.DrawFilledRect(texture, x, y, tile_size, tile_size, color.White, false)
+		}
+	}
+
+	vector.StrokeRect(texture, 1, 1, texture_size-1, texture_size-1, 1, color.RGBA{255, 0, 0, 255}, false)
+	return texture
+}
+
+// new_cube returns a unit cube, each face wound clockwise when seen from outside to suit flip_y.
+func new_cube() *mesh.Mesh {
+	// the corners of each face counter-clockwise from outside, point i has x, y and z from its bits 0, 1 and 2
+	faces := [6][4]uint16{
+		{0, 4, 6, 2}, // -x
+		{1, 3, 7, 5}, // +x
+		{0, 1, 5, 4}, // -y
+		{2, 6, 7, 3}, // +y
+		{0, 2, 3, 1}, // -z
+		{4, 5, 7, 6}, // +z
+	}
+
+	m := &mesh.Mesh{
+		Texcoords: []vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+	}
+	for i := 0; i < 8; i++ {
+		m.Points = append(m.Points, vec3{
+			float(i&1*2 - 1),
+			float(i>>1&1*2 - 1),
+			float(i>>2&1*2 - 1),
+		})
+	}
+	for _, f := range faces {
+		m.Triangles = append(m.Triangles,
+			mesh.Triangle{P1: f[0], P2: f[2], P3: f[1], T1: 0, T2: 2, T3: 1},
+			mesh.Triangle{P1: f[0], P2: f[3], P3: f[2], T1: 0, T2: 3, T3: 2},
+		)
+	}
+	m.ComputeBounds()
+	return m
+}
+
+// pack_uniforms unrolls the indexed mesh into the flat arrays the shader expects.
+func pack_uniforms(m *mesh.Mesh) (positions, texcoords []float32) {
+	if len(m.Triangles)*3 > max_vertices {
+		logger.Fatalf("mesh has %d triangles, the shader fits %d", len(m.Triangles), max_vertices/3)
+	}
+	for _, t := range m.Triangles {
+		for _, i := range [3]uint16{t.P1, t.P2, t.P3} {
+			p := m.Points[i]
+			positions = append(positions, p[0], p[1], p[2])
+		}
+		for _, i := range [3]uint16{t.T1, t.T2, t.T3} {
+			uv := m.Texcoords[i]
+			texcoords = append(texcoords, uv[0], uv[1])
+		}
+	}
+	// ebiten wants the whole array, the shader stops at Count
+	positions = append(positions, make([]float32, max_vertices*3-len(positions))...)
+	texcoords = append(texcoords, make([]float32, max_vertices*2-len(texcoords))...)
+	return positions, texcoords
+}
+
+type game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	shader   *ebiten.Shader
+	texture  *ebiten.Image
+	mesh     *mesh.Mesh
+	camera   *camera.Camera
+
+	positions []float32
+	texcoords []float32
+	vertices  []ebiten.Vertex
+
+	angle float
+
+	pipeline_time timing
+	kage_time     timing
+}
+
+// timing is a smoothed duration, like the frame time of the other demos.
+type timing time.Duration
+
+func (t *timing) add(d time.Duration) {
+	if *t == 0 {
+		*t = timing(d)
+	} else {
+		*t += (timing(d) - *t) / 2
+	}
+}
+
+func (t timing) String() string {
+	return time.Duration(t).String()
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.camera.Update()
+	if spin.Bool() {
+		self.angle += 0.01
+	}
+	return nil
+}
+
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	screen.Fill(clear_color.Color())
+
+	// the pipeline has no model matrix, both halves fold it into the view so they see exactly the same thing
+	view := self.camera.View().Mul4(mgl32.HomogRotate3DY(self.angle))
+	projection := flip_y.Mul4(mgl32.Perspective(fov, half_aspect, near, far))
+
+	start := time.Now()
+	self.draw_cpu(screen, projection, view)
+	self.pipeline_time.add(time.Since(start))
+
+	start = time.Now()
+	self.draw_gpu(screen, projection.Mul4(view))
+	self.kage_time.add(time.Since(start))
+
+	vector.StrokeLine(screen, half_width, 0, half_width, game_height, 1, color.Black, false)
+
+	ctx := self.context
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, "CPU pipeline + DrawTriangles", 0, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("CPU: %v", self.pipeline_time), 0, 28)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Triangles: %d of %d", ctx.Stats.Triangles, len(self.mesh.Triangles)), 0, 42)
+
+	ebitenutil.DebugPrintAt(screen, "Kage only, mesh in uniforms", half_width+4, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("CPU: %v", self.kage_time), half_width+4, 28)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Triangle tests: %d per pixel", len(self.mesh.Triangles)), half_width+4, 42)
+
+	ebitenutil.DebugPrintAt(screen, "CPU is the time spent issuing the draw, the GPU work happens later and isn't measured", 0, game_height-16)
+}
+
+// draw_cpu transforms, clips, culls and sorts on the CPU and rasterizes with DrawTriangles.
+func (self *game) draw_cpu(screen *ebiten.Image, projection, view mat4) {
+	ctx := self.context
+	ctx.SetViewport(0, 0, half_width, game_height)
+	ctx.SetProjection(projection)
+	ctx.SetView(view)
+
+	ctx.PushMesh(self.mesh)
+	ctx.Sort()
+	self.renderer.DrawTriangles(screen, self.texture, ctx.Triangles())
+	ctx.Reset()
+}
+
+// draw_gpu covers the right half with a single quad and leaves everything else to the shader.
+func (self *game) draw_gpu(screen *ebiten.Image, mvp mat4) {
+	x0, x1 := float(half_width), float(game_width)
+	y0, y1 := float(0), float(game_height)
+
+	self.vertices = append(self.vertices[:0],
+		ebiten.Vertex{DstX: x0, DstY: y0, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		ebiten.Vertex{DstX: x1, DstY: y0, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		ebiten.Vertex{DstX: x0, DstY: y1, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		ebiten.Vertex{DstX: x1, DstY: y1, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+	)
+
+	opts := &ebiten.DrawTrianglesShaderOptions{
+		Uniforms: map[string]any{
+			"MVP":       mvp[:],
+			"Positions": self.positions,
+			"Texcoords": self.texcoords,
+			"Count":     len(self.mesh.Triangles) * 3,
+			"Viewport":  []float32{x0, y0, x1 - x0, y1 - y0},
+		},
+		Images: [4]*ebiten.Image{self.texture},
+	}
+	screen.DrawTrianglesShader(self.vertices, []uint16{0, 1, 2, 1, 3, 2}, self.shader, opts)
+}
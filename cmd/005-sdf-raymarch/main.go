@@ -0,0 +1,300 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"runtime/pprof"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+var logger = logging.Tag("main")
+
+var (
+	fov          = cvar.Float("r_fov", 60, 0, "vertical field of view in degrees").Range(10, 150)
+	shadow_k     = cvar.Float("r_shadow_softness", 8, 0, "penumbra sharpness of the soft shadows, higher is harder").Range(1, 64)
+	sky_color    = cvar.Color("r_sky_color", color.RGBA{150, 180, 220, 255}, cvar.Persist, "color of rays that hit nothing")
+	animate      = cvar.Bool("r_animate", true, 0, "moves the sphere carving the box")
+	show_steps   = cvar.Bool("r_show_steps", false, 0, "colors each pixel by how many march steps it took")
+	light_height = cvar.Float("r_light_height", 1, 0, "height of the light direction, lower casts longer shadows").Range(0.05, 4)
+)
+
+var cpu_profile = flag.String("cpuprofile", "", "write cpu profile to `file`")
+
+// sdf_shader marches a ray per pixel through a scene described by signed distance functions. Nothing is
+// rasterized, the CPU only passes the camera in uniforms.
+var sdf_shader = `
+//kage:unit pixels
+package main
+
+const MaxSteps = 96
+const MaxDistance = 60.0
+const Epsilon = 0.001
+
+var Resolution vec2
+var Eye vec3
+var Right vec3
+var Up vec3
+var Forward vec3
+// TanHalfFov is tan(fovy/2).
+var TanHalfFov float
+var Time float
+var Light vec3
+var ShadowK float
+var Sky vec4
+var ShowSteps float
+
+func sd_sphere(p vec3, r float) float {
+	return length(p) - r
+}
+
+func sd_box(p vec3, b vec3) float {
+	q := abs(p) - b
+	return length(max(q, 0)) + min(max(q.x, max(q.y, q.z)), 0)
+}
+
+func sd_torus(p vec3, r1, r2 float) float {
+	q := vec2(length(p.xz)-r1, p.y)
+	return length(q) - r2
+}
+
+func op_union(a, b float) float {
+	return min(a, b)
+}
+
+func op_subtract(a, b float) float {
+	return max(a, -b)
+}
+
+func op_intersect(a, b float) float {
+	return max(a, b)
+}
+
+// op_smooth_union blends two shapes together over a distance of k.
+func op_smooth_union(a, b, k float) float {
+	h := clamp(0.5+0.5*(b-a)/k, 0, 1)
+	return mix(b, a, h) - k*h*(1-h)
+}
+
+// scene returns the distance to the nearest surface in x and the material in y.
+func scene(p vec3) vec2 {
+	ground := vec2(p.y+1, 0)
+
+	// a rounded box with a moving sphere carved out of it
+	box := op_intersect(sd_box(p-vec3(-1.5, 0, 0), vec3(0.8)), sd_sphere(p-vec3(-1.5, 0, 0), 1.1))
+	box = op_subtract(box, sd_sphere(p-vec3(-1.5+sin(Time)*0.8, 0.6, 0.6), 0.6))
+	d := vec2(box, 1)
+
+	torus := sd_torus(p-vec3(1.5, 0, 0), 0.8, 0.25)
+	blob := op_smooth_union(torus, sd_sphere(p-vec3(1.5, 0.3+sin(Time*0.7)*0.4, 0), 0.45), 0.3)
+	if blob < d.x {
+		d = vec2(blob, 2)
+	}
+
+	if ground.x < d.x {
+		d = ground
+	}
+	return d
+}
+
+func normal(p vec3) vec3 {
+	e := vec2(Epsilon, 0)
+	return normalize(vec3(
+		scene(p+e.xyy).x-scene(p-e.xyy).x,
+		scene(p+e.yxy).x-scene(p-e.yxy).x,
+		scene(p+e.yyx).x-scene(p-e.yyx).x,
+	))
+}
+
+// soft_shadow marches towards the light and keeps the closest miss, which approximates the penumbra.
+func soft_shadow(origin, dir vec3) float {
+	result := 1.0
+	t := 0.02
+	for i := 0; i < 48; i++ {
+		h := scene(origin + dir*t).x
+		if h < Epsilon {
+			return 0
+		}
+		result = min(result, ShadowK*h/t)
+		t += clamp(h, 0.02, 0.5)
+		if t > MaxDistance {
+			break
+		}
+	}
+	return clamp(result, 0, 1)
+}
+
+func checker(p vec3) vec3 {
+	c := mod(floor(p.x)+floor(p.z), 2)
+	return mix(vec3(0.35), vec3(0.8), c)
+}
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	// the image y axis points down
+	uv := (src*2 - Resolution) / Resolution.y
+	dir := normalize(Forward + (Right*uv.x-Up*uv.y)*TanHalfFov)
+
+	t := 0.0
+	material := -1.0
+	steps := 0.0
+	for i := 0; i < MaxSteps; i++ {
+		hit := scene(Eye + dir*t)
+		if hit.x < Epsilon*t {
+			material = hit.y
+			break
+		}
+		t += hit.x
+		steps += 1
+		if t > MaxDistance {
+			break
+		}
+	}
+
+	if ShowSteps > 0 {
+		return vec4(vec3(steps/MaxSteps), 1)
+	}
+	if material < 0 {
+		return Sky
+	}
+
+	p := Eye + dir*t
+	n := normal(p)
+	light := normalize(Light)
+
+	albedo := checker(p)
+	if material == 1 {
+		albedo = vec3(0.9, 0.4, 0.3)
+	} else if material == 2 {
+		albedo = vec3(0.3, 0.6, 0.9)
+	}
+
+	diffuse := max(dot(n, light), 0) * soft_shadow(p+n*Epsilon*4, light)
+	ambient := 0.15 + 0.1*n.y
+	rgb := albedo * (diffuse + ambient)
+
+	// fade into the sky with distance
+	rgb = mix(rgb, Sky.rgb, clamp(t/MaxDistance, 0, 1))
+	return vec4(rgb, 1)
+}
+`
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+
+	if *cpu_profile != "" {
+		f, err := os.Create(*cpu_profile)
+		if err != nil {
+			logger.Fatalf("could not create CPU profile: %v", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			logger.Fatalf("could not start CPU profile: %v", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	ebiten.SetWindowTitle("005-sdf-raymarch")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a shader which failed to compile in the window instead.
+func load() (ebiten.Game, error) {
+	shader, err := ebiten.NewShader([]byte(sdf_shader))
+	if err != nil {
+		return nil, err
+	}
+
+	return &game{
+		shader: shader,
+		camera: camera.New(vec3{0, 1.5, 6}, vec3{0, 0, 0}),
+	}, nil
+}
+
+type game struct {
+	shader *ebiten.Shader
+	camera *camera.Camera
+	time   float
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.camera.Update()
+	if animate.Bool() {
+		self.time += app.Delta()
+	}
+	return nil
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	w := screen.Bounds().Dx()
+	h := screen.Bounds().Dy()
+
+	c := self.camera
+	sky := sky_color.Color()
+	show := float(0)
+	if show_steps.Bool() {
+		show = 1
+	}
+
+	screen.DrawRectShader(w, h, self.shader, &ebiten.DrawRectShaderOptions{
+		Uniforms: map[string]any{
+			"Resolution": []float32{float(w), float(h)},
+			"Eye":        c.Position[:],
+			"Right":      vec_slice(c.Right()),
+			"Up":         vec_slice(c.Up()),
+			"Forward":    vec_slice(c.Forward()),
+			"TanHalfFov": float(math.Tan(float64(mgl32.DegToRad(fov.Float32())) / 2)),
+			"Time":       self.time,
+			"Light":      []float32{0.6, light_height.Float32(), 0.4},
+			"ShadowK":    shadow_k.Float32(),
+			"Sky":        []float32{float(sky.R) / 255, float(sky.G) / 255, float(sky.B) / 255, 1},
+			"ShowSteps":  show,
+		},
+	})
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Cam: %v", c.Position), 0, 14)
+}
+
+func vec_slice(v vec3) []float32 {
+	return v[:]
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/console"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pool"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+
+	// state_size is the width and height of the state images, one pixel per particle
+	state_size     = 128
+	particle_count = state_size * state_size
+
+	// max_speed must match MaxSpeed in the shaders, velocities are stored in [-max_speed, max_speed]
+	max_speed = 2.0
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+)
+
+var logger = logging.Tag("main")
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{10, 10, 20, 255}, cvar.Persist, "background color")
+	gravity     = cvar.Float("sim_gravity", 0.3, 0, "downwards acceleration in screens per second squared").Range(-4, 4)
+	swirl       = cvar.Float("sim_swirl", 0.6, 0, "strength of the vortex around the center").Range(-4, 4)
+	attraction  = cvar.Float("sim_attraction", 0.02, 0, "pull towards the cursor while attract is held").Range(0, 1)
+	damping     = cvar.Float("sim_damping", 0.995, 0, "velocity kept every tick").Range(0.9, 1)
+	readback    = cvar.Bool("sim_readback", true, 0, "read the positions back to draw the particles, off simulates without drawing them")
+)
+
+// The state images hold two 16-bit fixed point numbers per pixel, the high byte in R or B and the low byte in G
+// or A. Ebitengine images only have 8 bits per channel and the shaders can only write one pixel per draw, so
+// positions and velocities live in separate images and are updated in separate passes, each reading the last
+// frame's images and writing the spare ones.
+const codec = `
+const MaxSpeed = 2.0
+
+func decode(c vec2) float {
+	c = floor(c*255 + 0.5)
+	return (c.x*256 + c.y) / 65535
+}
+
+func encode(v float) vec2 {
+	x := floor(clamp(v, 0, 1)*65535 + 0.5)
+	hi := floor(x / 256)
+	return vec2(hi, x-hi*256) / 255
+}
+
+func decode_position(c vec4) vec2 {
+	return vec2(decode(c.rg), decode(c.ba))
+}
+
+func decode_velocity(c vec4) vec2 {
+	return (vec2(decode(c.rg), decode(c.ba))*2 - 1) * MaxSpeed
+}
+
+func encode_position(p vec2) vec4 {
+	return vec4(encode(p.x), encode(p.y))
+}
+
+func encode_velocity(v vec2) vec4 {
+	v = v/MaxSpeed*0.5 + 0.5
+	return vec4(encode(v.x), encode(v.y))
+}
+`
+
+// velocity_shader reads positions from image 0 and velocities from image 1 and writes the new velocities.
+var velocity_shader = `
+//kage:unit pixels
+package main
+
+var Dt float
+var Gravity float
+var Swirl float
+var Damping float
+var Cursor vec2
+var Attraction float
+` + codec + `
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	p := decode_position(imageSrc0At(src))
+	v := decode_velocity(imageSrc1At(src - imageSrc0Origin() + imageSrc1Origin()))
+
+	// a vortex around the center, Y points down so positive is clockwise on screen
+	from_center := p - vec2(0.5)
+	v += vec2(-from_center.y, from_center.x) * Swirl * Dt
+	v.y += Gravity * Dt
+
+	// softened inverse square pull towards the cursor
+	to_cursor := Cursor - p
+	d := dot(to_cursor, to_cursor) + 0.01
+	v += to_cursor / d * Attraction * Dt
+
+	v *= Damping
+
+	// bounce off the edges of the screen, losing some energy
+	next := p + v*Dt
+	if next.x < 0 || next.x > 1 {
+		v.x *= -0.7
+	}
+	if next.y < 0 || next.y > 1 {
+		v.y *= -0.7
+	}
+
+	return encode_velocity(clamp(v, -MaxSpeed, MaxSpeed))
+}
+`
+
+// position_shader reads positions from image 0 and the velocities just written from image 1.
+var position_shader = `
+//kage:unit pixels
+package main
+
+var Dt float
+` + codec + `
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	p := decode_position(imageSrc0At(src))
+	v := decode_velocity(imageSrc1At(src - imageSrc0Origin() + imageSrc1Origin()))
+	return encode_position(clamp(p+v*Dt, 0, 1))
+}
+`
+
+func main() {
+	flag.Parse()
+
+	input.Bind("attract", input.Mouse(ebiten.MouseButtonLeft))
+
+	ebiten.SetWindowTitle("006-gpgpu-particles")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a shader which failed to compile in the window instead.
+func load() (ebiten.Game, error) {
+	velocity, err := ebiten.NewShader([]byte(velocity_shader))
+	if err != nil {
+		return nil, err
+	}
+	position, err := ebiten.NewShader([]byte(position_shader))
+	if err != nil {
+		return nil, err
+	}
+
+	white := ebiten.NewImage(3, 3)
+	white.Fill(color.White)
+
+	game := &game{
+		velocity_shader: velocity,
+		position_shader: position,
+		// the inner pixel of a 3x3 image so sampling never bleeds in from the atlas
+		white:  white.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image),
+		pixels: make([]byte, particle_count*4),
+		reset:  true,
+	}
+	for i := range game.positions {
+		game.positions[i] = new_state_image()
+		game.velocities[i] = new_state_image()
+	}
+
+	console.Register("respawn", "", "scatters the particles again", func(args console.Args) error {
+		game.reset = true
+		return nil
+	})
+
+	return game, nil
+}
+
+// new_state_image returns an image which is never moved to an atlas, it's read and written every tick.
+func new_state_image() *ebiten.Image {
+	return ebiten.NewImageWithOptions(image.Rect(0, 0, state_size, state_size), &ebiten.NewImageOptions{
+		Unmanaged: true,
+	})
+}
+
+type game struct {
+	velocity_shader *ebiten.Shader
+	position_shader *ebiten.Shader
+	white           *ebiten.Image
+
+	// positions and velocities are ping-ponged, [current] is the latest state
+	positions  [2]*ebiten.Image
+	velocities [2]*ebiten.Image
+	current    int
+
+	reset bool
+
+	pixels   []byte
+	vertices []ebiten.Vertex
+	indices  []uint16
+
+	simulate_time time.Duration
+	readback_time time.Duration
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+// encode is the CPU side of the codec shared by the shaders.
+func encode(v float, dst []byte) {
+	x := uint16(min(max(v, 0), 1)*65535 + 0.5)
+	dst[0] = byte(x >> 8)
+	dst[1] = byte(x)
+}
+
+func decode(src []byte) float {
+	return float(uint16(src[0])<<8|uint16(src[1])) / 65535
+}
+
+// uploads are the pixels respawn writes the state images from, a pair of them for every reset.
+var uploads = pool.Slices[byte](particle_count * 4)
+
+// respawn scatters the particles over the screen at rest.
+func (self *game) respawn() {
+	p, v := uploads.Get(), uploads.Get()
+	defer uploads.Put(p)
+	defer uploads.Put(v)
+	positions, velocities := (*p)[:particle_count*4], (*v)[:particle_count*4]
+	for i := 0; i < particle_count; i++ {
+		encode(rand.Float32(), positions[i*4:])
+		encode(rand.Float32(), positions[i*4+2:])
+		// zero velocity is the middle of the range
+		encode(0.5, velocities[i*4:])
+		encode(0.5, velocities[i*4+2:])
+	}
+	self.positions[self.current].WritePixels(positions)
+	self.velocities[self.current].WritePixels(velocities)
+}
+
+func (self *game) Update() error {
+	if self.reset {
+		self.respawn()
+		self.reset = false
+	}
+
+	start := time.Now()
+
+	cx, cy := input.CursorPosition()
+	cursor := vec2{float(cx) / game_width, float(cy) / game_height}
+	pull := float(0)
+	if input.Pressed("attract") {
+		pull = attraction.Float32()
+	}
+
+	next := 1 - self.current
+	dt := app.Delta()
+
+	// both passes read the state of the last tick, the position pass also reads the velocities just written
+	self.velocities[next].DrawRectShader(state_size, state_size, self.velocity_shader, &ebiten.DrawRectShaderOptions{
+		Blend: ebiten.BlendCopy,
+		Uniforms: map[string]any{
+			"Dt":         dt,
+			"Gravity":    gravity.Float32(),
+			"Swirl":      swirl.Float32(),
+			"Damping":    damping.Float32(),
+			"Cursor":     cursor[:],
+			"Attraction": pull,
+		},
+		Images: [4]*ebiten.Image{self.positions[self.current], self.velocities[self.current]},
+	})
+	self.positions[next].DrawRectShader(state_size, state_size, self.position_shader, &ebiten.DrawRectShaderOptions{
+		Blend: ebiten.BlendCopy,
+		Uniforms: map[string]any{
+			"Dt": dt,
+		},
+		Images: [4]*ebiten.Image{self.positions[self.current], self.velocities[next]},
+	})
+	self.current = next
+
+	self.simulate_time = time.Since(start)
+	return nil
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	screen.Fill(clear_color.Color())
+
+	if readback.Bool() {
+		// ReadPixels waits for the GPU to finish the simulation, Kage has no way to move vertices so the CPU
+		// has to know where the particles are to draw them
+		start := time.Now()
+		self.positions[self.current].ReadPixels(self.pixels)
+		self.readback_time = time.Since(start)
+
+		self.draw_particles(screen)
+	}
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Particles: %d", particle_count), 0, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Simulate: %v (issuing only)", self.simulate_time), 0, 28)
+	if readback.Bool() {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Readback: %v (includes waiting for the GPU)", self.readback_time), 0, 42)
+	}
+}
+
+// draw_particles draws a 2x2 quad per particle at the positions read back from the GPU.
+func (self *game) draw_particles(screen *ebiten.Image) {
+	const size = 2
+	const batch = ebiten.MaxVertexCount / 4
+
+	for first := 0; first < particle_count; first += batch {
+		last := min(first+batch, particle_count)
+
+		self.vertices = self.vertices[:0]
+		self.indices = self.indices[:0]
+		for i := first; i < last; i++ {
+			p := self.pixels[i*4:]
+			x := decode(p[0:]) * game_width
+			y := decode(p[2:]) * game_height
+
+			// tint by where the particle came from in the state image so the flow is visible
+			r := float(i%state_size) / state_size
+			b := float(i/state_size) / state_size
+
+			base := uint16(len(self.vertices))
+			for _, corner := range [4]vec2{{0, 0}, {size, 0}, {0, size}, {size, size}} {
+				self.vertices = append(self.vertices, ebiten.Vertex{
+					DstX:   x + corner.X(),
+					DstY:   y + corner.Y(),
+					SrcX:   1,
+					SrcY:   1,
+					ColorR: 0.3 + r*0.7,
+					ColorG: 0.6,
+					ColorB: 0.3 + b*0.7,
+					ColorA: 1,
+				})
+			}
+			self.indices = append(self.indices, base, base+1, base+2, base+1, base+3, base+2)
+		}
+
+		screen.DrawTriangles(self.vertices, self.indices, self.white, nil)
+	}
+}
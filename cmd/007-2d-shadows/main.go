@@ -0,0 +1,439 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/framegraph"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/gamma"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/targets"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tonemap"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var (
+	ambient         = cvar.Color("r_ambient", color.RGBA{25, 25, 35, 255}, cvar.Persist, "light everything gets, shadowed or not")
+	shadow_samples  = cvar.Int("r_shadow_samples", 8, 0, "points each area light is sampled at, 1 gives hard shadows").Range(1, 32)
+	show_geometry   = cvar.Bool("r_shadow_geometry", false, 0, "outlines the shadow volumes of the first light")
+	show_lighting   = cvar.Bool("r_lighting", true, 0, "multiplies the lights over the floor, without it the light passes are culled")
+	linear          = cvar.Bool("r_linear", false, cvar.Persist, "lights in linear light: the floor and colors are decoded from sRGB and the frame is encoded back")
+	light_intensity = cvar.Float("r_light_intensity", 1, 0, "multiplies every light, above 1 clips unless r_linear and a tone mapper roll it off").Range(0, 16)
+	tone_mapping    = cvar.Int("r_tonemap", int(tonemap.None), cvar.Persist, "with r_linear, maps light above 1 into the screen: 0 clips it, 1 reinhard, 2 aces").Range(0, float64(tonemap.OperatorCount-1))
+	exposure        = cvar.Float("r_exposure", 0, 0, "with r_linear, brightens the frame by this many stops before tone mapping").Range(-4, 4)
+	hdr_range       = cvar.Float("r_hdr_range", 4, 0, "with r_linear, the light the frame can hold, its images hold it divided by this").Range(1, 16)
+)
+
+// light_shader draws the falloff of a single point light. It's additive, everything outside of Radius is black.
+var light_shader = `
+//kage:unit pixels
+package main
+
+var Center vec2
+var Radius float
+var Color vec3
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	d := length(dst.xy-Center) / Radius
+	if d >= 1 {
+		return vec4(0)
+	}
+	// inverse square falloff, bent to reach zero at the radius
+	falloff := (1 - d*d) * (1 - d*d) / (1 + 8*d*d)
+	return vec4(Color*falloff, 1)
+}
+`
+
+type light struct {
+	position vec2
+	radius   float
+	color    vec3
+	// size is the radius of the light's disc, 0 is a point light with hard shadows
+	size float
+}
+
+// polygon is an occluder, its points are in order, either winding works.
+type polygon []vec2
+
+func main() {
+	flag.Parse()
+
+	input.Bind("move_light", input.Mouse(ebiten.MouseButtonLeft))
+
+	ebiten.SetWindowTitle("007-2d-shadows")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a shader which failed to compile in the window instead.
+func load() (ebiten.Game, error) {
+	shader, err := ebiten.NewShader([]byte(light_shader))
+	if err != nil {
+		return nil, err
+	}
+	converter, err := gamma.New()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := tonemap.New()
+	if err != nil {
+		return nil, err
+	}
+
+	white := ebiten.NewImage(3, 3)
+	white.Fill(color.White)
+
+	floor := ebiten.NewImage(game_width, game_height)
+	draw_floor(floor)
+
+	return &game{
+		shader:  shader,
+		gamma:   converter,
+		tonemap: mapper,
+		white:   white.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image),
+		floor:   floor,
+		graph:   framegraph.New(),
+		lights: []light{
+			{position: vec2{400, 300}, radius: 450, color: vec3{1, 0.9, 0.7}, size: 12},
+			{position: vec2{120, 100}, radius: 350, color: vec3{0.3, 0.5, 1}},
+			{position: vec2{680, 500}, radius: 300, color: vec3{1, 0.3, 0.4}, size: 24},
+		},
+		occluders: []polygon{
+			rectangle(250, 180, 80, 40, 0.3),
+			rectangle(520, 220, 40, 120, -0.2),
+			rectangle(350, 420, 140, 20, 0),
+			{{150, 380}, {210, 470}, {100, 460}},
+			{{600, 90}, {660, 110}, {670, 170}, {620, 190}, {580, 140}},
+		},
+	}, nil
+}
+
+// rectangle returns a w by h rectangle centered on (x, y) and rotated by `angle` radians.
+func rectangle(x, y, w, h, angle float) polygon {
+	sin, cos := math.Sincos(float64(angle))
+	rotate := func(dx, dy float) vec2 {
+		return vec2{
+			x + dx*float(cos) - dy*float(sin),
+			y + dx*float(sin) + dy*float(cos),
+		}
+	}
+	return polygon{
+		rotate(-w/2, -h/2),
+		rotate(w/2, -h/2),
+		rotate(w/2, h/2),
+		rotate(-w/2, h/2),
+	}
+}
+
+type game struct {
+	shader  *ebiten.Shader
+	gamma   *gamma.Converter
+	tonemap *tonemap.Mapper
+	white   *ebiten.Image
+	// floor is the texture of the floor, in sRGB like any image loaded from a file would be
+	floor *ebiten.Image
+
+	// graph runs the passes of a frame and owns their images
+	graph *framegraph.Graph
+
+	lights    []light
+	occluders []polygon
+
+	vertices []ebiten.Vertex
+	indices  []uint16
+
+	shadow_quads int
+	// err is why the passes of the last frame couldn't run, which Update hands to the failure screen
+	err error
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	if self.err != nil {
+		return self.err
+	}
+	if input.Pressed("move_light") {
+		x, y := input.CursorPosition()
+		self.lights[0].position = vec2{float(x), float(y)}
+	}
+	return nil
+}
+
+// multiply_blend multiplies the destination by the source, for lighting an image that's already drawn.
+var multiply_blend = ebiten.Blend{
+	BlendFactorSourceRGB:        ebiten.BlendFactorZero,
+	BlendFactorSourceAlpha:      ebiten.BlendFactorZero,
+	BlendFactorDestinationRGB:   ebiten.BlendFactorSourceColor,
+	BlendFactorDestinationAlpha: ebiten.BlendFactorOne,
+	BlendOperationRGB:           ebiten.BlendOperationAdd,
+	BlendOperationAlpha:         ebiten.BlendOperationAdd,
+}
+
+// Draw declares the frame's passes to the graph, which works out their order and images. They're added in no
+// particular order on purpose: the graph runs the lights before the pass that reads their result.
+func (self *game) Draw(screen *ebiten.Image) {
+	g := self.graph
+	self.shadow_quads = 0
+	samples := shadow_samples.Int()
+
+	target := g.Import("screen", screen)
+	// lighting accumulates every light, it's multiplied over the floor
+	lighting := g.Create("lighting", game_width, game_height)
+	// sample holds a single light sample while its shadows are cut out of it
+	sample := g.Create("sample", game_width, game_height)
+	// frame is where the floor is lit, in linear light it's tone mapped onto the screen after. It holds the light
+	// divided by r_hdr_range then, and so does the lighting.
+	frame := target
+	light_scale := light_intensity.Float32()
+	if linear.Bool() {
+		frame = g.Create("linear", game_width, game_height)
+		light_scale /= hdr_range.Float32()
+	}
+
+	g.AddPass(framegraph.Pass{
+		Name:   "floor",
+		Writes: []framegraph.Resource{frame},
+		Run: func(g *framegraph.Graph) {
+			if linear.Bool() {
+				self.gamma.Decode(g.Image(frame), self.floor)
+			} else {
+				g.Image(frame).DrawImage(self.floor, nil)
+			}
+		},
+	})
+
+	if show_lighting.Bool() {
+		g.AddPass(framegraph.Pass{
+			Name:   "light floor",
+			Reads:  []framegraph.Resource{lighting},
+			Writes: []framegraph.Resource{frame},
+			Run: func(g *framegraph.Graph) {
+				g.Image(frame).DrawImage(g.Image(lighting), &ebiten.DrawImageOptions{Blend: multiply_blend})
+			},
+		})
+	}
+
+	g.AddPass(framegraph.Pass{
+		Name:   "lights",
+		Writes: []framegraph.Resource{lighting, sample},
+		Run: func(g *framegraph.Graph) {
+			if linear.Bool() {
+				g.Image(lighting).Fill(scale(gamma.Linear(ambient.Color()), 1/hdr_range.Float32()))
+			} else {
+				g.Image(lighting).Fill(ambient.Color())
+			}
+			for _, l := range self.lights {
+				n := samples
+				if l.size == 0 {
+					n = 1
+				}
+				for i := 0; i < n; i++ {
+					self.draw_sample(g.Image(lighting), g.Image(sample), l, sample_position(l, i, n), light_scale/float(n))
+				}
+			}
+		},
+	})
+
+	// the overlay isn't lit, it's drawn over the tone mapped frame in sRGB
+	if linear.Bool() {
+		g.AddPass(framegraph.Pass{
+			Name:   "tonemap",
+			Reads:  []framegraph.Resource{frame},
+			Writes: []framegraph.Resource{target},
+			Run: func(g *framegraph.Graph) {
+				self.tonemap.Draw(g.Image(target), g.Image(frame), tonemap.Options{
+					Operator: tonemap.Operator(tone_mapping.Int()),
+					Exposure: exposure.Float32(),
+					Range:    hdr_range.Float32(),
+				})
+			},
+		})
+	}
+
+	g.AddPass(framegraph.Pass{
+		Name:   "overlay",
+		Writes: []framegraph.Resource{target},
+		Run: func(g *framegraph.Graph) {
+			screen := g.Image(target)
+			for _, p := range self.occluders {
+				self.fill_polygon(screen, p, color.RGBA{60, 60, 70, 255})
+			}
+			for _, l := range self.lights {
+				vector.DrawFilledCircle(screen, l.position.X(), l.position.Y(), max(l.size, 3), light_color(l.color), true)
+			}
+			if show_geometry.Bool() {
+				self.draw_shadow_outlines(screen, self.lights[0].position)
+			}
+		},
+	})
+
+	if err := g.Execute(); err != nil {
+		self.err = err
+		return
+	}
+
+	pool := targets.Default.Stats()
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Shadow quads: %d, samples per area light: %d", self.shadow_quads, samples), 0, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Passes: %s", g.Order()), 0, 28)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Target pool: %d hits, %d misses, %d free", pool.Hits, pool.Misses, pool.Free), 0, 42)
+	ebitenutil.DebugPrintAt(screen, "Hold the left mouse button to move the white light", 0, 56)
+	if linear.Bool() {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Lit in linear light, tone mapped by %v at %+.1f stops", tonemap.Operator(tone_mapping.Int()), exposure.Float32()), 0, 70)
+	}
+}
+
+// sample_position spreads the samples of an area light over its disc on a spiral, so any count covers it evenly.
+func sample_position(l light, i, n int) vec2 {
+	if n == 1 {
+		return l.position
+	}
+	const golden_angle = 2.39996323
+	r := l.size * float(math.Sqrt((float64(i)+0.5)/float64(n)))
+	sin, cos := math.Sincos(float64(i) * golden_angle)
+	return l.position.Add(vec2{r * float(cos), r * float(sin)})
+}
+
+// draw_sample adds the light of one sample of `l` from `at` to the lighting, with its hard shadows cut out.
+// Averaging the hard shadows of many samples over the light's disc makes the soft ones.
+func (self *game) draw_sample(lighting, sample *ebiten.Image, l light, at vec2, weight float) {
+	clr := l.color
+	if linear.Bool() {
+		clr = vec3{gamma.ToLinear(clr.X()), gamma.ToLinear(clr.Y()), gamma.ToLinear(clr.Z())}
+	}
+	clr = clr.Mul(weight)
+
+	sample.Clear()
+	sample.DrawRectShader(game_width, game_height, self.shader, &ebiten.DrawRectShaderOptions{
+		Uniforms: map[string]any{
+			"Center": at[:],
+			"Radius": l.radius,
+			"Color":  clr[:],
+		},
+	})
+
+	self.build_shadows(at, l.radius)
+	self.shadow_quads += len(self.indices) / 6
+	sample.DrawTriangles(self.vertices, self.indices, self.white, &ebiten.DrawTrianglesOptions{
+		Blend: ebiten.BlendClear,
+	})
+
+	op := &ebiten.DrawImageOptions{}
+	op.Blend = ebiten.BlendLighter
+	lighting.DrawImage(sample, op)
+}
+
+// build_shadows fills vertices and indices with a quad per occluder edge, reaching from the edge away from
+// `light` until it's out of reach. Edges facing the light are extruded too, their shadows overlap the ones of the
+// back edges so it's simpler than picking the silhouette, and concave occluders just work.
+func (self *game) build_shadows(light vec2, reach float) {
+	self.vertices = self.vertices[:0]
+	self.indices = self.indices[:0]
+
+	for _, p := range self.occluders {
+		for i := range p {
+			a := p[i]
+			b := p[(i+1)%len(p)]
+
+			// extrude far enough to leave the light's radius from the nearest point of the edge
+			far_a := a.Add(a.Sub(light).Normalize().Mul(reach * 2))
+			far_b := b.Add(b.Sub(light).Normalize().Mul(reach * 2))
+
+			base := uint16(len(self.vertices))
+			for _, v := range [4]vec2{a, b, far_a, far_b} {
+				self.vertices = append(self.vertices, ebiten.Vertex{
+					DstX: v.X(), DstY: v.Y(),
+					SrcX: 1, SrcY: 1,
+					ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1,
+				})
+			}
+			self.indices = append(self.indices, base, base+1, base+2, base+1, base+3, base+2)
+		}
+	}
+}
+
+func (self *game) draw_shadow_outlines(screen *ebiten.Image, light vec2) {
+	self.build_shadows(light, self.lights[0].radius)
+	clr := color.RGBA{0, 255, 0, 255}
+	for i := 0; i < len(self.vertices); i += 4 {
+		q := self.vertices[i : i+4]
+		vector.StrokeLine(screen, q[0].DstX, q[0].DstY, q[2].DstX, q[2].DstY, 1, clr, false)
+		vector.StrokeLine(screen, q[1].DstX, q[1].DstY, q[3].DstX, q[3].DstY, 1, clr, false)
+		vector.StrokeLine(screen, q[0].DstX, q[0].DstY, q[1].DstX, q[1].DstY, 1, clr, false)
+	}
+}
+
+func draw_floor(screen *ebiten.Image) {
+	const tile = 40
+	screen.Fill(color.RGBA{170, 170, 170, 255})
+	for y := 0; y < game_height; y += tile {
+		for x := 0; x < game_width; x += tile {
+			if (x/tile+y/tile)%2 == 0 {
+				vector.DrawFilledRect(screen, float(x), float(y), tile, tile, color.RGBA{200, 200, 200, 255}, false)
+			}
+		}
+	}
+}
+
+func (self *game) fill_polygon(screen *ebiten.Image, p polygon, clr color.RGBA) {
+	var path vector.Path
+	path.MoveTo(p[0].X(), p[0].Y())
+	for _, v := range p[1:] {
+		path.LineTo(v.X(), v.Y())
+	}
+	path.Close()
+
+	vertices, indices := path.AppendVerticesAndIndicesForFilling(nil, nil)
+	for i := range vertices {
+		vertices[i].SrcX = 1
+		vertices[i].SrcY = 1
+		vertices[i].ColorR = float(clr.R) / 255
+		vertices[i].ColorG = float(clr.G) / 255
+		vertices[i].ColorB = float(clr.B) / 255
+		vertices[i].ColorA = float(clr.A) / 255
+	}
+	screen.DrawTriangles(vertices, indices, self.white, &ebiten.DrawTrianglesOptions{
+		FillRule: ebiten.NonZero,
+	})
+}
+
+// scale multiplies the channels of `c` by `f`, its alpha stays.
+func scale(c color.Color, f float) color.Color {
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{R: uint16(float(r) * f), G: uint16(float(g) * f), B: uint16(float(b) * f), A: uint16(a)}
+}
+
+func light_color(c vec3) color.RGBA {
+	return color.RGBA{uint8(c.X() * 255), uint8(c.Y() * 255), uint8(c.Z() * 255), 255}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/console"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/flags"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/grade"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+
+	// the indexed art is drawn small and scaled up, like the games it imitates
+	art_width  = 200
+	art_height = 150
+	art_scale  = 4
+
+	palette_size = 64
+)
+
+var logger = logging.Tag("main")
+
+var (
+	lut_strength  = cvar.Float("r_lut_strength", 1, 0, "blends from the original colors at 0 to the lut at 1").Range(0, 1)
+	palette_speed = cvar.Int("r_palette_speed", 3, 0, "ticks between palette cycling steps, 0 stops cycling").Range(0, 60)
+)
+
+var lut_path = flag.String("lut", "", "grade with the lut strip in the png `file`, reloaded when it changes")
+
+func main() {
+	flag.Parse()
+
+	ebiten.SetWindowTitle("008-color-grading")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a palette or lut which failed to load in the window instead.
+func load() (ebiten.Game, error) {
+	palettes := map[string][]color.Color{
+		"fire":  gradient(color.RGBA{0, 0, 0, 255}, color.RGBA{200, 30, 0, 255}, color.RGBA{255, 200, 40, 255}, color.RGBA{255, 255, 220, 255}),
+		"water": gradient(color.RGBA{0, 10, 40, 255}, color.RGBA{0, 80, 160, 255}, color.RGBA{80, 200, 255, 255}, color.RGBA{230, 255, 255, 255}),
+		"toxic": gradient(color.RGBA{20, 0, 30, 255}, color.RGBA{120, 0, 160, 255}, color.RGBA{80, 255, 60, 255}, color.RGBA{20, 0, 30, 255}),
+	}
+
+	game := &game{
+		palettes: make(map[string]*grade.Palette),
+		art:      grade.Indexed(art_width, art_height, plasma(), false),
+		scene:    ebiten.NewImage(game_width, game_height),
+	}
+	for name, colors := range palettes {
+		p, err := grade.NewPalette(colors)
+		if err != nil {
+			return nil, err
+		}
+		game.palettes[name] = p
+	}
+	game.palette = game.palettes["fire"]
+
+	if err := game.load_lut(); err != nil {
+		return nil, err
+	}
+
+	game.register_commands()
+	flags.Default.Live("lut", game.load_lut)
+
+	return game, nil
+}
+
+// load_lut grades with the lut -lut names, or the built-in sepia one without.
+func (self *game) load_lut() error {
+	var l *grade.LUT
+	var err error
+	if *lut_path != "" {
+		l, err = grade.LoadLUT(*lut_path)
+	} else {
+		l, err = grade.NewLUT(sepia(16))
+	}
+	if err != nil {
+		return err
+	}
+	self.lut = l
+	return nil
+}
+
+// gradient spreads palette_size colors evenly over the stops.
+func gradient(stops ...color.RGBA) []color.Color {
+	colors := make([]color.Color, palette_size)
+	for i := range colors {
+		t := float64(i) / float64(palette_size) * float64(len(stops)-1)
+		a := stops[int(t)]
+		b := stops[min(int(t)+1, len(stops)-1)]
+		f := t - math.Floor(t)
+		mix := func(x, y uint8) uint8 {
+			return uint8(float64(x) + (float64(y)-float64(x))*f)
+		}
+		colors[i] = color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+	}
+	return colors
+}
+
+// plasma returns the classic demoscene plasma as palette indices, it only looks alive once the palette cycles.
+func plasma() []uint8 {
+	indices := make([]uint8, art_width*art_height)
+	for y := 0; y < art_height; y++ {
+		for x := 0; x < art_width; x++ {
+			fx, fy := float64(x), float64(y)
+			v := math.Sin(fx/16) + math.Sin(fy/8) + math.Sin((fx+fy)/16) + math.Sin(math.Hypot(fx-100, fy-75)/8)
+			indices[y*art_width+x] = uint8((v + 4) / 8 * (palette_size - 1))
+		}
+	}
+	return indices
+}
+
+// sepia builds the default LUT in code, so the demo shows something without a file.
+func sepia(size int) *image.RGBA {
+	strip := grade.Identity(size)
+	for i := 0; i < len(strip.Pix); i += 4 {
+		r, g, b := float64(strip.Pix[i]), float64(strip.Pix[i+1]), float64(strip.Pix[i+2])
+		strip.Pix[i+0] = uint8(min(r*0.393+g*0.769+b*0.189, 255))
+		strip.Pix[i+1] = uint8(min(r*0.349+g*0.686+b*0.168, 255))
+		strip.Pix[i+2] = uint8(min(r*0.272+g*0.534+b*0.131, 255))
+	}
+	return strip
+}
+
+type game struct {
+	palettes map[string]*grade.Palette
+	palette  *grade.Palette
+	art      *ebiten.Image
+	lut      *grade.LUT
+
+	// scene is drawn to first so the lut can grade all of it
+	scene *ebiten.Image
+
+	ticks  int
+	offset int
+}
+
+func (self *game) register_commands() {
+	console.Register("lut", "[file.png]", "grades with a lut strip, reloaded when the file changes, or prints the current one", func(args console.Args) error {
+		if len(args) == 0 {
+			if self.lut.Path() == "" {
+				logger.Infof("using the built-in sepia lut, size %d", self.lut.Size())
+			} else {
+				logger.Infof("using %s, size %d", self.lut.Path(), self.lut.Size())
+			}
+			return nil
+		}
+		path, err := args.String(0)
+		if err != nil {
+			return err
+		}
+		l, err := grade.LoadLUT(path)
+		if err != nil {
+			return err
+		}
+		self.lut = l
+		return nil
+	})
+
+	console.Register("lut_identity", "<file.png> [size]", "writes a lut strip which changes nothing, to paint over in an image editor", func(args console.Args) error {
+		path, err := args.String(0)
+		if err != nil {
+			return err
+		}
+		size := 16
+		if len(args) > 1 {
+			if size, err = args.Int(1); err != nil {
+				return err
+			}
+		}
+		if size < 2 || size > 64 {
+			return fmt.Errorf("size must be between 2 and 64")
+		}
+		return grade.WriteIdentity(path, size)
+	})
+
+	console.Register("palette", "<fire|water|toxic>", "swaps the palette of the indexed art", func(args console.Args) error {
+		name, err := args.String(0)
+		if err != nil {
+			return err
+		}
+		p, ok := self.palettes[name]
+		if !ok {
+			return fmt.Errorf("unknown palette %q", name)
+		}
+		self.palette = p
+		return nil
+	})
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.lut.Reload()
+
+	self.ticks++
+	if speed := palette_speed.Int(); speed > 0 && self.ticks%speed == 0 {
+		self.offset++
+	}
+	return nil
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	opts := &grade.PaletteOptions{Offset: self.offset}
+	opts.GeoM.Scale(art_scale, art_scale)
+	self.scene.Clear()
+	self.palette.Draw(self.scene, self.art, opts)
+
+	self.lut.Apply(screen, self.scene, lut_strength.Float32())
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Palette offset: %d, lut strength: %.2f", self.offset, lut_strength.Float()), 0, 14)
+}
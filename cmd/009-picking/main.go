@@ -0,0 +1,543 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/console"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/highlight"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/minimap"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pip"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/spline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tween"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/walk"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	minimap_size   = 160
+	minimap_margin = 8
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+var logger = logging.Tag("main")
+
+var (
+	clear_color   = cvar.Color("r_clear_color", color.RGBA{130, 130, 130, 255}, cvar.Persist, "background color")
+	pick_mode     = cvar.Int("r_pick_mode", pick_triangles, 0, "0 picks by testing the sorted triangles, 1 by reading an object id buffer").Range(0, 1)
+	fov           = cvar.Float("r_fov", 60, 0, "vertical field of view in degrees, the zoom command blends it").Range(10, 150)
+	outline_mode  = cvar.Int("r_outline_mode", int(highlight.EdgeDetect), 0, "0 outlines by edge detecting a mask, 1 with an inverted hull").Range(0, float64(highlight.ModeCount-1))
+	outline_width = cvar.Float("r_outline_width", 2, 0, "outline width, in pixels for edge detection and tenths of a world unit for the hull").Range(0.5, highlight.MaxWidth)
+	show_minimap  = cvar.Bool("r_minimap", true, cvar.Persist, "shows the scene from above in the top right corner")
+	outline_color = cvar.Color("r_outline_color", color.RGBA{255, 160, 0, 255}, cvar.Persist, "color of the selection outline")
+	show_paths    = cvar.Bool("r_paths", false, 0, "draws the drone's path, a dot every unit along it, and its control points")
+)
+
+const (
+	pick_triangles = iota
+	pick_ids
+)
+
+type object struct {
+	name string
+	mesh *mesh.Mesh
+}
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+	input.Bind("select", input.Mouse(ebiten.MouseButtonRight))
+	input.Bind("focus_selected", input.Key(ebiten.KeyF))
+	input.Bind("toggle_walk", input.Key(ebiten.KeyG))
+	input.Bind("jump", input.Key(ebiten.KeySpace), input.GamepadButton(ebiten.StandardGamepadButtonRightBottom))
+
+	ebiten.SetWindowTitle("009-picking")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a renderer or highlighter which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	highlighter, err := highlight.New()
+	if err != nil {
+		return nil, err
+	}
+
+	game := &game{
+		context:     &pipeline.Context{FlipY: true},
+		renderer:    renderer,
+		highlighter: highlighter,
+		texture:     new_checkerboard(),
+		camera:      camera.New(vec3{0, 12, 30}, vec3{}),
+		objects:     new_scene(),
+		ids:         ebiten.NewImage(game_width, game_height),
+		minimap:     minimap.New(minimap_size, 10),
+		ui:          ui.NewContext(),
+		paths:       new_paths(),
+	}
+	meshes := make([]*mesh.Mesh, len(game.objects))
+	for i, o := range game.objects {
+		meshes[i] = o.mesh
+	}
+	game.world = walk.NewWorld(meshes...)
+
+	// the drone is added after the walk world, it flies through things rather than being walked on
+	game.drone = new_drone()
+	game.objects = append(game.objects, game.drone.object)
+
+	game.camera.Fov = mgl32.DegToRad(fov.Float32())
+	fov.Watch(func(v *cvar.Var) {
+		game.camera.Fov = mgl32.DegToRad(v.Float32())
+	})
+
+	// the intro, from far away down to the scene
+	game.camera.FlyTo(vec3{0, 5, 12}, vec3{}, 120, tween.InOutCubic)
+
+	game.register_commands()
+
+	return game, nil
+}
+
+// new_scene places a few shapes on a floor. Meshes are in world space, the pipeline has no model matrices.
+func new_scene() []*object {
+	place := func(m *mesh.Mesh, x, y, z float) *mesh.Mesh {
+		m.Transform(mgl32.Translate3D(x, y, z))
+		return m
+	}
+	objects := []*object{
+		{"floor", mesh.Plane(20)},
+		{"crate", place(mesh.Box(vec3{2, 2, 2}), -3, 1, 0)},
+		{"tower", place(mesh.Box(vec3{1, 4, 1}), 0, 2, -3)},
+		{"slab", place(mesh.Box(vec3{3, 0.5, 2}), 3, 0.25, 1)},
+		{"ball", place(mesh.UVSphere(1, 12, 16), 1, 1, 2)},
+		{"moon", place(mesh.UVSphere(0.5, 8, 12), 3, 1.5, 1)},
+	}
+	// stairs low enough to walk up, up to the crate
+	for i := 0; i < 6; i++ {
+		h := 0.3 * float(i+1)
+		objects = append(objects, &object{fmt.Sprintf("step %d", i+1), place(mesh.Box(vec3{2, h, 0.6}), -3, h/2, 3.8-0.6*float(i))})
+	}
+	return objects
+}
+
+// drone is an object flying around the scene on a path. Meshes are in world space, so its points are moved along
+// every tick.
+type drone struct {
+	*object
+	local    []vec3
+	follower *spline.Follower
+}
+
+func new_drone() *drone {
+	// a loop around the scene, dipping between the tower and the slab
+	path := spline.NewPath(&spline.CatmullRom{
+		Points: []vec3{{-6, 2, -6}, {0, 5, -7}, {6, 3, -5}, {2, 1.5, -1}, {7, 2.5, 4}, {0, 4, 7}, {-7, 3, 5}},
+		Loop:   true,
+	})
+	m := mesh.Box(vec3{0.6, 0.3, 1})
+	d := &drone{
+		object:   &object{name: "drone", mesh: m},
+		local:    slices.Clone(m.Points),
+		follower: spline.NewFollower(path, 3, spline.Loop),
+	}
+	d.place()
+	return d
+}
+
+// place moves the mesh to where the follower is, pointing the way it goes.
+func (d *drone) place() {
+	forward := d.follower.Forward()
+	t := mgl32.Translate3D(d.follower.Position().Elem())
+	if forward.Len() > 0 {
+		// the inverse of a view matrix looking along forward turns -z, the box's length, that way
+		t = t.Mul4(mgl32.LookAtV(vec3{}, forward, vec3{0, 1, 0}).Inv())
+	}
+	for i, p := range d.local {
+		d.mesh.Points[i] = mgl32.TransformCoordinate(p, t)
+	}
+	d.mesh.ComputeBounds()
+}
+
+func new_checkerboard() *ebiten.Image {
+	const texture_size = 64
+	const texture_subdivisions = 4
+	const tile_size = texture_size / texture_subdivisions
+
+	texture := ebiten.NewImage(texture_size, texture_size)
+	texture.Fill(color.RGBA{90, 90, 90, 255})
+
+	for row := 0; row < texture_subdivisions; row++ {
+		for col := 0; col < texture_subdivisions; col++ {
+			if (row+col)%2 == 0 {
+				continue
+			}
+			x := float(col * tile_size)
+			y := float(row * tile_size)
+			vector.DrawFilledRect(texture, x, y, tile_size, tile_size, color.RGBA{220, 220, 220, 255}, false)
+		}
+	}
+	return texture
+}
+
+type game struct {
+	context     *pipeline.Context
+	renderer    *render.Renderer
+	highlighter *highlight.Highlighter
+	texture     *ebiten.Image
+	camera      *camera.Camera
+
+	objects  []*object
+	selected *object
+	hovered  *object
+	drone    *drone
+	paths    *paths
+
+	// walker drives the camera in walk mode, colliding with world
+	walker  *walk.Controller
+	world   *walk.World
+	walking bool
+
+	// ids holds the mesh id of every pixel in r_pick_mode 1
+	ids *ebiten.Image
+
+	minimap *minimap.Minimap
+	ui      *ui.Context
+
+	// picking needs the triangles of a frame, so a click is remembered until the next Draw
+	pick_pending bool
+	pick_x       int
+	pick_y       int
+}
+
+func (self *game) register_commands() {
+	console.Register("fly", "<x> <y> <z> [ticks] [ease]", "flies the camera to a position, looking at the selection or the origin", func(args console.Args) error {
+		var p vec3
+		for i := range p {
+			f, err := args.Float(i)
+			if err != nil {
+				return err
+			}
+			p[i] = float(f)
+		}
+		ticks, ease, err := tween_args(args, 3)
+		if err != nil {
+			return err
+		}
+		target := vec3{}
+		if self.selected != nil {
+			target = self.selected.mesh.Sphere.Center
+		}
+		self.camera.FlyTo(p, target, ticks, ease)
+		return nil
+	})
+
+	console.Register("zoom", "<degrees> [ticks] [ease]", "blends the field of view, r_fov is left alone", func(args console.Args) error {
+		degrees, err := args.Float(0)
+		if err != nil {
+			return err
+		}
+		ticks, ease, err := tween_args(args, 1)
+		if err != nil {
+			return err
+		}
+		self.camera.BlendFov(mgl32.DegToRad(float(degrees)), ticks, ease)
+		return nil
+	})
+
+	console.Register("ride", "[speed] [ahead|center]", "rides the camera along the drone's path, looking ahead or at the center", func(args console.Args) error {
+		speed := 4.0
+		var err error
+		if len(args) > 0 {
+			if speed, err = args.Float(0); err != nil {
+				return err
+			}
+		}
+		var target *vec3
+		if len(args) > 1 {
+			switch args[1] {
+			case "ahead":
+			case "center":
+				target = &vec3{}
+			default:
+				return fmt.Errorf("look %q, either ahead or center", args[1])
+			}
+		}
+		path := self.drone.follower.Path
+		self.camera.Follow(spline.NewFollower(path, float(speed), spline.Loop), target)
+		return nil
+	})
+
+	console.Register("shake", "[amplitude] [ticks]", "shakes the camera", func(args console.Args) error {
+		amplitude, ticks := 0.3, 30
+		var err error
+		if len(args) > 0 {
+			if amplitude, err = args.Float(0); err != nil {
+				return err
+			}
+		}
+		if len(args) > 1 {
+			if ticks, err = args.Int(1); err != nil {
+				return err
+			}
+		}
+		self.camera.Shake(float(amplitude), ticks)
+		return nil
+	})
+}
+
+// tween_args parses the optional [ticks] [ease] arguments starting at `i`, a second with smoothstep by default.
+func tween_args(args console.Args, i int) (int, tween.Ease, error) {
+	ticks, ease := 60, tween.SmoothStep
+	if len(args) > i {
+		n, err := args.Int(i)
+		if err != nil {
+			return 0, nil, err
+		}
+		ticks = n
+	}
+	if len(args) > i+1 {
+		e, ok := tween.ByName(args[i+1])
+		if !ok {
+			return 0, nil, fmt.Errorf("unknown ease %q, one of %s", args[i+1], strings.Join(tween.Names(), ", "))
+		}
+		ease = e
+	}
+	return ticks, ease, nil
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	if input.JustPressed("toggle_walk") {
+		self.walking = !self.walking
+		if self.walking {
+			self.camera.Stop()
+			self.walker = walk.New(vec3{})
+			// it drops from where the camera is
+			self.walker.Position = self.camera.Position.Sub(vec3{0, self.walker.Eye, 0})
+		}
+	}
+
+	if self.walking {
+		self.walk()
+	} else {
+		self.camera.Update()
+	}
+
+	if input.JustPressed("select") {
+		self.pick_pending = true
+		self.pick_x, self.pick_y = input.CursorPosition()
+	}
+
+	self.drone.follower.Update(app.Delta())
+	self.drone.place()
+
+	if input.JustPressed("focus_selected") && self.selected != nil {
+		self.camera.Focus(self.selected.mesh.Sphere, self.camera.Fov, game_aspect)
+	}
+	return nil
+}
+
+// walk moves the camera with the walker, along the ground in the direction the camera faces.
+func (self *game) walk() {
+	self.camera.Look()
+
+	flat := func(v vec3) vec3 {
+		v[1] = 0
+		if v.Len() == 0 {
+			return v
+		}
+		return v.Normalize()
+	}
+	wish := flat(self.camera.Forward()).Mul(input.Axis("move_backward", "move_forward")).
+		Add(flat(self.camera.Right()).Mul(input.Axis("move_left", "move_right")))
+	if wish.Len() > 1 {
+		wish = wish.Normalize()
+	}
+
+	self.walker.Update(self.world, wish, input.JustPressed("jump"), 1/float(ebiten.TPS()))
+	self.camera.Position = self.walker.EyePosition()
+}
+
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) object_of(id uint32) *object {
+	for _, o := range self.objects {
+		if o.mesh.ID() == id {
+			return o
+		}
+	}
+	return nil
+}
+
+// draw_minimap renders the scene from above and shows it in a panel, with the camera and selection marked.
+func (self *game) draw_minimap(screen *ebiten.Image) {
+	m := self.minimap
+	m.Render(clear_color.Color(), func(ctx *pipeline.Context) {
+		for _, o := range self.objects {
+			ctx.PushMesh(o.mesh)
+		}
+	}, func(target *ebiten.Image, triangles []pipeline.Triangle) {
+		self.renderer.DrawTriangles(target, self.texture, triangles)
+	})
+	if self.selected != nil {
+		m.DrawMarker(self.selected.mesh.Sphere.Center, vec3{}, outline_color.Color())
+	}
+	m.DrawMarker(self.camera.Position, self.camera.Forward(), color.RGBA{255, 255, 255, 255})
+
+	const padding = 4
+	x := game_width - minimap_size - minimap_margin - padding*2
+	y := minimap_margin
+
+	self.ui.StartFrame(screen)
+	self.ui.Push(x, y, minimap_size+padding*2, minimap_size+padding*2, nil)
+	self.ui.Panel()
+	self.ui.Push(x+padding, y+padding, minimap_size, minimap_size, nil)
+	self.ui.Image(m.Image())
+	self.ui.Pop()
+	self.ui.Pop()
+	self.ui.EndFrame()
+}
+
+// pick returns the object drawn at (x, y), or nil. The id buffer must be drawn first in r_pick_mode 1.
+func (self *game) pick(x, y int) *object {
+	if pick_mode.Int() == pick_ids {
+		return self.object_of(render.IDAt(self.ids, x, y))
+	}
+	// the pixel's center, like the rasterizer samples it
+	if t, ok := self.context.Pick(float(x)+0.5, float(y)+0.5); ok {
+		return self.object_of(t.Mesh)
+	}
+	return nil
+}
+
+// draw draws the triangles in runs of the same texture, the scene's or the paths'.
+func (self *game) draw(screen *ebiten.Image, triangles []pipeline.Triangle) {
+	for len(triangles) > 0 {
+		texture := self.paths.texture(triangles[0].Mesh)
+		n := 1
+		for n < len(triangles) && self.paths.texture(triangles[n].Mesh) == texture {
+			n++
+		}
+		if texture == nil {
+			texture = self.texture
+		}
+		self.renderer.DrawTriangles(screen, texture, triangles[:n])
+		triangles = triangles[n:]
+	}
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 100)
+	ctx.SetView(self.camera.View())
+
+	screen.Fill(clear_color.Color())
+	for _, o := range self.objects {
+		ctx.PushMesh(o.mesh)
+	}
+	if show_paths.Bool() {
+		self.paths.push(ctx, self.drone.follower.Path)
+	}
+	ctx.Sort()
+	self.draw(screen, ctx.Triangles())
+
+	if pick_mode.Int() == pick_ids {
+		self.ids.Clear()
+		self.renderer.DrawIDs(self.ids, ctx.Triangles())
+		pip.Show("ids", self.ids)
+	}
+
+	cursor_x, cursor_y := input.CursorPosition()
+	self.hovered = self.pick(cursor_x, cursor_y)
+	if self.pick_pending {
+		self.pick_pending = false
+		self.selected = self.pick(self.pick_x, self.pick_y)
+	}
+
+	mode := highlight.Mode(outline_mode.Int())
+	if self.selected != nil {
+		width := outline_width.Float32()
+		if mode == highlight.InvertedHull {
+			width /= 10
+		}
+		self.highlighter.Draw(screen, ctx, self.selected.mesh, highlight.Options{
+			Mode:  mode,
+			Color: outline_color.Color(),
+			Width: width,
+		})
+	}
+	ctx.Reset()
+
+	if show_minimap.Bool() {
+		self.draw_minimap(screen)
+	}
+
+	name := func(o *object) string {
+		if o == nil {
+			return "nothing"
+		}
+		return o.name
+	}
+	method := "triangles"
+	if pick_mode.Int() == pick_ids {
+		method = "id buffer"
+	}
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Selected: %s, hovered: %s", name(self.selected), name(self.hovered)), 0, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Picking: %s, outline: %v", method, mode), 0, 28)
+	ebitenutil.DebugPrintAt(screen, "Right click to select, F to focus it, drag to look (C captures the mouse), WASD to move", 0, 42)
+	if self.walking {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Walking (G to fly), space to jump, grounded: %v", self.walker.Grounded), 0, 56)
+	} else {
+		ebitenutil.DebugPrintAt(screen, "Flying (G to walk)", 0, 56)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/spline"
+)
+
+// path_step is the length of the straight pieces the drone's path is drawn with.
+const path_step = 0.2
+
+// paths draws the drone's path, a dot every unit along it and its control points as lines and sprites through the
+// pipeline, so the scene hides them where it's in front of them.
+type paths struct {
+	curve    pipeline.Lines
+	controls pipeline.Lines
+	ticks    pipeline.Sprites
+	points   pipeline.Sprites
+	// textures are what each of them is drawn with, by ID
+	textures map[uint32]*ebiten.Image
+}
+
+func new_paths() *paths {
+	p := &paths{
+		curve:    pipeline.Lines{Width: 2},
+		controls: pipeline.Lines{Width: 1},
+		ticks:    pipeline.Sprites{Size: 5},
+		points:   pipeline.Sprites{Size: 8},
+	}
+	blue := color.RGBA{80, 200, 255, 255}
+	white := color.RGBA{255, 255, 255, 160}
+	p.textures = map[uint32]*ebiten.Image{
+		p.curve.ID():    new_solid(blue),
+		p.controls.ID(): new_solid(white),
+		p.ticks.ID():    new_dot(blue),
+		p.points.ID():   new_dot(white),
+	}
+	return p
+}
+
+func new_solid(clr color.Color) *ebiten.Image {
+	img := ebiten.NewImage(1, 1)
+	img.Fill(clr)
+	return img
+}
+
+func new_dot(clr color.Color) *ebiten.Image {
+	const size = 16
+	img := ebiten.NewImage(size, size)
+	vector.DrawFilledCircle(img, size/2, size/2, size/2-1, clr, true)
+	return img
+}
+
+// push pushes the lines and sprites of `path`, whose curve is a Catmull-Rom one.
+func (p *paths) push(ctx *pipeline.Context, path *spline.Path) {
+	p.curve.Points = p.curve.Points[:0]
+	length := path.Length()
+	previous := path.At(0)
+	for d := float(path_step); d < length+path_step; d += path_step {
+		next := path.At(min(d, length))
+		p.curve.Points = append(p.curve.Points, previous, next)
+		previous = next
+	}
+
+	p.ticks.Points = p.ticks.Points[:0]
+	for d := float(0); d <= length; d++ {
+		p.ticks.Points = append(p.ticks.Points, path.At(d))
+	}
+
+	points := path.Curve.(*spline.CatmullRom).Points
+	p.points.Points = points
+	p.controls.Points = p.controls.Points[:0]
+	for i, a := range points {
+		p.controls.Points = append(p.controls.Points, a, points[(i+1)%len(points)])
+	}
+
+	ctx.PushLines(&p.controls)
+	ctx.PushLines(&p.curve)
+	ctx.PushSprites(&p.ticks)
+	ctx.PushSprites(&p.points)
+}
+
+// texture is the texture of the triangles of `id`, nil for the scene's.
+func (p *paths) texture(id uint32) *ebiten.Image {
+	return p.textures[id]
+}
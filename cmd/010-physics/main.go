@@ -0,0 +1,494 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/audio"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/console"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/noise"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/physics"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/scene"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/trail"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	floor_size = 40
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
+)
+
+var logger = logging.Tag("main")
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{130, 160, 190, 255}, cvar.Persist, "background color")
+	iterations  = cvar.Int("p_iterations", 10, 0, "how often the solver goes over the contacts per step").Range(1, 50)
+	sleeping    = cvar.Bool("p_sleep", true, 0, "lets bodies at rest fall asleep, they're drawn gray")
+	gravity     = cvar.Float("p_gravity", 9.81, 0, "downward acceleration in units per second squared").Range(0, 50)
+	throw_speed = cvar.Float("p_throw_speed", 15, 0, "speed of the balls thrown with the right mouse button").Range(1, 60)
+	loud_speed  = cvar.Float("s_loud_speed", 10, 0, "impact speed heard at full volume, slower hits are quieter").Range(1, 50)
+	trail_time  = cvar.Float("r_trail_time", 0.6, 0, "seconds the trails of thrown balls last, 0 for none").Range(0, 5)
+)
+
+// the texture is a column of checkerboards, one per palette color, and the last one for sleeping bodies
+var palette = []color.RGBA{
+	{220, 90, 70, 255},
+	{240, 180, 60, 255},
+	{90, 180, 100, 255},
+	{70, 140, 220, 255},
+	{170, 110, 210, 255},
+	{200, 200, 200, 255},
+}
+
+const (
+	floor_row  = 5
+	asleep_row = 6
+	rows       = 7
+)
+
+// materials are those of the scene, each naming the row of the texture it's drawn with, the first few the colors
+// bodies are given
+var materials = []string{"red", "yellow", "green", "blue", "purple", "floor", "asleep"}
+
+// object is a body and the node of the scene it's drawn as, which is moved to where the body is every tick.
+type object struct {
+	body *physics.Body
+	node *scene.Node
+	// color is the material of the node while the body is awake
+	color string
+	// trail is the streak behind a thrown ball, nil for everything else
+	trail *trail.Trail
+}
+
+func new_object(body *physics.Body, color string) *object {
+	var recipe string
+	switch body.Shape {
+	case physics.Box:
+		size := body.HalfSize.Mul(2)
+		recipe = fmt.Sprintf("box %g %g %g", size.X(), size.Y(), size.Z())
+	case physics.Sphere:
+		recipe = fmt.Sprintf("sphere %g 8 12", body.Radius)
+	}
+	o := &object{body: body, node: &scene.Node{Transform: scene.Identity, Mesh: recipe}, color: color}
+	o.sync()
+	return o
+}
+
+// sync moves the node to where the body is and grays it out while the body is asleep.
+func (o *object) sync() {
+	o.node.Transform.Position = o.body.Position
+	o.node.Transform.Rotation = scene.Rotation(o.body.Orientation)
+	o.node.Material = o.color
+	if o.body.Asleep() {
+		o.node.Material = "asleep"
+	}
+}
+
+// placed is the mesh of a node: its points in the node's space, which are moved to where the node is every frame,
+// and its texture coordinates squeezed into each row of the texture it was drawn with.
+type placed struct {
+	mesh      *mesh.Mesh
+	local     []vec3
+	texcoords []vec2
+	rows      map[int][]vec2
+}
+
+// texture_row squeezes texture coordinates into one row of the texture.
+func texture_row(texcoords []vec2, row int) []vec2 {
+	out := make([]vec2, len(texcoords))
+	for i, t := range texcoords {
+		out[i] = vec2{t.X(), (float(row) + t.Y()) / rows}
+	}
+	return out
+}
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+	input.Bind("throw", input.Mouse(ebiten.MouseButtonRight))
+	input.Bind("drop", input.Key(ebiten.KeySpace))
+
+	ebiten.SetWindowTitle("010-physics")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a renderer or material which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	graph := scene.New()
+	for row, name := range materials {
+		graph.Materials[name] = &scene.Material{Params: map[string]vec4{"row": {float(row)}}}
+	}
+	floor := &scene.Node{Name: "floor", Transform: scene.Identity, Mesh: fmt.Sprint("plane ", floor_size), Material: "floor"}
+	graph.Nodes = []*scene.Node{floor}
+
+	streaks := texgen.Noise(64, 1, func(x, y float) float { return 0.4 + 0.6*noise.Value(x*2, y*16) })
+	trail_material, err := render.NewTrailMaterial(ebiten.NewImageFromImage(streaks))
+	if err != nil {
+		return nil, err
+	}
+	trail_material.Uniforms["Tint"] = []float32{1, 0.8, 0.5, 1}
+	trail_material.Uniforms["Scroll"] = float32(2)
+	trail_material.Uniforms["Fade"] = float32(0.7)
+
+	game := &game{
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		texture:  new_texture(),
+		trail:    trail_material,
+		trails:   make(map[uint32]bool),
+		camera:   camera.New(vec3{0, 8, 18}, vec3{0, 2, 0}),
+		world:    physics.NewWorld(),
+		graph:    graph,
+		floor:    floor,
+		meshes:   make(map[*scene.Node]*placed),
+		random:   rand.New(rand.NewSource(1)),
+	}
+	game.camera.Fov = mgl32.DegToRad(60)
+	load_sounds()
+	game.stack(6)
+	game.register_commands()
+
+	return game, nil
+}
+
+func new_texture() *ebiten.Image {
+	const texture_size = 64
+	const texture_subdivisions = 4
+	const tile_size = texture_size / texture_subdivisions
+
+	texture := ebiten.NewImage(texture_size, texture_size*rows)
+	for row := 0; row < rows; row++ {
+		light := color.RGBA{150, 150, 150, 255}
+		if row < len(palette) {
+			light = palette[row]
+		}
+		dark := color.RGBA{light.R / 3 * 2, light.G / 3 * 2, light.B / 3 * 2, 255}
+
+		y0 := float(row * texture_size)
+		vector.DrawFilledRect(texture, 0, y0, texture_size, texture_size, dark, false)
+		for i := 0; i < texture_subdivisions; i++ {
+			for j := 0; j < texture_subdivisions; j++ {
+				if (i+j)%2 == 0 {
+					continue
+				}
+				vector.DrawFilledRect(texture, float(j*tile_size), y0+float(i*tile_size), tile_size, tile_size, light, false)
+			}
+		}
+	}
+	return texture
+}
+
+// load_sounds makes up the sounds of the demo and the events playing them.
+func load_sounds() {
+	// a knock is a low sine dropping in pitch, like something hit ringing out, with a click of noise on top
+	knock := func(frequency, seconds float) *audio.Sound {
+		var phase float64
+		return audio.Synth(seconds, func(t float) float {
+			phase += 2 * math.Pi * float64(frequency*(1+2*audio.Decay(t, seconds/8))) / audio.SampleRate
+			click := (rand.Float32()*2 - 1) * audio.Decay(t, seconds/10)
+			return float(math.Sin(phase))*audio.Decay(t, seconds) + 0.3*click
+		})
+	}
+	bank := audio.Default
+	bank.Add("thud_low", knock(55, 0.35))
+	bank.Add("thud_high", knock(75, 0.3))
+	bank.Add("bonk_low", knock(180, 0.15))
+	bank.Add("bonk_high", knock(240, 0.12))
+	bank.Add("whoosh", audio.Noise(0.3, 0.5))
+
+	bank.On("box_impact", 1, "thud_low", "thud_high")
+	bank.On("ball_impact", 0.7, "bonk_low", "bonk_high")
+	bank.On("throw", 0.4, "whoosh")
+}
+
+type game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	texture  *ebiten.Image
+	camera   *camera.Camera
+
+	world   *physics.World
+	objects []*object
+	// graph is the scene the bodies are drawn as nodes of, beside the floor, and meshes are the meshes of its nodes
+	graph  *scene.Scene
+	floor  *scene.Node
+	meshes map[*scene.Node]*placed
+	// trail draws the trails, trails are the IDs of their meshes this frame
+	trail  *render.Material
+	trails map[uint32]bool
+	// time is the seconds the demo has run, the clock of the trails
+	time   float
+	random *rand.Rand
+
+	// step_time is how long the last physics step took
+	step_time time.Duration
+}
+
+func (self *game) add(body *physics.Body) {
+	self.world.Add(body)
+	o := new_object(body, materials[self.random.Intn(floor_row)])
+	self.objects = append(self.objects, o)
+	self.graph.Nodes = append(self.graph.Nodes, o.node)
+}
+
+func (self *game) clear() {
+	self.world.Clear()
+	self.objects = self.objects[:0]
+	self.graph.Nodes = []*scene.Node{self.floor}
+	clear(self.meshes)
+}
+
+// stack builds a tower of `height` boxes, each turned a little from the one below.
+func (self *game) stack(height int) {
+	for i := 0; i < height; i++ {
+		b := physics.NewBox(vec3{1.5, 1, 1.5}, 1, vec3{0, 0.5 + float(i), 0})
+		b.Orientation = mgl32.QuatRotate(float(i)*0.15, vec3{0, 1, 0})
+		self.add(b)
+	}
+}
+
+// drop drops `count` random boxes and balls from above the middle of the floor.
+func (self *game) drop(count int) {
+	r := self.random
+	for i := 0; i < count; i++ {
+		p := vec3{r.Float32()*6 - 3, 6 + float(i)*0.8, r.Float32()*6 - 3}
+		var b *physics.Body
+		if r.Intn(2) == 0 {
+			b = physics.NewBox(vec3{0.5 + r.Float32(), 0.5 + r.Float32(), 0.5 + r.Float32()}, 1, p)
+			b.AngularVelocity = vec3{r.Float32()*4 - 2, r.Float32()*4 - 2, r.Float32()*4 - 2}
+		} else {
+			b = physics.NewSphere(0.3+r.Float32()*0.4, 1, p)
+			b.Restitution = 0.5
+		}
+		self.add(b)
+	}
+}
+
+func (self *game) register_commands() {
+	console.Register("drop", "[count]", "drops random boxes and balls", func(args console.Args) error {
+		count := 20
+		if len(args) > 0 {
+			n, err := args.Int(0)
+			if err != nil {
+				return err
+			}
+			count = n
+		}
+		self.drop(count)
+		return nil
+	})
+
+	console.Register("stack", "[height]", "builds a tower of boxes", func(args console.Args) error {
+		height := 6
+		if len(args) > 0 {
+			n, err := args.Int(0)
+			if err != nil {
+				return err
+			}
+			height = n
+		}
+		self.stack(height)
+		return nil
+	})
+
+	console.Register("clear_bodies", "", "removes every body", func(args console.Args) error {
+		self.clear()
+		return nil
+	})
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.camera.Update()
+
+	if input.JustPressed("throw") {
+		ball := physics.NewSphere(0.4, 2, self.camera.Position.Add(self.camera.Forward()))
+		ball.Velocity = self.camera.Forward().Mul(throw_speed.Float32())
+		ball.Restitution = 0.4
+		self.add(ball)
+		self.objects[len(self.objects)-1].trail = &trail.Trail{Width: 0.5, Spacing: 0.2}
+		audio.Default.Trigger("throw", 1)
+	}
+	if input.JustPressed("drop") {
+		self.drop(20)
+	}
+
+	w := self.world
+	w.Iterations = iterations.Int()
+	w.Gravity = vec3{0, -gravity.Float32(), 0}
+	w.SleepSpeed = 0
+	if sleeping.Bool() {
+		w.SleepSpeed = 0.1
+	}
+
+	// the scene remembers where the bodies were, to draw them between there and where the steps leave them
+	self.graph.Tick()
+	start := time.Now()
+	steps, dt := app.Steps()
+	for range steps {
+		w.Step(dt)
+	}
+	self.step_time = time.Since(start)
+	for _, o := range self.objects {
+		o.sync()
+	}
+
+	self.time += app.Delta()
+	for _, o := range self.objects {
+		if o.trail != nil {
+			o.trail.Lifetime = trail_time.Float32()
+			o.trail.Add(o.body.Position, self.time)
+			o.trail.Update(self.time)
+		}
+	}
+
+	audio.Listen(self.camera)
+	for _, i := range w.Impacts() {
+		event := "box_impact"
+		if i.A.Shape == physics.Sphere {
+			event = "ball_impact"
+		}
+		audio.Default.TriggerAt(event, i.Point, min(i.Speed/loud_speed.Float32(), 1))
+	}
+	return nil
+}
+
+// place moves the mesh of `n` to where `transform` puts it and returns it, textured with the row its material names,
+// building it the first time. A node whose recipe doesn't build has none.
+func (self *game) place(n *scene.Node, transform mgl32.Mat4) *mesh.Mesh {
+	p, ok := self.meshes[n]
+	if !ok {
+		m, err := scene.BuildMesh(n.Mesh)
+		if err != nil {
+			logger.Warnf("node %s: %v", n.Name, err)
+		} else {
+			p = &placed{mesh: m, local: slices.Clone(m.Points), texcoords: m.Texcoords, rows: make(map[int][]vec2)}
+		}
+		self.meshes[n] = p
+	}
+	if p == nil {
+		return nil
+	}
+	row := asleep_row
+	if m := self.graph.Materials[n.Material]; m != nil {
+		row = int(m.Params["row"].X())
+	}
+	if p.rows[row] == nil {
+		p.rows[row] = texture_row(p.texcoords, row)
+	}
+	p.mesh.Texcoords = p.rows[row]
+	for i, q := range p.local {
+		p.mesh.Points[i] = mgl32.TransformCoordinate(q, transform)
+	}
+	p.mesh.ComputeBounds()
+	return p.mesh
+}
+
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+// draw draws the sorted triangles in runs, those of trails with their material and the rest with the texture, so
+// the trails stay in the order of the sort.
+func (self *game) draw(screen *ebiten.Image, triangles []pipeline.Triangle) {
+	self.trail.Uniforms["Time"] = self.time
+	for len(triangles) > 0 {
+		trail := self.trails[triangles[0].Mesh]
+		n := 1
+		for n < len(triangles) && self.trails[triangles[n].Mesh] == trail {
+			n++
+		}
+		if trail {
+			self.renderer.DrawMaterial(screen, self.trail, triangles[:n])
+		} else {
+			self.renderer.DrawTriangles(screen, self.texture, triangles[:n])
+		}
+		triangles = triangles[n:]
+	}
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 200)
+	ctx.SetView(self.camera.View())
+
+	screen.Fill(clear_color.Color())
+	self.graph.WalkBetween(app.Interpolation(), func(n *scene.Node, world mgl32.Mat4) {
+		if m := self.place(n, world); m != nil {
+			ctx.PushMesh(m)
+		}
+	})
+	clear(self.trails)
+	for _, o := range self.objects {
+		if o.trail == nil {
+			continue
+		}
+		if m := o.trail.Mesh(self.camera.Position); m != nil {
+			self.trails[m.ID()] = true
+			ctx.PushMesh(m)
+		}
+	}
+	ctx.Sort()
+	triangles := len(ctx.Triangles())
+	self.draw(screen, ctx.Triangles())
+	ctx.Reset()
+
+	stats := self.world.Stats()
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Bodies: %d, awake: %d, contacts: %d, step: %v", stats.Bodies, stats.Awake, stats.Contacts, self.step_time.Round(time.Microsecond)), 0, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Triangles: %d", triangles), 0, 28)
+	ebitenutil.DebugPrintAt(screen, "Space drops 20 bodies, right click throws a ball, drag to look, WASD to move", 0, 42)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/csg"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{40, 44, 52, 255}, cvar.Persist, "background color")
+	rings       = cvar.Int("csg_rings", 12, 0, "rings of the sphere, more is rounder and slower").Range(3, 64)
+	segments    = cvar.Int("csg_segments", 16, 0, "segments of the sphere around").Range(3, 64)
+	radius      = cvar.Float("csg_radius", 1.3, 0, "radius of the sphere").Range(0.1, 3)
+	speed       = cvar.Float("csg_speed", 0.6, 0, "how fast the sphere wanders through the cube").Range(0, 5)
+)
+
+type operation int
+
+const (
+	subtract operation = iota
+	intersect
+	union
+	operations
+)
+
+var operation_names = [operations]string{"cube - sphere", "cube & sphere", "cube | sphere"}
+
+// the texture is two checkerboards one above the other, the top one for the cube and the bottom one for the sphere,
+// so the surfaces the sphere leaves behind show which solid they came from
+const rows = 2
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+	input.Bind("operation", input.Key(ebiten.KeyTab))
+
+	ebiten.SetWindowTitle("011-csg")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a renderer which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	cube := mesh.Box(vec3{2, 2, 2})
+	cube.Texcoords = texture_row(cube.Texcoords, 0)
+
+	return &game{
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		texture:  new_texture(),
+		camera:   camera.New(vec3{3, 2.5, 4}, vec3{}),
+		cube:     csg.FromMesh(cube),
+	}, nil
+}
+
+func new_texture() *ebiten.Image {
+	const size = 64
+	img := image.NewRGBA(image.Rect(0, 0, size, size*rows))
+	cube := texgen.Checker(size, 4, color.RGBA{220, 200, 170, 255}, color.RGBA{160, 140, 110, 255})
+	sphere := texgen.Checker(size, 8, color.RGBA{90, 170, 230, 255}, color.RGBA{50, 100, 160, 255})
+	draw.Draw(img, image.Rect(0, 0, size, size), cube, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, size, size, 2*size), sphere, image.Point{}, draw.Src)
+	return ebiten.NewImageFromImage(img)
+}
+
+// texture_row squeezes texture coordinates into one row of the texture.
+func texture_row(texcoords []vec2, row int) []vec2 {
+	out := make([]vec2, len(texcoords))
+	for i, t := range texcoords {
+		out[i] = vec2{t.X(), (float(row) + t.Y()) / rows}
+	}
+	return out
+}
+
+type game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	texture  *ebiten.Image
+	camera   *camera.Camera
+
+	cube      *csg.Solid
+	operation operation
+	time      float
+
+	// result is the mesh of the last operation, or nil when it failed
+	result *mesh.Mesh
+	err    error
+	// took is how long the last operation took, from the sphere's mesh to the result's
+	took time.Duration
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.camera.Update()
+	if input.JustPressed("operation") {
+		self.operation = (self.operation + 1) % operations
+	}
+	self.time += speed.Float32() * app.Delta()
+
+	// the sphere wanders about the middle of the cube and pokes out of its sides
+	t := float64(self.time)
+	at := vec3{float(math.Sin(t)) * 1.2, float(math.Sin(t*0.7)) * 0.6, float(math.Cos(t*1.3)) * 0.8}
+
+	start := time.Now()
+	sphere := mesh.UVSphere(radius.Float32(), rings.Int(), segments.Int())
+	sphere.Texcoords = texture_row(sphere.Texcoords, 1)
+	moved := csg.FromMesh(sphere).Transform(mgl32.Translate3D(at.X(), at.Y(), at.Z()))
+	var solid *csg.Solid
+	switch self.operation {
+	case subtract:
+		solid = csg.Subtract(self.cube, moved)
+	case intersect:
+		solid = csg.Intersect(self.cube, moved)
+	default:
+		solid = csg.Union(self.cube, moved)
+	}
+	self.result, self.err = solid.Mesh()
+	self.took = time.Since(start)
+	return nil
+}
+
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 100)
+	ctx.SetView(self.camera.View())
+
+	screen.Fill(clear_color.Color())
+	var triangles int
+	if self.result != nil {
+		ctx.PushMesh(self.result)
+		ctx.Sort()
+		triangles = len(self.result.Triangles)
+		self.renderer.DrawTriangles(screen, self.texture, ctx.Triangles())
+		ctx.Reset()
+	}
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%s: %d triangles in %v", operation_names[self.operation], triangles, self.took.Round(time.Microsecond)), 0, 14)
+	if self.err != nil {
+		ebitenutil.DebugPrintAt(screen, self.err.Error(), 0, 28)
+	}
+	ebitenutil.DebugPrintAt(screen, "Tab changes the operation, drag to look, WASD to move", 0, game_height-16)
+}
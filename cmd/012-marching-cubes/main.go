@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/march"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/noise"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	// the field is sampled over a box this far from the origin each way
+	extent = 2
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{30, 30, 40, 255}, cvar.Persist, "background color")
+	resolution  = cvar.Int("mc_resolution", 32, 0, "samples of the field along each side of the box").Range(4, 64)
+	balls       = cvar.Int("mc_balls", 5, 0, "how many metaballs float about").Range(1, 16)
+	level       = cvar.Float("mc_level", 1, 0, "the value of the field the surface is drawn at").Range(0.1, 4)
+	speed       = cvar.Float("mc_speed", 1, 0, "how fast the field changes").Range(0, 5)
+)
+
+type field int
+
+const (
+	metaballs field = iota
+	terrain
+	fields
+)
+
+var field_names = [fields]string{"metaballs", "terrain"}
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+	input.Bind("field", input.Key(ebiten.KeyTab))
+	input.Bind("shading", input.Key(ebiten.KeyN))
+
+	ebiten.SetWindowTitle("012-marching-cubes")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a renderer which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	return &game{
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		texture:  ebiten.NewImageFromImage(texgen.Checker(64, 8, color.RGBA{230, 170, 90, 255}, color.RGBA{150, 90, 50, 255})),
+		camera:   camera.New(vec3{0, 2, 5.5}, vec3{}),
+		normals:  true,
+	}, nil
+}
+
+type game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	texture  *ebiten.Image
+	camera   *camera.Camera
+
+	grid    *march.Grid
+	surface *mesh.Mesh
+	field   field
+	time    float
+	// normals shades by the normals of the triangles rather than the texture, the renderer has no lights
+	normals bool
+
+	// how long the last sampling and surface took and how many allocations they made between them
+	sampled, marched time.Duration
+	allocations      uint64
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.camera.Update()
+	if input.JustPressed("field") {
+		self.field = (self.field + 1) % fields
+	}
+	if input.JustPressed("shading") {
+		self.normals = !self.normals
+	}
+	self.time += speed.Float32() * app.Delta()
+
+	// the grid is only made again when its resolution changes, everything else reuses its buffers
+	n := resolution.Int()
+	if self.grid == nil || self.grid.Size[0] != n {
+		self.grid = march.NewGrid([3]int{n, n, n}, vec3{-extent, -extent, -extent}, vec3{extent, extent, extent})
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	switch self.field {
+	case metaballs:
+		self.sample_metaballs()
+	default:
+		self.sample_terrain()
+	}
+	self.sampled = time.Since(start)
+	start = time.Now()
+	self.surface = self.grid.Surface(level.Float32())
+	self.marched = time.Since(start)
+	runtime.ReadMemStats(&after)
+	self.allocations = after.Mallocs - before.Mallocs
+	return nil
+}
+
+// sample_metaballs fills the grid with balls drifting along Lissajous curves, each adding 1/r² so they melt
+// together where they come close.
+func (self *game) sample_metaballs() {
+	var centers [16]vec3
+	count := balls.Int()
+	for i := range count {
+		t := float64(self.time) + float64(i)*1.7
+		f := 0.5 + float64(i)*0.13
+		centers[i] = vec3{
+			float(math.Sin(t*f)) * 1.1,
+			float(math.Sin(t*f*1.3+1)) * 1.1,
+			float(math.Cos(t*f*0.7+2)) * 1.1,
+		}
+	}
+	self.grid.Sample(func(p vec3) float {
+		var sum float
+		for _, c := range centers[:count] {
+			sum += 0.3 / max(p.Sub(c).LenSqr(), 1e-4)
+		}
+		return sum
+	})
+}
+
+// sample_terrain fills the grid with hills of fBm scrolling past, floating above the bottom of the box with
+// overhangs where a second layer of noise carves into them.
+func (self *game) sample_terrain() {
+	t := self.time * 0.3
+	self.grid.Sample(func(p vec3) float {
+		height := 1.5 * noise.FBM(noise.Simplex, p.X()*0.5+t, p.Z()*0.5)
+		carve := 0.4 * noise.Simplex(p.X()+t*2, p.Y()*1.5+p.Z())
+		return level.Float32() + height + carve - p.Y() - 0.5
+	})
+}
+
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 100)
+	ctx.SetView(self.camera.View())
+
+	screen.Fill(clear_color.Color())
+	if self.surface != nil {
+		ctx.PushMesh(self.surface)
+		ctx.Sort()
+		if self.normals {
+			self.renderer.DrawDebug(screen, ctx.Triangles(), render.DebugNormals, func(id uint32) *mesh.Mesh {
+				if id == self.surface.ID() {
+					return self.surface
+				}
+				return nil
+			})
+		} else {
+			self.renderer.DrawTriangles(screen, self.texture, ctx.Triangles())
+		}
+		ctx.Reset()
+	}
+
+	full := ""
+	if self.grid != nil && self.grid.Full {
+		full = ", cut short"
+	}
+	triangles := 0
+	if self.surface != nil {
+		triangles = len(self.surface.Triangles)
+	}
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%s: %d triangles%s", field_names[self.field], triangles, full), 0, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("sampled in %v, marched in %v, %d allocations", self.sampled.Round(time.Microsecond), self.marched.Round(time.Microsecond), self.allocations), 0, 28)
+	ebitenutil.DebugPrintAt(screen, "Tab changes the field, N the shading, drag to look, WASD to move", 0, game_height-16)
+}
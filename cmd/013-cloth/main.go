@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cloth"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	columns = 32
+	rows    = 32
+	spacing = 0.1
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{110, 120, 140, 255}, cvar.Persist, "background color")
+	iterations  = cvar.Int("cloth_iterations", 8, 0, "how often the constraints are gone over per step").Range(1, 40)
+	bend        = cvar.Float("cloth_bend", 0.3, 0, "how stiffly the cloth resists folding").Range(0, 1)
+	wind        = cvar.Float("cloth_wind", 2, 0, "how hard the wind blows through the cloth").Range(0, 20)
+	swing       = cvar.Float("cloth_swing", 1, 0, "how fast the ball swings through the cloth").Range(0, 5)
+)
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+	input.Bind("release", input.Key(ebiten.KeySpace))
+	input.Bind("reset", input.Key(ebiten.KeyR))
+
+	ebiten.SetWindowTitle("013-cloth")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the game, app.RunLoader shows a renderer which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	game := &game{
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		texture:  new_texture(),
+		camera:   camera.New(vec3{2.5, 0.5, 4.5}, vec3{0, -1, 0}),
+	}
+	game.reset()
+
+	return game, nil
+}
+
+// new_texture is two checkerboards, the top one for the front of the cloth, the bottom one for its back and the
+// ball.
+func new_texture() *ebiten.Image {
+	const size = 64
+	img := image.NewRGBA(image.Rect(0, 0, size, 2*size))
+	front := texgen.Checker(size, 8, color.RGBA{200, 60, 70, 255}, color.RGBA{240, 220, 200, 255})
+	back := texgen.Checker(size, 8, color.RGBA{60, 80, 160, 255}, color.RGBA{200, 210, 230, 255})
+	draw.Draw(img, image.Rect(0, 0, size, size), front, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, size, size, 2*size), back, image.Point{}, draw.Src)
+	return ebiten.NewImageFromImage(img)
+}
+
+type game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	texture  *ebiten.Image
+	camera   *camera.Camera
+
+	cloth *cloth.Cloth
+	// surface is the cloth's mesh, which Mesh makes again in place
+	surface *mesh.Mesh
+	ball    *mesh.Mesh
+	// shape is the points of the ball around the origin, it's moved to where it swings to every tick
+	shape []vec3
+	time  float
+
+	// step is how long the last step of the cloth and its mesh took
+	step time.Duration
+}
+
+// reset hangs a new curtain from its top corners and every fourth point between them.
+func (self *game) reset() {
+	c := cloth.New(columns, rows, spacing, vec3{-columns * spacing / 2, 1, 0})
+	for column := 0; column < columns; column += 4 {
+		c.Pinned[c.Index(column, 0)] = true
+	}
+	c.Pinned[c.Index(columns-1, 0)] = true
+	// the back of the mesh has its own texcoords, which go into the bottom half of the texture
+	m := c.Mesh()
+	for i := len(c.Points); i < len(m.Texcoords); i++ {
+		m.Texcoords[i] = vec2{m.Texcoords[i].X(), 0.5 + m.Texcoords[i].Y()/2}
+	}
+	for i := range len(c.Points) {
+		m.Texcoords[i] = vec2{m.Texcoords[i].X(), m.Texcoords[i].Y() / 2}
+	}
+	self.cloth = c
+	self.surface = m
+
+	self.ball = mesh.UVSphere(0.5, 10, 14)
+	for i, t := range self.ball.Texcoords {
+		self.ball.Texcoords[i] = vec2{t.X(), 0.5 + t.Y()/2}
+	}
+	self.shape = append([]vec3(nil), self.ball.Points...)
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.camera.Update()
+	if input.JustPressed("reset") {
+		self.reset()
+	}
+	if input.JustPressed("release") {
+		clear(self.cloth.Pinned)
+	}
+
+	self.time += app.Delta()
+
+	// the ball swings like a pendulum through the middle of the curtain
+	t := float64(self.time * swing.Float32())
+	at := vec3{float(math.Sin(t*0.6)) * 0.6, -1.4 + 0.3*float(math.Cos(t*1.2)), float(math.Sin(t)) * 1.2}
+	for i, p := range self.shape {
+		self.ball.Points[i] = p.Add(at)
+	}
+	self.ball.ComputeBounds()
+
+	c := self.cloth
+	c.Iterations = iterations.Int()
+	c.Stiffness[cloth.Bend] = bend.Float32()
+	gust := wind.Float32() * (0.6 + 0.4*float(math.Sin(float64(self.time)*0.7)))
+	c.Gravity = vec3{gust * 0.3, -9.81, -gust}
+	c.Spheres = append(c.Spheres[:0], cloth.Sphere{Center: at, Radius: 0.5})
+
+	start := time.Now()
+	steps, dt := app.Steps()
+	for range steps {
+		c.Step(dt)
+	}
+	c.Mesh()
+	self.step = time.Since(start)
+	return nil
+}
+
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 100)
+	ctx.SetView(self.camera.View())
+
+	screen.Fill(clear_color.Color())
+	ctx.PushMesh(self.surface)
+	ctx.PushMesh(self.ball)
+	ctx.Sort()
+	self.renderer.DrawTriangles(screen, self.texture, ctx.Triangles())
+	ctx.Reset()
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%d points, step and mesh: %v", len(self.cloth.Points), self.step.Round(time.Microsecond)), 0, 14)
+	ebitenutil.DebugPrintAt(screen, "Space lets the curtain go, R hangs it again, drag to look, WASD to move", 0, game_height-16)
+}
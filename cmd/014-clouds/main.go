@@ -0,0 +1,243 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/clouds"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/noise"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pip"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sky"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	// far is the far plane, and as far as the distance image goes
+	far = 100
+	// terrain_size is how wide the valley is, the clouds drift across it and come back around
+	terrain_size = 16
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+var (
+	soft     = cvar.Bool("clouds_soft", true, 0, "fade the puffs out where they meet the scene")
+	softness = cvar.Float("clouds_softness", 0.6, cvar.Persist, "how far in front of the scene puffs are drawn whole").Range(0.01, 4)
+	opacity  = cvar.Float("clouds_opacity", 0.8, cvar.Persist, "how dense the puffs are").Range(0, 2)
+	wind     = cvar.Float("clouds_wind", 0.3, 0, "how fast the clouds drift, in units a second").Range(-4, 4)
+	fog      = cvar.Bool("clouds_fog", true, 0, "draw the ground fog")
+)
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+
+	ebiten.SetWindowTitle("014-clouds")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// height is the height of the valley at x and z, hills around a dip in the middle where the fog gathers.
+func height(x, z float) float {
+	bowl := (x*x + z*z) / (terrain_size * terrain_size) * 6
+	return noise.FBM(noise.Perlin, x*0.2, z*0.2)*1.5 + bowl - 1
+}
+
+// load makes the game, app.RunLoader shows a renderer which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	skybox, err := sky.New()
+	if err != nil {
+		return nil, err
+	}
+	distance := ebiten.NewImage(game_width, game_height)
+	puffs, err := render.NewCloudMaterial(distance, far)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &game{
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		sky:      skybox,
+		camera:   camera.New(vec3{0, 1.5, 9}, vec3{0, 0.5, 0}),
+		terrain:  mesh.Heightfield(terrain_size, 64, height),
+		grass: &render.Material{Images: [4]*ebiten.Image{ebiten.NewImageFromImage(
+			texgen.Checker(64, 16, color.RGBA{70, 110, 60, 255}, color.RGBA{80, 125, 65, 255}),
+		)}},
+		distance: distance,
+		puffs:    puffs,
+	}
+
+	// a few clouds above the hills, and fog in the dip of the valley, a little above the ground
+	for i := range 6 {
+		at := vec3{(noise.Hash(float(i), 1) - 0.5) * terrain_size, 3.5 + noise.Hash(float(i), 2), (noise.Hash(float(i), 3) - 0.5) * terrain_size}
+		game.clouds = append(game.clouds, clouds.Cloud(at, vec3{1.8, 0.7, 1.2}, 24, float(i))...)
+	}
+	game.fog = clouds.Fog(vec3{0, -0.6, 0}, terrain_size*0.6, terrain_size*0.6, 0.7, func(x, z float) float {
+		return 0.1 * noise.Value(x, z)
+	}, 1)
+
+	return game, nil
+}
+
+type game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	sky      *sky.Sky
+	camera   *camera.Camera
+
+	terrain *mesh.Mesh
+	grass   *render.Material
+
+	// clouds drift with the wind, the fog stays put, field is whichever of them are drawn
+	clouds []clouds.Puff
+	fog    []clouds.Puff
+	field  clouds.Field
+	// distance is how far the scene is from the eye, which the puffs fade out against
+	distance *ebiten.Image
+	puffs    *render.Material
+	solid    []pipeline.Triangle
+	time     float
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.camera.Update()
+	self.sky.Update()
+	dt := app.Delta()
+	self.time += dt
+	for i := range self.clouds {
+		p := &self.clouds[i]
+		p.Center[0] += wind.Float32() * dt
+		// around the valley and back in the other side
+		if p.Center[0] > terrain_size/2+2 {
+			p.Center[0] -= terrain_size + 4
+		} else if p.Center[0] < -terrain_size/2-2 {
+			p.Center[0] += terrain_size + 4
+		}
+	}
+	return nil
+}
+
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, far)
+	ctx.SetView(self.camera.View())
+
+	// the sky doesn't know the pipeline flips Y, it's handed the flip in its matrix
+	view_projection := ctx.ProjectionMatrix().Mul4(ctx.ViewMatrix())
+	self.sky.Draw(screen, mgl32.Scale3D(1, -1, 1).Mul4(view_projection))
+
+	sun, sun_color, ambient := self.sky.SunDirection(), self.sky.SunColor(), self.sky.Ambient()
+	self.renderer.Lighting = &render.Lighting{
+		Lights: []render.Light{
+			{Kind: render.AmbientLight, Color: ambient},
+			{Kind: render.DirectionalLight, Color: sun_color, Direction: sun.Mul(-1)},
+		},
+		Meshes: func(id uint32) *mesh.Mesh {
+			if id == self.terrain.ID() {
+				return self.terrain
+			}
+			return nil
+		},
+	}
+
+	self.field.Puffs = append(self.field.Puffs[:0], self.clouds...)
+	if fog.Bool() {
+		self.field.Puffs = append(self.field.Puffs, self.fog...)
+	}
+	puffs := self.field.Mesh(ctx.ViewMatrix())
+
+	ctx.PushMesh(self.terrain)
+	ctx.Cull = pipeline.CullNone
+	ctx.PushMesh(puffs)
+	ctx.Cull = pipeline.CullBack
+	ctx.Sort()
+	triangles := ctx.Triangles()
+
+	// the puffs fade out against everything else, which is drawn into the distance image first
+	self.solid = self.solid[:0]
+	for _, t := range triangles {
+		if t.Mesh != puffs.ID() {
+			self.solid = append(self.solid, t)
+		}
+	}
+	self.distance.Fill(color.White)
+	self.renderer.DrawDistance(self.distance, self.solid, far)
+	pip.Show("distance", self.distance)
+
+	light := ambient.Add(sun_color.Mul(max(sun.Y(), 0)))
+	u := self.puffs.Uniforms
+	u["Light"] = []float32{min(light.X(), 1), min(light.Y(), 1), min(light.Z(), 1)}
+	u["Shade"] = []float32{ambient.X() * 1.2, ambient.Y() * 1.2, ambient.Z() * 1.2}
+	u["Opacity"] = opacity.Float32()
+	u["Softness"] = float(0.001)
+	if soft.Bool() {
+		u["Softness"] = softness.Float32()
+	}
+	u["Time"] = self.time
+
+	// the terrain and the puffs are drawn in depth order, a draw call for each run of either
+	for len(triangles) > 0 {
+		n := 1
+		for n < len(triangles) && triangles[n].Mesh == triangles[0].Mesh {
+			n++
+		}
+		if triangles[0].Mesh == puffs.ID() {
+			self.renderer.DrawMaterial(screen, self.puffs, triangles[:n])
+		} else {
+			self.renderer.DrawMaterial(screen, self.grass, triangles[:n])
+		}
+		triangles = triangles[n:]
+	}
+	ctx.Reset()
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%d puffs, soft: %v", len(self.field.Puffs), soft.Bool()), 0, 14)
+	ebitenutil.DebugPrintAt(screen, "drag to look, WASD to move, ` for the console", 0, game_height-16)
+}
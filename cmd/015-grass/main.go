@@ -0,0 +1,269 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/grass"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/noise"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/quality"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sky"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/targets"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	// field_size is how wide the meadow is, and field_patches how many patches of grass along each side of it
+	field_size    = 40
+	field_patches = 20
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var blades = flag.Int("blades", 400, "`blades` of grass in every patch, of 400 patches")
+
+var (
+	near       = cvar.Float("grass_near", 6, cvar.Persist, "how far every blade of grass is drawn").Range(0, 40)
+	far        = cvar.Float("grass_far", 16, cvar.Persist, "how far any grass is drawn").Range(1, 40)
+	wind       = cvar.Float("grass_wind", 0.6, 0, "how far the wind bends the grass, in blade heights").Range(0, 1.5)
+	wind_speed = cvar.Float("grass_wind_speed", 1.5, 0, "how fast the waves of wind roll across the meadow").Range(0, 8)
+	cull       = cvar.Bool("grass_cull", true, 0, "skip the patches out of view before making their meshes")
+	adaptive   = cvar.Bool("grass_adaptive", true, 0, "lower and raise the quality to hold grass_target_fps")
+	target_fps = cvar.Float("grass_target_fps", 60, cvar.Persist, "the frame rate the quality is lowered to hold").Range(10, 240)
+	fixed_tier = cvar.Int("grass_quality", len(quality.Tiers)-1, cvar.Persist, "the quality tier while grass_adaptive is off, 0 is the lowest").Range(0, float64(len(quality.Tiers)-1))
+	batch      = cvar.Bool("grass_batch", true, 0, "join runs of triangles drawn the same way which don't overlap the runs in between into one draw call")
+)
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+
+	ebiten.SetWindowTitle("015-grass")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// height is the height of the meadow at x and z, gently rolling.
+func height(x, z float) float {
+	return noise.FBM(noise.Perlin, x*0.08, z*0.08) * 2
+}
+
+// load makes the game, app.RunLoader shows a renderer which failed to build in the window instead.
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	skybox, err := sky.New()
+	if err != nil {
+		return nil, err
+	}
+	blade, err := render.NewGrassMaterial()
+	if err != nil {
+		return nil, err
+	}
+	aa, err := render.NewAntiAliaser()
+	if err != nil {
+		return nil, err
+	}
+
+	game := &game{
+		context:  &pipeline.Context{FlipY: true},
+		renderer: renderer,
+		sky:      skybox,
+		camera:   camera.New(vec3{0, 2.5, 12}, vec3{0, 1, 0}),
+		terrain:  mesh.Heightfield(field_size, 80, height),
+		soil: &render.Material{Images: [4]*ebiten.Image{ebiten.NewImageFromImage(
+			texgen.Checker(64, 32, color.RGBA{60, 80, 40, 255}, color.RGBA{66, 86, 44, 255}),
+		)}},
+		field:    grass.Scatter(field_size, field_patches, *blades, height, 1),
+		blade:    blade,
+		governor: quality.NewGovernor(target_fps.Float32()),
+		aa:       aa,
+	}
+	return game, nil
+}
+
+type game struct {
+	context  *pipeline.Context
+	renderer *render.Renderer
+	sky      *sky.Sky
+	camera   *camera.Camera
+
+	terrain *mesh.Mesh
+	soil    *render.Material
+	field   *grass.Field
+	blade   *render.Material
+	batch   render.Batch
+	time    float
+
+	// governor picks the quality the frames are drawn at, by how long the last took
+	governor *quality.Governor
+	last     time.Time
+	aa       *render.AntiAliaser
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.camera.Update()
+	self.sky.Update()
+	self.time += app.Delta()
+	return nil
+}
+
+// Pipeline exposes the pipeline to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	now := time.Now()
+	if !self.last.IsZero() {
+		self.governor.Target = target_fps.Float32()
+		self.governor.Frame(float(now.Sub(self.last).Seconds()))
+	}
+	self.last = now
+	tier := self.governor.Tier()
+	if !adaptive.Bool() {
+		tier = quality.Tiers[fixed_tier.Int()]
+	}
+	self.field.Density = tier.Particles
+
+	// the frame is drawn smaller and scaled up to the screen, smoothed at the smaller size
+	mode := render.AA(tier.AA)
+	if tier.Scale < 1 {
+		frame := targets.Default.Get(int(game_width*tier.Scale), int(game_height*tier.Scale))
+		self.aa.Draw(frame, mode, self.draw_scene)
+		op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+		op.GeoM.Scale(float64(1/tier.Scale), float64(1/tier.Scale))
+		screen.DrawImage(frame, op)
+		targets.Default.Put(frame)
+	} else {
+		self.aa.Draw(screen, mode, self.draw_scene)
+	}
+
+	s := self.field.Stats
+	total := s.Patches * *blades
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f", ebiten.ActualTPS(), ebiten.ActualFPS()), 0, 0)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Blades: %d of %d, patches culled: %d of %d", s.Blades, total,
+		s.Culled, s.Patches), 0, 14)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Triangles: %d, rejected meshes: %d", self.context.Stats.Triangles,
+		self.context.Stats.Rejected), 0, 28)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Draw calls: %d for %d runs", self.batch.Stats.DrawCalls,
+		self.batch.Stats.Draws), 0, 42)
+	governed := "fixed"
+	if adaptive.Bool() {
+		governed = fmt.Sprintf("holding %.0f FPS", self.governor.Target)
+	}
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Quality: %s (%s), scale %.2f, AA %v, %.0f%% of the blades", tier.Name,
+		governed, tier.Scale, mode, tier.Particles*100), 0, 56)
+}
+
+// draw_scene draws the meadow onto `target`, whose size the viewport is set to, smoothed by `mode`.
+func (self *game) draw_scene(target *ebiten.Image, mode render.AA) {
+	self.renderer.AntiAlias = mode == render.AAEbiten
+	ctx := self.context
+	w, h := target.Bounds().Dx(), target.Bounds().Dy()
+	ctx.SetViewport(0, 0, w, h)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 100)
+	ctx.SetView(self.camera.View())
+
+	// the sky doesn't know the pipeline flips Y, it's handed the flip in its matrix
+	view_projection := ctx.ProjectionMatrix().Mul4(ctx.ViewMatrix())
+	self.sky.Draw(target, mgl32.Scale3D(1, -1, 1).Mul4(view_projection))
+
+	sun, sun_color, ambient := self.sky.SunDirection(), self.sky.SunColor(), self.sky.Ambient()
+	self.renderer.Lighting = &render.Lighting{
+		Lights: []render.Light{
+			{Kind: render.AmbientLight, Color: ambient},
+			{Kind: render.DirectionalLight, Color: sun_color, Direction: sun.Mul(-1)},
+		},
+		Meshes: func(id uint32) *mesh.Mesh {
+			if id == self.terrain.ID() {
+				return self.terrain
+			}
+			return nil
+		},
+	}
+	// the blades stand every which way, they're lit as the ground is rather than by their faces
+	light := ambient.Add(sun_color.Mul(max(sun.Y(), 0)))
+	self.blade.Uniforms["Light"] = []float32{light.X(), light.Y(), light.Z()}
+
+	f := self.field
+	f.Near, f.Far = near.Float32(), max(far.Float32(), near.Float32()+0.1)
+	f.Sway = grass.Wind(vec2{0.8, 0.6}, wind.Float32(), wind_speed.Float32())
+	visible := ctx.Visible
+	if !cull.Bool() {
+		visible = nil
+	}
+	patches := f.Meshes(self.camera.Position, visible, self.time)
+
+	ctx.PushMesh(self.terrain)
+	ctx.Cull = pipeline.CullNone
+	for _, m := range patches {
+		ctx.PushMesh(m)
+	}
+	ctx.Cull = pipeline.CullBack
+	ctx.Sort()
+
+	// the ground and the grass are drawn in depth order, a draw for each run of either, which the batch joins
+	// into fewer draw calls
+	self.batch.Reset()
+	triangles := ctx.Triangles()
+	terrain := self.terrain.ID()
+	for len(triangles) > 0 {
+		ground := triangles[0].Mesh == terrain
+		n := 1
+		for n < len(triangles) && (triangles[n].Mesh == terrain) == ground {
+			n++
+		}
+		material := self.blade
+		if ground {
+			material = self.soil
+		}
+		self.batch.Add(target, material, triangles[:n])
+		if !batch.Bool() {
+			self.batch.Flush(self.renderer)
+		}
+		triangles = triangles[n:]
+	}
+	self.batch.Flush(self.renderer)
+	ctx.Reset()
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sky"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/states"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	menu_width  = 240
+	button_size = 32
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var transition_seconds = cvar.Float("launcher_transition", 0.6, cvar.Persist, "how many seconds a transition between screens takes").Range(0, 4)
+
+// plasma_shader is the plasma demo, sines of the position and time added up and mapped to colors.
+var plasma_shader = `
+//kage:unit pixels
+package main
+
+var Time float
+var Resolution vec2
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	p := (dst.xy*2 - Resolution) / Resolution.y * 3
+	v := sin(p.x+Time) + sin(p.y*1.3-Time*0.7) + sin(length(p+vec2(sin(Time*0.3), cos(Time*0.4))*2)*2)
+	return vec4(0.5+0.5*sin(v+vec3(0, 2.1, 4.2)), 1)
+}
+`
+
+func main() {
+	input.Bind("back", input.Key(ebiten.KeyEscape))
+
+	ebiten.SetWindowTitle("016-launcher")
+	ebiten.SetWindowSize(game_width, game_height)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the machine of screens, starting at the menu. app.RunLoader shows an effect which failed to build in
+// the window instead.
+func load() (ebiten.Game, error) {
+	fade, err := states.NewFade(color.Black)
+	if err != nil {
+		return nil, err
+	}
+	wipe, err := states.NewWipe(vec2{1, 0.2}, 48)
+	if err != nil {
+		return nil, err
+	}
+	skybox, err := sky.New()
+	if err != nil {
+		return nil, err
+	}
+	plasma, err := ebiten.NewShader([]byte(plasma_shader))
+	if err != nil {
+		return nil, err
+	}
+
+	effects := &effects{fade: fade, wipe: wipe}
+	menu := &menu{
+		effects: effects,
+		entries: []entry{
+			{"Sky", func() states.State { return &sky_demo{effects: effects, sky: skybox} }},
+			{"Plasma", func() states.State { return &plasma_demo{effects: effects, shader: plasma} }},
+		},
+	}
+	return states.New(game_width, game_height, menu), nil
+}
+
+// effects are the transitions between the screens.
+type effects struct {
+	fade, wipe *states.Effect
+}
+
+func (e *effects) fading() states.Transition {
+	return states.Transition{Effect: e.fade, Seconds: transition_seconds.Float32()}
+}
+
+func (e *effects) wiping() states.Transition {
+	return states.Transition{Effect: e.wipe, Seconds: transition_seconds.Float32()}
+}
+
+// entry is a demo of the menu, made anew every time it's picked.
+type entry struct {
+	name string
+	make func() states.State
+}
+
+// menu lists the demos, picking one wipes it in.
+type menu struct {
+	effects *effects
+	entries []entry
+	ui      *ui.Context
+	// picked is what was clicked while drawing, acted on in the next Update
+	picked func(m *states.Machine)
+}
+
+func (self *menu) Update(m *states.Machine) error {
+	if picked := self.picked; picked != nil {
+		self.picked = nil
+		picked(m)
+	}
+	if input.JustPressed("back") {
+		m.Pop(self.effects.fading())
+	}
+	return nil
+}
+
+func (self *menu) Draw(screen *ebiten.Image) {
+	if self.ui == nil {
+		self.ui = ui.NewContext()
+	}
+	screen.Fill(color.RGBA{24, 28, 36, 255})
+
+	rows := len(self.entries) + 2
+	x := (game_width - menu_width) / 2
+	y := (game_height - rows*button_size) / 2
+	self.ui.StartFrame(screen)
+	self.ui.Push(x, y, menu_width, rows*button_size, &ui.RowLayout{Height: button_size})
+	self.ui.Label("016-launcher", 0.5, 0.5)
+	for _, e := range self.entries {
+		self.ui.Button(ui.ButtonArgs{Text: e.name, AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: func() {
+			self.picked = func(m *states.Machine) { m.Push(e.make(), self.effects.wiping()) }
+		}}})
+	}
+	self.ui.Button(ui.ButtonArgs{Text: "Quit", AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: func() {
+		self.picked = func(m *states.Machine) { m.Pop(self.effects.fading()) }
+	}}})
+	self.ui.Pop()
+	self.ui.EndFrame()
+}
+
+// sky_demo turns slowly under the procedural sky.
+type sky_demo struct {
+	effects *effects
+	sky     *sky.Sky
+	time    float
+}
+
+func (self *sky_demo) Update(m *states.Machine) error {
+	self.sky.Update()
+	self.time += app.Delta()
+	if input.JustPressed("back") {
+		m.Push(&pause{effects: self.effects}, states.Transition{})
+	}
+	return nil
+}
+
+func (self *sky_demo) Draw(screen *ebiten.Image) {
+	angle := float64(self.time * 0.2)
+	forward := vec3{float(math.Sin(angle)), 0.25, float(-math.Cos(angle))}
+	view := mgl32.LookAtV(vec3{}, forward, vec3{0, 1, 0})
+	projection := mgl32.Perspective(mgl32.DegToRad(70), game_aspect, 0.1, 100)
+	// the sky expects the flip of the pipeline's FlipY in its matrix
+	self.sky.Draw(screen, mgl32.Scale3D(1, -1, 1).Mul4(projection.Mul4(view)))
+	ebitenutil.DebugPrintAt(screen, "Escape to pause", 0, 0)
+}
+
+// plasma_demo covers the screen with a plasma shader.
+type plasma_demo struct {
+	effects *effects
+	shader  *ebiten.Shader
+	time    float
+}
+
+func (self *plasma_demo) Update(m *states.Machine) error {
+	self.time += app.Delta()
+	if input.JustPressed("back") {
+		m.Push(&pause{effects: self.effects}, states.Transition{})
+	}
+	return nil
+}
+
+func (self *plasma_demo) Draw(screen *ebiten.Image) {
+	op := &ebiten.DrawRectShaderOptions{Uniforms: map[string]any{
+		"Time":       self.time,
+		"Resolution": []float32{game_width, game_height},
+	}}
+	screen.DrawRectShader(game_width, game_height, self.shader, op)
+	ebitenutil.DebugPrintAt(screen, "Escape to pause", 0, 0)
+}
+
+// pause is drawn over the demo it paused, which stands still under it.
+type pause struct {
+	effects *effects
+	ui      *ui.Context
+	picked  func(m *states.Machine)
+	// dim darkens the demo under the menu
+	dim *ebiten.Image
+}
+
+func (self *pause) Overlay() bool {
+	return true
+}
+
+func (self *pause) Update(m *states.Machine) error {
+	if picked := self.picked; picked != nil {
+		self.picked = nil
+		picked(m)
+	}
+	if input.JustPressed("back") {
+		m.Pop(states.Transition{})
+	}
+	return nil
+}
+
+func (self *pause) Draw(screen *ebiten.Image) {
+	if self.ui == nil {
+		self.ui = ui.NewContext()
+		self.dim = ebiten.NewImage(1, 1)
+		self.dim.Fill(color.RGBA{0, 0, 0, 160})
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(game_width, game_height)
+	screen.DrawImage(self.dim, op)
+
+	x := (game_width - menu_width) / 2
+	y := (game_height - 3*button_size) / 2
+	self.ui.StartFrame(screen)
+	self.ui.Push(x, y, menu_width, 3*button_size, &ui.RowLayout{Height: button_size})
+	self.ui.Label(fmt.Sprintf("paused (%s)", input.Describe("back")), 0.5, 0.5)
+	self.ui.Button(ui.ButtonArgs{Text: "Resume", AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: func() {
+		self.picked = func(m *states.Machine) { m.Pop(states.Transition{}) }
+	}}})
+	self.ui.Button(ui.ButtonArgs{Text: "Menu", AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: func() {
+		// off comes the pause, then the demo fades out to the menu
+		self.picked = func(m *states.Machine) {
+			m.Pop(states.Transition{})
+			m.Pop(self.effects.fading())
+		}
+	}}})
+	self.ui.Pop()
+	self.ui.EndFrame()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tiled"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tilemap"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+
+	// pan_speed is how many pixels of the screen the keys move the map by a second
+	pan_speed = 400
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var (
+	map_path = flag.String("map", "", "draw the Tiled map at `path`, a .tmx or a .tmj, instead of the one built in")
+
+	show_markers = cvar.Bool("tilemap_markers", false, 0, "outlines the shapes of the object layers, hidden layers too")
+	min_zoom     = cvar.Float("tilemap_min_zoom", 0.25, cvar.Persist, "how far the wheel zooms out").Range(0.05, 1)
+)
+
+// assets is the map built in, its external tileset and the images of both tilesets.
+//
+//go:embed map.tmx terrain.tsx tiles.png props.png
+var assets embed.FS
+
+func main() {
+	flag.Parse()
+
+	input.Bind("pan", input.Mouse(ebiten.MouseButtonLeft))
+	input.Bind("move_up", input.Key(ebiten.KeyW), input.Key(ebiten.KeyUp), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, -1))
+	input.Bind("move_down", input.Key(ebiten.KeyS), input.Key(ebiten.KeyDown), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, 1))
+	input.Bind("move_left", input.Key(ebiten.KeyA), input.Key(ebiten.KeyLeft), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, -1))
+	input.Bind("move_right", input.Key(ebiten.KeyD), input.Key(ebiten.KeyRight), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, 1))
+
+	ebiten.SetWindowTitle("017-tilemap")
+	ebiten.SetWindowSize(game_width, game_height)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load loads the map and its tilesets, app.RunLoader shows a map which failed to load in the window instead.
+func load() (ebiten.Game, error) {
+	var fsys fs.FS = assets
+	name := "map.tmx"
+	if *map_path != "" {
+		fsys, name = os.DirFS(filepath.Dir(*map_path)), filepath.Base(*map_path)
+	}
+	m, err := tiled.Load(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	renderer, err := tilemap.New(m, fsys)
+	if err != nil {
+		return nil, err
+	}
+	self := &game{renderer: renderer, view: view{Zoom: 2}}
+	// start over the spawn point when the map has one, otherwise over its middle
+	self.view.Center = vec2{float(m.Width * m.TileWidth / 2), float(m.Height * m.TileHeight / 2)}
+	if m.Orientation == tiled.Isometric {
+		self.view.Center = renderer.Projection.ToScreen(vec3{float(m.Width) / 2, float(m.Height) / 2, 0})
+	}
+	for _, l := range m.Layers {
+		for _, o := range l.Objects {
+			if o.Type == "spawn" {
+				self.view.Center = renderer.Pixels(vec2{o.X, o.Y})
+			}
+		}
+	}
+	return self, nil
+}
+
+// view is where the map is looked at: the pixel of the map at the center of the screen, and how many pixels of the
+// screen a pixel of the map covers.
+type view struct {
+	Center vec2  `json:"center"`
+	Zoom   float `json:"zoom"`
+}
+
+// geom moves the pixels of the map onto the screen.
+func (v view) geom() ebiten.GeoM {
+	var g ebiten.GeoM
+	g.Translate(float64(-v.Center[0]), float64(-v.Center[1]))
+	g.Scale(float64(v.Zoom), float64(v.Zoom))
+	g.Translate(game_width/2, game_height/2)
+	return g
+}
+
+type game struct {
+	renderer *tilemap.Renderer
+	view     view
+	elapsed  time.Duration
+
+	// dragging is whether the map is being dragged, dragged where the cursor was the tick before
+	dragging bool
+	dragged  vec2
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	self.elapsed += time.Duration(float64(app.Delta()) * float64(time.Second))
+
+	x, y := input.CursorPosition()
+	cursor := vec2{float(x), float(y)}
+	if input.Pressed("pan") {
+		if self.dragging {
+			self.view.Center = self.view.Center.Sub(cursor.Sub(self.dragged).Mul(1 / self.view.Zoom))
+		}
+		self.dragging, self.dragged = true, cursor
+	} else {
+		self.dragging = false
+	}
+	move := vec2{input.Axis("move_left", "move_right"), input.Axis("move_up", "move_down")}
+	self.view.Center = self.view.Center.Add(move.Mul(pan_speed / float(ebiten.TPS()) / self.view.Zoom))
+
+	// the wheel zooms around the cursor, which stays over the same pixel of the map
+	if _, wheel := input.Wheel(); wheel != 0 {
+		offset := cursor.Sub(vec2{game_width / 2, game_height / 2})
+		under := self.view.Center.Add(offset.Mul(1 / self.view.Zoom))
+		zoom := self.view.Zoom * float(1+0.1*max(min(wheel, 5), -5))
+		self.view.Zoom = max(min(zoom, 8), min_zoom.Float32())
+		self.view.Center = under.Sub(offset.Mul(1 / self.view.Zoom))
+	}
+	return nil
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{20, 24, 30, 255})
+
+	r := self.renderer
+	r.Batch.ResetStats()
+	geom := self.view.geom()
+	r.Draw(screen, geom, self.elapsed)
+	if show_markers.Bool() {
+		for _, l := range r.Map.Layers {
+			if l.Kind == tiled.ObjectLayer {
+				r.Outline(screen, l, geom, color.RGBA{255, 220, 80, 255})
+			}
+		}
+	}
+
+	stats := r.Batch.Stats()
+	title := r.Map.Properties["title"]
+	if title == "" {
+		title = "017-tilemap"
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf(
+		"%s\n%dx%d tiles, %d layers, %d tilesets\nsprites: %d in %d draw calls\nzoom: %.2f\ndrag or move to pan, the wheel zooms",
+		title, r.Map.Width, r.Map.Height, len(r.Map.Layers), len(r.Map.Tilesets),
+		stats.Sprites, stats.DrawCalls, self.view.Zoom,
+	))
+}
+
+// HotState keeps the view across restarts by cmd/dev.
+func (self *game) HotState() any {
+	return self.view
+}
+
+func (self *game) RestoreHotState(data []byte) error {
+	return json.Unmarshal(data, &self.view)
+}
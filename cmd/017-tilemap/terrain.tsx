@@ -0,0 +1,21 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" tiledversion="1.10.2" name="terrain" tilewidth="16" tileheight="16" tilecount="32" columns="8">
+ <image source="tiles.png" width="128" height="64"/>
+ <tile id="4" type="water">
+  <animation>
+   <frame tileid="4" duration="250"/>
+   <frame tileid="5" duration="250"/>
+   <frame tileid="6" duration="250"/>
+   <frame tileid="7" duration="250"/>
+  </animation>
+ </tile>
+ <tile id="10" type="wall"/>
+ <tile id="16">
+  <animation>
+   <frame tileid="16" duration="120"/>
+   <frame tileid="17" duration="120"/>
+   <frame tileid="18" duration="120"/>
+   <frame tileid="19" duration="120"/>
+  </animation>
+ </tile>
+</tileset>
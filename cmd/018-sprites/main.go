@@ -0,0 +1,317 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"image/color"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sprite"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/spritesheet"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	// walk_speed is how many pixels a second the creatures walk
+	walk_speed = 40
+	// world_scale is how many pixels of the 2D view make a unit of the 3D one
+	world_scale = 40
+	// creature_height is how tall a creature stands in the 3D view
+	creature_height = 0.8
+	// max_puffs is how many puffs of dust are kicked up at most at once
+	max_puffs = 2000
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{110, 120, 140, 255}, cvar.Persist, "background color")
+	walkers     = cvar.Int("sprites_walkers", 300, 0, "how many creatures wander around").Range(1, 10000)
+	puffs_on    = cvar.Bool("sprites_puffs", true, 0, "footsteps kick up puffs of dust, fired by an event of the walk clip")
+)
+
+// assets is the sheet of the creature, as Aseprite exports it, and its image.
+//
+//go:embed creature.json creature.png
+var assets embed.FS
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+	input.Bind("view", input.Key(ebiten.KeyTab))
+
+	ebiten.SetWindowTitle("018-sprites")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load loads the sheet, app.RunLoader shows a sheet which failed to load in the window instead.
+func load() (ebiten.Game, error) {
+	sheet, err := sprite.LoadSheet(assets, "creature.json")
+	if err != nil {
+		return nil, err
+	}
+	walk, idle, dust := sheet.Clips["walk"], sheet.Clips["idle"], sheet.Clips["dust"]
+	if walk == nil || idle == nil || dust == nil {
+		return nil, fmt.Errorf("the sheet has no walk, idle and dust tags")
+	}
+	// the feet land on the second and fifth frames
+	walk.On(1, "step").On(4, "step")
+
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	world := vec2{game_width, game_height}.Mul(1.0 / world_scale)
+	floor := mesh.Plane(max(world[0], world[1]) + 4)
+	floor.Transform(mgl32.Translate3D(world[0]/2, 0, world[1]/2))
+
+	return &game{
+		sheet:      sheet,
+		walk:       walk,
+		idle:       idle,
+		dust:       dust,
+		context:    &pipeline.Context{FlipY: true},
+		renderer:   renderer,
+		camera:     camera.New(vec3{world[0] / 2, 6, world[1] + 6}, vec3{world[0] / 2, 0, world[1] / 2}),
+		floor:      floor,
+		floor_tex:  ebiten.NewImageFromImage(texgen.Checker(256, 16, color.RGBA{96, 140, 80, 255}, color.RGBA{86, 128, 72, 255})),
+		billboards: &spritesheet.Billboards{Sheet: sheet.Sheet},
+	}, nil
+}
+
+// creature wanders from place to place, standing idle for a while at each.
+type creature struct {
+	at, target vec2
+	// wait is how many seconds it stands before it walks on
+	wait   float
+	left   bool
+	player spritesheet.Player
+}
+
+// puff is dust kicked up by a footstep, gone once its clip played.
+type puff struct {
+	at     vec2
+	player spritesheet.Player
+}
+
+type game struct {
+	sheet            *sprite.Sheet
+	walk, idle, dust *spritesheet.Clip
+
+	creatures []*creature
+	// order is the creatures from the back to the front, the order they're drawn in
+	order  []*creature
+	puffs  []puff
+	events []string
+	steps  int
+
+	batch sprite.Batch
+	// in3d shows the creatures standing on a floor in 3D, as billboards
+	in3d       bool
+	context    *pipeline.Context
+	renderer   *render.Renderer
+	camera     *camera.Camera
+	floor      *mesh.Mesh
+	floor_tex  *ebiten.Image
+	billboards *spritesheet.Billboards
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+// Pipeline exposes the pipeline of the 3D view to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Update() error {
+	if input.JustPressed("view") {
+		self.in3d = !self.in3d
+	}
+	if self.in3d {
+		self.camera.Update()
+	}
+
+	for len(self.creatures) < walkers.Int() {
+		c := &creature{at: vec2{rand.Float32() * game_width, rand.Float32() * game_height}, wait: rand.Float32() * 2}
+		c.player.Sheet = self.sheet.Sheet
+		self.creatures = append(self.creatures, c)
+	}
+	self.creatures = self.creatures[:walkers.Int()]
+
+	dt := app.Delta()
+	elapsed := time.Duration(float64(dt) * float64(time.Second))
+	for _, c := range self.creatures {
+		if c.wait > 0 {
+			c.wait -= dt
+			c.player.Play(self.idle)
+			if c.wait <= 0 {
+				c.target = vec2{rand.Float32() * game_width, rand.Float32() * game_height}
+			}
+		} else {
+			to := c.target.Sub(c.at)
+			if step := walk_speed * dt; to.Len() <= step {
+				c.at = c.target
+				c.wait = 0.5 + rand.Float32()*3
+			} else {
+				c.at = c.at.Add(to.Normalize().Mul(step))
+				c.left = to[0] < 0
+			}
+			c.player.Play(self.walk)
+		}
+
+		// the footsteps the walk clip fires kick up dust behind the creature
+		self.events = c.player.Update(elapsed, self.events[:0])
+		for _, e := range self.events {
+			if e != "step" {
+				continue
+			}
+			self.steps++
+			if puffs_on.Bool() && len(self.puffs) < max_puffs {
+				p := puff{at: c.at}
+				p.player.Sheet = self.sheet.Sheet
+				p.player.Play(self.dust)
+				self.puffs = append(self.puffs, p)
+			}
+		}
+	}
+
+	kept := self.puffs[:0]
+	for _, p := range self.puffs {
+		p.player.Update(elapsed, nil)
+		if !p.player.Done() {
+			kept = append(kept, p)
+		}
+	}
+	self.puffs = kept
+	return nil
+}
+
+// place is where frame `frame` goes to stand on `at`, mirrored when it faces left.
+func (self *game) place(frame int, at vec2, left bool) ebiten.GeoM {
+	size := self.sheet.Frames[frame].Size
+	var g ebiten.GeoM
+	g.Translate(-float64(size.X)/2, -float64(size.Y))
+	if left {
+		g.Scale(-1, 1)
+	}
+	g.Translate(float64(at[0]), float64(at[1]))
+	return g
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	self.order = append(self.order[:0], self.creatures...)
+	slices.SortFunc(self.order, func(a, b *creature) int {
+		switch {
+		case a.at[1] < b.at[1]:
+			return -1
+		case a.at[1] > b.at[1]:
+			return 1
+		}
+		return 0
+	})
+
+	var hud string
+	if self.in3d {
+		hud = self.draw_3d(screen)
+	} else {
+		hud = self.draw_2d(screen)
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f\n%d creatures, %d puffs, %d steps\n%s\n%s for the other view",
+		ebiten.ActualTPS(), ebiten.ActualFPS(), len(self.creatures), len(self.puffs), self.steps, hud, input.Describe("view")))
+}
+
+// draw_2d draws the creatures through a sprite batch, which makes a single draw call of them.
+func (self *game) draw_2d(screen *ebiten.Image) string {
+	screen.Fill(color.RGBA{96, 140, 80, 255})
+	b := &self.batch
+	b.ResetStats()
+	for i := range self.puffs {
+		p := &self.puffs[i]
+		s := self.sheet.Sprite(p.player.Frame(), self.place(p.player.Frame(), p.at, false))
+		b.Add(screen, &s)
+	}
+	for _, c := range self.order {
+		frame := c.player.Frame()
+		s := self.sheet.Sprite(frame, self.place(frame, c.at, c.left))
+		b.Add(screen, &s)
+	}
+	b.Flush()
+	stats := b.Stats()
+	return fmt.Sprintf("2D: %d sprites in %d draw calls", stats.Sprites, stats.DrawCalls)
+}
+
+// draw_3d stands the creatures on a floor as billboards turned to the camera, impostors of them in 3D.
+func (self *game) draw_3d(screen *ebiten.Image) string {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 100)
+	ctx.SetView(self.camera.View())
+	screen.Fill(clear_color.Color())
+
+	ctx.PushMesh(self.floor)
+	ctx.Sort()
+	self.renderer.DrawTriangles(screen, self.floor_tex, ctx.Triangles())
+	ctx.Reset()
+
+	eye := self.camera.Position
+	b := self.billboards
+	b.Reset()
+	ground := func(at vec2) vec3 {
+		return vec3{at[0] / world_scale, 0, at[1] / world_scale}
+	}
+	for i := range self.puffs {
+		p := &self.puffs[i]
+		b.Add(ground(p.at), creature_height, p.player.Frame(), eye, false)
+	}
+	for _, c := range self.order {
+		b.Add(ground(c.at), creature_height, c.player.Frame(), eye, c.left)
+	}
+	triangles := 0
+	if m := b.Mesh(); m != nil {
+		ctx.PushMesh(m)
+		ctx.Sort()
+		triangles = len(ctx.Triangles())
+		self.renderer.DrawTriangles(screen, self.sheet.Image, ctx.Triangles())
+		ctx.Reset()
+	}
+	return fmt.Sprintf("3D: %d billboards, %d triangles drawn, drag to look, WASD to move", b.Len(), triangles)
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/iso"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sprite"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+
+	// map_size is how many cells the map is across
+	map_size = 20
+	// max_height is how tall a column of the map is at most
+	max_height = 4
+	// mover_speed is how many cells a second things move
+	mover_speed = 1.5
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var (
+	true_iso   = cvar.Bool("iso_true", false, 0, "true isometry, instead of the 2:1 dimetric projection of pixel art")
+	stand_sort = cvar.Bool("iso_sort", true, 0, "order what stands on the map by how the boxes stand, instead of by the depth of their centers")
+	movers_n   = cvar.Int("iso_movers", 16, 0, "how many things move around the map").Range(0, 64)
+)
+
+// layout is the map, '.' the ground, '~' water and the digits columns that many tall.
+const layout = `
+~~~~~~..............
+~~~~~...............
+~~~~....1111....44..
+~~~.....1..1....44..
+~~......1..1........
+~.......1111........
+....................
+..2.................
+..2......3...3......
+..2.................
+..2.................
+.........3...3......
+....................
+.............22222..
+....................
+...1................
+...12...............
+...123..............
+...1234.............
+....................`
+
+// water is the height of a cell of water, which is flat like the ground but nothing moves onto
+const water = -1
+
+// material is what a box is made of, which is its colors
+type material int
+
+const (
+	grass material = iota
+	pond
+	dirt
+	stone
+	rock
+	snow
+	wood
+	red
+	blue
+	gold
+	materials
+)
+
+var palette = [materials]color.RGBA{
+	grass: {104, 160, 76, 255},
+	pond:  {70, 120, 190, 255},
+	dirt:  {150, 110, 70, 255},
+	stone: {150, 150, 140, 255},
+	rock:  {110, 105, 110, 255},
+	snow:  {235, 240, 245, 255},
+	wood:  {170, 120, 60, 255},
+	red:   {200, 70, 60, 255},
+	blue:  {70, 100, 200, 255},
+	gold:  {230, 190, 60, 255},
+}
+
+// column_materials are what the columns of each height are made of
+var column_materials = [max_height + 1]material{grass, dirt, stone, rock, snow}
+
+// kind is a kind of thing which moves around, a box of a size
+type kind struct {
+	size     vec3
+	material material
+}
+
+// kinds are the things which move. The wall is long, and the depth of its center has it behind what's in front of it.
+var kinds = []kind{
+	{vec3{1.6, 0.7, 0.6}, wood},
+	{vec3{0.5, 2.6, 1.2}, red},
+	{vec3{0.8, 0.8, 0.8}, blue},
+	{vec3{0.5, 0.5, 1.8}, gold},
+}
+
+// mover goes back and forth along x or y, turning around when it runs into something.
+type mover struct {
+	kind     int
+	at       vec3
+	velocity vec3
+}
+
+func (m *mover) box() iso.Box {
+	return iso.Box{Min: m.at, Max: m.at.Add(kinds[m.kind].size)}
+}
+
+func main() {
+	flag.Parse()
+
+	input.Bind("raise", input.Mouse(ebiten.MouseButtonLeft))
+	input.Bind("lower", input.Mouse(ebiten.MouseButtonRight))
+	input.Bind("view", input.Key(ebiten.KeyTab))
+
+	ebiten.SetWindowTitle("019-isometric")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func load() (ebiten.Game, error) {
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	self := &game{
+		context:  &pipeline.Context{FlipY: true, ExactSort: true},
+		renderer: renderer,
+		palette:  palette_image(),
+		scene:    &mesh.Mesh{},
+	}
+	rows := strings.Split(strings.TrimSpace(layout), "\n")
+	for y, row := range rows {
+		for x, c := range row {
+			switch {
+			case c == '~':
+				self.heights[y][x] = water
+			case c >= '0' && c <= '9':
+				self.heights[y][x] = int(c - '0')
+			}
+		}
+	}
+	return self, nil
+}
+
+type game struct {
+	// heights are the heights of the cells, by row
+	heights [map_size][map_size]int
+	movers  []mover
+
+	projection iso.Projection
+	// sheet is the sprites of the boxes seen through the projection, made again when it changes
+	sheet *sheet
+	batch sprite.Batch
+	// standing is what stands on the map, the columns and the movers, and order the order they're drawn in
+	standing []thing
+	boxes    []iso.Box
+	sorter   iso.Sorter
+	order    []int
+	picked   image.Point
+	hovering bool
+
+	// in3d draws the map as meshes through the pipeline, with the same projection as a matrix
+	in3d     bool
+	context  *pipeline.Context
+	renderer *render.Renderer
+	palette  *ebiten.Image
+	scene    *mesh.Mesh
+}
+
+// thing is a box which stands on the map, and its sprite.
+type thing struct {
+	box    iso.Box
+	sprite key
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+// HotState keeps the heights of the map across restarts by cmd/dev.
+func (self *game) HotState() any {
+	return self.heights
+}
+
+func (self *game) RestoreHotState(data []byte) error {
+	return json.Unmarshal(data, &self.heights)
+}
+
+// Pipeline exposes the pipeline of the mesh view to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+// height is how tall the column of cell x, y stands, false off the map.
+func (self *game) height(x, y int) (float, bool) {
+	if x < 0 || y < 0 || x >= map_size || y >= map_size {
+		return 0, false
+	}
+	return float(max(self.heights[y][x], 0)), true
+}
+
+// blocked is whether `b` runs into a column, water, the edge of the map or a mover other than `skip`.
+func (self *game) blocked(b iso.Box, skip int) bool {
+	if b.Min[0] < 0 || b.Min[1] < 0 || b.Max[0] > map_size || b.Max[1] > map_size {
+		return true
+	}
+	for y := int(b.Min[1]); y < map_size && float(y) < b.Max[1]; y++ {
+		for x := int(b.Min[0]); x < map_size && float(x) < b.Max[0]; x++ {
+			if self.heights[y][x] != 0 {
+				return true
+			}
+		}
+	}
+	for i := range self.movers {
+		if i != skip && intersect(b, self.movers[i].box()) {
+			return true
+		}
+	}
+	return false
+}
+
+func intersect(a, b iso.Box) bool {
+	for axis := range 3 {
+		if a.Max[axis] <= b.Min[axis] || b.Max[axis] <= a.Min[axis] {
+			return false
+		}
+	}
+	return true
+}
+
+func (self *game) Update() error {
+	if input.JustPressed("view") {
+		self.in3d = !self.in3d
+	}
+
+	projection := iso.Dimetric(32)
+	if true_iso.Bool() {
+		projection = iso.Isometric(32)
+	}
+	// the map in the middle of the screen
+	projection.Origin = vec2{game_width / 2, (game_height - map_size*projection.TileHeight + max_height*projection.Elevation) / 2}
+	if self.sheet == nil || projection != self.projection {
+		if self.sheet != nil {
+			self.sheet.image.Deallocate()
+		}
+		self.projection, self.sheet = projection, new_sheet(projection)
+	}
+
+	// new movers go where nothing is
+	for tries := 0; len(self.movers) < movers_n.Int() && tries < 100; tries++ {
+		k := rand.IntN(len(kinds))
+		m := mover{kind: k, at: vec3{rand.Float32() * map_size, rand.Float32() * map_size, 0}}
+		m.velocity[rand.IntN(2)] = mover_speed
+		if !self.blocked(m.box(), -1) {
+			self.movers = append(self.movers, m)
+		}
+	}
+	self.movers = self.movers[:min(len(self.movers), movers_n.Int())]
+	dt := app.Delta()
+	for i := range self.movers {
+		m := &self.movers[i]
+		moved := *m
+		moved.at = m.at.Add(m.velocity.Mul(dt))
+		if self.blocked(moved.box(), i) {
+			m.velocity = m.velocity.Mul(-1)
+		} else {
+			*m = moved
+		}
+	}
+
+	x, y := input.CursorPosition()
+	self.picked, self.hovering = self.projection.Pick(vec2{float(x), float(y)}, max_height, self.height)
+	if self.hovering {
+		h := &self.heights[self.picked.Y][self.picked.X]
+		cell := iso.Box{Min: vec3{float(self.picked.X), float(self.picked.Y), 0}, Max: vec3{float(self.picked.X + 1), float(self.picked.Y + 1), 1}}
+		switch {
+		case input.JustPressed("raise") && *h < max_height && !self.blocked_by_movers(cell):
+			*h = max(*h, 0) + 1
+		case input.JustPressed("lower") && *h > water && (*h > 0 || !self.blocked_by_movers(cell)):
+			*h--
+		}
+	}
+	return nil
+}
+
+// blocked_by_movers is whether a mover stands in `b`.
+func (self *game) blocked_by_movers(b iso.Box) bool {
+	for i := range self.movers {
+		if intersect(b, self.movers[i].box()) {
+			return true
+		}
+	}
+	return false
+}
+
+// stand lists the boxes standing on the map and orders them back to front.
+func (self *game) stand() {
+	self.standing = self.standing[:0]
+	for y := range map_size {
+		for x := range map_size {
+			if h := self.heights[y][x]; h > 0 {
+				self.standing = append(self.standing, thing{
+					box:    iso.Box{Min: vec3{float(x), float(y), 0}, Max: vec3{float(x + 1), float(y + 1), float(h)}},
+					sprite: key{vec3{1, 1, float(h)}, column_materials[h]},
+				})
+			}
+		}
+	}
+	for _, m := range self.movers {
+		k := kinds[m.kind]
+		self.standing = append(self.standing, thing{box: m.box(), sprite: key{k.size, k.material}})
+	}
+
+	if stand_sort.Bool() {
+		self.boxes = self.boxes[:0]
+		for _, t := range self.standing {
+			self.boxes = append(self.boxes, t.box)
+		}
+		self.order = self.sorter.Sort(self.projection, self.boxes)
+		return
+	}
+	self.order = self.order[:0]
+	for i := range self.standing {
+		self.order = append(self.order, i)
+	}
+	slices.SortStableFunc(self.order, func(a, b int) int {
+		return cmp.Compare(self.projection.Depth(self.standing[a].box.Center()), self.projection.Depth(self.standing[b].box.Center()))
+	})
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{30, 34, 44, 255})
+	self.stand()
+
+	var hud string
+	if self.in3d {
+		hud = self.draw_meshes(screen)
+	} else {
+		hud = self.draw_sprites(screen)
+	}
+	if self.hovering {
+		self.outline(screen, self.picked)
+	}
+
+	projection, sorting := "2:1 dimetric", "by how they stand"
+	if true_iso.Bool() {
+		projection = "true isometric"
+	}
+	if !stand_sort.Bool() {
+		sorting = "by the depth of their centers"
+	}
+	cell := "no cell"
+	if self.hovering {
+		cell = fmt.Sprintf("cell %d, %d, %d tall", self.picked.X, self.picked.Y, max(self.heights[self.picked.Y][self.picked.X], 0))
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f\n%s, %d boxes sorted %s\n%s\n%s\nclick to raise a cell, right click to lower it, %s for the other view",
+		ebiten.ActualTPS(), ebiten.ActualFPS(), projection, len(self.standing), sorting, hud, cell, input.Describe("view")))
+}
+
+// draw_sprites draws the ground and the boxes as sprites of a sheet, through a batch.
+func (self *game) draw_sprites(screen *ebiten.Image) string {
+	b := &self.batch
+	b.ResetStats()
+	// the ground is flat, its cells are drawn in the order of their depth before what stands on it
+	for y := range map_size {
+		for x := range map_size {
+			m := grass
+			switch self.heights[y][x] {
+			case water:
+				m = pond
+			case 0:
+			default:
+				continue
+			}
+			s := self.sheet.sprite(vec3{float(x), float(y), 0}, key{vec3{1, 1, 0}, m})
+			b.Add(screen, &s)
+		}
+	}
+	for _, i := range self.order {
+		t := &self.standing[i]
+		s := self.sheet.sprite(t.box.Min, t.sprite)
+		b.Add(screen, &s)
+	}
+	b.Flush()
+	stats := b.Stats()
+	return fmt.Sprintf("sprites: %d in %d draw calls", stats.Sprites, stats.DrawCalls)
+}
+
+// draw_meshes draws the ground and the boxes as meshes through the pipeline, projected by the matrix of the
+// projection, which lines them up with the sprites.
+func (self *game) draw_meshes(screen *ebiten.Image) string {
+	m := self.scene
+	m.Triangles, m.Points, m.Texcoords = m.Triangles[:0], m.Points[:0], m.Texcoords[:0]
+	for y := range map_size {
+		for x := range map_size {
+			h := self.heights[y][x]
+			if h > 0 {
+				continue
+			}
+			ground := grass
+			if h == water {
+				ground = pond
+			}
+			add_box(m, iso.Box{Min: vec3{float(x), float(y), 0}, Max: vec3{float(x + 1), float(y + 1), 0}}, ground)
+		}
+	}
+	for _, t := range self.standing {
+		add_box(m, t.box, t.sprite.material)
+	}
+
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	near := self.projection.Depth(vec3{map_size, map_size, max_height * 2})
+	ctx.SetProjection(self.projection.Matrix(game_width, game_height, near, -1))
+	ctx.SetView(mgl32.Ident4())
+	ctx.PushMesh(m)
+	ctx.Sort()
+	triangles := len(ctx.Triangles())
+	self.renderer.DrawTriangles(screen, self.palette, ctx.Triangles())
+	ctx.Reset()
+	return fmt.Sprintf("meshes: %d triangles, sorted by the pipeline", triangles)
+}
+
+// outline outlines the top of the column of `cell`.
+func (self *game) outline(screen *ebiten.Image, cell image.Point) {
+	z := float(max(self.heights[cell.Y][cell.X], 0))
+	x, y := float(cell.X), float(cell.Y)
+	corners := [4]vec2{
+		self.projection.ToScreen(vec3{x, y, z}),
+		self.projection.ToScreen(vec3{x + 1, y, z}),
+		self.projection.ToScreen(vec3{x + 1, y + 1, z}),
+		self.projection.ToScreen(vec3{x, y + 1, z}),
+	}
+	for i, a := range corners {
+		b := corners[(i+1)%4]
+		vector.StrokeLine(screen, a[0], a[1], b[0], b[1], 2, color.RGBA{255, 240, 120, 255}, true)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/iso"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sprite"
+)
+
+// the faces of a box which face the viewer, its top and its sides on the high x and high y
+const (
+	top = iota
+	side_x
+	side_y
+	faces
+)
+
+// shades darken the faces of a box, as if lit from above and the left
+var shades = [faces]float{top: 1, side_x: 0.62, side_y: 0.8}
+
+// key is a sprite of the sheet, a box of a size made of a material
+type key struct {
+	size     vec3
+	material material
+}
+
+// sheet is the sprites of every box drawn, pixel art made for a projection in a single image, so the batch draws
+// them in a single draw call.
+type sheet struct {
+	projection iso.Projection
+	image      *ebiten.Image
+	sprites    map[key]placed
+}
+
+// placed is where a sprite is in the sheet, and where its top left is from where the low corner of its box is on
+// screen
+type placed struct {
+	src    image.Rectangle
+	offset vec2
+}
+
+// new_sheet draws the sprites of the ground, the columns and the movers seen through `pr`.
+func new_sheet(pr iso.Projection) *sheet {
+	keys := []key{{vec3{1, 1, 0}, grass}, {vec3{1, 1, 0}, pond}}
+	for h := 1; h <= max_height; h++ {
+		keys = append(keys, key{vec3{1, 1, float(h)}, column_materials[h]})
+	}
+	for _, k := range kinds {
+		keys = append(keys, key{k.size, k.material})
+	}
+
+	// the sprites go side by side
+	pr.Origin = vec2{}
+	s := &sheet{projection: pr, sprites: make(map[key]placed)}
+	bounds := make([]image.Rectangle, len(keys))
+	width, height := 0, 0
+	for i, k := range keys {
+		lo, hi := vec2{math.MaxFloat32, math.MaxFloat32}, vec2{-math.MaxFloat32, -math.MaxFloat32}
+		for corner := range 8 {
+			p := pr.ToScreen(vec3{k.size[0] * float(corner&1), k.size[1] * float(corner>>1&1), k.size[2] * float(corner>>2)})
+			lo, hi = vec2{min(lo[0], p[0]), min(lo[1], p[1])}, vec2{max(hi[0], p[0]), max(hi[1], p[1])}
+		}
+		bounds[i] = image.Rect(int(math.Floor(float64(lo[0]))), int(math.Floor(float64(lo[1]))), int(math.Ceil(float64(hi[0]))), int(math.Ceil(float64(hi[1]))))
+		width += bounds[i].Dx() + 1
+		height = max(height, bounds[i].Dy())
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	x := 0
+	for i, k := range keys {
+		b := bounds[i]
+		src := image.Rect(x, 0, x+b.Dx(), b.Dy())
+		draw_box(img, src, b.Min, pr, k.size, palette[k.material])
+		s.sprites[k] = placed{src: src, offset: vec2{float(b.Min.X), float(b.Min.Y)}}
+		x += b.Dx() + 1
+	}
+	s.image = ebiten.NewImageFromImage(img)
+	return s
+}
+
+// draw_box draws into `dst` at `src` the box of `size` with its low corner at 0, 0, `origin` being the top left of
+// `src` around it. The faces are shaded and their edges darker.
+func draw_box(dst *image.RGBA, src image.Rectangle, origin image.Point, pr iso.Projection, size vec3, base color.RGBA) {
+	// an edge is a pixel wide across the tiles and up the sides
+	edge_x, edge_y := 2/pr.TileWidth, 1/pr.Elevation
+	inside := func(v, size, edge float) (bool, bool) {
+		return v >= 0 && v <= size, v < edge || v > size-edge
+	}
+	for py := src.Min.Y; py < src.Max.Y; py++ {
+		for px := src.Min.X; px < src.Max.X; px++ {
+			at := vec2{float(px-src.Min.X+origin.X) + 0.5, float(py-src.Min.Y+origin.Y) + 0.5}
+			face, on_edge := -1, false
+
+			// the top, then the sides, the top being in front of them
+			t := pr.ToTile(at, size[2])
+			in_x, edge_a := inside(t[0], size[0], edge_x)
+			in_y, edge_b := inside(t[1], size[1], edge_x)
+			if in_x && in_y {
+				face, on_edge = top, edge_a || edge_b
+			}
+			if size[2] > 0 && face < 0 {
+				// on the side at x = size x, the height where its column of pixels crosses it
+				t := pr.ToTile(at, 0)
+				y := t[1] + (size[0] - t[0])
+				z := (size[0] - t[0]) * pr.TileHeight / pr.Elevation
+				in_y, edge_a := inside(y, size[1], edge_x)
+				in_z, edge_b := inside(z, size[2], edge_y)
+				if in_y && in_z {
+					face, on_edge = side_x, edge_a || edge_b
+				}
+			}
+			if size[2] > 0 && face < 0 {
+				t := pr.ToTile(at, 0)
+				x := t[0] + (size[1] - t[1])
+				z := (size[1] - t[1]) * pr.TileHeight / pr.Elevation
+				in_x, edge_a := inside(x, size[0], edge_x)
+				in_z, edge_b := inside(z, size[2], edge_y)
+				if in_x && in_z {
+					face, on_edge = side_y, edge_a || edge_b
+				}
+			}
+			if face < 0 {
+				continue
+			}
+			shade := shades[face]
+			if on_edge {
+				shade *= 0.7
+			}
+			dst.SetRGBA(px, py, color.RGBA{
+				uint8(float(base.R) * shade),
+				uint8(float(base.G) * shade),
+				uint8(float(base.B) * shade),
+				255,
+			})
+		}
+	}
+}
+
+// sprite is sprite `k` with the low corner of its box at `at`, in tiles.
+func (s *sheet) sprite(at vec3, k key) sprite.Sprite {
+	p := s.sprites[k]
+	screen := s.projection.ToScreen(at)
+	var g ebiten.GeoM
+	g.Translate(float64(screen[0]+p.offset[0]), float64(screen[1]+p.offset[1]))
+	return sprite.Sprite{Image: s.image, Src: p.src, GeoM: g}
+}
+
+// palette_image is the colors of the materials as the faces of a box have them, a cell of a column for each
+// material and of a row for each face, which add_box maps the faces of the meshes onto.
+func palette_image() *ebiten.Image {
+	img := image.NewRGBA(image.Rect(0, 0, int(materials)*palette_cell, faces*palette_cell))
+	for m, base := range palette {
+		for f, shade := range shades {
+			c := color.RGBA{uint8(float(base.R) * shade), uint8(float(base.G) * shade), uint8(float(base.B) * shade), 255}
+			for y := range palette_cell {
+				for x := range palette_cell {
+					img.SetRGBA(m*palette_cell+x, f*palette_cell+y, c)
+				}
+			}
+		}
+	}
+	return ebiten.NewImageFromImage(img)
+}
+
+// palette_cell is how many pixels a color of the palette is across
+const palette_cell = 4
+
+// add_box adds the faces of `b` which face the viewer to `m`, in the world of iso.Projection.Matrix: y up, tile x
+// along x and tile y along z. The corners are numbered as mesh.Box numbers them, x, y and z from bits 0, 1 and 2.
+func add_box(m *mesh.Mesh, b iso.Box, mat material) {
+	corners := [faces][4]int{
+		top:    {2, 6, 7, 3},
+		side_x: {1, 3, 7, 5},
+		side_y: {4, 5, 7, 6},
+	}
+	lo, hi := vec3{b.Min[0], b.Min[2], b.Min[1]}, vec3{b.Max[0], b.Max[2], b.Max[1]}
+	for f, corner := range corners {
+		if f != top && b.Max[2] == b.Min[2] {
+			break
+		}
+		uv := vec2{(float(mat) + 0.5) / float(materials), (float(f) + 0.5) / faces}
+		base := uint16(len(m.Points))
+		t := uint16(len(m.Texcoords))
+		m.Texcoords = append(m.Texcoords, uv)
+		for _, i := range corner {
+			p := lo
+			for axis := range 3 {
+				if i>>axis&1 == 1 {
+					p[axis] = hi[axis]
+				}
+			}
+			m.Points = append(m.Points, p)
+		}
+		m.Triangles = append(m.Triangles,
+			mesh.Triangle{P1: base, P2: base + 1, P3: base + 2, T1: t, T2: t, T3: t},
+			mesh.Triangle{P1: base, P2: base + 2, P3: base + 3, T1: t, T2: t, T3: t},
+		)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sdf"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/text"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	// small is how many pixels an em the text of the comparison is, and zoom how much it's blown up
+	small = 10
+	zoom  = 6
+	// label_size is how tall an em of the labels is in the 3D view
+	label_size = 0.45
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{24, 26, 36, 255}, cvar.Persist, "background color")
+	outline     = cvar.Float("sdf_outline", 2, 0, "how many pixels wide the outline is, 0 for none").Range(0, 12)
+	glow        = cvar.Float("sdf_glow", 8, 0, "how many pixels the glow fades over, 0 for none").Range(0, 16)
+	softness    = cvar.Float("sdf_softness", 0, 0, "how many pixels the edges are blurred over").Range(0, 8)
+	spin        = cvar.Bool("sdf_spin", true, 0, "the big word turns and grows and shrinks")
+)
+
+// names are the labels of the pillars of the 3D view
+var names = []string{"Mercury", "Venus", "Earth", "Mars", "Jupiter", "Saturn", "Uranus", "Neptune"}
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+	input.Bind("view", input.Key(ebiten.KeyTab))
+
+	ebiten.SetWindowTitle("020-sdf-text")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load generates the fields of the font and makes the scene of the 3D view.
+func load() (ebiten.Game, error) {
+	font := text.SDFSans()
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	style := label_style(font)
+	material, err := render.NewSDFMaterial(font.Image(), &style)
+	if err != nil {
+		return nil, err
+	}
+
+	floor := mesh.Plane(16)
+	var pillars []*mesh.Mesh
+	var labels []*label
+	for i, name := range names {
+		angle := float64(i) / float64(len(names)) * 2 * math.Pi
+		at := vec3{float(math.Cos(angle)) * 5, 0, float(math.Sin(angle)) * 5}
+		height := 1 + float(i%3)*0.6
+		p := mesh.Box(vec3{0.6, height, 0.6})
+		p.Transform(mgl32.Translate3D(at[0], height/2, at[2]))
+		p.ComputeBounds()
+		pillars = append(pillars, p)
+
+		base := font.Mesh(name, label_size, true)
+		labels = append(labels, &label{
+			at:   at.Add(vec3{0, height + 0.5, 0}),
+			base: base,
+			mesh: &mesh.Mesh{Triangles: base.Triangles, Texcoords: base.Texcoords},
+		})
+	}
+	solid, err := mesh.Merge(append([]*mesh.Mesh{floor}, pillars...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &game{
+		font:      font,
+		zoomed:    ebiten.NewImage(64, 2*small),
+		context:   &pipeline.Context{FlipY: true},
+		renderer:  renderer,
+		camera:    camera.New(vec3{0, 4, 10}, vec3{0, 1, 0}),
+		solid:     solid,
+		solid_tex: ebiten.NewImageFromImage(texgen.Checker(256, 16, color.RGBA{70, 74, 92, 255}, color.RGBA{60, 64, 80, 255})),
+		labels:    labels,
+		material:  material,
+	}, nil
+}
+
+// label is the name of a pillar floating over it, turned to face the camera every frame.
+type label struct {
+	at vec3
+	// base is the label as the atlas made it, mesh it turned and moved into place
+	base, mesh *mesh.Mesh
+}
+
+// text_style is the style the cvars set.
+func text_style() *text.SDFStyle {
+	return &text.SDFStyle{
+		Color:        color.White,
+		Outline:      outline.Float(),
+		OutlineColor: color.RGBA{20, 40, 120, 255},
+		Glow:         glow.Float(),
+		GlowColor:    color.RGBA{150, 60, 0, 150},
+		Softness:     softness.Float(),
+	}
+}
+
+// label_style is the style for the labels, as wide in the field as text at the size of the atlas.
+func label_style(font *text.SDF) sdf.Style {
+	return font.Field(text_style(), float64(font.Size))
+}
+
+type game struct {
+	font *text.SDF
+	time float
+
+	// zoomed holds the small text the comparison blows up
+	zoomed *ebiten.Image
+
+	// in3d shows the labels over pillars in 3D
+	in3d      bool
+	context   *pipeline.Context
+	renderer  *render.Renderer
+	camera    *camera.Camera
+	solid     *mesh.Mesh
+	solid_tex *ebiten.Image
+	labels    []*label
+	material  *render.Material
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+// Pipeline exposes the pipeline of the 3D view to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Update() error {
+	if input.JustPressed("view") {
+		self.in3d = !self.in3d
+	}
+	if self.in3d {
+		self.camera.Update()
+	}
+	if spin.Bool() {
+		self.time += app.Delta()
+	}
+	return nil
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	screen.Fill(clear_color.Color())
+	var hud string
+	if self.in3d {
+		hud = self.draw_3d(screen)
+	} else {
+		hud = self.draw_2d(screen)
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f\n%s\n%s for the other view, ` for the console",
+		ebiten.ActualTPS(), ebiten.ActualFPS(), hud, input.Describe("view")))
+}
+
+// draw_2d draws text at many sizes from the same fields, a word turning and scaling, and small text blown up from a
+// bitmap and from the fields.
+func (self *game) draw_2d(screen *ebiten.Image) string {
+	style := text_style()
+	ui.DrawHeading(screen.SubImage(image.Rect(0, 40, game_width, 110)).(*ebiten.Image), "Signed distance field text", 44, style, 0.5, 0.5)
+
+	// the sizes grow by half each line
+	y := 130.0
+	for size := 8.0; size < 100; size *= 1.5 {
+		var g ebiten.GeoM
+		g.Translate(20, y)
+		self.font.Draw(screen, fmt.Sprintf("%.0f px", size), size, g, style)
+		y += size * float64(self.font.LineHeight)
+	}
+
+	// the word turns about its middle, its outline and glow turning and scaling with it
+	word := "Kage"
+	const size = 90
+	extent := self.font.Measure(word, size)
+	var g ebiten.GeoM
+	g.Translate(-float64(extent[0])/2, -float64(extent[1])/2)
+	g.Rotate(0.4 * math.Sin(float64(self.time)*0.9))
+	scale := 1 + 0.6*math.Sin(float64(self.time)*0.6)
+	g.Scale(scale, scale)
+	g.Translate(560, 270)
+	self.font.Draw(screen, word, size, g, style)
+
+	// the same line at 10 pixels blown up 6 times: rasterized once and stretched, and drawn from the fields
+	line := "Sharp edges?"
+	self.zoomed.Clear()
+	text.Draw(self.zoomed, line, text.Sans.Face(small), 0, 0, float64(self.zoomed.Bounds().Dx()), 2*small, 0, color.White)
+	var bitmap ebiten.GeoM
+	bitmap.Scale(zoom, zoom)
+	bitmap.Translate(20, 470)
+	screen.DrawImage(self.zoomed, &ebiten.DrawImageOptions{GeoM: bitmap, Filter: ebiten.FilterLinear})
+	var field ebiten.GeoM
+	field.Translate(420, 470)
+	self.font.Draw(screen, line, small*zoom, field, &text.SDFStyle{Color: color.White})
+	ebitenutil.DebugPrintAt(screen, "a bitmap at 10 px, 6x", 20, 450)
+	ebitenutil.DebugPrintAt(screen, "the fields at 10 px, 6x", 420, 450)
+
+	return fmt.Sprintf("2D: %d glyphs %v pixels an em in a %dx%d atlas, drawn at any size",
+		len(self.font.Glyphs), self.font.Size, self.font.Image().Bounds().Dx(), self.font.Image().Bounds().Dy())
+}
+
+// draw_3d floats the names of pillars over them, labels made of quads of the atlas which turn to face the camera.
+func (self *game) draw_3d(screen *ebiten.Image) string {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 100)
+	ctx.SetView(self.camera.View())
+
+	ctx.PushMesh(self.solid)
+	ctx.Cull = self.material.Cull
+	eye := self.camera.Position
+	for _, l := range self.labels {
+		// turned about the vertical, its front to the camera
+		yaw := math.Atan2(float64(eye[0]-l.at[0]), float64(eye[2]-l.at[2]))
+		l.mesh.Points = append(l.mesh.Points[:0], l.base.Points...)
+		l.mesh.Transform(mgl32.Translate3D(l.at[0], l.at[1], l.at[2]).Mul4(mgl32.HomogRotate3DY(float(yaw))))
+		l.mesh.ComputeBounds()
+		ctx.PushMesh(l.mesh)
+	}
+	ctx.Cull = pipeline.CullBack
+	ctx.Sort()
+
+	style := label_style(self.font)
+	self.material.Uniforms = style.Uniforms()
+	// the pillars and the labels are drawn in depth order, a draw call for each run of either
+	triangles := ctx.Triangles()
+	drawn := len(triangles)
+	solid := self.solid.ID()
+	for len(triangles) > 0 {
+		n := 1
+		for n < len(triangles) && (triangles[n].Mesh == solid) == (triangles[0].Mesh == solid) {
+			n++
+		}
+		if triangles[0].Mesh == solid {
+			self.renderer.DrawTriangles(screen, self.solid_tex, triangles[:n])
+		} else {
+			self.renderer.DrawMaterial(screen, self.material, triangles[:n])
+		}
+		triangles = triangles[n:]
+	}
+	ctx.Reset()
+	return fmt.Sprintf("3D: %d labels, %d triangles drawn, drag to look, WASD to move", len(self.labels), drawn)
+}
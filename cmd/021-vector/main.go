@@ -0,0 +1,366 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecdraw"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecscene"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+
+	// grid_x by grid_y gauges fill the other view
+	grid_x = 12
+	grid_y = 9
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec4  = mgl32.Vec4
+)
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{30, 32, 40, 255}, cvar.Persist, "background color")
+	caching     = cvar.Bool("vec_cache", true, 0, "keep the triangles of paths which didn't change, off cuts every path again every frame")
+	tolerance   = cvar.Float("vec_tolerance", 0.25, 0, "how many pixels flattened curves may stray from them").Range(0.05, 4)
+	antialias   = cvar.Bool("vec_antialias", true, 0, "smooth the edges of the triangles")
+)
+
+func main() {
+	flag.Parse()
+
+	input.Bind("view", input.Key(ebiten.KeyTab))
+
+	ebiten.SetWindowTitle("021-vector")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load makes the scenes of both views.
+func load() (ebiten.Game, error) {
+	art := new_gauge_art()
+	g := &game{
+		scene:  vecscene.New(),
+		stress: vecscene.New(),
+		blob:   &vecscene.Path{},
+	}
+
+	dial := art.gauge("gauge", 90)
+	dial.Transform = mgl32.Translate2D(140, 160)
+	g.needles = append(g.needles, dial.Find("needle"))
+
+	blob := vecscene.NewNode("blob", g.blob)
+	blob.Transform = mgl32.Translate2D(140, 440)
+	blob.Fill = &vecscene.FillPaint{Color: rgba(0.9, 0.3, 0.6, 0.7)}
+	blob.Stroke = &vecscene.StrokePaint{Color: rgba(1, 0.8, 0.9, 1), StrokeStyle: vecscene.StrokeStyle{Width: 3, Join: vecscene.RoundJoin}}
+
+	landscape := new_landscape()
+	landscape.Transform = mgl32.Translate2D(310, 40)
+	g.sun_rays, g.cloud = landscape.Find("rays"), landscape.Find("cloud")
+
+	g.scene.Root.Add(dial, blob, landscape, new_samples())
+
+	// the other view is lots of smaller gauges sharing the paths of the big one
+	for i := range grid_x * grid_y {
+		x, y := i%grid_x, i/grid_x
+		n := art.gauge(fmt.Sprintf("gauge %d", i), 28)
+		n.Transform = mgl32.Translate2D(40+float(x)*65, 60+float(y)*63)
+		g.stress.Root.Add(n)
+		g.grid = append(g.grid, n.Find("needle"))
+	}
+	return g, nil
+}
+
+// rgba is a color premultiplied, what scenes take.
+func rgba(r, g, b, a float) vec4 {
+	return vec4{r * a, g * a, b * a, a}
+}
+
+// gauge_art are the paths of a gauge of radius 1, shared by every gauge.
+type gauge_art struct {
+	dial, ticks, band, needle, hub *vecscene.Path
+}
+
+// the dial goes from the bottom left round the top to the bottom right, y down
+const (
+	gauge_from = 0.75 * math.Pi
+	gauge_turn = 1.5 * math.Pi
+)
+
+func new_gauge_art() *gauge_art {
+	a := &gauge_art{&vecscene.Path{}, &vecscene.Path{}, &vecscene.Path{}, &vecscene.Path{}, &vecscene.Path{}}
+	a.dial.Circle(0, 0, 1)
+	for i := range 11 {
+		angle := gauge_from + gauge_turn*float(i)/10
+		c, s := float(math.Cos(float64(angle))), float(math.Sin(float64(angle)))
+		inner := float(0.8)
+		if i%5 == 0 {
+			inner = 0.7
+		}
+		a.ticks.MoveTo(c*inner, s*inner)
+		a.ticks.LineTo(c*0.9, s*0.9)
+	}
+	// the band marks the last quarter of the dial
+	a.band.Arc(0, 0, 0.6, gauge_from+gauge_turn*0.75, gauge_from+gauge_turn)
+	a.needle.MoveTo(-0.15, 0)
+	a.needle.LineTo(0, -0.05)
+	a.needle.LineTo(0.85, 0)
+	a.needle.LineTo(0, 0.05)
+	a.needle.Close()
+	a.hub.Circle(0, 0, 0.09)
+	return a
+}
+
+// gauge makes a gauge of radius `r`, its needle a child named "needle" which only ever turns. The paths are of
+// radius 1 and scaled by the node, and the strokes widths scaled with them.
+func (a *gauge_art) gauge(name string, r float) *vecscene.Node {
+	root := vecscene.NewNode(name, nil)
+	scaled := vecscene.NewNode("", nil)
+	scaled.Transform = mgl32.Scale2D(r, r)
+
+	dial := vecscene.NewNode("dial", a.dial)
+	dial.Fill = &vecscene.FillPaint{Color: rgba(0.12, 0.13, 0.17, 1)}
+	dial.Stroke = &vecscene.StrokePaint{Color: rgba(0.7, 0.72, 0.8, 1), StrokeStyle: vecscene.StrokeStyle{Width: 0.06}}
+	ticks := vecscene.NewNode("ticks", a.ticks)
+	ticks.Stroke = &vecscene.StrokePaint{Color: rgba(0.9, 0.9, 0.95, 1), StrokeStyle: vecscene.StrokeStyle{Width: 0.04}}
+	band := vecscene.NewNode("band", a.band)
+	band.Stroke = &vecscene.StrokePaint{Color: rgba(0.9, 0.25, 0.2, 0.8), StrokeStyle: vecscene.StrokeStyle{Width: 0.1, Cap: vecscene.RoundCap}}
+	needle := vecscene.NewNode("needle", a.needle)
+	needle.Fill = &vecscene.FillPaint{Color: rgba(1, 0.55, 0.1, 1)}
+	hub := vecscene.NewNode("hub", a.hub)
+	hub.Fill = &vecscene.FillPaint{Color: rgba(0.8, 0.8, 0.85, 1)}
+
+	return root.Add(scaled.Add(dial, ticks, band, needle, hub))
+}
+
+// new_landscape makes a little illustration: hills, a house with a window of four panes cut out by the even-odd
+// rule, a cloud of overlapping circles filled by the non-zero one and a sun whose rays turn.
+func new_landscape() *vecscene.Node {
+	root := vecscene.NewNode("landscape", nil)
+
+	var sky vecscene.Path
+	sky.Rect(0, 0, 460, 250)
+	sky_node := vecscene.NewNode("sky", &sky)
+	sky_node.Fill = &vecscene.FillPaint{Color: rgba(0.45, 0.7, 0.95, 1)}
+
+	var disc, rays vecscene.Path
+	disc.Circle(0, 0, 26)
+	for i := range 10 {
+		a := float64(i) / 10 * 2 * math.Pi
+		at := func(angle float64, r float) (float, float) {
+			return float(math.Cos(angle)) * r, float(math.Sin(angle)) * r
+		}
+		x, y := at(a-0.12, 32)
+		rays.MoveTo(x, y)
+		x, y = at(a, 48)
+		rays.LineTo(x, y)
+		x, y = at(a+0.12, 32)
+		rays.LineTo(x, y)
+		rays.Close()
+	}
+	sun := vecscene.NewNode("sun", &disc)
+	sun.Transform = mgl32.Translate2D(380, 64)
+	sun.Fill = &vecscene.FillPaint{Color: rgba(1, 0.85, 0.3, 1)}
+	ray_node := vecscene.NewNode("rays", &rays)
+	ray_node.Fill = &vecscene.FillPaint{Color: rgba(1, 0.7, 0.2, 1)}
+	sun.Add(ray_node)
+
+	var puffs vecscene.Path
+	for _, c := range [][3]float{{0, 0, 22}, {26, -10, 28}, {54, 0, 20}, {28, 8, 20}} {
+		puffs.Circle(c[0], c[1], c[2])
+	}
+	cloud := vecscene.NewNode("cloud", &puffs)
+	cloud.Fill = &vecscene.FillPaint{Color: rgba(1, 1, 1, 0.9)}
+
+	var far, near vecscene.Path
+	far.MoveTo(0, 250)
+	far.LineTo(0, 170)
+	far.CubeTo(90, 110, 180, 200, 280, 150)
+	far.CubeTo(340, 120, 420, 140, 460, 160)
+	far.LineTo(460, 250)
+	far.Close()
+	near.MoveTo(0, 250)
+	near.LineTo(0, 215)
+	near.QuadTo(160, 160, 300, 215)
+	near.QuadTo(400, 250, 460, 205)
+	near.LineTo(460, 250)
+	near.Close()
+	far_node := vecscene.NewNode("far hills", &far)
+	far_node.Fill = &vecscene.FillPaint{Color: rgba(0.3, 0.55, 0.3, 1)}
+	near_node := vecscene.NewNode("near hills", &near)
+	near_node.Fill = &vecscene.FillPaint{Color: rgba(0.35, 0.7, 0.3, 1)}
+
+	var walls, roof, door, window vecscene.Path
+	walls.Rect(0, 0, 90, 60)
+	roof.MoveTo(-12, 2)
+	roof.LineTo(45, -40)
+	roof.LineTo(102, 2)
+	roof.Close()
+	door.RoundedRect(52, 22, 22, 38, 8)
+	window.Rect(14, 14, 28, 28)
+	for _, pane := range [][2]float{{17, 17}, {30, 17}, {17, 30}, {30, 30}} {
+		window.Rect(pane[0], pane[1], 10, 10)
+	}
+	house := vecscene.NewNode("house", &walls)
+	house.Transform = mgl32.Translate2D(110, 160)
+	house.Fill = &vecscene.FillPaint{Color: rgba(0.92, 0.88, 0.78, 1)}
+	roof_node := vecscene.NewNode("roof", &roof)
+	roof_node.Fill = &vecscene.FillPaint{Color: rgba(0.7, 0.25, 0.2, 1)}
+	roof_node.Stroke = &vecscene.StrokePaint{Color: rgba(0.45, 0.15, 0.12, 1), StrokeStyle: vecscene.StrokeStyle{Width: 4, Join: vecscene.RoundJoin}}
+	door_node := vecscene.NewNode("door", &door)
+	door_node.Fill = &vecscene.FillPaint{Color: rgba(0.45, 0.3, 0.2, 1)}
+	window_node := vecscene.NewNode("window", &window)
+	window_node.Fill = &vecscene.FillPaint{Color: rgba(0.3, 0.25, 0.2, 1), Rule: vecscene.EvenOdd}
+	house.Add(roof_node, door_node, window_node)
+
+	return root.Add(sky_node, sun, cloud, far_node, near_node, house)
+}
+
+// new_samples makes the same zigzag stroked with each join and cap, a thin line along the middle of each.
+func new_samples() *vecscene.Node {
+	root := vecscene.NewNode("samples", nil)
+	root.Transform = mgl32.Translate2D(330, 360)
+	var zigzag vecscene.Path
+	zigzag.MoveTo(0, 60)
+	zigzag.LineTo(40, 0)
+	zigzag.LineTo(70, 60)
+	zigzag.LineTo(110, 20)
+	for i, style := range []vecscene.StrokeStyle{
+		{Width: 18, Join: vecscene.MiterJoin, Cap: vecscene.ButtCap},
+		{Width: 18, Join: vecscene.RoundJoin, Cap: vecscene.RoundCap},
+		{Width: 18, Join: vecscene.BevelJoin, Cap: vecscene.SquareCap},
+	} {
+		n := vecscene.NewNode("", &zigzag)
+		n.Transform = mgl32.Translate2D(float(i)*150, 0)
+		n.Stroke = &vecscene.StrokePaint{Color: rgba(0.3, 0.6, 0.9, 1), StrokeStyle: style}
+		middle := vecscene.NewNode("", &zigzag)
+		middle.Stroke = &vecscene.StrokePaint{Color: rgba(1, 1, 1, 1), StrokeStyle: vecscene.StrokeStyle{Width: 1.5}}
+		root.Add(n.Add(middle))
+	}
+	return root
+}
+
+type game struct {
+	time float
+
+	scene   *vecscene.Scene
+	needles []*vecscene.Node
+	// blob is a path built again every frame
+	blob            *vecscene.Path
+	sun_rays, cloud *vecscene.Node
+
+	// stress fills the other view with gauges
+	stress  *vecscene.Scene
+	grid    []*vecscene.Node
+	in_grid bool
+
+	drawer vecdraw.Drawer
+	// took is how long the last frame took to build and draw the scene
+	took time.Duration
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+func (self *game) Update() error {
+	if input.JustPressed("view") {
+		self.in_grid = !self.in_grid
+	}
+	self.time += app.Delta()
+	t := float64(self.time)
+
+	// only the transforms of the needles, the rays and the cloud change, their triangles are kept
+	for _, n := range self.needles {
+		n.Transform = mgl32.HomogRotate2D(needle_angle(t, 0))
+	}
+	for i, n := range self.grid {
+		n.Transform = mgl32.HomogRotate2D(needle_angle(t, float64(i)*0.7))
+	}
+	self.sun_rays.Transform = mgl32.HomogRotate2D(float(t * 0.3))
+	self.cloud.Transform = mgl32.Translate2D(float(215-165*math.Cos(t*0.12)), 50)
+
+	// the blob is eight points bobbing in and out, joined by curves through the middles between them
+	self.blob.Reset()
+	var points [8]vec2
+	for i := range points {
+		a := float64(i) / float64(len(points)) * 2 * math.Pi
+		r := 70 + 14*math.Sin(t*1.7+float64(i)*1.3) + 8*math.Sin(t*2.9-float64(i)*2.1)
+		points[i] = vec2{float(math.Cos(a) * r), float(math.Sin(a) * r)}
+	}
+	mid := func(i int) vec2 {
+		return points[i%len(points)].Add(points[(i+1)%len(points)]).Mul(0.5)
+	}
+	start := mid(0)
+	self.blob.MoveTo(start[0], start[1])
+	for i := 1; i <= len(points); i++ {
+		c, to := points[i%len(points)], mid(i)
+		self.blob.QuadTo(c[0], c[1], to[0], to[1])
+	}
+	self.blob.Close()
+	return nil
+}
+
+// needle_angle is where a needle points at `t`, wandering over the dial.
+func needle_angle(t, phase float64) float {
+	v := 0.5 + 0.35*math.Sin(t*0.8+phase) + 0.15*math.Sin(t*2.3+phase*1.7)
+	return float(gauge_from + gauge_turn*v)
+}
+
+// invalidate throws away the triangles of `n` and every node under it.
+func invalidate(n *vecscene.Node) {
+	n.Invalidate()
+	for _, c := range n.Children {
+		invalidate(c)
+	}
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	screen.Fill(clear_color.Color())
+	scene := self.scene
+	if self.in_grid {
+		scene = self.stress
+	}
+	if !caching.Bool() {
+		invalidate(scene.Root)
+	}
+	scene.Tolerance = float(tolerance.Float())
+	self.drawer.AntiAlias = antialias.Bool()
+
+	start := time.Now()
+	self.drawer.Draw(screen, scene, ebiten.GeoM{})
+	self.took = time.Since(start)
+
+	if !self.in_grid {
+		ebitenutil.DebugPrintAt(screen, "miter, butt", 330, 440)
+		ebitenutil.DebugPrintAt(screen, "round, round", 480, 440)
+		ebitenutil.DebugPrintAt(screen, "bevel, square", 630, 440)
+		ebitenutil.DebugPrintAt(screen, "the gauge only turns its needle, the blob changes every frame", 20, 560)
+	}
+	st := scene.Stats
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f\n%d nodes: %d tessellated, %d cached, %d triangles in %d draw calls, %.2f ms\n%s for the other view, ` for the console",
+		ebiten.ActualTPS(), ebiten.ActualFPS(), st.Nodes, st.Tessellated, st.Cached, st.Triangles, self.drawer.DrawCalls,
+		float64(self.took.Microseconds())/1000, input.Describe("view")))
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/app"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/render"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/svg"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecdraw"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecscene"
+)
+
+const (
+	game_width  = 800
+	game_height = 600
+	game_aspect = float(game_width) / float(game_height)
+
+	// logo_size is how wide the logo stands in the 3D view
+	logo_size = 4
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+//go:embed *.svg
+var assets embed.FS
+
+// icons are drawn in a row at each of sizes
+var (
+	icons = []string{"heart.svg", "home.svg", "gear.svg", "play.svg", "bell.svg"}
+	sizes = []float{16, 24, 32, 48, 96}
+)
+
+var logo_path = flag.String("svg", "", "show the SVG `file` instead of the logo")
+
+var (
+	clear_color = cvar.Color("r_clear_color", color.RGBA{30, 32, 48, 255}, cvar.Persist, "background color")
+	breathe     = cvar.Bool("svg_breathe", true, 0, "the logo grows and shrinks, tessellated again every time its scale doubles or halves")
+	spin        = cvar.Bool("svg_spin", true, 0, "the logo turns in the 3D view")
+)
+
+func main() {
+	flag.Parse()
+
+	camera.Bind()
+	input.Bind("view", input.Key(ebiten.KeyTab))
+
+	ebiten.SetWindowTitle("022-svg")
+	ebiten.SetWindowSize(game_width, game_height)
+	ebiten.SetVsyncEnabled(false)
+
+	err := app.RunLoader(load, &ebiten.RunGameOptions{
+		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// load imports the icons and the logo, and makes the mesh of the logo for the 3D view.
+func load() (ebiten.Game, error) {
+	var fsys fs.FS = assets
+	name := "logo.svg"
+	if *logo_path != "" {
+		fsys, name = os.DirFS(filepath.Dir(*logo_path)), filepath.Base(*logo_path)
+	}
+	logo, err := svg.Load(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// each icon at each size is imported again, since a node keeps the triangles of the one scale it's drawn at
+	scene := vecscene.New()
+	var labels []image.Point
+	y := float(70)
+	for _, size := range sizes {
+		x := float(20)
+		for _, name := range icons {
+			icon, err := svg.Load(assets, name)
+			if err != nil {
+				return nil, err
+			}
+			icon.Root.Transform = mgl32.Translate2D(x, y).Mul3(mgl32.Scale2D(size/icon.Width, size/icon.Height)).Mul3(icon.Root.Transform)
+			scene.Root.Add(icon.Root)
+			x += size + 12
+		}
+		labels = append(labels, image.Pt(int(x), int(y+size/2)-8))
+		y += size + 14
+	}
+	logo_node := vecscene.NewNode("logo", nil)
+	scene.Root.Add(logo_node.Add(logo.Root))
+
+	renderer, err := render.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+	base, colors := logo.Mesh(0.25, 0.5)
+	// the mesh is in the logo's pixels, scaled down to stand logo_size wide
+	scale := logo_size / max(logo.Width, logo.Height)
+	base.Transform(mgl32.Scale3D(scale, scale, scale))
+	var swatches []*ebiten.Image
+	for _, c := range colors {
+		swatches = append(swatches, swatch(c))
+	}
+	return &game{
+		scene:     scene,
+		logo:      logo,
+		logo_node: logo_node,
+		labels:    labels,
+		context:   &pipeline.Context{FlipY: true},
+		renderer:  renderer,
+		camera:    camera.New(vec3{0, 3, 8}, vec3{0, 2, 0}),
+		floor:     mesh.Plane(16),
+		floor_tex: ebiten.NewImageFromImage(texgen.Checker(256, 16, color.RGBA{70, 74, 92, 255}, color.RGBA{60, 64, 80, 255})),
+		base:      base,
+		mesh:      &mesh.Mesh{Triangles: base.Triangles, Texcoords: base.Texcoords, Materials: base.Materials},
+		swatches:  swatches,
+	}, nil
+}
+
+// swatch is an image of the premultiplied color `c` to draw triangles of it with, the center pixel of a 3x3 one so
+// sampling never bleeds into the atlas around it.
+func swatch(c mgl32.Vec4) *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.RGBA64{uint16(c[0] * 0xffff), uint16(c[1] * 0xffff), uint16(c[2] * 0xffff), uint16(c[3] * 0xffff)})
+	return img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+}
+
+type game struct {
+	time float
+
+	scene     *vecscene.Scene
+	logo      *svg.Image
+	logo_node *vecscene.Node
+	drawer    vecdraw.Drawer
+	// labels are where the sizes of the rows of icons are printed
+	labels []image.Point
+
+	// in3d shows the logo as a mesh in 3D
+	in3d      bool
+	context   *pipeline.Context
+	renderer  *render.Renderer
+	camera    *camera.Camera
+	floor     *mesh.Mesh
+	floor_tex *ebiten.Image
+	// base is the mesh of the logo, mesh it turned into place
+	base, mesh *mesh.Mesh
+	// swatches are the colors of the materials of the mesh
+	swatches []*ebiten.Image
+}
+
+func (self *game) Layout(outerWidth, outerHeight int) (int, int) {
+	return game_width, game_height
+}
+
+// Camera keeps the camera where it was across restarts by cmd/dev.
+func (self *game) Camera() *camera.Camera {
+	return self.camera
+}
+
+// Pipeline exposes the pipeline of the 3D view to the frame debugger.
+func (self *game) Pipeline() *pipeline.Context {
+	return self.context
+}
+
+func (self *game) Update() error {
+	if input.JustPressed("view") {
+		self.in3d = !self.in3d
+	}
+	if self.in3d {
+		self.camera.Update()
+		if spin.Bool() {
+			self.time += app.Delta()
+		}
+	} else if breathe.Bool() {
+		self.time += app.Delta()
+	}
+	return nil
+}
+
+func (self *game) Draw(screen *ebiten.Image) {
+	screen.Fill(clear_color.Color())
+	var hud string
+	if self.in3d {
+		hud = self.draw_3d(screen)
+	} else {
+		hud = self.draw_2d(screen)
+	}
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %.0f FPS: %.0f\n%s\n%s for the other view, ` for the console",
+		ebiten.ActualTPS(), ebiten.ActualFPS(), hud, input.Describe("view")))
+}
+
+// draw_2d draws the icons at several sizes and the logo growing and shrinking, all through one scene.
+func (self *game) draw_2d(screen *ebiten.Image) string {
+	// the logo is fitted into a square under the icons, scaled between a third and the whole of it
+	const box = 240
+	scale := box / max(self.logo.Width, self.logo.Height) * float(0.65+0.35*math.Sin(float64(self.time)*0.7))
+	self.logo_node.Transform = mgl32.Translate2D(600, 470).
+		Mul3(mgl32.Scale2D(scale, scale)).
+		Mul3(mgl32.Translate2D(-self.logo.Width/2, -self.logo.Height/2))
+
+	self.drawer.AntiAlias = true
+	self.drawer.Draw(screen, self.scene, ebiten.GeoM{})
+	for i, at := range self.labels {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%.0f px", sizes[i]), at.X, at.Y)
+	}
+	st := self.scene.Stats
+	return fmt.Sprintf("%d icons, %d nodes: %d tessellated, %d cached, %d triangles", len(icons)*len(sizes), st.Nodes,
+		st.Tessellated, st.Cached, st.Triangles)
+}
+
+// draw_3d stands the mesh of the logo on a floor. Its layers are too close to sort by depth, so its triangles are
+// drawn in the order they're painted, or the other way round seen from behind, a draw call for each run of a color.
+func (self *game) draw_3d(screen *ebiten.Image) string {
+	ctx := self.context
+	ctx.SetViewport(0, 0, game_width, game_height)
+	ctx.SetPerspective(self.camera.Fov, game_aspect, 0.1, 100)
+	ctx.SetView(self.camera.View())
+
+	ctx.PushMesh(self.floor)
+	ctx.Sort()
+	// the logo goes after the floor, unsorted
+	turn := mgl32.HomogRotate3DY(self.time * 0.6)
+	center := vec3{0, 2.4, 0}
+	self.mesh.Points = append(self.mesh.Points[:0], self.base.Points...)
+	self.mesh.Transform(mgl32.Translate3D(center[0], center[1], center[2]).Mul4(turn))
+	self.mesh.ComputeBounds()
+	ctx.Cull = pipeline.CullNone
+	ctx.PushMesh(self.mesh)
+	ctx.Cull = pipeline.CullBack
+
+	triangles := ctx.Triangles()
+	drawn := len(triangles)
+	floor := self.floor.ID()
+	n := 0
+	for n < len(triangles) && triangles[n].Mesh == floor {
+		n++
+	}
+	self.renderer.DrawTriangles(screen, self.floor_tex, triangles[:n])
+	calls := 1
+
+	var runs [][]pipeline.Triangle
+	for logo := triangles[n:]; len(logo) > 0; {
+		n := 1
+		for n < len(logo) && logo[n].Material == logo[0].Material {
+			n++
+		}
+		runs = append(runs, logo[:n])
+		logo = logo[n:]
+	}
+	front := turn.Mul4x1(mgl32.Vec4{0, 0, 1, 0}).Vec3()
+	if front.Dot(self.camera.Position.Sub(center)) < 0 {
+		slices.Reverse(runs)
+	}
+	for _, run := range runs {
+		self.renderer.DrawTriangles(screen, self.swatches[run[0].Material], run)
+		calls++
+	}
+	ctx.Reset()
+	return fmt.Sprintf("3D: the logo as a mesh of %d triangles in %d colors, %d drawn in %d draw calls, drag to look, WASD to move",
+		len(self.base.Triangles), len(self.swatches), drawn, calls)
+}
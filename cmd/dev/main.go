@@ -0,0 +1,161 @@
+// dev runs a demo and rebuilds and restarts it whenever a Go source or shader of the module changes, so an edit is
+// on screen seconds after it's saved. The demo keeps its cvars, its camera and whatever else it keeps through
+// app.HotStater across the restart: dev asks it over the remote API to write them to its -hot-state file and quit,
+// and starts the new build with the same file. A build which fails leaves the demo running as it was.
+//
+//	go run ./cmd/dev ./cmd/015-grass -blades 200
+//
+// Run it from the root of the module, it watches the sources under the directory it's run in.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/watch"
+)
+
+var logger = logging.Tag("dev")
+
+// quit_timeout is how long the demo has to save its state and quit before it's killed
+const quit_timeout = 5 * time.Second
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: dev [flags] <package of the demo> [flags of the demo]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), flag.Args()[1:]); err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+}
+
+// demo is a running build of the demo.
+type demo struct {
+	cmd *exec.Cmd
+	// exited is closed once it exited, err is how
+	exited chan struct{}
+	err    error
+}
+
+func run(pkg string, args []string) error {
+	dir, err := os.MkdirTemp("", "ebiten-kage-playground-dev")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "demo")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	addr, err := free_address()
+	if err != nil {
+		return err
+	}
+	args = append(args, "-remote", addr, "-hot-state", filepath.Join(dir, "state.json"))
+
+	if err := build(pkg, binary); err != nil {
+		return err
+	}
+	sources := watch.NewTree(".", ".go", ".kage")
+	d, err := start(binary, args)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(watch.DefaultInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.exited:
+			// closed by whoever's in front of it, which ends the session
+			return d.err
+		case <-ticker.C:
+		}
+		if !sources.Changed() {
+			continue
+		}
+
+		logger.Infof("rebuilding %s", pkg)
+		next := binary + ".next"
+		if err := build(pkg, next); err != nil {
+			logger.Warnf("the build failed, the demo keeps running: %v", err)
+			continue
+		}
+		stop(d, addr)
+		if err := os.Rename(next, binary); err != nil {
+			return err
+		}
+		if d, err = start(binary, args); err != nil {
+			return err
+		}
+	}
+}
+
+// free_address is an address on localhost nothing listens on, for the remote API of the demo.
+func free_address() (string, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
+
+func build(pkg, binary string) error {
+	cmd := exec.Command("go", "build", "-o", binary, pkg)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func start(binary string, args []string) (*demo, error) {
+	d := &demo{cmd: exec.Command(binary, args...), exited: make(chan struct{})}
+	d.cmd.Stdout, d.cmd.Stderr, d.cmd.Stdin = os.Stdout, os.Stderr, os.Stdin
+	if err := d.cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		d.err = d.cmd.Wait()
+		close(d.exited)
+	}()
+	return d, nil
+}
+
+// stop has the demo save its state and quit, and kills it when it doesn't in time.
+func stop(d *demo, addr string) {
+	client := http.Client{Timeout: quit_timeout}
+	res, err := client.Post("http://"+addr+"/reload", "text/plain", nil)
+	if err == nil {
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			err = errors.New(res.Status)
+		}
+	}
+	if err != nil {
+		logger.Warnf("the demo didn't save its state: %v", err)
+	}
+
+	select {
+	case <-d.exited:
+	case <-time.After(quit_timeout):
+		logger.Warnf("the demo didn't quit, killing it")
+		d.cmd.Process.Kill()
+		<-d.exited
+	}
+}
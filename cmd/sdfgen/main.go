@@ -0,0 +1,60 @@
+// sdfgen makes the signed distance field atlas of a font ahead of time, a JSON file and a PNG next to it which
+// sdf.Load loads, so a program needn't rasterize the glyphs when it starts and can have characters beyond Latin-1.
+//
+//	go run ./cmd/sdfgen -font NotoSans-Regular.ttf -runes "αβγδ" -o assets/fonts/noto.json
+//
+// Without -font it makes the atlas of Go Regular, and the printable characters of Latin-1 are always in it.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sdf"
+)
+
+var logger = logging.Tag("sdfgen")
+
+var (
+	font_path = flag.String("font", "", "the .ttf or .otf file, Go Regular when empty")
+	runes     = flag.String("runes", "", "characters to have besides Latin-1")
+	size      = flag.Float64("size", 48, "pixels an em of the fields, larger keeps sharper corners")
+	spread    = flag.Float64("spread", 8, "pixels the fields reach either side of the edges, as wide as outlines and glows can be")
+	out       = flag.String("o", "font.json", "the JSON file to write, the PNG goes next to it")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	src := goregular.TTF
+	if *font_path != "" {
+		var err error
+		if src, err = os.ReadFile(*font_path); err != nil {
+			return err
+		}
+	}
+	f, err := sfnt.Parse(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *font_path, err)
+	}
+	a, err := sdf.Generate(f, sdf.Latin1+*runes, float32(*size), float32(*spread))
+	if err != nil {
+		return err
+	}
+	if err := a.Save(*out); err != nil {
+		return err
+	}
+	logger.Infof("%d glyphs in %dx%d pixels written to %s", len(a.Glyphs), a.Image.Bounds().Dx(), a.Image.Bounds().Dy(), *out)
+	return nil
+}
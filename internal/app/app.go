@@ -0,0 +1,345 @@
+// Package app is the small framework every demo runs inside of. It wraps a demo's ebiten.Game and adds the
+// things every demo wants but shouldn't have to implement: input polling and debugging controls such as
+// pausing, single-frame stepping, the frame debugger and the log console.
+package app
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/console"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/debugserver"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/framedebug"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/jobs"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pip"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/targets"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/text"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tweaks"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+// UIUpdater is implemented by demos which have UI that must keep responding while the demo is paused.
+// UpdateUI is called every tick before Update, regardless of whether the demo is paused.
+type UIUpdater interface {
+	UpdateUI() error
+}
+
+// Pipeliner is implemented by demos which render through the software pipeline. It enables the frame debugger.
+type Pipeliner interface {
+	Pipeline() *pipeline.Context
+}
+
+// Paused reports whether the demo's Update is currently frozen. Update is never called while paused
+// unless the user steps a frame, but a demo may still want to know from its UpdateUI or Draw.
+var Paused bool
+
+var logger = logging.Tag("app")
+
+// cvar_save_delay is how long tweaks have to stay as they are before they're saved.
+const cvar_save_delay = 500 * time.Millisecond
+
+var log_level = cvar.Int("log_level", int(logging.StderrLevel), cvar.Persist, "minimum level written to stderr, 0 is debug and 3 is error").
+	Range(0, float64(logging.LevelCount-1)).
+	Watch(func(v *cvar.Var) {
+		logging.StderrLevel = logging.Level(v.Int())
+	})
+
+type runner struct {
+	game ebiten.Game
+	// loader makes the game, again when a failure is retried
+	loader func() (ebiten.Game, error)
+
+	paused bool
+	// step is set when a single Update should be let through while paused
+	step bool
+
+	debugger   *framedebug.Debugger
+	console    *console.Console
+	tweaks     *tweaks.Panel
+	background background_panel
+	memory     memory_panel
+	spikes     spike_watchdog
+	pip        pip_strip
+	settings   settings_panel
+
+	// screenshots are where the next frame is saved, each answered once it's written
+	screenshots []screenshot
+	quit        bool
+
+	// server is the debug dashboard's, when there's one
+	server       *debugserver.Server
+	frame_wanted bool // the next frame is sent to the dashboard
+
+	// calls are the remote API's requests, run at the start of the next tick
+	calls chan func()
+
+	// ticks is the number of ticks which ran, including paused ones.
+	ticks int
+
+	replaying      bool
+	verify_pending bool  // the playback ran out, the next frame drawn is compared against the golden files
+	verified       bool  // the comparison happened, the result is in replay_err
+	replay_err     error //
+}
+
+// Run runs `game` the same way ebiten.RunGameWithOptions would. `options` may be nil.
+// Saved input bindings are loaded once the demo's and framework's defaults are bound.
+//
+// The -exec flag runs a file of console commands on the first tick, and -lang picks the language of the strings.
+//
+// The -record and -replay flags record the input of a session and play it back. A playback ends by comparing
+// the last frame and the session's stats against golden files next to the recording, see verify_replay.
+func Run(game ebiten.Game, options *ebiten.RunGameOptions) error {
+	return run(func() (ebiten.Game, error) { return game, nil }, options)
+}
+
+func run(load func() (ebiten.Game, error), options *ebiten.RunGameOptions) error {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+
+	logging.CaptureStdlog()
+	text.LoadFallbacks()
+
+	input.Bind("pause", input.Key(ebiten.KeyPause), input.Key(ebiten.KeyF9))
+	input.Bind("step", input.Key(ebiten.KeyF10))
+	input.Bind("capture_frame", input.Key(ebiten.KeyF12))
+	input.Bind("toggle_console", input.Key(ebiten.KeyBackquote))
+	input.Bind("toggle_tweaks", input.Key(ebiten.KeyF7))
+	input.Bind("toggle_background", input.Key(ebiten.KeyF6))
+	input.Bind("toggle_settings", input.Key(ebiten.KeyF8))
+	input.Bind("toggle_memory", input.Key(ebiten.KeyF5))
+	input.Bind("toggle_targets", input.Key(ebiten.KeyF2))
+	input.Bind("slow_motion", input.Key(ebiten.KeyF3))
+	input.Bind("fast_forward", input.Key(ebiten.KeyF4))
+	// the function keys type nothing, and the console's key has to close it
+	input.WhileTyping("pause", "step", "capture_frame", "toggle_console", "toggle_tweaks", "toggle_background",
+		"toggle_settings", "toggle_memory", "toggle_targets", "slow_motion", "fast_forward")
+
+	if err := input.Load(); err != nil {
+		logger.Warnf("could not load input bindings: %v", err)
+	}
+
+	if err := cvar.Load(); err != nil {
+		logger.Warnf("could not load cvars: %v", err)
+	}
+	logging.StderrLevel = logging.Level(log_level.Int())
+	Background.Budget = time.Duration(background_budget.Float() * float64(time.Millisecond))
+
+	hot, err := read_hot_state()
+	if err != nil {
+		logger.Warnf("could not read the hot state: %v", err)
+	} else if hot != nil {
+		hot.restore_cvars()
+	}
+
+	r := &runner{loader: load, console: console.New(), tweaks: tweaks.New()}
+	r.register_commands()
+
+	if err := r.start_replay(); err != nil {
+		return err
+	}
+	if err := r.load(); err != nil {
+		return err
+	}
+	if _, failed := r.game.(*failure); hot != nil && !failed {
+		hot.restore_game(r)
+	}
+	if err := apply_flags(); err != nil {
+		return err
+	}
+	if err := r.start_debug_server(); err != nil {
+		return err
+	}
+	if err := r.start_remote(); err != nil {
+		return err
+	}
+	defer r.finish_recording()
+
+	err = ebiten.RunGameWithOptions(r, options)
+	// tweaks made just before quitting haven't settled yet
+	if err := cvar.Save(); err != nil {
+		logger.Errorf("could not save cvars: %v", err)
+	}
+	return err
+}
+
+func (r *runner) Update() error {
+	input.Update()
+
+	if r.quit {
+		return ebiten.Termination
+	}
+
+	// tweaks made from the console or the panel are saved once they stop changing
+	if err := cvar.SaveSettled(cvar_save_delay); err != nil {
+		logger.Errorf("could not save cvars: %v", err)
+	}
+
+	if f, ok := r.game.(*failure); ok {
+		if err := r.update_failure(f); err != nil {
+			return err
+		}
+	}
+
+	if *exec_path != "" {
+		if err := r.run_exec(); err != nil {
+			return err
+		}
+	}
+
+	if r.replaying && input.PlaybackDone() {
+		r.verify_pending = true
+		if r.verified {
+			if r.replay_err != nil {
+				return r.replay_err
+			}
+			return ebiten.Termination
+		}
+		return nil
+	}
+
+	r.ticks++
+
+	if input.JustPressed("pause") {
+		r.paused = !r.paused
+	}
+
+	if r.paused && input.JustPressed("step") {
+		r.step = true
+	}
+
+	if r.debugger != nil && input.JustPressed("capture_frame") {
+		r.debugger.Capture()
+		// pausing keeps the frame on screen identical to the captured one while it's inspected
+		r.paused = true
+		r.frame_wanted = r.server != nil
+	}
+
+	if input.JustPressed("toggle_console") {
+		r.console.Toggle()
+	} else {
+		r.console.Update()
+	}
+
+	if input.JustPressed("toggle_tweaks") {
+		r.tweaks.Toggle()
+	}
+
+	if input.JustPressed("toggle_background") {
+		r.background.open = !r.background.open
+	}
+
+	if input.JustPressed("toggle_settings") {
+		r.settings.open = !r.settings.open
+	}
+
+	if input.JustPressed("toggle_memory") {
+		r.memory.open = !r.memory.open
+	}
+
+	if input.JustPressed("toggle_targets") {
+		r.pip.open = !r.pip.open
+	}
+
+	update_time_scale()
+	r.update_debug_server()
+	r.run_remote_calls()
+
+	jobs.Default.Complete()
+	Background.Run()
+
+	Paused = r.paused
+
+	ui.Update()
+
+	if u, ok := r.game.(UIUpdater); ok {
+		if err := u.UpdateUI(); err != nil {
+			return r.fail(err, nil)
+		}
+	}
+
+	if r.paused && !r.step {
+		return nil
+	}
+	r.step = false
+
+	last_tick = time.Now()
+	if err, stack := recovered(r.game.Update); err != nil {
+		return r.fail(err, stack)
+	}
+	return nil
+}
+
+func (r *runner) Draw(screen *ebiten.Image) {
+	if r.replaying {
+		r.game.Draw(screen)
+	} else if err, stack := recovered(func() error { r.game.Draw(screen); return nil }); err != nil {
+		r.fail(err, stack)
+	}
+
+	if len(r.screenshots) > 0 {
+		r.take_screenshots(screen)
+	}
+
+	if r.frame_wanted {
+		r.send_frame(screen)
+	}
+
+	if r.verify_pending && !r.verified {
+		r.replay_err = r.verify_replay(screen)
+		r.verified = true
+	}
+
+	if r.debugger != nil {
+		r.debugger.Draw(screen)
+	}
+
+	r.pip.Draw(screen)
+	r.background.Draw(screen)
+	var context *pipeline.Context
+	if p, ok := r.game.(Pipeliner); ok {
+		context = p.Pipeline()
+	}
+	r.memory.Draw(screen, context)
+	r.settings.Draw(screen, r)
+	r.tweaks.Draw(screen)
+	r.console.Draw(screen)
+
+	h := screen.Bounds().Dy()
+	if r.paused {
+		msg := fmt.Sprintf("PAUSED (%s resume, %s step)", input.Describe("pause"), input.Describe("step"))
+		ebitenutil.DebugPrintAt(screen, msg, 0, h-16)
+		h -= 16
+	}
+	if msg := time_scale_label(); msg != "" {
+		ebitenutil.DebugPrintAt(screen, msg, 0, h-16)
+	}
+
+	r.spikes.Frame(r, context)
+
+	ui.NextFrame()
+	pip.EndFrame()
+	targets.Default.EndFrame()
+}
+
+// set_game runs `game` from now on, the frame debugger follows it when it renders through the pipeline.
+func (r *runner) set_game(game ebiten.Game) {
+	r.game = game
+	r.debugger = nil
+	if p, ok := game.(Pipeliner); ok {
+		r.debugger = framedebug.New(p.Pipeline())
+	}
+}
+
+func (r *runner) Layout(outside_width, outside_height int) (int, int) {
+	return r.game.Layout(outside_width, outside_height)
+}
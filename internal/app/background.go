@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/budget"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+// Background runs the background tasks of the demo, a slice of them every tick, whether the demo is paused or not.
+var Background = budget.New(2 * time.Millisecond)
+
+var background_budget = cvar.Float("app_background_ms", 2, cvar.Persist, "milliseconds per tick background tasks may use").
+	Range(0, 16).
+	Watch(func(v *cvar.Var) {
+		Background.Budget = time.Duration(v.Float() * float64(time.Millisecond))
+	})
+
+const (
+	background_width      = 360
+	background_row_height = 16
+)
+
+// background_panel lists the background tasks and how much of the budget each one used.
+type background_panel struct {
+	ui   *ui.Context
+	open bool
+}
+
+func (p *background_panel) Draw(screen *ebiten.Image) {
+	if !p.open {
+		return
+	}
+	if p.ui == nil {
+		p.ui = ui.NewContext()
+	}
+
+	tasks := Background.Tasks()
+	rows := 1 + len(tasks) + len(Background.Finished)
+
+	bounds := screen.Bounds()
+	height := rows * background_row_height
+	x := bounds.Max.X - background_width
+	y := bounds.Max.Y - height
+
+	p.ui.StartFrame(screen)
+	p.ui.Push(x, y, background_width, height, nil)
+	p.ui.Panel()
+	p.ui.Pop()
+
+	p.ui.Push(x+4, y, background_width-4, height, &ui.RowLayout{Height: background_row_height})
+	p.ui.Label(fmt.Sprintf("background %v / %v", Background.Used.Round(time.Microsecond), Background.Budget), 0, 0.5)
+	for _, t := range tasks {
+		p.ui.Label(fmt.Sprintf("%-20.20s %8v %5d steps", t.Name, t.Tick.Round(time.Microsecond), t.Steps), 0, 0.5)
+	}
+	for _, t := range Background.Finished {
+		p.ui.Label(fmt.Sprintf("%-20.20s done in %v", t.Name, t.Total.Round(time.Microsecond)), 0, 0.5)
+	}
+	p.ui.Pop()
+
+	p.ui.EndFrame()
+}
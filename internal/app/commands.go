@@ -0,0 +1,202 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/console"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/invalidate"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/locale"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/targets"
+)
+
+var (
+	exec_path = flag.String("exec", "", "run the console commands in `file` once the demo starts")
+	lang      = flag.String("lang", "", "show the strings of the demo in `language`, such as de or ja")
+)
+
+// register_commands adds the console commands every demo has.
+func (r *runner) register_commands() {
+	console.Register("screenshot", "[file]", "saves the next frame as a png, without any overlays", func(args console.Args) error {
+		path := time.Now().Format("screenshot-20060102-150405.png")
+		if len(args) > 0 {
+			path = args[0]
+		}
+		r.screenshots = append(r.screenshots, screenshot{path: path})
+		return nil
+	})
+
+	console.Register("pause", "[on|off]", "pauses or resumes the demo", func(args console.Args) error {
+		if len(args) == 0 {
+			r.paused = !r.paused
+			return nil
+		}
+		paused, err := args.Bool(0)
+		if err != nil {
+			return err
+		}
+		r.paused = paused
+		return nil
+	})
+
+	console.Register("set", "<cvar> <value>", "sets a cvar, the value can be an expression such as 60*pi/180", func(args console.Args) error {
+		v, err := lookup_cvar(args)
+		if err != nil {
+			return err
+		}
+		if len(args) < 2 {
+			return console.ErrUsage
+		}
+		if err := v.Set(strings.Join(args[1:], " ")); err != nil {
+			return err
+		}
+		logger.Infof("%s = %s", v.Name(), v)
+		return nil
+	})
+
+	console.Register("get", "<cvar>", "prints the value of a cvar", func(args console.Args) error {
+		v, err := lookup_cvar(args)
+		if err != nil {
+			return err
+		}
+		logger.Infof("%s = %s (%s) - %s", v.Name(), v, v.Kind(), v.Help())
+		return nil
+	})
+
+	console.Register("reset", "<cvar>", "restores the default value of a cvar", func(args console.Args) error {
+		v, err := lookup_cvar(args)
+		if err != nil {
+			return err
+		}
+		v.Reset()
+		logger.Infof("%s = %s", v.Name(), v)
+		return nil
+	})
+
+	console.Register("cvars", "[prefix]", "lists the cvars, or the ones starting with prefix", func(args console.Args) error {
+		prefix := ""
+		if len(args) > 0 {
+			prefix = args[0]
+		}
+		for _, v := range cvar.All() {
+			if strings.HasPrefix(v.Name(), prefix) {
+				logger.Infof("%s = %s", v.Name(), v)
+			}
+		}
+		return nil
+	})
+
+	console.Register("targets", "", "prints the statistics of the render target pool", func(args console.Args) error {
+		s := targets.Default.Stats()
+		logger.Infof("%d hits, %d misses, %d in use, %d free, %d freed", s.Hits, s.Misses, s.InUse, s.Free, s.Freed)
+		for key, n := range targets.Default.Keys() {
+			logger.Infof("  %v: %d free", key, n)
+		}
+		return nil
+	})
+
+	console.Register("purge", "[cache...]", "throws away cached data so it's rebuilt, every cache without arguments", func(args console.Args) error {
+		for _, name := range args {
+			if !slices.Contains(invalidate.Names(), name) {
+				return fmt.Errorf("unknown cache %q, see caches", name)
+			}
+		}
+		n := invalidate.Purge(args...)
+		logger.Infof("purged %d caches", n)
+		return nil
+	})
+
+	console.Register("caches", "", "lists the caches purge knows about", func(args console.Args) error {
+		logger.Infof("%s", strings.Join(invalidate.Names(), ", "))
+		return nil
+	})
+
+	console.Register("language", "[code]", "switches the language of the demo's strings, lists them without arguments", func(args console.Args) error {
+		if len(args) == 0 {
+			logger.Infof("%s, available: %s", locale.Language(), strings.Join(locale.Languages(), ", "))
+			return nil
+		}
+		locale.SetLanguage(args[0])
+		return nil
+	})
+
+	console.Register("spikes", "[file]", "lists the frames which took longer than app_spike_ms, or saves their reports", func(args console.Args) error {
+		if len(args) > 0 {
+			if err := r.spikes.save(args[0]); err != nil {
+				return err
+			}
+			logger.Infof("saved %d spikes to %s", len(r.spikes.spikes), args[0])
+			return nil
+		}
+		if len(r.spikes.spikes) == 0 {
+			logger.Infof("no spikes over %vms", spike_ms)
+		}
+		for _, s := range r.spikes.spikes {
+			logger.Infof("%s tick %d took %v, %d gcs, %d log lines", s.Time.Format("15:04:05.000"), s.Tick,
+				s.Frame.Round(time.Microsecond), s.GCs, len(s.Log))
+		}
+		return nil
+	})
+
+	console.Register("quit", "", "exits the demo", func(args console.Args) error {
+		r.quit = true
+		return nil
+	})
+}
+
+// screenshot is a file the next frame is saved to, and what to answer once it's written when a remote request
+// asked for it.
+type screenshot struct {
+	path  string
+	reply func(err error)
+}
+
+// take_screenshots saves the frame to every file asked for since the last one.
+func (r *runner) take_screenshots(screen *ebiten.Image) {
+	bounds := screen.Bounds()
+	frame := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	screen.ReadPixels(frame.Pix)
+
+	for _, s := range r.screenshots {
+		err := write_png(s.path, frame)
+		if s.reply != nil {
+			s.reply(err)
+		}
+		if err != nil {
+			logger.Errorf("could not save screenshot: %v", err)
+			continue
+		}
+		logger.Infof("saved screenshot %s", s.path)
+	}
+	clear(r.screenshots)
+	r.screenshots = r.screenshots[:0]
+}
+
+// run_exec runs the -exec file. It's deferred to the first tick so the commands run against a fully started demo.
+func (r *runner) run_exec() error {
+	path := *exec_path
+	*exec_path = ""
+	if err := console.Exec(path); err != nil {
+		return fmt.Errorf("could not run %s: %w", path, err)
+	}
+	return nil
+}
+
+func lookup_cvar(args console.Args) (*cvar.Var, error) {
+	name, err := args.String(0)
+	if err != nil {
+		return nil, err
+	}
+	v := cvar.Lookup(name)
+	if v == nil {
+		return nil, fmt.Errorf("unknown cvar %q", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/debugserver"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+)
+
+var debug_server = flag.String("debug-server", "", "serve stats, the log and frames to a dashboard in a browser at `address`, such as localhost:6060")
+
+// stats_ticks is how often the stats are sent to the dashboard, in ticks.
+const stats_ticks = 30
+
+type log_entry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Tag     string `json:"tag"`
+	Message string `json:"message"`
+}
+
+// start_debug_server serves the dashboard when -debug-server says where, and sends it the log from now on.
+func (r *runner) start_debug_server() error {
+	if *debug_server == "" {
+		return nil
+	}
+	s := debugserver.New()
+	if err := s.ListenAndServe(*debug_server); err != nil {
+		return err
+	}
+	r.server = s
+	logging.Listen(func(e logging.Entry) {
+		s.Send("log", log_entry{e.Time.Format("15:04:05.000"), e.Level.String(), e.Tag, e.Message})
+	})
+	logger.Infof("debug dashboard at http://%s/", *debug_server)
+	return nil
+}
+
+// update_debug_server sends the stats every so often and takes in what the dashboards asked for.
+func (r *runner) update_debug_server() {
+	s := r.server
+	if s == nil {
+		return
+	}
+	for {
+		request, ok := s.Request()
+		if !ok {
+			break
+		}
+		switch request {
+		case "frame":
+			r.frame_wanted = true
+		default:
+			logger.Warnf("the dashboard asked for %q, which isn't a thing", request)
+		}
+	}
+	if r.ticks%stats_ticks != 0 || s.Clients() == 0 {
+		return
+	}
+
+	s.Send("stats", r.stats())
+}
+
+// stats are the numbers the dashboard and the remote API report.
+func (r *runner) stats() map[string]any {
+	stats := map[string]any{
+		"tps":        ebiten.ActualTPS(),
+		"fps":        ebiten.ActualFPS(),
+		"ticks":      r.ticks,
+		"paused":     r.paused,
+		"time scale": TimeScale(),
+		"spikes":     len(r.spikes.spikes),
+	}
+	if p, ok := r.game.(Pipeliner); ok {
+		pipeline := p.Pipeline().Stats
+		stats["meshes"] = pipeline.Meshes
+		stats["rejected"] = pipeline.Rejected
+		stats["triangles"] = pipeline.Triangles
+	}
+	return stats
+}
+
+// send_frame sends the frame the demo drew, without any overlays, to the dashboards. It's encoded in the
+// background, only reading it back holds up the frame.
+func (r *runner) send_frame(screen *ebiten.Image) {
+	r.frame_wanted = false
+	bounds := screen.Bounds()
+	frame := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	screen.ReadPixels(frame.Pix)
+	go func() {
+		var b bytes.Buffer
+		if err := png.Encode(&b, frame); err != nil {
+			logger.Errorf("could not encode the frame for the dashboard: %v", err)
+			return
+		}
+		r.server.SendFrame(b.Bytes())
+	}()
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/flags"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+// RunLoader runs the demo `load` makes the same way Run runs a game. When loading fails, returning an error or
+// panicking, the window shows what went wrong instead of the demo: the error, the stack of a panic, and buttons to
+// retry the loading or to reload, which starts the demo over with the same flags. Errors the demo's Update returns
+// and panics in its Update and Draw are shown the same way. A replay still ends with the error, it has no one to
+// click the buttons.
+func RunLoader(load func() (ebiten.Game, error), options *ebiten.RunGameOptions) error {
+	return run(load, options)
+}
+
+// failure is what's shown in place of a demo which failed.
+type failure struct {
+	err   error
+	stack []string
+
+	ui   *ui.Context
+	list ui.List
+
+	retry  bool
+	reload bool
+}
+
+// recovered runs `fn`, turning a panic into an error along with the stack it happened on.
+func recovered(fn func() error) (err error, stack []byte) {
+	defer func() {
+		if p := recover(); p != nil {
+			err, stack = fmt.Errorf("panic: %v", p), debug.Stack()
+		}
+	}()
+	return fn(), nil
+}
+
+// load makes the demo, or the failure to show in its place.
+func (r *runner) load() error {
+	var game ebiten.Game
+	err, stack := recovered(func() (err error) {
+		game, err = r.loader()
+		return err
+	})
+	if err == nil {
+		r.set_game(game)
+		return nil
+	}
+	return r.fail(err, stack)
+}
+
+// fail shows `err` in place of the demo, or returns it when there's no one to show it to.
+func (r *runner) fail(err error, stack []byte) error {
+	if r.replaying || errors.Is(err, ebiten.Termination) {
+		return err
+	}
+	logger.Errorf("the demo failed: %v", err)
+	f := &failure{err: err}
+	if len(stack) > 0 {
+		f.stack = strings.Split(strings.TrimSpace(string(stack)), "\n")
+	}
+	r.set_game(f)
+	return nil
+}
+
+// update_failure retries or reloads once a button asked for it.
+func (r *runner) update_failure(f *failure) error {
+	switch {
+	case f.retry:
+		return r.load()
+	case f.reload:
+		reload()
+		r.quit = true
+	}
+	return nil
+}
+
+// reload starts the demo over as a new process with its flags, changes waiting for a restart included. This one
+// quits once it started.
+func reload() {
+	path, err := os.Executable()
+	if err != nil {
+		logger.Errorf("could not reload: %v", err)
+		return
+	}
+	cmd := exec.Command(path, flags.Default.Args()...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		logger.Errorf("could not reload: %v", err)
+	}
+}
+
+const failure_row_height = 16
+
+func (f *failure) Update() error {
+	return nil
+}
+
+func (f *failure) Draw(screen *ebiten.Image) {
+	if f.ui == nil {
+		f.ui = ui.NewContext()
+	}
+	screen.Fill(color.RGBA{40, 16, 20, 255})
+
+	bounds := screen.Bounds().Inset(16)
+	message := strings.Split(f.err.Error(), "\n")
+
+	f.ui.StartFrame(screen)
+	f.ui.Push(bounds.Min.X, bounds.Min.Y, bounds.Dx(), bounds.Dy(), nil)
+	f.ui.Panel()
+	f.ui.Pop()
+
+	inside := bounds.Inset(8)
+	y := inside.Min.Y
+	f.ui.Push(inside.Min.X, y, inside.Dx(), (1+len(message))*failure_row_height, &ui.RowLayout{Height: failure_row_height})
+	f.ui.Label("The demo failed:", 0, 0.5)
+	for _, line := range message {
+		f.ui.Label(line, 0, 0.5)
+	}
+	f.ui.Pop()
+	y += (2 + len(message)) * failure_row_height
+
+	// the stack takes what room is left above the buttons
+	if len(f.stack) > 0 {
+		height := inside.Max.Y - 2*failure_row_height - y
+		f.ui.Push(inside.Min.X, y, inside.Dx(), height, nil)
+		f.ui.List(&f.list, len(f.stack), failure_row_height, func(i int) {
+			f.ui.Label(strings.ReplaceAll(f.stack[i], "\t", "    "), 0, 0.5)
+		})
+		f.ui.Pop()
+	}
+
+	f.ui.Push(inside.Min.X, inside.Max.Y-failure_row_height-4, 200, failure_row_height+4, &ui.GridLayout{Columns: 2, Rows: 1})
+	f.ui.Button(ui.ButtonArgs{Text: "Retry", AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: func() { f.retry = true }}})
+	f.ui.Button(ui.ButtonArgs{Text: "Reload", AlignX: 0.5, AlignY: 0.5, Behavior: ui.ButtonBehavior{OnActivate: func() { f.reload = true }}})
+	f.ui.Pop()
+
+	f.ui.EndFrame()
+}
+
+func (f *failure) Layout(outside_width, outside_height int) (int, int) {
+	return outside_width, outside_height
+}
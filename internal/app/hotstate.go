@@ -0,0 +1,104 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+)
+
+var hot_state_path = flag.String("hot-state", "", "keep the cvars, camera and state of the demo in `file` across restarts, for cmd/dev")
+
+// Camerer is implemented by demos with a fly camera, which is kept across restarts by cmd/dev.
+type Camerer interface {
+	Camera() *camera.Camera
+}
+
+// HotStater is implemented by demos with more state to keep across restarts by cmd/dev than their cvars and camera,
+// like the scene being edited. HotState is saved as JSON, and RestoreHotState is given it back once the demo is
+// loaded again, maybe by a newer build which changed what it looks like.
+type HotStater interface {
+	HotState() any
+	RestoreHotState(data []byte) error
+}
+
+// hot_state is what the -hot-state file holds.
+type hot_state struct {
+	Cvars  map[string]string `json:"cvars"`
+	Camera *camera.Camera    `json:"camera,omitempty"`
+	Game   json.RawMessage   `json:"game,omitempty"`
+}
+
+// save_hot_state writes the state of the demo to the -hot-state file.
+func (r *runner) save_hot_state() error {
+	if *hot_state_path == "" {
+		return errors.New("there's no -hot-state file")
+	}
+	state := hot_state{Cvars: make(map[string]string)}
+	for _, v := range cvar.All() {
+		state.Cvars[v.Name()] = v.String()
+	}
+	if c, ok := r.game.(Camerer); ok {
+		state.Camera = c.Camera()
+	}
+	if h, ok := r.game.(HotStater); ok {
+		game, err := json.Marshal(h.HotState())
+		if err != nil {
+			return err
+		}
+		state.Game = game
+	}
+	src, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*hot_state_path, src, 0o644)
+}
+
+// read_hot_state reads the -hot-state file, nil when there's none yet.
+func read_hot_state() (*hot_state, error) {
+	if *hot_state_path == "" {
+		return nil, nil
+	}
+	src, err := os.ReadFile(*hot_state_path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state hot_state
+	if err := json.Unmarshal(src, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// restore_cvars sets the cvars kept, before the demo is loaded so it loads with them. Those which are gone in the
+// new build, or won't take their old value, are skipped.
+func (s *hot_state) restore_cvars() {
+	for name, value := range s.Cvars {
+		if v := cvar.Lookup(name); v != nil {
+			if err := v.Set(value); err != nil {
+				logger.Warnf("could not restore %s = %s: %v", name, value, err)
+			}
+		}
+	}
+}
+
+// restore_game gives the demo back its camera and state, once it's loaded.
+func (s *hot_state) restore_game(r *runner) {
+	if c, ok := r.game.(Camerer); ok && s.Camera != nil {
+		kept := c.Camera()
+		kept.Position, kept.Pitch, kept.Yaw = s.Camera.Position, s.Camera.Pitch, s.Camera.Yaw
+		kept.Speed, kept.Fov = s.Camera.Speed, s.Camera.Fov
+	}
+	if h, ok := r.game.(HotStater); ok && len(s.Game) > 0 {
+		if err := h.RestoreHotState(s.Game); err != nil {
+			logger.Warnf("could not restore the state of the demo: %v", err)
+		}
+	}
+}
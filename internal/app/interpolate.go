@@ -0,0 +1,26 @@
+package app
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+)
+
+var interpolate = cvar.Bool("r_interpolate", true, cvar.Persist, "draws what ticks move between where the last two ticks left it, smooth when frames come faster than ticks")
+
+// last_tick is when the demo's Update last ran.
+var last_tick time.Time
+
+// Interpolation is how far the frame being drawn is from the tick before the last, at 0, to the last, at 1, for
+// drawing what Update moves between the two, see scene.WalkBetween. Frames are drawn a tick behind that way, but
+// they're smooth when the display runs faster than the ticks. It's 1 while paused, during replays, whose frames
+// have to come out the same every time, and with r_interpolate off.
+func Interpolation() float32 {
+	if !interpolate.Bool() || Paused || *replay_path != "" || last_tick.IsZero() {
+		return 1
+	}
+	tick := time.Second / time.Duration(ebiten.TPS())
+	return float32(min(float64(time.Since(last_tick))/float64(tick), 1))
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/memory"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+const (
+	memory_width      = 360
+	memory_row_height = 16
+)
+
+// memory_panel shows the heap, the garbage collector, what every frame allocated and the bytes of every counter of
+// package memory. The runtime's statistics are only read while it's open.
+type memory_panel struct {
+	ui      *ui.Context
+	open    bool
+	tracker memory.Tracker
+}
+
+// size formats `bytes` in the largest unit it's at least one of.
+func size(bytes int64) string {
+	switch b := float64(bytes); {
+	case bytes >= 1<<30 || bytes <= -1<<30:
+		return fmt.Sprintf("%.1f GiB", b/(1<<30))
+	case bytes >= 1<<20 || bytes <= -1<<20:
+		return fmt.Sprintf("%.1f MiB", b/(1<<20))
+	case bytes >= 1<<10 || bytes <= -1<<10:
+		return fmt.Sprintf("%.1f KiB", b/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
+
+// Draw draws the panel, `context` is the pipeline of the demo, nil when it has none.
+func (p *memory_panel) Draw(screen *ebiten.Image, context *pipeline.Context) {
+	if !p.open {
+		p.tracker = memory.Tracker{}
+		return
+	}
+	if p.ui == nil {
+		p.ui = ui.NewContext()
+	}
+
+	f := p.tracker.Sample()
+	peak, pause := p.tracker.Peak()
+	counters := memory.Counters()
+	rows := 4 + len(counters)
+	if context != nil {
+		rows++
+	}
+
+	bounds := screen.Bounds()
+	height := rows * memory_row_height
+	x := bounds.Max.X - memory_width
+	y := bounds.Min.Y
+
+	p.ui.StartFrame(screen)
+	p.ui.Push(x, y, memory_width, height, nil)
+	p.ui.Panel()
+	p.ui.Pop()
+
+	p.ui.Push(x+4, y, memory_width-4, height, &ui.RowLayout{Height: memory_row_height})
+	p.ui.Label(fmt.Sprintf("heap %s, %d objects, %s from the OS", size(int64(f.Heap)), f.Objects,
+		size(int64(f.System))), 0, 0.5)
+	p.ui.Label(fmt.Sprintf("frame %d allocs, %s (peak %s)", f.Allocs, size(int64(f.AllocBytes)),
+		size(int64(peak))), 0, 0.5)
+	p.ui.Label(fmt.Sprintf("gc %d this frame, longest pause %v", f.GCs, pause.Round(time.Microsecond)), 0, 0.5)
+	p.ui.Label("held by", 0, 0.5)
+	for _, c := range counters {
+		p.ui.Label(fmt.Sprintf("  %-20.20s %12s", c.Name(), size(c.Bytes())), 0, 0.5)
+	}
+	if context != nil {
+		p.ui.Label(fmt.Sprintf("  %-20.20s %12s", "meshes pushed", size(int64(context.Stats.MeshBytes))), 0, 0.5)
+	}
+	p.ui.Pop()
+
+	p.ui.EndFrame()
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pip"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+const (
+	pip_width       = 128
+	pip_height      = 96
+	pip_label       = 14
+	pip_enlarged_of = 0.75
+)
+
+// pip_strip shows a thumbnail of every offscreen image the frame handed to pip.Show along the bottom of the
+// screen, clicking one shows it large and clicking it again closes it.
+type pip_strip struct {
+	ui       *ui.Context
+	open     bool
+	enlarged string
+}
+
+func (p *pip_strip) Draw(screen *ebiten.Image) {
+	pip.Enabled = p.open
+	if !p.open {
+		return
+	}
+	if p.ui == nil {
+		p.ui = ui.NewContext()
+	}
+
+	bounds := screen.Bounds()
+	views := pip.Views()
+	p.ui.StartFrame(screen)
+
+	strip := bounds.Max.Y - pip_height - pip_label
+	for i, v := range views {
+		x := bounds.Min.X + i*pip_width
+		p.ui.Push(x, strip, pip_width, pip_height+pip_label, nil)
+		p.ui.Button(ui.ButtonArgs{Text: v.Name, AlignX: 0.5, Selected: v.Name == p.enlarged, Behavior: ui.ButtonBehavior{
+			OnActivate: func() {
+				if p.enlarged == v.Name {
+					p.enlarged = ""
+				} else {
+					p.enlarged = v.Name
+				}
+			},
+		}})
+		p.ui.Pop()
+		p.ui.Push(x+2, strip+pip_label, pip_width-4, pip_height-2, nil)
+		p.ui.Image(v.Image)
+		p.ui.Pop()
+	}
+
+	for _, v := range views {
+		if v.Name != p.enlarged {
+			continue
+		}
+		w, h := int(float64(bounds.Dx())*pip_enlarged_of), int(float64(bounds.Dy())*pip_enlarged_of)
+		x, y := bounds.Min.X+(bounds.Dx()-w)/2, bounds.Min.Y+(strip-h)/2
+		p.ui.Push(x, y, w, h, nil)
+		p.ui.Panel()
+		p.ui.Pop()
+		p.ui.Push(x+4, y+pip_label, w-8, h-pip_label-4, nil)
+		p.ui.Image(v.Image)
+		p.ui.Pop()
+		p.ui.Push(x+4, y, w-8, pip_label, nil)
+		size := v.Size()
+		p.ui.Label(fmt.Sprintf("%s %dx%d", v.Name, size.X, size.Y), 0, 0.5)
+		p.ui.Pop()
+	}
+
+	p.ui.EndFrame()
+}
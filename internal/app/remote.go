@@ -0,0 +1,218 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/console"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+)
+
+var remote_addr = flag.String("remote", "", "take commands over HTTP at `address`, such as localhost:6061, see remote.go for the API")
+
+// The remote API, all of it JSON:
+//
+//	GET  /stats                  the numbers the debug dashboard shows
+//	GET  /cvars                  every cvar and its value
+//	GET  /cvars/{name}           a cvar, its value, kind and help
+//	PUT  /cvars/{name}           sets a cvar to the body, such as 60 or 60*pi/180
+//	POST /screenshot?file=a.png  saves the next frame in the screenshots directory, answered once it's written
+//	POST /exec                   runs the body as a console command, such as load teapot.obj
+//	POST /reload                 saves the -hot-state file and quits, for cmd/dev to start the new build
+//
+// Errors are answered as {"error": "..."}. The game runs a tick at a time, so everything is done by the next tick on
+// the game's goroutine and nothing races it.
+//
+// The API is for scripts on the machine, not other machines or web pages, which could otherwise have a browser post
+// commands to it: it only listens on loopback addresses, requests with an Origin, which browsers send along with
+// those of pages, are refused, and so are those for a host by name other than localhost, which a page could point at
+// the API by rebinding its DNS. Screenshots only go into the screenshots directory.
+
+// remote_screenshots is the directory, under the working directory, the remote API saves screenshots in.
+const remote_screenshots = "screenshots"
+
+// remote_timeout is how long a request waits for the game to get to it, a paused one still ticks.
+const remote_timeout = 10 * time.Second
+
+// remote_handler handles a request on the game's goroutine, answering it with `reply` then or later. `body` is the
+// body of the request, read already.
+type remote_handler func(req *http.Request, body string, reply func(v any, err error))
+
+type cvar_json struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Kind  string `json:"kind,omitempty"`
+	Help  string `json:"help,omitempty"`
+}
+
+// start_remote serves the remote API when -remote says where.
+func (r *runner) start_remote() error {
+	if *remote_addr == "" {
+		return nil
+	}
+	l, err := net.Listen("tcp", *remote_addr)
+	if err != nil {
+		return err
+	}
+	// anyone who reaches the API can run any command, so it's only reached from this machine
+	if addr, ok := l.Addr().(*net.TCPAddr); !ok || !addr.IP.IsLoopback() {
+		l.Close()
+		return fmt.Errorf("-remote %s is reachable from other machines, listen on localhost instead", *remote_addr)
+	}
+	r.calls = make(chan func(), 16)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stats", r.handle(func(_ *http.Request, _ string, reply func(any, error)) {
+		reply(r.stats(), nil)
+	}))
+	mux.HandleFunc("GET /cvars", r.handle(func(_ *http.Request, _ string, reply func(any, error)) {
+		values := make(map[string]string)
+		for _, v := range cvar.All() {
+			values[v.Name()] = v.String()
+		}
+		reply(values, nil)
+	}))
+	mux.HandleFunc("GET /cvars/{name}", r.handle(func(req *http.Request, body string, reply func(any, error)) {
+		v := cvar.Lookup(req.PathValue("name"))
+		if v == nil {
+			reply(nil, errors.New("no such cvar"))
+			return
+		}
+		reply(cvar_json{v.Name(), v.String(), v.Kind().String(), v.Help()}, nil)
+	}))
+	mux.HandleFunc("PUT /cvars/{name}", r.handle(func(req *http.Request, body string, reply func(any, error)) {
+		v := cvar.Lookup(req.PathValue("name"))
+		if v == nil {
+			reply(nil, errors.New("no such cvar"))
+			return
+		}
+		if err := v.Set(body); err != nil {
+			reply(nil, err)
+			return
+		}
+		logger.Infof("%s = %s, remotely", v.Name(), v)
+		reply(cvar_json{Name: v.Name(), Value: v.String()}, nil)
+	}))
+	mux.HandleFunc("POST /screenshot", r.handle(func(req *http.Request, _ string, reply func(any, error)) {
+		name := req.URL.Query().Get("file")
+		if name == "" {
+			name = time.Now().Format("screenshot-20060102-150405.png")
+		}
+		if !filepath.IsLocal(name) {
+			reply(nil, errors.New("file has to be a relative path which stays in the screenshots directory"))
+			return
+		}
+		path := filepath.Join(remote_screenshots, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			reply(nil, err)
+			return
+		}
+		r.screenshots = append(r.screenshots, screenshot{path, func(err error) {
+			reply(map[string]string{"file": path}, err)
+		}})
+	}))
+	mux.HandleFunc("POST /exec", r.handle(func(req *http.Request, body string, reply func(any, error)) {
+		if err := console.Execute(body); err != nil {
+			reply(nil, err)
+			return
+		}
+		reply(map[string]bool{"ok": true}, nil)
+	}))
+
+	mux.HandleFunc("POST /reload", r.handle(func(req *http.Request, _ string, reply func(any, error)) {
+		if err := r.save_hot_state(); err != nil {
+			reply(nil, err)
+			return
+		}
+		r.quit = true
+		reply(map[string]string{"file": *hot_state_path}, nil)
+	}))
+
+	go http.Serve(l, mux)
+	logger.Infof("remote API at http://%s/", *remote_addr)
+	return nil
+}
+
+// handle makes a handler which has `fn` run on the game's goroutine and writes its reply.
+func (r *runner) handle(fn remote_handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !from_script(req) {
+			write_json(w, http.StatusForbidden, map[string]string{"error": "the remote API doesn't take requests from web pages"})
+			return
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			write_json(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		type result struct {
+			v   any
+			err error
+		}
+		done := make(chan result, 1)
+		call := func() {
+			fn(req, strings.TrimSpace(string(body)), func(v any, err error) { done <- result{v, err} })
+		}
+		select {
+		case r.calls <- call:
+		case <-time.After(remote_timeout):
+			write_json(w, http.StatusServiceUnavailable, map[string]string{"error": "the game is busy"})
+			return
+		}
+
+		select {
+		case res := <-done:
+			if res.err != nil {
+				write_json(w, http.StatusBadRequest, map[string]string{"error": res.err.Error()})
+				return
+			}
+			write_json(w, http.StatusOK, res.v)
+		case <-time.After(remote_timeout):
+			write_json(w, http.StatusGatewayTimeout, map[string]string{"error": "the game didn't get to it"})
+		case <-req.Context().Done():
+		}
+	}
+}
+
+// from_script reports whether `req` comes from a script on this machine rather than a web page, see the API above.
+func from_script(req *http.Request) bool {
+	if req.Header.Get("Origin") != "" {
+		return false
+	}
+	if addr, err := netip.ParseAddrPort(req.RemoteAddr); err != nil || !addr.Addr().IsLoopback() {
+		return false
+	}
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+	return strings.EqualFold(host, "localhost") || net.ParseIP(strings.Trim(host, "[]")) != nil
+}
+
+func write_json(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// run_remote_calls runs the calls which came in since the last tick.
+func (r *runner) run_remote_calls() {
+	for {
+		select {
+		case call := <-r.calls:
+			call()
+		default:
+			return
+		}
+	}
+}
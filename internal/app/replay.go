@@ -0,0 +1,186 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+var (
+	record_path = flag.String("record", "", "record the input of this session to `file`")
+	replay_path = flag.String("replay", "", "play back the input recorded in `file` and compare the last frame against its golden files")
+)
+
+// golden_tolerance is how far a channel of a pixel may drift from the golden image before it counts as different.
+// GPUs and drivers don't rasterize identically, so an exact match is too strict.
+const golden_tolerance = 2
+
+// golden_max_different is the fraction of pixels which may differ from the golden image.
+const golden_max_different = 0.001
+
+// replay_stats is what's compared besides the image after a replay completes.
+type replay_stats struct {
+	Ticks    int             `json:"ticks"`
+	Pipeline *pipeline.Stats `json:"pipeline,omitempty"`
+}
+
+// ErrGoldenMismatch is returned from Run when a replay doesn't match its golden files.
+var ErrGoldenMismatch = errors.New("replay does not match golden files")
+
+func (r *runner) start_replay() error {
+	if *record_path != "" {
+		input.StartRecording()
+	}
+
+	if *replay_path != "" {
+		recording, err := input.LoadRecording(*replay_path)
+		if err != nil {
+			return fmt.Errorf("could not load replay: %w", err)
+		}
+		input.Play(recording)
+		r.replaying = true
+	}
+
+	return nil
+}
+
+func (r *runner) finish_recording() {
+	if recording := input.StopRecording(); recording != nil {
+		if err := recording.Save(*record_path); err != nil {
+			logger.Errorf("could not save recording: %v", err)
+		} else {
+			logger.Infof("recorded %d ticks to %s", len(recording.Frames), *record_path)
+		}
+	}
+}
+
+// verify_replay compares the frame the game just drew and the stats of the session against the golden
+// files of the replay. Missing golden files are written instead, so the first run of a replay creates them.
+func (r *runner) verify_replay(screen *ebiten.Image) error {
+	base := strings.TrimSuffix(*replay_path, ".json")
+	image_path := base + ".golden.png"
+	stats_path := base + ".golden.json"
+
+	bounds := screen.Bounds()
+	frame := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	screen.ReadPixels(frame.Pix)
+
+	stats := replay_stats{Ticks: r.ticks}
+	if p, ok := r.game.(Pipeliner); ok {
+		s := p.Pipeline().Stats
+		stats.Pipeline = &s
+	}
+
+	golden_stats, err := os.ReadFile(stats_path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return write_golden(image_path, stats_path, frame, stats)
+	} else if err != nil {
+		return err
+	}
+
+	actual_stats, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+
+	var mismatch []string
+
+	if !bytes.Equal(bytes.TrimSpace(golden_stats), actual_stats) {
+		mismatch = append(mismatch, fmt.Sprintf("stats: expected %s, got %s", bytes.TrimSpace(golden_stats), actual_stats))
+	}
+
+	if different, err := compare_golden_image(image_path, frame); err != nil {
+		return err
+	} else if different > golden_max_different {
+		mismatch = append(mismatch, fmt.Sprintf("image: %.2f%% of pixels differ", different*100))
+	}
+
+	if len(mismatch) > 0 {
+		// keep the actual frame around so it can be inspected next to the golden one
+		if err := write_png(base+".actual.png", frame); err != nil {
+			logger.Errorf("could not write actual frame: %v", err)
+		}
+		return fmt.Errorf("%w: %s", ErrGoldenMismatch, strings.Join(mismatch, "; "))
+	}
+
+	logger.Infof("replay %s matches its golden files", *replay_path)
+	return nil
+}
+
+func write_golden(image_path, stats_path string, frame *image.RGBA, stats replay_stats) error {
+	if err := write_png(image_path, frame); err != nil {
+		return err
+	}
+	dst, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(stats_path, dst, 0o644); err != nil {
+		return err
+	}
+	logger.Infof("wrote golden files %s and %s", image_path, stats_path)
+	return nil
+}
+
+func write_png(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
+
+// compare_golden_image returns the fraction of pixels of `frame` which differ from the golden image.
+func compare_golden_image(path string, frame *image.RGBA) (float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	golden, err := png.Decode(f)
+	if err != nil {
+		return 0, err
+	}
+
+	if golden.Bounds() != frame.Bounds() {
+		return 1, nil
+	}
+
+	var different int
+	bounds := frame.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := golden.At(x, y).RGBA()
+			r2, g2, b2, a2 := frame.At(x, y).RGBA()
+			if channel_differs(r1, r2) || channel_differs(g1, g2) || channel_differs(b1, b2) || channel_differs(a1, a2) {
+				different++
+			}
+		}
+	}
+	return float64(different) / float64(bounds.Dx()*bounds.Dy()), nil
+}
+
+func channel_differs(a, b uint32) bool {
+	// RGBA() returns 16 bit channels
+	a >>= 8
+	b >>= 8
+	if a > b {
+		return a-b > golden_tolerance
+	}
+	return b-a > golden_tolerance
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/flags"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/locale"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+var vsync = flag.Bool("vsync", false, "wait for the display to refresh before showing a frame, each demo picks when it's not given")
+
+func init() {
+	flags.Default.Live("vsync", func() error {
+		ebiten.SetVsyncEnabled(*vsync)
+		return nil
+	})
+	flags.Default.Live("lang", func() error {
+		locale.SetLanguage(*lang)
+		return nil
+	})
+}
+
+// apply_flags applies the live flags given on the command line over what the demo set up for itself, and shows
+// what the demo picked for the rest.
+func apply_flags() error {
+	if !flags.Default.Given("vsync") {
+		*vsync = ebiten.IsVsyncEnabled()
+	}
+	return flags.Default.Apply()
+}
+
+const (
+	settings_width      = 480
+	settings_row_height = 18
+	settings_max_rows   = 16
+)
+
+// settings_columns are the flag's name, its value and whether it's live.
+var settings_columns = []ui.Size{
+	{Preferred: image.Pt(140, settings_row_height)},
+	{Preferred: image.Pt(240, settings_row_height), Stretch: 1},
+	{Preferred: image.Pt(90, settings_row_height)},
+}
+
+// settings_panel lists the flags of the demo and changes them: live ones as soon as enter is pressed in their field,
+// the others once the demo is restarted with them.
+type settings_panel struct {
+	ui   *ui.Context
+	open bool
+	list ui.List
+
+	fields map[string]*ui.TextInput
+	// status is the usage of the flag under the cursor, or what the last change of the flag `failed` failed with
+	status string
+	failed string
+}
+
+func (p *settings_panel) Draw(screen *ebiten.Image, r *runner) {
+	if !p.open {
+		return
+	}
+	if p.ui == nil {
+		p.ui = ui.NewContext()
+		p.fields = make(map[string]*ui.TextInput)
+	}
+
+	all := flags.Default.All()
+	rows := min(len(all), settings_max_rows)
+	bounds := screen.Bounds()
+	height := (rows + 3) * settings_row_height
+	x, y := bounds.Max.X-settings_width, bounds.Min.Y
+
+	p.ui.StartFrame(screen)
+	p.ui.Push(x, y, settings_width, height, nil)
+	p.ui.Panel()
+	p.ui.Pop()
+
+	p.ui.Push(x+4, y, settings_width-8, settings_row_height, nil)
+	p.ui.Label("flags (enter applies, restart for the rest)", 0, 0.5)
+	p.ui.Pop()
+
+	hovered, usage := "", ""
+	list := image.Rect(x+4, y+settings_row_height, x+settings_width-4, y+(rows+1)*settings_row_height)
+	p.ui.Push(list.Min.X, list.Min.Y, list.Dx(), list.Dy(), nil)
+	p.ui.List(&p.list, len(all), settings_row_height, func(i int) {
+		f := all[i]
+		p.ui.SetLayout(&ui.StackLayout{Gap: 4, Sizes: settings_columns})
+
+		area := p.ui.Next()
+		ui.DrawString(area, f.Name, 0, 0.5)
+		if ui.CursorWithin(area.Bounds()) {
+			hovered, usage = f.Name, f.Usage
+		}
+
+		field := p.fields[f.Name]
+		if field == nil {
+			field = &ui.TextInput{}
+			p.fields[f.Name] = field
+		}
+		if !field.Focused && field.Text != f.Value {
+			field.Set(f.Value)
+		}
+		if p.ui.TextInput(field) {
+			p.set(f.Name, field.Text)
+		}
+
+		switch {
+		case f.Pending:
+			p.ui.Label("on restart", 0, 0.5)
+		case f.Live:
+			p.ui.Label("live", 0, 0.5)
+		default:
+			p.ui.Label("", 0, 0.5)
+		}
+	})
+	p.ui.Pop()
+
+	if hovered != "" && hovered != p.failed {
+		p.status, p.failed = usage, ""
+	}
+	p.ui.Push(x+4, list.Max.Y, settings_width-8, settings_row_height, nil)
+	p.ui.Label(p.status, 0, 0.5)
+	p.ui.Pop()
+
+	if flags.Default.Pending() {
+		p.ui.Push(x+4, list.Max.Y+settings_row_height, 180, settings_row_height-2, nil)
+		p.ui.Button(ui.ButtonArgs{
+			Text:   "restart with changes",
+			AlignX: 0.5,
+			AlignY: 0.5,
+			Behavior: ui.ButtonBehavior{
+				OnActivate: func() {
+					reload()
+					r.quit = true
+				},
+			},
+		})
+		p.ui.Pop()
+	}
+
+	p.ui.EndFrame()
+}
+
+// set changes a flag, what went wrong stays in the status line until the cursor moves over another flag.
+func (p *settings_panel) set(name, value string) {
+	if err := flags.Default.Set(name, value); err != nil {
+		p.status, p.failed = fmt.Sprintf("-%s: %v", name, err), name
+		return
+	}
+	p.status, p.failed = "", ""
+	logger.Infof("-%s=%s", name, value)
+}
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+var (
+	spike_ms      = cvar.Float("app_spike_ms", 50, cvar.Persist, "frames taking longer than this many milliseconds are kept as spikes, 0 turns the watchdog off").Range(0, 1000)
+	spike_capture = cvar.Bool("app_spike_capture", false, cvar.Persist, "record the pipeline of the frame after a spike, for the spike's report")
+)
+
+const (
+	// max_spikes is how many spikes the watchdog keeps, the oldest are dropped
+	max_spikes = 8
+	// spike_log is how far back before a spike the log lines of its report go
+	spike_log = time.Second
+	// spike_warmup is how many frames are drawn before the watchdog starts, loading hitches the first few
+	spike_warmup = 30
+)
+
+// spike is a frame which took too long, and what was going on around it.
+type spike struct {
+	Time  time.Time
+	Frame time.Duration
+	Tick  int
+	// Stats are the runner's stats as of the frame, see runner.stats.
+	Stats map[string]any
+
+	// Heap is the bytes of the heap after the frame, GCs how many times the collector ran during it and Pause
+	// the longest it stopped the world for.
+	Heap  uint64
+	GCs   uint32
+	Pause time.Duration
+
+	// Log is what was logged in the second before the frame ended.
+	Log []logging.Entry
+	// Capture is the pipeline of a frame right after, when app_spike_capture is on and the demo has a pipeline.
+	Capture *pipeline.Capture
+}
+
+// spike_watchdog times the frames and keeps those over app_spike_ms, so a hitch which happens once in a while can
+// be looked into after it happened instead of caught in the act.
+type spike_watchdog struct {
+	last   time.Time
+	frames int
+	num_gc uint32
+	spikes []*spike
+	// pending is the spike waiting for its capture, which was armed when `before` was the last capture
+	pending *spike
+	before  *pipeline.Capture
+}
+
+// Frame is called at the end of every frame drawn, `context` is the pipeline of the demo or nil.
+func (w *spike_watchdog) Frame(r *runner, context *pipeline.Context) {
+	now := time.Now()
+	frame := now.Sub(w.last)
+	w.last = now
+	w.frames++
+
+	if w.pending != nil && context != nil {
+		if c := context.LastCapture(); c != w.before {
+			w.pending.Capture = c
+			w.pending = nil
+		}
+	}
+
+	threshold := time.Duration(spike_ms.Float() * float64(time.Millisecond))
+	if threshold <= 0 || w.frames <= spike_warmup || r.paused || frame < threshold {
+		return
+	}
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	s := &spike{
+		Time:  now,
+		Frame: frame,
+		Tick:  r.ticks,
+		Stats: r.stats(),
+		Heap:  stats.HeapAlloc,
+		GCs:   stats.NumGC - w.num_gc,
+	}
+	// the pauses since the last spike, of which only those of the frame count
+	for i := range min(s.GCs, 256) {
+		end := time.Unix(0, int64(stats.PauseEnd[(stats.NumGC-i+255)%256]))
+		if end.Before(now.Add(-frame)) {
+			s.GCs = i
+			break
+		}
+		s.Pause = max(s.Pause, time.Duration(stats.PauseNs[(stats.NumGC-i+255)%256]))
+	}
+	w.num_gc = stats.NumGC
+	for _, e := range logging.Entries(nil) {
+		if now.Sub(e.Time) <= spike_log {
+			s.Log = append(s.Log, e)
+		}
+	}
+
+	if spike_capture.Bool() && context != nil && w.pending == nil {
+		w.before = context.LastCapture()
+		w.pending = s
+		context.CaptureNextFrame()
+	}
+
+	if len(w.spikes) == max_spikes {
+		w.spikes = slices.Delete(w.spikes, 0, 1)
+	}
+	w.spikes = append(w.spikes, s)
+	logger.Warnf("spike: frame %d took %v, %d gcs pausing %v", s.Tick, frame.Round(time.Microsecond), s.GCs,
+		s.Pause.Round(time.Microsecond))
+}
+
+// report describes the spike for a person reading it later.
+func (s *spike) report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "spike at %s, tick %d: the frame took %v\n", s.Time.Format("15:04:05.000"), s.Tick,
+		s.Frame.Round(time.Microsecond))
+	fmt.Fprintf(&b, "heap %d bytes, %d gcs during the frame, longest pause %v\n", s.Heap, s.GCs, s.Pause)
+	keys := make([]string, 0, len(s.Stats))
+	for k := range s.Stats {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "  %s: %v\n", k, s.Stats[k])
+	}
+	if c := s.Capture; c != nil {
+		meshes := map[uint32]int{}
+		for _, sub := range c.Submissions {
+			meshes[sub.MeshID]++
+		}
+		fmt.Fprintf(&b, "capture of the next frame: %d triangles submitted of %d meshes, %d clipped, %d drawn\n",
+			len(c.Submissions), len(meshes), len(c.Clipped), len(c.Sorted))
+	}
+	fmt.Fprintf(&b, "log, the last %v:\n", spike_log)
+	for _, e := range s.Log {
+		fmt.Fprintf(&b, "  %s\n", e)
+	}
+	return b.String()
+}
+
+// save writes the reports of the spikes to `path`.
+func (w *spike_watchdog) save(path string) error {
+	var b strings.Builder
+	for _, s := range w.spikes {
+		b.WriteString(s.report())
+		b.WriteString("\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
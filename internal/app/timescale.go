@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+)
+
+var time_scale = cvar.Float("app_timescale", 1, 0, "how fast time runs for what demos animate and simulate, 0.25 is slow motion and 4 fast forward, the UI keeps its pace").
+	Range(0, max_time_scale)
+
+const (
+	min_time_scale = 1.0 / 16
+	max_time_scale = 8
+)
+
+// TimeScale is how fast time runs for the demo, 1 being real time, see Delta.
+func TimeScale() float32 {
+	return time_scale.Float32()
+}
+
+// Delta is the seconds of demo time a tick moves things on by: a tick's worth at 1 / TPS, times app_timescale.
+// Animation, particles and physics go by it so they can be watched in slow motion or fast forwarded, while the
+// camera and UI keep going by real ticks.
+func Delta() float32 {
+	return TimeScale() / float32(ebiten.TPS())
+}
+
+// Steps splits Delta into `n` steps of `dt` no longer than a tick, for simulations which blow up when stepped by
+// more: fast forward steps them more often instead. There are none while time stands still.
+func Steps() (n int, dt float32) {
+	d := Delta()
+	if d <= 0 {
+		return 0, 0
+	}
+	n = int(math.Ceil(float64(TimeScale())))
+	return n, d / float32(n)
+}
+
+// update_time_scale halves or doubles the time scale from the keyboard.
+func update_time_scale() {
+	scale := time_scale.Float()
+	switch {
+	case input.JustPressed("slow_motion"):
+		scale = max(scale/2, min_time_scale)
+	case input.JustPressed("fast_forward"):
+		scale = min(scale*2, max_time_scale)
+	default:
+		return
+	}
+	time_scale.SetFloat(scale)
+	logger.Infof("time scale %v", scale)
+}
+
+// time_scale_label is shown while time doesn't run at its normal pace.
+func time_scale_label() string {
+	if time_scale.Float() == 1 {
+		return ""
+	}
+	return fmt.Sprintf("TIME x%g (%s slower, %s faster)", time_scale.Float(), input.Describe("slow_motion"), input.Describe("fast_forward"))
+}
@@ -0,0 +1,188 @@
+// Package audio plays sounds for the demos: a bank of named sounds loaded from files or synthesized, events that
+// play one of them when something happens, and voices placed in the world which are heard from the listener, the
+// active camera. Positional voices get quieter with distance and pan to the side they're on.
+//
+// Everything runs on ebiten's audio context, created on the first sound. Call Listen every tick with the camera
+// for positional voices to follow it.
+package audio
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+)
+
+var logger = logging.Tag("audio")
+
+var (
+	volume = cvar.Float("s_volume", 0.8, cvar.Persist, "the volume of every sound").Range(0, 1)
+	mute   = cvar.Bool("s_mute", false, cvar.Persist, "silences every sound")
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+// SampleRate is the rate of the audio context, sounds are resampled to it when they're loaded.
+const SampleRate = 48000
+
+var (
+	context_once sync.Once
+	context      *audio.Context
+)
+
+// shared returns the audio context, ebiten allows one per process.
+func shared() *audio.Context {
+	context_once.Do(func() {
+		if context = audio.CurrentContext(); context == nil {
+			context = audio.NewContext(SampleRate)
+		}
+	})
+	return context
+}
+
+// Sound is decoded audio, 16 bit little endian stereo at SampleRate.
+type Sound struct {
+	pcm []byte
+}
+
+// Seconds is the length of the sound.
+func (s *Sound) Seconds() float {
+	return float(len(s.pcm)/4) / SampleRate
+}
+
+// Decode decodes a wav or ogg vorbis file, told apart by the extension of `name`.
+func Decode(name string, r io.Reader) (*Sound, error) {
+	var stream io.Reader
+	var err error
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".wav":
+		stream, err = wav.DecodeWithSampleRate(SampleRate, r)
+	case ".ogg":
+		stream, err = vorbis.DecodeWithSampleRate(SampleRate, r)
+	default:
+		return nil, fmt.Errorf("%s: can't decode %q files, only .wav and .ogg", name, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	pcm, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &Sound{pcm: pcm}, nil
+}
+
+// Load decodes the sound file at `path`.
+func Load(path string) (*Sound, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(path, f)
+}
+
+// event is what an event plays.
+type event struct {
+	sounds []string
+	volume float
+}
+
+// Bank holds sounds by name and the events that play them.
+type Bank struct {
+	sounds map[string]*Sound
+	events map[string]event
+}
+
+func NewBank() *Bank {
+	return &Bank{
+		sounds: make(map[string]*Sound),
+		events: make(map[string]event),
+	}
+}
+
+// Default is the bank the demos fill with their sounds.
+var Default = NewBank()
+
+// Add adds `sound` as `name`, replacing whatever was called that.
+func (b *Bank) Add(name string, sound *Sound) {
+	b.sounds[name] = sound
+}
+
+// Load adds the sound file at `path` as `name`.
+func (b *Bank) Load(name, path string) error {
+	s, err := Load(path)
+	if err != nil {
+		return err
+	}
+	b.Add(name, s)
+	return nil
+}
+
+// Sound returns the sound called `name`, nil if there's none.
+func (b *Bank) Sound(name string) *Sound {
+	return b.sounds[name]
+}
+
+// Names returns the names of the sounds.
+func (b *Bank) Names() []string {
+	names := make([]string, 0, len(b.sounds))
+	for name := range b.sounds {
+		names = append(names, name)
+	}
+	return names
+}
+
+// On makes `name` play one of `sounds` at `volume` whenever it's triggered, a different one every time when there
+// are several so repeated events don't sound mechanical.
+func (b *Bank) On(name string, volume float, sounds ...string) {
+	b.events[name] = event{sounds: sounds, volume: volume}
+}
+
+// pick returns the sound and volume of event `name`.
+func (b *Bank) pick(name string) (*Sound, float) {
+	e, ok := b.events[name]
+	if !ok || len(e.sounds) == 0 {
+		return nil, 0
+	}
+	sound := e.sounds[rand.IntN(len(e.sounds))]
+	s := b.sounds[sound]
+	if s == nil {
+		logger.Warnf("event %s plays %s, which isn't in the bank", name, sound)
+	}
+	return s, e.volume
+}
+
+// Trigger plays the sound of event `name` everywhere, the way interface sounds are heard. Events without a sound
+// are silent, so the code raising them doesn't have to care whether they have one. `scale` scales the volume of
+// the event, such as by how hard something hit.
+func (b *Bank) Trigger(name string, scale float) *Voice {
+	s, volume := b.pick(name)
+	if s == nil {
+		return nil
+	}
+	return Play(s, volume*scale)
+}
+
+// TriggerAt plays the sound of event `name` at `position`.
+func (b *Bank) TriggerAt(name string, position vec3, scale float) *Voice {
+	s, volume := b.pick(name)
+	if s == nil {
+		return nil
+	}
+	return PlayAt(s, position, volume*scale)
+}
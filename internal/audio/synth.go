@@ -0,0 +1,47 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+)
+
+// Synth renders `seconds` of `wave`, called with the time in seconds and returning a sample from -1 to 1. The
+// demos have no sound files, their sounds are made up this way.
+func Synth(seconds float, wave func(t float) float) *Sound {
+	n := int(seconds * SampleRate)
+	pcm := make([]byte, n*4)
+	for i := 0; i < n; i++ {
+		v := max(min(wave(float(i)/SampleRate), 1), -1)
+		sample := uint16(int16(v * math.MaxInt16))
+		binary.LittleEndian.PutUint16(pcm[i*4:], sample)
+		binary.LittleEndian.PutUint16(pcm[i*4+2:], sample)
+	}
+	return &Sound{pcm: pcm}
+}
+
+// Decay is an envelope which starts at 1 and falls off exponentially, to about a thousandth after `seconds`. A
+// short fade in keeps the start from clicking.
+func Decay(t, seconds float) float {
+	const attack = 0.002
+	fade := min(t/attack, 1)
+	return fade * float(math.Exp(float64(-7*t/seconds)))
+}
+
+// Tone is a sine at `frequency` which dies away over `seconds`, a blip or a chime depending on the length.
+func Tone(frequency, seconds float) *Sound {
+	return Synth(seconds, func(t float) float {
+		return float(math.Sin(2*math.Pi*float64(frequency*t))) * Decay(t, seconds)
+	})
+}
+
+// Noise is a burst of noise which dies away over `seconds`. `brightness` from 0 to 1 goes from a muffled rumble to
+// a hiss, it's a low pass on the noise.
+func Noise(seconds, brightness float) *Sound {
+	var low float
+	return Synth(seconds, func(t float) float {
+		low += (rand.Float32()*2 - 1 - low) * max(brightness, 0.01)
+		// the low pass takes the edge off the loudness as well
+		return low * (1 + 2*(1-brightness)) * Decay(t, seconds)
+	})
+}
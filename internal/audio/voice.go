@@ -0,0 +1,178 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/camera"
+)
+
+// max_voices is how many sounds play at once. A pile of boxes landing doesn't need a hundred thuds, the ones past
+// it are dropped.
+const max_voices = 32
+
+// Voice is a sound playing.
+type Voice struct {
+	// Volume scales the sound, 1 plays it as it is.
+	Volume float
+	// Position is where a positional voice is, it may be moved while it plays.
+	Position vec3
+	// Positional voices are heard from the listener, the others everywhere the same.
+	Positional bool
+	// Reference is the distance up to which a positional voice is heard at full volume, and Far the one it fades
+	// out at. The gain halves every time the distance doubles in between.
+	Reference float
+	Far       float
+
+	player *audio.Player
+	stream *stream
+}
+
+var (
+	voices   []*Voice
+	listener struct {
+		position vec3
+		right    vec3
+	}
+)
+
+// Play plays `sound` at `volume`, heard the same wherever the listener is. It returns nil when there are too many
+// voices playing already.
+func Play(sound *Sound, volume float) *Voice {
+	return play(&Voice{Volume: volume}, sound)
+}
+
+// PlayAt plays `sound` at `position`, heard from the listener.
+func PlayAt(sound *Sound, position vec3, volume float) *Voice {
+	return play(&Voice{Volume: volume, Position: position, Positional: true, Reference: 2, Far: 60}, sound)
+}
+
+func play(v *Voice, sound *Sound) *Voice {
+	reap()
+	if len(voices) >= max_voices || mute.Bool() {
+		return nil
+	}
+	v.stream = &stream{pcm: sound.pcm}
+	v.update()
+	player, err := shared().NewPlayer(v.stream)
+	if err != nil {
+		logger.Errorf("could not play a sound: %v", err)
+		return nil
+	}
+	// the stream is already short, a big buffer only delays the volume following the listener
+	player.SetBufferSize(50 * time.Millisecond)
+	v.player = player
+	player.Play()
+	voices = append(voices, v)
+	return v
+}
+
+// Playing reports whether the voice is still playing.
+func (v *Voice) Playing() bool {
+	return v.player != nil && v.player.IsPlaying()
+}
+
+// Stop stops the voice.
+func (v *Voice) Stop() {
+	if v.player == nil {
+		return
+	}
+	v.player.Close()
+	v.player = nil
+}
+
+// Listen makes `c` the listener: positional voices are heard from where it is and panned by the way it faces.
+func Listen(c *camera.Camera) {
+	listener.position = c.Position
+	listener.right = c.Right()
+	reap()
+	for _, v := range voices {
+		v.update()
+	}
+}
+
+// reap forgets the voices that finished.
+func reap() {
+	voices = slices.DeleteFunc(voices, func(v *Voice) bool {
+		if v.Playing() {
+			return false
+		}
+		v.Stop()
+		return true
+	})
+}
+
+// update passes the volume of the voice on to its stream.
+func (v *Voice) update() {
+	gain, pan := v.Volume*volume.Float32(), float(0)
+	if mute.Bool() {
+		gain = 0
+	}
+	if v.Positional {
+		distance, side := spatialize(v.Position, v.Reference, v.Far)
+		gain, pan = gain*distance, side
+	}
+	// equal power panning, a sound straight ahead is as loud as one to the side
+	angle := float64(pan+1) * math.Pi / 4
+	left, right := gain, gain
+	if v.Positional {
+		left, right = gain*float(math.Cos(angle)*math.Sqrt2), gain*float(math.Sin(angle)*math.Sqrt2)
+	}
+	v.stream.left.Store(math.Float32bits(left))
+	v.stream.right.Store(math.Float32bits(right))
+}
+
+// spatialize returns the gain of a sound at `position` and the side it's heard on, -1 for left through 1 for right.
+func spatialize(position vec3, reference, far float) (gain, pan float) {
+	d := position.Sub(listener.position)
+	distance := d.Len()
+	if distance >= far {
+		return 0, 0
+	}
+	if distance <= reference {
+		gain = 1
+	} else {
+		// inverse distance, shifted down to reach 0 at far instead of trailing off forever
+		gain = (reference/distance - reference/far) / (1 - reference/far)
+	}
+	if distance > 0 {
+		// sounds on top of the listener are heard from everywhere, the side only counts past the reference
+		pan = d.Mul(1/distance).Dot(listener.right) * min(distance/reference, 1)
+	}
+	return gain, pan
+}
+
+// stream reads a sound scaled by the gains of its channels. It's read by the audio goroutine while the game
+// changes the gains, hence the atomics.
+type stream struct {
+	pcm         []byte
+	offset      int
+	left, right atomic.Uint32
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	if s.offset >= len(s.pcm) {
+		return 0, io.EOF
+	}
+	// whole frames, 2 channels of 2 bytes
+	n := min(len(p), len(s.pcm)-s.offset) &^ 3
+	left, right := math.Float32frombits(s.left.Load()), math.Float32frombits(s.right.Load())
+	for i := 0; i < n; i += 4 {
+		l := int16(binary.LittleEndian.Uint16(s.pcm[s.offset+i:]))
+		r := int16(binary.LittleEndian.Uint16(s.pcm[s.offset+i+2:]))
+		binary.LittleEndian.PutUint16(p[i:], uint16(scale(l, left)))
+		binary.LittleEndian.PutUint16(p[i+2:], uint16(scale(r, right)))
+	}
+	s.offset += n
+	return n, nil
+}
+
+func scale(sample int16, gain float) int16 {
+	return int16(max(min(float(sample)*gain, math.MaxInt16), math.MinInt16))
+}
@@ -0,0 +1,167 @@
+// Package bake works out the light reaching the corners of meshes ahead of time, by casting rays against the
+// triangles of the scene: ambient light is darkened by how much of the sky over a corner is blocked, ambient
+// occlusion, and the light of lamps and the sun only counts when nothing is in the way. The result goes into
+// mesh.Mesh.Colors, which the renderer draws instead of lighting the meshes every frame, see render.Lighting.
+//
+// Baked light is only right for the scene as it was baked, meshes and lights which move afterwards keep the
+// light and shadows of where they were.
+package bake
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/jobs"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+type LightKind int
+
+const (
+	Ambient LightKind = iota
+	Directional
+	Point
+)
+
+// Light is a light of the scene in world space, the same as render.Light.
+type Light struct {
+	Kind LightKind
+	// Color is the color of the light times its intensity.
+	Color vec3
+	// Position is where a point light is and Direction where a directional light shines towards, of length 1.
+	Position  vec3
+	Direction vec3
+	// Range is how far a point light reaches, it fades out to nothing there.
+	Range float
+}
+
+// Options describe a bake.
+type Options struct {
+	Lights []Light
+	// Samples is how many rays every corner casts for ambient occlusion, 0 leaves ambient light unblocked.
+	Samples int
+	// Distance is how far away geometry still blocks ambient light, further than that it's open sky.
+	Distance float
+	// Shadows has geometry block the light of point and directional lights.
+	Shadows bool
+}
+
+// bias is how far rays start off the surface they're cast from, so they don't hit it.
+const bias = 1e-3
+
+// Bake fills the Colors of `meshes`, which have their points in world space, with the light reaching every corner
+// of their triangles, every mesh blocking the light of the others. The corners are shared out between the
+// workers of jobs.Default.
+func Bake(meshes []*mesh.Mesh, opts Options) {
+	s := new_scene(meshes)
+	type corner struct {
+		m    *mesh.Mesh
+		i    int
+		p, n vec3
+	}
+	var corners []corner
+	for _, m := range meshes {
+		m.Colors = make([]vec3, 3*len(m.Triangles))
+		for i, t := range m.Triangles {
+			p1, p2, p3 := m.Points[t.P1], m.Points[t.P2], m.Points[t.P3]
+			face := p2.Sub(p1).Cross(p3.Sub(p1))
+			if face.Len() == 0 {
+				continue
+			}
+			face = face.Normalize()
+			for j, p := range [3]vec3{p1, p2, p3} {
+				n := face
+				if len(m.Normals) > 0 {
+					n = m.Normals[[3]uint16{t.N1, t.N2, t.N3}[j]]
+				}
+				corners = append(corners, corner{m: m, i: 3*i + j, p: p, n: n})
+			}
+		}
+	}
+
+	jobs.Default.ParallelFor(len(corners), 64, func(start, end int) {
+		for i := start; i < end; i++ {
+			c := corners[i]
+			// the same corner gets the same rays every bake, the light doesn't flicker between bakes
+			random := rand.New(rand.NewPCG(uint64(i), 0x6261_6b65))
+			c.m.Colors[c.i] = s.light(c.p, c.n, opts, random)
+		}
+	})
+}
+
+// light is the light reaching the point `p` of a surface facing `n`.
+func (s *scene) light(p, n vec3, opts Options, random *rand.Rand) vec3 {
+	from := p.Add(n.Mul(bias))
+	var sum vec3
+	for _, light := range opts.Lights {
+		switch light.Kind {
+		case Ambient:
+			sum = sum.Add(light.Color.Mul(s.open(from, n, opts, random)))
+		case Directional:
+			lambert := -n.Dot(light.Direction)
+			if lambert <= 0 || opts.Shadows && s.hit(from, light.Direction.Mul(-1), math.MaxFloat32) {
+				continue
+			}
+			sum = sum.Add(light.Color.Mul(lambert))
+		case Point:
+			to := light.Position.Sub(p)
+			d := to.Len()
+			if d == 0 || d >= light.Range {
+				continue
+			}
+			to = to.Mul(1 / d)
+			lambert := n.Dot(to)
+			if lambert <= 0 || opts.Shadows && s.hit(from, to, d) {
+				continue
+			}
+			falloff := 1 - (d/light.Range)*(d/light.Range)
+			sum = sum.Add(light.Color.Mul(lambert * falloff * falloff))
+		}
+	}
+	return sum
+}
+
+// open is how much of the hemisphere over `from` facing `n` is open, from 0 when every ray hits something within
+// Distance to 1. Rays are spread by the cosine of their angle to `n`, the way ambient light falls on a surface.
+func (s *scene) open(from, n vec3, opts Options, random *rand.Rand) float {
+	if opts.Samples <= 0 {
+		return 1
+	}
+	u := orthogonal(n)
+	v := n.Cross(u)
+	missed := 0
+	for range opts.Samples {
+		// a point on the unit disk lifted onto the hemisphere
+		r := float(math.Sqrt(random.Float64()))
+		a := 2 * math.Pi * random.Float64()
+		x, y := r*float(math.Cos(a)), r*float(math.Sin(a))
+		z := float(math.Sqrt(float64(max(1-x*x-y*y, 0))))
+		dir := u.Mul(x).Add(v.Mul(y)).Add(n.Mul(z))
+		if !s.hit(from, dir, opts.Distance) {
+			missed++
+		}
+	}
+	return float(missed) / float(opts.Samples)
+}
+
+// orthogonal is a vector of length 1 at right angles to `n`.
+func orthogonal(n vec3) vec3 {
+	if abs(n.X()) < 0.9 {
+		return n.Cross(vec3{1, 0, 0}).Normalize()
+	}
+	return n.Cross(vec3{0, 1, 0}).Normalize()
+}
+
+func abs(x float) float {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
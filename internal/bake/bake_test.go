@@ -0,0 +1,89 @@
+package bake
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+var sky = Light{Kind: Ambient, Color: vec3{1, 1, 1}}
+
+func TestOpenSkyIsUnoccluded(t *testing.T) {
+	floor := mesh.Plane(1)
+	Bake([]*mesh.Mesh{floor}, Options{Lights: []Light{sky}, Samples: 16, Distance: 10})
+	if len(floor.Colors) != 3*len(floor.Triangles) {
+		t.Fatalf("%d colors for %d triangles", len(floor.Colors), len(floor.Triangles))
+	}
+	for i, c := range floor.Colors {
+		if c != (vec3{1, 1, 1}) {
+			t.Fatalf("corner %d is %v under an open sky", i, c)
+		}
+	}
+}
+
+func TestCeilingOccludesFloor(t *testing.T) {
+	floor := mesh.Plane(1)
+	ceiling := mesh.Plane(10)
+	ceiling.Transform(mgl32.Translate3D(0, 0.1, 0).Mul4(mgl32.HomogRotate3DX(mgl32.DegToRad(180))))
+	Bake([]*mesh.Mesh{floor, ceiling}, Options{Lights: []Light{sky}, Samples: 64, Distance: 1})
+	for i, c := range floor.Colors {
+		if c.X() > 0.5 {
+			t.Fatalf("corner %d is %v under a ceiling", i, c)
+		}
+	}
+	// the ceiling faces down onto the floor, which is smaller, most of what it sees is beyond the distance
+	for i, c := range ceiling.Colors {
+		if c.X() < 0.5 {
+			t.Fatalf("ceiling corner %d is %v", i, c)
+		}
+	}
+}
+
+func TestShadows(t *testing.T) {
+	sun := Light{Kind: Directional, Color: vec3{1, 1, 1}, Direction: vec3{0, -1, 0}}
+	bake := func(shadows bool) vec3 {
+		floor := mesh.Plane(1)
+		blocker := mesh.Box(vec3{2, 0.1, 2})
+		blocker.Transform(mgl32.Translate3D(0, 1, 0))
+		Bake([]*mesh.Mesh{floor, blocker}, Options{Lights: []Light{sun}, Shadows: shadows})
+		return floor.Colors[0]
+	}
+	if c := bake(false); c != (vec3{1, 1, 1}) {
+		t.Fatalf("unshadowed floor is %v", c)
+	}
+	if c := bake(true); c != (vec3{}) {
+		t.Fatalf("shadowed floor is %v", c)
+	}
+}
+
+func TestPointLightFallsOff(t *testing.T) {
+	floor := mesh.Plane(2)
+	lamp := Light{Kind: Point, Color: vec3{1, 1, 1}, Position: vec3{0, 1, 0}, Range: 1.5}
+	Bake([]*mesh.Mesh{floor}, Options{Lights: []Light{lamp}, Shadows: true})
+	// the corners of the plane are sqrt(3) from the lamp, out of its range
+	for i, c := range floor.Colors {
+		if c != (vec3{}) {
+			t.Fatalf("corner %d is %v out of range", i, c)
+		}
+	}
+	lamp.Range = 3
+	Bake([]*mesh.Mesh{floor}, Options{Lights: []Light{lamp}})
+	if c := floor.Colors[0]; c.X() <= 0 || c.X() >= 1 {
+		t.Fatalf("corner in range is %v", c)
+	}
+}
+
+func TestRayTriangle(t *testing.T) {
+	tri := triangle{a: vec3{0, 0, 0}, ab: vec3{1, 0, 0}, ac: vec3{0, 1, 0}}
+	if d, ok := ray_triangle(vec3{0.25, 0.25, 2}, vec3{0, 0, -1}, tri); !ok || mgl32.Abs(d-2) > 1e-6 {
+		t.Fatalf("hit at %v, %v", d, ok)
+	}
+	if _, ok := ray_triangle(vec3{0.75, 0.75, 2}, vec3{0, 0, -1}, tri); ok {
+		t.Fatal("hit outside of the triangle")
+	}
+	if _, ok := ray_triangle(vec3{0.25, 0.25, 2}, vec3{0, 0, 1}, tri); ok {
+		t.Fatal("hit behind the ray")
+	}
+}
@@ -0,0 +1,87 @@
+package bake
+
+import "github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+
+// triangle is a triangle ready for ray casting, a corner and its two edges from it.
+type triangle struct {
+	a, ab, ac vec3
+}
+
+// group is the triangles of a mesh and a sphere around them, rays which miss the sphere skip them all.
+type group struct {
+	sphere    mesh.Sphere
+	triangles []triangle
+}
+
+// scene is what rays are cast against.
+type scene struct {
+	groups []group
+}
+
+func new_scene(meshes []*mesh.Mesh) *scene {
+	s := &scene{}
+	for _, m := range meshes {
+		m.ComputeBounds()
+		g := group{sphere: m.Sphere}
+		for _, t := range m.Triangles {
+			a := m.Points[t.P1]
+			g.triangles = append(g.triangles, triangle{a: a, ab: m.Points[t.P2].Sub(a), ac: m.Points[t.P3].Sub(a)})
+		}
+		s.groups = append(s.groups, g)
+	}
+	return s
+}
+
+// hit reports whether the ray from `from` along `dir`, of length 1, hits a triangle closer than `distance`.
+// Triangles are hit from either side.
+func (s *scene) hit(from, dir vec3, distance float) bool {
+	for i := range s.groups {
+		g := &s.groups[i]
+		if !ray_sphere(from, dir, distance, g.sphere) {
+			continue
+		}
+		for _, t := range g.triangles {
+			if d, ok := ray_triangle(from, dir, t); ok && d < distance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ray_sphere reports whether the ray can reach `s` within `distance`.
+func ray_sphere(from, dir vec3, distance float, s mesh.Sphere) bool {
+	to := s.Center.Sub(from)
+	along := to.Dot(dir)
+	r2 := s.Radius * s.Radius
+	if along < 0 {
+		// the sphere is behind, only a ray from within it reaches it
+		return to.Dot(to) <= r2
+	}
+	if along-s.Radius > distance {
+		return false
+	}
+	return to.Dot(to)-along*along <= r2
+}
+
+// ray_triangle is how far along the ray `t` is hit, by Möller and Trumbore.
+func ray_triangle(from, dir vec3, t triangle) (float, bool) {
+	p := dir.Cross(t.ac)
+	det := t.ab.Dot(p)
+	if abs(det) < 1e-9 {
+		return 0, false
+	}
+	inv := 1 / det
+	s := from.Sub(t.a)
+	u := s.Dot(p) * inv
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+	q := s.Cross(t.ab)
+	v := dir.Dot(q) * inv
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+	d := t.ac.Dot(q) * inv
+	return d, d > 0
+}
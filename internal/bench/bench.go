@@ -0,0 +1,181 @@
+// Package bench measures the software pipeline on generated scenes of increasing size: a camera flies a fixed path
+// around and through each scene under every configuration of the pipeline, and the time each frame took is
+// written up as a CSV or markdown report. Scenes are a terrain, one large mesh which the job pool transforms in
+// parallel, and a field of spheres, many small meshes which sorting and culling have to deal with.
+package bench
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+// Sizes are the triangle counts of the scenes benchmarked, smallest first.
+var Sizes = []int{10_000, 50_000, 100_000, 200_000}
+
+// scene_size is how far across the terrain is, the spheres stand on the middle of it.
+const scene_size = 40
+
+// sphere_rings and sphere_segments make the spheres, 240 triangles each.
+const (
+	sphere_rings    = 8
+	sphere_segments = 16
+)
+
+// Scene makes a scene of about `triangles` triangles, half of them terrain and half spheres, every mesh with a
+// structure-of-arrays copy of its points as the pipeline transforms fastest.
+func Scene(triangles int) []*mesh.Mesh {
+	cells := int(math.Sqrt(float64(triangles) / 4))
+	terrain := mesh.Heightfield(scene_size, cells, func(x, z float) float {
+		return float(math.Sin(float64(x)*0.3)*math.Cos(float64(z)*0.25)) * 1.5
+	})
+	scene := []*mesh.Mesh{terrain}
+
+	per_sphere := len(mesh.UVSphere(1, sphere_rings, sphere_segments).Triangles)
+	spheres := max((triangles-len(terrain.Triangles))/per_sphere, 0)
+	side := int(math.Ceil(math.Sqrt(float64(spheres))))
+	spacing := float(scene_size/2) / float(max(side, 1))
+	for i := range spheres {
+		x := (float(i%side) - float(side-1)/2) * spacing
+		z := (float(i/side) - float(side-1)/2) * spacing
+		sphere := mesh.UVSphere(spacing*0.35, sphere_rings, sphere_segments)
+		sphere.Transform(mgl32.Translate3D(x, 3, z))
+		scene = append(scene, sphere)
+	}
+	for _, m := range scene {
+		m.StoreSoA()
+	}
+	return scene
+}
+
+// Triangles is how many triangles the meshes have between them.
+func Triangles(scene []*mesh.Mesh) int {
+	n := 0
+	for _, m := range scene {
+		n += len(m.Triangles)
+	}
+	return n
+}
+
+// Camera is where the camera is `t` of the way along its path, from 0 to 1, and what it looks at. It circles the
+// scene twice, the second time dropping down among the spheres, where most of the scene is to the sides of it
+// and behind it and triangles cross the edges of the view.
+func Camera(t float) (eye, target vec3) {
+	angle := 4 * math.Pi * float64(t)
+	// 0 far out and high up, 1 down among the spheres
+	low := float(0.5 - 0.5*math.Cos(2*math.Pi*float64(t)))
+	radius := 32 - 26*low
+	height := 14 - 10*low
+	eye = vec3{radius * float(math.Cos(angle)), height, radius * float(math.Sin(angle))}
+	// low down it looks ahead along the circle rather than at the middle
+	ahead := vec3{-float(math.Sin(angle)), 0, float(math.Cos(angle))}.Mul(8 * low)
+	return eye, ahead.Add(vec3{0, 2, 0})
+}
+
+// Config is a configuration of the pipeline.
+type Config struct {
+	ExactSort bool
+	GuardBand bool
+	Serial    bool
+}
+
+// Configs are every configuration, which each scene is benchmarked under.
+func Configs() []Config {
+	var configs []Config
+	for _, exact := range []bool{false, true} {
+		for _, guard := range []bool{false, true} {
+			for _, serial := range []bool{false, true} {
+				configs = append(configs, Config{exact, guard, serial})
+			}
+		}
+	}
+	return configs
+}
+
+// guard_band is how much wider than the view the guard band is, when there is one.
+const guard_band = 2
+
+// Apply configures `ctx`.
+func (c Config) Apply(ctx *pipeline.Context) {
+	ctx.ExactSort = c.ExactSort
+	ctx.GuardBand = 0
+	if c.GuardBand {
+		ctx.GuardBand = guard_band
+	}
+	ctx.Serial = c.Serial
+}
+
+// Sort, Clip and Parallelism name the configuration for the report.
+func (c Config) Sort() string {
+	if c.ExactSort {
+		return "exact"
+	}
+	return "bucket"
+}
+
+func (c Config) Clip() string {
+	if c.GuardBand {
+		return fmt.Sprintf("guard band x%d", guard_band)
+	}
+	return "view"
+}
+
+func (c Config) Parallelism() string {
+	if c.Serial {
+		return "off"
+	}
+	return "on"
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("%s sort, %s clipping, parallelism %s", c.Sort(), c.Clip(), c.Parallelism())
+}
+
+// Frame sends the scene through the pipeline as seen from `t` of the way along the camera's path and sorts it,
+// everything a frame does but draw. The viewport and projection are the caller's.
+func Frame(ctx *pipeline.Context, scene []*mesh.Mesh, t float) {
+	eye, target := Camera(t)
+	ctx.SetView(mgl32.LookAtV(eye, target, vec3{0, 1, 0}))
+	for _, m := range scene {
+		ctx.PushMesh(m)
+	}
+	ctx.Sort()
+}
+
+// Result is how a scene did under a configuration.
+type Result struct {
+	Config
+	// Size is how many triangles the scene has, Drawn how many made it through the pipeline on average.
+	Size  int
+	Drawn int
+	// Pipeline and Draw are how long each frame spent in the pipeline and drawing.
+	Pipeline []time.Duration
+	Draw     []time.Duration
+}
+
+// Add records a frame.
+func (r *Result) Add(pipeline, draw time.Duration, drawn int) {
+	n := len(r.Pipeline)
+	r.Drawn = (r.Drawn*n + drawn) / (n + 1)
+	r.Pipeline = append(r.Pipeline, pipeline)
+	r.Draw = append(r.Draw, draw)
+}
+
+// Frames is the time of each frame, pipeline and drawing.
+func (r *Result) Frames() []time.Duration {
+	frames := make([]time.Duration, len(r.Pipeline))
+	for i := range frames {
+		frames[i] = r.Pipeline[i] + r.Draw[i]
+	}
+	return frames
+}
@@ -0,0 +1,84 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+func TestSceneSizes(t *testing.T) {
+	for _, size := range Sizes {
+		n := Triangles(Scene(size))
+		// the spheres come in whole spheres and the terrain in whole cells
+		if n > size || n < size*9/10 {
+			t.Fatalf("a scene of %d triangles has %d", size, n)
+		}
+	}
+}
+
+func TestEveryConfigDraws(t *testing.T) {
+	scene := Scene(Sizes[0])
+	configs := Configs()
+	if len(configs) != 8 {
+		t.Fatalf("%d configs, want every sort, clipping and parallelism", len(configs))
+	}
+	ctx := &pipeline.Context{FlipY: true}
+	ctx.SetViewport(0, 0, 320, 240)
+	ctx.SetPerspective(mgl32.DegToRad(60), 4.0/3, 0.1, 200)
+	for _, c := range configs {
+		c.Apply(ctx)
+		for _, at := range []float{0, 0.5} {
+			Frame(ctx, scene, at)
+			if len(ctx.Triangles()) == 0 {
+				t.Fatalf("nothing was drawn with %v at %v", c, at)
+			}
+			ctx.Reset()
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var d []time.Duration
+	for i := 1; i <= 100; i++ {
+		d = append(d, time.Duration(i))
+	}
+	if p := Percentile(d, 95); p != 95 {
+		t.Fatalf("95th percentile of 1 to 100 is %d", p)
+	}
+	if m := Mean(d); m != 50 {
+		t.Fatalf("mean of 1 to 100 is %d", m)
+	}
+}
+
+func TestReports(t *testing.T) {
+	r := &Result{Config: Config{ExactSort: true}, Size: 1000}
+	r.Add(2*time.Millisecond, time.Millisecond, 10)
+	r.Add(4*time.Millisecond, time.Millisecond, 20)
+
+	var b bytes.Buffer
+	if err := WriteCSV(&b, []*Result{r}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("the csv is %d rows: %v", len(rows), err)
+	}
+	want := []string{"1000", "exact", "view", "on", "2", "15", "3.000", "4.000", "1.000", "4.000", "5.000"}
+	if strings.Join(rows[1], ",") != strings.Join(want, ",") {
+		t.Fatalf("the row is %v, want %v", rows[1], want)
+	}
+
+	b.Reset()
+	if err := WriteMarkdown(&b, []*Result{r}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "| 1000 | exact | view | on |") {
+		t.Fatalf("the markdown is\n%s", b.String())
+	}
+}
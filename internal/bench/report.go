@@ -0,0 +1,90 @@
+package bench
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mean is the average of `durations`, 0 for none.
+func Mean(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	return sum / time.Duration(len(durations))
+}
+
+// Percentile is the duration `p` percent of `durations` are no longer than, the nearest one, 0 for none.
+func Percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	i := int(p/100*float64(len(sorted)) + 0.5)
+	return sorted[min(max(i-1, 0), len(sorted)-1)]
+}
+
+var columns = []string{"triangles", "sort", "clipping", "parallelism", "frames", "drawn", "pipeline ms", "pipeline p95 ms", "draw ms", "frame ms", "frame p95 ms"}
+
+func row(r *Result) []string {
+	ms := func(d time.Duration) string { return strconv.FormatFloat(d.Seconds()*1000, 'f', 3, 64) }
+	frames := r.Frames()
+	return []string{
+		strconv.Itoa(r.Size), r.Sort(), r.Clip(), r.Parallelism(), strconv.Itoa(len(frames)), strconv.Itoa(r.Drawn),
+		ms(Mean(r.Pipeline)), ms(Percentile(r.Pipeline, 95)), ms(Mean(r.Draw)), ms(Mean(frames)), ms(Percentile(frames, 95)),
+	}
+}
+
+// WriteCSV writes a row of the times of every result, after a row of column names.
+func WriteCSV(w io.Writer, results []*Result) error {
+	c := csv.NewWriter(w)
+	c.Write(columns)
+	for _, r := range results {
+		c.Write(row(r))
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// WriteMarkdown writes the same as WriteCSV as a markdown table.
+func WriteMarkdown(w io.Writer, results []*Result) error {
+	var b strings.Builder
+	b.WriteString("# Pipeline benchmark\n\n")
+	fmt.Fprintf(&b, "Times are means over every frame in milliseconds, p95 the 95th percentile.\n\n")
+	b.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+	b.WriteString(strings.Repeat("|---", len(columns)) + "|\n")
+	for _, r := range results {
+		b.WriteString("| " + strings.Join(row(r), " | ") + " |\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Write writes the report to `path`, as CSV when it ends in .csv and as markdown otherwise.
+func Write(path string, results []*Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = WriteCSV(f, results)
+	} else {
+		err = WriteMarkdown(f, results)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,94 @@
+// Package budget runs background work in small slices of every tick, so things like optimizing meshes,
+// generating mips or parsing assets can happen inside Update without a hitch. Work is split into steps by the
+// task itself, the scheduler calls steps round-robin until the tick's budget is spent.
+package budget
+
+import (
+	"slices"
+	"time"
+)
+
+// Task is a piece of background work registered with a Scheduler.
+type Task struct {
+	Name string
+
+	// step does a small amount of work and reports whether the task is complete.
+	step func() bool
+
+	// Tick is the time the task used during the last tick.
+	Tick time.Duration
+	// Total is the time the task used since it was added.
+	Total time.Duration
+	// Steps is how many times the task was stepped.
+	Steps int
+	// Done is set once the task completed.
+	Done bool
+}
+
+type Scheduler struct {
+	// Budget is how much time Run may spend per tick. A step is never interrupted, so a slow step can overrun it.
+	Budget time.Duration
+
+	tasks []*Task
+	// next is the task Run starts with, so every task gets its turn even when the budget runs out early
+	next int
+
+	// Used is the time the last Run took.
+	Used time.Duration
+	// Finished keeps the last few completed tasks around for display.
+	Finished []*Task
+}
+
+// finished_history is how many completed tasks are kept in Scheduler.Finished.
+const finished_history = 8
+
+func New(budget time.Duration) *Scheduler {
+	return &Scheduler{Budget: budget}
+}
+
+// Add schedules `step` to be called every tick until it returns true. Steps should take well below the budget.
+func (s *Scheduler) Add(name string, step func() (done bool)) *Task {
+	t := &Task{Name: name, step: step}
+	s.tasks = append(s.tasks, t)
+	return t
+}
+
+// Tasks returns the tasks which haven't completed.
+func (s *Scheduler) Tasks() []*Task {
+	return s.tasks
+}
+
+// Run steps the tasks round-robin until the budget is spent or every task completed.
+func (s *Scheduler) Run() {
+	start := time.Now()
+	deadline := start.Add(s.Budget)
+
+	for _, t := range s.tasks {
+		t.Tick = 0
+	}
+
+	for len(s.tasks) > 0 && time.Now().Before(deadline) {
+		s.next %= len(s.tasks)
+		t := s.tasks[s.next]
+
+		step_start := time.Now()
+		t.Done = t.step()
+		took := time.Since(step_start)
+
+		t.Tick += took
+		t.Total += took
+		t.Steps++
+
+		if t.Done {
+			s.tasks = slices.Delete(s.tasks, s.next, s.next+1)
+			s.Finished = append(s.Finished, t)
+			if len(s.Finished) > finished_history {
+				s.Finished = s.Finished[1:]
+			}
+		} else {
+			s.next++
+		}
+	}
+
+	s.Used = time.Since(start)
+}
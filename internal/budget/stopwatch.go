@@ -0,0 +1,47 @@
+package budget
+
+import "time"
+
+// Stopwatch accumulates the time spent between Start and Stop, over any number of laps.
+type Stopwatch struct {
+	started time.Time
+	running bool
+	elapsed time.Duration
+}
+
+func (s *Stopwatch) Start() {
+	if !s.running {
+		s.started = time.Now()
+		s.running = true
+	}
+}
+
+// Stop ends the current lap and returns its duration.
+func (s *Stopwatch) Stop() time.Duration {
+	if !s.running {
+		return 0
+	}
+	lap := time.Since(s.started)
+	s.elapsed += lap
+	s.running = false
+	return lap
+}
+
+// Elapsed returns the total time of every lap, including the running one.
+func (s *Stopwatch) Elapsed() time.Duration {
+	if s.running {
+		return s.elapsed + time.Since(s.started)
+	}
+	return s.elapsed
+}
+
+func (s *Stopwatch) Reset() {
+	*s = Stopwatch{}
+}
+
+// Time runs `fn` and returns how long it took.
+func Time(fn func()) time.Duration {
+	start := time.Now()
+	fn()
+	return time.Since(start)
+}
@@ -0,0 +1,160 @@
+// Package camera is the fly camera the demos share: hold the look button and drag to turn, move with WASD,
+// the arrow keys or the left stick. Capturing the mouse turns without holding anything, like in a shooter, until
+// Escape releases it. The camera can also animate itself, see MoveTo, FlyTo, Focus and Follow.
+package camera
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+)
+
+var sensitivity = cvar.Float("m_sensitivity", 1, cvar.Persist, "how fast the mouse turns the camera").Range(0.1, 5)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+	mat4  = mgl32.Mat4
+)
+
+// Bind binds the default inputs of the camera actions. Call it before app.Run so saved bindings apply.
+func Bind() {
+	input.Bind("camera_look", input.Mouse(ebiten.MouseButtonLeft))
+	input.Bind("camera_capture", input.Key(ebiten.KeyC))
+	input.Bind("camera_release", input.Key(ebiten.KeyEscape))
+	input.Bind("move_forward", input.Key(ebiten.KeyW), input.Key(ebiten.KeyUp), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, -1))
+	input.Bind("move_backward", input.Key(ebiten.KeyS), input.Key(ebiten.KeyDown), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, 1))
+	input.Bind("move_left", input.Key(ebiten.KeyA), input.Key(ebiten.KeyLeft), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, -1))
+	input.Bind("move_right", input.Key(ebiten.KeyD), input.Key(ebiten.KeyRight), input.GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, 1))
+}
+
+type Camera struct {
+	Pitch    float
+	Yaw      float
+	Position vec3
+	// Speed is the distance moved per tick.
+	Speed float
+	// Fov is the vertical field of view in radians. The camera doesn't project anything itself, it's kept here so
+	// BlendFov can animate it.
+	Fov float
+
+	drag_x   int
+	drag_y   int
+	dragging bool
+	captured bool
+
+	move   *move
+	turn   *turn
+	zoom   *zoom
+	shake  *shake
+	follow *follow
+	// jitter is the offset of the shake, only View sees it
+	jitter vec3
+}
+
+// New returns a camera at `position` looking towards `target`.
+func New(position, target vec3) *Camera {
+	c := &Camera{Position: position, Speed: 0.1, Fov: mgl32.DegToRad(60)}
+	c.LookAt(target)
+	return c
+}
+
+// LookAt turns the camera towards `target`.
+func (c *Camera) LookAt(target vec3) {
+	if pitch, yaw, ok := angles_towards(c.Position, target); ok {
+		c.Pitch, c.Yaw = pitch, yaw
+	}
+}
+
+// rotation is the view matrix without the translation, its rows are the camera's right, up and back axes.
+func (c *Camera) rotation() mat4 {
+	return mgl32.HomogRotate3DX(c.Pitch).Mul4(mgl32.HomogRotate3DY(-c.Yaw))
+}
+
+func (c *Camera) Forward() vec3 {
+	return c.rotation().Row(2).Vec3().Mul(-1)
+}
+
+func (c *Camera) Right() vec3 {
+	return c.rotation().Row(0).Vec3()
+}
+
+func (c *Camera) Up() vec3 {
+	return c.rotation().Row(1).Vec3()
+}
+
+func (c *Camera) View() mat4 {
+	p := c.Position.Add(c.jitter)
+	return c.rotation().Mul4(mgl32.Translate3D(-p.X(), -p.Y(), -p.Z()))
+}
+
+// Capture hides the cursor and turns the camera with every movement of the mouse, which no longer stops at the
+// edges of the window.
+func (c *Camera) Capture() {
+	ebiten.SetCursorMode(ebiten.CursorModeCaptured)
+	c.captured = true
+	// the cursor may jump when it's captured, the first delta is skipped
+	c.dragging = false
+}
+
+// Release gives the cursor back.
+func (c *Camera) Release() {
+	ebiten.SetCursorMode(ebiten.CursorModeVisible)
+	c.captured = false
+	c.dragging = false
+}
+
+func (c *Camera) Captured() bool {
+	return c.captured
+}
+
+// Update applies a tick of input.
+func (c *Camera) Update() {
+	looked := c.Look()
+
+	forward := input.Axis("move_backward", "move_forward")
+	right := input.Axis("move_left", "move_right")
+
+	if looked || forward != 0 || right != 0 {
+		c.move, c.turn, c.follow = nil, nil, nil
+	}
+	c.update_tweens()
+
+	c.Position = c.Position.
+		Add(c.Forward().Mul(forward * c.Speed)).
+		Add(c.Right().Mul(right * c.Speed))
+}
+
+// Look applies a tick of turning with the mouse, and capturing and releasing it, without moving. Update does it
+// too, it's for demos that move the camera themselves. It reports whether the camera turned.
+func (c *Camera) Look() bool {
+	if c.captured && (input.JustPressed("camera_release") || !ebiten.IsFocused()) {
+		c.Release()
+	} else if !c.captured && input.JustPressed("camera_capture") {
+		c.Capture()
+	}
+
+	looked := false
+	if c.captured || input.Pressed("camera_look") {
+		// captured, the position keeps counting past the window's edges and only its deltas mean anything
+		cx, cy := input.CursorPosition()
+
+		// doing the logic in the next update ensures we don't get some crazy snapping
+		if c.dragging {
+			scale := sensitivity.Float32() / 100
+			c.Pitch = mgl32.Clamp(c.Pitch+float(cy-c.drag_y)*scale, -math.Pi/2, math.Pi/2)
+			c.Yaw += float(cx-c.drag_x) * scale
+			looked = cx != c.drag_x || cy != c.drag_y
+		}
+		c.dragging = true
+		c.drag_x = cx
+		c.drag_y = cy
+	} else {
+		c.dragging = false
+	}
+	return looked
+}
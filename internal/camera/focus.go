@@ -0,0 +1,27 @@
+package camera
+
+import (
+	"math"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tween"
+)
+
+// focus_ticks is how long Focus takes to get there.
+const focus_ticks = 24
+
+// focus_margin leaves some room around what's framed.
+const focus_margin = 1.15
+
+// Focus moves the camera back or forth along its view direction, over a few ticks, until `sphere` fills the view
+// of a projection with the vertical field of view `fovy`, in radians, and `aspect`. Like "frame selected" in
+// modelling tools it keeps looking the same way, only the position changes. Moving or looking cancels it.
+func (c *Camera) Focus(sphere mesh.Sphere, fovy, aspect float) {
+	half := float64(fovy) / 2
+	// the narrower of the two fields of view decides, or wide windows cut off the sides
+	half = min(half, math.Atan(math.Tan(half)*float64(aspect)))
+	distance := float(float64(sphere.Radius) * focus_margin / math.Sin(half))
+
+	// eased in and out so it neither jumps nor stops dead
+	c.MoveTo(sphere.Center.Sub(c.Forward().Mul(distance)), focus_ticks, tween.SmoothStep)
+}
@@ -0,0 +1,44 @@
+package camera
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/spline"
+)
+
+// follow rides a path, it's a channel like the tweens and replaces moves
+type follow struct {
+	*spline.Follower
+	// target is looked at instead of ahead when set
+	target *vec3
+}
+
+// Follow moves the camera along the path of `f`, at its speed, looking the way it goes, like a dolly on rails.
+// With a `target` it keeps looking at that instead. The follower is advanced by the camera, once per tick. It ends
+// with the path of a Once follower, moving or looking cancels it.
+func (c *Camera) Follow(f *spline.Follower, target *vec3) {
+	c.follow = &follow{Follower: f, target: target}
+	c.move, c.turn = nil, nil
+}
+
+// Following reports whether the camera is on a path.
+func (c *Camera) Following() bool {
+	return c.follow != nil
+}
+
+func (c *Camera) update_follow() {
+	f := c.follow
+	if f == nil {
+		return
+	}
+	f.Update(1 / float(ebiten.TPS()))
+	c.Position = f.Position()
+	if f.target != nil {
+		c.LookAt(*f.target)
+	} else if forward := f.Forward(); forward.Len() > 0 {
+		c.LookAt(c.Position.Add(forward))
+	}
+	if f.Done() {
+		c.follow = nil
+	}
+}
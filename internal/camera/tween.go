@@ -0,0 +1,140 @@
+package camera
+
+import (
+	"math"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tween"
+)
+
+// The camera animates on separate channels: moving, turning, zooming and shaking. Each channel runs one tween at
+// a time and starting another replaces it, but the channels run at the same time, so a move and a turn started
+// together make a single sweeping shot. Moving or looking with the inputs cancels the moves and turns.
+
+type move struct {
+	tween.Tween
+	from, to vec3
+	// target is looked at during the move when set
+	target *vec3
+}
+
+type turn struct {
+	tween.Tween
+	from_pitch, from_yaw float
+	to_pitch, to_yaw     float
+}
+
+type zoom struct {
+	tween.Tween
+	from, to float
+}
+
+type shake struct {
+	tween.Tween
+	amplitude float
+}
+
+// MoveTo moves the camera to `position` over `ticks` ticks, without turning it.
+func (c *Camera) MoveTo(position vec3, ticks int, ease tween.Ease) {
+	c.move = &move{Tween: tween.New(ticks, ease), from: c.Position, to: position}
+}
+
+// TurnTo turns the camera towards `target` over `ticks` ticks, the short way around.
+func (c *Camera) TurnTo(target vec3, ticks int, ease tween.Ease) {
+	pitch, yaw, ok := angles_towards(c.Position, target)
+	if !ok {
+		return
+	}
+	c.turn = &turn{
+		Tween:      tween.New(ticks, ease),
+		from_pitch: c.Pitch,
+		from_yaw:   c.Yaw,
+		to_pitch:   pitch,
+		to_yaw:     yaw,
+	}
+}
+
+// FlyTo moves the camera to `position` over `ticks` ticks while it keeps looking at `target`, the usual intro
+// fly-in or orbit to a screenshot angle.
+func (c *Camera) FlyTo(position, target vec3, ticks int, ease tween.Ease) {
+	c.MoveTo(position, ticks, ease)
+	c.move.target = &target
+	c.turn = nil
+}
+
+// BlendFov changes Fov to `fov` radians over `ticks` ticks.
+func (c *Camera) BlendFov(fov float, ticks int, ease tween.Ease) {
+	c.zoom = &zoom{Tween: tween.New(ticks, ease), from: c.Fov, to: fov}
+}
+
+// Shake jitters the view by up to `amplitude` world units, dying down over `ticks` ticks. The jitter is part of
+// View only, Position stays put.
+func (c *Camera) Shake(amplitude float, ticks int) {
+	c.shake = &shake{Tween: tween.New(ticks, tween.OutQuad), amplitude: amplitude}
+}
+
+// Animating reports whether any tween, or Follow, is still running.
+func (c *Camera) Animating() bool {
+	return c.move != nil || c.turn != nil || c.zoom != nil || c.shake != nil || c.follow != nil
+}
+
+// Stop ends every tween, and Follow, where it is.
+func (c *Camera) Stop() {
+	c.move, c.turn, c.zoom, c.shake, c.follow = nil, nil, nil, nil, nil
+	c.jitter = vec3{}
+}
+
+// angles_towards returns the pitch and yaw that look from `from` to `target`.
+func angles_towards(from, target vec3) (pitch, yaw float, ok bool) {
+	dir := target.Sub(from)
+	if dir.Len() == 0 {
+		return 0, 0, false
+	}
+	dir = dir.Normalize()
+	pitch = float(-math.Asin(float64(dir.Y())))
+	yaw = float(math.Atan2(float64(-dir.X()), float64(-dir.Z())))
+	return pitch, yaw, true
+}
+
+// update_tweens advances every channel by a tick.
+func (c *Camera) update_tweens() {
+	if m := c.move; m != nil {
+		c.Position = tween.LerpVec3(m.from, m.to, m.Step())
+		if m.target != nil {
+			c.LookAt(*m.target)
+		}
+		if m.Done() {
+			c.move = nil
+		}
+	}
+
+	c.update_follow()
+
+	if t := c.turn; t != nil {
+		p := t.Step()
+		c.Pitch = tween.Lerp(t.from_pitch, t.to_pitch, p)
+		c.Yaw = tween.LerpAngle(t.from_yaw, t.to_yaw, p)
+		if t.Done() {
+			c.turn = nil
+		}
+	}
+
+	if z := c.zoom; z != nil {
+		c.Fov = tween.Lerp(z.from, z.to, z.Step())
+		if z.Done() {
+			c.zoom = nil
+		}
+	}
+
+	c.jitter = vec3{}
+	if s := c.shake; s != nil {
+		strength := s.amplitude * (1 - s.Step())
+		// a few sines at unrelated frequencies look random enough and replay the same every time
+		n := float64(s.Tick())
+		c.jitter = c.Right().Mul(strength * float(math.Sin(n*1.9)+math.Sin(n*3.7)) / 2).
+			Add(c.Up().Mul(strength * float(math.Sin(n*2.3)+math.Sin(n*4.1)) / 2))
+		if s.Done() {
+			c.shake = nil
+			c.jitter = vec3{}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Package clipboard copies text to and pastes text from the system clipboard, so what's typed into the UI can
+// come from and go to other programs. Every platform has a shim of its own: the Win32 API on Windows, pbcopy and
+// pbpaste on macOS, wl-copy or xclip or xsel on Linux and the async clipboard API in browsers.
+//
+// Reading the clipboard can take a while, a program to start or a browser to ask for permission, so it happens
+// on a worker and the text is handed over on the main thread. When the system clipboard can't be reached, text
+// is copied to and pasted from a clipboard of the process's own, which still works within the demo.
+package clipboard
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/jobs"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+)
+
+var logger = logging.Tag("clipboard")
+
+var errUnavailable = errors.New("no system clipboard")
+
+var (
+	mutex sync.Mutex
+	// local is the last text copied, what's pasted when the system clipboard can't be reached
+	local string
+	// warned is set once the failure to reach the system clipboard was logged, it isn't logged every paste
+	warned bool
+)
+
+// Copy puts `text` on the clipboard.
+func Copy(text string) {
+	mutex.Lock()
+	local = text
+	mutex.Unlock()
+
+	jobs.Default.Submit(jobs.Low, func() {
+		if err := write(text); err != nil {
+			warn(err)
+		}
+	})
+}
+
+// Paste reads the clipboard and calls `fn` with its text on the main thread, during a later tick.
+func Paste(fn func(text string)) {
+	var text string
+	jobs.Default.SubmitThen(jobs.Low, func() {
+		var err error
+		if text, err = read(); err != nil {
+			warn(err)
+			mutex.Lock()
+			text = local
+			mutex.Unlock()
+		}
+	}, func() {
+		fn(text)
+	})
+}
+
+func warn(err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !warned {
+		warned = true
+		logger.Warnf("the system clipboard can't be used, copying within the demo only: %v", err)
+	}
+}
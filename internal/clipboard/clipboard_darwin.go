@@ -0,0 +1,17 @@
+package clipboard
+
+import (
+	"os/exec"
+	"strings"
+)
+
+func read() (string, error) {
+	out, err := exec.Command("pbpaste").Output()
+	return string(out), err
+}
+
+func write(text string) error {
+	cmd := exec.Command("pbcopy")
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
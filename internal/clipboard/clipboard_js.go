@@ -0,0 +1,59 @@
+package clipboard
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// await blocks the worker until `promise` settles. The browser keeps running meanwhile, it's only this goroutine
+// that waits.
+func await(promise js.Value) (js.Value, error) {
+	type result struct {
+		value js.Value
+		err   error
+	}
+	done := make(chan result, 1)
+	var then, catch js.Func
+	then = js.FuncOf(func(this js.Value, args []js.Value) any {
+		done <- result{value: args[0]}
+		return nil
+	})
+	catch = js.FuncOf(func(this js.Value, args []js.Value) any {
+		done <- result{err: errors.New(args[0].Call("toString").String())}
+		return nil
+	})
+	defer then.Release()
+	defer catch.Release()
+	promise.Call("then", then).Call("catch", catch)
+	r := <-done
+	return r.value, r.err
+}
+
+func api() (js.Value, error) {
+	clipboard := js.Global().Get("navigator").Get("clipboard")
+	if clipboard.IsUndefined() {
+		return js.Value{}, errors.Join(errUnavailable, errors.New("the page isn't served over https"))
+	}
+	return clipboard, nil
+}
+
+func read() (string, error) {
+	clipboard, err := api()
+	if err != nil {
+		return "", err
+	}
+	text, err := await(clipboard.Call("readText"))
+	if err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+func write(text string) error {
+	clipboard, err := api()
+	if err != nil {
+		return err
+	}
+	_, err = await(clipboard.Call("writeText", text))
+	return err
+}
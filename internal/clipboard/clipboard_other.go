@@ -0,0 +1,11 @@
+//go:build !windows && !darwin && !unix && !js
+
+package clipboard
+
+func read() (string, error) {
+	return "", errUnavailable
+}
+
+func write(text string) error {
+	return errUnavailable
+}
@@ -0,0 +1,50 @@
+//go:build unix && !darwin
+
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tool is a program which reads or writes the clipboard.
+type tool struct {
+	read, write []string
+}
+
+// tools are tried in order, the Wayland one only under Wayland
+func tools() []tool {
+	var out []tool
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		out = append(out, tool{read: []string{"wl-paste", "--no-newline"}, write: []string{"wl-copy"}})
+	}
+	return append(out,
+		tool{read: []string{"xclip", "-selection", "clipboard", "-out"}, write: []string{"xclip", "-selection", "clipboard", "-in"}},
+		tool{read: []string{"xsel", "--clipboard", "--output"}, write: []string{"xsel", "--clipboard", "--input"}},
+	)
+}
+
+func read() (string, error) {
+	for _, t := range tools() {
+		if _, err := exec.LookPath(t.read[0]); err != nil {
+			continue
+		}
+		out, err := exec.Command(t.read[0], t.read[1:]...).Output()
+		return string(out), err
+	}
+	return "", errors.Join(errUnavailable, errors.New("none of wl-paste, xclip or xsel is installed"))
+}
+
+func write(text string) error {
+	for _, t := range tools() {
+		if _, err := exec.LookPath(t.write[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(t.write[0], t.write[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+	return errors.Join(errUnavailable, errors.New("none of wl-copy, xclip or xsel is installed"))
+}
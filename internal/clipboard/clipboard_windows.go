@@ -0,0 +1,104 @@
+package clipboard
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32   = syscall.NewLazyDLL("user32.dll")
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	open_clipboard     = user32.NewProc("OpenClipboard")
+	close_clipboard    = user32.NewProc("CloseClipboard")
+	empty_clipboard    = user32.NewProc("EmptyClipboard")
+	get_clipboard_data = user32.NewProc("GetClipboardData")
+	set_clipboard_data = user32.NewProc("SetClipboardData")
+
+	global_alloc  = kernel32.NewProc("GlobalAlloc")
+	global_free   = kernel32.NewProc("GlobalFree")
+	global_lock   = kernel32.NewProc("GlobalLock")
+	global_unlock = kernel32.NewProc("GlobalUnlock")
+	lstrlen       = kernel32.NewProc("lstrlenW")
+	move_memory   = kernel32.NewProc("RtlMoveMemory")
+)
+
+const (
+	cf_unicodetext = 13
+	gmem_moveable  = 2
+)
+
+// open opens the clipboard, which belongs to the thread that opened it until shut closes it.
+func open() error {
+	runtime.LockOSThread()
+	if r, _, err := open_clipboard.Call(0); r == 0 {
+		runtime.UnlockOSThread()
+		return err
+	}
+	return nil
+}
+
+func shut() {
+	close_clipboard.Call()
+	runtime.UnlockOSThread()
+}
+
+func read() (string, error) {
+	if err := open(); err != nil {
+		return "", err
+	}
+	defer shut()
+
+	h, _, _ := get_clipboard_data.Call(cf_unicodetext)
+	if h == 0 {
+		// nothing, or nothing that's text
+		return "", nil
+	}
+	p, _, err := global_lock.Call(h)
+	if p == 0 {
+		return "", err
+	}
+	defer global_unlock.Call(h)
+
+	// the text stays where Windows keeps it, it's copied out rather than pointed at
+	n, _, _ := lstrlen.Call(p)
+	buf := make([]uint16, n+1)
+	move_memory.Call(uintptr(unsafe.Pointer(&buf[0])), p, n*2)
+	return syscall.UTF16ToString(buf), nil
+}
+
+func write(text string) error {
+	buf, err := syscall.UTF16FromString(text)
+	if err != nil {
+		return err
+	}
+	if err := open(); err != nil {
+		return err
+	}
+	defer shut()
+
+	if r, _, err := empty_clipboard.Call(); r == 0 {
+		return err
+	}
+	size := uintptr(len(buf) * 2)
+	h, _, err := global_alloc.Call(gmem_moveable, size)
+	if h == 0 {
+		return err
+	}
+	p, _, err := global_lock.Call(h)
+	if p == 0 {
+		global_free.Call(h)
+		return err
+	}
+	move_memory.Call(p, uintptr(unsafe.Pointer(&buf[0])), size)
+	global_unlock.Call(h)
+
+	// the clipboard owns the memory once it's set, only a failure leaves it to us
+	if r, _, err := set_clipboard_data.Call(cf_unicodetext, h); r == 0 {
+		global_free.Call(h)
+		return errors.Join(errUnavailable, err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package cloth is a mass-spring cloth: a grid of points moved by Verlet integration and held together by
+// constraints between neighbours, which collides with spheres. Its mesh is two-sided and made again in place
+// every step, normals included.
+package cloth
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+// Kinds of constraint, each with its own stiffness.
+const (
+	// Structural constraints join each point to the next one across and down, they keep the cloth from stretching.
+	Structural = iota
+	// Shear constraints join points diagonally, they keep the squares of the grid square.
+	Shear
+	// Bend constraints join each point to the one two along, they make the cloth resist folding.
+	Bend
+	kinds
+)
+
+type constraint struct {
+	a, b int32
+	rest float
+	kind uint8
+}
+
+// Sphere is something round the cloth falls over.
+type Sphere struct {
+	Center vec3
+	Radius float
+}
+
+// Cloth is `Columns` by `Rows` points, row by row from the top left.
+type Cloth struct {
+	Columns, Rows int
+	Points        []vec3
+	// Pinned points stay where they are, like the corners of a curtain on its rail.
+	Pinned []bool
+
+	Gravity vec3
+	// Damping is how much of its speed a point loses every step, from 0 to 1.
+	Damping float
+	// Iterations is how often the constraints are gone over each step, more is stiffer and slower.
+	Iterations int
+	// Stiffness is how much of their error constraints of each kind fix each iteration, from 0 to 1.
+	Stiffness [kinds]float
+	Spheres   []Sphere
+
+	previous    []vec3
+	constraints []constraint
+	// scratch for summing the normals of the faces around each point
+	normals []vec3
+	mesh    mesh.Mesh
+}
+
+// New makes a cloth hanging straight down from `origin`, `columns` points along +x and `rows` down -y,
+// `spacing` apart, facing +z. A cloth has at most 32768 points.
+func New(columns, rows int, spacing float, origin vec3) *Cloth {
+	columns, rows = max(columns, 2), max(rows, 2)
+	// the two sides of the mesh have a normal of their own for every point, and indices are 16 bit
+	columns = min(columns, 0x8000/2)
+	rows = min(rows, 0x8000/columns)
+	c := &Cloth{
+		Columns:    columns,
+		Rows:       rows,
+		Gravity:    vec3{0, -9.81, 0},
+		Damping:    0.01,
+		Iterations: 8,
+		Stiffness:  [kinds]float{1, 1, 0.3},
+	}
+	for row := range rows {
+		for column := range columns {
+			c.Points = append(c.Points, origin.Add(vec3{float(column) * spacing, -float(row) * spacing, 0}))
+		}
+	}
+	c.previous = append([]vec3(nil), c.Points...)
+	c.Pinned = make([]bool, len(c.Points))
+	c.normals = make([]vec3, len(c.Points))
+
+	join := func(column, row, dc, dr int, kind uint8) {
+		if column+dc < 0 || column+dc >= columns || row+dr >= rows {
+			return
+		}
+		a, b := c.Index(column, row), c.Index(column+dc, row+dr)
+		c.constraints = append(c.constraints, constraint{int32(a), int32(b), c.Points[a].Sub(c.Points[b]).Len(), kind})
+	}
+	for row := range rows {
+		for column := range columns {
+			join(column, row, 1, 0, Structural)
+			join(column, row, 0, 1, Structural)
+			join(column, row, 1, 1, Shear)
+			join(column, row, -1, 1, Shear)
+			join(column, row, 2, 0, Bend)
+			join(column, row, 0, 2, Bend)
+		}
+	}
+	c.build_mesh()
+	return c
+}
+
+// Index is the index of the point at `column`, `row`.
+func (c *Cloth) Index(column, row int) int {
+	return row*c.Columns + column
+}
+
+// Move puts point `i` at `p` without giving it any speed, for dragging pinned points about.
+func (c *Cloth) Move(i int, p vec3) {
+	c.Points[i] = p
+	c.previous[i] = p
+}
+
+// Step moves the cloth on by `dt` seconds.
+func (c *Cloth) Step(dt float) {
+	// Verlet integration, the speed is how far a point moved last step
+	keep := 1 - c.Damping
+	pull := c.Gravity.Mul(dt * dt)
+	for i, p := range c.Points {
+		if c.Pinned[i] {
+			c.previous[i] = p
+			continue
+		}
+		velocity := p.Sub(c.previous[i]).Mul(keep)
+		c.previous[i] = p
+		c.Points[i] = p.Add(velocity).Add(pull)
+	}
+
+	for range c.Iterations {
+		for _, k := range c.constraints {
+			c.satisfy(k)
+		}
+		c.collide()
+	}
+}
+
+// satisfy moves the two points of `k` towards being its rest length apart, the pinned one stays.
+func (c *Cloth) satisfy(k constraint) {
+	a, b := &c.Points[k.a], &c.Points[k.b]
+	d := b.Sub(*a)
+	length := d.Len()
+	if length == 0 {
+		return
+	}
+	correction := d.Mul((length - k.rest) / length * c.Stiffness[k.kind])
+	pinned_a, pinned_b := c.Pinned[k.a], c.Pinned[k.b]
+	switch {
+	case pinned_a && pinned_b:
+	case pinned_a:
+		*b = b.Sub(correction)
+	case pinned_b:
+		*a = a.Add(correction)
+	default:
+		*a = a.Add(correction.Mul(0.5))
+		*b = b.Sub(correction.Mul(0.5))
+	}
+}
+
+// collide pushes points out of the spheres. The cloth has no thickness, so they're kept a little off the surface.
+func (c *Cloth) collide() {
+	for _, s := range c.Spheres {
+		r := s.Radius * 1.02
+		for i, p := range c.Points {
+			d := p.Sub(s.Center)
+			if c.Pinned[i] || d.LenSqr() >= r*r {
+				continue
+			}
+			if l := d.Len(); l > 0 {
+				c.Points[i] = s.Center.Add(d.Mul(r / l))
+			}
+		}
+	}
+}
+
+// Mesh is the mesh of the cloth where it is now, the front facing +z before it moved and the back the other
+// way, each with the texture over it once, the back mirrored. It's the cloth's own, made again in place by every
+// call.
+func (c *Cloth) Mesh() *mesh.Mesh {
+	m := &c.mesh
+	copy(m.Points, c.Points)
+
+	for i := range c.normals {
+		c.normals[i] = vec3{}
+	}
+	front := m.Triangles[:len(m.Triangles)/2]
+	for _, t := range front {
+		p1, p2, p3 := m.Points[t.P1], m.Points[t.P2], m.Points[t.P3]
+		// the cross product is as long as the triangle is large, so larger faces count for more
+		n := p2.Sub(p1).Cross(p3.Sub(p1))
+		c.normals[t.P1] = c.normals[t.P1].Add(n)
+		c.normals[t.P2] = c.normals[t.P2].Add(n)
+		c.normals[t.P3] = c.normals[t.P3].Add(n)
+	}
+	n := len(c.Points)
+	for i, normal := range c.normals {
+		if normal.Len() > 0 {
+			normal = normal.Normalize()
+		}
+		m.Normals[i] = normal
+		m.Normals[n+i] = normal.Mul(-1)
+	}
+	m.ComputeBounds()
+	return m
+}
+
+// build_mesh makes the triangles and texcoords of the mesh, which never change.
+func (c *Cloth) build_mesh() {
+	m := &c.mesh
+	n := len(c.Points)
+	m.Points = make([]vec3, n)
+	m.Normals = make([]vec3, 2*n)
+	m.Texcoords = make([]vec2, 2*n)
+	for row := range c.Rows {
+		for column := range c.Columns {
+			u, v := float(column)/float(c.Columns-1), float(row)/float(c.Rows-1)
+			m.Texcoords[c.Index(column, row)] = vec2{u, v}
+			m.Texcoords[n+c.Index(column, row)] = vec2{1 - u, v}
+		}
+	}
+	var back []mesh.Triangle
+	for row := range c.Rows - 1 {
+		for column := range c.Columns - 1 {
+			a, b := uint16(c.Index(column, row)), uint16(c.Index(column+1, row))
+			d, e := uint16(c.Index(column, row+1)), uint16(c.Index(column+1, row+1))
+			// counter-clockwise seen from +z, where rows go down
+			for _, t := range [2][3]uint16{{a, d, b}, {b, d, e}} {
+				m.Triangles = append(m.Triangles, mesh.Triangle{
+					P1: t[0], P2: t[1], P3: t[2],
+					T1: t[0], T2: t[1], T3: t[2],
+					N1: t[0], N2: t[1], N3: t[2],
+				})
+				// the back is the same points the other way round, with the back's texcoords and normals
+				o := uint16(n)
+				back = append(back, mesh.Triangle{
+					P1: t[2], P2: t[1], P3: t[0],
+					T1: o + t[2], T2: o + t[1], T3: o + t[0],
+					N1: o + t[2], N2: o + t[1], N3: o + t[0],
+				})
+			}
+		}
+	}
+	m.Triangles = append(m.Triangles, back...)
+}
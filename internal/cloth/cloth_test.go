@@ -0,0 +1,74 @@
+package cloth
+
+import "testing"
+
+func TestHangs(t *testing.T) {
+	c := New(10, 10, 0.1, vec3{0, 0, 0})
+	left, right := c.Index(0, 0), c.Index(9, 0)
+	c.Pinned[left], c.Pinned[right] = true, true
+	rail := [2]vec3{c.Points[left], c.Points[right]}
+	for range 300 {
+		c.Step(1.0 / 60)
+	}
+	if c.Points[left] != rail[0] || c.Points[right] != rail[1] {
+		t.Fatalf("the pinned corners moved to %v and %v", c.Points[left], c.Points[right])
+	}
+	for _, k := range c.constraints {
+		if k.kind != Structural {
+			continue
+		}
+		if l := c.Points[k.a].Sub(c.Points[k.b]).Len(); l > k.rest*1.1 {
+			t.Fatalf("points %d and %d are %v apart, %v at rest", k.a, k.b, l, k.rest)
+		}
+	}
+	// it hangs below its rail, and the middle sags
+	if c.Points[c.Index(5, 9)].Y() > -0.8 || c.Points[c.Index(5, 0)].Y() >= 0 {
+		t.Fatalf("the cloth hangs to %v with the middle of its top at %v", c.Points[c.Index(5, 9)], c.Points[c.Index(5, 0)])
+	}
+}
+
+func TestStaysOutOfSpheres(t *testing.T) {
+	// a cloth lying flat, dropped onto a ball
+	c := New(12, 12, 0.2, vec3{-1.1, 0, 0})
+	for i, p := range c.Points {
+		c.Move(i, vec3{p.X(), 1, p.Y() + 1.1})
+	}
+	ball := Sphere{Center: vec3{0, 0, 0}, Radius: 0.6}
+	c.Spheres = []Sphere{ball}
+	for step := range 200 {
+		c.Step(1.0 / 60)
+		for i, p := range c.Points {
+			if p.Sub(ball.Center).Len() < ball.Radius {
+				t.Fatalf("point %d is inside the ball at %v after %d steps", i, p, step)
+			}
+		}
+	}
+}
+
+func TestMesh(t *testing.T) {
+	c := New(8, 6, 0.1, vec3{})
+	m := c.Mesh()
+	if len(m.Triangles) != 2*2*7*5 {
+		t.Fatalf("the mesh of an 8 by 6 cloth has %d triangles", len(m.Triangles))
+	}
+	// the front faces +z and the back -z, normals and all
+	half := len(m.Triangles) / 2
+	for i, tri := range m.Triangles {
+		p1, p2, p3 := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+		face := p2.Sub(p1).Cross(p3.Sub(p1)).Normalize()
+		want := float(1)
+		if i >= half {
+			want = -1
+		}
+		if abs(face.Z()-want) > 1e-5 || abs(m.Normals[tri.N1].Z()-want) > 1e-5 {
+			t.Fatalf("triangle %d faces %v with a normal of %v", i, face, m.Normals[tri.N1])
+		}
+	}
+	if n := testing.AllocsPerRun(10, func() { c.Step(1.0 / 60); c.Mesh() }); n != 0 {
+		t.Fatalf("a step and its mesh allocate %v times", n)
+	}
+}
+
+func abs(x float) float {
+	return max(x, -x)
+}
@@ -0,0 +1,145 @@
+// Package clouds makes clouds and ground fog out of puffs: soft blobs drawn on quads which always face the camera,
+// many of them overlapping to look like a volume. Noise from package noise decides where the puffs of a cloud
+// go and how big they are, so no two clouds have the same shape. The quads are an ordinary mesh, pushed through
+// the pipeline with the rest of the scene and sorted in with its triangles, and drawn with the material of
+// render.NewCloudMaterial, which fades them out where they cut into the scene instead of leaving a hard edge.
+package clouds
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/noise"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pack"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	mat4  = mgl32.Mat4
+)
+
+// Puff is a blob of cloud.
+type Puff struct {
+	Center vec3
+	// Size is how wide its quad is.
+	Size float
+	// Density is how opaque it is at its middle, from 0 to 1.
+	Density float
+	// Seed picks the noise the shader erodes the edge of the puff with, a whole number from 0 to 255.
+	Seed float
+}
+
+// Cloud is a cloud of about `count` puffs in the ellipsoid of radii `extent` around `center`. Puffs go where fBm
+// noise is densest, are larger towards the middle, and are flatter below than above, like a cumulus. `seed`
+// tells clouds apart.
+func Cloud(center, extent vec3, count int, seed float) []Puff {
+	var puffs []Puff
+	largest := max(extent.X(), extent.Y(), extent.Z())
+	for i := 0; len(puffs) < count && i < count*8; i++ {
+		// a point in the unit sphere, from the hash of the cloud and the try
+		k := float(i) + seed*1000
+		p := vec3{noise.Hash(k, 1)*2 - 1, noise.Hash(k, 2)*2 - 1, noise.Hash(k, 3)*2 - 1}
+		if p.Len() > 1 {
+			continue
+		}
+		// the bottom is cut flat, a third of the way down
+		p[1] = max(p[1], -0.35)
+		// the noise thins out the cloud in patches, more so towards its edge
+		n := noise.FBM(noise.Value, p.X()*2+seed*7, p.Z()*2+p.Y())
+		density := n * (1 - p.Len()*0.6)
+		if density < 0.25 {
+			continue
+		}
+		puffs = append(puffs, Puff{
+			Center:  center.Add(vec3{p.X() * extent.X(), p.Y() * extent.Y(), p.Z() * extent.Z()}),
+			Size:    largest * (0.5 + density),
+			Density: min(density*1.5, 1),
+			Seed:    float(int(noise.Hash(k, 4)*256) % 256),
+		})
+	}
+	return puffs
+}
+
+// Fog is a layer of ground fog over the `width` by `depth` rectangle around `center`, a grid of puffs
+// `spacing` apart, which `height` tells how high above `center` they are at x and z. Noise thins it out in
+// patches so the ground shows through here and there.
+func Fog(center vec3, width, depth, spacing float, height func(x, z float) float, seed float) []Puff {
+	var puffs []Puff
+	columns, rows := int(width/spacing), int(depth/spacing)
+	for row := range rows + 1 {
+		for column := range columns + 1 {
+			x := center.X() - width/2 + float(column)*spacing
+			z := center.Z() - depth/2 + float(row)*spacing
+			k := float(row*(columns+1) + column)
+			// jitter the grid so the puffs don't line up
+			x += (noise.Hash(k, seed) - 0.5) * spacing
+			z += (noise.Hash(seed, k) - 0.5) * spacing
+			density := noise.FBM(noise.Value, x*0.4+seed, z*0.4)
+			if density < 0.35 {
+				continue
+			}
+			puffs = append(puffs, Puff{
+				Center:  vec3{x, center.Y() + height(x, z), z},
+				Size:    spacing * 2.5,
+				Density: min((density-0.35)*2, 1),
+				Seed:    float(int(noise.Hash(k, seed+1)*256) % 256),
+			})
+		}
+	}
+	return puffs
+}
+
+// Field is puffs to draw, the clouds and the fog of a scene.
+type Field struct {
+	Puffs []Puff
+
+	mesh mesh.Mesh
+}
+
+// Mesh makes the quads of the puffs facing the camera of `view`, their edges along the sides of the screen. It's
+// made again in place every call, so call it once the camera moved.
+//
+// Every quad has its own texture coordinates, tagged by pack.Tag with the seed of the puff in u and its density in
+// 15ths in v. See render.NewCloudMaterial.
+func (f *Field) Mesh(view mat4) *mesh.Mesh {
+	// the rows of the rotation of the view are the camera's axes in world space
+	right := vec3{view.At(0, 0), view.At(0, 1), view.At(0, 2)}
+	up := vec3{view.At(1, 0), view.At(1, 1), view.At(1, 2)}
+
+	m := &f.mesh
+	m.Points = m.Points[:0]
+	m.Texcoords = m.Texcoords[:0]
+	m.Triangles = m.Triangles[:0]
+	for i, p := range f.Puffs {
+		if 4*i+3 > math.MaxUint16 {
+			break
+		}
+		h := p.Size / 2
+		r, u := right.Mul(h), up.Mul(h)
+		m.Points = append(m.Points,
+			p.Center.Sub(r).Add(u),
+			p.Center.Add(r).Add(u),
+			p.Center.Add(r).Sub(u),
+			p.Center.Sub(r).Sub(u),
+		)
+		seed, density := int(p.Seed), int(math.Round(float64(p.Density*15)))
+		m.Texcoords = append(m.Texcoords,
+			pack.Tag(vec2{0, 0}, seed, density),
+			pack.Tag(vec2{1, 0}, seed, density),
+			pack.Tag(vec2{1, 1}, seed, density),
+			pack.Tag(vec2{0, 1}, seed, density),
+		)
+		// counter-clockwise on screen, though clouds are drawn without culling
+		a := uint16(4 * i)
+		m.Triangles = append(m.Triangles,
+			mesh.Triangle{P1: a, P2: a + 3, P3: a + 2, T1: a, T2: a + 3, T3: a + 2},
+			mesh.Triangle{P1: a, P2: a + 2, P3: a + 1, T1: a, T2: a + 2, T3: a + 1},
+		)
+	}
+	m.ComputeBounds()
+	return m
+}
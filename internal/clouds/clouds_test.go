@@ -0,0 +1,72 @@
+package clouds
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestCloudStaysInItsExtent(t *testing.T) {
+	center, extent := vec3{1, 5, -2}, vec3{3, 1, 2}
+	puffs := Cloud(center, extent, 40, 7)
+	if len(puffs) == 0 || len(puffs) > 40 {
+		t.Fatalf("%d puffs for 40", len(puffs))
+	}
+	for i, p := range puffs {
+		d := p.Center.Sub(center)
+		q := vec3{d.X() / extent.X(), d.Y() / extent.Y(), d.Z() / extent.Z()}
+		if q.Len() > 1+1e-5 || q.Y() < -0.35-1e-5 {
+			t.Fatalf("puff %d at %v is outside of the cloud", i, p.Center)
+		}
+		if p.Density <= 0 || p.Density > 1 || p.Seed < 0 || p.Seed > 255 || p.Seed != float(int(p.Seed)) {
+			t.Fatalf("puff %d is %+v", i, p)
+		}
+	}
+	if other := Cloud(center, extent, 40, 8); len(other) > 0 && other[0] == puffs[0] {
+		t.Fatal("clouds of different seeds are the same")
+	}
+}
+
+func TestFogFollowsTheGround(t *testing.T) {
+	ground := func(x, z float) float { return x * 0.1 }
+	puffs := Fog(vec3{0, 1, 0}, 10, 6, 0.5, ground, 3)
+	if len(puffs) == 0 {
+		t.Fatal("no fog")
+	}
+	for i, p := range puffs {
+		x, z := p.Center.X(), p.Center.Z()
+		if x < -5.25 || x > 5.25 || z < -3.25 || z > 3.25 {
+			t.Fatalf("puff %d at %v is outside of the fog", i, p.Center)
+		}
+		if want := 1 + ground(x, z); p.Center.Y() != want {
+			t.Fatalf("puff %d is at height %v, not %v", i, p.Center.Y(), want)
+		}
+	}
+}
+
+func TestQuadsFaceTheCamera(t *testing.T) {
+	f := &Field{Puffs: []Puff{{Center: vec3{1, 2, 3}, Size: 2, Density: 0.5, Seed: 9}, {Center: vec3{-4, 0, 1}, Size: 1, Density: 1}}}
+	view := mgl32.LookAtV(vec3{5, 4, 10}, vec3{}, vec3{0, 1, 0})
+	m := f.Mesh(view)
+	if len(m.Points) != 8 || len(m.Triangles) != 4 {
+		t.Fatalf("%d points and %d triangles for 2 puffs", len(m.Points), len(m.Triangles))
+	}
+	for i := 0; i < len(m.Points); i += 4 {
+		z := mgl32.TransformCoordinate(m.Points[i], view).Z()
+		for _, p := range m.Points[i+1 : i+4] {
+			if d := mgl32.TransformCoordinate(p, view).Z() - z; d > 1e-4 || d < -1e-4 {
+				t.Fatalf("quad %d isn't facing the camera", i/4)
+			}
+		}
+	}
+	if a, b := m.Points[0], m.Points[1]; b.Sub(a).Len()-2 > 1e-5 {
+		t.Fatalf("quad is %v wide, not 2", b.Sub(a).Len())
+	}
+	// the seed and density of the first puff, 0.5 in 15ths rounded
+	if uv := m.Texcoords[2]; uv != (vec2{10, 9}) {
+		t.Fatalf("texcoords %v", uv)
+	}
+	if again := f.Mesh(view); again != m || len(again.Points) != 8 {
+		t.Fatal("the mesh wasn't made again in place")
+	}
+}
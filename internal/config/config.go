@@ -0,0 +1,87 @@
+// Package config persists small pieces of playground state (input bindings, layouts, tweak variables)
+// into a single JSON file so they survive restarts. Each subsystem owns a named section of the file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Path is the location of the config file. It defaults to a file inside the user's config directory,
+// and can be overwritten before the first Load or Save to keep demos isolated from each other.
+var Path = default_path()
+
+var mu sync.Mutex
+
+func default_path() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "ebiten-kage-playground.json"
+	}
+	return filepath.Join(dir, "ebiten-kage-playground", "config.json")
+}
+
+// read returns every section of the config file. A missing file is not an error.
+func read() (map[string]json.RawMessage, error) {
+	sections := make(map[string]json.RawMessage)
+	src, err := os.ReadFile(Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return sections, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(src, &sections); err != nil {
+		return nil, fmt.Errorf("bad config %s: %w", Path, err)
+	}
+	return sections, nil
+}
+
+// Load decodes `section` into `v`. It reports false when the section doesn't exist yet, leaving `v` untouched.
+func Load(section string, v any) (bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	sections, err := read()
+	if err != nil {
+		return false, err
+	}
+	raw, ok := sections[section]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("bad config section %q: %w", section, err)
+	}
+	return true, nil
+}
+
+// Save encodes `v` into `section`, preserving every other section of the file.
+func Save(section string, v any) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	sections, err := read()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sections[section] = raw
+
+	dst, err := json.MarshalIndent(sections, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(Path, dst, 0o644)
+}
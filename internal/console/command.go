@@ -0,0 +1,233 @@
+package console
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+)
+
+var logger = logging.Tag("console")
+
+// Command is something which can be typed into the console, e.g. `fov 45` or `load model.obj`.
+type Command struct {
+	Name string
+	// Usage describes the arguments, e.g. "<file> [scale]"
+	Usage string
+	Help  string
+	Run   func(args Args) error
+}
+
+var commands = map[string]*Command{}
+
+// Register adds a command to the console, replacing any command with the same name.
+func Register(name, usage, help string, run func(args Args) error) {
+	commands[name] = &Command{
+		Name:  name,
+		Usage: usage,
+		Help:  help,
+		Run:   run,
+	}
+}
+
+// Commands returns every registered command, sorted by name.
+func Commands() []*Command {
+	cmds := make([]*Command, 0, len(commands))
+	for _, cmd := range commands {
+		cmds = append(cmds, cmd)
+	}
+	slices.SortFunc(cmds, func(a, b *Command) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return cmds
+}
+
+// ErrUsage is returned by a command which was given the wrong arguments. The console answers it by
+// printing the command's usage.
+var ErrUsage = errors.New("wrong arguments")
+
+// Args are the arguments a command was run with, not including the command's name.
+type Args []string
+
+func (a Args) String(i int) (string, error) {
+	if i >= len(a) {
+		return "", ErrUsage
+	}
+	return a[i], nil
+}
+
+func (a Args) Float(i int) (float64, error) {
+	s, err := a.String(i)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a number", ErrUsage, s)
+	}
+	return v, nil
+}
+
+func (a Args) Int(i int) (int, error) {
+	s, err := a.String(i)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not an integer", ErrUsage, s)
+	}
+	return v, nil
+}
+
+func (a Args) Bool(i int) (bool, error) {
+	s, err := a.String(i)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(s) {
+	case "1", "true", "on", "yes":
+		return true, nil
+	case "0", "false", "off", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: %q is not a boolean", ErrUsage, s)
+}
+
+// Split breaks a line into words. Words are separated by whitespace unless quoted with " or ', and
+// everything after an unquoted # is a comment.
+func Split(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	in_word := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			in_word = true
+		case r == '#':
+			goto done
+		case r == ' ' || r == '\t':
+			if in_word {
+				words = append(words, word.String())
+				word.Reset()
+				in_word = false
+			}
+		default:
+			word.WriteRune(r)
+			in_word = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+
+done:
+	if in_word {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Execute runs a single line of input. Errors are logged as well as returned.
+func Execute(line string) error {
+	words, err := Split(line)
+	if err != nil {
+		logger.Errorf("%s", err)
+		return err
+	}
+
+	if len(words) == 0 {
+		return nil
+	}
+
+	cmd, ok := commands[words[0]]
+	if !ok {
+		err := fmt.Errorf("unknown command %q, try help", words[0])
+		logger.Errorf("%s", err)
+		return err
+	}
+
+	if err := cmd.Run(Args(words[1:])); err != nil {
+		if errors.Is(err, ErrUsage) {
+			if err != ErrUsage {
+				logger.Errorf("%s", err)
+			}
+			logger.Infof("usage: %s %s", cmd.Name, cmd.Usage)
+		} else {
+			logger.Errorf("%s: %s", cmd.Name, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Exec runs every line of a file as a command, stopping at the first one which fails.
+func Exec(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if err := Execute(scanner.Text()); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// cleared hides every entry logged before it from the console.
+var cleared time.Time
+
+func init() {
+	Register("help", "[command]", "lists the commands, or describes one", func(args Args) error {
+		if len(args) > 0 {
+			cmd, ok := commands[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown command %q", args[0])
+			}
+			logger.Infof("%s %s - %s", cmd.Name, cmd.Usage, cmd.Help)
+			return nil
+		}
+		for _, cmd := range Commands() {
+			logger.Infof("%s %s - %s", cmd.Name, cmd.Usage, cmd.Help)
+		}
+		return nil
+	})
+
+	Register("exec", "<file>", "runs every line of a file as a command", func(args Args) error {
+		path, err := args.String(0)
+		if err != nil {
+			return err
+		}
+		return Exec(path)
+	})
+
+	Register("echo", "<text...>", "logs its arguments", func(args Args) error {
+		logger.Infof("%s", strings.Join(args, " "))
+		return nil
+	})
+
+	Register("clear", "", "clears the console", func(args Args) error {
+		cleared = time.Now()
+		return nil
+	})
+}
@@ -0,0 +1,171 @@
+// Package console is the in-game console overlay. It shows the log history kept by the logging package, which can
+// be scrolled and filtered by level and tag, and runs the commands typed into it, see Register.
+package console
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/textedit"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+const (
+	row_height = 16
+	prompt     = "> "
+	// height is the portion of the screen covered by the console
+	height = 0.45
+)
+
+type Console struct {
+	ui *ui.Context
+
+	open bool
+	// list scrolls the matching entries, following the newest one
+	list ui.List
+	// level is the minimum level shown
+	level logging.Level
+	// tag is the only tag shown, or every tag when empty
+	tag string
+
+	// line is the command being typed, edited like any text field
+	line textedit.Line
+	// history holds the previously executed lines, browsed with up and down
+	history       []string
+	history_index int
+
+	entries []logging.Entry
+	matches []logging.Entry
+}
+
+func New() *Console {
+	return &Console{
+		ui:    ui.NewContext(),
+		list:  ui.List{Follow: true},
+		level: logging.LevelInfo,
+	}
+}
+
+func (c *Console) Toggle() {
+	c.open = !c.open
+}
+
+func (c *Console) Open() bool {
+	return c.open
+}
+
+// Update handles typing into the console, which has the keyboard to itself while it's open. It does nothing while
+// the console is closed.
+func (c *Console) Update() {
+	if !c.open {
+		return
+	}
+	input.Type()
+
+	submitted := ui.EditLine(&c.line)
+
+	if input.KeyJustPressed(ebiten.KeyArrowUp) && c.history_index > 0 {
+		c.history_index--
+		c.line.Set(c.history[c.history_index])
+	}
+
+	if input.KeyJustPressed(ebiten.KeyArrowDown) && c.history_index < len(c.history) {
+		c.history_index++
+		if c.history_index < len(c.history) {
+			c.line.Set(c.history[c.history_index])
+		} else {
+			c.line.Set("")
+		}
+	}
+
+	if submitted {
+		line := c.line.Text
+		c.line.Set("")
+		c.list.ScrollToEnd()
+		if line != "" {
+			c.history = append(c.history, line)
+			c.history_index = len(c.history)
+			logger.Infof("> %s", line)
+			Execute(line)
+		}
+	}
+}
+
+func (c *Console) Draw(screen *ebiten.Image) {
+	if !c.open {
+		return
+	}
+
+	bounds := screen.Bounds()
+	panel := image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Min.Y+int(float64(bounds.Dy())*height))
+	// one row for the filters and one for the command line
+	visible_rows := panel.Dy()/row_height - 2
+
+	c.entries = logging.Entries(c.entries[:0])
+	c.matches = c.matches[:0]
+	for _, e := range c.entries {
+		if e.Level >= c.level && (c.tag == "" || e.Tag == c.tag) && e.Time.After(cleared) {
+			c.matches = append(c.matches, e)
+		}
+	}
+
+	c.ui.StartFrame(screen)
+	c.ui.Push(panel.Min.X, panel.Min.Y, panel.Dx(), panel.Dy(), nil)
+	c.ui.Panel()
+
+	tags := logging.Tags()
+	filters := int(logging.LevelCount) + 1 + len(tags)
+	c.ui.Push(panel.Min.X, panel.Min.Y, min(panel.Dx(), filters*64), row_height, &ui.GridLayout{Columns: filters, Rows: 1})
+	for level := logging.Level(0); level < logging.LevelCount; level++ {
+		c.ui.Button(ui.ButtonArgs{
+			Text:     level.String(),
+			AlignX:   0.5,
+			AlignY:   0.5,
+			Selected: level == c.level,
+			Behavior: ui.ButtonBehavior{
+				OnActivate: func() {
+					c.level = level
+				},
+			},
+		})
+	}
+	for _, tag := range append([]string{""}, tags...) {
+		text := tag
+		if tag == "" {
+			text = "all"
+		}
+		c.ui.Button(ui.ButtonArgs{
+			Text:     text,
+			AlignX:   0.5,
+			AlignY:   0.5,
+			Selected: tag == c.tag,
+			Behavior: ui.ButtonBehavior{
+				OnActivate: func() {
+					c.tag = tag
+					c.list.ScrollToEnd()
+				},
+			},
+		})
+	}
+	c.ui.Pop()
+
+	// newest entries are at the bottom, scrolling moves back in time
+	c.ui.Push(panel.Min.X+4, panel.Min.Y+row_height, panel.Dx()-4, visible_rows*row_height, nil)
+	c.ui.List(&c.list, len(c.matches), row_height, func(i int) {
+		c.ui.Label(c.matches[i].String(), 0, 0)
+	})
+	c.ui.Pop()
+
+	c.ui.Push(panel.Min.X+4, panel.Max.Y-row_height, panel.Dx()-4, row_height, nil)
+	c.ui.Label(prompt, 0, 0.5)
+	line := c.ui.Next()
+	x := line.Bounds().Min.X + ui.TextSize(prompt).Preferred.X
+	ui.DrawLine(line, &c.line, float64(x), line.Bounds().Min.Y, row_height, true, c.ui.Theme)
+	c.ui.Pop()
+
+	c.ui.Pop()
+	c.ui.EndFrame()
+}
@@ -0,0 +1,163 @@
+package csg
+
+// node is a node of a BSP tree. Its polygons lie in its plane, the front subtree holds what's in front of the
+// plane and the back subtree what's behind it. A tree of a closed solid has the inside of the solid behind the
+// planes of its leaves.
+type node struct {
+	plane       plane
+	polygons    []polygon
+	front, back *node
+	built       bool
+}
+
+func build(polygons []polygon) *node {
+	n := &node{}
+	n.add(polygons)
+	return n
+}
+
+// add sorts `polygons` into the tree, splitting them where they cross planes.
+func (n *node) add(polygons []polygon) {
+	if len(polygons) == 0 {
+		return
+	}
+	if !n.built {
+		n.plane = polygons[0].plane
+		n.built = true
+	}
+	var front, back []polygon
+	for _, p := range polygons {
+		n.plane.split(p, &n.polygons, &n.polygons, &front, &back)
+	}
+	if len(front) > 0 {
+		if n.front == nil {
+			n.front = &node{}
+		}
+		n.front.add(front)
+	}
+	if len(back) > 0 {
+		if n.back == nil {
+			n.back = &node{}
+		}
+		n.back.add(back)
+	}
+}
+
+// invert turns the solid of the tree inside out.
+func (n *node) invert() {
+	for i, p := range n.polygons {
+		n.polygons[i] = p.flip()
+	}
+	n.plane = n.plane.flip()
+	if n.front != nil {
+		n.front.invert()
+	}
+	if n.back != nil {
+		n.back.invert()
+	}
+	n.front, n.back = n.back, n.front
+}
+
+// clip is what's left of `polygons` outside the solid of the tree.
+func (n *node) clip(polygons []polygon) []polygon {
+	if !n.built {
+		return polygons
+	}
+	var front, back []polygon
+	for _, p := range polygons {
+		n.plane.split(p, &front, &back, &front, &back)
+	}
+	if n.front != nil {
+		front = n.front.clip(front)
+	}
+	if n.back != nil {
+		back = n.back.clip(back)
+	} else {
+		back = nil
+	}
+	return append(front, back...)
+}
+
+// clip_to removes what's inside the solid of `other` from the tree.
+func (n *node) clip_to(other *node) {
+	n.polygons = other.clip(n.polygons)
+	if n.front != nil {
+		n.front.clip_to(other)
+	}
+	if n.back != nil {
+		n.back.clip_to(other)
+	}
+}
+
+// all appends every polygon of the tree to `polygons`.
+func (n *node) all(polygons []polygon) []polygon {
+	polygons = append(polygons, n.polygons...)
+	if n.front != nil {
+		polygons = n.front.all(polygons)
+	}
+	if n.back != nil {
+		polygons = n.back.all(polygons)
+	}
+	return polygons
+}
+
+const (
+	coplanar = 0
+	front    = 1
+	back     = 2
+	spanning = front | back
+)
+
+// split puts `p` into the list where it lies relative to the plane, cutting it in two when it crosses it.
+// Polygons in the plane go to the coplanar lists, by which way they face.
+func (pl plane) split(p polygon, coplanar_front, coplanar_back, in_front, behind *[]polygon) {
+	var kind int
+	sides := make([]int, len(p.vertices))
+	distances := make([]float, len(p.vertices))
+	for i, v := range p.vertices {
+		distances[i] = pl.normal.Dot(v.Position) - pl.w
+		switch {
+		case distances[i] < -epsilon:
+			sides[i] = back
+		case distances[i] > epsilon:
+			sides[i] = front
+		}
+		kind |= sides[i]
+	}
+
+	switch kind {
+	case coplanar:
+		if pl.normal.Dot(p.plane.normal) > 0 {
+			*coplanar_front = append(*coplanar_front, p)
+		} else {
+			*coplanar_back = append(*coplanar_back, p)
+		}
+	case front:
+		*in_front = append(*in_front, p)
+	case back:
+		*behind = append(*behind, p)
+	default:
+		var f, b []Vertex
+		for i, v := range p.vertices {
+			j := (i + 1) % len(p.vertices)
+			if sides[i] != back {
+				f = append(f, v)
+			}
+			if sides[i] != front {
+				b = append(b, v)
+			}
+			if sides[i]|sides[j] == spanning {
+				t := distances[i] / (distances[i] - distances[j])
+				cut := v.lerp(p.vertices[j], t)
+				f = append(f, cut)
+				b = append(b, cut)
+			}
+		}
+		if len(f) >= 3 {
+			*in_front = append(*in_front, polygon{f, p.plane, p.material})
+		}
+		if len(b) >= 3 {
+			*behind = append(*behind, polygon{b, p.plane, p.material})
+		}
+	}
+}
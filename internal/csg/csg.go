@@ -0,0 +1,232 @@
+// Package csg combines solids the way constructive solid geometry does: the union of two, one with the other cut
+// out of it, or where they overlap. Solids are kept as polygons in BSP trees, after csg.js, and turned back into
+// meshes once combined. The meshes have to be closed, every edge shared by two triangles, or inside and outside
+// aren't defined.
+package csg
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+// epsilon is how far from a plane a point still counts as on it. Points are float32, so it's coarser than csg.js'.
+const epsilon = 1e-4
+
+// Vertex is a corner of a polygon of a Solid.
+type Vertex struct {
+	Position vec3
+	Texcoord vec2
+	Normal   vec3
+}
+
+func (v Vertex) lerp(to Vertex, t float) Vertex {
+	return Vertex{
+		Position: v.Position.Add(to.Position.Sub(v.Position).Mul(t)),
+		Texcoord: v.Texcoord.Add(to.Texcoord.Sub(v.Texcoord).Mul(t)),
+		Normal:   v.Normal.Add(to.Normal.Sub(v.Normal).Mul(t)),
+	}
+}
+
+type plane struct {
+	normal vec3
+	w      float
+}
+
+func (p plane) flip() plane {
+	return plane{p.normal.Mul(-1), -p.w}
+}
+
+// polygon is convex and flat, its vertices counter-clockwise seen from outside.
+type polygon struct {
+	vertices []Vertex
+	plane    plane
+	material string
+}
+
+func (p polygon) flip() polygon {
+	vertices := make([]Vertex, len(p.vertices))
+	for i, v := range p.vertices {
+		v.Normal = v.Normal.Mul(-1)
+		vertices[len(vertices)-1-i] = v
+	}
+	return polygon{vertices, p.plane.flip(), p.material}
+}
+
+// Solid is a closed surface of polygons, see FromMesh. Combining solids makes new ones, the ones combined are left
+// as they were; polygons are never changed in place, splitting and flipping them makes new ones.
+type Solid struct {
+	polygons []polygon
+}
+
+// FromMesh makes a solid of the triangles of `m`, with their texcoords, normals and materials. Triangles without
+// area are left out. Meshes without normals get the normals of their faces.
+func FromMesh(m *mesh.Mesh) *Solid {
+	s := &Solid{polygons: make([]polygon, 0, len(m.Triangles))}
+	for _, t := range m.Triangles {
+		p := [3]vec3{m.Points[t.P1], m.Points[t.P2], m.Points[t.P3]}
+		n := p[1].Sub(p[0]).Cross(p[2].Sub(p[0]))
+		if n.Len() < 1e-12 {
+			continue
+		}
+		n = n.Normalize()
+		normals := [3]vec3{n, n, n}
+		if len(m.Normals) > 0 {
+			normals = [3]vec3{m.Normals[t.N1], m.Normals[t.N2], m.Normals[t.N3]}
+		}
+		var material string
+		if int(t.Material) < len(m.Materials) {
+			material = m.Materials[t.Material]
+		}
+		s.polygons = append(s.polygons, polygon{
+			vertices: []Vertex{
+				{p[0], m.Texcoords[t.T1], normals[0]},
+				{p[1], m.Texcoords[t.T2], normals[1]},
+				{p[2], m.Texcoords[t.T3], normals[2]},
+			},
+			plane:    plane{n, n.Dot(p[0])},
+			material: material,
+		})
+	}
+	return s
+}
+
+// Transform is the solid moved by `t`.
+func (s *Solid) Transform(t mgl32.Mat4) *Solid {
+	turn := t.Mat3().Inv().Transpose()
+	out := &Solid{polygons: make([]polygon, len(s.polygons))}
+	for i, p := range s.polygons {
+		vertices := make([]Vertex, len(p.vertices))
+		for j, v := range p.vertices {
+			v.Position = mgl32.TransformCoordinate(v.Position, t)
+			if n := turn.Mul3x1(v.Normal); n.Len() > 0 {
+				v.Normal = n.Normalize()
+			}
+			vertices[j] = v
+		}
+		out.polygons[i] = polygon{vertices: vertices, material: p.material}
+		out.polygons[i].plane = plane_of(vertices)
+	}
+	return out
+}
+
+// plane_of is the plane through the first three vertices.
+func plane_of(vertices []Vertex) plane {
+	a, b, c := vertices[0].Position, vertices[1].Position, vertices[2].Position
+	n := b.Sub(a).Cross(c.Sub(a))
+	if n.Len() > 0 {
+		n = n.Normalize()
+	}
+	return plane{n, n.Dot(a)}
+}
+
+// Union is everything inside either solid.
+func Union(a, b *Solid) *Solid {
+	x, y := build(a.polygons), build(b.polygons)
+	x.clip_to(y)
+	y.clip_to(x)
+	y.invert()
+	y.clip_to(x)
+	y.invert()
+	x.add(y.all(nil))
+	return &Solid{polygons: x.all(nil)}
+}
+
+// Subtract is `a` with everything inside `b` cut out of it.
+func Subtract(a, b *Solid) *Solid {
+	x, y := build(a.polygons), build(b.polygons)
+	x.invert()
+	x.clip_to(y)
+	y.clip_to(x)
+	y.invert()
+	y.clip_to(x)
+	y.invert()
+	x.add(y.all(nil))
+	x.invert()
+	return &Solid{polygons: x.all(nil)}
+}
+
+// Intersect is everything inside both solids.
+func Intersect(a, b *Solid) *Solid {
+	x, y := build(a.polygons), build(b.polygons)
+	x.invert()
+	y.clip_to(x)
+	y.invert()
+	x.clip_to(y)
+	y.clip_to(x)
+	x.add(y.all(nil))
+	x.invert()
+	return &Solid{polygons: x.all(nil)}
+}
+
+// Polygons is how many polygons the solid is made of.
+func (s *Solid) Polygons() int {
+	return len(s.polygons)
+}
+
+// Mesh turns the solid into a mesh, its polygons into fans of triangles, with normals and tangents and bounded.
+// Corners which are the same are shared. It fails when the mesh would have more points than a mesh can index.
+func (s *Solid) Mesh() (*mesh.Mesh, error) {
+	m := &mesh.Mesh{}
+	points := make(map[vec3]uint16)
+	texcoords := make(map[vec2]uint16)
+	normals := make(map[vec3]uint16)
+	materials := make(map[string]uint16)
+	var overflow bool
+	index := func(v Vertex) (p, t, n uint16) {
+		if len(m.Points) > math.MaxUint16 || len(m.Texcoords) > math.MaxUint16 || len(m.Normals) > math.MaxUint16 {
+			overflow = true
+			return
+		}
+		return intern(points, &m.Points, v.Position), intern(texcoords, &m.Texcoords, v.Texcoord), intern(normals, &m.Normals, v.Normal)
+	}
+
+	for _, p := range s.polygons {
+		material, ok := materials[p.material]
+		if !ok {
+			material = uint16(len(m.Materials))
+			materials[p.material] = material
+			m.Materials = append(m.Materials, p.material)
+		}
+		p1, t1, n1 := index(p.vertices[0])
+		for i := 2; i < len(p.vertices); i++ {
+			p2, t2, n2 := index(p.vertices[i-1])
+			p3, t3, n3 := index(p.vertices[i])
+			m.Triangles = append(m.Triangles, mesh.Triangle{
+				P1: p1, P2: p2, P3: p3,
+				T1: t1, T2: t2, T3: t3,
+				N1: n1, N2: n2, N3: n3,
+				Material: material,
+			})
+		}
+	}
+	if overflow {
+		return nil, fmt.Errorf("csg: %d polygons are too many corners for a mesh", len(s.polygons))
+	}
+	// solids made of meshes without materials stay without
+	if len(m.Materials) == 1 && m.Materials[0] == "" {
+		m.Materials = nil
+	}
+	m.ComputeBounds()
+	m.ComputeTangents()
+	return m, nil
+}
+
+func intern[T comparable](indices map[T]uint16, values *[]T, v T) uint16 {
+	i, ok := indices[v]
+	if !ok {
+		i = uint16(len(*values))
+		indices[v] = i
+		*values = append(*values, v)
+	}
+	return i
+}
@@ -0,0 +1,62 @@
+package csg
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+// volume is the volume a closed mesh encloses, the sum of the tetrahedra from the origin to its triangles.
+func volume(m *mesh.Mesh) float {
+	var v float
+	for _, t := range m.Triangles {
+		v += m.Points[t.P1].Dot(m.Points[t.P2].Cross(m.Points[t.P3])) / 6
+	}
+	return v
+}
+
+func TestBoxes(t *testing.T) {
+	// two boxes of 2 a side overlapping in a box of 1 a side
+	a := FromMesh(mesh.Box(vec3{2, 2, 2}))
+	b := FromMesh(mesh.Box(vec3{2, 2, 2})).Transform(mgl32.Translate3D(1, 1, 1))
+	for _, test := range []struct {
+		name   string
+		solid  *Solid
+		volume float
+	}{
+		{"union", Union(a, b), 15},
+		{"subtract", Subtract(a, b), 7},
+		{"intersect", Intersect(a, b), 1},
+	} {
+		m, err := test.solid.Mesh()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := volume(m); v < test.volume-1e-3 || v > test.volume+1e-3 {
+			t.Errorf("the %s of two boxes has a volume of %v, want %v", test.name, v, test.volume)
+		}
+	}
+	// the solids combined are left alone
+	if m, _ := a.Mesh(); volume(m) < 8-1e-3 || volume(m) > 8+1e-3 {
+		t.Fatalf("the first box has a volume of %v after combining it", volume(m))
+	}
+}
+
+func TestHollowCube(t *testing.T) {
+	sphere := mesh.UVSphere(0.8, 8, 12)
+	cube := mesh.Box(vec3{2, 2, 2})
+	m, err := Subtract(FromMesh(cube), FromMesh(sphere)).Mesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := volume(cube) - volume(sphere)
+	if v := volume(m); v < want-1e-3 || v > want+1e-3 {
+		t.Fatalf("a cube with a sphere cut out of its middle has a volume of %v, want %v", v, want)
+	}
+	// cutting a sphere out of everything leaves nothing
+	if s := Subtract(FromMesh(sphere), FromMesh(cube)); s.Polygons() != 0 {
+		t.Fatalf("%d polygons are left of a sphere with a larger cube cut out", s.Polygons())
+	}
+}
@@ -0,0 +1,116 @@
+// Package curve has curves of a value drawn through keyframes, for authoring what would otherwise be a formula:
+// the easing of an animation, how large a particle is over its life or how thick fog is with distance. The UI
+// edits them with ui.CurveEdit, anything with a t asks for the value with Evaluate.
+package curve
+
+import "slices"
+
+type float = float32
+
+// Key is a keyframe, the curve passes through V at T. In and Out are the slopes it arrives at and leaves the key
+// with, equal ones make it smooth through the key and different ones make a corner.
+type Key struct {
+	T, V    float
+	In, Out float
+}
+
+// Curve is a cubic Hermite curve through Keys, which are ordered by T. Before the first key it holds the first
+// value, after the last the last.
+type Curve struct {
+	Keys []Key
+}
+
+// Linear is a straight curve from 0 at 0 to 1 at 1.
+func Linear() *Curve {
+	return &Curve{Keys: []Key{{T: 0, V: 0, In: 1, Out: 1}, {T: 1, V: 1, In: 1, Out: 1}}}
+}
+
+// FromEase samples `ease` from 0 to 1 into a curve of `keys` keys, at least 2, with the slopes of the ease at each.
+func FromEase(ease func(t float) float, keys int) *Curve {
+	keys = max(keys, 2)
+	c := &Curve{Keys: make([]Key, keys)}
+	for i := range c.Keys {
+		t := float(i) / float(keys-1)
+		slope := slope(ease, t)
+		c.Keys[i] = Key{T: t, V: ease(t), In: slope, Out: slope}
+	}
+	return c
+}
+
+// slope is the derivative of `f` at `t`, from a step to either side within 0 to 1.
+func slope(f func(t float) float, t float) float {
+	const h = 1e-3
+	a, b := max(t-h, 0), min(t+h, 1)
+	return (f(b) - f(a)) / (b - a)
+}
+
+// Evaluate is the value of the curve at `t`, 0 without keys.
+func (c *Curve) Evaluate(t float) float {
+	keys := c.Keys
+	switch {
+	case len(keys) == 0:
+		return 0
+	case t <= keys[0].T:
+		return keys[0].V
+	case t >= keys[len(keys)-1].T:
+		return keys[len(keys)-1].V
+	}
+	// the first key after t, the one before it starts the segment t is in
+	i, _ := slices.BinarySearchFunc(keys, t, func(k Key, t float) int {
+		if k.T <= t {
+			return -1
+		}
+		return 1
+	})
+	a, b := keys[i-1], keys[i]
+	dt := b.T - a.T
+	if dt <= 0 {
+		return b.V
+	}
+	s := (t - a.T) / dt
+	s2, s3 := s*s, s*s*s
+	return (2*s3-3*s2+1)*a.V + (s3-2*s2+s)*dt*a.Out + (-2*s3+3*s2)*b.V + (s3-s2)*dt*b.In
+}
+
+// Slope is the derivative of the curve at `t`.
+func (c *Curve) Slope(t float) float {
+	if len(c.Keys) < 2 {
+		return 0
+	}
+	const h = 1e-3
+	return (c.Evaluate(t+h) - c.Evaluate(t-h)) / (2 * h)
+}
+
+// Add adds a key at `t` with value `v`, its slopes those the curve has there, and returns its index.
+func (c *Curve) Add(t, v float) int {
+	slope := c.Slope(t)
+	i, _ := slices.BinarySearchFunc(c.Keys, t, func(k Key, t float) int {
+		if k.T < t {
+			return -1
+		}
+		return 1
+	})
+	c.Keys = slices.Insert(c.Keys, i, Key{T: t, V: v, In: slope, Out: slope})
+	return i
+}
+
+// Remove removes key `i`.
+func (c *Curve) Remove(i int) {
+	c.Keys = slices.Delete(c.Keys, i, i+1)
+}
+
+// Move moves key `i` to `t` and `v`. It doesn't pass the keys next to it, the order of the keys stays the same.
+func (c *Curve) Move(i int, t, v float) {
+	if i > 0 {
+		t = max(t, c.Keys[i-1].T)
+	}
+	if i < len(c.Keys)-1 {
+		t = min(t, c.Keys[i+1].T)
+	}
+	c.Keys[i].T, c.Keys[i].V = t, v
+}
+
+// Clone returns a copy of the curve which doesn't share its keys.
+func (c *Curve) Clone() *Curve {
+	return &Curve{Keys: slices.Clone(c.Keys)}
+}
@@ -0,0 +1,68 @@
+package curve
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, epsilon float) bool {
+	return math.Abs(float64(a-b)) <= float64(epsilon)
+}
+
+func TestEvaluatePassesThroughKeys(t *testing.T) {
+	c := &Curve{Keys: []Key{{T: 0, V: 2}, {T: 0.25, V: -1, In: 4, Out: -3}, {T: 1, V: 5}}}
+	for _, k := range c.Keys {
+		if got := c.Evaluate(k.T); !near(got, k.V, 1e-6) {
+			t.Errorf("at %v the curve is %v, its key says %v", k.T, got, k.V)
+		}
+	}
+	if c.Evaluate(-1) != 2 || c.Evaluate(3) != 5 {
+		t.Errorf("outside of its keys the curve is %v and %v", c.Evaluate(-1), c.Evaluate(3))
+	}
+	if (&Curve{}).Evaluate(0.5) != 0 {
+		t.Error("a curve without keys isn't 0")
+	}
+
+	lin := Linear()
+	for _, x := range []float{0.1, 0.5, 0.9} {
+		if got := lin.Evaluate(x); !near(got, x, 1e-6) {
+			t.Errorf("the linear curve is %v at %v", got, x)
+		}
+	}
+}
+
+func TestFromEaseFollowsIt(t *testing.T) {
+	smoothstep := func(t float) float { return t * t * (3 - 2*t) }
+	c := FromEase(smoothstep, 5)
+	for i := 0; i <= 100; i++ {
+		x := float(i) / 100
+		if got, want := c.Evaluate(x), smoothstep(x); !near(got, want, 1e-3) {
+			t.Fatalf("at %v the curve is %v, the ease %v", x, got, want)
+		}
+	}
+}
+
+func TestAddKeepsTheShape(t *testing.T) {
+	c := FromEase(func(t float) float { return t * t }, 2)
+	before := c.Clone()
+	i := c.Add(0.3, c.Evaluate(0.3))
+	if i != 1 || len(c.Keys) != 3 {
+		t.Fatalf("the key went in at %d of %d", i, len(c.Keys))
+	}
+	for j := 0; j <= 10; j++ {
+		x := float(j) / 10
+		if got, want := c.Evaluate(x), before.Evaluate(x); !near(got, want, 1e-2) {
+			t.Fatalf("adding a key on the curve moved it from %v to %v at %v", want, got, x)
+		}
+	}
+
+	// keys don't pass their neighbours
+	c.Move(1, 2, 0.5)
+	if c.Keys[1].T != 1 || c.Keys[1].V != 0.5 {
+		t.Fatalf("moving a key past the last left it at %+v", c.Keys[1])
+	}
+	c.Remove(1)
+	if len(c.Keys) != 2 || c.Keys[1].T != 1 {
+		t.Fatalf("removing a key left %+v", c.Keys)
+	}
+}
@@ -0,0 +1,363 @@
+// Package cvar holds tweak variables: named floats, ints, bools and colors which can be changed at runtime from the
+// console or the tweak panel instead of recompiling, e.g. `set r_wireframe 1`. Values are parsed as expressions,
+// so `set r_fov 60*pi/180` or `set r_far r_near*1000` work too.
+//
+// Variables are declared once, usually as package-level vars of the subsystem which reads them, and read every
+// time they're needed. Subsystems which have to do work when a value changes can Watch it instead.
+package cvar
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/config"
+)
+
+// config_section is the name of the section persisted variables are saved to.
+const config_section = "cvars"
+
+type Kind int
+
+const (
+	KindFloat Kind = iota
+	KindInt
+	KindBool
+	KindColor
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindFloat:
+		return "float"
+	case KindInt:
+		return "int"
+	case KindBool:
+		return "bool"
+	case KindColor:
+		return "color"
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+type Flags int
+
+const (
+	// Persist saves the variable to the config file whenever it changes.
+	Persist Flags = 1 << iota
+)
+
+type Var struct {
+	name  string
+	help  string
+	kind  Kind
+	flags Flags
+
+	// number holds floats, ints and bools (0 or 1)
+	number     float64
+	clr        color.RGBA
+	def_number float64
+	def_clr    color.RGBA
+
+	// min and max clamp numbers when they're not equal
+	min, max float64
+
+	watchers []func(v *Var)
+}
+
+var vars = make(map[string]*Var)
+
+// saved holds the persisted values loaded from the config file, they're applied to variables
+// registered after Load as well.
+var saved = make(map[string]string)
+
+func register(name, help string, kind Kind, flags Flags) *Var {
+	if _, ok := vars[name]; ok {
+		panic(fmt.Sprintf("cvar %q registered twice", name))
+	}
+	v := &Var{
+		name:  name,
+		help:  help,
+		kind:  kind,
+		flags: flags,
+	}
+	vars[name] = v
+	return v
+}
+
+// restore applies the persisted value of a freshly registered variable, if there's one.
+func (v *Var) restore() *Var {
+	if s, ok := saved[v.name]; ok && v.flags&Persist != 0 {
+		v.set_string(s)
+	}
+	return v
+}
+
+func Float(name string, value float64, flags Flags, help string) *Var {
+	v := register(name, help, KindFloat, flags)
+	v.number, v.def_number = value, value
+	return v.restore()
+}
+
+func Int(name string, value int, flags Flags, help string) *Var {
+	v := register(name, help, KindInt, flags)
+	v.number, v.def_number = float64(value), float64(value)
+	return v.restore()
+}
+
+func Bool(name string, value bool, flags Flags, help string) *Var {
+	v := register(name, help, KindBool, flags)
+	v.number = bool_number(value)
+	v.def_number = v.number
+	return v.restore()
+}
+
+func Color(name string, value color.RGBA, flags Flags, help string) *Var {
+	v := register(name, help, KindColor, flags)
+	v.clr, v.def_clr = value, value
+	return v.restore()
+}
+
+// Range clamps the variable to [min, max] from now on.
+func (v *Var) Range(min, max float64) *Var {
+	v.min, v.max = min, max
+	v.set_number(v.number)
+	return v
+}
+
+// Watch calls `fn` every time the value of the variable changes.
+func (v *Var) Watch(fn func(v *Var)) *Var {
+	v.watchers = append(v.watchers, fn)
+	return v
+}
+
+func (v *Var) Name() string      { return v.name }
+func (v *Var) Help() string      { return v.help }
+func (v *Var) Kind() Kind        { return v.kind }
+func (v *Var) Flags() Flags      { return v.flags }
+func (v *Var) Float() float64    { return v.number }
+func (v *Var) Float32() float32  { return float32(v.number) }
+func (v *Var) Int() int          { return int(v.number) }
+func (v *Var) Bool() bool        { return v.number != 0 }
+func (v *Var) Color() color.RGBA { return v.clr }
+
+// Bounds returns the range set with Range, min and max are equal when the variable is unbounded.
+func (v *Var) Bounds() (min, max float64) {
+	return v.min, v.max
+}
+
+// Step is a sensible increment for nudging the variable from the UI.
+func (v *Var) Step() float64 {
+	switch {
+	case v.kind != KindFloat:
+		return 1
+	case v.min != v.max:
+		return (v.max - v.min) / 100
+	case v.def_number != 0:
+		return math.Abs(v.def_number) / 10
+	}
+	return 0.1
+}
+
+func (v *Var) SetFloat(f float64) {
+	v.set_number(f)
+}
+
+func (v *Var) SetInt(i int) {
+	v.set_number(float64(i))
+}
+
+func (v *Var) SetBool(b bool) {
+	v.set_number(bool_number(b))
+}
+
+func (v *Var) SetColor(c color.RGBA) {
+	if v.kind != KindColor || c == v.clr {
+		return
+	}
+	v.clr = c
+	v.changed()
+}
+
+// Set parses `s` as an expression of the variable's kind and assigns it.
+func (v *Var) Set(s string) error {
+	return v.set_string(s)
+}
+
+// Reset restores the value the variable was declared with.
+func (v *Var) Reset() {
+	if v.kind == KindColor {
+		v.SetColor(v.def_clr)
+	} else {
+		v.set_number(v.def_number)
+	}
+}
+
+func (v *Var) set_number(f float64) {
+	if v.kind == KindColor || math.IsNaN(f) {
+		return
+	}
+	if v.min != v.max {
+		f = min(max(f, v.min), v.max)
+	}
+	switch v.kind {
+	case KindInt:
+		f = math.Round(f)
+	case KindBool:
+		f = bool_number(f != 0)
+	}
+	if f == v.number {
+		return
+	}
+	v.number = f
+	v.changed()
+}
+
+func (v *Var) set_string(s string) error {
+	if v.kind == KindColor {
+		c, err := parse_color(s)
+		if err != nil {
+			return err
+		}
+		v.SetColor(c)
+		return nil
+	}
+
+	if v.kind == KindBool {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true", "on", "yes":
+			v.SetBool(true)
+			return nil
+		case "false", "off", "no":
+			v.SetBool(false)
+			return nil
+		}
+	}
+
+	f, err := Eval(s)
+	if err != nil {
+		return err
+	}
+	v.set_number(f)
+	return nil
+}
+
+func (v *Var) changed() {
+	for _, fn := range v.watchers {
+		fn(v)
+	}
+	if v.flags&Persist != 0 {
+		saved[v.name] = v.String()
+		dirty, changed_at = true, time.Now()
+	}
+}
+
+func (v *Var) String() string {
+	switch v.kind {
+	case KindInt:
+		return strconv.Itoa(v.Int())
+	case KindBool:
+		return strconv.FormatBool(v.Bool())
+	case KindColor:
+		return fmt.Sprintf("#%02x%02x%02x%02x", v.clr.R, v.clr.G, v.clr.B, v.clr.A)
+	}
+	return strconv.FormatFloat(v.number, 'g', -1, 64)
+}
+
+func bool_number(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// parse_color accepts #rrggbb, #rrggbbaa or three to four expressions for the channels, e.g. `255 128 0`.
+func parse_color(s string) (color.RGBA, error) {
+	s = strings.TrimSpace(s)
+
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) == 6 {
+			hex += "ff"
+		}
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 8 {
+			return color.RGBA{}, fmt.Errorf("bad color %q", s)
+		}
+		return color.RGBA{uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)}, nil
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) != 3 && len(fields) != 4 {
+		return color.RGBA{}, fmt.Errorf("bad color %q, expected #rrggbb or 3 to 4 channels", s)
+	}
+	channels := [4]uint8{255, 255, 255, 255}
+	for i, field := range fields {
+		f, err := Eval(field)
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		channels[i] = uint8(min(max(math.Round(f), 0), 255))
+	}
+	return color.RGBA{channels[0], channels[1], channels[2], channels[3]}, nil
+}
+
+// Lookup returns the variable with the given name, or nil.
+func Lookup(name string) *Var {
+	return vars[name]
+}
+
+// All returns every variable, sorted by name.
+func All() []*Var {
+	all := make([]*Var, 0, len(vars))
+	for _, v := range vars {
+		all = append(all, v)
+	}
+	slices.SortFunc(all, func(a, b *Var) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return all
+}
+
+// dirty is set when a persisted variable changed since the last Save, changed_at is when the last one did.
+var (
+	dirty      bool
+	changed_at time.Time
+)
+
+// Load applies the persisted values in the config file.
+func Load() error {
+	loaded := make(map[string]string)
+	if _, err := config.Load(config_section, &loaded); err != nil {
+		return err
+	}
+	for name, s := range loaded {
+		saved[name] = s
+		if v, ok := vars[name]; ok && v.flags&Persist != 0 {
+			v.set_string(s)
+		}
+	}
+	dirty = false
+	return nil
+}
+
+// Save writes the persisted variables to the config file, if any of them changed.
+func Save() error {
+	if !dirty {
+		return nil
+	}
+	dirty = false
+	return config.Save(config_section, saved)
+}
+
+// SaveSettled is Save once no persisted variable changed for `quiet`, so a variable dragged along a slider is
+// written once it's let go rather than every frame of the drag.
+func SaveSettled(quiet time.Duration) error {
+	if !dirty || time.Since(changed_at) < quiet {
+		return nil
+	}
+	return Save()
+}
@@ -0,0 +1,283 @@
+package cvar
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Eval evaluates an arithmetic expression. It supports + - * / % and ^, parentheses, the constants pi and e,
+// booleans, a few functions such as sin or min, and the names of numeric variables, which evaluate to their
+// current value. ^ binds tighter than a sign, as in maths, so -2^2 is -4. Expressions coming to infinity or
+// something that isn't a number, like 1/0 or sqrt(-1), are errors.
+func Eval(s string) (float64, error) {
+	p := parser{src: s}
+	p.next()
+	f, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok != "" {
+		return 0, fmt.Errorf("unexpected %q in %q", p.tok, s)
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("%q is %v, not a number", s, f)
+	}
+	return f, nil
+}
+
+var constants = map[string]float64{
+	"pi":    math.Pi,
+	"e":     math.E,
+	"true":  1,
+	"false": 0,
+}
+
+var functions = map[string]func(args []float64) (float64, error){
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"tan":   unary(math.Tan),
+	"sqrt":  unary(math.Sqrt),
+	"abs":   unary(math.Abs),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"round": unary(math.Round),
+	"rad":   unary(func(x float64) float64 { return x * math.Pi / 180 }),
+	"deg":   unary(func(x float64) float64 { return x * 180 / math.Pi }),
+	"min": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("min needs arguments")
+		}
+		m := args[0]
+		for _, a := range args[1:] {
+			m = min(m, a)
+		}
+		return m, nil
+	},
+	"max": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("max needs arguments")
+		}
+		m := args[0]
+		for _, a := range args[1:] {
+			m = max(m, a)
+		}
+		return m, nil
+	},
+}
+
+func unary(fn func(float64) float64) func(args []float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("expected 1 argument, got %d", len(args))
+		}
+		return fn(args[0]), nil
+	}
+}
+
+// parser is a recursive descent parser which evaluates as it goes.
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = unary { ("*" | "/" | "%") unary }
+//	unary  = ( "-" | "+" ) unary | power
+//	power  = atom [ "^" unary ]
+//	atom   = number | name | name "(" [ expr { "," expr } ] ")" | "(" expr ")"
+type parser struct {
+	src string
+	pos int
+	// tok is the current token, empty at the end of the input
+	tok string
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (is_digit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		// exponents such as 1e-3
+		if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			end := p.pos + 1
+			if end < len(p.src) && (p.src[end] == '-' || p.src[end] == '+') {
+				end++
+			}
+			if end < len(p.src) && is_digit(p.src[end]) {
+				for end < len(p.src) && is_digit(p.src[end]) {
+					end++
+				}
+				p.pos = end
+			}
+		}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || is_digit(p.src[p.pos]) || unicode.IsLetter(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func is_digit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *parser) expr() (float64, error) {
+	f, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok
+		p.next()
+		g, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			f += g
+		} else {
+			f -= g
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) term() (float64, error) {
+	f, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for p.tok == "*" || p.tok == "/" || p.tok == "%" {
+		op := p.tok
+		p.next()
+		g, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "*":
+			f *= g
+		case "/":
+			f /= g
+		case "%":
+			f = math.Mod(f, g)
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) power() (float64, error) {
+	f, err := p.atom()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok == "^" {
+		p.next()
+		// the exponent may have a sign of its own, 2^-1, and is right associative, 2^3^2 is 2^9
+		g, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		f = math.Pow(f, g)
+	}
+	return f, nil
+}
+
+func (p *parser) unary() (float64, error) {
+	switch p.tok {
+	case "-":
+		p.next()
+		f, err := p.unary()
+		return -f, err
+	case "+":
+		p.next()
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *parser) atom() (float64, error) {
+	tok := p.tok
+
+	switch {
+	case tok == "":
+		return 0, fmt.Errorf("unexpected end of %q", p.src)
+
+	case tok == "(":
+		p.next()
+		f, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		if p.tok != ")" {
+			return 0, fmt.Errorf("missing ) in %q", p.src)
+		}
+		p.next()
+		return f, nil
+
+	case is_digit(tok[0]) || tok[0] == '.':
+		p.next()
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad number %q", tok)
+		}
+		return f, nil
+
+	case tok[0] == '_' || unicode.IsLetter(rune(tok[0])):
+		p.next()
+		if p.tok == "(" {
+			return p.call(tok)
+		}
+		if f, ok := constants[strings.ToLower(tok)]; ok {
+			return f, nil
+		}
+		if v, ok := vars[tok]; ok && v.kind != KindColor {
+			return v.number, nil
+		}
+		return 0, fmt.Errorf("unknown name %q", tok)
+	}
+
+	return 0, fmt.Errorf("unexpected %q in %q", tok, p.src)
+}
+
+func (p *parser) call(name string) (float64, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+	p.next() // (
+
+	var args []float64
+	for p.tok != ")" {
+		f, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, f)
+		if p.tok == "," {
+			p.next()
+		} else if p.tok != ")" {
+			return 0, fmt.Errorf("missing ) after arguments of %s in %q", name, p.src)
+		}
+	}
+	p.next() // )
+
+	f, err := fn(args)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
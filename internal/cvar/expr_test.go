@@ -0,0 +1,57 @@
+package cvar
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	Float("test_expr_speed", 2.5, 0, "read by TestEval")
+
+	for _, tc := range []struct {
+		src  string
+		want float64
+	}{
+		{"1", 1},
+		{" 1.5e2 ", 150},
+		{".5", 0.5},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"12 / 4 / 3", 1},
+		{"7 % 4", 3},
+		{"-2^2", -4},
+		{"(-2)^2", 4},
+		{"2^-1", 0.5},
+		{"2^3^2", 512},
+		{"--3", 3},
+		{"-+3", -3},
+		{"2 * -3", -6},
+		{"pi", math.Pi},
+		{"E", math.E},
+		{"true + false", 1},
+		{"deg(rad(90))", 90},
+		{"min(3, 1, 2) + max(4, 5)", 6},
+		{"sqrt(abs(-16))", 4},
+		{"test_expr_speed * 2", 5},
+	} {
+		got, err := Eval(tc.src)
+		if err != nil {
+			t.Errorf("%q: %v", tc.src, err)
+			continue
+		}
+		if math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%q is %v, not %v", tc.src, got, tc.want)
+		}
+	}
+
+	for _, src := range []string{
+		"", "1 +", "(1", "1)", "2 3", "nope", "nope(1)", "sin(1, 2)", "min()", "1..2",
+		// not numbers
+		"1/0", "-1/0", "0/0", "sqrt(-1)", "10^400", "5 % 0",
+	} {
+		if got, err := Eval(src); err == nil {
+			t.Errorf("%q is %v, not an error", src, got)
+		}
+	}
+}
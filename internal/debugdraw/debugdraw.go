@@ -0,0 +1,106 @@
+// Package debugdraw draws world space lines and points over a frame, for showing what isn't a mesh such as paths
+// and directions. Everything is projected with the view and projection of a pipeline.Context and drawn with the
+// vector package on top of whatever is there, without depth.
+package debugdraw
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/spline"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+// Line draws a line `width` pixels wide from `a` to `b`.
+func Line(dst *ebiten.Image, ctx *pipeline.Context, a, b vec3, width float, clr color.Color) {
+	pa, pb, ok := ctx.ProjectLine(a, b)
+	if !ok {
+		return
+	}
+	vector.StrokeLine(dst, pa.X(), pa.Y(), pb.X(), pb.Y(), width, clr, true)
+}
+
+// Point draws a dot `radius` pixels in size at `p`.
+func Point(dst *ebiten.Image, ctx *pipeline.Context, p vec3, radius float, clr color.Color) {
+	s, ok := ctx.Project(p)
+	if !ok {
+		return
+	}
+	vector.DrawFilledCircle(dst, s.X(), s.Y(), radius, clr, true)
+}
+
+// Polyline draws lines through `points`, back to the first one when `closed`.
+func Polyline(dst *ebiten.Image, ctx *pipeline.Context, points []vec3, closed bool, width float, clr color.Color) {
+	for i := 1; i < len(points); i++ {
+		Line(dst, ctx, points[i-1], points[i], width, clr)
+	}
+	if closed && len(points) > 2 {
+		Line(dst, ctx, points[len(points)-1], points[0], width, clr)
+	}
+}
+
+// path_step is the length of the straight pieces paths are drawn with.
+const path_step = 0.2
+
+// Path draws the curve of `path` and a tick every `spacing` units along it, which are evenly spaced however
+// unevenly the curve's own parameter runs. A `spacing` of 0 leaves them out.
+func Path(dst *ebiten.Image, ctx *pipeline.Context, path *spline.Path, spacing float, clr color.Color) {
+	length := path.Length()
+	previous := path.At(0)
+	for d := float(path_step); d < length+path_step; d += path_step {
+		next := path.At(min(d, length))
+		Line(dst, ctx, previous, next, 2, clr)
+		previous = next
+	}
+	if spacing <= 0 {
+		return
+	}
+	for d := float(0); d <= length; d += spacing {
+		Point(dst, ctx, path.At(d), 2, clr)
+	}
+}
+
+// Controls draws the points of a curve and the lines between them, the control polygon of a Bézier curve.
+func Controls(dst *ebiten.Image, ctx *pipeline.Context, points []vec3, closed bool, clr color.Color) {
+	Polyline(dst, ctx, points, closed, 1, clr)
+	for _, p := range points {
+		Point(dst, ctx, p, 4, clr)
+	}
+}
+
+// circle_segments is how many straight pieces circles are drawn with.
+const circle_segments = 32
+
+// Circle draws a circle of `radius` around `center` in the plane facing `axis`.
+func Circle(dst *ebiten.Image, ctx *pipeline.Context, center, axis vec3, radius, width float, clr color.Color) {
+	// two directions across the axis, from whichever of x and y is further from it
+	n := axis.Normalize()
+	u := vec3{1, 0, 0}
+	if mgl32.Abs(n.X()) > 0.9 {
+		u = vec3{0, 1, 0}
+	}
+	u = n.Cross(u).Normalize()
+	v := n.Cross(u)
+	points := make([]vec3, circle_segments)
+	for i := range points {
+		a := 2 * math.Pi * float64(i) / circle_segments
+		points[i] = center.Add(u.Mul(radius * float(math.Cos(a)))).Add(v.Mul(radius * float(math.Sin(a))))
+	}
+	Polyline(dst, ctx, points, true, width, clr)
+}
+
+// Sphere draws a sphere of `radius` around `center` as three circles, one around each axis.
+func Sphere(dst *ebiten.Image, ctx *pipeline.Context, center vec3, radius, width float, clr color.Color) {
+	for _, axis := range []vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+		Circle(dst, ctx, center, axis, radius, width, clr)
+	}
+}
@@ -0,0 +1,197 @@
+// Package debugserver streams what a demo would otherwise clutter its window with, its stats, its log and frames,
+// over a WebSocket to a dashboard in a browser, which it serves itself. Messages go to every open dashboard; ones a
+// slow dashboard can't keep up with are dropped rather than holding up the demo.
+package debugserver
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+//go:embed dashboard.html
+var dashboard []byte
+
+// queue is how many messages wait for each client before more are dropped.
+const queue = 64
+
+// Server serves the dashboard at / and the WebSocket it connects to at /ws.
+type Server struct {
+	lock    sync.Mutex
+	clients map[*client]bool
+	// requests are what dashboards asked for, such as a frame, until the demo gets to them
+	requests chan string
+}
+
+type client struct {
+	conn net.Conn
+	out  chan message
+}
+
+type message struct {
+	opcode  byte
+	payload []byte
+}
+
+// New makes a server, see ListenAndServe or use it as an http.Handler.
+func New() *Server {
+	return &Server{
+		clients:  make(map[*client]bool),
+		requests: make(chan string, queue),
+	}
+}
+
+// ListenAndServe serves on `addr`, such as localhost:6060, in the background. It fails when the address is taken.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go http.Serve(l, s)
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboard)
+	case "/ws":
+		if !same_origin(r) {
+			http.Error(w, "the dashboard only connects from its own page", http.StatusForbidden)
+			return
+		}
+		upgrade(w, r, func(conn net.Conn, rw *bufio.ReadWriter) {
+			c := &client{conn: conn, out: make(chan message, queue)}
+			s.lock.Lock()
+			s.clients[c] = true
+			s.lock.Unlock()
+			go s.write(c)
+			go s.read(c, rw.Reader)
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// same_origin reports whether `r` comes from the dashboard's own page, or from a script, which sends no Origin.
+// Browsers let any page open a WebSocket to localhost, and the Origin they send is the only way to tell, and a Host
+// which isn't localhost or an address is a page which rebound its own name to it.
+func same_origin(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if !strings.EqualFold(host, "localhost") && net.ParseIP(strings.Trim(host, "[]")) == nil {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// Clients is how many dashboards are connected.
+func (s *Server) Clients() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.clients)
+}
+
+// Send sends `data` as JSON to every dashboard, as {"kind": kind, "data": data}.
+func (s *Server) Send(kind string, data any) error {
+	payload, err := json.Marshal(struct {
+		Kind string `json:"kind"`
+		Data any    `json:"data"`
+	}{kind, data})
+	if err != nil {
+		return err
+	}
+	s.broadcast(message{op_text, payload})
+	return nil
+}
+
+// SendFrame sends a frame encoded as a PNG to every dashboard.
+func (s *Server) SendFrame(png []byte) {
+	s.broadcast(message{op_binary, png})
+}
+
+// Request returns the next thing a dashboard asked for, false when there's nothing.
+func (s *Server) Request() (string, bool) {
+	select {
+	case r := <-s.requests:
+		return r, true
+	default:
+		return "", false
+	}
+}
+
+func (s *Server) broadcast(m message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for c := range s.clients {
+		c.send(m)
+	}
+}
+
+// send queues `m` for the client, or drops it when the client is too far behind.
+func (c *client) send(m message) {
+	select {
+	case c.out <- m:
+	default:
+	}
+}
+
+// write sends the client its messages until the connection fails or closes.
+func (s *Server) write(c *client) {
+	w := bufio.NewWriter(c.conn)
+	for m := range c.out {
+		if write_frame(w, m.opcode, m.payload) != nil || w.Flush() != nil {
+			break
+		}
+		if m.opcode == op_close {
+			break
+		}
+	}
+	c.conn.Close()
+}
+
+// read reads what the client sends until it closes, answering pings and queuing text as requests.
+func (s *Server) read(c *client, r *bufio.Reader) {
+	defer s.drop(c)
+	for {
+		opcode, payload, err := read_frame(r)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case op_text:
+			select {
+			case s.requests <- string(payload):
+			default:
+			}
+		case op_ping:
+			c.send(message{op_pong, payload})
+		case op_close:
+			c.send(message{op_close, payload})
+			return
+		}
+	}
+}
+
+// drop forgets the client, its writer closes the connection once it ran out of messages.
+func (s *Server) drop(c *client) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.clients[c] {
+		delete(s.clients, c)
+		close(c.out)
+	}
+}
@@ -0,0 +1,146 @@
+package debugserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// the example from RFC 6455
+	if got := accept_key("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("accept key is %s", got)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	for _, n := range []int{0, 5, 125, 126, 300, 0xffff, 0x10000} {
+		payload := bytes.Repeat([]byte{'x'}, n)
+		var b bytes.Buffer
+		if err := write_frame(&b, op_binary, payload); err != nil {
+			t.Fatal(err)
+		}
+		opcode, got, err := read_frame(&b)
+		if err != nil || opcode != op_binary || !bytes.Equal(got, payload) {
+			t.Fatalf("a frame of %d bytes read back as %d bytes, opcode %d: %v", n, len(got), opcode, err)
+		}
+	}
+}
+
+// dial connects to the server's socket the way a browser would.
+func dial(t *testing.T, server *httptest.Server) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	r := bufio.NewReader(conn)
+	response, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols || response.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("the handshake was answered with %s", response.Status)
+	}
+	return conn, r
+}
+
+func TestServer(t *testing.T) {
+	s := New()
+	server := httptest.NewServer(s)
+	defer server.Close()
+	conn, r := dial(t, server)
+
+	// the client is registered once the handshake is answered
+	eventually(t, "the client connected", func() bool { return s.Clients() == 1 })
+	if err := s.Send("stats", map[string]int{"triangles": 12}); err != nil {
+		t.Fatal(err)
+	}
+	opcode, payload, err := read_frame(r)
+	if err != nil || opcode != op_text {
+		t.Fatalf("read opcode %d: %v", opcode, err)
+	}
+	var m struct {
+		Kind string
+		Data map[string]int
+	}
+	if err := json.Unmarshal(payload, &m); err != nil || m.Kind != "stats" || m.Data["triangles"] != 12 {
+		t.Fatalf("the dashboard got %s", payload)
+	}
+
+	// what the browser sends is masked
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op_text, 0x80 | 5, mask[0], mask[1], mask[2], mask[3]}
+	for i, c := range []byte("frame") {
+		frame = append(frame, c^mask[i%4])
+	}
+	conn.Write(frame)
+	var request string
+	eventually(t, "the request arrived", func() (ok bool) {
+		request, ok = s.Request()
+		return ok
+	})
+	if request != "frame" {
+		t.Fatalf("the dashboard asked for %q", request)
+	}
+
+	// closing is answered with a close, and the client is forgotten
+	conn.Write([]byte{0x80 | op_close, 0x80, 0, 0, 0, 0})
+	if opcode, _, err := read_frame(r); err != nil || opcode != op_close {
+		t.Fatalf("the close was answered with opcode %d: %v", opcode, err)
+	}
+	eventually(t, "the client was forgotten", func() bool { return s.Clients() == 0 })
+}
+
+// eventually waits for `done`, what happens on the server's goroutines happens a little later.
+func eventually(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !done(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("it never happened that %s", what)
+		}
+	}
+}
+
+func TestForeignOrigin(t *testing.T) {
+	for _, test := range []struct {
+		host, origin string
+		want         int
+	}{
+		{"localhost:6060", "", http.StatusBadRequest},
+		{"localhost:6060", "http://localhost:6060", http.StatusBadRequest},
+		{"127.0.0.1:6060", "http://127.0.0.1:6060", http.StatusBadRequest},
+		{"localhost:6060", "https://example.com", http.StatusForbidden},
+		{"localhost:6060", "http://localhost:8080", http.StatusForbidden},
+		{"example.com:6060", "http://example.com:6060", http.StatusForbidden},
+	} {
+		// without an Upgrade header, a handshake which gets past the origin is refused as not a WebSocket
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.Host = test.host
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		w := httptest.NewRecorder()
+		New().ServeHTTP(w, r)
+		if w.Code != test.want {
+			t.Errorf("a handshake from %q to %s was answered with %d, want %d", test.origin, test.host, w.Code, test.want)
+		}
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	w := httptest.NewRecorder()
+	New().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "WebSocket") {
+		t.Fatalf("the dashboard was served with %d", w.Code)
+	}
+}
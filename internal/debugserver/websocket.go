@@ -0,0 +1,129 @@
+package debugserver
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Just enough of RFC 6455 for a dashboard: the handshake and single frame messages. Fragmented messages from the
+// browser aren't put back together, the dashboard never sends any.
+
+// Opcodes of frames.
+const (
+	op_text   = 0x1
+	op_binary = 0x2
+	op_close  = 0x8
+	op_ping   = 0x9
+	op_pong   = 0xa
+)
+
+// websocket_guid is what the handshake appends to the client's key, from the RFC.
+const websocket_guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// max_incoming is the largest frame read from a client, anything the dashboard sends is tiny.
+const max_incoming = 1 << 16
+
+// accept_key is the Sec-WebSocket-Accept answering the client's Sec-WebSocket-Key.
+func accept_key(key string) string {
+	sum := sha1.Sum([]byte(key + websocket_guid))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// upgrade takes over the connection of a WebSocket handshake request and answers it. `accepted` is called once the
+// answer is on its way, so no frame written to the connection from then on can get ahead of it.
+func upgrade(w http.ResponseWriter, r *http.Request, accepted func(net.Conn, *bufio.ReadWriter)) error {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "this is a websocket", http.StatusBadRequest)
+		return errors.New("not a websocket handshake")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "can't take over the connection", http.StatusInternalServerError)
+		return errors.New("connection can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", accept_key(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+	accepted(conn, rw)
+	return nil
+}
+
+// write_frame writes a whole message as one frame. Servers don't mask.
+func write_frame(w io.Writer, opcode byte, payload []byte) error {
+	header := make([]byte, 0, 10)
+	header = append(header, 0x80|opcode)
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// read_frame reads a frame, unmasking it when it's masked, as every frame from a browser is.
+func read_frame(r io.Reader) (opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var length [2]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(length[:]))
+	case 127:
+		var length [8]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(length[:])
+	}
+	if n > max_incoming {
+		return 0, nil, fmt.Errorf("frame of %d bytes is too large", n)
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
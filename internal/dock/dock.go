@@ -0,0 +1,308 @@
+// Package dock lays out windows in a dock space, the way editors arrange their panels. A space is a tree: splits
+// divide an area in two, leaves hold a group of windows shown as tabs, one at a time. Windows are moved around by
+// docking them onto a leaf, into its tabs or against one of its edges, which splits it.
+//
+// The package only keeps the tree, the ui package draws it and drags the windows around. Spaces are JSON and
+// saved to the config file, so arrangements survive restarts.
+package dock
+
+import (
+	"image"
+	"slices"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/config"
+)
+
+// Side is where a window is docked onto a leaf.
+type Side int
+
+const (
+	// Center adds the window to the tabs of the leaf.
+	Center Side = iota
+	Left
+	Right
+	Top
+	Bottom
+)
+
+// Node is a split or a leaf.
+type Node struct {
+	// Children are the two halves of a split, left and right or top and bottom when Vertical. Ratio is how much
+	// of the split the first one gets.
+	Children []*Node `json:"children,omitempty"`
+	Vertical bool    `json:"vertical,omitempty"`
+	Ratio    float64 `json:"ratio,omitempty"`
+
+	// Windows are the tabs of a leaf, Active the one shown.
+	Windows []string `json:"windows,omitempty"`
+	Active  int      `json:"active,omitempty"`
+}
+
+// Leaf reports whether the node is a leaf.
+func (n *Node) Leaf() bool {
+	return len(n.Children) == 0
+}
+
+// Shown is the window shown in a leaf, empty when it has none.
+func (n *Node) Shown() string {
+	if len(n.Windows) == 0 {
+		return ""
+	}
+	return n.Windows[min(max(n.Active, 0), len(n.Windows)-1)]
+}
+
+// Space is a tree of windows.
+type Space struct {
+	Root *Node `json:"root"`
+}
+
+// NewSpace returns a space with every one of `windows` tabbed together.
+func NewSpace(windows ...string) *Space {
+	return &Space{Root: &Node{Windows: windows}}
+}
+
+// Leaves returns the leaves in order, left to right and top to bottom.
+func (s *Space) Leaves() []*Node {
+	var leaves []*Node
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if n.Leaf() {
+			leaves = append(leaves, n)
+			return
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(s.Root)
+	return leaves
+}
+
+// Find returns the leaf holding `window`, nil if it isn't docked.
+func (s *Space) Find(window string) *Node {
+	for _, leaf := range s.Leaves() {
+		if slices.Contains(leaf.Windows, window) {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// Show makes `window` the shown tab of its leaf.
+func (s *Space) Show(window string) {
+	if leaf := s.Find(window); leaf != nil {
+		leaf.Active = slices.Index(leaf.Windows, window)
+	}
+}
+
+// split_ratio is how much of a leaf a window docked against its edge takes.
+const split_ratio = 0.3
+
+// Dock moves `window` to `side` of `target`, a leaf of the space. Windows which weren't docked yet are added.
+func (s *Space) Dock(window string, target *Node, side Side) {
+	if slices.Equal(target.Windows, []string{window}) {
+		// it's already there, and alone, splitting would leave an empty leaf behind
+		return
+	}
+	if from, to := s.remove(window); target == from {
+		target = to
+	}
+
+	if side == Center {
+		target.Windows = append(target.Windows, window)
+		target.Active = len(target.Windows) - 1
+		return
+	}
+
+	docked := &Node{Windows: []string{window}}
+	moved := &Node{Windows: target.Windows, Active: target.Active, Children: target.Children, Vertical: target.Vertical, Ratio: target.Ratio}
+	*target = Node{Vertical: side == Top || side == Bottom, Ratio: split_ratio, Children: []*Node{docked, moved}}
+	if side == Right || side == Bottom {
+		target.Ratio = 1 - split_ratio
+		target.Children[0], target.Children[1] = moved, docked
+	}
+}
+
+// Remove takes `window` out of the space. A leaf left empty is closed, its sibling takes up the whole split.
+func (s *Space) Remove(window string) {
+	s.remove(window)
+}
+
+// remove removes `window`. When that closes a leaf its sibling moves into the split's node: `from` is the node
+// which isn't part of the tree anymore and `to` the one now holding what it held.
+func (s *Space) remove(window string) (from, to *Node) {
+	leaf := s.Find(window)
+	if leaf == nil {
+		return nil, nil
+	}
+	i := slices.Index(leaf.Windows, window)
+	leaf.Windows = slices.Delete(leaf.Windows, i, i+1)
+	if leaf.Active > i || leaf.Active >= len(leaf.Windows) {
+		leaf.Active = max(leaf.Active-1, 0)
+	}
+	if len(leaf.Windows) > 0 {
+		return nil, nil
+	}
+
+	parent := s.parent(leaf)
+	if parent == nil {
+		return nil, nil
+	}
+	sibling := parent.Children[0]
+	if sibling == leaf {
+		sibling = parent.Children[1]
+	}
+	*parent = *sibling
+	return sibling, parent
+}
+
+// parent returns the split `n` is a child of, nil for the root.
+func (s *Space) parent(n *Node) *Node {
+	var find func(at *Node) *Node
+	find = func(at *Node) *Node {
+		for _, c := range at.Children {
+			if c == n {
+				return at
+			}
+			if p := find(c); p != nil {
+				return p
+			}
+		}
+		return nil
+	}
+	return find(s.Root)
+}
+
+// Fit makes the space hold exactly `windows`: ones it doesn't know anymore are removed and new ones tabbed into
+// the first leaf. Spaces loaded from the config file are fitted to the windows of the current version of a demo.
+func (s *Space) Fit(windows []string) {
+	if s.Root == nil {
+		s.Root = &Node{}
+	}
+	for _, leaf := range s.Leaves() {
+		for _, w := range slices.Clone(leaf.Windows) {
+			if !slices.Contains(windows, w) {
+				s.Remove(w)
+			}
+		}
+	}
+	for _, w := range windows {
+		if s.Find(w) == nil {
+			first := s.Leaves()[0]
+			first.Windows = append(first.Windows, w)
+		}
+	}
+}
+
+// Area is where a leaf is.
+type Area struct {
+	Node *Node
+	Rect image.Rectangle
+}
+
+// Splitter is the gap between the children of a split, dragging it moves the split.
+type Splitter struct {
+	Node *Node
+	Rect image.Rectangle
+	// Span is the rectangle of the whole split, the ratio is relative to it.
+	Span image.Rectangle
+}
+
+// Layout divides `rect` between the leaves, leaving `gap` pixels between the children of splits for splitters.
+func (s *Space) Layout(rect image.Rectangle, gap int) ([]Area, []Splitter) {
+	var areas []Area
+	var splitters []Splitter
+	var walk func(n *Node, r image.Rectangle)
+	walk = func(n *Node, r image.Rectangle) {
+		if n.Leaf() {
+			areas = append(areas, Area{Node: n, Rect: r})
+			return
+		}
+		a, b, between := split(r, n.Vertical, n.Ratio, gap)
+		splitters = append(splitters, Splitter{Node: n, Rect: between, Span: r})
+		walk(n.Children[0], a)
+		walk(n.Children[1], b)
+	}
+	walk(s.Root, rect)
+	return areas, splitters
+}
+
+func split(r image.Rectangle, vertical bool, ratio float64, gap int) (a, b, between image.Rectangle) {
+	if vertical {
+		y := r.Min.Y + int(float64(r.Dy()-gap)*ratio)
+		return image.Rect(r.Min.X, r.Min.Y, r.Max.X, y),
+			image.Rect(r.Min.X, y+gap, r.Max.X, r.Max.Y),
+			image.Rect(r.Min.X, y, r.Max.X, y+gap)
+	}
+	x := r.Min.X + int(float64(r.Dx()-gap)*ratio)
+	return image.Rect(r.Min.X, r.Min.Y, x, r.Max.Y),
+		image.Rect(x+gap, r.Min.Y, r.Max.X, r.Max.Y),
+		image.Rect(x, r.Min.Y, x+gap, r.Max.Y)
+}
+
+// edge is the portion of a leaf along each edge which docks against that edge, the middle docks into the tabs.
+const edge = 0.25
+
+// Zone returns the side of `rect` a window dropped at `p` docks to.
+func Zone(rect image.Rectangle, p image.Point) Side {
+	if rect.Empty() {
+		return Center
+	}
+	x := float64(p.X-rect.Min.X) / float64(rect.Dx())
+	y := float64(p.Y-rect.Min.Y) / float64(rect.Dy())
+	// the nearest edge, if it's near enough
+	side, nearest := Center, edge
+	for _, e := range []struct {
+		side     Side
+		distance float64
+	}{{Left, x}, {Right, 1 - x}, {Top, y}, {Bottom, 1 - y}} {
+		if e.distance < nearest {
+			side, nearest = e.side, e.distance
+		}
+	}
+	return side
+}
+
+// Preview is the part of `rect` a window docked to `side` would take.
+func Preview(rect image.Rectangle, side Side) image.Rectangle {
+	switch side {
+	case Left:
+		a, _, _ := split(rect, false, split_ratio, 0)
+		return a
+	case Right:
+		_, b, _ := split(rect, false, 1-split_ratio, 0)
+		return b
+	case Top:
+		a, _, _ := split(rect, true, split_ratio, 0)
+		return a
+	case Bottom:
+		_, b, _ := split(rect, true, 1-split_ratio, 0)
+		return b
+	}
+	return rect
+}
+
+// config_section holds the spaces of every demo, by name.
+const config_section = "docks"
+
+// Load returns the space saved as `name`, or `fallback` when there's none, fitted to `windows`.
+func Load(name string, fallback *Space, windows []string) (*Space, error) {
+	var saved map[string]*Space
+	_, err := config.Load(config_section, &saved)
+	s := saved[name]
+	if s == nil || s.Root == nil {
+		s = fallback
+	}
+	s.Fit(windows)
+	return s, err
+}
+
+// Save saves the space as `name`.
+func (s *Space) Save(name string) error {
+	saved := map[string]*Space{}
+	if _, err := config.Load(config_section, &saved); err != nil {
+		return err
+	}
+	saved[name] = s
+	return config.Save(config_section, saved)
+}
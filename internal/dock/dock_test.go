@@ -0,0 +1,129 @@
+package dock
+
+import (
+	"encoding/json"
+	"image"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/config"
+)
+
+func windows(s *Space) [][]string {
+	var out [][]string
+	for _, leaf := range s.Leaves() {
+		out = append(out, leaf.Windows)
+	}
+	return out
+}
+
+func TestDockSplitsAndTabs(t *testing.T) {
+	s := NewSpace("viewport", "log", "list")
+	s.Dock("log", s.Root, Bottom)
+	if got := windows(s); !reflect.DeepEqual(got, [][]string{{"viewport", "list"}, {"log"}}) {
+		t.Fatalf("docking log to the bottom made %v", got)
+	}
+	if !s.Root.Vertical || s.Root.Ratio != 1-split_ratio {
+		t.Fatalf("the split is %+v", s.Root)
+	}
+
+	s.Dock("list", s.Find("viewport"), Left)
+	if got := windows(s); !reflect.DeepEqual(got, [][]string{{"list"}, {"viewport"}, {"log"}}) {
+		t.Fatalf("docking list to the left made %v", got)
+	}
+
+	s.Dock("viewport", s.Find("log"), Center)
+	if got := windows(s); !reflect.DeepEqual(got, [][]string{{"list"}, {"log", "viewport"}}) {
+		t.Fatalf("tabbing viewport with log made %v", got)
+	}
+	if s.Find("log").Shown() != "viewport" {
+		t.Fatal("a window docked into tabs isn't shown")
+	}
+}
+
+func TestDockOntoItself(t *testing.T) {
+	s := NewSpace("a", "b")
+	s.Dock("b", s.Root, Right)
+	before := windows(s)
+	s.Dock("b", s.Find("b"), Left)
+	if got := windows(s); !reflect.DeepEqual(got, before) {
+		t.Fatalf("docking a lone window against its own leaf made %v", got)
+	}
+
+	// a window docked against its own leaf, with company, splits it
+	s = NewSpace("a", "b")
+	s.Dock("b", s.Root, Top)
+	if got := windows(s); !reflect.DeepEqual(got, [][]string{{"b"}, {"a"}}) {
+		t.Fatalf("docking b above its own tabs made %v", got)
+	}
+}
+
+func TestRemoveCollapses(t *testing.T) {
+	s := NewSpace("a", "b", "c")
+	s.Dock("b", s.Root, Left)
+	s.Dock("c", s.Find("a"), Bottom)
+	s.Remove("b")
+	if got := windows(s); !reflect.DeepEqual(got, [][]string{{"a"}, {"c"}}) || !s.Root.Vertical {
+		t.Fatalf("removing b left %v, root %+v", got, s.Root)
+	}
+	// moving the last window of a leaf next to its sibling, whose node moves up the tree
+	s.Dock("a", s.Find("c"), Right)
+	if got := windows(s); !reflect.DeepEqual(got, [][]string{{"c"}, {"a"}}) || s.Root.Vertical {
+		t.Fatalf("moving a next to c made %v, root %+v", got, s.Root)
+	}
+}
+
+func TestFit(t *testing.T) {
+	s := NewSpace("old", "a")
+	s.Dock("old", s.Root, Right)
+	s.Fit([]string{"a", "new"})
+	if got := windows(s); !reflect.DeepEqual(got, [][]string{{"a", "new"}}) {
+		t.Fatalf("fitting made %v", got)
+	}
+}
+
+func TestLayoutAndZones(t *testing.T) {
+	s := NewSpace("a", "b")
+	s.Dock("b", s.Root, Right)
+	s.Root.Ratio = 0.5
+	areas, splitters := s.Layout(image.Rect(0, 0, 104, 50), 4)
+	if len(areas) != 2 || areas[0].Rect != image.Rect(0, 0, 50, 50) || areas[1].Rect != image.Rect(54, 0, 104, 50) {
+		t.Fatalf("laid out %+v", areas)
+	}
+	if len(splitters) != 1 || splitters[0].Rect != image.Rect(50, 0, 54, 50) {
+		t.Fatalf("splitters %+v", splitters)
+	}
+
+	r := image.Rect(0, 0, 100, 100)
+	for p, want := range map[image.Point]Side{{50, 50}: Center, {5, 50}: Left, {95, 40}: Right, {50, 3}: Top, {60, 90}: Bottom} {
+		if got := Zone(r, p); got != want {
+			t.Fatalf("%v is in zone %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestSavesToConfig(t *testing.T) {
+	defer func(path string) { config.Path = path }(config.Path)
+	config.Path = filepath.Join(t.TempDir(), "config.json")
+
+	s := NewSpace("a", "b")
+	s.Dock("b", s.Root, Bottom)
+	if err := s.Save("test"); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load("test", NewSpace(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(s)
+	got, _ := json.Marshal(loaded)
+	if string(got) != string(want) {
+		t.Fatalf("loaded %s, saved %s", got, want)
+	}
+
+	fresh, err := Load("other", NewSpace("x"), []string{"x"})
+	if err != nil || !reflect.DeepEqual(windows(fresh), [][]string{{"x"}}) {
+		t.Fatalf("a space never saved loaded as %v: %v", windows(fresh), err)
+	}
+}
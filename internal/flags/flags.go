@@ -0,0 +1,136 @@
+// Package flags is the registry of a demo's command-line flags, the framework's and its own, which the settings
+// panel lists and changes. Flags are declared with the standard flag package as always. The ones which can change
+// while the demo runs are registered as live along with what applies them; changes to the rest wait for the demo to
+// be restarted, and Args is the command line to restart it with.
+package flags
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Registry is the registry of the flags of a flag set.
+type Registry struct {
+	set  *flag.FlagSet
+	live map[string]func() error
+	// pending are the values of flags which wait for a restart
+	pending map[string]string
+}
+
+// New makes a registry of the flags of `set`, every flag it has or will have is in it.
+func New(set *flag.FlagSet) *Registry {
+	return &Registry{
+		set:     set,
+		live:    make(map[string]func() error),
+		pending: make(map[string]string),
+	}
+}
+
+// Default is the registry of the command line's flags.
+var Default = New(flag.CommandLine)
+
+// Flag is a flag as the settings panel shows it.
+type Flag struct {
+	Name  string
+	Usage string
+	// Value is what the flag is, or what it will be once the demo restarts when it's Pending.
+	Value   string
+	Default string
+	// Live flags change as the demo runs, the others are Pending once changed.
+	Live    bool
+	Pending bool
+}
+
+// Live registers the flag `name` as one which changes while the demo runs: `apply` is called once the flag has a new
+// value, and when it fails the flag is put back the way it was. Registering it again replaces `apply`.
+func (r *Registry) Live(name string, apply func() error) {
+	r.live[name] = apply
+}
+
+// All are the flags in the order of their names.
+func (r *Registry) All() []Flag {
+	var all []Flag
+	r.set.VisitAll(func(f *flag.Flag) {
+		value, pending := r.pending[f.Name]
+		if !pending {
+			value = f.Value.String()
+		}
+		_, live := r.live[f.Name]
+		all = append(all, Flag{Name: f.Name, Usage: f.Usage, Value: value, Default: f.DefValue, Live: live, Pending: pending})
+	})
+	return all
+}
+
+// Given reports whether the flag `name` was given on the command line, or set since.
+func (r *Registry) Given(name string) bool {
+	given := false
+	r.set.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+	return given
+}
+
+// Set changes the flag `name`. A live flag is applied at once, any other keeps its value until the demo restarts.
+// Values the flag doesn't take are refused either way.
+func (r *Registry) Set(name, value string) error {
+	f := r.set.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("no flag %s", name)
+	}
+	old := f.Value.String()
+
+	apply, live := r.live[name]
+	if !live {
+		// the value is only checked, what the demo read at the start stays in effect until it restarts
+		if err := f.Value.Set(value); err != nil {
+			return err
+		}
+		value = f.Value.String()
+		f.Value.Set(old)
+		if value == old {
+			delete(r.pending, name)
+		} else {
+			r.pending[name] = value
+		}
+		return nil
+	}
+	if err := r.set.Set(name, value); err != nil {
+		return err
+	}
+	if err := apply(); err != nil {
+		f.Value.Set(old)
+		return err
+	}
+	return nil
+}
+
+// Pending reports whether any flag waits for a restart.
+func (r *Registry) Pending() bool {
+	return len(r.pending) > 0
+}
+
+// Args is the command line to restart the demo with: every flag which isn't at its default, changes waiting for the
+// restart included, followed by the arguments after the flags.
+func (r *Registry) Args() []string {
+	var args []string
+	for _, f := range r.All() {
+		if f.Value != f.Default {
+			args = append(args, fmt.Sprintf("-%s=%s", f.Name, f.Value))
+		}
+	}
+	return append(args, r.set.Args()...)
+}
+
+// Apply applies every live flag given on the command line again, for demos which set the same thing themselves
+// while they start, the way most of them turn vsync off.
+func (r *Registry) Apply() error {
+	for name, apply := range r.live {
+		if !r.Given(name) {
+			continue
+		}
+		if err := apply(); err != nil {
+			return fmt.Errorf("-%s: %w", name, err)
+		}
+	}
+	return nil
+}
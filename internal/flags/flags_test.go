@@ -0,0 +1,87 @@
+package flags
+
+import (
+	"errors"
+	"flag"
+	"slices"
+	"testing"
+)
+
+func registry() (*Registry, *string, *int) {
+	set := flag.NewFlagSet("demo", flag.ContinueOnError)
+	model := set.String("model", "", "the model")
+	scale := set.Int("scale", 1, "the render scale")
+	return New(set), model, scale
+}
+
+func TestLiveFlagsApply(t *testing.T) {
+	r, _, scale := registry()
+	applied := 0
+	r.Live("scale", func() error {
+		if *scale > 4 {
+			return errors.New("too big")
+		}
+		applied = *scale
+		return nil
+	})
+
+	if err := r.Set("scale", "2"); err != nil || applied != 2 || *scale != 2 {
+		t.Fatalf("setting scale to 2 applied %d, it's %d: %v", applied, *scale, err)
+	}
+	if err := r.Set("scale", "8"); err == nil || *scale != 2 {
+		t.Fatalf("a scale which failed to apply left it at %d: %v", *scale, err)
+	}
+	if err := r.Set("scale", "big"); err == nil {
+		t.Fatal("scale took a word")
+	}
+	if r.Pending() {
+		t.Fatal("a live flag waits for a restart")
+	}
+}
+
+func TestOtherFlagsWaitForARestart(t *testing.T) {
+	r, model, _ := registry()
+	if err := r.Set("model", "teapot.obj"); err != nil {
+		t.Fatal(err)
+	}
+	if *model != "" || !r.Pending() {
+		t.Fatalf("the model changed to %q before the restart", *model)
+	}
+	all := r.All()
+	if i := slices.IndexFunc(all, func(f Flag) bool { return f.Name == "model" }); !all[i].Pending || all[i].Value != "teapot.obj" {
+		t.Fatalf("the model is listed as %+v", all[i])
+	}
+
+	// changing it back is no change
+	r.Set("model", "")
+	if r.Pending() {
+		t.Fatal("a flag changed back waits for a restart")
+	}
+}
+
+func TestArgs(t *testing.T) {
+	r, _, _ := registry()
+	r.set.Parse([]string{"-scale", "3", "scene.json"})
+	r.Set("model", "teapot.obj")
+	if got, want := r.Args(), []string{"-model=teapot.obj", "-scale=3", "scene.json"}; !slices.Equal(got, want) {
+		t.Fatalf("restarting with %v, want %v", got, want)
+	}
+	if !r.Given("scale") || r.Given("model") {
+		t.Fatal("given flags are the ones on the command line")
+	}
+}
+
+func TestApply(t *testing.T) {
+	r, _, scale := registry()
+	applied := []int{}
+	r.Live("scale", func() error { applied = append(applied, *scale); return nil })
+	r.Apply()
+	if len(applied) != 0 {
+		t.Fatal("a flag not given was applied")
+	}
+	r.set.Parse([]string{"-scale", "3"})
+	r.Apply()
+	if !slices.Equal(applied, []int{3}) {
+		t.Fatalf("applied %v", applied)
+	}
+}
@@ -0,0 +1,106 @@
+// Package flex divides a line of pixels between items that each have a minimum and a preferred size, the way UI
+// layouts share their room between widgets. Items get their preferred size when there's room for it, share what's
+// left over by their stretch factors, and shrink towards their minimum when there isn't.
+package flex
+
+// Item is what an item of a line asks for.
+type Item struct {
+	// Min is the least the item can do with, Preferred what it would like to have.
+	Min, Preferred int
+	// Stretch is the item's share of the room left over once every item has its preferred size. Items which
+	// don't stretch keep their preferred size, and when none do the room is left over at the end.
+	Stretch float64
+}
+
+// Solve returns the size of each of `items` along a line `room` pixels long with `gap` pixels between them. When
+// even their minimums don't fit the items get those, and the line overflows.
+func Solve(items []Item, room, gap int) []int {
+	sizes := make([]int, len(items))
+	if len(items) == 0 {
+		return sizes
+	}
+	room -= gap * (len(items) - 1)
+
+	var min_sum, preferred_sum int
+	var stretch_sum float64
+	for _, it := range items {
+		min_sum += it.Min
+		preferred_sum += max(it.Preferred, it.Min)
+		stretch_sum += max(it.Stretch, 0)
+	}
+
+	switch {
+	case room >= preferred_sum:
+		weights := make([]float64, len(items))
+		for i, it := range items {
+			sizes[i] = max(it.Preferred, it.Min)
+			weights[i] = max(it.Stretch, 0)
+		}
+		if stretch_sum > 0 {
+			add(sizes, distribute(room-preferred_sum, weights, stretch_sum))
+		}
+	case room > min_sum:
+		// everyone gives up the same part of what they'd like over their minimum
+		weights := make([]float64, len(items))
+		for i, it := range items {
+			sizes[i] = max(it.Preferred, it.Min)
+			weights[i] = float64(sizes[i] - it.Min)
+		}
+		shrink := distribute(preferred_sum-room, weights, float64(preferred_sum-min_sum))
+		for i := range sizes {
+			sizes[i] -= shrink[i]
+		}
+	default:
+		for i, it := range items {
+			sizes[i] = it.Min
+		}
+	}
+	return sizes
+}
+
+// Measure returns what a line of `items` with `gap` between them asks for, to nest lines in lines. The line
+// stretches as much as its most stretching item.
+func Measure(items []Item, gap int) Item {
+	var line Item
+	for i, it := range items {
+		if i > 0 {
+			line.Min += gap
+			line.Preferred += gap
+		}
+		line.Min += it.Min
+		line.Preferred += max(it.Preferred, it.Min)
+		line.Stretch = max(line.Stretch, it.Stretch)
+	}
+	return line
+}
+
+// Largest returns what an item as large as the largest of `items` asks for, like a column of cells.
+func Largest(items ...Item) Item {
+	var out Item
+	for _, it := range items {
+		out.Min = max(out.Min, it.Min)
+		out.Preferred = max(out.Preferred, it.Preferred, it.Min)
+		out.Stretch = max(out.Stretch, it.Stretch)
+	}
+	return out
+}
+
+// distribute splits `total` pixels by `weights` summing to `sum`, rounding so that the parts add up to `total`.
+func distribute(total int, weights []float64, sum float64) []int {
+	parts := make([]int, len(weights))
+	var acc float64
+	given := 0
+	for i, w := range weights {
+		acc += w
+		upto := int(float64(total)*acc/sum + 0.5)
+		parts[i] = upto - given
+		given = upto
+	}
+	return parts
+}
+
+func add(dst, src []int) {
+	for i := range dst {
+		dst[i] += src[i]
+	}
+}
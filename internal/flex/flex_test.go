@@ -0,0 +1,56 @@
+package flex
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSolve(t *testing.T) {
+	items := []Item{
+		{Min: 10, Preferred: 20},
+		{Min: 10, Preferred: 40, Stretch: 1},
+		{Min: 0, Preferred: 10, Stretch: 3},
+	}
+	for _, c := range []struct {
+		room int
+		want []int
+	}{
+		// room to spare goes to the stretching items, 1:3
+		{room: 114, want: []int{20, 50, 40}},
+		// just the preferred sizes
+		{room: 74, want: []int{20, 40, 10}},
+		// shrinking by 30 of the 50 over the minimums, everyone gives up 3/5
+		{room: 44, want: []int{14, 22, 4}},
+		// not even the minimums fit
+		{room: 10, want: []int{10, 10, 0}},
+	} {
+		got := Solve(items, c.room, 2)
+		if !slices.Equal(got, c.want) {
+			t.Fatalf("solving for %d gave %v, want %v", c.room, got, c.want)
+		}
+	}
+}
+
+func TestSolveAddsUp(t *testing.T) {
+	items := []Item{{Stretch: 1}, {Stretch: 1}, {Stretch: 1}}
+	for room := 0; room < 20; room++ {
+		sizes := Solve(items, room, 0)
+		sum := 0
+		for _, s := range sizes {
+			sum += s
+		}
+		if sum != room {
+			t.Fatalf("%v doesn't add up to %d", sizes, room)
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	line := Measure([]Item{{Min: 5, Preferred: 10}, {Min: 3, Preferred: 2, Stretch: 2}}, 4)
+	if line != (Item{Min: 12, Preferred: 17, Stretch: 2}) {
+		t.Fatalf("measured %+v", line)
+	}
+	if l := Largest(Item{Min: 5, Preferred: 10}, Item{Min: 8, Preferred: 9, Stretch: 1}); l != (Item{Min: 8, Preferred: 10, Stretch: 1}) {
+		t.Fatalf("largest is %+v", l)
+	}
+}
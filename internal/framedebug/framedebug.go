@@ -0,0 +1,252 @@
+// Package framedebug is a frame debugger for the software pipeline. It browses a pipeline.Capture stage by
+// stage and highlights the selected triangle in the viewport.
+package framedebug
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+type stage int
+
+const (
+	stage_submitted stage = iota
+	stage_clipped
+	stage_unsorted
+	stage_sorted
+	stage_count
+)
+
+var stage_names = [stage_count]string{"submit", "clip", "cull", "sort"}
+
+const (
+	panel_width  = 320
+	row_height   = 16
+	header_rows  = 3
+	details_rows = 6
+)
+
+type Debugger struct {
+	ctx *pipeline.Context
+	ui  *ui.Context
+
+	open     bool
+	stage    stage
+	selected int
+	list     ui.List
+}
+
+func New(ctx *pipeline.Context) *Debugger {
+	return &Debugger{
+		ctx: ctx,
+		ui:  ui.NewContext(),
+	}
+}
+
+// Capture requests the next frame to be captured and opens the browser once it's available.
+func (d *Debugger) Capture() {
+	d.ctx.CaptureNextFrame()
+	d.open = true
+	d.selected = 0
+	d.list = ui.List{}
+}
+
+func (d *Debugger) Open() bool {
+	return d.open && d.ctx.LastCapture() != nil
+}
+
+// rows returns the number of entries in the current stage.
+func (d *Debugger) rows(capture *pipeline.Capture) int {
+	switch d.stage {
+	case stage_submitted:
+		return len(capture.Submissions)
+	case stage_clipped:
+		return len(capture.Clipped)
+	case stage_unsorted:
+		return len(capture.Unsorted)
+	case stage_sorted:
+		return len(capture.Sorted)
+	}
+	return 0
+}
+
+// source returns the submission index the given row of the current stage came from.
+func (d *Debugger) source(capture *pipeline.Capture, row int) int {
+	switch d.stage {
+	case stage_clipped:
+		return capture.Clipped[row].Source
+	case stage_unsorted:
+		return capture.Unsorted[row].Source
+	case stage_sorted:
+		return capture.Sorted[row].Source
+	}
+	return row
+}
+
+func (d *Debugger) describe(capture *pipeline.Capture, row int) string {
+	switch d.stage {
+	case stage_submitted:
+		s := capture.Submissions[row]
+		clip := ""
+		if s.NeedsClip {
+			clip = " clip"
+		}
+		return fmt.Sprintf("%5d mesh %d tri %d%s -> %d", row, s.Mesh, s.Index, clip, s.Drawn)
+	case stage_clipped:
+		return fmt.Sprintf("%5d <- %d", row, capture.Clipped[row].Source)
+	case stage_unsorted:
+		t := capture.Unsorted[row]
+		return fmt.Sprintf("%5d <- %d z=%.5f", row, t.Source, t.Distance)
+	case stage_sorted:
+		t := capture.Sorted[row]
+		return fmt.Sprintf("%5d <- %d z=%.5f", row, t.Source, t.Distance)
+	}
+	return ""
+}
+
+func (d *Debugger) details(capture *pipeline.Capture, row int) string {
+	var v1, v2, v3 pipeline.Vertex
+	switch d.stage {
+	case stage_submitted:
+		s := capture.Submissions[row]
+		v1, v2, v3 = s.V1, s.V2, s.V3
+	case stage_clipped:
+		s := capture.Clipped[row]
+		v1, v2, v3 = s.V1, s.V2, s.V3
+	case stage_unsorted:
+		s := capture.Unsorted[row]
+		v1, v2, v3 = s.V1, s.V2, s.V3
+	case stage_sorted:
+		s := capture.Sorted[row]
+		v1, v2, v3 = s.V1, s.V2, s.V3
+	}
+	s := capture.Submissions[d.source(capture, row)]
+	return fmt.Sprintf("source: mesh %d (id %d) triangle %d material %d\nclipped into %d, drawn %d\n%s\n%s\n%s",
+		s.Mesh, s.MeshID, s.Index, s.Material, s.Clipped, s.Drawn,
+		format_vertex(v1), format_vertex(v2), format_vertex(v3),
+	)
+}
+
+func format_vertex(v pipeline.Vertex) string {
+	p := v.Position
+	return fmt.Sprintf("%7.2f %7.2f %6.3f %6.2f", p.X(), p.Y(), p.Z(), p.W())
+}
+
+func (d *Debugger) Draw(screen *ebiten.Image) {
+	capture := d.ctx.LastCapture()
+	if !d.open || capture == nil {
+		return
+	}
+
+	bounds := screen.Bounds()
+	panel_x := bounds.Max.X - panel_width
+	panel_height := bounds.Dy()
+
+	n := d.rows(capture)
+	visible_rows := panel_height/row_height - header_rows - details_rows
+	d.selected = min(max(d.selected, 0), max(n-1, 0))
+
+	if n > 0 {
+		d.highlight(screen, capture)
+	}
+
+	d.ui.StartFrame(screen)
+	d.ui.Push(panel_x, bounds.Min.Y, panel_width, panel_height, nil)
+	d.ui.Panel()
+
+	d.ui.Push(panel_x, bounds.Min.Y, panel_width, row_height, &ui.GridLayout{Columns: int(stage_count) + 1, Rows: 1})
+	for s := stage(0); s < stage_count; s++ {
+		d.ui.Button(ui.ButtonArgs{
+			Text:     stage_names[s],
+			AlignX:   0.5,
+			AlignY:   0.5,
+			Selected: s == d.stage,
+			Behavior: ui.ButtonBehavior{
+				OnActivate: func() {
+					if d.stage != s {
+						d.stage = s
+						d.selected = 0
+						d.list.Top = 0
+					}
+				},
+			},
+		})
+	}
+	d.ui.Button(ui.ButtonArgs{
+		Text:   "close",
+		AlignX: 0.5,
+		AlignY: 0.5,
+		Behavior: ui.ButtonBehavior{
+			OnActivate: func() {
+				d.open = false
+			},
+		},
+	})
+	d.ui.Pop()
+
+	d.ui.Push(panel_x, bounds.Min.Y+row_height, panel_width, (header_rows-1)*row_height, nil)
+	d.ui.Label(fmt.Sprintf("%d submitted, %d clipped\n%d unsorted, %d sorted",
+		len(capture.Submissions), len(capture.Clipped), len(capture.Unsorted), len(capture.Sorted)), 0, 0)
+	d.ui.Pop()
+
+	d.ui.Push(panel_x, bounds.Min.Y+header_rows*row_height, panel_width, visible_rows*row_height, nil)
+	d.ui.List(&d.list, n, row_height, func(row int) {
+		d.ui.Button(ui.ButtonArgs{
+			Text:     d.describe(capture, row),
+			AlignY:   0.5,
+			Selected: row == d.selected,
+			Behavior: ui.ButtonBehavior{
+				OnActivate: func() {
+					d.selected = row
+				},
+			},
+		})
+	})
+	d.ui.Pop()
+
+	if n > 0 {
+		d.ui.Push(panel_x, bounds.Max.Y-details_rows*row_height, panel_width, details_rows*row_height, nil)
+		d.ui.Label(d.details(capture, d.selected), 0, 0)
+		d.ui.Pop()
+	}
+
+	d.ui.Pop()
+	d.ui.EndFrame()
+}
+
+var (
+	highlight_source = color.RGBA{255, 255, 0, 255}
+	highlight_exact  = color.RGBA{255, 0, 0, 255}
+)
+
+// highlight outlines every drawn triangle which came from the same submission as the selection. If the
+// selection is itself a drawn triangle it is outlined in a different color on top.
+func (d *Debugger) highlight(screen *ebiten.Image, capture *pipeline.Capture) {
+	source := d.source(capture, d.selected)
+
+	for _, t := range capture.Sorted {
+		if t.Source == source {
+			stroke_triangle(screen, t, highlight_source)
+		}
+	}
+
+	switch d.stage {
+	case stage_unsorted:
+		stroke_triangle(screen, capture.Unsorted[d.selected], highlight_exact)
+	case stage_sorted:
+		stroke_triangle(screen, capture.Sorted[d.selected], highlight_exact)
+	}
+}
+
+func stroke_triangle(dst *ebiten.Image, t pipeline.Stage, clr color.Color) {
+	p1, p2, p3 := t.V1.Position, t.V2.Position, t.V3.Position
+	vector.StrokeLine(dst, p1.X(), p1.Y(), p2.X(), p2.Y(), 1, clr, true)
+	vector.StrokeLine(dst, p2.X(), p2.Y(), p3.X(), p3.Y(), 1, clr, true)
+	vector.StrokeLine(dst, p3.X(), p3.Y(), p1.X(), p1.Y(), 1, clr, true)
+}
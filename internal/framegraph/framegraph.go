@@ -0,0 +1,304 @@
+// Package framegraph runs the render passes of a frame in dependency order. Passes declare the images they read
+// and write up front; the graph then orders the passes so every image is written before it's read, skips passes
+// whose results nobody uses, and hands out transient images that are reused between passes and frames instead of
+// every pass keeping its own.
+//
+// A frame declares everything, then calls Execute:
+//
+//	lighting := g.Create("lighting", w, h)
+//	screen := g.Import("screen", dst)
+//	g.AddPass(framegraph.Pass{Name: "lights", Writes: []framegraph.Resource{lighting}, Run: ...})
+//	g.AddPass(framegraph.Pass{Name: "composite", Reads: []framegraph.Resource{lighting}, Writes: []framegraph.Resource{screen}, Run: ...})
+//	g.Execute()
+package framegraph
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pip"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/targets"
+)
+
+// Resource is a handle to an image of the frame being declared. It's only valid until Execute.
+type Resource int
+
+type resource struct {
+	name     string
+	size     image.Point
+	imported bool
+	image    *ebiten.Image
+
+	// first and last are the positions in the execution order of the first and last pass using the image
+	first, last int
+}
+
+// Pass is a unit of work that reads and writes images. Writing also counts as reading when the pass draws over
+// what's already there, so passes writing the same image run in the order they were added.
+type Pass struct {
+	Name   string
+	Reads  []Resource
+	Writes []Resource
+	// Keep runs the pass even when nothing reads what it writes, for passes with side effects.
+	Keep bool
+	// Run does the work, Image returns the images of the pass's resources while it runs.
+	Run func(g *Graph)
+}
+
+// Stats describe the last Execute.
+type Stats struct {
+	Passes int
+	Culled int
+	// Images is how many transient images were in use at once, at most.
+	Images int
+}
+
+type Graph struct {
+	// Pool is where the transient images are borrowed from.
+	Pool *targets.Pool
+
+	resources []resource
+	passes    []Pass
+
+	stats Stats
+	order []string
+}
+
+// New returns a graph borrowing its images from targets.Default.
+func New() *Graph {
+	return &Graph{Pool: targets.Default}
+}
+
+// Import adds an image that lives outside of the graph, like the screen. Passes writing imported images are the
+// results of the frame, they're never culled.
+func (g *Graph) Import(name string, img *ebiten.Image) Resource {
+	g.resources = append(g.resources, resource{
+		name:     name,
+		size:     img.Bounds().Size(),
+		imported: true,
+		image:    img,
+	})
+	return Resource(len(g.resources) - 1)
+}
+
+// Create adds a transient image of `width` by `height`. It's cleared before the first pass using it runs, and
+// its image goes back to the pool after the last one, for other resources to reuse.
+func (g *Graph) Create(name string, width, height int) Resource {
+	g.resources = append(g.resources, resource{
+		name: name,
+		size: image.Pt(width, height),
+	})
+	return Resource(len(g.resources) - 1)
+}
+
+func (g *Graph) AddPass(p Pass) {
+	g.passes = append(g.passes, p)
+}
+
+// Image returns the image of `r`, only valid in the Run of a pass that declared it.
+func (g *Graph) Image(r Resource) *ebiten.Image {
+	img := g.resources[r].image
+	if img == nil {
+		panic(fmt.Sprintf("framegraph: %s is not in use by the running pass", g.resources[r].name))
+	}
+	return img
+}
+
+// Stats returns the statistics of the last Execute.
+func (g *Graph) Stats() Stats {
+	return g.stats
+}
+
+// Order returns the names of the passes of the last Execute in the order they ran, culled ones in parentheses.
+func (g *Graph) Order() string {
+	return strings.Join(g.order, " > ")
+}
+
+// Execute runs the declared passes and clears the declarations for the next frame. It fails without running
+// anything when passes depend on each other in a cycle.
+func (g *Graph) Execute() error {
+	defer g.reset()
+
+	order, err := g.sort()
+	if err != nil {
+		return err
+	}
+	live := g.cull(order)
+
+	g.stats = Stats{}
+	g.order = g.order[:0]
+	var running []int
+	for _, p := range order {
+		if live[p] {
+			running = append(running, p)
+			g.order = append(g.order, g.passes[p].Name)
+		} else {
+			g.stats.Culled++
+			g.order = append(g.order, "("+g.passes[p].Name+")")
+		}
+	}
+	g.stats.Passes = len(running)
+
+	for i := range g.resources {
+		g.resources[i].first, g.resources[i].last = -1, -1
+	}
+	for i, p := range running {
+		for _, r := range g.uses(p) {
+			res := &g.resources[r]
+			if res.first < 0 {
+				res.first = i
+			}
+			res.last = i
+		}
+	}
+
+	in_use := 0
+	for i, p := range running {
+		for _, r := range g.uses(p) {
+			if res := &g.resources[r]; !res.imported && res.first == i && res.image == nil {
+				res.image = g.Pool.Get(res.size.X, res.size.Y)
+				in_use++
+			}
+		}
+		g.stats.Images = max(g.stats.Images, in_use)
+
+		g.passes[p].Run(g)
+
+		for _, r := range g.uses(p) {
+			if res := &g.resources[r]; !res.imported && res.last == i && res.image != nil {
+				pip.Show(res.name, res.image)
+				g.Pool.Put(res.image)
+				res.image = nil
+				in_use--
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Graph) uses(p int) []Resource {
+	pass := &g.passes[p]
+	return append(pass.Reads[:len(pass.Reads):len(pass.Reads)], pass.Writes...)
+}
+
+// sort orders the passes so that every pass runs after the ones writing what it uses, keeping the order they
+// were added in where it doesn't matter.
+func (g *Graph) sort() ([]int, error) {
+	// writers of every resource, in the order they were added
+	writers := make([][]int, len(g.resources))
+	for p := range g.passes {
+		for _, r := range g.passes[p].Writes {
+			writers[r] = append(writers[r], p)
+		}
+	}
+
+	after := make([][]int, len(g.passes))
+	for p := range g.passes {
+		for _, r := range g.uses(p) {
+			earlier := 0
+			for _, w := range writers[r] {
+				if w < p {
+					after[p] = append(after[p], w)
+					earlier++
+				}
+			}
+			if earlier == 0 && !writes(&g.passes[p], r) {
+				// nothing added before this pass writes it, so it reads what the later writers leave
+				after[p] = append(after[p], writers[r]...)
+				continue
+			}
+			for _, w := range writers[r] {
+				// later writers draw over what this pass used, so they wait for it
+				if w > p {
+					after[w] = append(after[w], p)
+				}
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.passes))
+	order := make([]int, 0, len(g.passes))
+
+	var visit func(p int) error
+	visit = func(p int) error {
+		switch state[p] {
+		case visiting:
+			return fmt.Errorf("framegraph: pass %s is part of a dependency cycle", g.passes[p].Name)
+		case visited:
+			return nil
+		}
+		state[p] = visiting
+		for _, q := range after[p] {
+			if err := visit(q); err != nil {
+				return err
+			}
+		}
+		state[p] = visited
+		order = append(order, p)
+		return nil
+	}
+	for p := range g.passes {
+		if err := visit(p); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func writes(p *Pass, r Resource) bool {
+	for _, w := range p.Writes {
+		if w == r {
+			return true
+		}
+	}
+	return false
+}
+
+// cull marks the passes that contribute to an imported image or are kept, walking back from them through what
+// they use. Passes only ever need the ones before them in `order`, so a single walk from the back finds all.
+func (g *Graph) cull(order []int) []bool {
+	live := make([]bool, len(g.passes))
+	needed := make([]bool, len(g.resources))
+
+	for i := len(order) - 1; i >= 0; i-- {
+		p := order[i]
+		pass := &g.passes[p]
+		if pass.Keep {
+			live[p] = true
+		}
+		for _, r := range pass.Writes {
+			if g.resources[r].imported || needed[r] {
+				live[p] = true
+			}
+		}
+		if live[p] {
+			for _, r := range pass.Reads {
+				needed[r] = true
+			}
+			for _, r := range pass.Writes {
+				needed[r] = true
+			}
+		}
+	}
+	return live
+}
+
+func (g *Graph) reset() {
+	for i := range g.resources {
+		if img := g.resources[i].image; img != nil && !g.resources[i].imported {
+			g.Pool.Put(img)
+		}
+	}
+	clear(g.resources)
+	g.resources = g.resources[:0]
+	clear(g.passes)
+	g.passes = g.passes[:0]
+}
@@ -0,0 +1,109 @@
+// Package gamma converts images and colors between sRGB, how they're stored and shown, and linear light, which is
+// what adding and multiplying lights assumes. Lighting sRGB values as they are darkens the falloff of lights and
+// washes out where they overlap. A gamma-correct frame decodes its textures and colors to linear, lights them, and
+// encodes the result back to sRGB as a post pass.
+//
+// ebiten has no sRGB textures and no images with more than 8 bits a channel, so textures are decoded by drawing
+// them, and linear images band in the darks, where sRGB spends most of its values.
+package gamma
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type float = float32
+
+// ToLinear decodes an sRGB channel from 0 to 1 to linear light.
+func ToLinear(v float) float {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return float(math.Pow((float64(v)+0.055)/1.055, 2.4))
+}
+
+// ToSRGB encodes a linear channel from 0 to 1 to sRGB.
+func ToSRGB(v float) float {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return float(1.055*math.Pow(float64(v), 1/2.4) - 0.055)
+}
+
+// Linear is `c` decoded to linear light, for filling and drawing with in a linear image.
+func Linear(c color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return color.RGBA64{}
+	}
+	// the channels are premultiplied, the curve applies to the color without its alpha
+	channel := func(v uint32) uint16 {
+		return uint16(ToLinear(float(v)/float(a))*float(a) + 0.5)
+	}
+	return color.RGBA64{R: channel(r), G: channel(g), B: channel(b), A: uint16(a)}
+}
+
+// shader draws image 0 decoded from sRGB to linear, or encoded back when Encode is 1.
+var shader = `
+//kage:unit pixels
+package main
+
+var Encode float
+
+func to_linear(c vec3) vec3 {
+	return mix(c/12.92, pow((c+0.055)/1.055, vec3(2.4)), step(0.04045, c))
+}
+
+func to_srgb(c vec3) vec3 {
+	return mix(c*12.92, 1.055*pow(c, vec3(1/2.4))-0.055, step(0.0031308, c))
+}
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	c := imageSrc0At(src)
+	if c.a == 0 {
+		return c
+	}
+	rgb := clamp(c.rgb/c.a, 0, 1)
+	if Encode > 0.5 {
+		rgb = to_srgb(rgb)
+	} else {
+		rgb = to_linear(rgb)
+	}
+	return vec4(rgb*c.a, c.a)
+}
+`
+
+// Converter decodes and encodes images.
+type Converter struct {
+	shader *ebiten.Shader
+}
+
+func New() (*Converter, error) {
+	s, err := ebiten.NewShader([]byte(shader))
+	if err != nil {
+		return nil, err
+	}
+	return &Converter{shader: s}, nil
+}
+
+// Decode draws the sRGB image `src` onto `dst` in linear light, at the top left of `dst`.
+func (c *Converter) Decode(dst, src *ebiten.Image) {
+	c.draw(dst, src, 0)
+}
+
+// Encode draws the linear image `src` onto `dst` in sRGB, the last pass of a gamma-correct frame.
+func (c *Converter) Encode(dst, src *ebiten.Image) {
+	c.draw(dst, src, 1)
+}
+
+func (c *Converter) draw(dst, src *ebiten.Image, encode float) {
+	size := src.Bounds().Size()
+	op := &ebiten.DrawRectShaderOptions{
+		Uniforms: map[string]any{"Encode": encode},
+		Images:   [4]*ebiten.Image{src},
+	}
+	op.GeoM.Translate(float64(dst.Bounds().Min.X), float64(dst.Bounds().Min.Y))
+	dst.DrawRectShader(size.X, size.Y, c.shader, op)
+}
@@ -0,0 +1,215 @@
+// Package grade changes the colors of finished images: color grading through 3D lookup tables, and palette swaps
+// for indexed art.
+package grade
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/watch"
+)
+
+var logger = logging.Tag("grade")
+
+// lut_shader looks every pixel of image 0 up in the strip in image 1. The strip holds the size×size×size cube as
+// `size` slices side by side, blue picks the slice and red and green the texel within it. Kage only samples the
+// nearest texel so the shader blends the 8 around the color itself, otherwise small tables would band.
+var lut_shader = `
+//kage:unit pixels
+package main
+
+var Strength float
+
+func slice_at(slice float, rg vec2, size float) vec3 {
+	p := rg * (size - 1)
+	p0 := floor(p)
+	p1 := min(p0+1, size-1)
+	f := p - p0
+
+	o := imageSrc1Origin() + vec2(slice*size, 0) + 0.5
+	c00 := imageSrc1At(o + vec2(p0.x, p0.y)).rgb
+	c10 := imageSrc1At(o + vec2(p1.x, p0.y)).rgb
+	c01 := imageSrc1At(o + vec2(p0.x, p1.y)).rgb
+	c11 := imageSrc1At(o + vec2(p1.x, p1.y)).rgb
+	return mix(mix(c00, c10, f.x), mix(c01, c11, f.x), f.y)
+}
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	c := imageSrc0At(src)
+	if c.a == 0 {
+		return c
+	}
+	rgb := c.rgb / c.a
+
+	size := imageSrc1Size().y
+	b := rgb.b * (size - 1)
+	b0 := floor(b)
+	b1 := min(b0+1, size-1)
+	graded := mix(slice_at(b0, rgb.rg, size), slice_at(b1, rgb.rg, size), b-b0)
+
+	return vec4(mix(rgb, graded, Strength)*c.a, c.a)
+}
+`
+
+var lut_compiled *ebiten.Shader
+
+func lut_program() (*ebiten.Shader, error) {
+	if lut_compiled == nil {
+		shader, err := ebiten.NewShader([]byte(lut_shader))
+		if err != nil {
+			return nil, err
+		}
+		lut_compiled = shader
+	}
+	return lut_compiled, nil
+}
+
+// LUT is a 3D color lookup table stored as a horizontal strip of slices, the layout most grading tools export: an
+// image size² wide and size tall. Paint over a screenshot with the identity strip next to it, save just the strip,
+// and the LUT applies the same adjustments to every frame.
+type LUT struct {
+	image  *ebiten.Image
+	shader *ebiten.Shader
+	size   int
+
+	path  string
+	watch *watch.File
+}
+
+// NewLUT wraps a strip image, it fails when the image isn't size² by size.
+func NewLUT(strip image.Image) (*LUT, error) {
+	shader, err := lut_program()
+	if err != nil {
+		return nil, err
+	}
+	size, err := strip_size(strip.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	return &LUT{
+		image:  ebiten.NewImageFromImage(strip),
+		shader: shader,
+		size:   size,
+	}, nil
+}
+
+func strip_size(bounds image.Rectangle) (int, error) {
+	w, h := bounds.Dx(), bounds.Dy()
+	if h < 2 || w != h*h {
+		return 0, fmt.Errorf("a lut strip must be size² by size pixels, got %dx%d", w, h)
+	}
+	return h, nil
+}
+
+// LoadLUT loads a strip from a PNG. The LUT reloads itself when the file changes, see Reload.
+func LoadLUT(path string) (*LUT, error) {
+	w := watch.New(path)
+	strip, err := read_png(path)
+	if err != nil {
+		return nil, err
+	}
+	l, err := NewLUT(strip)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	l.path = path
+	l.watch = w
+	return l, nil
+}
+
+func read_png(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// Size is the number of entries along each axis of the cube.
+func (l *LUT) Size() int {
+	return l.size
+}
+
+// Path is the file the LUT was loaded from, or empty.
+func (l *LUT) Path() string {
+	return l.path
+}
+
+// Reload loads the file again if it changed since it was last loaded and reports whether it did. Call it once a
+// tick. A file which fails to load is logged and the LUT keeps its last good table, so a half-saved file from an
+// image editor doesn't take the grade away.
+func (l *LUT) Reload() bool {
+	if l.watch == nil || !l.watch.Changed() || !l.watch.Exists() {
+		return false
+	}
+	strip, err := read_png(l.path)
+	if err == nil {
+		_, err = strip_size(strip.Bounds())
+	}
+	if err != nil {
+		logger.Warnf("keeping the last lut, reloading %s failed: %v", l.path, err)
+		return false
+	}
+
+	l.image.Deallocate()
+	l.image = ebiten.NewImageFromImage(strip)
+	l.size = strip.Bounds().Dy()
+	logger.Infof("reloaded %s", l.path)
+	return true
+}
+
+// Apply draws `src` graded into `dst` at the origin. `strength` blends between the original colors at 0 and the
+// table at 1.
+func (l *LUT) Apply(dst, src *ebiten.Image, strength float32) {
+	b := src.Bounds()
+	x0, y0 := float32(b.Min.X), float32(b.Min.Y)
+	x1, y1 := float32(b.Max.X), float32(b.Max.Y)
+	w, h := x1-x0, y1-y0
+
+	vertices := []ebiten.Vertex{
+		{DstX: 0, DstY: 0, SrcX: x0, SrcY: y0, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		{DstX: w, DstY: 0, SrcX: x1, SrcY: y0, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		{DstX: 0, DstY: h, SrcX: x0, SrcY: y1, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		{DstX: w, DstY: h, SrcX: x1, SrcY: y1, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+	}
+	dst.DrawTrianglesShader(vertices, []uint16{0, 1, 2, 1, 3, 2}, l.shader, &ebiten.DrawTrianglesShaderOptions{
+		Uniforms: map[string]any{
+			"Strength": strength,
+		},
+		Images: [4]*ebiten.Image{src, l.image},
+		Blend:  ebiten.BlendCopy,
+	})
+}
+
+// Identity returns the strip of a LUT which changes nothing, the starting point for painting a new one.
+func Identity(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size*size, size))
+	scale := func(i int) uint8 {
+		return uint8(i * 255 / (size - 1))
+	}
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				img.SetRGBA(b*size+r, g, color.RGBA{scale(r), scale(g), scale(b), 255})
+			}
+		}
+	}
+	return img
+}
+
+// WriteIdentity saves the identity strip of `size` to a PNG.
+func WriteIdentity(path string, size int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, Identity(size))
+}
@@ -0,0 +1,130 @@
+package grade
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// palette_shader draws indexed art: the red channel of image 0 is an index into the row of colors in image 1.
+// Alpha is kept, so indexed sprites can still be transparent.
+var palette_shader = `
+//kage:unit pixels
+package main
+
+// Offset rotates the indices, for palette cycling.
+var Offset float
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	c := imageSrc0At(src)
+	if c.a == 0 {
+		return vec4(0)
+	}
+
+	count := imageSrc1Size().x
+	index := mod(floor(c.r/c.a*255+0.5)+Offset, count)
+	entry := imageSrc1At(imageSrc1Origin() + vec2(index+0.5, 0.5))
+	return entry * c.a * color.a
+}
+`
+
+var palette_compiled *ebiten.Shader
+
+// Palette maps the indices of indexed images to colors, swapping palettes recolors the art without touching it.
+type Palette struct {
+	image  *ebiten.Image
+	shader *ebiten.Shader
+	colors int
+}
+
+// NewPalette returns a palette of up to 256 colors, index i of an indexed image is drawn as colors[i].
+func NewPalette(colors []color.Color) (*Palette, error) {
+	if palette_compiled == nil {
+		shader, err := ebiten.NewShader([]byte(palette_shader))
+		if err != nil {
+			return nil, err
+		}
+		palette_compiled = shader
+	}
+
+	colors = colors[:min(len(colors), 256)]
+	row := image.NewRGBA(image.Rect(0, 0, max(len(colors), 1), 1))
+	for i, c := range colors {
+		row.Set(i, 0, c)
+	}
+	return &Palette{
+		image:  ebiten.NewImageFromImage(row),
+		shader: palette_compiled,
+		colors: len(colors),
+	}, nil
+}
+
+// Len is the number of colors.
+func (p *Palette) Len() int {
+	return p.colors
+}
+
+// Set changes a single entry, for fades and flashes.
+func (p *Palette) Set(index int, c color.Color) {
+	r, g, b, a := c.RGBA()
+	p.image.SubImage(image.Rect(index, 0, index+1, 1)).(*ebiten.Image).WritePixels([]byte{
+		byte(r >> 8), byte(g >> 8), byte(b >> 8), byte(a >> 8),
+	})
+}
+
+// Indexed returns an image holding `indices` in its red channel, for Draw. Index 0 is transparent when
+// `transparent` is set, the usual convention of indexed art.
+func Indexed(width, height int, indices []uint8, transparent bool) *ebiten.Image {
+	pixels := make([]byte, width*height*4)
+	for i, index := range indices[:width*height] {
+		if transparent && index == 0 {
+			continue
+		}
+		pixels[i*4+0] = index
+		pixels[i*4+3] = 255
+	}
+	img := ebiten.NewImage(width, height)
+	img.WritePixels(pixels)
+	return img
+}
+
+// PaletteOptions are the options of Palette.Draw.
+type PaletteOptions struct {
+	GeoM ebiten.GeoM
+	// Offset is added to every index, wrapping around the palette. Increasing it every few ticks cycles colors
+	// like the water and fire of old games.
+	Offset int
+	// Alpha scales the opacity, 0 is treated as 1.
+	Alpha float32
+}
+
+// Draw draws the indexed image `indexed` onto `dst` with the palette's colors.
+func (p *Palette) Draw(dst, indexed *ebiten.Image, opts *PaletteOptions) {
+	if opts == nil {
+		opts = &PaletteOptions{}
+	}
+	alpha := opts.Alpha
+	if alpha == 0 {
+		alpha = 1
+	}
+
+	b := indexed.Bounds()
+	corners := [4]image.Point{b.Min, {b.Max.X, b.Min.Y}, {b.Min.X, b.Max.Y}, b.Max}
+	var vertices [4]ebiten.Vertex
+	for i, c := range corners {
+		x, y := opts.GeoM.Apply(float64(c.X-b.Min.X), float64(c.Y-b.Min.Y))
+		vertices[i] = ebiten.Vertex{
+			DstX: float32(x), DstY: float32(y),
+			SrcX: float32(c.X), SrcY: float32(c.Y),
+			ColorR: 1, ColorG: 1, ColorB: 1, ColorA: alpha,
+		}
+	}
+
+	dst.DrawTrianglesShader(vertices[:], []uint16{0, 1, 2, 1, 3, 2}, p.shader, &ebiten.DrawTrianglesShaderOptions{
+		Uniforms: map[string]any{
+			"Offset": float32(opts.Offset),
+		},
+		Images: [4]*ebiten.Image{indexed, p.image},
+	})
+}
@@ -0,0 +1,208 @@
+// Package grass scatters blades of grass over terrain, thousands of them, each two quads crossed at right angles
+// so it looks like a tuft from every side. The blades are grouped into square patches, and every frame only the
+// patches in view and in reach of the camera get a mesh, with fewer blades the further away they are. Where the
+// tips of the blades go is up to Field.Sway, which the wind of Wind moves them by. The meshes are ordinary meshes
+// for the pipeline, drawn with the material of render.NewGrassMaterial.
+package grass
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/noise"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pack"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+// max_blades is how many blades a patch has at most, the indices of the points of its mesh are 16 bit.
+const max_blades = math.MaxUint16 / 8
+
+// Blade is a blade of grass.
+type Blade struct {
+	Root   vec3
+	Height float
+	Width  float
+	// Angle turns the blade around the vertical, in radians.
+	Angle float
+	// Phase sets the blade apart from its neighbours, from 0 to 1, for the wind and the shade of its green.
+	Phase float
+}
+
+// Patch is the blades of a square of the field.
+type Patch struct {
+	// Blades are in no order, so any number of the first of them are spread over the whole patch.
+	Blades []Blade
+	// Sphere bounds the blades, swaying as far as they're tall.
+	Sphere mesh.Sphere
+
+	mesh mesh.Mesh
+}
+
+// Stats are what the last Meshes drew.
+type Stats struct {
+	Patches int
+	// Culled is the patches out of view or out of reach, they got no mesh.
+	Culled int
+	Blades int
+}
+
+// Field is patches of grass.
+type Field struct {
+	Patches []*Patch
+	// Near is how far from the camera every blade of a patch is drawn, and Far how far any are. In between fewer
+	// and fewer are, by the distance to the nearest edge of the patch.
+	Near float
+	Far  float
+	// Density is how many of the blades of the patches are drawn at most, from 0 to 1, for thinning out the whole
+	// field when frames are slow. Scatter sets it to 1.
+	Density float
+	// Sway is how far the tip of `b` is moved at `time`, nil keeps the blades still. See Wind.
+	Sway func(b *Blade, time float) vec3
+
+	Stats  Stats
+	meshes []*mesh.Mesh
+}
+
+// Scatter makes a square field `size` across centered on the origin, cut into `patches` patches along each side,
+// of `count` blades each at most, with their roots at `height` of x and z. Noise makes the grass taller and
+// thicker in places. `seed` tells fields apart.
+func Scatter(size float, patches, count int, height func(x, z float) float, seed float) *Field {
+	patches = max(patches, 1)
+	count = min(max(count, 1), max_blades)
+	f := &Field{Near: size / 4, Far: size / 2, Density: 1}
+	side := size / float(patches)
+	for row := range patches {
+		for column := range patches {
+			p := &Patch{}
+			x0, z0 := float(column)*side-size/2, float(row)*side-size/2
+			k := float(row*patches+column) + seed*7919
+			tallest := float(0)
+			for i := range count {
+				j := float(i)
+				x := x0 + noise.Hash(k, j*3)*side
+				z := z0 + noise.Hash(k, j*3+1)*side
+				lush := noise.FBM(noise.Value, x*0.3+seed, z*0.3)
+				// sparse patches keep a few short blades instead of going bare
+				b := Blade{
+					Root:   vec3{x, height(x, z), z},
+					Height: 0.15 + lush*0.35 + noise.Hash(k, j*3+2)*0.1,
+					Width:  0.12 + lush*0.08,
+					Angle:  noise.Hash(j*3+2, k) * math.Pi,
+					Phase:  noise.Hash(j, k*3),
+				}
+				tallest = max(tallest, b.Height)
+				p.Blades = append(p.Blades, b)
+			}
+			p.Sphere = bounds(p.Blades, tallest)
+			f.Patches = append(f.Patches, p)
+		}
+	}
+	return f
+}
+
+// bounds is the sphere around the blades, as they are and leaning over by as far as `tallest` in any direction.
+func bounds(blades []Blade, tallest float) mesh.Sphere {
+	lo, hi := blades[0].Root, blades[0].Root
+	for _, b := range blades {
+		for i := range 3 {
+			lo[i], hi[i] = min(lo[i], b.Root[i]), max(hi[i], b.Root[i])
+		}
+	}
+	lo = lo.Sub(vec3{tallest, 0, tallest})
+	hi = hi.Add(vec3{tallest, tallest, tallest})
+	return mesh.Sphere{Center: lo.Add(hi).Mul(0.5), Radius: hi.Sub(lo).Len() / 2}
+}
+
+// Meshes are the meshes of the patches of grass seen from `eye` at `time`, as for Sway, less those `visible` says
+// are out of view, which can be nil to keep every patch in reach. Blades about to drop out as a patch gets further
+// away shrink into the ground instead of popping out of it. The meshes are the field's own, made again in place by
+// every call.
+func (f *Field) Meshes(eye vec3, visible func(s mesh.Sphere) bool, time float) []*mesh.Mesh {
+	f.meshes = f.meshes[:0]
+	f.Stats = Stats{Patches: len(f.Patches)}
+	for _, p := range f.Patches {
+		d := max(p.Sphere.Center.Sub(eye).Len()-p.Sphere.Radius, 0)
+		if d >= f.Far || (visible != nil && !visible(p.Sphere)) {
+			f.Stats.Culled++
+			continue
+		}
+		density := f.Density
+		if d > f.Near {
+			density *= 1 - (d-f.Near)/(f.Far-f.Near)
+		}
+		n := int(math.Ceil(float64(density * float(len(p.Blades)))))
+		if n == 0 {
+			f.Stats.Culled++
+			continue
+		}
+		// the last tenth of the blades drawn shrink as they go
+		fade := max(float(len(p.Blades))/10, 1)
+		p.build(n, func(i int) float {
+			return min((density*float(len(p.Blades))-float(i))/fade, 1)
+		}, f.Sway, time)
+		f.meshes = append(f.meshes, &p.mesh)
+		f.Stats.Blades += n
+	}
+	return f.meshes
+}
+
+// build makes the mesh of the first `n` blades of the patch, scaled by `scale` of their index.
+//
+// Every blade has four texture coordinates of its own, shared by its two quads: v runs from 0 at the tip to 1 at
+// the root and u across it, tagged by pack.Tag with the shade of the blade from 0 to 7, its phase in 8ths. See
+// render.NewGrassMaterial.
+func (p *Patch) build(n int, scale func(i int) float, sway func(b *Blade, time float) vec3, time float) {
+	m := &p.mesh
+	m.Points = m.Points[:0]
+	m.Texcoords = m.Texcoords[:0]
+	m.Triangles = m.Triangles[:0]
+	for i := range p.Blades[:n] {
+		b := &p.Blades[i]
+		s := scale(i)
+		sin, cos := math.Sincos(float64(b.Angle))
+		across := [2]vec3{
+			{float(cos) * b.Width / 2 * s, 0, float(sin) * b.Width / 2 * s},
+			{-float(sin) * b.Width / 2 * s, 0, float(cos) * b.Width / 2 * s},
+		}
+		tip := b.Root.Add(vec3{0, b.Height * s, 0})
+		if sway != nil {
+			tip = tip.Add(sway(b, time).Mul(s))
+		}
+		shade := int(b.Phase * 8)
+		t := uint16(len(m.Texcoords))
+		m.Texcoords = append(m.Texcoords,
+			pack.Tag(vec2{0, 0}, shade, 0), pack.Tag(vec2{1, 0}, shade, 0),
+			pack.Tag(vec2{1, 1}, shade, 0), pack.Tag(vec2{0, 1}, shade, 0),
+		)
+		for _, a := range across {
+			q := uint16(len(m.Points))
+			m.Points = append(m.Points, tip.Sub(a), tip.Add(a), b.Root.Add(a), b.Root.Sub(a))
+			// drawn from both sides, so which way they wind doesn't matter
+			m.Triangles = append(m.Triangles,
+				mesh.Triangle{P1: q, P2: q + 3, P3: q + 2, T1: t, T2: t + 3, T3: t + 2},
+				mesh.Triangle{P1: q, P2: q + 2, P3: q + 1, T1: t, T2: t + 2, T3: t + 1},
+			)
+		}
+	}
+	m.ComputeBounds()
+}
+
+// Wind sways blades along `direction`, of length 1, by as much as `strength` times their height: a gentle wave
+// rolling across the field `speed` units a second, and slower gusts where noise says.
+func Wind(direction vec2, strength, speed float) func(b *Blade, time float) vec3 {
+	return func(b *Blade, time float) vec3 {
+		along := b.Root.X()*direction.X() + b.Root.Z()*direction.Y()
+		wave := float(math.Sin(float64((along-time*speed)*2 + b.Phase*math.Pi)))
+		gust := noise.Value(b.Root.X()*0.15-time*speed*0.2*direction.X(), b.Root.Z()*0.15-time*speed*0.2*direction.Y())
+		lean := strength * b.Height * (0.3 + 0.3*wave + gust)
+		// leaning over, the tip comes down as well as along, so the blade keeps about its length
+		return vec3{direction.X() * lean, -lean * lean / (2 * b.Height), direction.Y() * lean}
+	}
+}
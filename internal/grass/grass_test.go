@@ -0,0 +1,103 @@
+package grass
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+func flat(x, z float) float {
+	return 1
+}
+
+func TestScatterKeepsBladesInTheirPatches(t *testing.T) {
+	f := Scatter(8, 4, 100, flat, 1)
+	if len(f.Patches) != 16 {
+		t.Fatalf("%d patches, want 16", len(f.Patches))
+	}
+	for _, p := range f.Patches {
+		if len(p.Blades) != 100 {
+			t.Fatalf("%d blades in a patch, want 100", len(p.Blades))
+		}
+		for _, b := range p.Blades {
+			if b.Root.Y() != 1 || mgl32.Abs(b.Root.X()) > 4 || mgl32.Abs(b.Root.Z()) > 4 {
+				t.Fatalf("a blade is rooted at %v, off the field", b.Root)
+			}
+			tip := b.Root.Add(vec3{0, b.Height, 0})
+			if p.Sphere.Center.Sub(b.Root).Len() > p.Sphere.Radius || p.Sphere.Center.Sub(tip).Len() > p.Sphere.Radius {
+				t.Fatalf("a blade from %v to %v is out of the bounds of its patch", b.Root, tip)
+			}
+		}
+	}
+}
+
+func TestMeshesThinOutWithDistance(t *testing.T) {
+	f := Scatter(40, 10, 200, flat, 2)
+	f.Near, f.Far = 5, 15
+
+	f.Meshes(vec3{0, 2, 0}, nil, 0)
+	// the four patches around the camera are near enough for every blade
+	full := 0
+	for _, p := range f.Patches {
+		if len(p.mesh.Triangles) == 4*200 {
+			full++
+		}
+	}
+	if full < 4 {
+		t.Fatalf("%d patches have all their blades, want the 4 under the camera at least", full)
+	}
+	blades := f.Stats.Blades
+	if f.Stats.Culled == 0 || blades >= 100*200 {
+		t.Fatalf("%d blades in %d patches with %d culled, the far ones should be thinned out or gone", blades,
+			f.Stats.Patches, f.Stats.Culled)
+	}
+
+	// half the density, about half the blades
+	f.Density = 0.5
+	f.Meshes(vec3{0, 2, 0}, nil, 0)
+	if half := f.Stats.Blades; half > blades/2+100 || half < blades/2-100 {
+		t.Fatalf("%d blades at half the density, of %d", half, blades)
+	}
+
+	// nothing in view, nothing drawn
+	meshes := f.Meshes(vec3{0, 2, 0}, func(s mesh.Sphere) bool { return false }, 0)
+	if len(meshes) != 0 || f.Stats.Culled != 100 || f.Stats.Blades != 0 {
+		t.Fatalf("%d meshes with every patch out of view", len(meshes))
+	}
+}
+
+func TestSwayMovesTheTips(t *testing.T) {
+	f := Scatter(2, 1, 1, flat, 3)
+	b := f.Patches[0].Blades[0]
+	f.Sway = func(*Blade, float) vec3 { return vec3{0.5, 0, 0} }
+
+	m := f.Meshes(b.Root, nil, 0)[0]
+	// the tips are the first two points of each quad, the roots the last two
+	for quad := range 2 {
+		tips := m.Points[4*quad].Add(m.Points[4*quad+1]).Mul(0.5)
+		roots := m.Points[4*quad+2].Add(m.Points[4*quad+3]).Mul(0.5)
+		if !roots.ApproxEqualThreshold(b.Root, 1e-5) {
+			t.Fatalf("the root moved to %v from %v", roots, b.Root)
+		}
+		if want := b.Root.Add(vec3{0.5, b.Height, 0}); !tips.ApproxEqualThreshold(want, 1e-5) {
+			t.Fatalf("the tip is at %v, want %v", tips, want)
+		}
+	}
+}
+
+func TestWindKeepsTheBladesLength(t *testing.T) {
+	wind := Wind(vec2{1, 0}, 0.5, 1)
+	b := &Blade{Root: vec3{3, 0, 1}, Height: 0.4}
+	for time := float(0); time < 10; time += 0.5 {
+		offset := wind(b, time)
+		tip := vec3{0, b.Height, 0}.Add(offset)
+		if offset.Z() != 0 {
+			t.Fatalf("the wind along x moved the tip %v", offset)
+		}
+		if tip.Y() > b.Height || tip.Len() > b.Height*1.2 {
+			t.Fatalf("at %v the tip is at %v, for a blade %v tall", time, tip, b.Height)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package highlight outlines selected meshes. It has two ways of doing it, each with its own look and cost:
+//
+//   - EdgeDetect draws the mesh's triangles into a mask image and then, for every pixel outside of the mask,
+//     searches the neighbourhood for mask pixels. The outline is the same number of pixels wide at any distance.
+//   - InvertedHull pushes a copy of the mesh, inflated along its normals and turned inside out, through a pipeline
+//     of its own and cuts the mesh's own silhouette out of it. The outline is thick in world units, so it thins
+//     out with distance, and it costs triangles instead of fill rate.
+//
+// Both draw the outline over everything else, like most editors do, so selections stay visible behind other
+// meshes.
+package highlight
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/invalidate"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pip"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/targets"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+type Mode int
+
+const (
+	EdgeDetect Mode = iota
+	InvertedHull
+	ModeCount
+)
+
+func (m Mode) String() string {
+	switch m {
+	case EdgeDetect:
+		return "edge detect"
+	case InvertedHull:
+		return "inverted hull"
+	}
+	return fmt.Sprintf("mode(%d)", int(m))
+}
+
+// MaxWidth is the widest outline EdgeDetect can draw, in pixels. It bounds the search of the shader.
+const MaxWidth = 4
+
+// edge_shader draws Color where a mask pixel is within Width of a pixel outside of the mask.
+var edge_shader = `
+//kage:unit pixels
+package main
+
+const MaxWidth = 4
+
+var Width float
+var Color vec4
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	if imageSrc0At(src).a > 0 {
+		return vec4(0)
+	}
+	for y := -MaxWidth; y <= MaxWidth; y++ {
+		for x := -MaxWidth; x <= MaxWidth; x++ {
+			offset := vec2(float(x), float(y))
+			if dot(offset, offset) <= Width*Width && imageSrc0At(src+offset).a > 0 {
+				return Color
+			}
+		}
+	}
+	return vec4(0)
+}
+`
+
+type Highlighter struct {
+	shader *ebiten.Shader
+	white  *ebiten.Image
+
+	// hull is the pipeline of InvertedHull, it copies the view of the scene's pipeline
+	hull pipeline.Context
+	// hulls caches the inflated copy of every mesh that was outlined
+	hulls map[hull_key]*mesh.Mesh
+
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+type hull_key struct {
+	mesh      uint32
+	thickness float
+}
+
+func New() (*Highlighter, error) {
+	shader, err := ebiten.NewShader([]byte(edge_shader))
+	if err != nil {
+		return nil, err
+	}
+	white := ebiten.NewImage(3, 3)
+	white.Fill(color.White)
+	h := &Highlighter{
+		shader: shader,
+		white:  white.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image),
+		hulls:  make(map[hull_key]*mesh.Mesh),
+	}
+	invalidate.Register("outline_hulls", h.Forget)
+	return h, nil
+}
+
+// Options describe an outline.
+type Options struct {
+	Mode  Mode
+	Color color.Color
+	// Width is in pixels for EdgeDetect, up to MaxWidth, and in world units for InvertedHull.
+	Width float
+}
+
+// Draw outlines `m` on `target`, the image the scene was drawn to. `scene` is the pipeline the scene was drawn
+// with, it must not have been Reset yet: EdgeDetect uses the mesh's triangles from it and InvertedHull its view
+// and projection.
+func (h *Highlighter) Draw(target *ebiten.Image, scene *pipeline.Context, m *mesh.Mesh, opts Options) {
+	// sub-images share the coordinates of their parent, a mask reaching to the far corner of the target lines
+	// up with the pipeline's pixels without any offsets
+	size := target.Bounds().Max
+	mask := targets.Default.Get(size.X, size.Y)
+	defer targets.Default.Put(mask)
+
+	switch opts.Mode {
+	case EdgeDetect:
+		h.fill(mask, scene.Triangles(), m.ID(), color.White, ebiten.Blend{})
+		pip.Show("highlight mask", mask)
+
+		r, g, b, a := opts.Color.RGBA()
+		target.DrawRectShader(size.X, size.Y, h.shader, &ebiten.DrawRectShaderOptions{
+			Uniforms: map[string]any{
+				"Width": min(opts.Width, MaxWidth),
+				"Color": []float32{float(r) / 0xffff, float(g) / 0xffff, float(b) / 0xffff, float(a) / 0xffff},
+			},
+			Images: [4]*ebiten.Image{mask},
+		})
+
+	case InvertedHull:
+		hull := h.hull_of(m, opts.Width)
+
+		h.hull.SetViewport(scene.Viewport())
+		h.hull.SetProjection(scene.ProjectionMatrix())
+		h.hull.SetView(scene.ViewMatrix())
+		h.hull.FlipY = scene.FlipY
+		h.hull.PushMesh(hull)
+
+		// the hull's back faces, then the mesh's front faces cut out of them, leave a ring around the silhouette
+		h.fill(mask, h.hull.Triangles(), hull.ID(), opts.Color, ebiten.Blend{})
+		h.fill(mask, scene.Triangles(), m.ID(), color.White, ebiten.BlendClear)
+		pip.Show("highlight mask", mask)
+		h.hull.Reset()
+
+		target.DrawImage(mask, nil)
+	}
+}
+
+// fill draws the triangles of mesh `id` in a flat color.
+func (h *Highlighter) fill(dst *ebiten.Image, triangles []pipeline.Triangle, id uint32, clr color.Color, blend ebiten.Blend) {
+	r, g, b, a := clr.RGBA()
+	h.vertices = h.vertices[:0]
+	h.indices = h.indices[:0]
+
+	flush := func() {
+		dst.DrawTriangles(h.vertices, h.indices, h.white, &ebiten.DrawTrianglesOptions{Blend: blend})
+		h.vertices = h.vertices[:0]
+		h.indices = h.indices[:0]
+	}
+
+	for _, t := range triangles {
+		if t.Mesh != id {
+			continue
+		}
+		if len(h.vertices)+3 > ebiten.MaxVertexCount {
+			flush()
+		}
+		base := uint16(len(h.vertices))
+		for _, v := range [3]pipeline.Vertex{t.V1, t.V2, t.V3} {
+			h.vertices = append(h.vertices, ebiten.Vertex{
+				DstX:   v.Position.X(),
+				DstY:   v.Position.Y(),
+				SrcX:   1,
+				SrcY:   1,
+				ColorR: float(r) / 0xffff,
+				ColorG: float(g) / 0xffff,
+				ColorB: float(b) / 0xffff,
+				ColorA: float(a) / 0xffff,
+			})
+		}
+		h.indices = append(h.indices, base, base+1, base+2)
+	}
+	if len(h.indices) > 0 {
+		flush()
+	}
+}
+
+// hull_of returns `m` inflated by `thickness` along its normals and turned inside out, so only the faces pointing
+// away from the camera survive culling and the mesh hides the rest.
+func (h *Highlighter) hull_of(m *mesh.Mesh, thickness float) *mesh.Mesh {
+	key := hull_key{m.ID(), thickness}
+	if hull, ok := h.hulls[key]; ok {
+		return hull
+	}
+
+	// the normal of each point is the average of the faces around it, weighted by their area, so hard edges
+	// split open less than with either face's normal alone
+	normals := make([]vec3, len(m.Points))
+	for _, t := range m.Triangles {
+		p1, p2, p3 := m.Points[t.P1], m.Points[t.P2], m.Points[t.P3]
+		n := p2.Sub(p1).Cross(p3.Sub(p1))
+		normals[t.P1] = normals[t.P1].Add(n)
+		normals[t.P2] = normals[t.P2].Add(n)
+		normals[t.P3] = normals[t.P3].Add(n)
+	}
+
+	hull := &mesh.Mesh{
+		Points:    make([]vec3, len(m.Points)),
+		Texcoords: m.Texcoords,
+		Triangles: make([]mesh.Triangle, len(m.Triangles)),
+	}
+	for i, p := range m.Points {
+		n := normals[i]
+		if n.Len() > 0 {
+			n = n.Normalize()
+		}
+		hull.Points[i] = p.Add(n.Mul(thickness))
+	}
+	for i, t := range m.Triangles {
+		t.P2, t.P3 = t.P3, t.P2
+		t.T2, t.T3 = t.T3, t.T2
+		hull.Triangles[i] = t
+	}
+	hull.ComputeBounds()
+
+	h.hulls[key] = hull
+	return hull
+}
+
+// Forget drops the cached hulls, call it when meshes are modified or thrown away.
+func (h *Highlighter) Forget() {
+	clear(h.hulls)
+}
@@ -0,0 +1,159 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type device int
+
+const (
+	device_key device = iota
+	device_mouse
+	device_gamepad_button
+	device_gamepad_axis
+)
+
+// Binding is a single physical input that can trigger an action. Bindings are stored in the config file
+// using their text form, for example "key:W", "mouse:left", "button:0" or "axis:1-".
+type Binding struct {
+	device device
+	code   int
+	// sign selects which half of a gamepad axis is considered, it is either -1 or 1.
+	sign int
+}
+
+// Key binds a keyboard key.
+func Key(key ebiten.Key) Binding {
+	return Binding{device: device_key, code: int(key)}
+}
+
+// Mouse binds a mouse button.
+func Mouse(button ebiten.MouseButton) Binding {
+	return Binding{device: device_mouse, code: int(button)}
+}
+
+// GamepadButton binds a button on any gamepad with a standard layout.
+func GamepadButton(button ebiten.StandardGamepadButton) Binding {
+	return Binding{device: device_gamepad_button, code: int(button)}
+}
+
+// GamepadAxis binds one direction of an axis on any gamepad with a standard layout.
+// `sign` should be -1 for the negative half or 1 for the positive half.
+func GamepadAxis(axis ebiten.StandardGamepadAxis, sign int) Binding {
+	if sign < 0 {
+		sign = -1
+	} else {
+		sign = 1
+	}
+	return Binding{device: device_gamepad_axis, code: int(axis), sign: sign}
+}
+
+var mouse_names = [...]string{"left", "right", "middle"}
+
+func (b Binding) String() string {
+	switch b.device {
+	case device_key:
+		return "key:" + ebiten.Key(b.code).String()
+	case device_mouse:
+		if b.code >= 0 && b.code < len(mouse_names) {
+			return "mouse:" + mouse_names[b.code]
+		}
+		return "mouse:" + strconv.Itoa(b.code)
+	case device_gamepad_button:
+		return "button:" + strconv.Itoa(b.code)
+	case device_gamepad_axis:
+		if b.sign < 0 {
+			return "axis:" + strconv.Itoa(b.code) + "-"
+		}
+		return "axis:" + strconv.Itoa(b.code) + "+"
+	}
+	return "unknown"
+}
+
+// ParseBinding parses the text form of a binding, see Binding.
+func ParseBinding(s string) (Binding, error) {
+	kind, name, ok := strings.Cut(s, ":")
+	if !ok {
+		return Binding{}, fmt.Errorf("bad binding %q: missing device", s)
+	}
+	switch kind {
+	case "key":
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(name)); err != nil {
+			return Binding{}, fmt.Errorf("bad binding %q: %w", s, err)
+		}
+		return Key(key), nil
+	case "mouse":
+		for i, n := range mouse_names {
+			if n == name {
+				return Mouse(ebiten.MouseButton(i)), nil
+			}
+		}
+		code, err := strconv.Atoi(name)
+		if err != nil || code < 0 || code > int(ebiten.MouseButtonMax) {
+			return Binding{}, fmt.Errorf("bad binding %q: unknown mouse button", s)
+		}
+		return Mouse(ebiten.MouseButton(code)), nil
+	case "button":
+		code, err := strconv.Atoi(name)
+		if err != nil || code < 0 || code > int(ebiten.StandardGamepadButtonMax) {
+			return Binding{}, fmt.Errorf("bad binding %q: unknown gamepad button", s)
+		}
+		return GamepadButton(ebiten.StandardGamepadButton(code)), nil
+	case "axis":
+		sign := 1
+		if strings.HasSuffix(name, "-") {
+			sign = -1
+		}
+		code, err := strconv.Atoi(strings.TrimRight(name, "+-"))
+		if err != nil || code < 0 || code > int(ebiten.StandardGamepadAxisMax) {
+			return Binding{}, fmt.Errorf("bad binding %q: unknown gamepad axis", s)
+		}
+		return GamepadAxis(ebiten.StandardGamepadAxis(code), sign), nil
+	}
+	return Binding{}, fmt.Errorf("bad binding %q: unknown device %q", s, kind)
+}
+
+func (b Binding) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *Binding) UnmarshalText(text []byte) (err error) {
+	*b, err = ParseBinding(string(text))
+	return
+}
+
+// axis_deadzone is how far an axis must be pushed before a digital query considers it pressed.
+const axis_deadzone = 0.5
+
+// value returns how strongly the binding is held, in the range [0, 1].
+func (b Binding) value(gamepads []ebiten.GamepadID) float {
+	switch b.device {
+	case device_key:
+		if KeyPressed(ebiten.Key(b.code)) {
+			return 1
+		}
+	case device_mouse:
+		if MousePressed(ebiten.MouseButton(b.code)) {
+			return 1
+		}
+	case device_gamepad_button:
+		for _, id := range gamepads {
+			if ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButton(b.code)) {
+				return 1
+			}
+		}
+	case device_gamepad_axis:
+		var strongest float
+		for _, id := range gamepads {
+			v := float(ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxis(b.code))) * float(b.sign)
+			strongest = max(strongest, v)
+		}
+		return min(strongest, 1)
+	}
+	return 0
+}
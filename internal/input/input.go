@@ -0,0 +1,294 @@
+// Package input maps named actions such as "move_forward" or "toggle_wireframe" onto physical inputs.
+//
+// Demos describe what they want to react to by binding default inputs to action names, then only ever ask
+// about actions. Bindings can be replaced at runtime and are persisted in the config file, so a user can
+// rebind anything without touching the demo code.
+package input
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/config"
+)
+
+type float = float32
+
+// config_section is the name of the section bindings are persisted to.
+const config_section = "input"
+
+type action struct {
+	bindings []Binding
+	defaults []Binding
+	// rebound is set once the user replaces the defaults, after which Bind no longer touches the bindings.
+	rebound bool
+	// while_typing actions fire from their keys while text is typed, see WhileTyping
+	while_typing bool
+	// value is how strongly the action is held by its mouse and gamepad bindings, key_value by its keys, which
+	// are left out while text is typed
+	value, key_value       float
+	down, key_down         bool
+	was_down, was_key_down bool
+}
+
+// gamepad_axis is an axis of a gamepad, see axes.
+type gamepad_axis struct {
+	id   ebiten.GamepadID
+	axis ebiten.StandardGamepadAxis
+}
+
+var (
+	actions  = make(map[string]*action)
+	gamepads []ebiten.GamepadID
+	// axes are the directions the axes of the gamepads are pushed past the dead zone this tick and last_axes last
+	// tick, so Capture only reports an axis on the tick it's pushed, like a button
+	axes, last_axes = make(map[gamepad_axis]int), make(map[gamepad_axis]int)
+
+	// typing is set while text is typed, through the tick after the last Type, and typed since the last Update
+	typing, typed bool
+)
+
+func get(name string) *action {
+	a, ok := actions[name]
+	if !ok {
+		a = &action{}
+		actions[name] = a
+	}
+	return a
+}
+
+// Bind appends default bindings to an action. Defaults are what Reset restores and what is used
+// until the user rebinds the action.
+func Bind(name string, bindings ...Binding) {
+	a := get(name)
+	for _, b := range bindings {
+		if !slices.Contains(a.defaults, b) {
+			a.defaults = append(a.defaults, b)
+		}
+		if !a.rebound && !slices.Contains(a.bindings, b) {
+			a.bindings = append(a.bindings, b)
+		}
+	}
+}
+
+// Rebind replaces every binding of an action.
+func Rebind(name string, bindings ...Binding) {
+	a := get(name)
+	a.bindings = slices.Clone(bindings)
+	a.rebound = true
+}
+
+// Reset restores the default bindings of every action.
+func Reset() {
+	for _, a := range actions {
+		a.bindings = slices.Clone(a.defaults)
+		a.rebound = false
+	}
+}
+
+// Bindings returns the current bindings of an action.
+func Bindings(name string) []Binding {
+	if a, ok := actions[name]; ok {
+		return slices.Clone(a.bindings)
+	}
+	return nil
+}
+
+// Describe returns a short human readable list of the bindings of an action, such as "W / Up".
+func Describe(name string) string {
+	var names []string
+	for _, b := range Bindings(name) {
+		_, short, _ := strings.Cut(b.String(), ":")
+		names = append(names, short)
+	}
+	if len(names) == 0 {
+		return "unbound"
+	}
+	return strings.Join(names, " / ")
+}
+
+// Actions returns the names of every known action in alphabetical order.
+func Actions() []string {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Update polls the devices, or advances the playback, and then every binding. It must be called exactly once
+// at the start of each tick so that JustPressed and JustReleased are consistent for the whole tick.
+func Update() {
+	update_frame()
+
+	gamepads = gamepads[:0]
+	if playback == nil {
+		for _, id := range ebiten.AppendGamepadIDs(nil) {
+			if ebiten.IsStandardGamepadLayoutAvailable(id) {
+				gamepads = append(gamepads, id)
+			}
+		}
+	}
+	axes, last_axes = last_axes, axes
+	clear(axes)
+	for _, id := range gamepads {
+		for axis := ebiten.StandardGamepadAxis(0); axis <= ebiten.StandardGamepadAxisMax; axis++ {
+			if v := ebiten.StandardGamepadAxisValue(id, axis); v <= -axis_deadzone || v >= axis_deadzone {
+				axes[gamepad_axis{id, axis}] = int(v / abs(v))
+			}
+		}
+	}
+
+	typing, typed = typed, false
+
+	for _, a := range actions {
+		a.was_down, a.was_key_down = a.down, a.key_down
+		a.value, a.key_value = 0, 0
+		for _, b := range a.bindings {
+			if b.device == device_key {
+				a.key_value = max(a.key_value, b.value(gamepads))
+			} else {
+				a.value = max(a.value, b.value(gamepads))
+			}
+		}
+		a.down, a.key_down = a.value >= axis_deadzone, a.key_value >= axis_deadzone
+	}
+}
+
+// Type hands the keyboard to text being typed for the rest of this tick and the next: actions don't fire from their
+// keys meanwhile, unless they're marked by WhileTyping, so typing into the console doesn't move the camera. The
+// console and focused text fields call it every tick. KeyPressed and the other functions reading keys directly
+// don't mind it.
+func Type() {
+	typing, typed = true, true
+}
+
+// Typing reports whether the keyboard is handed to text being typed, see Type.
+func Typing() bool {
+	return typing
+}
+
+// WhileTyping lets actions fire from their keys while text is typed, for those on keys which don't type, like the
+// function keys, or which close what's being typed into.
+func WhileTyping(names ...string) {
+	for _, name := range names {
+		get(name).while_typing = true
+	}
+}
+
+// held reports whether the action is held this tick and whether it was last tick, without its keys while text is
+// typed.
+func (a *action) held() (now, before bool) {
+	if typing && !a.while_typing {
+		return a.down, a.was_down
+	}
+	return a.down || a.key_down, a.was_down || a.was_key_down
+}
+
+// Pressed reports whether the action is currently held.
+func Pressed(name string) bool {
+	a, ok := actions[name]
+	if !ok {
+		return false
+	}
+	now, _ := a.held()
+	return now
+}
+
+// JustPressed reports whether the action started being held this tick.
+func JustPressed(name string) bool {
+	a, ok := actions[name]
+	if !ok {
+		return false
+	}
+	now, before := a.held()
+	return now && !before
+}
+
+// JustReleased reports whether the action stopped being held this tick.
+func JustReleased(name string) bool {
+	a, ok := actions[name]
+	if !ok {
+		return false
+	}
+	now, before := a.held()
+	return !now && before
+}
+
+// Value returns how strongly the action is held in the range [0, 1]. Digital inputs are either 0 or 1.
+func Value(name string) float {
+	a, ok := actions[name]
+	switch {
+	case !ok:
+		return 0
+	case typing && !a.while_typing:
+		return a.value
+	}
+	return max(a.value, a.key_value)
+}
+
+// Axis combines two opposing actions into a single value in the range [-1, 1].
+func Axis(negative, positive string) float {
+	return Value(positive) - Value(negative)
+}
+
+// Capture returns the first physical input that was pressed this tick. It's intended for rebinding
+// UIs which wait for the user to press whatever they'd like to bind.
+func Capture() (Binding, bool) {
+	for key := ebiten.Key(0); key <= ebiten.KeyMax; key++ {
+		if KeyJustPressed(key) {
+			return Key(key), true
+		}
+	}
+	for button := ebiten.MouseButton0; button <= ebiten.MouseButtonMax; button++ {
+		if MouseJustPressed(button) {
+			return Mouse(button), true
+		}
+	}
+	for _, id := range gamepads {
+		if buttons := inpututil.AppendJustPressedStandardGamepadButtons(id, nil); len(buttons) > 0 {
+			return GamepadButton(buttons[0]), true
+		}
+		for axis := ebiten.StandardGamepadAxis(0); axis <= ebiten.StandardGamepadAxisMax; axis++ {
+			k := gamepad_axis{id, axis}
+			if direction := axes[k]; direction != 0 && last_axes[k] != direction {
+				return GamepadAxis(axis, direction), true
+			}
+		}
+	}
+	return Binding{}, false
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Load replaces the bindings of every action found in the config file. Actions which were never
+// saved keep their defaults.
+func Load() error {
+	saved := make(map[string][]Binding)
+	if _, err := config.Load(config_section, &saved); err != nil {
+		return err
+	}
+	for name, bindings := range saved {
+		Rebind(name, bindings...)
+	}
+	return nil
+}
+
+// Save writes the bindings of every action to the config file.
+func Save() error {
+	saved := make(map[string][]Binding, len(actions))
+	for name, a := range actions {
+		saved[name] = a.bindings
+	}
+	return config.Save(config_section, saved)
+}
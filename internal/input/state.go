@@ -0,0 +1,172 @@
+package input
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Frame is the state of the keyboard and mouse during one tick. Gamepads are not part of a frame,
+// so they're ignored while a recording is played back.
+type Frame struct {
+	CursorX int `json:"x"`
+	CursorY int `json:"y"`
+
+	WheelX float64 `json:"wx,omitempty"`
+	WheelY float64 `json:"wy,omitempty"`
+
+	Keys  []ebiten.Key         `json:"keys,omitempty"`
+	Mouse []ebiten.MouseButton `json:"mouse,omitempty"`
+
+	// Chars is the text typed during the tick, after keyboard layout and modifiers are applied.
+	Chars string `json:"chars,omitempty"`
+}
+
+// Recording is a sequence of frames, one per tick.
+type Recording struct {
+	Frames []Frame `json:"frames"`
+}
+
+func LoadRecording(path string) (*Recording, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Recording
+	if err := json.Unmarshal(src, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *Recording) Save(path string) error {
+	dst, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, dst, 0o644)
+}
+
+var (
+	frame Frame
+
+	key_down        [ebiten.KeyMax + 1]bool
+	prev_key_down   [ebiten.KeyMax + 1]bool
+	mouse_down      [ebiten.MouseButtonMax + 1]bool
+	prev_mouse_down [ebiten.MouseButtonMax + 1]bool
+
+	recording     *Recording
+	playback      *Recording
+	playback_tick int
+)
+
+// poll reads the live state of the keyboard and mouse.
+func poll() (f Frame) {
+	f.CursorX, f.CursorY = ebiten.CursorPosition()
+	f.WheelX, f.WheelY = ebiten.Wheel()
+	for key := ebiten.Key(0); key <= ebiten.KeyMax; key++ {
+		if ebiten.IsKeyPressed(key) {
+			f.Keys = append(f.Keys, key)
+		}
+	}
+	for button := ebiten.MouseButton0; button <= ebiten.MouseButtonMax; button++ {
+		if ebiten.IsMouseButtonPressed(button) {
+			f.Mouse = append(f.Mouse, button)
+		}
+	}
+	f.Chars = string(ebiten.AppendInputChars(nil))
+	return
+}
+
+// update_frame advances the keyboard and mouse state by one tick, either from the devices or from a playback.
+func update_frame() {
+	prev_key_down = key_down
+	prev_mouse_down = mouse_down
+
+	if playback != nil {
+		if playback_tick < len(playback.Frames) {
+			frame = playback.Frames[playback_tick]
+			playback_tick++
+		} else {
+			// hold the last frame's cursor but let go of everything else
+			frame = Frame{CursorX: frame.CursorX, CursorY: frame.CursorY}
+		}
+	} else {
+		frame = poll()
+	}
+
+	if recording != nil {
+		recording.Frames = append(recording.Frames, frame)
+	}
+
+	clear(key_down[:])
+	clear(mouse_down[:])
+	for _, key := range frame.Keys {
+		key_down[key] = true
+	}
+	for _, button := range frame.Mouse {
+		mouse_down[button] = true
+	}
+}
+
+// StartRecording begins recording every tick, replacing any recording in progress.
+func StartRecording() {
+	recording = &Recording{}
+}
+
+// StopRecording ends the recording in progress and returns it, or nil if nothing was being recorded.
+func StopRecording() *Recording {
+	r := recording
+	recording = nil
+	return r
+}
+
+// Play replaces the keyboard and mouse with the frames of `r` until it runs out.
+func Play(r *Recording) {
+	playback = r
+	playback_tick = 0
+}
+
+// Playing reports whether a playback is in progress.
+func Playing() bool {
+	return playback != nil && playback_tick < len(playback.Frames)
+}
+
+// PlaybackDone reports whether a playback was started and every frame of it has been consumed.
+func PlaybackDone() bool {
+	return playback != nil && playback_tick >= len(playback.Frames)
+}
+
+func CursorPosition() (x, y int) {
+	return frame.CursorX, frame.CursorY
+}
+
+func Wheel() (x, y float64) {
+	return frame.WheelX, frame.WheelY
+}
+
+// Chars returns the text typed this tick.
+func Chars() string {
+	return frame.Chars
+}
+
+func KeyPressed(key ebiten.Key) bool {
+	return key >= 0 && key <= ebiten.KeyMax && key_down[key]
+}
+
+func KeyJustPressed(key ebiten.Key) bool {
+	return KeyPressed(key) && !prev_key_down[key]
+}
+
+func MousePressed(button ebiten.MouseButton) bool {
+	return button >= 0 && button <= ebiten.MouseButtonMax && mouse_down[button]
+}
+
+func MouseJustPressed(button ebiten.MouseButton) bool {
+	return MousePressed(button) && !prev_mouse_down[button]
+}
+
+func MouseJustReleased(button ebiten.MouseButton) bool {
+	return button >= 0 && button <= ebiten.MouseButtonMax && !mouse_down[button] && prev_mouse_down[button]
+}
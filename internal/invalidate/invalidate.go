@@ -0,0 +1,76 @@
+// Package invalidate keeps track of everything derived from other data and kept around to save work: pixels read
+// back from textures, baked meshes, pooled images. Each cache registers a hook that throws its contents away, to be
+// rebuilt the next time they're needed, and anything that makes them stale or just wants the memory back calls
+// Purge.
+//
+// ebiten restores its own images when the graphics context is lost, so nothing calls the hooks on its own. They
+// run from the console's purge command, or from code that knows its caches went stale.
+package invalidate
+
+import (
+	"slices"
+	"sync"
+)
+
+type hook struct {
+	id   int
+	name string
+	fn   func()
+}
+
+var (
+	mutex   sync.Mutex
+	hooks   []hook
+	last_id int
+)
+
+// Register adds a hook under `name`. Many caches may share a name, like every instance of a renderer, and are
+// purged together. The returned function removes the hook, for caches that go away before the program does.
+func Register(name string, fn func()) (unregister func()) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	last_id++
+	id := last_id
+	hooks = append(hooks, hook{id: id, name: name, fn: fn})
+
+	return func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		hooks = slices.DeleteFunc(hooks, func(h hook) bool {
+			return h.id == id
+		})
+	}
+}
+
+// Names returns the names hooks are registered under, sorted and without duplicates.
+func Names() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	names := make([]string, 0, len(hooks))
+	for _, h := range hooks {
+		names = append(names, h.name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Purge runs the hooks registered under `names`, or every hook without any, in the order they were registered.
+// It returns how many ran.
+func Purge(names ...string) int {
+	mutex.Lock()
+	var run []hook
+	for _, h := range hooks {
+		if len(names) == 0 || slices.Contains(names, h.name) {
+			run = append(run, h)
+		}
+	}
+	mutex.Unlock()
+
+	// outside of the lock, so hooks may register and unregister
+	for _, h := range run {
+		h.fn()
+	}
+	return len(run)
+}
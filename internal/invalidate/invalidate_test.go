@@ -0,0 +1,43 @@
+package invalidate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPurge(t *testing.T) {
+	var purged []string
+	record := func(name string) func() {
+		return func() { purged = append(purged, name) }
+	}
+
+	remove_a := Register("test_a", record("a1"))
+	defer Register("test_a", record("a2"))()
+	defer Register("test_b", record("b"))()
+
+	if n := Purge("test_a"); n != 2 || !slices.Equal(purged, []string{"a1", "a2"}) {
+		t.Fatalf("purging test_a ran %d hooks: %v", n, purged)
+	}
+
+	purged = nil
+	remove_a()
+	if n := Purge("test_a", "test_b"); n != 2 || !slices.Equal(purged, []string{"a2", "b"}) {
+		t.Fatalf("purging after removing a hook ran %d hooks: %v", n, purged)
+	}
+}
+
+func TestNames(t *testing.T) {
+	defer Register("test_y", func() {})()
+	defer Register("test_x", func() {})()
+	defer Register("test_y", func() {})()
+
+	var names []string
+	for _, name := range Names() {
+		if name == "test_x" || name == "test_y" {
+			names = append(names, name)
+		}
+	}
+	if !slices.Equal(names, []string{"test_x", "test_y"}) {
+		t.Fatalf("names are %v", names)
+	}
+}
@@ -0,0 +1,137 @@
+// Package iso projects a grid of tiles, and what stands on it, the way classic isometric games draw them: a tile is
+// a diamond on screen, its x axis going right and down and its y axis left and down, and height raises things
+// straight up. Isometric and Dimetric are the usual presets. Points convert from tiles to the screen and back, Pick
+// finds the cell under the mouse when cells are columns of different heights, and Sorter orders boxes to be drawn
+// back to front where sorting by a single depth can't.
+//
+// Matrix is the same projection for package pipeline, so meshes line up with sprites drawn by the helpers.
+package iso
+
+import (
+	"image"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
+	mat4  = mgl32.Mat4
+)
+
+// Projection maps tile coordinates to the screen. A point in tiles is x, y on the grid and z its height, in units
+// of Elevation. The projection doesn't have to be of a real camera turned towards the grid, pixel art's usual one
+// isn't, and everything here works with any.
+type Projection struct {
+	// TileWidth and TileHeight are the size of the diamond a tile is on screen, in pixels.
+	TileWidth, TileHeight float
+	// Elevation is how many pixels up a unit of height raises a point.
+	Elevation float
+	// Origin is where the top corner of tile 0, 0 is on screen.
+	Origin vec2
+}
+
+// Isometric is true isometry, of a camera looking down at the grid from 35.26° with the x, y and z axes 120° apart
+// on screen, tiles `tile_width` wide. A unit of height is as long as the side of a tile, so unit cubes are cubes.
+func Isometric(tile_width float) Projection {
+	h := tile_width / float(math.Sqrt(3))
+	return Projection{TileWidth: tile_width, TileHeight: h, Elevation: h}
+}
+
+// Dimetric is the 2:1 projection of pixel art, tiles `tile_width` wide and half as tall, whose lines step two pixels
+// across for one down. A unit of height is as tall as a tile is deep, half its width, so a unit cube fits a square
+// sprite.
+func Dimetric(tile_width float) Projection {
+	return Projection{TileWidth: tile_width, TileHeight: tile_width / 2, Elevation: tile_width / 2}
+}
+
+// ToScreen is where point `p` in tiles is on screen.
+func (pr Projection) ToScreen(p vec3) vec2 {
+	return vec2{
+		pr.Origin[0] + (p[0]-p[1])*pr.TileWidth/2,
+		pr.Origin[1] + (p[0]+p[1])*pr.TileHeight/2 - p[2]*pr.Elevation,
+	}
+}
+
+// ToTile is the point at height `z` seen at `s` on screen, its x and y in tiles.
+func (pr Projection) ToTile(s vec2, z float) vec2 {
+	across := (s[0] - pr.Origin[0]) / (pr.TileWidth / 2)
+	down := (s[1] - pr.Origin[1] + z*pr.Elevation) / (pr.TileHeight / 2)
+	return vec2{(down + across) / 2, (down - across) / 2}
+}
+
+// Cell is the cell of the ground, at height 0, seen at `s` on screen.
+func (pr Projection) Cell(s vec2) image.Point {
+	t := pr.ToTile(s, 0)
+	return image.Pt(int(math.Floor(float64(t[0]))), int(math.Floor(float64(t[1]))))
+}
+
+// Depth is how near point `p` is to the viewer, larger in front. Points on the same spot of the screen are drawn
+// in its order, and so are things small enough that whatever is in front of the other is so at their centers, like
+// cells of the grid and what stands on one. Sorter orders things larger than that.
+func (pr Projection) Depth(p vec3) float {
+	if pr.Elevation == 0 {
+		return p[0] + p[1]
+	}
+	return p[0] + p[1] + p[2]*pr.TileHeight/pr.Elevation
+}
+
+// Matrix is the projection as a matrix for a pipeline.Context with FlipY, drawing into a viewport `width` by `height`
+// pixels. Its world has y up, tile x along x and tile y along z, and the view is the identity: it's set with
+// SetProjection, and SetView(mgl32.Ident4()). `near` and `far` are the Depth of the nearest and farthest points
+// drawn, which map to the near and far planes.
+func (pr Projection) Matrix(width, height int, near, far float) mat4 {
+	ax, ay, az := 2/float(width), 2/float(height), 2/(near-far)
+	tw, th, e := pr.TileWidth/2, pr.TileHeight/2, pr.Elevation
+	k := float(0)
+	if e != 0 {
+		k = pr.TileHeight / e
+	}
+	return mgl32.Mat4FromRows(
+		vec4{ax * tw, 0, -ax * tw, ax*pr.Origin[0] - 1},
+		vec4{-ay * th, ay * e, -ay * th, 1 - ay*pr.Origin[1]},
+		vec4{-az, -az * k, -az, 1 + az*far},
+		vec4{0, 0, 0, 1},
+	)
+}
+
+// Pick is the cell whose column is seen at `s` on screen. The column of cell x, y stands `height(x, y)` tall, which is
+// false for cells off the map, and `top` is as tall as the tallest one. A column in front hides the cells behind it.
+func (pr Projection) Pick(s vec2, top float, height func(x, y int) (float, bool)) (image.Point, bool) {
+	// going down from `top` towards the ground the ray from the eye goes back, x and y falling by `slope` a unit
+	slope := float(0)
+	if pr.TileHeight != 0 {
+		slope = pr.Elevation / pr.TileHeight
+	}
+	if top <= 0 || slope == 0 {
+		c := pr.Cell(s)
+		h, ok := height(c.X, c.Y)
+		return c, ok && h >= 0
+	}
+
+	start := pr.ToTile(s, top)
+	x, y := int(math.Floor(float64(start[0]))), int(math.Floor(float64(start[1])))
+	// next_x and next_y are how far back the ray goes before it leaves the cell through its low x and y sides
+	next_x, next_y := start[0]-float(x), start[1]-float(y)
+	length := top * slope
+	for {
+		leave := min(next_x, next_y, length)
+		// the ray is in the column of the cell down to height `below`, which the column has to reach
+		below := top - leave/slope
+		if h, ok := height(x, y); ok && h >= below {
+			return image.Pt(x, y), true
+		}
+		if leave >= length {
+			return image.Point{}, false
+		}
+		if next_x <= next_y {
+			x, next_x = x-1, next_x+1
+		}
+		if next_y <= leave {
+			y, next_y = y-1, next_y+1
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package iso
+
+import (
+	"image"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+func near(a, b vec2) bool {
+	return a.Sub(b).Len() < 1e-3
+}
+
+func TestToTileUndoesToScreen(t *testing.T) {
+	for _, pr := range []Projection{Isometric(64), Dimetric(32), {TileWidth: 40, TileHeight: 24, Elevation: 10, Origin: vec2{300, 20}}} {
+		for _, p := range []vec3{{0, 0, 0}, {3.5, -2, 0}, {1, 7, 2.25}} {
+			s := pr.ToScreen(p)
+			if got := pr.ToTile(s, p[2]); !near(got, vec2{p[0], p[1]}) {
+				t.Errorf("%+v: %v went to %v and back to %v", pr, p, s, got)
+			}
+		}
+		// the middle of a cell is in it, and its corners just inside are too
+		for _, c := range []image.Point{{0, 0}, {4, -3}, {-2, 5}} {
+			for _, at := range []vec2{{0.5, 0.5}, {0.01, 0.01}, {0.99, 0.01}, {0.01, 0.99}} {
+				s := pr.ToScreen(vec3{float(c.X) + at[0], float(c.Y) + at[1], 0})
+				if got := pr.Cell(s); got != c {
+					t.Errorf("%+v: %v of cell %v is in %v", pr, at, c, got)
+				}
+			}
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+	// the axes of true isometry are all as long on screen and 120° apart
+	pr := Isometric(64)
+	x, y, z := pr.ToScreen(vec3{1, 0, 0}), pr.ToScreen(vec3{0, 1, 0}), pr.ToScreen(vec3{0, 0, 1})
+	for _, pair := range [][2]vec2{{x, y}, {y, z}, {z, x}} {
+		a, b := pair[0], pair[1]
+		if math.Abs(float64(a.Len()-b.Len())) > 1e-3 || math.Abs(float64(a.Dot(b)/(a.Len()*b.Len()))+0.5) > 1e-4 {
+			t.Errorf("the axes %v and %v aren't alike and 120° apart", a, b)
+		}
+	}
+
+	// 2:1 lines, and a unit cube the size of a square sprite
+	pr = Dimetric(32)
+	if x := pr.ToScreen(vec3{1, 0, 0}); x != (vec2{16, 8}) {
+		t.Errorf("the x axis goes to %v", x)
+	}
+	top, bottom := pr.ToScreen(vec3{0, 0, 1}), pr.ToScreen(vec3{1, 1, 0})
+	if bottom.Sub(top) != (vec2{0, 32}) {
+		t.Errorf("a unit cube is %v tall", bottom.Sub(top))
+	}
+}
+
+func TestMatrixLinesUpWithToScreen(t *testing.T) {
+	for _, pr := range []Projection{Isometric(64), Dimetric(32)} {
+		pr.Origin = vec2{400, 100}
+		c := &pipeline.Context{FlipY: true}
+		c.SetViewport(0, 0, 800, 600)
+		c.SetProjection(pr.Matrix(800, 600, 20, -5))
+		c.SetView(mgl32.Ident4())
+
+		for _, p := range []vec3{{0, 0, 0}, {3, 1, 2}, {-1, 4, 0.5}} {
+			got, ok := c.Project(vec3{p[0], p[2], p[1]})
+			if want := pr.ToScreen(p); !ok || !near(got, want) {
+				t.Errorf("%v is at %v through the matrix, not %v", p, got, want)
+			}
+		}
+
+		// a box shows the three faces which face the viewer, its top and the sides on the high x and y
+		box := mesh.Box(vec3{2, 2, 2})
+		box.Transform(mgl32.Translate3D(3, 1, 3))
+		c.PushMesh(box)
+		if n := len(c.Triangles()); n != 6 {
+			t.Errorf("%d triangles of a box are drawn", n)
+		}
+		c.Reset()
+	}
+}
+
+// column is a map of cells 0 tall from 0, 0 to 8, 8, but for the one at 4, 4 which is 3 tall.
+func column(x, y int) (float, bool) {
+	if x < 0 || y < 0 || x >= 8 || y >= 8 {
+		return 0, false
+	}
+	if x == 4 && y == 4 {
+		return 3, true
+	}
+	return 0, true
+}
+
+func TestPick(t *testing.T) {
+	pr := Dimetric(32)
+	for _, tc := range []struct {
+		at   vec3
+		want image.Point
+		ok   bool
+	}{
+		// the ground
+		{vec3{1.5, 2.5, 0}, image.Pt(1, 2), true},
+		// the top of the column
+		{vec3{4.5, 4.5, 3}, image.Pt(4, 4), true},
+		// the side of the column hides the ground behind it
+		{vec3{4.9, 4.5, 1}, image.Pt(4, 4), true},
+		{vec3{3.5, 3.5, 0}, image.Pt(4, 4), true},
+		// but not what's in front
+		{vec3{5.5, 5.5, 0}, image.Pt(5, 5), true},
+		// and nothing is off the map
+		{vec3{-1.5, 3, 0}, image.Point{}, false},
+	} {
+		got, ok := pr.Pick(pr.ToScreen(tc.at), 3, column)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%v picked %v %v, want %v %v", tc.at, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	pr := Dimetric(32)
+	wall := Box{vec3{0, 0, 0}, vec3{1, 6, 1}}
+	crate := Box{vec3{1.5, 0, 0}, vec3{2.5, 1, 1}}
+	if pr.Depth(wall.Center()) < pr.Depth(crate.Center()) {
+		t.Fatal("the wall is behind the crate by the depth of its center, which isn't what's tested")
+	}
+	// the wall is on the low side of x 1 and the crate on the high side, the crate is in front
+	var s Sorter
+	if order := s.Sort(pr, []Box{crate, wall}); !slices.Equal(order, []int{1, 0}) {
+		t.Errorf("the crate and the wall are drawn in the order %v", order)
+	}
+
+	// a pillar among blocks, and a block far to the side which overlaps nothing
+	boxes := []Box{{vec3{2, 2, 0}, vec3{3, 3, 2}}, {vec3{20, -20, 0}, vec3{21, -19, 1}}}
+	for y := range 4 {
+		for x := range 4 {
+			if x != 2 || y != 2 {
+				boxes = append(boxes, Box{vec3{float(x), float(y), 0}, vec3{float(x + 1), float(y + 1), 1}})
+			}
+		}
+	}
+	order := s.Sort(pr, boxes)
+	if len(order) != len(boxes) {
+		t.Fatalf("%d boxes sorted of %d", len(order), len(boxes))
+	}
+	at := make([]int, len(boxes))
+	for i, b := range order {
+		at[b] = i
+	}
+	for i, b := range boxes[2:] {
+		// the blocks on screen across from it can go anywhere
+		if x := b.Min[0] - b.Min[1]; x <= -2 || x >= 2 {
+			continue
+		}
+		// the blocks behind it are drawn before the pillar, those in front of it after
+		in_front := b.Min[0] >= 3 || b.Min[1] >= 3
+		if (at[0] < at[i+2]) != in_front {
+			t.Errorf("the block at %v is drawn %d, the pillar %d", b.Min, at[i+2], at[0])
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package iso
+
+import (
+	"cmp"
+	"slices"
+)
+
+// overlap_epsilon is how much the outlines of two boxes on screen overlap before one is ordered against the other,
+// so boxes which only touch, like neighbouring cells, aren't
+const overlap_epsilon = 1e-3
+
+// Box is the space something takes, from Min to Max in tiles.
+type Box struct {
+	Min, Max vec3
+}
+
+func (b Box) Center() vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+// Sorter orders boxes to be drawn back to front. Sorting by the Depth of their centers gets boxes wrong which are
+// long, a wall in front of a crate can have its center behind the crate's, so boxes are ordered by how they stand:
+// of two whose outlines on screen overlap, the one on the low side of a plane between them is behind.
+//
+// The zero value is ready to use, and keeps its buffers from a sort to the next.
+type Sorter struct {
+	outlines []outline
+	by_x     []int
+	behind   [][]int
+	visited  []uint8
+	order    []int
+}
+
+// outline is the hexagon a box is on screen, as its extent along the normals of the three sides of the hexagon
+type outline struct {
+	lo, hi [3]float
+	depth  float
+}
+
+const (
+	unvisited uint8 = iota
+	visiting
+	done
+)
+
+// Sort is the order to draw `boxes` in seen through `pr`, their indices from the farthest to the nearest. Boxes which
+// intersect have no right order, they're ordered by the Depth of their centers. The order is valid until the next
+// Sort. It takes a pass over the boxes which overlap on screen, so it's meant for what moves, a grid of cells is
+// drawn in order of Depth.
+func (s *Sorter) Sort(pr Projection, boxes []Box) []int {
+	n := len(boxes)
+	s.outlines = slices.Grow(s.outlines[:0], n)[:n]
+	s.by_x = s.by_x[:0]
+	s.visited = slices.Grow(s.visited[:0], n)[:n]
+	for len(s.behind) < n {
+		s.behind = append(s.behind, nil)
+	}
+
+	// the sides of the hexagon go along the x, y and z axes, and the extents are of the linear functions of a point
+	// in tiles which are constant along each
+	e, th := pr.Elevation, pr.TileHeight
+	along := [3]vec3{{0, -th, e}, {th, 0, -e}, {1, -1, 0}}
+	for i, b := range boxes {
+		o := &s.outlines[i]
+		for a, f := range along {
+			o.lo[a], o.hi[a] = 0, 0
+			for axis := range 3 {
+				lo, hi := f[axis]*b.Min[axis], f[axis]*b.Max[axis]
+				o.lo[a] += min(lo, hi)
+				o.hi[a] += max(lo, hi)
+			}
+		}
+		o.depth = pr.Depth(b.Center())
+		s.behind[i] = s.behind[i][:0]
+		s.visited[i] = unvisited
+		s.by_x = append(s.by_x, i)
+	}
+
+	// a sweep across the screen finds the pairs which overlap
+	slices.SortFunc(s.by_x, func(a, b int) int {
+		return cmp.Compare(s.outlines[a].lo[2], s.outlines[b].lo[2])
+	})
+	for p, i := range s.by_x {
+		a := &s.outlines[i]
+		for _, j := range s.by_x[p+1:] {
+			b := &s.outlines[j]
+			if b.lo[2] >= a.hi[2]-overlap_epsilon {
+				break
+			}
+			if b.lo[0] >= a.hi[0]-overlap_epsilon || a.lo[0] >= b.hi[0]-overlap_epsilon ||
+				b.lo[1] >= a.hi[1]-overlap_epsilon || a.lo[1] >= b.hi[1]-overlap_epsilon {
+				continue
+			}
+			switch {
+			case behind(boxes[i], boxes[j]):
+				s.behind[j] = append(s.behind[j], i)
+			case behind(boxes[j], boxes[i]):
+				s.behind[i] = append(s.behind[i], j)
+			case a.depth < b.depth:
+				s.behind[j] = append(s.behind[j], i)
+			default:
+				s.behind[i] = append(s.behind[i], j)
+			}
+		}
+	}
+
+	// drawing whatever is behind a box before it, the boxes taken by depth so ties stay as they'd be without
+	slices.SortStableFunc(s.by_x, func(a, b int) int {
+		return cmp.Compare(s.outlines[a].depth, s.outlines[b].depth)
+	})
+	s.order = s.order[:0]
+	for _, i := range s.by_x {
+		s.visit(i)
+	}
+	return s.order
+}
+
+// behind is whether `a` is behind `b`, on the low side of a plane along one of the axes between them, the viewer
+// being on the high side of all three.
+func behind(a, b Box) bool {
+	return a.Max[0] <= b.Min[0] || a.Max[1] <= b.Min[1] || a.Max[2] <= b.Min[2]
+}
+
+// visit adds box `i` to the order after the boxes behind it. A box already being visited is skipped, which breaks
+// the cycles of boxes which intersect.
+func (s *Sorter) visit(i int) {
+	if s.visited[i] != unvisited {
+		return
+	}
+	s.visited[i] = visiting
+	for _, j := range s.behind[i] {
+		s.visit(j)
+	}
+	s.visited[i] = done
+	s.order = append(s.order, i)
+}
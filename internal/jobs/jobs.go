@@ -0,0 +1,202 @@
+// Package jobs is the playground's worker pool. Subsystems submit closures instead of managing goroutines
+// themselves: the rasterizer binning tiles, the pipeline transforming large meshes, loaders parsing assets.
+//
+// Jobs run on worker goroutines in priority order. Results that have to be applied on the main thread, like
+// anything touching ebiten images or demo state, go into a completion callback which runs during Complete.
+package jobs
+
+import (
+	"runtime"
+	"sync"
+)
+
+type Priority int
+
+const (
+	Low Priority = iota
+	Normal
+	High
+	priority_count
+)
+
+// job is one to run, or without `fn` the turn of its group, whose next job a worker runs then.
+type job struct {
+	fn    func()
+	then  func()
+	group *Group
+}
+
+type Pool struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// queues holds the pending jobs of each priority, and a turn for each pending job of a group
+	queues [priority_count][]job
+
+	// completions are callbacks waiting for Complete
+	completions []func()
+}
+
+// NewPool starts a pool with `workers` goroutines, GOMAXPROCS when 0.
+func NewPool(workers int) *Pool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &Pool{}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Default is the pool shared by every subsystem.
+var Default = NewPool(0)
+
+func (p *Pool) work() {
+	for {
+		p.mu.Lock()
+		j, ok := p.pop()
+		for !ok {
+			p.cond.Wait()
+			j, ok = p.pop()
+		}
+		p.mu.Unlock()
+		p.run(j)
+	}
+}
+
+// pop takes the next job with the highest priority. The pool must be locked.
+func (p *Pool) pop() (job, bool) {
+	for {
+		j, ok := take(&p.queues)
+		if !ok || j.fn != nil {
+			return j, ok
+		}
+		// the group's waiter may have run its jobs already, leaving its turns with nothing to do
+		if j, ok := take(&j.group.queues); ok {
+			return j, true
+		}
+	}
+}
+
+// take takes the first of the highest priority of `queues`.
+func take(queues *[priority_count][]job) (job, bool) {
+	for priority := priority_count - 1; priority >= 0; priority-- {
+		if q := queues[priority]; len(q) > 0 {
+			j := q[0]
+			q[0] = job{}
+			queues[priority] = q[1:]
+			return j, true
+		}
+	}
+	return job{}, false
+}
+
+func (p *Pool) run(j job) {
+	j.fn()
+
+	p.mu.Lock()
+	if j.then != nil {
+		p.completions = append(p.completions, j.then)
+	}
+	if j.group != nil {
+		j.group.pending--
+	}
+	p.mu.Unlock()
+
+	// wakes up groups waiting for this job
+	p.cond.Broadcast()
+}
+
+func (p *Pool) push(priority Priority, j job) {
+	priority = min(max(priority, Low), High)
+	p.mu.Lock()
+	if g := j.group; g != nil {
+		g.queues[priority] = append(g.queues[priority], j)
+		g.pending++
+		j = job{group: g}
+	}
+	p.queues[priority] = append(p.queues[priority], j)
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// Submit runs `fn` on a worker.
+func (p *Pool) Submit(priority Priority, fn func()) {
+	p.push(priority, job{fn: fn})
+}
+
+// SubmitThen runs `fn` on a worker, then `then` on the main thread during the next Complete after `fn` returned.
+func (p *Pool) SubmitThen(priority Priority, fn func(), then func()) {
+	p.push(priority, job{fn: fn, then: then})
+}
+
+// Complete runs the completion callbacks of finished jobs. The app calls it once per tick on the main thread.
+func (p *Pool) Complete() {
+	p.mu.Lock()
+	completions := p.completions
+	p.completions = nil
+	p.mu.Unlock()
+
+	for _, fn := range completions {
+		fn()
+	}
+}
+
+// Group is a set of jobs which can be waited for together.
+type Group struct {
+	pool *Pool
+	// queues are the group's jobs no one took yet and pending those which haven't finished, both guarded by the
+	// pool's mutex
+	queues  [priority_count][]job
+	pending int
+}
+
+func (p *Pool) Group() *Group {
+	return &Group{pool: p}
+}
+
+func (g *Group) Submit(priority Priority, fn func()) {
+	g.pool.push(priority, job{fn: fn, group: g})
+}
+
+// Wait returns once every job submitted to the group finished. While waiting, the calling goroutine runs the
+// group's pending jobs itself, so waiting from inside a job doesn't deadlock the pool. It leaves other jobs to the
+// workers, a frame waiting for its own never stalls on a loader's.
+func (g *Group) Wait() {
+	p := g.pool
+	p.mu.Lock()
+	for g.pending > 0 {
+		if j, ok := take(&g.queues); ok {
+			p.mu.Unlock()
+			p.run(j)
+			p.mu.Lock()
+			continue
+		}
+		p.cond.Wait()
+	}
+	p.mu.Unlock()
+}
+
+// ParallelFor calls `fn` over [0, n) split into ranges of at most `grain` items, on the workers and the calling
+// goroutine, and returns once every range is done.
+func (p *Pool) ParallelFor(n, grain int, fn func(start, end int)) {
+	if n <= 0 {
+		return
+	}
+	grain = max(grain, 1)
+	if n <= grain {
+		fn(0, n)
+		return
+	}
+
+	g := p.Group()
+	for start := 0; start < n; start += grain {
+		end := min(start+grain, n)
+		g.Submit(High, func() {
+			fn(start, end)
+		})
+	}
+	g.Wait()
+}
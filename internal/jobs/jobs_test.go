@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelForCoversEveryIndex(t *testing.T) {
+	p := NewPool(4)
+	counts := make([]int32, 10007)
+
+	p.ParallelFor(len(counts), 64, func(start, end int) {
+		for i := start; i < end; i++ {
+			atomic.AddInt32(&counts[i], 1)
+		}
+	})
+
+	for i, c := range counts {
+		if c != 1 {
+			t.Fatalf("index %d visited %d times", i, c)
+		}
+	}
+}
+
+func TestNestedWaitDoesNotDeadlock(t *testing.T) {
+	// a single worker which waits for jobs only it could run
+	p := NewPool(1)
+	var sum atomic.Int32
+
+	outer := p.Group()
+	outer.Submit(Normal, func() {
+		inner := p.Group()
+		for i := 0; i < 10; i++ {
+			inner.Submit(Normal, func() {
+				sum.Add(1)
+			})
+		}
+		inner.Wait()
+	})
+	outer.Wait()
+
+	if sum.Load() != 10 {
+		t.Fatalf("ran %d inner jobs, want 10", sum.Load())
+	}
+}
+
+func TestCompletionsRunOnComplete(t *testing.T) {
+	p := NewPool(2)
+	ran := make(chan struct{})
+	done := false
+
+	p.SubmitThen(Normal, func() {
+		close(ran)
+	}, func() {
+		done = true
+	})
+	<-ran
+
+	if done {
+		t.Fatal("completion ran before Complete")
+	}
+
+	// the completion is queued right after the job returns
+	deadline := time.Now().Add(time.Second)
+	for !done && time.Now().Before(deadline) {
+		p.Complete()
+		runtime.Gosched()
+	}
+	if !done {
+		t.Fatal("completion didn't run during Complete")
+	}
+}
+
+func TestWaitOnlyRunsItsGroup(t *testing.T) {
+	// the one worker is busy, so the waiter is left with both its own job and someone else's
+	p := NewPool(1)
+	busy := make(chan struct{})
+	p.Submit(High, func() {
+		<-busy
+	})
+	var other atomic.Bool
+	p.Submit(Low, func() {
+		other.Store(true)
+	})
+
+	g := p.Group()
+	ran := false
+	g.Submit(Normal, func() {
+		ran = true
+	})
+	g.Wait()
+	if !ran || other.Load() {
+		t.Fatalf("the wait ran its job %v and the other %v", ran, other.Load())
+	}
+
+	// the worker takes the other job once it's free
+	close(busy)
+	deadline := time.Now().Add(time.Second)
+	for !other.Load() && time.Now().Before(deadline) {
+		runtime.Gosched()
+	}
+	if !other.Load() {
+		t.Fatal("the other job never ran")
+	}
+}
@@ -0,0 +1,235 @@
+// Package kage preprocesses Kage sources before ebiten compiles them, so shaders can share functions instead of
+// pasting them into every file:
+//
+//	#include "noise.kage"
+//	#define STEPS 64
+//	#ifdef FOG
+//	color = mix(color, FogColor, fog(dst))
+//	#endif
+//
+// An include is replaced by the file it names, relative to the file including it, or from the built-in Library
+// when there's no such file. Every file is included once however often it's named, and the package clause and
+// //kage: directives of included files are left out, so they're valid Kage on their own. A define replaces its
+// name wherever it appears as a whole word after it, in included files as well. #ifdef and #ifndef keep the lines
+// up to their #else or #endif when the name is defined, or isn't, which is how one file is the source of the
+// variants of a shader, see Features.
+package kage
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/noise"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pack"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sdf"
+)
+
+//go:embed lib
+var library embed.FS
+
+// Library are the files every shader can include by name: texture.kage to read the texture coordinates the
+// pipeline hands the shaders of materials, probe.kage for reflective materials to reflect their probe,
+// noise.kage, the Kage half of package noise, pack.kage, the Kage half of package pack, and sdf.kage, the Kage half
+// of package sdf.
+var Library = func() map[string]string {
+	files := map[string]string{"noise.kage": noise.Kage, "pack.kage": pack.Kage, "sdf.kage": sdf.Kage}
+	entries, _ := library.ReadDir("lib")
+	for _, e := range entries {
+		src, _ := library.ReadFile("lib/" + e.Name())
+		files[e.Name()] = string(src)
+	}
+	return files
+}()
+
+// Preprocess reads the file `name` with `read` and returns it with its includes and defines resolved. `defines`
+// are defined before the first line, like for settings picked in Go.
+func Preprocess(name string, read func(name string) ([]byte, error), defines map[string]string) ([]byte, error) {
+	p := &preprocessor{read: read, defines: make(map[string]string), included: make(map[string]bool)}
+	for k, v := range defines {
+		p.defines[k] = v
+	}
+	if err := p.file(name, false); err != nil {
+		return nil, err
+	}
+	return p.out.Bytes(), nil
+}
+
+// PreprocessSource preprocesses `src`, the source of a shader kept in Go, which can include the files of the
+// Library. `name` is what errors call it.
+func PreprocessSource(name, src string, defines map[string]string) ([]byte, error) {
+	return Preprocess(name, func(file string) ([]byte, error) {
+		if file == name {
+			return []byte(src), nil
+		}
+		return nil, fs.ErrNotExist
+	}, defines)
+}
+
+// Features are the defines of the variant of a shader with `features` on, each defined as true so they can be
+// checked with #ifdef and used as a bool.
+func Features(features []string) map[string]string {
+	defines := make(map[string]string, len(features))
+	for _, f := range features {
+		defines[f] = "true"
+	}
+	return defines
+}
+
+// Variant is the name of the variant of `name` with `features` on, like "lit.kage+FOG+NORMAL_MAP". The features
+// are sorted and repeats dropped, so the same features in any order are the same variant.
+func Variant(name string, features []string) string {
+	features = slices.Clone(features)
+	slices.Sort(features)
+	return strings.Join(append([]string{name}, slices.Compact(features)...), "+")
+}
+
+type preprocessor struct {
+	read     func(name string) ([]byte, error)
+	defines  map[string]string
+	included map[string]bool
+	out      bytes.Buffer
+	// branches are the #ifdefs the line is in, whether they keep their lines
+	branches []branch
+}
+
+type branch struct {
+	keep, in_else bool
+}
+
+// skipping is whether the line is in a branch which is left out.
+func (p *preprocessor) skipping() bool {
+	return slices.ContainsFunc(p.branches, func(b branch) bool { return !b.keep })
+}
+
+func (p *preprocessor) file(name string, included bool) error {
+	src, err := p.read(name)
+	if builtin, ok := Library[path.Base(name)]; errors.Is(err, fs.ErrNotExist) && included && ok {
+		src, err = []byte(builtin), nil
+	}
+	if err != nil {
+		return err
+	}
+	depth := len(p.branches)
+	for i, line := range strings.Split(string(src), "\n") {
+		if err := p.line(name, line, included); err != nil {
+			return fmt.Errorf("%s:%d: %w", name, i+1, err)
+		}
+	}
+	if len(p.branches) != depth {
+		return fmt.Errorf("%s: #ifdef without #endif", name)
+	}
+	return nil
+}
+
+func (p *preprocessor) line(name, line string, included bool) error {
+	trimmed := strings.TrimSpace(line)
+	directive, rest, _ := strings.Cut(trimmed, " ")
+	rest = strings.TrimSpace(rest)
+	switch directive {
+	case "#ifdef", "#ifndef":
+		if !identifier(rest) {
+			return fmt.Errorf("%s wants a name", directive)
+		}
+		_, defined := p.defines[rest]
+		keep := defined == (directive == "#ifdef")
+		p.branches = append(p.branches, branch{keep: keep})
+		return nil
+	case "#else":
+		n := len(p.branches)
+		if n == 0 || p.branches[n-1].in_else {
+			return fmt.Errorf("#else without #ifdef")
+		}
+		b := &p.branches[n-1]
+		b.keep, b.in_else = !b.keep, true
+		return nil
+	case "#endif":
+		if len(p.branches) == 0 {
+			return fmt.Errorf("#endif without #ifdef")
+		}
+		p.branches = p.branches[:len(p.branches)-1]
+		return nil
+	}
+	if p.skipping() {
+		return nil
+	}
+	switch {
+	case directive == "#include":
+		target, ok := strings.CutPrefix(rest, `"`)
+		if target, ok = strings.CutSuffix(target, `"`); !ok || target == "" {
+			return fmt.Errorf(`#include wants a "file"`)
+		}
+		target = path.Join(path.Dir(name), target)
+		if p.included[target] {
+			return nil
+		}
+		p.included[target] = true
+		return p.file(target, true)
+	case directive == "#define":
+		key, value, _ := strings.Cut(rest, " ")
+		value = strings.TrimSpace(value)
+		if !identifier(key) || value == "" {
+			return fmt.Errorf("#define wants a name and a value")
+		}
+		p.defines[key] = p.expand(value)
+		return nil
+	case strings.HasPrefix(directive, "#"):
+		return fmt.Errorf("unknown directive %s", directive)
+	case included && (directive == "package" || strings.HasPrefix(trimmed, "//kage:")):
+		return nil
+	}
+	p.out.WriteString(p.expand(line))
+	p.out.WriteByte('\n')
+	return nil
+}
+
+// expand replaces the defined names in `s`, whole words only.
+func (p *preprocessor) expand(s string) string {
+	if len(p.defines) == 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if !word_start(s[i]) || i > 0 && word(s[i-1]) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(s) && word(s[j]) {
+			j++
+		}
+		if v, ok := p.defines[s[i:j]]; ok {
+			b.WriteString(v)
+		} else {
+			b.WriteString(s[i:j])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func word_start(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func word(c byte) bool {
+	return word_start(c) || '0' <= c && c <= '9'
+}
+
+func identifier(s string) bool {
+	if s == "" || !word_start(s[0]) {
+		return false
+	}
+	for i := range len(s) {
+		if !word(s[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,196 @@
+package kage
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVariants(t *testing.T) {
+	files := fstest.MapFS{"lit.kage": {Data: []byte(`package main
+#ifdef FOG
+fog
+#ifndef NORMAL_MAP
+fog without normal map
+#endif
+#else
+no fog
+#endif
+#ifdef NORMAL_MAP
+normal map FOG
+#endif
+`)}}
+	read := func(name string) ([]byte, error) { return fs.ReadFile(files, name) }
+	for _, test := range []struct {
+		features []string
+		want     string
+	}{
+		{nil, "package main\nno fog\n\n"},
+		{[]string{"FOG"}, "package main\nfog\nfog without normal map\n\n"},
+		{[]string{"NORMAL_MAP", "FOG"}, "package main\nfog\nnormal map true\n\n"},
+	} {
+		out, err := Preprocess("lit.kage", read, Features(test.features))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != test.want {
+			t.Errorf("the variant with %v is %q, want %q", test.features, out, test.want)
+		}
+	}
+	if a, b := Variant("lit.kage", []string{"NORMAL_MAP", "FOG", "FOG"}), Variant("lit.kage", []string{"FOG", "NORMAL_MAP"}); a != b {
+		t.Fatalf("the same features are the variants %s and %s", a, b)
+	}
+
+	for _, bad := range []string{"#ifdef A\n", "#endif\n", "#else\n", "#ifdef A\n#else\n#else\n#endif\n", "#ifdef\n#endif\n"} {
+		files["bad.kage"] = &fstest.MapFile{Data: []byte(bad)}
+		if _, err := Preprocess("bad.kage", read, nil); err == nil {
+			t.Errorf("%q preprocessed", bad)
+		}
+	}
+}
+
+func TestPreprocess(t *testing.T) {
+	files := fstest.MapFS{
+		"shaders/main.kage": {Data: []byte(`//kage:unit pixels
+package main
+
+#include "common.kage"
+#include "common.kage"
+#include "noise.kage"
+#define STEPS 8
+#define TWICE STEPS*2
+
+func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
+	for i := 0; i < TWICE; i++ {
+	}
+	return vec4(SCALE, STEPS_LEFT, 0, 1)
+}
+`)},
+		"shaders/common.kage": {Data: []byte("//kage:unit pixels\npackage main\n\nfunc common() float { return SCALE }\n")},
+	}
+	read := func(name string) ([]byte, error) { return fs.ReadFile(files, name) }
+	out, err := Preprocess("shaders/main.kage", read, map[string]string{"SCALE": "0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(out)
+	for _, want := range []string{
+		"func common() float { return 0.5 }",
+		"func value_noise(",
+		"i < 8*2;",
+		"vec4(0.5, STEPS_LEFT, 0, 1)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("%q is missing from\n%s", want, src)
+		}
+	}
+	if n := strings.Count(src, "package main"); n != 1 {
+		t.Errorf("%d package clauses in\n%s", n, src)
+	}
+	if n := strings.Count(src, "func common()"); n != 1 {
+		t.Errorf("common.kage was included %d times", n)
+	}
+
+	for _, bad := range []string{`#include common.kage`, `#include "missing.kage"`, `#define`, `#define 1 2`, `#pragma once`} {
+		files["bad.kage"] = &fstest.MapFile{Data: []byte("package main\n" + bad + "\n")}
+		if _, err := Preprocess("bad.kage", read, nil); err == nil || !strings.Contains(err.Error(), "bad.kage:2") {
+			t.Errorf("%s preprocessed, or the error doesn't say where: %v", bad, err)
+		}
+	}
+}
+
+// TestBuiltInShaders preprocesses the source of every shader kept in Go in the repository, and checks what comes out
+// is Kage ebiten can parse and that sources with directives aren't handed to ebiten.NewShader as they are.
+func TestBuiltInShaders(t *testing.T) {
+	// the files of each package, since a shader may be compiled in another file than the one it's in
+	fset := token.NewFileSet()
+	packages := make(map[string][]*ast.File)
+	err := filepath.WalkDir(filepath.Join("..", ".."), func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+			return err
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return err
+		}
+		packages[filepath.Dir(file)] = append(packages[filepath.Dir(file)], f)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shaders := 0
+	for dir, files := range packages {
+		sources := make(map[string]string)
+		for _, f := range files {
+			for _, decl := range f.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.VAR && gen.Tok != token.CONST {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					v := spec.(*ast.ValueSpec)
+					for i, name := range v.Names {
+						if name.Name != "shader" && !strings.HasSuffix(name.Name, "_shader") || i >= len(v.Values) {
+							continue
+						}
+						if lit, ok := v.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING && lit.Value[0] == '`' {
+							sources[name.Name] = strings.Trim(lit.Value, "`")
+						}
+					}
+				}
+			}
+		}
+		for name, src := range sources {
+			shaders++
+			out, err := PreprocessSource(name+".kage", src, nil)
+			if err != nil {
+				t.Errorf("%s: %s: %v", dir, name, err)
+				continue
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), name+".kage", out, 0); err != nil {
+				t.Errorf("%s: %s doesn't parse once preprocessed: %v", dir, name, err)
+			}
+		}
+
+		// ebiten.NewShader([]byte(x_shader)) compiles the source as it is, which has to have no directives then
+		for _, f := range files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || len(call.Args) != 1 {
+					return true
+				}
+				if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "NewShader" {
+					return true
+				}
+				conversion, ok := call.Args[0].(*ast.CallExpr)
+				if !ok || len(conversion.Args) != 1 {
+					return true
+				}
+				if id, ok := conversion.Args[0].(*ast.Ident); ok && directive(sources[id.Name]) {
+					t.Errorf("%s: %s is compiled without being preprocessed", fset.Position(call.Pos()), id.Name)
+				}
+				return true
+			})
+		}
+	}
+	if shaders == 0 {
+		t.Fatal("found no shaders")
+	}
+}
+
+// directive reports whether `src` has a line the preprocessor handles.
+func directive(src string) bool {
+	for _, line := range strings.Split(src, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+// Package locale holds the translated strings of the demos. Strings are looked up by key in the table of the
+// current language, falling back to English and then to the key itself, so a missing translation shows up as
+// something readable instead of nothing.
+//
+// Tables are JSON objects of keys to strings, one file per language named after it: en.json, de.json, ja.json.
+// Strings may have fmt verbs filled in by T. Translations which need the arguments in another order use explicit
+// indexes, "%[2]s %[1]d".
+package locale
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Fallback is the language of strings missing from the current language.
+const Fallback = "en"
+
+var (
+	tables   = map[string]map[string]string{}
+	language = Fallback
+)
+
+// Add adds the strings of `table` to language `lang`, replacing the ones it had under the same keys.
+func Add(lang string, table map[string]string) {
+	t := tables[lang]
+	if t == nil {
+		t = make(map[string]string, len(table))
+		tables[lang] = t
+	}
+	for key, s := range table {
+		t[key] = s
+	}
+}
+
+// Parse adds the strings of the JSON table `data` to language `lang`.
+func Parse(lang string, data []byte) error {
+	var table map[string]string
+	if err := json.Unmarshal(data, &table); err != nil {
+		return fmt.Errorf("%s strings: %w", lang, err)
+	}
+	Add(lang, table)
+	return nil
+}
+
+// Load adds the table in the file at `path`, the language is the name of the file.
+func Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return Parse(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), data)
+}
+
+// LoadFS adds every .json table in `dir` of `fsys`, such as the embedded strings of a demo.
+func LoadFS(fsys fs.FS, dir string) error {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if err := Parse(strings.TrimSuffix(path.Base(name), ".json"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Languages returns the languages with a table, sorted.
+func Languages() []string {
+	langs := make([]string, 0, len(tables))
+	for lang := range tables {
+		langs = append(langs, lang)
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+// Language returns the current language.
+func Language() string {
+	return language
+}
+
+// SetLanguage switches to `lang`. Languages without a table are fine, everything falls back.
+func SetLanguage(lang string) {
+	language = lang
+}
+
+// Has reports whether `key` has a string in the current language, not counting the fallbacks.
+func Has(key string) bool {
+	_, ok := tables[language][key]
+	return ok
+}
+
+// T returns the string of `key` in the current language, formatted with `args` when there are any.
+func T(key string, args ...any) string {
+	return In(language, key, args...)
+}
+
+// In returns the string of `key` in `lang`, such as the name of every language in itself for picking one.
+func In(lang, key string, args ...any) string {
+	s, ok := tables[lang][key]
+	if !ok {
+		s, ok = tables[Fallback][key]
+	}
+	if !ok {
+		s = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
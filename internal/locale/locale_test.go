@@ -0,0 +1,75 @@
+package locale
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFallsBack(t *testing.T) {
+	defer SetLanguage(Language())
+	Add("en", map[string]string{"test_hello": "Hello", "test_only_en": "Only in English"})
+	Add("de", map[string]string{"test_hello": "Hallo"})
+
+	SetLanguage("de")
+	if s := T("test_hello"); s != "Hallo" {
+		t.Fatalf("hello in German is %q", s)
+	}
+	if s := T("test_only_en"); s != "Only in English" {
+		t.Fatalf("a string missing from German is %q, want the English one", s)
+	}
+	if s := T("test_missing"); s != "test_missing" {
+		t.Fatalf("a string missing everywhere is %q, want the key", s)
+	}
+	if Has("test_only_en") {
+		t.Fatal("German has a string only English has")
+	}
+
+	if s := In("de", "test_hello"); s != "Hallo" || T("test_hello") != "Hallo" {
+		t.Fatalf("hello in German is %q", s)
+	}
+
+	SetLanguage("xx")
+	if s := T("test_hello"); s != "Hello" {
+		t.Fatalf("hello in a language without a table is %q", s)
+	}
+}
+
+func TestFormatsArguments(t *testing.T) {
+	defer SetLanguage(Language())
+	Add("en", map[string]string{"test_count": "%d boxes in %s"})
+	Add("ja", map[string]string{"test_count": "%[2]sに箱が%[1]d個"})
+
+	SetLanguage("en")
+	if s := T("test_count", 3, "the stack"); s != "3 boxes in the stack" {
+		t.Fatalf("formatted %q", s)
+	}
+	SetLanguage("ja")
+	if s := T("test_count", 3, "山"); s != "山に箱が3個" {
+		t.Fatalf("formatted with reordered arguments %q", s)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"strings/en.json":   {Data: []byte(`{"test_quit": "Quit"}`)},
+		"strings/ru.json":   {Data: []byte(`{"test_quit": "Выход"}`)},
+		"strings/notes.txt": {Data: []byte(`not a table`)},
+	}
+	if err := LoadFS(fsys, "strings"); err != nil {
+		t.Fatal(err)
+	}
+	if langs := Languages(); !slices.Contains(langs, "ru") || slices.Contains(langs, "notes") {
+		t.Fatalf("loaded languages %v", langs)
+	}
+
+	defer SetLanguage(Language())
+	SetLanguage("ru")
+	if s := T("test_quit"); s != "Выход" {
+		t.Fatalf("quit in Russian is %q", s)
+	}
+
+	if err := LoadFS(fstest.MapFS{"bad/de.json": {Data: []byte(`{"test_quit": 1}`)}}, "bad"); err == nil {
+		t.Fatal("a table of numbers loaded")
+	}
+}
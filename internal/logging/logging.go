@@ -0,0 +1,169 @@
+// Package logging is the playground's leveled logger. Every entry is tagged with the subsystem which
+// logged it and kept in a bounded history, which the in-game console displays. Only entries at or above
+// StderrLevel are also written to stderr, so chatty debug output doesn't spam the terminal.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelCount
+)
+
+var level_names = [LevelCount]string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l >= 0 && l < LevelCount {
+		return level_names[l]
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel returns the level with the given name.
+func ParseLevel(s string) (Level, error) {
+	for l, name := range level_names {
+		if strings.EqualFold(s, name) {
+			return Level(l), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Tag     string
+	Message string
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s %-5s %s: %s", e.Time.Format("15:04:05.000"), e.Level, e.Tag, e.Message)
+}
+
+// history is how many entries are kept around for the console.
+const history = 1024
+
+var (
+	mu      sync.Mutex
+	entries []Entry // ring buffer once it reaches `history` entries
+	next    int     // next index to write once the ring buffer is full
+	tags    []string
+
+	// StderrLevel is the minimum level written to stderr.
+	StderrLevel           = LevelInfo
+	stderr      io.Writer = os.Stderr
+
+	listeners []func(Entry)
+)
+
+// Listen calls `fn` with every entry written from now on, as it's written. It's called with the log locked, so it
+// mustn't log itself and should be quick.
+func Listen(fn func(Entry)) {
+	mu.Lock()
+	defer mu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+func write(level Level, tag, message string) {
+	e := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Tag:     tag,
+		Message: message,
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(entries) < history {
+		entries = append(entries, e)
+	} else {
+		entries[next] = e
+		next = (next + 1) % history
+	}
+
+	if !slices.Contains(tags, tag) {
+		tags = append(tags, tag)
+		slices.Sort(tags)
+	}
+
+	if level >= StderrLevel {
+		fmt.Fprintln(stderr, e)
+	}
+	for _, fn := range listeners {
+		fn(e)
+	}
+}
+
+// Entries appends every entry in the history to `dst`, oldest first.
+func Entries(dst []Entry) []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+	dst = append(dst, entries[next:]...)
+	return append(dst, entries[:next]...)
+}
+
+// Tags returns every tag which has logged something, sorted.
+func Tags() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	return slices.Clone(tags)
+}
+
+// Logger writes entries under a single tag.
+type Logger struct {
+	tag string
+}
+
+func Tag(tag string) Logger {
+	return Logger{tag: tag}
+}
+
+func (l Logger) Debugf(format string, args ...any) {
+	write(LevelDebug, l.tag, fmt.Sprintf(format, args...))
+}
+
+func (l Logger) Infof(format string, args ...any) {
+	write(LevelInfo, l.tag, fmt.Sprintf(format, args...))
+}
+
+func (l Logger) Warnf(format string, args ...any) {
+	write(LevelWarn, l.tag, fmt.Sprintf(format, args...))
+}
+
+func (l Logger) Errorf(format string, args ...any) {
+	write(LevelError, l.tag, fmt.Sprintf(format, args...))
+}
+
+// Fatalf logs an error and exits the process.
+func (l Logger) Fatalf(format string, args ...any) {
+	l.Errorf(format, args...)
+	os.Exit(1)
+}
+
+type stdlog_writer struct{}
+
+func (stdlog_writer) Write(p []byte) (int, error) {
+	write(LevelInfo, "log", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// CaptureStdlog routes the standard library's log package through the logger, under the "log" tag.
+func CaptureStdlog() {
+	log.SetFlags(0)
+	log.SetOutput(stdlog_writer{})
+}
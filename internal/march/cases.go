@@ -0,0 +1,87 @@
+package march
+
+// Corner c of a cube is at x, y and z from its bits 0, 1 and 2, and the 12 edges join corners which differ in
+// one bit, the lower corner first.
+var cube_edges = func() (edges [12][2]uint8) {
+	i := 0
+	for a := range uint8(8) {
+		for bit := uint8(1); bit < 8; bit <<= 1 {
+			if a&bit == 0 {
+				edges[i] = [2]uint8{a, a | bit}
+				i++
+			}
+		}
+	}
+	return edges
+}()
+
+func edge_between(a, b uint8) uint8 {
+	a, b = min(a, b), max(a, b)
+	for i, e := range cube_edges {
+		if e == [2]uint8{a, b} {
+			return uint8(i)
+		}
+	}
+	panic("corners which aren't neighbours")
+}
+
+// cases are the triangles of the surface through a cube for each of the 256 ways its corners can be inside or
+// outside, as edges the points are on. Rather than the usual table they're worked out when the program starts:
+//
+//   - on each face the surface crosses, a line joins the edge where going round the face counter-clockwise leaves
+//     the corners inside to the edge where it last entered them, so the inside is to the left of the line
+//   - the lines of the six faces join up into loops around the inside, which are cut into fans of triangles
+//
+// Faces with two corners inside on opposite corners could be cut either way; cutting off each inside corner on
+// its own only depends on the face, so the cubes on both sides of it agree and the surface has no cracks.
+var cases = func() (cases [256][][3]uint8) {
+	var faces [6][4]uint8
+	for axis := range 3 {
+		u, v := (axis+1)%3, (axis+2)%3
+		for side := range 2 {
+			var f [4]uint8
+			for i, uv := range [4][2]int{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+				f[i] = uint8(side<<axis | uv[0]<<u | uv[1]<<v)
+			}
+			// u cross v is along the axis, so the corners go counter-clockwise seen from the + side
+			if side == 0 {
+				f[1], f[3] = f[3], f[1]
+			}
+			faces[2*axis+side] = f
+		}
+	}
+
+	for inside := range 256 {
+		in := func(c uint8) bool { return inside>>c&1 == 1 }
+		next := make(map[uint8]uint8)
+		for _, f := range faces {
+			for k := range 4 {
+				if !in(f[k]) || in(f[(k+1)%4]) {
+					continue
+				}
+				for j := k + 3; j > k; j-- {
+					if !in(f[j%4]) && in(f[(j+1)%4]) {
+						next[edge_between(f[k], f[(k+1)%4])] = edge_between(f[j%4], f[(j+1)%4])
+						break
+					}
+				}
+			}
+		}
+		for e := range uint8(12) {
+			if _, ok := next[e]; !ok {
+				continue
+			}
+			loop := []uint8{e}
+			for at := next[e]; at != e; at = next[at] {
+				loop = append(loop, at)
+			}
+			for _, at := range loop {
+				delete(next, at)
+			}
+			for i := 2; i < len(loop); i++ {
+				cases[inside] = append(cases[inside], [3]uint8{loop[0], loop[i], loop[i-1]})
+			}
+		}
+	}
+	return cases
+}()
@@ -0,0 +1,182 @@
+// Package march turns a field of numbers sampled on a grid, metaballs or noise, into the mesh of the surface where
+// the field crosses a level, by marching cubes. A Grid keeps every buffer it needs between calls, so a surface can
+// be made again every frame without allocating.
+package march
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+// Grid is a box sampled at Size points along each axis.
+type Grid struct {
+	Size     [3]int
+	Min, Max vec3
+	// Values are the samples, x fastest, then y, then z.
+	Values []float
+
+	mesh mesh.Mesh
+	// edges are the index of the point on each of the three edges leading from each sample in +x, +y and +z,
+	// -1 for none yet, so cubes sharing an edge share its point
+	edges []int32
+	// Full is set when the last surface had more points than a mesh can index, and was cut short.
+	Full bool
+}
+
+// NewGrid makes a grid of `size` samples along each axis, at least 2, over the box from `lo` to `hi`.
+func NewGrid(size [3]int, lo, hi vec3) *Grid {
+	for i := range size {
+		size[i] = max(size[i], 2)
+	}
+	n := size[0] * size[1] * size[2]
+	return &Grid{
+		Size:   size,
+		Min:    lo,
+		Max:    hi,
+		Values: make([]float, n),
+		edges:  make([]int32, 3*n),
+	}
+}
+
+func (g *Grid) index(x, y, z int) int {
+	return x + g.Size[0]*(y+g.Size[1]*z)
+}
+
+// Point is where the sample at `x`, `y`, `z` is.
+func (g *Grid) Point(x, y, z int) vec3 {
+	step := g.step()
+	return g.Min.Add(vec3{float(x) * step[0], float(y) * step[1], float(z) * step[2]})
+}
+
+func (g *Grid) step() vec3 {
+	d := g.Max.Sub(g.Min)
+	return vec3{d[0] / float(g.Size[0]-1), d[1] / float(g.Size[1]-1), d[2] / float(g.Size[2]-1)}
+}
+
+// Sample sets every value of the grid to `field` at its point.
+func (g *Grid) Sample(field func(p vec3) float) {
+	for z := range g.Size[2] {
+		for y := range g.Size[1] {
+			for x := range g.Size[0] {
+				g.Values[g.index(x, y, z)] = field(g.Point(x, y, z))
+			}
+		}
+	}
+}
+
+// Surface makes the mesh of where the field is `level`, facing away from where it's above it: a metaball's
+// surface faces out. The normals follow the field and the texture is mapped from above, over the box. The mesh is
+// the grid's own and is made again in place by the next call, its points, normals and triangles are reused.
+func (g *Grid) Surface(level float) *mesh.Mesh {
+	m := &g.mesh
+	m.Points = m.Points[:0]
+	m.Normals = m.Normals[:0]
+	m.Texcoords = m.Texcoords[:0]
+	m.Triangles = m.Triangles[:0]
+	m.Tangents = nil
+	for i := range g.edges {
+		g.edges[i] = -1
+	}
+	g.Full = false
+
+	var corners [8]float
+	for z := range g.Size[2] - 1 {
+		for y := range g.Size[1] - 1 {
+			for x := range g.Size[0] - 1 {
+				var inside uint8
+				for c := range 8 {
+					corners[c] = g.Values[g.index(x+c&1, y+c>>1&1, z+c>>2&1)]
+					if corners[c] > level {
+						inside |= 1 << c
+					}
+				}
+				for _, t := range cases[inside] {
+					var tri [3]uint16
+					for i, e := range t {
+						p, ok := g.vertex(x, y, z, e, level)
+						if !ok {
+							g.Full = true
+							m.ComputeBounds()
+							return m
+						}
+						tri[i] = p
+					}
+					m.Triangles = append(m.Triangles, mesh.Triangle{
+						P1: tri[0], P2: tri[1], P3: tri[2],
+						T1: tri[0], T2: tri[1], T3: tri[2],
+						N1: tri[0], N2: tri[1], N3: tri[2],
+					})
+				}
+			}
+		}
+	}
+	m.ComputeBounds()
+	return m
+}
+
+// vertex is the index of the point on edge `e` of the cube at `x`, `y`, `z`, made the first time it's asked for.
+func (g *Grid) vertex(x, y, z int, e uint8, level float) (uint16, bool) {
+	edge := cube_edges[e]
+	a, b := edge[0], edge[1]
+	ax, ay, az := x+int(a&1), y+int(a>>1&1), z+int(a>>2&1)
+	bx, by, bz := x+int(b&1), y+int(b>>1&1), z+int(b>>2&1)
+	slot := 3*g.index(ax, ay, az) + edge_axis(a, b)
+	if i := g.edges[slot]; i >= 0 {
+		return uint16(i), true
+	}
+	m := &g.mesh
+	if len(m.Points) > 0xffff {
+		return 0, false
+	}
+
+	va, vb := g.Values[g.index(ax, ay, az)], g.Values[g.index(bx, by, bz)]
+	t := float(0.5)
+	if va != vb {
+		t = (level - va) / (vb - va)
+	}
+	pa, pb := g.Point(ax, ay, az), g.Point(bx, by, bz)
+	p := pa.Add(pb.Sub(pa).Mul(t))
+	n := g.gradient(ax, ay, az).Mul(1 - t).Add(g.gradient(bx, by, bz).Mul(t)).Mul(-1)
+	if n.Len() > 0 {
+		n = n.Normalize()
+	}
+	size := g.Max.Sub(g.Min)
+	m.Points = append(m.Points, p)
+	m.Normals = append(m.Normals, n)
+	m.Texcoords = append(m.Texcoords, vec2{(p[0] - g.Min[0]) / size[0], (p[2] - g.Min[2]) / size[2]})
+	i := uint16(len(m.Points) - 1)
+	g.edges[slot] = int32(i)
+	return i, true
+}
+
+// gradient is how the field changes around a sample, by central differences, one sided at the edges of the grid.
+func (g *Grid) gradient(x, y, z int) vec3 {
+	step := g.step()
+	var d vec3
+	at := [3]int{x, y, z}
+	for axis := range 3 {
+		lo, hi := at, at
+		lo[axis] = max(at[axis]-1, 0)
+		hi[axis] = min(at[axis]+1, g.Size[axis]-1)
+		d[axis] = (g.Values[g.index(hi[0], hi[1], hi[2])] - g.Values[g.index(lo[0], lo[1], lo[2])]) / (float(hi[axis]-lo[axis]) * step[axis])
+	}
+	return d
+}
+
+// edge_axis is which axis the edge between corners `a` and `b` runs along, a being the lower corner.
+func edge_axis(a, b uint8) int {
+	switch a ^ b {
+	case 1:
+		return 0
+	case 2:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,74 @@
+package march
+
+import (
+	"math"
+	"testing"
+)
+
+func sphere(p vec3) float {
+	return 1 - p.Len()
+}
+
+func TestSphere(t *testing.T) {
+	g := NewGrid([3]int{24, 24, 24}, vec3{-1.5, -1.5, -1.5}, vec3{1.5, 1.5, 1.5})
+	g.Sample(sphere)
+	m := g.Surface(0)
+	var volume float
+	for i, tri := range m.Triangles {
+		p1, p2, p3 := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+		volume += p1.Dot(p2.Cross(p3)) / 6
+		n := p2.Sub(p1).Cross(p3.Sub(p1))
+		if n.Len() > 1e-9 && n.Dot(p1.Add(p2).Add(p3)) <= 0 {
+			t.Fatalf("triangle %d faces into the sphere", i)
+		}
+		if m.Normals[tri.N1].Dot(p1) < 0.95 {
+			t.Fatalf("the normal at %v is %v", p1, m.Normals[tri.N1])
+		}
+	}
+	if want := float(4.0 / 3 * math.Pi); volume < want*0.97 || volume > want*1.01 {
+		t.Fatalf("the sphere has a volume of %v, want about %v", volume, want)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	// every edge of a closed surface is shared by two triangles going opposite ways, checked on a lumpy field
+	// which has every case
+	g := NewGrid([3]int{12, 12, 12}, vec3{-1, -1, -1}, vec3{1, 1, 1})
+	g.Sample(func(p vec3) float {
+		return float(math.Sin(float64(p[0]*7))*math.Sin(float64(p[1]*5+1))*math.Sin(float64(p[2]*6+2))) - 0.05
+	})
+	// the edges of the box are open, so only the inner samples count
+	for z := range 12 {
+		for y := range 12 {
+			for x := range 12 {
+				if x == 0 || y == 0 || z == 0 || x == 11 || y == 11 || z == 11 {
+					g.Values[g.index(x, y, z)] = -1
+				}
+			}
+		}
+	}
+	m := g.Surface(0)
+	if len(m.Triangles) < 100 {
+		t.Fatalf("the lumpy field has a surface of %d triangles", len(m.Triangles))
+	}
+	edges := make(map[[2]uint16]int)
+	for _, tri := range m.Triangles {
+		for _, e := range [3][2]uint16{{tri.P1, tri.P2}, {tri.P2, tri.P3}, {tri.P3, tri.P1}} {
+			edges[e]++
+		}
+	}
+	for e, n := range edges {
+		if n != 1 || edges[[2]uint16{e[1], e[0]}] != 1 {
+			t.Fatalf("the edge %v is in %d triangles and the other way round in %d", e, n, edges[[2]uint16{e[1], e[0]}])
+		}
+	}
+}
+
+func TestSurfaceDoesNotAllocate(t *testing.T) {
+	g := NewGrid([3]int{16, 16, 16}, vec3{-1.5, -1.5, -1.5}, vec3{1.5, 1.5, 1.5})
+	g.Sample(sphere)
+	g.Surface(0)
+	if n := testing.AllocsPerRun(10, func() { g.Surface(0) }); n != 0 {
+		t.Fatalf("making the surface again allocates %v times", n)
+	}
+}
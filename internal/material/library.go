@@ -0,0 +1,112 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/watch"
+)
+
+var logger = logging.Tag("material")
+
+// Library loads materials by name from the files in Dir, `name.json`, and the files they reference. Files missing
+// from Dir are read from Fallback when there is one, like the defaults embedded in a demo, so copying a file into
+// Dir is enough to start editing it.
+type Library struct {
+	Dir      string
+	Fallback fs.FS
+
+	assets map[string]*Asset
+}
+
+// Asset is a material as loaded by a library, kept up to date by Reload.
+type Asset struct {
+	Name string
+	// Material is the last material which loaded, nil while none did.
+	Material *Material
+	// Err is why the material didn't load the last time it was tried, nil when it did.
+	Err error
+	// Version counts the loads, whatever was built from the material is stale when it changes.
+	Version int
+
+	library *Library
+	// watches are the files the material was read from, its own and whatever was read through Read
+	watches map[string]*watch.File
+}
+
+// Get returns the material called `name`, loading it the first time. The asset is returned even when the material
+// didn't load, with Err set, and it's tried again when its file changes.
+func (l *Library) Get(name string) *Asset {
+	if a, ok := l.assets[name]; ok {
+		return a
+	}
+	if l.assets == nil {
+		l.assets = make(map[string]*Asset)
+	}
+	a := &Asset{Name: name, library: l, watches: make(map[string]*watch.File)}
+	l.assets[name] = a
+	a.load()
+	if a.Err != nil {
+		logger.Warnf("material %s: %v", name, a.Err)
+	}
+	return a
+}
+
+// Reload loads the materials whose files changed again and returns the names of those which did. Call it once a
+// tick. A material which fails to load is logged and keeps its last good version, so a half-saved file doesn't
+// take it away.
+func (l *Library) Reload() []string {
+	var reloaded []string
+	for name, a := range l.assets {
+		changed := false
+		for _, w := range a.watches {
+			// every watch is asked, so none reports the same change twice
+			changed = w.Changed() || changed
+		}
+		if !changed {
+			continue
+		}
+		if a.load(); a.Err != nil {
+			logger.Warnf("keeping the last material %s, reloading failed: %v", name, a.Err)
+			continue
+		}
+		logger.Infof("reloaded material %s", name)
+		reloaded = append(reloaded, name)
+	}
+	return reloaded
+}
+
+func (a *Asset) load() {
+	src, err := a.Read(a.Name + ".json")
+	if err == nil {
+		var m *Material
+		if m, err = Parse(src); err == nil {
+			a.Material = m
+			a.Version++
+		}
+	}
+	if err != nil {
+		a.Err = fmt.Errorf("%s.json: %w", a.Name, err)
+		return
+	}
+	a.Err = nil
+}
+
+// Read reads `path`, relative to the library's directory, and watches it so the material reloads when it changes.
+// Renderers read the shader and the textures of the material through it.
+func (a *Asset) Read(path string) ([]byte, error) {
+	l := a.library
+	disk := filepath.Join(l.Dir, filepath.FromSlash(path))
+	if _, ok := a.watches[disk]; !ok {
+		a.watches[disk] = watch.New(disk)
+	}
+	src, err := os.ReadFile(disk)
+	if errors.Is(err, fs.ErrNotExist) && l.Fallback != nil {
+		return fs.ReadFile(l.Fallback, path)
+	}
+	return src, err
+}
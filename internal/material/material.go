@@ -0,0 +1,119 @@
+// Package material reads materials from JSON files: the Kage shader to draw with, its textures and uniforms, and
+// how to blend and cull. A Library loads them by name and loads them again when their files change, so materials
+// are tweaked while a demo runs rather than in Go code. Turning a material into something drawable is up to the
+// renderer, see render.NewMaterial.
+package material
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"unicode"
+)
+
+// Blend modes.
+const (
+	// Alpha draws over what's there by the alpha of the source, the default.
+	Alpha = "alpha"
+	// Add adds the source, for glows.
+	Add = "add"
+	// Multiply darkens what's there by the source.
+	Multiply = "multiply"
+	// Opaque replaces what's there.
+	Opaque = "opaque"
+)
+
+// Cull modes.
+const (
+	// Back leaves out the triangles facing away, the default.
+	Back = "back"
+	// Front leaves out the triangles facing the camera, for insides.
+	Front = "front"
+	// None draws both sides.
+	None = "none"
+)
+
+// MaxTextures is how many textures a shader can sample.
+const MaxTextures = 4
+
+// Material is what a material file holds, for example:
+//
+//	{
+//		"shader": "glow.kage",
+//		"features": ["FOG"],
+//		"defines": {"STEPS": "8"},
+//		"textures": ["checker.png"],
+//		"uniforms": {"Tint": [1, 0.8, 0.4, 1], "Strength": [2]},
+//		"blend": "add"
+//	}
+type Material struct {
+	// Shader is the Kage file to draw with, relative to the material. Empty draws the textures as they are.
+	Shader string `json:"shader,omitempty"`
+	// Features are the features of the shader the material wants, like FOG, which pick the variant of the shader
+	// compiled for it. See package kage.
+	Features []string `json:"features,omitempty"`
+	// Defines are defined for the shader before its first line.
+	Defines map[string]string `json:"defines,omitempty"`
+	// Textures are the images the shader samples, relative to the material, all of the same size.
+	Textures []string `json:"textures,omitempty"`
+	// Uniforms are the values of the shader's uniforms by name, a single number for a float.
+	Uniforms map[string][]float32 `json:"uniforms,omitempty"`
+	Blend    string               `json:"blend,omitempty"`
+	Cull     string               `json:"cull,omitempty"`
+	// AntiAlias has ebiten smooth the edges of the material's triangles, or not, whatever the renderer does for
+	// the rest. Left out it's up to the renderer.
+	AntiAlias *bool `json:"antialias,omitempty"`
+	// Reflective has the renderer hand the shader the cubemap of the nearest reflection probe as its image and
+	// the normals of the triangles as their color, see probe.kage of package kage. It takes the place of textures.
+	Reflective bool `json:"reflective,omitempty"`
+}
+
+// Parse reads a material from the contents of a file. Fields it doesn't know are errors, they're typos.
+func Parse(src []byte) (*Material, error) {
+	d := json.NewDecoder(bytes.NewReader(src))
+	d.DisallowUnknownFields()
+	m := &Material{}
+	if err := d.Decode(m); err != nil {
+		return nil, err
+	}
+	if m.Blend == "" {
+		m.Blend = Alpha
+	}
+	if m.Cull == "" {
+		m.Cull = Back
+	}
+	if !slices.Contains([]string{Alpha, Add, Multiply, Opaque}, m.Blend) {
+		return nil, fmt.Errorf("unknown blend mode %q", m.Blend)
+	}
+	if !slices.Contains([]string{Back, Front, None}, m.Cull) {
+		return nil, fmt.Errorf("unknown cull mode %q", m.Cull)
+	}
+	if m.Reflective && len(m.Textures) > 0 {
+		return nil, fmt.Errorf("reflective materials sample the cubemap of their probe, they have no textures")
+	}
+	if len(m.Textures) > MaxTextures {
+		return nil, fmt.Errorf("%d textures, a shader samples %d at most", len(m.Textures), MaxTextures)
+	}
+	for _, f := range m.Features {
+		if !feature(f) {
+			return nil, fmt.Errorf("feature %q isn't a name", f)
+		}
+	}
+	for name, v := range m.Uniforms {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("uniform %s has no value", name)
+		}
+	}
+	return m, nil
+}
+
+// feature reports whether `s` can be the name of a feature, made of letters, digits and underscores.
+func feature(s string) bool {
+	for i, c := range s {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return s != ""
+}
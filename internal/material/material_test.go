@@ -0,0 +1,82 @@
+package material
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(`{"shader": "glow.kage", "features": ["FOG", "NORMAL_MAP"], "uniforms": {"Tint": [1, 0, 0, 1]}, "cull": "none"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Blend != Alpha || m.Cull != None || len(m.Uniforms["Tint"]) != 4 || m.AntiAlias != nil {
+		t.Fatalf("parsed %+v", m)
+	}
+	if m, err := Parse([]byte(`{"antialias": false}`)); err != nil || m.AntiAlias == nil || *m.AntiAlias {
+		t.Fatalf("antialias false parsed as %+v: %v", m, err)
+	}
+	for _, src := range []string{
+		`{"blend": "screen"}`,
+		`{"cull": "sideways"}`,
+		`{"textures": ["a", "b", "c", "d", "e"]}`,
+		`{"reflective": true, "textures": ["a"]}`,
+		`{"uniforms": {"Tint": []}}`,
+		`{"features": ["FOG", "NORMAL MAP"]}`,
+		`{"shdaer": "glow.kage"}`,
+		`{`,
+	} {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("%s parsed", src)
+		}
+	}
+}
+
+func TestLibraryReloads(t *testing.T) {
+	dir := t.TempDir()
+	l := Library{Dir: dir, Fallback: fstest.MapFS{
+		"glow.json": {Data: []byte(`{"shader": "glow.kage"}`)},
+		"glow.kage": {Data: []byte(`package main`)},
+	}}
+	a := l.Get("glow")
+	if a.Err != nil || a.Material.Shader != "glow.kage" || a.Version != 1 {
+		t.Fatalf("the fallback material loaded as %+v", a)
+	}
+	if _, err := a.Read("glow.kage"); err != nil {
+		t.Fatalf("the fallback shader didn't read: %v", err)
+	}
+	for _, w := range a.watches {
+		w.Interval = 0
+	}
+	if reloaded := l.Reload(); len(reloaded) != 0 {
+		t.Fatalf("reloaded %v without a change", reloaded)
+	}
+
+	// a file in the directory takes over from the fallback
+	write := func(name, src string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("glow.json", `{"shader": "glow.kage", "blend": "add"}`)
+	if reloaded := l.Reload(); len(reloaded) != 1 || a.Material.Blend != Add || a.Version != 2 {
+		t.Fatalf("reloaded %v, the material is %+v", reloaded, a.Material)
+	}
+	// as does editing what the material read
+	write("glow.kage", `package main // edited`)
+	if reloaded := l.Reload(); len(reloaded) != 1 || a.Version != 3 {
+		t.Fatalf("editing the shader reloaded %v", reloaded)
+	}
+
+	// a broken file keeps the last good material
+	write("glow.json", `{"blend": "screen"}`)
+	if reloaded := l.Reload(); len(reloaded) != 0 || a.Err == nil || a.Material.Blend != Add {
+		t.Fatalf("a broken file reloaded %v, the material is %+v", reloaded, a.Material)
+	}
+
+	if missing := l.Get("missing"); missing.Err == nil || missing.Material != nil {
+		t.Fatalf("a missing material loaded as %+v", missing)
+	}
+}
@@ -0,0 +1,157 @@
+// Package memory keeps track of what the playground holds on to and allocates. Subsystems count the bytes of
+// what they keep, their meshes, textures and buffers, on Counters, and a Tracker reads the runtime's statistics
+// every frame for the heap, the garbage collector and how much every frame allocated, which should be nothing in
+// the hot loop. The memory panel of package app shows both.
+package memory
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter counts the bytes a subsystem holds.
+type Counter struct {
+	name  string
+	bytes atomic.Int64
+	read  func() int64
+}
+
+var (
+	counters_mutex sync.Mutex
+	counters       []*Counter
+)
+
+func register(c *Counter) *Counter {
+	counters_mutex.Lock()
+	defer counters_mutex.Unlock()
+	counters = append(counters, c)
+	return c
+}
+
+// NewCounter makes a counter called `name` which the subsystem adds to and takes from as it allocates and frees.
+func NewCounter(name string) *Counter {
+	return register(&Counter{name: name})
+}
+
+// Gauge makes a counter called `name` which reads its bytes from `read` whenever it's asked, for subsystems which
+// know what they hold without counting it.
+func Gauge(name string, read func() int64) *Counter {
+	return register(&Counter{name: name, read: read})
+}
+
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// Add adds `bytes`, which are negative for what was freed.
+func (c *Counter) Add(bytes int64) {
+	c.bytes.Add(bytes)
+}
+
+// Bytes is how many bytes the subsystem holds.
+func (c *Counter) Bytes() int64 {
+	if c.read != nil {
+		return c.read()
+	}
+	return c.bytes.Load()
+}
+
+// Counters are every counter, by name.
+func Counters() []*Counter {
+	counters_mutex.Lock()
+	defer counters_mutex.Unlock()
+	sorted := slices.Clone(counters)
+	slices.SortFunc(sorted, func(a, b *Counter) int { return strings.Compare(a.name, b.name) })
+	return sorted
+}
+
+// Buffer counts a slice which is kept and grown, like the buffers which spare a hot loop allocating, by its
+// capacity. The zero value counts nothing yet.
+type Buffer struct {
+	capacity int
+}
+
+// Track counts the slice at `capacity` elements of `size` bytes on `c`, after it may have grown.
+func (b *Buffer) Track(c *Counter, capacity int, size uintptr) {
+	if capacity != b.capacity {
+		c.Add(int64(capacity-b.capacity) * int64(size))
+		b.capacity = capacity
+	}
+}
+
+// Frame is what happened to memory during a frame.
+type Frame struct {
+	// Heap is the bytes of live and not yet collected objects, Objects how many there are, and System what the
+	// runtime got from the operating system for the heap.
+	Heap    uint64
+	Objects uint64
+	System  uint64
+	// Allocs are the allocations made during the frame and AllocBytes their bytes.
+	Allocs     uint64
+	AllocBytes uint64
+	// GCs are how many times the garbage collector ran during the frame, and Pause the longest it stopped the world
+	// for.
+	GCs   uint32
+	Pause time.Duration
+}
+
+// history is how many frames a Tracker remembers
+const history = 120
+
+// Tracker reads the runtime's statistics once a frame. Reading them stops the world for a moment, so it's only
+// worth it while they're shown.
+type Tracker struct {
+	last  runtime.MemStats
+	read  bool
+	stats runtime.MemStats
+
+	// frames are the last frames, frames[next] is the oldest once there are history of them
+	frames []Frame
+	next   int
+}
+
+// Sample reads the statistics and returns what happened since the last Sample. The first one has nothing to go by,
+// its allocations are zero.
+func (t *Tracker) Sample() Frame {
+	runtime.ReadMemStats(&t.stats)
+	s := &t.stats
+	f := Frame{Heap: s.HeapAlloc, Objects: s.HeapObjects, System: s.HeapSys}
+	if t.read {
+		f.Allocs = s.Mallocs - t.last.Mallocs
+		f.AllocBytes = s.TotalAlloc - t.last.TotalAlloc
+		f.GCs = s.NumGC - t.last.NumGC
+		// the pauses of the last 256 collections are kept in a ring, the newest at (NumGC+255)%256
+		for i := range min(f.GCs, uint32(len(s.PauseNs))) {
+			pause := time.Duration(s.PauseNs[(s.NumGC-i+255)%256])
+			f.Pause = max(f.Pause, pause)
+		}
+	}
+	t.last, t.read = *s, true
+
+	if len(t.frames) < history {
+		t.frames = append(t.frames, f)
+	} else {
+		t.frames[t.next] = f
+		t.next = (t.next + 1) % history
+	}
+	return f
+}
+
+// Frames are the frames sampled lately, the oldest first.
+func (t *Tracker) Frames() []Frame {
+	return append(slices.Clone(t.frames[t.next:]), t.frames[:t.next]...)
+}
+
+// Peak is the most any one of the frames sampled lately allocated, and the longest pause of the collector during
+// them.
+func (t *Tracker) Peak() (alloc_bytes uint64, pause time.Duration) {
+	for _, f := range t.frames {
+		alloc_bytes = max(alloc_bytes, f.AllocBytes)
+		pause = max(pause, f.Pause)
+	}
+	return alloc_bytes, pause
+}
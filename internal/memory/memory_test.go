@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	c := NewCounter("test buffers")
+	c.Add(100)
+	c.Add(-30)
+	if c.Bytes() != 70 {
+		t.Fatalf("%d bytes, want 70", c.Bytes())
+	}
+	held := int64(5)
+	g := Gauge("test gauge", func() int64 { return held })
+	held = 9
+	if g.Bytes() != 9 {
+		t.Fatalf("the gauge reads %d, want 9", g.Bytes())
+	}
+
+	var names []string
+	for _, c := range Counters() {
+		names = append(names, c.Name())
+	}
+	if len(names) < 2 || names[0] > names[len(names)-1] {
+		t.Fatalf("the counters are %v, want them all by name", names)
+	}
+}
+
+func TestBufferCountsGrowth(t *testing.T) {
+	c := NewCounter("test slice")
+	var b Buffer
+	var s []int32
+	for i := range 1000 {
+		s = append(s, int32(i))
+		b.Track(c, cap(s), 4)
+	}
+	if c.Bytes() != int64(cap(s))*4 {
+		t.Fatalf("%d bytes counted for a slice of %d int32s", c.Bytes(), cap(s))
+	}
+	s = s[:0]
+	b.Track(c, cap(s), 4)
+	if c.Bytes() != int64(cap(s))*4 {
+		t.Fatalf("emptying the slice without shrinking it changed the count to %d", c.Bytes())
+	}
+}
+
+var sink [][]byte
+
+func TestTrackerCountsAllocations(t *testing.T) {
+	var tr Tracker
+	if f := tr.Sample(); f.Allocs != 0 || f.AllocBytes != 0 {
+		t.Fatalf("the first sample has %d allocations", f.Allocs)
+	}
+	for range 10 {
+		sink = append(sink, make([]byte, 1<<16))
+	}
+	f := tr.Sample()
+	if f.Allocs < 10 || f.AllocBytes < 10<<16 {
+		t.Fatalf("%d allocations of %d bytes, want 10 of 64k at least", f.Allocs, f.AllocBytes)
+	}
+	sink = nil
+
+	runtime.GC()
+	if f := tr.Sample(); f.GCs == 0 {
+		t.Fatal("a collection during the frame wasn't counted")
+	}
+	if peak, _ := tr.Peak(); peak < 10<<16 {
+		t.Fatalf("the peak is %d bytes", peak)
+	}
+	if frames := tr.Frames(); len(frames) != 3 {
+		t.Fatalf("%d frames, want 3", len(frames))
+	}
+}
@@ -0,0 +1,350 @@
+package mesh
+
+import (
+	"math"
+	"slices"
+)
+
+// Shape is a flat outline in the XY plane with holes cut in it, like a letter or a logo. Which way round the
+// rings go doesn't matter, and they're closed, the last point joins the first.
+type Shape struct {
+	Outline []vec2
+	Holes   [][]vec2
+}
+
+// Points are the points of the outline followed by those of every hole in turn, what Triangulate indexes.
+func (s Shape) Points() []vec2 {
+	points := slices.Clone(s.Outline)
+	for _, h := range s.Holes {
+		points = append(points, h...)
+	}
+	return points
+}
+
+// rings are the indices of the outline, counter-clockwise, and of the holes, clockwise, into Points.
+func (s Shape) rings() [][]int {
+	points := s.Points()
+	ring := func(from, n int, clockwise bool) []int {
+		r := make([]int, n)
+		for i := range r {
+			r[i] = from + i
+		}
+		if (signed_area(points, r) < 0) != clockwise {
+			slices.Reverse(r)
+		}
+		return r
+	}
+	rings := [][]int{ring(0, len(s.Outline), false)}
+	from := len(s.Outline)
+	for _, h := range s.Holes {
+		rings = append(rings, ring(from, len(h), true))
+		from += len(h)
+	}
+	return rings
+}
+
+// Triangulate cuts the shape into triangles counter-clockwise seen from +z, indexing Points. Each hole is joined
+// to the outline by a bridge to the nearest point it can see, and the ring that makes is cut up by ear clipping.
+// Rings which cross themselves or each other come out wrong but never hang.
+func (s Shape) Triangulate() [][3]int {
+	points := s.Points()
+	rings := s.rings()
+	if len(rings[0]) < 3 {
+		return nil
+	}
+
+	// holes are bridged from the rightmost in, the way most ear clippers do it
+	holes := slices.Clone(rings[1:])
+	rightmost := func(r []int) int {
+		best := 0
+		for i, p := range r {
+			if points[p].X() > points[r[best]].X() {
+				best = i
+			}
+		}
+		return best
+	}
+	slices.SortFunc(holes, func(a, b []int) int {
+		return -compare(points[a[rightmost(a)]].X(), points[b[rightmost(b)]].X())
+	})
+
+	polygon := slices.Clone(rings[0])
+	for i, hole := range holes {
+		if len(hole) < 3 {
+			continue
+		}
+		m := rightmost(hole)
+		at := bridge(points, polygon, hole[m], holes[i:])
+		if at < 0 {
+			continue
+		}
+		joined := slices.Clone(polygon[:at+1])
+		for j := range len(hole) + 1 {
+			joined = append(joined, hole[(m+j)%len(hole)])
+		}
+		polygon = append(joined, polygon[at:]...)
+	}
+	return clip_ears(points, polygon)
+}
+
+// bridge is where in `polygon` the point `from` of a hole can be joined to, the nearest point of it which the
+// straight line from `from` reaches without crossing the polygon or one of `holes`, or -1.
+func bridge(points []vec2, polygon []int, from int, holes [][]int) int {
+	order := make([]int, len(polygon))
+	for i := range order {
+		order[i] = i
+	}
+	distance := func(i int) float { return points[polygon[i]].Sub(points[from]).LenSqr() }
+	slices.SortStableFunc(order, func(a, b int) int { return compare(distance(a), distance(b)) })
+
+	crosses := func(a, b vec2, ring []int) bool {
+		for i := range ring {
+			if segments_cross(a, b, points[ring[i]], points[ring[(i+1)%len(ring)]]) {
+				return true
+			}
+		}
+		return false
+	}
+candidates:
+	for _, i := range order {
+		a, b := points[from], points[polygon[i]]
+		if crosses(a, b, polygon) {
+			continue
+		}
+		for _, h := range holes {
+			if crosses(a, b, h) {
+				continue candidates
+			}
+		}
+		return i
+	}
+	return -1
+}
+
+// clip_ears cuts the counter-clockwise ring `polygon` into triangles by cutting off ears, corners whose triangle
+// has no other point of the ring in it, one at a time.
+func clip_ears(points []vec2, polygon []int) [][3]int {
+	var triangles [][3]int
+	v := slices.Clone(polygon)
+	for len(v) > 3 {
+		n := len(v)
+		ear := -1
+		for i := range n {
+			a, b, c := v[(i+n-1)%n], v[i], v[(i+1)%n]
+			if cross2(points[a], points[b], points[c]) <= 0 {
+				continue
+			}
+			if !contains_any(points, v, a, b, c) {
+				ear = i
+				break
+			}
+		}
+		if ear < 0 {
+			// nothing is an ear in a ring which crosses itself, drop its flattest corner and go on
+			flattest := float(math.Inf(1))
+			for i := range n {
+				if f := abs(cross2(points[v[(i+n-1)%n]], points[v[i]], points[v[(i+1)%n]])); f < flattest {
+					ear, flattest = i, f
+				}
+			}
+		}
+		a, b, c := v[(ear+n-1)%n], v[ear], v[(ear+1)%n]
+		if cross2(points[a], points[b], points[c]) > 0 {
+			triangles = append(triangles, [3]int{a, b, c})
+		}
+		v = slices.Delete(v, ear, ear+1)
+	}
+	if len(v) == 3 && cross2(points[v[0]], points[v[1]], points[v[2]]) > 0 {
+		triangles = append(triangles, [3]int{v[0], v[1], v[2]})
+	}
+	return triangles
+}
+
+// contains_any reports whether a point of `ring` other than the corners is inside or on the triangle `a`, `b`, `c`.
+// The ends of bridges are the same point twice, they count as the corner they're at.
+func contains_any(points []vec2, ring []int, a, b, c int) bool {
+	pa, pb, pc := points[a], points[b], points[c]
+	for _, i := range ring {
+		p := points[i]
+		if p == pa || p == pb || p == pc {
+			continue
+		}
+		if cross2(pa, pb, p) >= 0 && cross2(pb, pc, p) >= 0 && cross2(pc, pa, p) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// cross2 is twice the signed area of the triangle `a`, `b`, `c`, positive when it's counter-clockwise.
+func cross2(a, b, c vec2) float {
+	return (b.X()-a.X())*(c.Y()-a.Y()) - (b.Y()-a.Y())*(c.X()-a.X())
+}
+
+func signed_area(points []vec2, ring []int) float {
+	var area float
+	for i := range ring {
+		a, b := points[ring[i]], points[ring[(i+1)%len(ring)]]
+		area += a.X()*b.Y() - b.X()*a.Y()
+	}
+	return area / 2
+}
+
+// segments_cross reports whether the segments `a`-`b` and `c`-`d` cross, touching at an end doesn't count.
+func segments_cross(a, b, c, d vec2) bool {
+	if a == c || a == d || b == c || b == d {
+		return false
+	}
+	d1, d2 := cross2(a, b, c), cross2(a, b, d)
+	d3, d4 := cross2(c, d, a), cross2(c, d, b)
+	return (d1 > 0) != (d2 > 0) && (d3 > 0) != (d4 > 0) && d1 != 0 && d2 != 0 && d3 != 0 && d4 != 0
+}
+
+func compare(a, b float) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Extrude makes a prism of `depth` out of the shape, centered on the origin: its front faces +z, its back -z and
+// its sides run along z. The front and the back map the texture over the box around the shape, the sides wrap
+// it around each ring once from front to back. The normals are smooth where the sides bend less than DefaultCrease,
+// so curves flattened into many points come out round.
+func Extrude(s Shape, depth float) *Mesh {
+	shape := s.Points()
+	h := depth / 2
+	m := &Mesh{}
+	for _, p := range shape {
+		m.Points = append(m.Points, vec3{p.X(), p.Y(), h})
+	}
+	for _, p := range shape {
+		m.Points = append(m.Points, vec3{p.X(), p.Y(), -h})
+	}
+	back := uint16(len(shape))
+
+	// the caps
+	m.Texcoords = cap_texcoords(shape)
+	for _, t := range s.Triangulate() {
+		a, b, c := uint16(t[0]), uint16(t[1]), uint16(t[2])
+		m.Triangles = append(m.Triangles,
+			Triangle{P1: a, P2: b, P3: c, T1: a, T2: b, T3: c},
+			Triangle{P1: back + c, P2: back + b, P3: back + a, T1: c, T2: b, T3: a},
+		)
+	}
+
+	// the sides, outlines counter-clockwise and holes clockwise face out with the same winding
+	for _, ring := range s.rings() {
+		var perimeter float
+		for i := range ring {
+			perimeter += shape[ring[(i+1)%len(ring)]].Sub(shape[ring[i]]).Len()
+		}
+		var along float
+		for i := range ring {
+			p, q := uint16(ring[i]), uint16(ring[(i+1)%len(ring)])
+			u0 := along / max(perimeter, 1e-6)
+			along += shape[q].Sub(shape[p]).Len()
+			u1 := along / max(perimeter, 1e-6)
+			t := uint16(len(m.Texcoords))
+			m.Texcoords = append(m.Texcoords, vec2{u0, 0}, vec2{u1, 0}, vec2{u0, 1}, vec2{u1, 1})
+			m.Triangles = append(m.Triangles,
+				Triangle{P1: back + p, P2: back + q, P3: q, T1: t + 2, T2: t + 3, T3: t + 1},
+				Triangle{P1: back + p, P2: q, P3: p, T1: t + 2, T2: t + 1, T3: t},
+			)
+		}
+	}
+
+	m.ComputeNormals(DefaultCrease)
+	m.ComputeBounds()
+	m.ComputeTangents()
+	return m
+}
+
+// Fill makes a flat mesh of the shape at z = 0 facing +z, the front of Extrude without the rest. The texture is
+// mapped over the box around the shape.
+func Fill(s Shape) *Mesh {
+	shape := s.Points()
+	m := &Mesh{Texcoords: cap_texcoords(shape)}
+	for _, p := range shape {
+		m.Points = append(m.Points, vec3{p.X(), p.Y(), 0})
+	}
+	for _, t := range s.Triangulate() {
+		a, b, c := uint16(t[0]), uint16(t[1]), uint16(t[2])
+		m.Triangles = append(m.Triangles, Triangle{P1: a, P2: b, P3: c, T1: a, T2: b, T3: c})
+	}
+	m.Normals = []vec3{{0, 0, 1}}
+	m.ComputeBounds()
+	m.ComputeTangents()
+	return m
+}
+
+// cap_texcoords map the box around `shape` to the texture, with v down the image and y up.
+func cap_texcoords(shape []vec2) []vec2 {
+	if len(shape) == 0 {
+		return nil
+	}
+	lo, hi := shape[0], shape[0]
+	for _, p := range shape {
+		lo, hi = vec2{min(lo.X(), p.X()), min(lo.Y(), p.Y())}, vec2{max(hi.X(), p.X()), max(hi.Y(), p.Y())}
+	}
+	size := vec2{max(hi.X()-lo.X(), 1e-6), max(hi.Y()-lo.Y(), 1e-6)}
+	texcoords := make([]vec2, len(shape))
+	for i, p := range shape {
+		texcoords[i] = vec2{(p.X() - lo.X()) / size.X(), (hi.Y() - p.Y()) / size.Y()}
+	}
+	return texcoords
+}
+
+// Lathe spins `profile` around the y axis in `segments` steps, x of its points being how far from the axis they
+// are and y how high up. A profile from bottom to top makes a surface facing out, like a vase; one which starts
+// and ends on the axis is closed. The texture wraps around once and runs along the profile from top to bottom.
+func Lathe(profile []vec2, segments int) *Mesh {
+	segments = max(segments, 3)
+	m := &Mesh{}
+	if len(profile) < 2 {
+		return m
+	}
+
+	var length float
+	lengths := []float{0}
+	for i := 1; i < len(profile); i++ {
+		length += profile[i].Sub(profile[i-1]).Len()
+		lengths = append(lengths, length)
+	}
+	for i, p := range profile {
+		for segment := range segments {
+			phi := 2 * math.Pi * float64(segment) / float64(segments)
+			m.Points = append(m.Points, vec3{p.X() * float(math.Cos(phi)), p.Y(), -p.X() * float(math.Sin(phi))})
+		}
+		// the seam has its own column of texcoords, like UVSphere
+		for segment := range segments + 1 {
+			m.Texcoords = append(m.Texcoords, vec2{float(segment) / float(segments), 1 - lengths[i]/max(length, 1e-6)})
+		}
+	}
+
+	point := func(i, segment int) uint16 { return uint16(i*segments + segment%segments) }
+	texcoord := func(i, segment int) uint16 { return uint16(i*(segments+1) + segment) }
+	for i := range len(profile) - 1 {
+		for segment := range segments {
+			a, b := point(i, segment), point(i, segment+1)
+			c, d := point(i+1, segment), point(i+1, segment+1)
+			ta, tb := texcoord(i, segment), texcoord(i, segment+1)
+			tc, td := texcoord(i+1, segment), texcoord(i+1, segment+1)
+			// points on the axis are where a whole ring of triangles comes to a point
+			if profile[i].X() != 0 {
+				m.Triangles = append(m.Triangles, Triangle{P1: a, P2: b, P3: c, T1: ta, T2: tb, T3: tc})
+			}
+			if profile[i+1].X() != 0 {
+				m.Triangles = append(m.Triangles, Triangle{P1: b, P2: d, P3: c, T1: tb, T2: td, T3: tc})
+			}
+		}
+	}
+
+	m.ComputeNormals(DefaultCrease)
+	m.ComputeBounds()
+	m.ComputeTangents()
+	return m
+}
@@ -0,0 +1,67 @@
+package mesh
+
+import (
+	"math"
+	"testing"
+)
+
+// volume is the volume a closed mesh encloses, the sum of the tetrahedra from the origin to its triangles.
+func volume(m *Mesh) float {
+	var v float
+	for _, t := range m.Triangles {
+		v += m.Points[t.P1].Dot(m.Points[t.P2].Cross(m.Points[t.P3])) / 6
+	}
+	return v
+}
+
+// frame is a square 4 across with a square hole 2 across in it, off center, the hole wound the same way.
+var frame = Shape{
+	Outline: []vec2{{-2, -2}, {2, -2}, {2, 2}, {-2, 2}},
+	Holes:   [][]vec2{{{-1, -1}, {1, -1}, {1, 0.5}, {-1, 0.5}}},
+}
+
+func TestTriangulateHoles(t *testing.T) {
+	points := frame.Points()
+	var area float
+	for i, tri := range frame.Triangulate() {
+		a := cross2(points[tri[0]], points[tri[1]], points[tri[2]]) / 2
+		if a <= 0 {
+			t.Fatalf("triangle %d is wound clockwise", i)
+		}
+		area += a
+	}
+	if area < 13-1e-4 || area > 13+1e-4 {
+		t.Fatalf("the frame is triangulated into %v square units, want 13", area)
+	}
+
+	// a U, which has corners that aren't ears
+	u := Shape{Outline: []vec2{{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}}}
+	if tris := u.Triangulate(); len(tris) != 6 {
+		t.Fatalf("a U of 8 points is %d triangles, want 6", len(tris))
+	}
+}
+
+func TestExtrudeIsClosed(t *testing.T) {
+	m := Extrude(frame, 0.5)
+	if v := volume(m); v < 6.5-1e-3 || v > 6.5+1e-3 {
+		t.Fatalf("the extruded frame has a volume of %v, want 6.5", v)
+	}
+	// the sides of a square are hard, its front flat
+	for i, tri := range m.Triangles {
+		n := m.Normals[tri.N1]
+		p1, p2, p3 := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+		if face := p2.Sub(p1).Cross(p3.Sub(p1)).Normalize(); n.Dot(face) < 0.999 {
+			t.Fatalf("triangle %d faces %v but its normal is %v", i, face, n)
+		}
+	}
+}
+
+func TestLatheCylinder(t *testing.T) {
+	const segments = 32
+	m := Lathe([]vec2{{0, -1}, {1, -1}, {1, 1}, {0, 1}}, segments)
+	want := float(segments) / 2 * float(math.Sin(2*math.Pi/segments)) * 2
+	if v := volume(m); v < want-1e-3 || v > want+1e-3 {
+		t.Fatalf("a lathed cylinder has a volume of %v, want %v", v, want)
+	}
+	faces_outwards(t, "lathe", Lathe([]vec2{{0, -1}, {0.7, -0.7}, {1, 0}, {0.7, 0.7}, {0, 1}}, 12))
+}
@@ -0,0 +1,58 @@
+package mesh
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Merge makes one mesh of `meshes`, one draw of their triangles instead of one each. Materials of the same name
+// are shared. Normals, tangents and colors are kept when every mesh has them. It fails when the meshes have more points,
+// texcoords or normals between them than a mesh can index.
+func Merge(meshes ...*Mesh) (*Mesh, error) {
+	m := &Mesh{}
+	normals, tangents, colors := true, true, true
+	for _, part := range meshes {
+		normals = normals && len(part.Normals) > 0
+		tangents = tangents && len(part.Tangents) == 3*len(part.Triangles)
+		colors = colors && len(part.Colors) == 3*len(part.Triangles)
+	}
+	for _, part := range meshes {
+		if len(m.Points)+len(part.Points) > math.MaxUint16+1 || len(m.Texcoords)+len(part.Texcoords) > math.MaxUint16+1 ||
+			normals && len(m.Normals)+len(part.Normals) > math.MaxUint16+1 {
+			return nil, fmt.Errorf("merging %d meshes: more than %d points", len(meshes), math.MaxUint16+1)
+		}
+		points, texcoords, normal := uint16(len(m.Points)), uint16(len(m.Texcoords)), uint16(len(m.Normals))
+		materials := make([]uint16, len(part.Materials))
+		for i, name := range part.Materials {
+			index := slices.Index(m.Materials, name)
+			if index < 0 {
+				index = len(m.Materials)
+				m.Materials = append(m.Materials, name)
+			}
+			materials[i] = uint16(index)
+		}
+		for _, t := range part.Triangles {
+			t.P1, t.P2, t.P3 = t.P1+points, t.P2+points, t.P3+points
+			t.T1, t.T2, t.T3 = t.T1+texcoords, t.T2+texcoords, t.T3+texcoords
+			t.N1, t.N2, t.N3 = t.N1+normal, t.N2+normal, t.N3+normal
+			if int(t.Material) < len(materials) {
+				t.Material = materials[t.Material]
+			}
+			m.Triangles = append(m.Triangles, t)
+		}
+		m.Points = append(m.Points, part.Points...)
+		m.Texcoords = append(m.Texcoords, part.Texcoords...)
+		if normals {
+			m.Normals = append(m.Normals, part.Normals...)
+		}
+		if tangents {
+			m.Tangents = append(m.Tangents, part.Tangents...)
+		}
+		if colors {
+			m.Colors = append(m.Colors, part.Colors...)
+		}
+	}
+	m.ComputeBounds()
+	return m, nil
+}
@@ -0,0 +1,144 @@
+// Package mesh holds the triangle meshes fed to the pipeline and the loaders that produce them.
+package mesh
+
+import (
+	"math"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
+)
+
+// Triangle indexes three points, three texture coordinates and three normals of a Mesh.
+type Triangle struct {
+	P1, P2, P3 uint16
+	T1, T2, T3 uint16
+	// N1, N2 and N3 index Mesh.Normals, they're meaningless for meshes without normals.
+	N1, N2, N3 uint16
+	// Material indexes Mesh.Materials, it's meaningless for meshes without materials.
+	Material uint16
+}
+
+// Mesh is an indexed triangle mesh. Points and texcoords are indexed separately since that's how
+// they come out of most modelling tools.
+type Mesh struct {
+	Triangles []Triangle
+	Points    []vec3
+	Texcoords []vec2
+	// Normals are the normals of the corners of triangles, when the mesh has them.
+	Normals []vec3
+	// Tangents are the tangents of the corners of the triangles, the three of triangle i from 3*i, with the
+	// handedness of the bitangent in w. See ComputeTangents.
+	Tangents []vec4
+	// Colors are the light baked into the corners of the triangles, the three of triangle i from 3*i, when the
+	// mesh has them. See package bake.
+	Colors []vec3
+	// Materials are the names of the materials triangles refer to, e.g. from usemtl statements.
+	Materials []string
+
+	// SoA is an optional copy of Points in a structure-of-arrays layout, see StoreSoA.
+	SoA *Points
+
+	// Sphere bounds every point, it's only valid after ComputeBounds.
+	Sphere  Sphere
+	bounded bool
+
+	id uint32
+}
+
+var last_id atomic.Uint32
+
+// ID returns a number unique to this mesh for the lifetime of the process, so what was drawn can be mapped back
+// to the mesh it came from across frames. IDs are assigned on first use and are never 0.
+func (m *Mesh) ID() uint32 {
+	if m.id == 0 {
+		m.id = NewID()
+	}
+	return m.id
+}
+
+// NewID returns a new ID from the same counter as meshes, for other things drawn through the pipeline which need
+// telling apart from them.
+func NewID() uint32 {
+	return last_id.Add(1)
+}
+
+// Sphere is a bounding sphere.
+type Sphere struct {
+	Center vec3
+	Radius float
+}
+
+// ComputeBounds fits Sphere around the points of the mesh. Loaders call it, meshes built by hand have to call it
+// themselves, and again whenever Points change. The sphere is centered on the box around the points, which is not
+// the tightest sphere but close enough to reject meshes outside the view.
+func (m *Mesh) ComputeBounds() {
+	if len(m.Points) == 0 {
+		m.Sphere = Sphere{}
+		m.bounded = false
+		return
+	}
+
+	lo, hi := m.Points[0], m.Points[0]
+	for _, p := range m.Points[1:] {
+		for i := range p {
+			lo[i] = min(lo[i], p[i])
+			hi[i] = max(hi[i], p[i])
+		}
+	}
+
+	center := lo.Add(hi).Mul(0.5)
+	var radius_sq float
+	for _, p := range m.Points {
+		radius_sq = max(radius_sq, p.Sub(center).LenSqr())
+	}
+
+	m.Sphere = Sphere{
+		Center: center,
+		Radius: float(math.Sqrt(float64(radius_sq))),
+	}
+	m.bounded = true
+}
+
+// Transform moves every point of the mesh by `t`, turns its normals and tangents to match and updates the bounds.
+// The pipeline has no model matrix, meshes are placed in the world by transforming them once.
+func (m *Mesh) Transform(t mgl32.Mat4) {
+	for i, p := range m.Points {
+		m.Points[i] = mgl32.TransformCoordinate(p, t)
+	}
+	m.transform_directions(t)
+	if m.SoA != nil {
+		m.StoreSoA()
+	}
+	m.ComputeBounds()
+}
+
+// Bytes is the memory of the mesh's points, texture coordinates, triangles and what else it has, by their lengths.
+func (m *Mesh) Bytes() int {
+	n := len(m.Triangles)*int(unsafe.Sizeof(Triangle{})) + len(m.Points)*12 + len(m.Texcoords)*8 +
+		len(m.Normals)*12 + len(m.Tangents)*16 + len(m.Colors)*12
+	if m.SoA != nil {
+		n += len(m.SoA.X) * 12
+	}
+	return n
+}
+
+// Bounded reports whether Sphere is valid.
+func (m *Mesh) Bounded() bool {
+	return m.bounded
+}
+
+// ensure_texcoords guarantees every texcoord index of the mesh is valid. Meshes without texture
+// coordinates get a single (0, 0) texcoord which every triangle points at.
+func (m *Mesh) ensure_texcoords() {
+	if len(m.Texcoords) == 0 {
+		m.Texcoords = append(m.Texcoords, vec2{})
+	}
+}
@@ -0,0 +1,179 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// DefaultCrease is the crease angle LoadOBJ smooths files without normals by, 30 degrees like Blender's auto smooth.
+const DefaultCrease = float(math.Pi / 6)
+
+// ComputeNormals replaces Normals with smooth ones for meshes which came without, STL files or minimal OBJs. The
+// faces around each point are gathered into creases, a face going into the first one whose first face's normal is
+// within `crease` radians of its own, and a corner takes the faces of its crease weighted by their angle at the
+// point, so the edges between faces bent further than that stay hard. A crease of 0 leaves every face flat and one of
+// π smooths everything. Points are matched by position, so seams in the texcoords don't crease.
+func (m *Mesh) ComputeNormals(crease float) {
+	// a point sums the faces around it into each of its creases, and all of them for faces without area, which have
+	// no normal to compare and take all their neighbours'
+	type creased struct {
+		face, sum vec3
+		index     int32
+	}
+	type point struct {
+		creases []creased
+		all     vec3
+		index   int32
+	}
+	// a corner is in crease `crease` of its point, -1 for a face without area
+	type corner struct {
+		point  *point
+		crease int32
+	}
+	threshold := float(math.Cos(float64(crease)))
+	faces := make([]vec3, len(m.Triangles))
+	points := make(map[vec3]*point)
+	corners := make([][3]corner, len(m.Triangles))
+	for i, t := range m.Triangles {
+		p := [3]vec3{m.Points[t.P1], m.Points[t.P2], m.Points[t.P3]}
+		if n := p[1].Sub(p[0]).Cross(p[2].Sub(p[0])); n.Len() > 0 {
+			faces[i] = n.Normalize()
+		}
+		face := faces[i]
+		for j := range 3 {
+			pt := points[p[j]]
+			if pt == nil {
+				pt = &point{index: -1}
+				points[p[j]] = pt
+			}
+			weighted := face.Mul(angle_between(p[(j+1)%3].Sub(p[j]), p[(j+2)%3].Sub(p[j])))
+			pt.all = pt.all.Add(weighted)
+			c := corner{pt, -1}
+			if face.Len() > 0 && crease > 0 {
+				// the first faces of the creases are further than `crease` apart, so only so many fit around a point
+				for k := range pt.creases {
+					if pt.creases[k].face.Dot(face) >= threshold {
+						c.crease = int32(k)
+						break
+					}
+				}
+				if c.crease < 0 {
+					c.crease = int32(len(pt.creases))
+					pt.creases = append(pt.creases, creased{face: face, index: -1})
+				}
+				pt.creases[c.crease].sum = pt.creases[c.crease].sum.Add(weighted)
+			}
+			corners[i][j] = c
+		}
+	}
+
+	// normals a rounding error apart are shared
+	type rounded [3]int32
+	indices := make(map[rounded]uint16)
+	var nearby map[[3]int32][]uint16
+	m.Normals = m.Normals[:0]
+	index := func(n vec3) uint16 {
+		r := rounded{int32(math.Round(float64(n[0]) * 1e5)), int32(math.Round(float64(n[1]) * 1e5)), int32(math.Round(float64(n[2]) * 1e5))}
+		i, ok := indices[r]
+		switch {
+		case ok:
+		case len(m.Normals) > math.MaxUint16:
+			// triangles index no more normals than a uint16 counts, past which the nearest of them stands in
+			i = m.nearest_normal(n, &nearby)
+			indices[r] = i
+		default:
+			i = uint16(len(m.Normals))
+			indices[r] = i
+			m.Normals = append(m.Normals, n)
+		}
+		return i
+	}
+	// normal is the index of the normal of sum, or of `face` when it sums to nothing
+	normal := func(sum, face vec3) uint16 {
+		switch {
+		case sum.Len() > 1e-12:
+			return index(sum.Normalize())
+		case face.Len() > 0:
+			return index(face)
+		}
+		return index(vec3{0, 1, 0})
+	}
+
+	for i := range m.Triangles {
+		face := faces[i]
+		var n [3]uint16
+		for j, c := range corners[i] {
+			switch {
+			case face.Len() == 0:
+				if c.point.index < 0 {
+					c.point.index = int32(normal(c.point.all, face))
+				}
+				n[j] = uint16(c.point.index)
+			case c.crease < 0:
+				// a crease of 0 or less
+				n[j] = index(face)
+			default:
+				cr := &c.point.creases[c.crease]
+				if cr.index < 0 {
+					cr.index = int32(normal(cr.sum, face))
+				}
+				n[j] = uint16(cr.index)
+			}
+		}
+		t := &m.Triangles[i]
+		t.N1, t.N2, t.N3 = n[0], n[1], n[2]
+	}
+}
+
+// nearest_normal is the index of the normal nearest `n`. It's looked for among those in a cell of `grid` a twentieth
+// wide around it, made the first time, and only when none are there among them all.
+func (m *Mesh) nearest_normal(n vec3, grid *map[[3]int32][]uint16) uint16 {
+	cell := func(v vec3) [3]int32 {
+		return [3]int32{int32(math.Round(float64(v[0]) * 20)), int32(math.Round(float64(v[1]) * 20)), int32(math.Round(float64(v[2]) * 20))}
+	}
+	if *grid == nil {
+		*grid = make(map[[3]int32][]uint16)
+		for i, normal := range m.Normals {
+			c := cell(normal)
+			(*grid)[c] = append((*grid)[c], uint16(i))
+		}
+	}
+	best, closest := uint16(0), float(-2)
+	c := cell(n)
+	for x := c[0] - 1; x <= c[0]+1; x++ {
+		for y := c[1] - 1; y <= c[1]+1; y++ {
+			for z := c[2] - 1; z <= c[2]+1; z++ {
+				for _, i := range (*grid)[[3]int32{x, y, z}] {
+					if d := m.Normals[i].Dot(n); d > closest {
+						best, closest = i, d
+					}
+				}
+			}
+		}
+	}
+	if closest == -2 {
+		for i, normal := range m.Normals {
+			if d := normal.Dot(n); d > closest {
+				best, closest = uint16(i), d
+			}
+		}
+	}
+	return best
+}
+
+// transform_directions turns Normals and Tangents along with points transformed by `t`.
+func (m *Mesh) transform_directions(t mgl32.Mat4) {
+	turn := t.Mat3()
+	normal := turn.Inv().Transpose()
+	for i, n := range m.Normals {
+		if n = normal.Mul3x1(n); n.Len() > 0 {
+			m.Normals[i] = n.Normalize()
+		}
+	}
+	for i, tangent := range m.Tangents {
+		if d := turn.Mul3x1(tangent.Vec3()); d.Len() > 0 {
+			m.Tangents[i] = d.Normalize().Vec4(tangent.W())
+		}
+	}
+}
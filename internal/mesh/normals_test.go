@@ -0,0 +1,96 @@
+package mesh
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestBoxEdgesStayHard(t *testing.T) {
+	m := Box(vec3{1, 2, 3})
+	m.ComputeNormals(DefaultCrease)
+	if len(m.Normals) != 6 {
+		t.Fatalf("a box has %d normals, want one a side", len(m.Normals))
+	}
+	m.ComputeNormals(math.Pi)
+	// smoothed all round, every corner of the box points out along its diagonal
+	if len(m.Normals) != 8 {
+		t.Fatalf("a smoothed box has %d normals, want one a corner", len(m.Normals))
+	}
+}
+
+func TestSphereIsSmooth(t *testing.T) {
+	m := UVSphere(2, 16, 24)
+	m.ComputeNormals(DefaultCrease)
+	for i, tri := range m.Triangles {
+		for j, p := range [3]uint16{tri.P1, tri.P2, tri.P3} {
+			n := m.Normals[[3]uint16{tri.N1, tri.N2, tri.N3}[j]]
+			if d := n.Dot(m.Points[p].Normalize()); d < 0.99 {
+				t.Fatalf("corner %d of triangle %d points %v, away from the center by %v", j, i, n, d)
+			}
+		}
+	}
+}
+
+func TestTransformTurnsNormals(t *testing.T) {
+	m := Plane(2)
+	m.ComputeNormals(DefaultCrease)
+	m.ComputeTangents()
+	m.Transform(mgl32.HomogRotate3DZ(math.Pi / 2).Mul4(mgl32.Scale3D(1, 3, 1)))
+	if m.Normals[0].Sub(vec3{-1, 0, 0}).Len() > 1e-5 || m.Tangents[0].Sub(vec4{0, 1, 0, -1}).Len() > 1e-5 {
+		t.Fatalf("the plane turned on its side faces %v with a tangent of %v", m.Normals[0], m.Tangents[0])
+	}
+}
+
+func TestNormalsFitTheirIndices(t *testing.T) {
+	// a bumpy grid left flat has a normal a face, twice as many as a triangle can index
+	const size = 256
+	m := &Mesh{}
+	for z := range size {
+		for x := range size {
+			m.Points = append(m.Points, vec3{float(x), float(math.Sin(float64(x*x+z*7)) * 0.4), float(z)})
+		}
+	}
+	for z := range size - 1 {
+		for x := range size - 1 {
+			i := uint16(z*size + x)
+			m.Triangles = append(m.Triangles, Triangle{P1: i, P2: i + size, P3: i + 1}, Triangle{P1: i + 1, P2: i + size, P3: i + size + 1})
+		}
+	}
+	m.ComputeNormals(0)
+	if len(m.Normals) > math.MaxUint16+1 {
+		t.Fatalf("%d normals", len(m.Normals))
+	}
+	for i, tri := range m.Triangles {
+		a, b, c := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+		face := b.Sub(a).Cross(c.Sub(a)).Normalize()
+		for _, n := range [3]uint16{tri.N1, tri.N2, tri.N3} {
+			if int(n) >= len(m.Normals) {
+				t.Fatalf("triangle %d indexes normal %d of %d", i, n, len(m.Normals))
+			}
+			if d := m.Normals[n].Dot(face); d < 0.99 {
+				t.Fatalf("triangle %d faces %v but has a normal of %v", i, face, m.Normals[n])
+			}
+		}
+	}
+}
+
+func TestFanApexIsSmooth(t *testing.T) {
+	// a low cone of many faces, all of which meet at its apex
+	const sides = 32768
+	m := &Mesh{Points: []vec3{{0, 0.1, 0}}}
+	for i := range sides {
+		a := float64(i) / sides * 2 * math.Pi
+		m.Points = append(m.Points, vec3{float(math.Cos(a)), 0, float(-math.Sin(a))})
+	}
+	for i := range sides {
+		m.Triangles = append(m.Triangles, Triangle{P1: 0, P2: uint16(1 + i), P3: uint16(1 + (i+1)%sides)})
+	}
+	m.ComputeNormals(DefaultCrease)
+	for i, tri := range m.Triangles {
+		if n := m.Normals[tri.N1]; n.Sub(vec3{0, 1, 0}).Len() > 1e-3 {
+			t.Fatalf("the apex of triangle %d points %v", i, n)
+		}
+	}
+}
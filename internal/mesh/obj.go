@@ -0,0 +1,151 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// LoadOBJ parses a Wavefront OBJ file. Only points, texture coordinates, normals, faces and the names of the
+// materials faces use are read, every other statement (groups, material libraries, ...) is skipped. Faces with
+// more than three corners are triangulated as a fan. Files without normals are smoothed by DefaultCrease, and the
+// tangents are computed once the mesh is read.
+func LoadOBJ(src []byte) (*Mesh, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	mesh := &Mesh{}
+	var material uint16
+
+	for line_number := 1; scanner.Scan(); line_number++ {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			v, err := parse_floats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad vertex: %w", line_number, err)
+			}
+			mesh.Points = append(mesh.Points, vec3{v[0], v[1], v[2]})
+		case "vt":
+			v, err := parse_floats(fields[1:], 2)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad texcoord: %w", line_number, err)
+			}
+			mesh.Texcoords = append(mesh.Texcoords, vec2{v[0], v[1]})
+		case "vn":
+			v, err := parse_floats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad normal: %w", line_number, err)
+			}
+			mesh.Normals = append(mesh.Normals, vec3{v[0], v[1], v[2]})
+		case "usemtl":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: usemtl without a name", line_number)
+			}
+			index := slices.Index(mesh.Materials, fields[1])
+			if index < 0 {
+				index = len(mesh.Materials)
+				mesh.Materials = append(mesh.Materials, fields[1])
+			}
+			material = uint16(index)
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("line %d: bad face: expected at least 3 corners", line_number)
+			}
+			corners := make([][3]uint16, len(fields)-1)
+			for i, field := range fields[1:] {
+				corner, err := parse_corner(field)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: bad face: %w", line_number, err)
+				}
+				corners[i] = corner
+			}
+			for i := 2; i < len(corners); i++ {
+				mesh.Triangles = append(mesh.Triangles, Triangle{
+					P1: corners[0][0],
+					P2: corners[i-1][0],
+					P3: corners[i][0],
+					T1: corners[0][1],
+					T2: corners[i-1][1],
+					T3: corners[i][1],
+					N1: corners[0][2],
+					N2: corners[i-1][2],
+					N3: corners[i][2],
+
+					Material: material,
+				})
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	mesh.ensure_texcoords()
+
+	for i, t := range mesh.Triangles {
+		if int(max(t.P1, t.P2, t.P3)) >= len(mesh.Points) {
+			return nil, fmt.Errorf("triangle %d: point index out of range", i)
+		}
+		if int(max(t.T1, t.T2, t.T3)) >= len(mesh.Texcoords) {
+			return nil, fmt.Errorf("triangle %d: texcoord index out of range", i)
+		}
+		if len(mesh.Normals) > 0 && int(max(t.N1, t.N2, t.N3)) >= len(mesh.Normals) {
+			return nil, fmt.Errorf("triangle %d: normal index out of range", i)
+		}
+	}
+
+	if len(mesh.Normals) == 0 {
+		mesh.ComputeNormals(DefaultCrease)
+	}
+	mesh.ComputeBounds()
+	mesh.ComputeTangents()
+
+	return mesh, nil
+}
+
+func parse_floats(fields []string, n int) ([]float, error) {
+	if len(fields) < n {
+		return nil, fmt.Errorf("expected %d components, got %d", n, len(fields))
+	}
+	values := make([]float, n)
+	for i := range values {
+		v, err := strconv.ParseFloat(fields[i], 32)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("component %d is not finite", i)
+		}
+		values[i] = float(v)
+	}
+	return values, nil
+}
+
+// parse_corner parses a face corner such as "1", "1/2", "1//3" or "1/2/3" into zero-based point, texcoord and
+// normal indices. Corners without a texcoord or a normal point at the first.
+func parse_corner(field string) (corner [3]uint16, err error) {
+	parts := strings.Split(field, "/")
+	for i := 0; i < 3 && i < len(parts); i++ {
+		if parts[i] == "" {
+			continue
+		}
+		index, err := strconv.ParseUint(parts[i], 10, 16)
+		if err != nil {
+			return corner, err
+		}
+		if index == 0 {
+			return corner, fmt.Errorf("index 0 in %q, obj indices start at 1", field)
+		}
+		corner[i] = uint16(index - 1)
+	}
+	return corner, nil
+}
@@ -0,0 +1,116 @@
+package mesh
+
+import (
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoadOBJ(t *testing.T) {
+	m, err := LoadOBJ([]byte("v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nvt 0 0\nusemtl wall\nf 1/1 2/1 3/1 4/1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Points) != 4 || len(m.Triangles) != 2 || len(m.Materials) != 1 {
+		t.Fatalf("a quad loaded as %d points, %d triangles and %d materials", len(m.Points), len(m.Triangles), len(m.Materials))
+	}
+
+	for _, bad := range []string{
+		"v 0 0\n",
+		"v 0 0 nan\n",
+		"f 1 2\n",
+		"v 0 0 0\nf 1 2 3\n",
+		"v 0 0 0\nf 0 1 1\n",
+		"v 0 0 0\nf 1/2 1/2 1/2\n",
+		"f 1 1 99999\n",
+		"usemtl\n",
+	} {
+		if _, err := LoadOBJ([]byte(bad)); err == nil {
+			t.Fatalf("%q loaded", bad)
+		}
+	}
+}
+
+// excerpt is a small model made of the first `faces` faces of an OBJ file and the points, texcoords and normals they
+// use, renumbered. The fuzzer slows to a crawl on files the size of the demos' models.
+func excerpt(src []byte, faces int) []byte {
+	kinds := []string{"v", "vt", "vn"}
+	var statements [3][]string
+	var kept [3][]string
+	numbers := [3]map[string]int{{}, {}, {}}
+	var out []string
+	for _, line := range strings.Split(string(src), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if i := slices.Index(kinds, fields[0]); i >= 0 {
+			statements[i] = append(statements[i], line)
+		}
+		if fields[0] != "f" || len(out) == faces {
+			continue
+		}
+		for j, field := range fields[1:] {
+			parts := strings.Split(field, "/")
+			for i, part := range parts[:min(len(parts), 3)] {
+				if part == "" {
+					continue
+				}
+				if _, ok := numbers[i][part]; !ok {
+					index, _ := strconv.Atoi(part)
+					kept[i] = append(kept[i], statements[i][index-1])
+					numbers[i][part] = len(kept[i])
+				}
+				parts[i] = strconv.Itoa(numbers[i][part])
+			}
+			fields[j+1] = strings.Join(parts, "/")
+		}
+		out = append(out, strings.Join(fields, " "))
+	}
+	return []byte(strings.Join(slices.Concat(kept[0], kept[1], kept[2], out), "\n") + "\n")
+}
+
+// FuzzLoadOBJ checks that whatever it's given, LoadOBJ either fails or makes a mesh which every index of is in
+// range and which is no bigger than the file it came from. It's seeded with the demos' models, run it with
+// go test -run FuzzLoadOBJ -fuzz FuzzLoadOBJ ./internal/mesh
+func FuzzLoadOBJ(f *testing.F) {
+	for _, path := range []string{"../../cmd/000-simple/suzanne.obj", "../../cmd/001-textures/wall.obj"} {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		seed := excerpt(src, 32)
+		if m, err := LoadOBJ(seed); err != nil || len(m.Triangles) == 0 {
+			f.Fatalf("the start of %s is no model: %v", path, err)
+		}
+		f.Add(seed)
+	}
+	f.Add([]byte("v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\nf 1//1 2//1 3//1\n"))
+	f.Add([]byte("v 1e38 1e38 1e38\nv -1e38 0 0\nv 0 0 0\nf 1 2 3 1 2 3\n"))
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		m, err := LoadOBJ(src)
+		if err != nil {
+			return
+		}
+		if len(m.Points)+len(m.Triangles) > len(src) {
+			t.Fatalf("%d bytes loaded as %d points and %d triangles", len(src), len(m.Points), len(m.Triangles))
+		}
+		for i, tri := range m.Triangles {
+			if int(max(tri.P1, tri.P2, tri.P3)) >= len(m.Points) || int(max(tri.T1, tri.T2, tri.T3)) >= len(m.Texcoords) ||
+				int(max(tri.N1, tri.N2, tri.N3)) >= len(m.Normals) || len(m.Materials) > 0 && int(tri.Material) >= len(m.Materials) {
+				t.Fatalf("triangle %d is %+v of %d points, %d texcoords, %d normals and %d materials", i, tri, len(m.Points), len(m.Texcoords), len(m.Normals), len(m.Materials))
+			}
+		}
+		for _, p := range m.Points {
+			for _, c := range p {
+				if math.IsNaN(float64(c)) || math.IsInf(float64(c), 0) {
+					t.Fatalf("a point is %v", p)
+				}
+			}
+		}
+	})
+}
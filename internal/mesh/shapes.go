@@ -0,0 +1,158 @@
+package mesh
+
+import "math"
+
+// The shapes are wound counter-clockwise when seen from outside, like most modelling tools export, and are
+// bounded already.
+
+// Box returns a box of `size` centered on the origin. Every face maps the whole texture.
+func Box(size vec3) *Mesh {
+	h := size.Mul(0.5)
+
+	// the corners of each face counter-clockwise from outside, point i has x, y and z from its bits 0, 1 and 2
+	faces := [6][4]uint16{
+		{0, 4, 6, 2}, // -x
+		{1, 3, 7, 5}, // +x
+		{0, 1, 5, 4}, // -y
+		{2, 6, 7, 3}, // +y
+		{0, 2, 3, 1}, // -z
+		{4, 5, 7, 6}, // +z
+	}
+
+	m := &Mesh{
+		Texcoords: []vec2{{0, 1}, {1, 1}, {1, 0}, {0, 0}},
+	}
+	for i := 0; i < 8; i++ {
+		m.Points = append(m.Points, vec3{
+			h[0] * float(i&1*2-1),
+			h[1] * float(i>>1&1*2-1),
+			h[2] * float(i>>2&1*2-1),
+		})
+	}
+	for _, f := range faces {
+		m.Triangles = append(m.Triangles,
+			Triangle{P1: f[0], P2: f[1], P3: f[2], T1: 0, T2: 1, T3: 2},
+			Triangle{P1: f[0], P2: f[2], P3: f[3], T1: 0, T2: 2, T3: 3},
+		)
+	}
+	m.ComputeBounds()
+	return m
+}
+
+// Plane returns a square in the XZ plane facing +Y, `size` wide and centered on the origin. The texture is
+// stretched once over it.
+func Plane(size float) *Mesh {
+	h := size / 2
+	m := &Mesh{
+		Points: []vec3{
+			{-h, 0, -h},
+			{h, 0, -h},
+			{-h, 0, h},
+			{h, 0, h},
+		},
+		Texcoords: []vec2{{0, 0}, {1, 0}, {0, 1}, {1, 1}},
+		Triangles: []Triangle{
+			{P1: 2, P2: 1, P3: 0, T1: 2, T2: 1, T3: 0},
+			{P1: 2, P2: 3, P3: 1, T1: 2, T2: 3, T3: 1},
+		},
+	}
+	m.ComputeBounds()
+	return m
+}
+
+// Heightfield returns a square grid `size` across centered on the origin, `cells` cells along each side, with
+// every point raised to `height` of its x and z. The texture stretches over the whole grid once.
+func Heightfield(size float, cells int, height func(x, z float) float) *Mesh {
+	// the indices of the points are 16 bit
+	cells = min(max(cells, 1), 254)
+	m := &Mesh{}
+	for row := 0; row <= cells; row++ {
+		v := float(row) / float(cells)
+		for column := 0; column <= cells; column++ {
+			u := float(column) / float(cells)
+			x, z := (u-0.5)*size, (v-0.5)*size
+			m.Points = append(m.Points, vec3{x, height(x, z), z})
+			m.Texcoords = append(m.Texcoords, vec2{u, v})
+		}
+	}
+	// wound like Plane, facing up
+	for row := range cells {
+		for column := range cells {
+			a := uint16(row*(cells+1) + column)
+			b, c := a+1, a+uint16(cells+1)
+			d := c + 1
+			m.Triangles = append(m.Triangles,
+				Triangle{P1: c, P2: b, P3: a, T1: c, T2: b, T3: a},
+				Triangle{P1: c, P2: d, P3: b, T1: c, T2: d, T3: b},
+			)
+		}
+	}
+	m.ComputeBounds()
+	return m
+}
+
+// UVSphere returns a sphere of `radius` centered on the origin made of `rings` bands from pole to pole, each
+// split into `segments`. The texture wraps around it once, like a globe.
+func UVSphere(radius float, rings, segments int) *Mesh {
+	rings = max(rings, 2)
+	segments = max(segments, 3)
+
+	m := &Mesh{}
+	// the seam has its own column of texcoords so the texture doesn't wrap backwards across the last segment
+	for ring := 0; ring <= rings; ring++ {
+		v := float(ring) / float(rings)
+		for segment := 0; segment <= segments; segment++ {
+			m.Texcoords = append(m.Texcoords, vec2{float(segment) / float(segments), v})
+		}
+	}
+
+	// the poles are single points, the rings in between share points across the seam
+	m.Points = append(m.Points, vec3{0, radius, 0})
+	for ring := 1; ring < rings; ring++ {
+		theta := math.Pi * float64(ring) / float64(rings)
+		for segment := 0; segment < segments; segment++ {
+			phi := 2 * math.Pi * float64(segment) / float64(segments)
+			m.Points = append(m.Points, vec3{
+				radius * float(math.Sin(theta)*math.Cos(phi)),
+				radius * float(math.Cos(theta)),
+				-radius * float(math.Sin(theta)*math.Sin(phi)),
+			})
+		}
+	}
+	m.Points = append(m.Points, vec3{0, -radius, 0})
+	bottom := uint16(len(m.Points) - 1)
+
+	point := func(ring, segment int) uint16 {
+		switch ring {
+		case 0:
+			return 0
+		case rings:
+			return bottom
+		}
+		return uint16(1 + (ring-1)*segments + segment%segments)
+	}
+	texcoord := func(ring, segment int) uint16 {
+		return uint16(ring*(segments+1) + segment)
+	}
+
+	for ring := 0; ring < rings; ring++ {
+		for segment := 0; segment < segments; segment++ {
+			a, b := ring, ring+1
+			s, t := segment, segment+1
+			if ring != 0 {
+				m.Triangles = append(m.Triangles, Triangle{
+					P1: point(a, s), P2: point(b, s), P3: point(a, t),
+					T1: texcoord(a, s), T2: texcoord(b, s), T3: texcoord(a, t),
+				})
+			}
+			if ring != rings-1 {
+				m.Triangles = append(m.Triangles, Triangle{
+					P1: point(a, t), P2: point(b, s), P3: point(b, t),
+					T1: texcoord(a, t), T2: texcoord(b, s), T3: texcoord(b, t),
+				})
+			}
+		}
+	}
+	m.ComputeBounds()
+	return m
+}
@@ -0,0 +1,66 @@
+package mesh
+
+import "testing"
+
+// faces_outwards checks that every triangle of a convex mesh around the origin is wound counter-clockwise seen
+// from outside, so its normal points away from the center.
+func faces_outwards(t *testing.T, name string, m *Mesh) {
+	t.Helper()
+	for i, tri := range m.Triangles {
+		p1, p2, p3 := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+		normal := p2.Sub(p1).Cross(p3.Sub(p1))
+		if normal.Len() == 0 {
+			t.Fatalf("%s: triangle %d is degenerate", name, i)
+		}
+		center := p1.Add(p2).Add(p3).Mul(1.0 / 3)
+		if normal.Dot(center) <= 0 {
+			t.Fatalf("%s: triangle %d faces inwards", name, i)
+		}
+		for _, index := range []uint16{tri.T1, tri.T2, tri.T3} {
+			if int(index) >= len(m.Texcoords) {
+				t.Fatalf("%s: triangle %d has texcoord %d of %d", name, i, index, len(m.Texcoords))
+			}
+		}
+	}
+}
+
+func TestShapesFaceOutwards(t *testing.T) {
+	faces_outwards(t, "box", Box(vec3{1, 2, 3}))
+	faces_outwards(t, "sphere", UVSphere(2, 8, 12))
+}
+
+func TestPlaneFacesUp(t *testing.T) {
+	m := Plane(4)
+	for i, tri := range m.Triangles {
+		p1, p2, p3 := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+		if n := p2.Sub(p1).Cross(p3.Sub(p1)); n.Y() <= 0 {
+			t.Fatalf("triangle %d faces %v", i, n)
+		}
+	}
+}
+
+func TestShapesAreBounded(t *testing.T) {
+	for name, m := range map[string]*Mesh{"box": Box(vec3{1, 1, 1}), "plane": Plane(1), "sphere": UVSphere(1, 4, 4)} {
+		if !m.Bounded() {
+			t.Fatalf("%s isn't bounded", name)
+		}
+		for _, p := range m.Points {
+			if p.Sub(m.Sphere.Center).Len() > m.Sphere.Radius*1.0001 {
+				t.Fatalf("%s: %v is outside of its bounds", name, p)
+			}
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	m := Box(vec3{1, 1, 1})
+	// 9 uint16 indices and the material of each triangle
+	want := len(m.Triangles)*20 + len(m.Points)*12 + len(m.Texcoords)*8 + len(m.Normals)*12
+	if m.Bytes() != want {
+		t.Fatalf("a box is %d bytes, want %d", m.Bytes(), want)
+	}
+	m.StoreSoA()
+	if m.Bytes() != want+len(m.Points)*12 {
+		t.Fatalf("a box with its points stored twice is %d bytes", m.Bytes())
+	}
+}
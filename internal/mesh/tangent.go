@@ -0,0 +1,122 @@
+package mesh
+
+import (
+	"math"
+	"slices"
+)
+
+// ComputeTangents computes Tangents the way MikkTSpace does, the tangent space Blender, Substance and most bakers
+// use, so a normal map baked there comes out right here:
+//
+//   - each triangle's tangent and bitangent follow u and v across it, from its points and texcoords
+//   - a corner takes the tangents of the triangles around it which share its point, normal and texcoord and are
+//     mapped the same way round, each weighted by its angle at the corner, made perpendicular to the normal
+//   - w is 1 when the texture isn't mirrored there and -1 when it is, the bitangent is w * normal × tangent
+//
+// Corners whose triangles have no area in texture space get the tangent of their neighbours. Meshes without
+// normals use the normals of the faces. It matches MikkTSpace on meshes like those bakers take, it doesn't follow
+// it into every degenerate case.
+func (m *Mesh) ComputeTangents() {
+	type key struct {
+		point, normal vec3
+		texcoord      vec2
+		mirrored      bool
+	}
+	type corner struct {
+		key
+		degenerate bool
+	}
+	sums := make(map[key]vec3)
+	corners := make([]corner, 0, 3*len(m.Triangles))
+
+	for _, t := range m.Triangles {
+		p := [3]vec3{m.Points[t.P1], m.Points[t.P2], m.Points[t.P3]}
+		uv := [3]vec2{m.Texcoords[t.T1], m.Texcoords[t.T2], m.Texcoords[t.T3]}
+		face := p[1].Sub(p[0]).Cross(p[2].Sub(p[0]))
+		if face.Len() > 0 {
+			face = face.Normalize()
+		}
+		n := [3]vec3{face, face, face}
+		if len(m.Normals) > 0 {
+			n = [3]vec3{m.Normals[t.N1], m.Normals[t.N2], m.Normals[t.N3]}
+			for i := range n {
+				if n[i].Len() > 0 {
+					n[i] = n[i].Normalize()
+				}
+			}
+		}
+
+		// how u and v run across the triangle
+		d1, d2 := p[1].Sub(p[0]), p[2].Sub(p[0])
+		t1, t2 := uv[1].Sub(uv[0]), uv[2].Sub(uv[0])
+		area := t1.X()*t2.Y() - t1.Y()*t2.X()
+		os := d1.Mul(t2.Y()).Sub(d2.Mul(t1.Y()))
+		mirrored := area < 0
+		if mirrored {
+			os = os.Mul(-1)
+		}
+		degenerate := area == 0 || os.Len() == 0
+
+		for i := range 3 {
+			k := key{point: p[i], normal: n[i], texcoord: uv[i], mirrored: mirrored}
+			corners = append(corners, corner{key: k, degenerate: degenerate})
+			if degenerate {
+				continue
+			}
+			// the angle at the corner, in the plane the normal is perpendicular to
+			prev, next := p[(i+2)%3].Sub(p[i]), p[(i+1)%3].Sub(p[i])
+			angle := angle_between(project(prev, n[i]), project(next, n[i]))
+			sums[k] = sums[k].Add(project(os, n[i]).Normalize().Mul(angle))
+		}
+	}
+
+	m.Tangents = slices.Grow(m.Tangents[:0], len(corners))[:len(corners)]
+	for i, c := range corners {
+		sum, ok := sums[c.key]
+		if !ok && c.degenerate {
+			// the neighbours which have tangents, whichever way round they're mapped
+			other := c.key
+			other.mirrored = !other.mirrored
+			sum = sums[other]
+		}
+		tangent := orthogonal(c.normal)
+		if sum.Len() > 1e-12 {
+			tangent = sum.Normalize()
+		}
+		w := float(1)
+		if c.mirrored {
+			w = -1
+		}
+		m.Tangents[i] = tangent.Vec4(w)
+	}
+}
+
+// project is `v` in the plane perpendicular to the unit vector `n`.
+func project(v, n vec3) vec3 {
+	return v.Sub(n.Mul(n.Dot(v)))
+}
+
+func angle_between(a, b vec3) float {
+	l := a.Len() * b.Len()
+	if l == 0 {
+		return 0
+	}
+	return float(math.Acos(float64(min(max(a.Dot(b)/l, -1), 1))))
+}
+
+// orthogonal is some unit vector perpendicular to `n`, for corners which have no tangent of their own.
+func orthogonal(n vec3) vec3 {
+	axis := vec3{1, 0, 0}
+	if abs(n.X()) > 0.9 {
+		axis = vec3{0, 1, 0}
+	}
+	t := project(axis, n)
+	if t.Len() == 0 {
+		return axis
+	}
+	return t.Normalize()
+}
+
+func abs(x float) float {
+	return float(math.Abs(float64(x)))
+}
@@ -0,0 +1,60 @@
+package mesh
+
+import "testing"
+
+func TestPlaneTangents(t *testing.T) {
+	m := Plane(2)
+	m.ComputeTangents()
+	// u runs along +x, v along +z, which with the normal up is mirrored
+	for i, tangent := range m.Tangents {
+		if !tangent.ApproxEqual(vec4{1, 0, 0, -1}) {
+			t.Fatalf("tangent %d of a plane is %v", i, tangent)
+		}
+	}
+	for i := range m.Texcoords {
+		m.Texcoords[i][0] = 1 - m.Texcoords[i][0]
+	}
+	m.ComputeTangents()
+	for i, tangent := range m.Tangents {
+		if !tangent.ApproxEqual(vec4{-1, 0, 0, 1}) {
+			t.Fatalf("tangent %d of a plane mapped the other way round is %v", i, tangent)
+		}
+	}
+}
+
+func TestOBJTangentsAreSmooth(t *testing.T) {
+	// two triangles folded along their shared edge, which share normals, so the corners on the edge share a tangent
+	m, err := LoadOBJ([]byte(`
+v 0 0 0
+v 1 0 0
+v 0 1 0
+v 1 1 1
+vt 0 0
+vt 1 0
+vt 0 1
+vt 1 1
+vn 0 0 1
+vn 0 0.2 1
+vn -0.2 0 1
+vn -0.2 0.2 1
+f 1/1/1 2/2/2 3/3/3
+f 2/2/2 4/4/4 3/3/3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Tangents) != 6 {
+		t.Fatalf("%d tangents for two triangles", len(m.Tangents))
+	}
+	for i, tangent := range m.Tangents {
+		tri := m.Triangles[i/3]
+		n := m.Normals[[3]uint16{tri.N1, tri.N2, tri.N3}[i%3]].Normalize()
+		if d := tangent.Vec3().Dot(n); d > 1e-5 || d < -1e-5 || tangent.W() != 1 {
+			t.Fatalf("tangent %d is %v, against its normal %v", i, tangent, n)
+		}
+	}
+	// the second corner of the first triangle is the first of the second
+	if !m.Tangents[1].ApproxEqual(m.Tangents[3]) || !m.Tangents[2].ApproxEqual(m.Tangents[5]) {
+		t.Fatalf("the corners along the fold have the tangents %v", m.Tangents)
+	}
+}
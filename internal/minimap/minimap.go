@@ -0,0 +1,85 @@
+// Package minimap renders a scene a second time, from straight above with an orthographic camera, into a small
+// image for a corner of the screen. -Z is up on the map.
+package minimap
+
+import (
+	"image/color"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+type Minimap struct {
+	// Center is the point of the world in the middle of the map.
+	Center vec3
+	// Extent is how far the map reaches from Center along X and Z, in world units.
+	Extent float
+	// Height is how far above Center the camera sits, geometry higher than that is cut off.
+	Height float
+
+	image    *ebiten.Image
+	pipeline pipeline.Context
+}
+
+// New returns a square map `size` pixels wide, showing `extent` units around the origin.
+func New(size int, extent float) *Minimap {
+	return &Minimap{
+		Extent: extent,
+		Height: 50,
+		image:  ebiten.NewImage(size, size),
+		pipeline: pipeline.Context{
+			FlipY: true,
+		},
+	}
+}
+
+// Image is the map as of the last Render.
+func (m *Minimap) Image() *ebiten.Image {
+	return m.image
+}
+
+// Render redraws the map. `push` pushes the scene's meshes into the map's pipeline and `draw` draws the sorted
+// triangles into `target`, with the same renderer as the main view.
+func (m *Minimap) Render(background color.Color, push func(ctx *pipeline.Context), draw func(target *ebiten.Image, triangles []pipeline.Triangle)) {
+	size := m.image.Bounds().Dx()
+	ctx := &m.pipeline
+	ctx.SetViewport(0, 0, size, size)
+	ctx.SetProjection(mgl32.Ortho(-m.Extent, m.Extent, -m.Extent, m.Extent, 0, m.Height*2))
+	ctx.SetView(mgl32.LookAtV(m.Center.Add(vec3{0, m.Height, 0}), m.Center, vec3{0, 0, -1}))
+
+	push(ctx)
+	ctx.Sort()
+	m.image.Fill(background)
+	draw(m.image, ctx.Triangles())
+	ctx.Reset()
+}
+
+// ToMap returns where the world position `p` is on the map image, in pixels.
+func (m *Minimap) ToMap(p vec3) (x, y float) {
+	size := float(m.image.Bounds().Dx())
+	x = (p.X() - m.Center.X() + m.Extent) / (2 * m.Extent) * size
+	y = (p.Z() - m.Center.Z() + m.Extent) / (2 * m.Extent) * size
+	return x, y
+}
+
+// DrawMarker draws an arrow at `p` on the map, pointing along `direction` as seen from above.
+func (m *Minimap) DrawMarker(p, direction vec3, clr color.Color) {
+	const length = 8
+	x, y := m.ToMap(p)
+	vector.DrawFilledCircle(m.image, x, y, 3, clr, true)
+
+	flat := mgl32.Vec2{direction.X(), direction.Z()}
+	if flat.Len() == 0 {
+		return
+	}
+	flat = flat.Normalize().Mul(length)
+	vector.StrokeLine(m.image, x, y, x+flat.X(), y+flat.Y(), 2, clr, true)
+}
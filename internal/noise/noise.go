@@ -0,0 +1,122 @@
+// Package noise is value, Perlin and simplex noise and fBm of them, for terrain, water, dissolving and skies.
+// Every function has a twin of the same name in noise.kage, which shaders include as "noise.kage", and both
+// compute in float32 the same way, so a heightfield made on the CPU lines up with the shader drawing its snow.
+package noise
+
+import (
+	_ "embed"
+	"math"
+)
+
+type float = float32
+
+// Kage is the source of the Kage half of the package.
+//
+//go:embed noise.kage
+var Kage string
+
+// Octaves is how many octaves the fBm functions sum, FBM_OCTAVES in Kage.
+const Octaves = 5
+
+func fract(x float) float {
+	return x - float(math.Floor(float64(x)))
+}
+
+func floor(x float) float {
+	return float(math.Floor(float64(x)))
+}
+
+func mix(a, b, t float) float {
+	return a + (b-a)*t
+}
+
+// Hash is a number from 0 to 1 which looks random for every point.
+func Hash(x, y float) float {
+	x, y = fract(x*123.34), fract(y*456.21)
+	d := x*(x+45.32) + y*(y+45.32)
+	x, y = x+d, y+d
+	return fract(x * y)
+}
+
+// Hash3 is Hash for points in space.
+func Hash3(x, y, z float) float {
+	x, y, z = fract(x*0.1031), fract(y*0.1030), fract(z*0.0973)
+	d := x*(y+33.33) + y*(x+33.33) + z*(z+33.33)
+	x, y, z = x+d, y+d, z+d
+	return fract((x + y) * z)
+}
+
+// Value is smooth noise from 0 to 1, changing about once per unit.
+func Value(x, y float) float {
+	ix, iy := floor(x), floor(y)
+	fx, fy := x-ix, y-iy
+	ux, uy := fx*fx*(3-2*fx), fy*fy*(3-2*fy)
+	a := Hash(ix, iy)
+	b := Hash(ix+1, iy)
+	c := Hash(ix, iy+1)
+	d := Hash(ix+1, iy+1)
+	return mix(mix(a, b, ux), mix(c, d, ux), uy)
+}
+
+// gradient is the unit gradient of the lattice point at `ix`, `iy`, pointing wherever its hash says.
+func gradient(ix, iy float) (float, float) {
+	angle := float64(Hash(ix, iy) * 6.2831853)
+	return float(math.Cos(angle)), float(math.Sin(angle))
+}
+
+// dot_gradient is the dot product of the gradient of the lattice point with `x`, `y`.
+func dot_gradient(ix, iy, x, y float) float {
+	gx, gy := gradient(ix, iy)
+	return gx*x + gy*y
+}
+
+// Perlin is gradient noise from about -1 to 1, 0 on every lattice point.
+func Perlin(x, y float) float {
+	ix, iy := floor(x), floor(y)
+	fx, fy := x-ix, y-iy
+	ux := fx * fx * fx * (fx*(fx*6-15) + 10)
+	uy := fy * fy * fy * (fy*(fy*6-15) + 10)
+	a := dot_gradient(ix, iy, fx, fy)
+	b := dot_gradient(ix+1, iy, fx-1, fy)
+	c := dot_gradient(ix, iy+1, fx, fy-1)
+	d := dot_gradient(ix+1, iy+1, fx-1, fy-1)
+	return mix(mix(a, b, ux), mix(c, d, ux), uy) * 1.4142135
+}
+
+// simplex_corner is what a corner of a simplex adds to the noise at `x`, `y` from it.
+func simplex_corner(ix, iy, x, y float) float {
+	h := max(0.5-(x*x+y*y), 0)
+	h *= h
+	return h * h * dot_gradient(ix, iy, x, y)
+}
+
+// Simplex is simplex noise from about -1 to 1, gradient noise over triangles rather than squares, with fewer
+// artifacts along the axes than Perlin.
+func Simplex(x, y float) float {
+	const f2 = 0.36602540
+	const g2 = 0.21132487
+	s := (x + y) * f2
+	ix, iy := floor(x+s), floor(y+s)
+	t := (ix + iy) * g2
+	x0, y0 := x-ix+t, y-iy+t
+	var ox, oy float = 0, 1
+	if x0 > y0 {
+		ox, oy = 1, 0
+	}
+	x1, y1 := x0-ox+g2, y0-oy+g2
+	x2, y2 := x0-1+2*g2, y0-1+2*g2
+	return 70 * (simplex_corner(ix, iy, x0, y0) + simplex_corner(ix+ox, iy+oy, x1, y1) + simplex_corner(ix+1, iy+1, x2, y2))
+}
+
+// FBM sums Octaves octaves of `noise`, each twice as fine and half as strong. It's fbm, fbm_perlin and
+// fbm_simplex in Kage for Value, Perlin and Simplex, and keeps their range.
+func FBM(noise func(x, y float) float, x, y float) float {
+	var sum float
+	amplitude := float(0.5)
+	for range Octaves {
+		sum += amplitude * noise(x, y)
+		x, y = x*2, y*2
+		amplitude *= 0.5
+	}
+	return sum
+}
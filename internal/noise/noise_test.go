@@ -0,0 +1,59 @@
+package noise
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRanges(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		noise  func(x, y float) float
+		lo, hi float
+	}{
+		{"Hash", Hash, 0, 1},
+		{"Value", Value, 0, 1},
+		{"Perlin", Perlin, -1.05, 1.05},
+		{"Simplex", Simplex, -1.05, 1.05},
+		{"FBM of Value", func(x, y float) float { return FBM(Value, x, y) }, 0, 1},
+	} {
+		lo, hi := float(math.Inf(1)), float(math.Inf(-1))
+		for i := range 200 {
+			for j := range 200 {
+				v := test.noise(float(i)*0.173-17, float(j)*0.191-19)
+				lo, hi = min(lo, v), max(hi, v)
+			}
+		}
+		if lo < test.lo || hi > test.hi {
+			t.Errorf("%s ranges from %v to %v, outside %v to %v", test.name, lo, hi, test.lo, test.hi)
+		}
+		// noise that's almost flat is as broken as noise out of range
+		if hi-lo < (test.hi-test.lo)/3 {
+			t.Errorf("%s only ranges from %v to %v", test.name, lo, hi)
+		}
+	}
+}
+
+func TestLattice(t *testing.T) {
+	for i := range 10 {
+		x, y := float(i)*3-7, float(i)*5-11
+		if v := Value(x, y); v != Hash(x, y) {
+			t.Errorf("value noise at the lattice point %v,%v is %v, its hash %v", x, y, v, Hash(x, y))
+		}
+		if v := Perlin(x, y); v != 0 {
+			t.Errorf("perlin noise at the lattice point %v,%v is %v", x, y, v)
+		}
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	const step = 1e-3
+	for _, noise := range []func(x, y float) float{Value, Perlin, Simplex} {
+		for i := range 1000 {
+			x, y := float(i)*0.0137, float(i)*0.0071+3
+			if d := noise(x+step, y) - noise(x, y); d > 0.05 || d < -0.05 {
+				t.Fatalf("noise jumps by %v at %v,%v", d, x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package pack is how shaders are handed more than the pipeline has room for. ebiten gives a vertex a source
+// position and four floats of color, and gives a render target four channels of 8 bits, so extra data rides along
+// in whatever is spare: the whole parts of texture coordinates, the color channels, two channels of a target for
+// one precise value. Every encoding here has its decoding in pack.kage, which shaders include as "pack.kage",
+// under the same name in snake case, so the Go which packs and the shader which unpacks can't drift apart.
+//
+// The renderer's own layout, which the packings fit around:
+//
+//	source position   texture coordinates divided by W, tags in their whole parts, see Tag
+//	red, green, blue  the light, or the normal for reflective materials, see Normal, divided by W
+//	alpha             1/W, which everything else is divided back by
+//
+// Values divided by W come out right across the triangle after the shader divides them by the alpha. Values
+// which are the same at every corner, like IDs, survive interpolation as they are, near enough for ID to round.
+package pack
+
+import (
+	_ "embed"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+// Kage is the source of the Kage half of the package.
+//
+//go:embed pack.kage
+var Kage string
+
+// MaxTag is the largest tag Tag keeps, larger ones leave too few bits of the float for the coordinate: at MaxTag
+// there are 15 left, a 32768th of the texture.
+const MaxTag = 255
+
+// Normal is the normal `n`, of length 1, from 0 to 1 in every channel, as colors and targets keep it.
+func Normal(n vec3) vec3 {
+	return n.Mul(0.5).Add(vec3{0.5, 0.5, 0.5})
+}
+
+// UnpackNormal is the normal Normal packed into `c`, of length 1 again.
+func UnpackNormal(c vec3) vec3 {
+	n := c.Mul(2).Sub(vec3{1, 1, 1})
+	if n.Len() == 0 {
+		return n
+	}
+	return n.Normalize()
+}
+
+// Depth is `d`, from 0 to 1, in two channels of 8 bits: the top 8 bits in the first and the bottom 8 in the
+// second, for depths and distances which need more than the 256 steps of one channel.
+func Depth(d float) vec2 {
+	d = min(max(d, 0), 1) * 255
+	whole := float(math.Floor(float64(d)))
+	return vec2{whole / 255, d - whole}
+}
+
+// UnpackDepth is the depth Depth packed into `c`.
+func UnpackDepth(c vec2) float {
+	return c.X() + c.Y()/255
+}
+
+// Tag adds the whole numbers `u` and `v`, from 0 to MaxTag, to the texture coordinates `uv`, from 0 to 1, so the
+// shader of a mesh gets a couple of small numbers for every corner without a vertex attribute of its own, like
+// the seed of a cloud puff or the shade of a blade of grass. The tags should be the same at every corner of a
+// triangle, which turns them into a value for the whole triangle. The texture coordinates can't wrap.
+func Tag(uv vec2, u, v int) vec2 {
+	u, v = min(max(u, 0), MaxTag), min(max(v, 0), MaxTag)
+	return uv.Add(vec2{float(u), float(v)})
+}
+
+// Untag is the texture coordinates and the tags Tag packed into `uv`. A coordinate of exactly 1 reads as 0 of
+// the next tag, as it does in the shader, where the corners of triangles aren't sampled.
+func Untag(uv vec2) (vec2, int, int) {
+	u, v := math.Floor(float64(uv.X())), math.Floor(float64(uv.Y()))
+	return uv.Sub(vec2{float(u), float(v)}), int(u), int(v)
+}
+
+// ID is the whole number `id` as a float for a vertex color channel, for materials and objects. Floats count
+// exactly up to 2^24.
+func ID(id int) float {
+	return float(id)
+}
+
+// UnpackID is the ID which ID packed into `f`, after interpolation.
+func UnpackID(f float) int {
+	return int(math.Floor(float64(f) + 0.5))
+}
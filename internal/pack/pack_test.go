@@ -0,0 +1,74 @@
+package pack
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestNormalRoundTrips(t *testing.T) {
+	for _, n := range []vec3{{1, 0, 0}, {0, -1, 0}, vec3{1, 2, -3}.Normalize(), vec3{-0.2, 0.1, 0.9}.Normalize()} {
+		c := Normal(n)
+		for i := range 3 {
+			if c[i] < 0 || c[i] > 1 {
+				t.Fatalf("%v packs to %v, out of 0 to 1", n, c)
+			}
+		}
+		// as an 8 bit target keeps it
+		var quantized vec3
+		for i := range 3 {
+			quantized[i] = float(math.Round(float64(c[i]*255))) / 255
+		}
+		if got := UnpackNormal(quantized); got.Dot(n) < 0.999 {
+			t.Fatalf("%v comes back as %v", n, got)
+		}
+	}
+}
+
+func TestDepthKeeps16Bits(t *testing.T) {
+	for i := range 1000 {
+		d := float(i) / 999
+		c := Depth(d)
+		// the target rounds both channels to 8 bits
+		c = vec2{float(math.Round(float64(c.X()*255))) / 255, float(math.Round(float64(c.Y()*255))) / 255}
+		if got := UnpackDepth(c); math.Abs(float64(got-d)) > 1.0/65025 {
+			t.Fatalf("%v comes back as %v", d, got)
+		}
+	}
+	if got := UnpackDepth(Depth(2)); got != 1 {
+		t.Fatalf("depths past 1 come back as %v, want them kept to 1", got)
+	}
+}
+
+func TestTagRoundTrips(t *testing.T) {
+	for _, test := range []struct {
+		uv   vec2
+		u, v int
+	}{
+		{vec2{0.25, 0.75}, 0, 0},
+		{vec2{0.5, 0.125}, 17, 3},
+		{vec2{0.999, 0.001}, MaxTag, MaxTag},
+	} {
+		uv, u, v := Untag(Tag(test.uv, test.u, test.v))
+		// the larger the tag the fewer bits are left for the coordinate, 15 of them at MaxTag
+		d := uv.Sub(test.uv)
+		if u != test.u || v != test.v || max(mgl32.Abs(d.X()), mgl32.Abs(d.Y())) > 1.0/32768 {
+			t.Fatalf("%v tagged %d %d comes back as %v tagged %d %d", test.uv, test.u, test.v, uv, u, v)
+		}
+	}
+	if _, u, v := Untag(Tag(vec2{}, MaxTag+10, -3)); u != MaxTag || v != 0 {
+		t.Fatalf("tags out of range come back as %d %d, want them kept to 0 to MaxTag", u, v)
+	}
+}
+
+func TestIDSurvivesInterpolation(t *testing.T) {
+	for _, id := range []int{0, 1, 7, 4095, 1 << 20} {
+		f := ID(id)
+		// the same at three corners, blended with weights which don't quite add up
+		blended := f*0.3333333 + f*0.3333333 + f*0.3333334
+		if got := UnpackID(blended); got != id {
+			t.Fatalf("%d comes back as %d", id, got)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package physics is a small rigid body simulation: boxes and spheres fall, bounce and tumble onto a ground plane
+// and each other, and go to sleep once they come to rest. Contacts are resolved with sequential impulses, warm
+// started from the previous step, and penetration is pushed out with a velocity bias.
+//
+// It's written to give the renderer something to chew on rather than to be accurate. Box against box only finds
+// contacts between faces, so two boxes meeting edge on edge can sink into each other until a face touches.
+package physics
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+	mat3  = mgl32.Mat3
+	mat4  = mgl32.Mat4
+	quat  = mgl32.Quat
+)
+
+type Shape int
+
+const (
+	Sphere Shape = iota
+	Box
+)
+
+func (s Shape) String() string {
+	switch s {
+	case Sphere:
+		return "sphere"
+	case Box:
+		return "box"
+	}
+	return "unknown"
+}
+
+// Body is a rigid body. Bodies without mass are static: they collide but never move.
+type Body struct {
+	Shape Shape
+	// Radius is the radius of spheres.
+	Radius float
+	// HalfSize is half the size of boxes.
+	HalfSize vec3
+
+	Position        vec3
+	Orientation     quat
+	Velocity        vec3
+	AngularVelocity vec3
+
+	// Restitution is how bouncy the body is, from 0 to 1.
+	Restitution float
+	Friction    float
+
+	inverse_mass float
+	// inverse_inertia is the diagonal of the inverse inertia tensor in the body's own space
+	inverse_inertia vec3
+
+	asleep bool
+	// idle is how long, in seconds, the body has been slow enough to sleep
+	idle float
+}
+
+// NewSphere returns a solid sphere of `radius` and `mass` at `position`, static when `mass` is 0.
+func NewSphere(radius, mass float, position vec3) *Body {
+	b := new_body(Sphere, mass, position)
+	b.Radius = radius
+	if mass > 0 {
+		i := 2.0 / 5 * mass * radius * radius
+		b.inverse_inertia = vec3{1 / i, 1 / i, 1 / i}
+	}
+	return b
+}
+
+// NewBox returns a solid box of `size` and `mass` at `position`, static when `mass` is 0.
+func NewBox(size vec3, mass float, position vec3) *Body {
+	b := new_body(Box, mass, position)
+	b.HalfSize = size.Mul(0.5)
+	if mass > 0 {
+		x, y, z := size.X()*size.X(), size.Y()*size.Y(), size.Z()*size.Z()
+		b.inverse_inertia = vec3{12 / (mass * (y + z)), 12 / (mass * (x + z)), 12 / (mass * (x + y))}
+	}
+	return b
+}
+
+func new_body(shape Shape, mass float, position vec3) *Body {
+	b := &Body{
+		Shape:       shape,
+		Position:    position,
+		Orientation: mgl32.QuatIdent(),
+		Restitution: 0.2,
+		Friction:    0.6,
+	}
+	if mass > 0 {
+		b.inverse_mass = 1 / mass
+	}
+	return b
+}
+
+// Static reports whether the body never moves.
+func (b *Body) Static() bool {
+	return b.inverse_mass == 0
+}
+
+// Asleep reports whether the body came to rest and is skipped until something hits it.
+func (b *Body) Asleep() bool {
+	return b.asleep
+}
+
+// Wake makes a sleeping body move again, e.g. after changing its velocity.
+func (b *Body) Wake() {
+	b.asleep = false
+	b.idle = 0
+}
+
+// Transform places the body's shape, centered on the origin, where the body is.
+func (b *Body) Transform() mat4 {
+	return mgl32.Translate3D(b.Position.X(), b.Position.Y(), b.Position.Z()).Mul4(b.Orientation.Mat4())
+}
+
+// bounding_radius is the radius of a sphere around the body, for quickly rejecting pairs.
+func (b *Body) bounding_radius() float {
+	if b.Shape == Sphere {
+		return b.Radius
+	}
+	return b.HalfSize.Len()
+}
+
+// ApplyImpulse changes the velocities as if `impulse` hit the body at the world space point `at`, and wakes it.
+func (b *Body) ApplyImpulse(impulse, at vec3) {
+	if b.Static() {
+		return
+	}
+	b.Wake()
+	b.apply_impulse(impulse, at.Sub(b.Position))
+}
+
+// apply_impulse applies `impulse` at `r`, relative to the body's position.
+func (b *Body) apply_impulse(impulse, r vec3) {
+	b.Velocity = b.Velocity.Add(impulse.Mul(b.inverse_mass))
+	b.AngularVelocity = b.AngularVelocity.Add(b.inverse_inertia_world(r.Cross(impulse)))
+}
+
+// moves reports whether the solver may change the body's velocity, sleeping bodies count as static.
+func (b *Body) moves() bool {
+	return b != nil && !b.Static() && !b.asleep
+}
+
+// inverse_inertia_world multiplies `v` by the inverse inertia tensor rotated into world space.
+func (b *Body) inverse_inertia_world(v vec3) vec3 {
+	local := b.Orientation.Inverse().Rotate(v)
+	local = vec3{local.X() * b.inverse_inertia.X(), local.Y() * b.inverse_inertia.Y(), local.Z() * b.inverse_inertia.Z()}
+	return b.Orientation.Rotate(local)
+}
+
+// velocity_at is the velocity of the point at `r` relative to the body's position.
+func (b *Body) velocity_at(r vec3) vec3 {
+	if b == nil {
+		return vec3{}
+	}
+	return b.Velocity.Add(b.AngularVelocity.Cross(r))
+}
+
+// axes are the body's local axes in world space.
+func (b *Body) axes() mat3 {
+	return b.Orientation.Mat4().Mat3()
+}
+
+// corner returns corner `i` of a box in world space, its signs on x, y and z are bits 0, 1 and 2 of `i`.
+func (b *Body) corner(i int) vec3 {
+	h := b.HalfSize
+	local := vec3{
+		h[0] * float(i&1*2-1),
+		h[1] * float(i>>1&1*2-1),
+		h[2] * float(i>>2&1*2-1),
+	}
+	return b.Position.Add(b.Orientation.Rotate(local))
+}
@@ -0,0 +1,273 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// contact is a point where two bodies touch. The normal points from b towards a, a is pushed along it and b the
+// other way. A nil b is the ground.
+type contact struct {
+	a, b *Body
+	// feature tells the contacts of a pair apart, so they can be matched with the previous step's
+	feature int
+
+	point  vec3
+	normal vec3
+	depth  float
+
+	// the rest is the solver's
+	ra, rb          vec3
+	tangents        [2]vec3
+	normal_mass     float
+	tangent_mass    [2]float
+	bias            float
+	friction        float
+	normal_impulse  float
+	tangent_impulse [2]float
+}
+
+// margin keeps contacts which are about to touch, a negative depth is a gap. Resting contacts would otherwise come
+// and go every other step as the bodies settle in and out of touching, and stacks never calm down.
+const margin = 0.02
+
+// collide_ground appends the contacts of `b` with the ground plane at height `ground`.
+func collide_ground(b *Body, ground float, out []contact) []contact {
+	up := vec3{0, 1, 0}
+	switch b.Shape {
+	case Sphere:
+		if depth := ground - (b.Position.Y() - b.Radius); depth > -margin {
+			out = append(out, contact{a: b, point: b.Position.Sub(up.Mul(b.Radius)), normal: up, depth: depth})
+		}
+	case Box:
+		for i := 0; i < 8; i++ {
+			c := b.corner(i)
+			if depth := ground - c.Y(); depth > -margin {
+				out = append(out, contact{a: b, feature: i, point: c, normal: up, depth: depth})
+			}
+		}
+	}
+	return out
+}
+
+// collide appends the contacts between `a` and `b`.
+func collide(a, b *Body, out []contact) []contact {
+	switch {
+	case a.Shape == Sphere && b.Shape == Sphere:
+		return sphere_sphere(a, b, out)
+	case a.Shape == Sphere && b.Shape == Box:
+		return sphere_box(a, b, out)
+	case a.Shape == Box && b.Shape == Sphere:
+		return sphere_box(b, a, out)
+	default:
+		return box_box(a, b, out)
+	}
+}
+
+func sphere_sphere(a, b *Body, out []contact) []contact {
+	d := a.Position.Sub(b.Position)
+	dist := d.Len()
+	if dist >= a.Radius+b.Radius+margin {
+		return out
+	}
+	normal := vec3{0, 1, 0}
+	if dist > 0 {
+		normal = d.Mul(1 / dist)
+	}
+	return append(out, contact{
+		a:      a,
+		b:      b,
+		point:  b.Position.Add(normal.Mul(b.Radius)),
+		normal: normal,
+		depth:  a.Radius + b.Radius - dist,
+	})
+}
+
+// sphere_box pushes the sphere `s` out of the box's nearest point, or nearest face when its center is inside.
+func sphere_box(s, box *Body, out []contact) []contact {
+	inverse := box.Orientation.Inverse()
+	local := inverse.Rotate(s.Position.Sub(box.Position))
+	h := box.HalfSize
+
+	var nearest vec3
+	for i := range nearest {
+		nearest[i] = mgl32.Clamp(local[i], -h[i], h[i])
+	}
+
+	var normal vec3
+	var depth float
+	if nearest == local {
+		// inside, out through the nearest face
+		axis := 0
+		for i := 1; i < 3; i++ {
+			if h[i]-abs(local[i]) < h[axis]-abs(local[axis]) {
+				axis = i
+			}
+		}
+		normal[axis] = sign(local[axis])
+		nearest[axis] = h[axis] * normal[axis]
+		depth = s.Radius + h[axis] - abs(local[axis])
+	} else {
+		away := local.Sub(nearest)
+		dist := away.Len()
+		if dist >= s.Radius+margin {
+			return out
+		}
+		normal = away.Mul(1 / dist)
+		depth = s.Radius - dist
+	}
+
+	return append(out, contact{
+		a:      s,
+		b:      box,
+		point:  box.Position.Add(box.Orientation.Rotate(nearest)),
+		normal: box.Orientation.Rotate(normal),
+		depth:  depth,
+	})
+}
+
+// box_box finds the face axis the boxes overlap least on and clips the other box's face against that face. Only the
+// face axes are used for contacts, the edge axes just rule out pairs that don't touch.
+func box_box(a, b *Body, out []contact) []contact {
+	d := b.Position.Sub(a.Position)
+	axes_a, axes_b := a.axes(), b.axes()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			n := axes_a.Col(i).Cross(axes_b.Col(j))
+			if n.Len() < 1e-4 {
+				// parallel edges, the face axes cover them
+				continue
+			}
+			n = n.Normalize()
+			if extent(a, n)+extent(b, n)+margin < abs(d.Dot(n)) {
+				return out
+			}
+		}
+	}
+
+	axis_a, depth_a, ok_a := face_axis(a, b)
+	axis_b, depth_b, ok_b := face_axis(b, a)
+	if !ok_a || !ok_b {
+		return out
+	}
+
+	// the face overlapping least first, but when that leaves nothing, e.g. a box tipped over the edge of the
+	// other, the other box's face. Ties go to a, so boxes of the same size don't swap roles every other step.
+	n := len(out)
+	if depth_b >= depth_a*0.95-0.01 {
+		out = face_contacts(a, b, axis_a, 0, out)
+		if len(out) == n {
+			out = face_contacts(b, a, axis_b, 8, out)
+		}
+	} else {
+		out = face_contacts(b, a, axis_b, 8, out)
+		if len(out) == n {
+			out = face_contacts(a, b, axis_a, 0, out)
+		}
+	}
+	return out
+}
+
+// extent is how far box `b` reaches along `n` from its center.
+func extent(b *Body, n vec3) float {
+	axes := b.axes()
+	return b.HalfSize.X()*abs(axes.Col(0).Dot(n)) +
+		b.HalfSize.Y()*abs(axes.Col(1).Dot(n)) +
+		b.HalfSize.Z()*abs(axes.Col(2).Dot(n))
+}
+
+// face_axis returns the index of the face axis of `ref` on which `other` overlaps it least and by how much, or
+// false when they're apart on one of them.
+func face_axis(ref, other *Body) (int, float, bool) {
+	d := other.Position.Sub(ref.Position)
+	axes := ref.axes()
+	best, best_depth := 0, float(math.MaxFloat32)
+	for i := 0; i < 3; i++ {
+		n := axes.Col(i)
+		depth := ref.HalfSize[i] + extent(other, n) - abs(d.Dot(n))
+		if depth < -margin {
+			return 0, 0, false
+		}
+		if depth < best_depth {
+			best, best_depth = i, depth
+		}
+	}
+	return best, best_depth, true
+}
+
+// face_contacts clips the face of `inc` facing `ref` against the face of `ref` on axis `axis` and appends the
+// points of it that are below that face, or within the margin above it. Their features start at `feature`.
+func face_contacts(ref, inc *Body, axis int, feature int, out []contact) []contact {
+	axes := ref.axes()
+	normal := axes.Col(axis)
+	if inc.Position.Sub(ref.Position).Dot(normal) < 0 {
+		normal = normal.Mul(-1)
+	}
+	h := ref.HalfSize
+
+	// the incident face is the one turned most against the reference face
+	inc_axes := inc.axes()
+	k, facing := 0, float(0)
+	for i := 0; i < 3; i++ {
+		if d := inc_axes.Col(i).Dot(normal); abs(d) > abs(facing) {
+			k, facing = i, d
+		}
+	}
+	center := inc.Position.Sub(inc_axes.Col(k).Mul(sign(facing) * inc.HalfSize[k]))
+	u := inc_axes.Col((k + 1) % 3).Mul(inc.HalfSize[(k+1)%3])
+	v := inc_axes.Col((k + 2) % 3).Mul(inc.HalfSize[(k+2)%3])
+	face := []vec3{center.Add(u).Add(v), center.Sub(u).Add(v), center.Sub(u).Sub(v), center.Add(u).Sub(v)}
+
+	// cut off whatever is beside the reference face
+	for j := 0; j < 3; j++ {
+		if j == axis {
+			continue
+		}
+		side := axes.Col(j)
+		offset := ref.Position.Dot(side)
+		// a little wider, or corners right on the edge, as in a stack, turn into two points each
+		face = clip(face, side, offset+h[j]+slop)
+		face = clip(face, side.Mul(-1), -offset+h[j]+slop)
+	}
+
+	for i, p := range face {
+		dist := p.Sub(ref.Position).Dot(normal) - h[axis]
+		// behind the face, but not out the other side of ref
+		if dist >= margin || dist < -2*h[axis] {
+			continue
+		}
+		out = append(out, contact{a: inc, b: ref, feature: feature + i, point: p, normal: normal, depth: -dist})
+	}
+	return out
+}
+
+// clip keeps the part of the convex polygon `polygon` where the dot product with `n` is at most `offset`.
+func clip(polygon []vec3, n vec3, offset float) []vec3 {
+	var clipped []vec3
+	for i, a := range polygon {
+		b := polygon[(i+1)%len(polygon)]
+		da, db := a.Dot(n)-offset, b.Dot(n)-offset
+		if da <= 0 {
+			clipped = append(clipped, a)
+		}
+		if da*db < 0 {
+			clipped = append(clipped, a.Add(b.Sub(a).Mul(da/(da-db))))
+		}
+	}
+	return clipped
+}
+
+func abs(f float) float {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+func sign(f float) float {
+	if f < 0 {
+		return -1
+	}
+	return 1
+}
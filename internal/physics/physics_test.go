@@ -0,0 +1,158 @@
+package physics
+
+import (
+	"testing"
+)
+
+const dt = 1.0 / 60
+
+func run(w *World, seconds float) {
+	for i := 0; i < int(seconds/dt); i++ {
+		w.Step(dt)
+	}
+}
+
+func TestSpheresComeToRestOnTheGround(t *testing.T) {
+	w := NewWorld()
+	b := w.Add(NewSphere(0.5, 1, vec3{0, 5, 0}))
+	run(w, 5)
+
+	if y := b.Position.Y(); y < 0.5-slop*2 || y > 0.5+slop {
+		t.Fatalf("the sphere rests at %v, it should touch the ground", b.Position)
+	}
+	if !b.Asleep() {
+		t.Fatalf("the sphere at rest is awake, moving at %v", b.Velocity)
+	}
+}
+
+func TestBouncesWithRestitution(t *testing.T) {
+	w := NewWorld()
+	b := w.Add(NewSphere(0.5, 1, vec3{0, 5, 0}))
+	b.Restitution = 0.8
+
+	falling, bounced := false, false
+	for i := 0; i < 120; i++ {
+		w.Step(dt)
+		if b.Velocity.Y() < -1 {
+			falling = true
+		}
+		if falling && b.Velocity.Y() > 3 {
+			bounced = true
+		}
+	}
+	if !bounced {
+		t.Fatal("a bouncy sphere dropped from 5 didn't bounce")
+	}
+}
+
+func TestImpactsAreReported(t *testing.T) {
+	w := NewWorld()
+	b := w.Add(NewSphere(0.5, 1, vec3{0, 5, 0}))
+	var impacts []Impact
+	for i := 0; i < 180; i++ {
+		w.Step(dt)
+		impacts = append(impacts, w.Impacts()...)
+	}
+
+	if len(impacts) == 0 {
+		t.Fatal("a sphere dropped on the ground didn't hit it")
+	}
+	// it falls 4.5 before touching
+	if i := impacts[0]; i.A != b || i.B != nil || abs(i.Speed-9.4) > 0.3 {
+		t.Fatalf("the impact was %+v, want the sphere hitting the ground at 9.4", i)
+	}
+	// then bounces a little, each hit softer than the last
+	for i := 1; i < len(impacts); i++ {
+		if impacts[i].Speed >= impacts[i-1].Speed/2 {
+			t.Fatalf("hit %d at %v after one at %v, contacts are reported more than once", i, impacts[i].Speed, impacts[i-1].Speed)
+		}
+	}
+	if len(w.Impacts()) != 0 {
+		t.Fatal("a resting sphere still hits the ground")
+	}
+}
+
+func TestBoxesSettleFlat(t *testing.T) {
+	w := NewWorld()
+	b := w.Add(NewBox(vec3{1, 1, 1}, 1, vec3{0, 3, 0}))
+	// dropped on a corner
+	b.AngularVelocity = vec3{2, 0, 3}
+	run(w, 6)
+
+	if y := b.Position.Y(); y < 0.5-slop*2 || y > 0.5+slop {
+		t.Fatalf("the box rests at %v, it should lie on a face", b.Position)
+	}
+	if !b.Asleep() {
+		t.Fatalf("the box at rest is awake, moving at %v and spinning at %v", b.Velocity, b.AngularVelocity)
+	}
+}
+
+func TestStacksStand(t *testing.T) {
+	w := NewWorld()
+	var boxes []*Body
+	for i := 0; i < 4; i++ {
+		boxes = append(boxes, w.Add(NewBox(vec3{1, 1, 1}, 1, vec3{0, 0.5 + float(i)*1.02, 0})))
+	}
+	run(w, 5)
+
+	for i, b := range boxes {
+		want := 0.5 + float(i)
+		if abs(b.Position.Y()-want) > 0.05 || abs(b.Position.X()) > 0.05 || abs(b.Position.Z()) > 0.05 {
+			t.Fatalf("box %d of the stack ended up at %v", i, b.Position)
+		}
+		if !b.Asleep() {
+			t.Fatalf("box %d of the stack is still awake", i)
+		}
+	}
+	if s := w.Stats(); s.Awake != 0 {
+		t.Fatalf("%d bodies are awake in a stack at rest", s.Awake)
+	}
+}
+
+func TestSleepersWakeWhenHit(t *testing.T) {
+	w := NewWorld()
+	box := w.Add(NewBox(vec3{1, 1, 1}, 1, vec3{0, 0.5, 0}))
+	run(w, 2)
+	if !box.Asleep() {
+		t.Fatal("the box didn't fall asleep")
+	}
+
+	ball := w.Add(NewSphere(0.25, 1, vec3{-3, 0.6, 0}))
+	ball.Velocity = vec3{8, 0, 0}
+	run(w, 0.5)
+
+	if box.Position.X() < 0.1 {
+		t.Fatalf("a ball hit the sleeping box and it stayed at %v, asleep %v", box.Position, box.Asleep())
+	}
+}
+
+func TestSpheresCollide(t *testing.T) {
+	w := NewWorld()
+	w.Gravity = vec3{}
+	a := w.Add(NewSphere(0.5, 1, vec3{-2, 1, 0}))
+	b := w.Add(NewSphere(0.5, 1, vec3{2, 1, 0}))
+	a.Velocity, b.Velocity = vec3{2, 0, 0}, vec3{-2, 0, 0}
+	run(w, 2)
+
+	if d := b.Position.Sub(a.Position).Len(); d < 1-slop*2 {
+		t.Fatalf("the spheres overlap, %v apart", d)
+	}
+	if a.Velocity.X() > 0 || b.Velocity.X() < 0 {
+		t.Fatalf("the spheres didn't push each other back, moving at %v and %v", a.Velocity, b.Velocity)
+	}
+}
+
+func TestStaticBodiesDontMove(t *testing.T) {
+	w := NewWorld()
+	wall := w.Add(NewBox(vec3{1, 4, 4}, 0, vec3{0, 2, 0}))
+	ball := w.Add(NewSphere(0.5, 1, vec3{-3, 1, 0}))
+	ball.Velocity = vec3{10, 0, 0}
+	run(w, 1)
+
+	if wall.Position != (vec3{0, 2, 0}) {
+		t.Fatalf("the static wall moved to %v", wall.Position)
+	}
+	if x := ball.Position.X(); x > -0.9 {
+		t.Fatalf("the ball went into the wall, to x = %v", x)
+	}
+}
@@ -0,0 +1,353 @@
+package physics
+
+import (
+	"math"
+)
+
+// World steps bodies over a ground plane.
+type World struct {
+	Gravity vec3
+	// Ground is the height of the ground plane, an infinite static floor facing up.
+	Ground float
+	// Iterations is how often the solver goes over the contacts per step, more makes stacks stiffer.
+	Iterations int
+	// Bodies slower than SleepSpeed, in units or radians per second, for SleepTime seconds fall asleep. They are
+	// skipped until something moving hits them. A SleepSpeed of 0 keeps everything awake.
+	SleepSpeed float
+	SleepTime  float
+
+	Bodies []*Body
+
+	contacts []contact
+	impacts  []Impact
+	// previous holds the impulses of the last step's contacts, the solver starts from them
+	previous map[contact_key][3]float
+}
+
+type contact_key struct {
+	a, b    *Body
+	feature int
+}
+
+// Impact is two bodies hitting each other, or a body hitting the ground when B is nil.
+type Impact struct {
+	A, B  *Body
+	Point vec3
+	// Speed is how fast they came together, along the normal of the contact.
+	Speed float
+}
+
+// Stats describes the last step.
+type Stats struct {
+	Bodies   int
+	Awake    int
+	Contacts int
+}
+
+const (
+	// baumgarte is the fraction of the penetration pushed out per step and slop how much is left, so resting
+	// contacts don't jitter in and out
+	baumgarte = 0.2
+	slop      = 0.01
+	// bounces slower than restitution_speed don't bounce, or nothing would ever come to rest
+	restitution_speed = 1
+	// damping per second, a little air keeps things from spinning forever
+	linear_damping  = 0.05
+	angular_damping = 0.1
+	// rolling_resistance slows down spheres rolling on something, they'd roll on forever otherwise
+	rolling_resistance = 0.05
+)
+
+// NewWorld returns an empty world with earth's gravity and the ground at 0.
+func NewWorld() *World {
+	return &World{
+		Gravity:    vec3{0, -9.81, 0},
+		Iterations: 10,
+		SleepSpeed: 0.1,
+		SleepTime:  0.5,
+		previous:   map[contact_key][3]float{},
+	}
+}
+
+// Add adds `b` to the world and returns it.
+func (w *World) Add(b *Body) *Body {
+	w.Bodies = append(w.Bodies, b)
+	return b
+}
+
+// Remove takes `b` out of the world.
+func (w *World) Remove(b *Body) {
+	for i, other := range w.Bodies {
+		if other == b {
+			w.Bodies = append(w.Bodies[:i], w.Bodies[i+1:]...)
+			return
+		}
+	}
+}
+
+// Clear removes every body.
+func (w *World) Clear() {
+	w.Bodies = w.Bodies[:0]
+	w.contacts = w.contacts[:0]
+	w.impacts = w.impacts[:0]
+	clear(w.previous)
+}
+
+// Stats describes the last step.
+func (w *World) Stats() Stats {
+	s := Stats{Bodies: len(w.Bodies), Contacts: len(w.contacts)}
+	for _, b := range w.Bodies {
+		if b.moves() {
+			s.Awake++
+		}
+	}
+	return s
+}
+
+// Impacts returns the hits of the last step, the hardest contact of every pair that came together faster than
+// things bounce. Resting and sliding contacts aren't impacts. The slice is reused by the next step.
+func (w *World) Impacts() []Impact {
+	return w.impacts
+}
+
+// Step advances the world by `dt` seconds. It's meant to be called with a fixed `dt`, such as once per tick.
+func (w *World) Step(dt float) {
+	if dt <= 0 {
+		return
+	}
+
+	for _, b := range w.Bodies {
+		if !b.moves() {
+			continue
+		}
+		b.Velocity = b.Velocity.Add(w.Gravity.Mul(dt)).Mul(1 / (1 + dt*linear_damping))
+		b.AngularVelocity = b.AngularVelocity.Mul(1 / (1 + dt*angular_damping))
+	}
+
+	w.find_contacts()
+	w.wake_touched()
+	w.solve(dt)
+
+	for _, b := range w.Bodies {
+		if !b.moves() {
+			continue
+		}
+		b.Position = b.Position.Add(b.Velocity.Mul(dt))
+		spin := quat{V: b.AngularVelocity}.Mul(b.Orientation).Scale(dt / 2)
+		b.Orientation = b.Orientation.Add(spin).Normalize()
+	}
+
+	w.sleep(dt)
+}
+
+func (w *World) find_contacts() {
+	w.contacts = w.contacts[:0]
+	for i, a := range w.Bodies {
+		if a.moves() {
+			w.contacts = collide_ground(a, w.Ground, w.contacts)
+		}
+		for _, b := range w.Bodies[i+1:] {
+			if !a.moves() && !b.moves() {
+				continue
+			}
+			r := a.bounding_radius() + b.bounding_radius()
+			if a.Position.Sub(b.Position).LenSqr() > r*r {
+				continue
+			}
+			w.contacts = collide(a, b, w.contacts)
+		}
+	}
+}
+
+// wake_touched wakes sleeping bodies hit by moving ones. Bodies that are merely awake but slowing down, like the
+// last box of a stack to settle, don't wake the ones that settled first.
+func (w *World) wake_touched() {
+	moving := func(b *Body) bool {
+		return b.moves() && b.idle == 0
+	}
+	for _, c := range w.contacts {
+		if c.b == nil {
+			continue
+		}
+		if c.a.asleep && moving(c.b) {
+			c.a.Wake()
+		}
+		if c.b.asleep && moving(c.a) {
+			c.b.Wake()
+		}
+	}
+}
+
+func (w *World) solve(dt float) {
+	w.impacts = w.impacts[:0]
+	for i := range w.contacts {
+		w.contacts[i].prepare(dt)
+		w.record_impact(&w.contacts[i], dt)
+	}
+
+	// warm start, the impulses of the last step are a good guess for this one
+	for i := range w.contacts {
+		c := &w.contacts[i]
+		if j, ok := w.previous[contact_key{c.a, c.b, c.feature}]; ok {
+			c.normal_impulse, c.tangent_impulse = j[0], [2]float{j[1], j[2]}
+			c.apply(c.normal.Mul(j[0]).Add(c.tangents[0].Mul(j[1])).Add(c.tangents[1].Mul(j[2])))
+		}
+	}
+
+	for n := 0; n < w.Iterations; n++ {
+		for i := range w.contacts {
+			w.contacts[i].solve()
+		}
+	}
+
+	for i := range w.contacts {
+		w.contacts[i].roll()
+	}
+
+	clear(w.previous)
+	for _, c := range w.contacts {
+		w.previous[contact_key{c.a, c.b, c.feature}] = [3]float{c.normal_impulse, c.tangent_impulse[0], c.tangent_impulse[1]}
+	}
+}
+
+// record_impact remembers `c` as an impact if it's hitting this step. Contacts of the pair are next to each
+// other, the fastest one stands for them.
+func (w *World) record_impact(c *contact, dt float) {
+	speed := -c.relative_velocity().Dot(c.normal)
+	// a speculative contact only hits if the gap closes within the step
+	if speed < restitution_speed || c.depth+speed*dt < 0 {
+		return
+	}
+	for i := len(w.impacts) - 1; i >= 0 && w.impacts[i].A == c.a; i-- {
+		if w.impacts[i].B == c.b {
+			if speed > w.impacts[i].Speed {
+				w.impacts[i].Point, w.impacts[i].Speed = c.point, speed
+			}
+			return
+		}
+	}
+	w.impacts = append(w.impacts, Impact{A: c.a, B: c.b, Point: c.point, Speed: speed})
+}
+
+func (w *World) sleep(dt float) {
+	if w.SleepSpeed <= 0 {
+		return
+	}
+	limit := w.SleepSpeed * w.SleepSpeed
+	for _, b := range w.Bodies {
+		if !b.moves() {
+			continue
+		}
+		if b.Velocity.LenSqr() > limit || b.AngularVelocity.LenSqr() > limit {
+			b.idle = 0
+			continue
+		}
+		b.idle += dt
+		if b.idle >= w.SleepTime {
+			b.asleep = true
+			b.Velocity, b.AngularVelocity = vec3{}, vec3{}
+		}
+	}
+}
+
+// prepare works out what the solver needs to know about the contact.
+func (c *contact) prepare(dt float) {
+	c.ra = c.point.Sub(c.a.Position)
+	c.friction = c.a.Friction
+	restitution := c.a.Restitution
+	if c.b != nil {
+		c.rb = c.point.Sub(c.b.Position)
+		c.friction = float(math.Sqrt(float64(c.a.Friction * c.b.Friction)))
+		restitution = max(restitution, c.b.Restitution)
+	}
+
+	c.tangents = perpendicular(c.normal)
+	c.normal_mass = c.effective_mass(c.normal)
+	c.tangent_mass = [2]float{c.effective_mass(c.tangents[0]), c.effective_mass(c.tangents[1])}
+
+	if c.depth < 0 {
+		// not touching yet, they may close the gap but no more
+		c.bias = c.depth / dt
+	} else {
+		c.bias = baumgarte / dt * max(c.depth-slop, 0)
+	}
+	if vn := c.relative_velocity().Dot(c.normal); vn < -restitution_speed {
+		c.bias = max(c.bias, -restitution*vn)
+	}
+}
+
+// solve makes the bodies stop approaching each other at the contact and rub less, within what friction allows.
+func (c *contact) solve() {
+	vn := c.relative_velocity().Dot(c.normal)
+	old := c.normal_impulse
+	c.normal_impulse = max(old+c.normal_mass*(c.bias-vn), 0)
+	c.apply(c.normal.Mul(c.normal_impulse - old))
+
+	limit := c.friction * c.normal_impulse
+	for i, t := range c.tangents {
+		vt := c.relative_velocity().Dot(t)
+		old := c.tangent_impulse[i]
+		c.tangent_impulse[i] = max(-limit, min(old-c.tangent_mass[i]*vt, limit))
+		c.apply(t.Mul(c.tangent_impulse[i] - old))
+	}
+}
+
+// roll brakes the spheres of the contact, in proportion to how hard they're pressed on.
+func (c *contact) roll() {
+	for _, b := range [2]*Body{c.a, c.b} {
+		if !b.moves() || b.Shape != Sphere {
+			continue
+		}
+		// spheres are round, their inertia is the same around every axis
+		limit := rolling_resistance * c.normal_impulse * b.Radius * b.inverse_inertia.X()
+		if spin := b.AngularVelocity.Len(); spin <= limit {
+			b.AngularVelocity = vec3{}
+		} else {
+			b.AngularVelocity = b.AngularVelocity.Mul(1 - limit/spin)
+		}
+	}
+}
+
+func (c *contact) relative_velocity() vec3 {
+	v := c.a.velocity_at(c.ra)
+	if c.b != nil {
+		v = v.Sub(c.b.velocity_at(c.rb))
+	}
+	return v
+}
+
+// apply applies `impulse` to a and the opposite to b.
+func (c *contact) apply(impulse vec3) {
+	if c.a.moves() {
+		c.a.apply_impulse(impulse, c.ra)
+	}
+	if c.b.moves() {
+		c.b.apply_impulse(impulse.Mul(-1), c.rb)
+	}
+}
+
+// effective_mass is the mass the contact pushes against along `d`.
+func (c *contact) effective_mass(d vec3) float {
+	var k float
+	if c.a.moves() {
+		k += c.a.inverse_mass + c.a.inverse_inertia_world(c.ra.Cross(d)).Cross(c.ra).Dot(d)
+	}
+	if c.b.moves() {
+		k += c.b.inverse_mass + c.b.inverse_inertia_world(c.rb.Cross(d)).Cross(c.rb).Dot(d)
+	}
+	if k == 0 {
+		return 0
+	}
+	return 1 / k
+}
+
+// perpendicular returns two directions perpendicular to `n` and each other.
+func perpendicular(n vec3) [2]vec3 {
+	var t vec3
+	if abs(n.X()) > 0.57 {
+		t = vec3{n.Y(), -n.X(), 0}.Normalize()
+	} else {
+		t = vec3{0, n.Z(), -n.Y()}.Normalize()
+	}
+	return [2]vec3{t, n.Cross(t)}
+}
@@ -0,0 +1,99 @@
+// Package pip collects the offscreen images a frame draws, the distance image, the ID buffer, the images of the
+// passes of a frame graph, for the picture-in-picture strip of package app to show. Passes hand them to Show once
+// they've drawn them. They're copied then, since a pooled image is drawn over by the next pass to borrow it, and
+// nothing is copied while the strip is closed.
+package pip
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/memory"
+)
+
+// Enabled is set while the strip is open, Show does nothing otherwise.
+var Enabled bool
+
+// stale_frames is how many frames a view is kept after it was last shown
+const stale_frames = 60
+
+// View is an image shown during the last few frames, as it was when it was shown.
+type View struct {
+	Name  string
+	Image *ebiten.Image
+	// frame is the frame it was last shown
+	frame int
+}
+
+var (
+	views []*View
+	frame int
+)
+
+func init() {
+	memory.Gauge("picture-in-picture", func() int64 {
+		var pixels int64
+		for _, v := range views {
+			size := v.Size()
+			pixels += int64(size.X * size.Y)
+		}
+		return pixels * 4
+	})
+}
+
+// Show copies `img` to be shown as `name`. An image shown again under the same name replaces the last one.
+func Show(name string, img *ebiten.Image) {
+	if !Enabled || img == nil {
+		return
+	}
+	var v *View
+	for _, view := range views {
+		if view.Name == name {
+			v = view
+			break
+		}
+	}
+	if v == nil {
+		v = &View{Name: name}
+		views = append(views, v)
+	}
+
+	bounds := img.Bounds()
+	if v.Image == nil || v.Image.Bounds().Size() != bounds.Size() {
+		if v.Image != nil {
+			v.Image.Deallocate()
+		}
+		v.Image = ebiten.NewImage(bounds.Dx(), bounds.Dy())
+	}
+	// copied as they are, so an ID or a packed depth isn't blended with what's under it
+	op := &ebiten.DrawImageOptions{Blend: ebiten.BlendCopy}
+	op.GeoM.Translate(float64(-bounds.Min.X), float64(-bounds.Min.Y))
+	v.Image.DrawImage(img, op)
+	v.frame = frame
+}
+
+// Views are the images shown lately, in the order they were first shown.
+func Views() []*View {
+	return views
+}
+
+// Size is the size of the image of the view.
+func (v *View) Size() image.Point {
+	return v.Image.Bounds().Size()
+}
+
+// EndFrame drops the views which haven't been shown for a while, and all of them once the strip is closed.
+func EndFrame() {
+	frame++
+	kept := views[:0]
+	for _, v := range views {
+		if Enabled && frame-v.frame <= stale_frames {
+			kept = append(kept, v)
+		} else {
+			v.Image.Deallocate()
+		}
+	}
+	clear(views[len(kept):])
+	views = kept
+}
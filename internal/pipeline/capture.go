@@ -0,0 +1,123 @@
+package pipeline
+
+// Submission is a triangle as it was submitted to the pipeline, before clipping. Its vertices are in clip space.
+type Submission struct {
+	// Mesh is the index of the mesh within the frame, in the order they were pushed.
+	Mesh int
+	// Index is the index of the triangle within its mesh.
+	Index int
+	// MeshID is the ID of the mesh, see mesh.Mesh.ID.
+	MeshID   uint32
+	Material uint16
+
+	V1, V2, V3 Vertex
+
+	// NeedsClip is set when at least one vertex was outside of the view volume.
+	NeedsClip bool
+	// Clipped is the number of triangles which came out of the clipper, 0 means it was entirely outside.
+	Clipped int
+	// Drawn is the number of triangles which survived back-face culling and were drawn.
+	Drawn int
+}
+
+// Stage is a snapshot of a triangle in one of the later stages of the pipeline.
+type Stage struct {
+	// Source is the index of the Submission this triangle came from.
+	Source int
+	Origin
+
+	V1, V2, V3 Vertex
+	Distance   float
+}
+
+// Capture records every triangle which went through the pipeline in one frame, a small RenderDoc for the
+// software pipeline. Every stage refers back to the submission it came from.
+type Capture struct {
+	// Submissions are the triangles before clipping, in clip space.
+	Submissions []Submission
+	// Clipped are the triangles after clipping, still in clip space and not yet culled.
+	Clipped []Stage
+	// Unsorted are the triangles in screen space, after culling and before sorting.
+	Unsorted []Stage
+	// Sorted are the triangles in screen space in the order they were drawn.
+	Sorted []Stage
+}
+
+// CaptureNextFrame requests the next frame to be recorded. The capture is available from LastCapture
+// once that frame is Reset.
+func (c *Context) CaptureNextFrame() {
+	c.capture_armed = true
+}
+
+// Capturing reports whether a capture was requested and hasn't completed yet.
+func (c *Context) Capturing() bool {
+	return c.capture_armed || c.capture != nil
+}
+
+// LastCapture returns the last completed capture, or nil.
+func (c *Context) LastCapture() *Capture {
+	return c.last_capture
+}
+
+func (c *Capture) submit(mesh int, origin Origin, v1, v2, v3 Vertex, needs_clip bool) int32 {
+	c.Submissions = append(c.Submissions, Submission{
+		Mesh:      mesh,
+		Index:     int(origin.Index),
+		MeshID:    origin.Mesh,
+		Material:  origin.Material,
+		V1:        v1,
+		V2:        v2,
+		V3:        v3,
+		NeedsClip: needs_clip,
+	})
+	return int32(len(c.Submissions) - 1)
+}
+
+func (c *Context) capture_clipped(v1, v2, v3 Vertex) {
+	if c.capture == nil {
+		return
+	}
+	c.capture.Submissions[c.source].Clipped++
+	c.capture.Clipped = append(c.capture.Clipped, Stage{
+		Source: int(c.source),
+		Origin: c.origin,
+		V1:     v1,
+		V2:     v2,
+		V3:     v3,
+	})
+}
+
+func (c *Capture) record(dst []Stage, triangles []Triangle) []Stage {
+	for _, t := range triangles {
+		dst = append(dst, Stage{
+			Source:   int(t.source),
+			Origin:   t.Origin,
+			V1:       t.V1,
+			V2:       t.V2,
+			V3:       t.V3,
+			Distance: t.Distance,
+		})
+	}
+	return dst
+}
+
+// finish_capture completes the capture of the frame that just ended and begins a new one if requested.
+func (c *Context) finish_capture() {
+	if capture := c.capture; capture != nil {
+		// Sort wasn't called, the triangles were drawn in submission order
+		if len(capture.Sorted) == 0 && len(c.triangles) > 0 {
+			capture.Unsorted = capture.record(capture.Unsorted, c.triangles)
+			capture.Sorted = capture.record(capture.Sorted, c.triangles)
+		}
+		for _, s := range capture.Sorted {
+			capture.Submissions[s.Source].Drawn++
+		}
+		c.last_capture = capture
+		c.capture = nil
+	}
+
+	if c.capture_armed {
+		c.capture = &Capture{}
+		c.capture_armed = false
+	}
+}
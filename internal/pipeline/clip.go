@@ -0,0 +1,157 @@
+package pipeline
+
+import "github.com/thedaneeffect/ebiten-kage-playground/internal/pool"
+
+type plane struct {
+	origin vec4
+	normal vec4
+}
+
+// test determines if `v` is in front of the plane.
+func (p plane) test(v vec4) bool {
+	return v.Sub(p.origin).Dot(p.normal) > 0
+}
+
+// intersection returns the point of contact of a line segment between a->b to our plane.
+func (p plane) intersection(a, b vec4) vec4 {
+	u := b.Sub(a)
+	w := a.Sub(p.origin)
+	d := p.normal.Dot(u)
+	n := -p.normal.Dot(w)
+	return a.Add(u.Mul(n / d))
+}
+
+var clip_planes = [...]plane{
+	{origin: vec4{1, 0, 0, 1}, normal: vec4{-1, 0, 0, 1}}, // right
+	{origin: vec4{-1, 0, 0, 1}, normal: vec4{1, 0, 0, 1}}, // left
+	{origin: vec4{0, 1, 0, 1}, normal: vec4{0, -1, 0, 1}}, // bottom
+	{origin: vec4{0, -1, 0, 1}, normal: vec4{0, 1, 0, 1}}, // top
+	{origin: vec4{0, 0, 1, 1}, normal: vec4{0, 0, -1, 1}}, // front
+	{origin: vec4{0, 0, -1, 1}, normal: vec4{0, 0, 1, 1}}, // back
+}
+
+// clip_planes_reverse_z are the planes of a reverse-Z projection, where depth goes from 1 at the near plane
+// to 0 at the far plane instead of -1 to 1.
+var clip_planes_reverse_z = [...]plane{
+	clip_planes[0],
+	clip_planes[1],
+	clip_planes[2],
+	clip_planes[3],
+	clip_planes[4],
+	{origin: vec4{0, 0, 0, 0}, normal: vec4{0, 0, 1, 0}}, // back
+}
+
+// guard_band_planes are `planes` with the sides pushed out to `g` times as far from the middle of the view.
+func guard_band_planes(planes [6]plane, g float) [6]plane {
+	for i := range 4 {
+		planes[i].origin[0] *= g
+		planes[i].origin[1] *= g
+		planes[i].normal[3] = g
+	}
+	return planes
+}
+
+// clip_out_of_bounds reports whether `a` is outside the view volume, its sides `g` times as far out.
+func clip_out_of_bounds(a vec4, reverse_z bool, g float) bool {
+	x, y, z, w := a.X(), a.Y(), a.Z(), a.W()
+	z_min := -w
+	if reverse_z {
+		z_min = 0
+	}
+	gw := g * w
+	return x < -gw || x > gw || y < -gw || y > gw || z < z_min || z > w
+}
+
+// max_clip_points is how many points a clipped triangle can have, 9 is a safe number to ensure we never run out
+// of space while clipping.
+const max_clip_points = 9
+
+// polygon is the temporary buffers of the sutherland_hodgman_3d function, the points of the clipped triangle and
+// the points of the one before it. They're pooled so clipping allocates nothing, on as many goroutines as
+// need them.
+type polygon struct {
+	output [max_clip_points]vec4
+	input  [max_clip_points]vec4
+}
+
+var polygons = pool.New(func() *polygon { return &polygon{} }, nil)
+
+// https://en.wikipedia.org/wiki/Sutherland-Hodgman_algorithm
+// the points returned are in `dst`, they're only good until it's used again.
+func sutherland_hodgman_3d(p1, p2, p3 vec4, planes []plane, dst *polygon) []vec4 {
+	output := append(dst.output[:0], p1, p2, p3)
+
+	for _, plane := range planes {
+		copy(dst.input[:], output)       // copy output polygon to our input
+		input := dst.input[:len(output)] //
+		output = dst.output[:0]          // clear our output polygon
+
+		if len(input) == 0 {
+			return nil
+		}
+
+		prev_point := input[len(input)-1]
+
+		for _, point := range input {
+			if plane.test(point) {
+				if !plane.test(prev_point) {
+					output = append(output, plane.intersection(prev_point, point))
+				}
+				output = append(output, point)
+			} else if plane.test(prev_point) {
+				output = append(output, plane.intersection(prev_point, point))
+			}
+			prev_point = point
+		}
+	}
+	return output
+}
+
+// https://en.wikipedia.org/wiki/Barycentric_coordinate_system
+// a triangle without any area has no coordinates to speak of, every point of it is taken to be its first.
+func barycentric(p1, p2, p3, p vec3) vec3 {
+	v0 := p2.Sub(p1)
+	v1 := p3.Sub(p1)
+	v2 := p.Sub(p1)
+	d00 := v0.Dot(v0)
+	d01 := v0.Dot(v1)
+	d11 := v1.Dot(v1)
+	d20 := v2.Dot(v0)
+	d21 := v2.Dot(v1)
+	d := d00*d11 - d01*d01
+	if d == 0 {
+		return vec3{1, 0, 0}
+	}
+	v := (d11*d20 - d01*d21) / d
+	w := (d00*d21 - d01*d20) / d
+	u := 1 - v - w
+	return vec3{u, v, w}
+}
+
+func interpolate_vec4(v1, v2, v3 vec4, f vec3) (result vec4) {
+	result = result.Add(v1.Mul(f.X()))
+	result = result.Add(v2.Mul(f.Y()))
+	result = result.Add(v3.Mul(f.Z()))
+	return
+}
+
+func interpolate_vec2(v1, v2, v3 vec2, f vec3) (result vec2) {
+	result = result.Add(v1.Mul(f.X()))
+	result = result.Add(v2.Mul(f.Y()))
+	result = result.Add(v3.Mul(f.Z()))
+	return
+}
+
+func interpolate_vec3(v1, v2, v3 vec3, f vec3) (result vec3) {
+	result = result.Add(v1.Mul(f.X()))
+	result = result.Add(v2.Mul(f.Y()))
+	result = result.Add(v3.Mul(f.Z()))
+	return
+}
+
+func interpolate_vertex(v1, v2, v3 Vertex, f vec3) (result Vertex) {
+	result.Position = interpolate_vec4(v1.Position, v2.Position, v3.Position, f)
+	result.Texcoord = interpolate_vec2(v1.Texcoord, v2.Texcoord, v3.Texcoord, f)
+	result.Color = interpolate_vec3(v1.Color, v2.Color, v3.Color, f)
+	return
+}
@@ -0,0 +1,211 @@
+package pipeline
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+func TestGuardBand(t *testing.T) {
+	// a triangle in the middle of the view poking out of its right edge, which is at x 10
+	m := &mesh.Mesh{
+		Points:    []vec3{{0, 0, 0}, {12, 0, 0}, {0, 1, 0}},
+		Texcoords: []vec2{{}},
+		Triangles: []mesh.Triangle{{P1: 0, P2: 1, P3: 2}},
+	}
+	c := project_context()
+	c.PushMesh(m)
+	if lo, hi := spread(c.Triangles()); hi.X() != 400 || lo.X() != 200 {
+		t.Fatalf("clipped to the view, the triangle covers x %v to %v, want 200 to 400", lo.X(), hi.X())
+	}
+	c.Reset()
+
+	c.GuardBand = 2
+	c.PushMesh(m)
+	if n := len(c.Triangles()); n != 1 {
+		t.Fatalf("within the guard band the triangle came out as %d, want itself", n)
+	}
+	if _, hi := spread(c.Triangles()); hi.X() != 440 {
+		t.Fatalf("within the guard band the triangle reaches x %v, want 440", hi.X())
+	}
+	c.Reset()
+
+	// out past the guard band it's clipped to the band
+	m.Points[1] = vec3{30, 0, 0}
+	c.PushMesh(m)
+	if _, hi := spread(c.Triangles()); hi.X() != 600 {
+		t.Fatalf("past the guard band the triangle reaches x %v, want 600", hi.X())
+	}
+}
+
+// random_point is a point in clip space around the view volume, some of them behind the eye.
+func random_point(rng *rand.Rand) vec4 {
+	r := func(lo, hi float) float { return lo + rng.Float32()*(hi-lo) }
+	return vec4{r(-4, 4), r(-4, 4), r(-4, 4), r(-1, 4)}
+}
+
+// plane_sets are the planes a triangle can be clipped to and what to call them.
+var plane_sets = []struct {
+	name   string
+	planes [6]plane
+}{
+	{"view", clip_planes},
+	{"reverse z", clip_planes_reverse_z},
+	{"guard band", guard_band_planes(clip_planes, 2)},
+}
+
+// inside is how far in front of every plane `p` is, negative when it's behind one.
+func inside(planes [6]plane, p vec4) float {
+	d := float(math.Inf(1))
+	for _, plane := range planes {
+		d = min(d, p.Sub(plane.origin).Dot(plane.normal))
+	}
+	return d
+}
+
+func finite(v ...float) bool {
+	for _, f := range v {
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// near reports whether `a` and `b` are no more than `eps` apart in any of their components.
+func near(a, b []float, eps float) bool {
+	for i := range a {
+		if float(math.Abs(float64(a[i]-b[i]))) > eps {
+			return false
+		}
+	}
+	return true
+}
+
+// area is the area of the polygon `points` once projected to NDC.
+func area(points []vec4) float {
+	a := float(0)
+	for i := range points {
+		p, q := points[i], points[(i+1)%len(points)]
+		a += p.X()/p.W()*q.Y()/q.W() - q.X()/q.W()*p.Y()/p.W()
+	}
+	return float(math.Abs(float64(a))) / 2
+}
+
+func TestClippedPointsAreInside(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, set := range plane_sets {
+		for range 10000 {
+			p1, p2, p3 := random_point(rng), random_point(rng), random_point(rng)
+			points := sutherland_hodgman_3d(p1, p2, p3, set.planes[:], &polygon{})
+			if len(points) > max_clip_points {
+				t.Fatalf("%s: %v %v %v clipped to %d points", set.name, p1, p2, p3, len(points))
+			}
+			for _, p := range points {
+				if !finite(p[:]...) || inside(set.planes, p) < -1e-4*max(1, p.Len()) {
+					t.Fatalf("%s: %v %v %v clipped to %v, which is outside", set.name, p1, p2, p3, p)
+				}
+			}
+		}
+	}
+}
+
+func TestClippingKeepsWhatIsInside(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, set := range plane_sets {
+		for range 10000 {
+			p1, p2, p3 := random_point(rng), random_point(rng), random_point(rng)
+			points := sutherland_hodgman_3d(p1, p2, p3, set.planes[:], &polygon{})
+			inside_all := inside(set.planes, p1) > 0 && inside(set.planes, p2) > 0 && inside(set.planes, p3) > 0
+			if inside_all {
+				// a triangle entirely inside comes out as it went in
+				if len(points) != 3 || points[0] != p1 || points[1] != p2 || points[2] != p3 {
+					t.Fatalf("%s: %v %v %v is inside but clipped to %v", set.name, p1, p2, p3, points)
+				}
+				continue
+			}
+			if p1.W() <= 0 || p2.W() <= 0 || p3.W() <= 0 || len(points) < 3 {
+				continue
+			}
+			// in front of the eye clipping cuts the triangle down to what of it is in view, never adding to it
+			if got, was := area(points), area([]vec4{p1, p2, p3}); got > was*(1+1e-3)+1e-4 {
+				t.Fatalf("%s: %v %v %v was %v across and clipped to %v across", set.name, p1, p2, p3, was, got)
+			}
+		}
+	}
+}
+
+func TestBarycentric(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 10000 {
+		p1, p2, p3 := random_point(rng).Vec3(), random_point(rng).Vec3(), random_point(rng).Vec3()
+		if p2.Sub(p1).Cross(p3.Sub(p1)).Len() < 0.1 {
+			continue
+		}
+		u, v := rng.Float32(), rng.Float32()
+		if u+v > 1 {
+			u, v = 1-u, 1-v
+		}
+		want := vec3{1 - u - v, u, v}
+		p := p1.Mul(want[0]).Add(p2.Mul(want[1])).Add(p3.Mul(want[2]))
+		if got := barycentric(p1, p2, p3, p); !near(got[:], want[:], 1e-3) {
+			t.Fatalf("%v is %v of the way between %v %v %v, not %v", p, want, p1, p2, p3, got)
+		}
+	}
+}
+
+func TestClippedPointsAreOnTheTriangle(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 10000 {
+		p1, p2, p3 := random_point(rng), random_point(rng), random_point(rng)
+		if p2.Vec3().Sub(p1.Vec3()).Cross(p3.Vec3().Sub(p1.Vec3())).Len() < 0.1 {
+			continue
+		}
+		for _, p := range sutherland_hodgman_3d(p1, p2, p3, clip_planes[:], &polygon{}) {
+			b := barycentric(p1.Vec3(), p2.Vec3(), p3.Vec3(), p.Vec3())
+			back := interpolate_vec4(p1, p2, p3, b)
+			if min(b[0], b[1], b[2]) < -1e-3 || !near(back[:], p[:], 1e-3) {
+				t.Fatalf("%v clipped from %v %v %v is %v of the way between them", p, p1, p2, p3, b)
+			}
+		}
+	}
+}
+
+func TestDegenerateTriangles(t *testing.T) {
+	a, b := vec4{-3, 0.5, 0, 1}, vec4{3, -0.5, 0.2, 1}
+	triangles := [][3]vec4{
+		{a, a, a},                        // a point
+		{a, b, b},                        // two of its points the same
+		{a, b, a.Add(b).Mul(0.5)},        // a line
+		{a, b, a.Add(b.Sub(a).Mul(4))},   // a line, its last point past the others
+		{{}, {}, {}},                     // nothing at all
+		{{0, 0, -0.2, 0}, a, b},          // through the eye, where a projection puts it
+		{{0, 0, 0, -1}, {0, 0, 0, 1}, a}, // from behind the eye
+	}
+	for _, tri := range triangles {
+		for _, set := range plane_sets {
+			for _, p := range sutherland_hodgman_3d(tri[0], tri[1], tri[2], set.planes[:], &polygon{}) {
+				if !finite(p[:]...) {
+					t.Fatalf("%s: %v clipped to %v", set.name, tri, p)
+				}
+			}
+		}
+		if b := barycentric(tri[0].Vec3(), tri[1].Vec3(), tri[2].Vec3(), a.Vec3()); !finite(b[:]...) {
+			t.Fatalf("%v is %v of the way between %v", a, b, tri)
+		}
+
+		// and none of it makes it to the screen as anything but numbers
+		c := project_context()
+		c.Cull = CullNone
+		c.clip_triangle_and_push(Vertex{Position: tri[0]}, Vertex{Position: tri[1]}, Vertex{Position: tri[2]})
+		for _, triangle := range c.Triangles() {
+			for _, v := range [3]Vertex{triangle.V1, triangle.V2, triangle.V3} {
+				if !finite(append(v.Position[:], v.Texcoord[:]...)...) {
+					t.Fatalf("%v was pushed as %v", tri, triangle)
+				}
+			}
+		}
+	}
+}
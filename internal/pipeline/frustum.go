@@ -0,0 +1,44 @@
+package pipeline
+
+import "github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+
+// frustum is the six planes of the view frustum in world space, with normals pointing inwards. Each plane is
+// (a, b, c, d) where a point p is inside when a*p.x + b*p.y + c*p.z + d >= 0.
+type frustum [6]vec4
+
+// frustum_from extracts the planes from a projection-view matrix (Gribb & Hartmann). Since the matrix maps
+// world space into clip space, the planes come out in world space.
+func frustum_from(m mat4) (f frustum) {
+	r0, r1, r2, r3 := m.Row(0), m.Row(1), m.Row(2), m.Row(3)
+	f[0] = r3.Add(r0) // left
+	f[1] = r3.Sub(r0) // right
+	f[2] = r3.Add(r1) // bottom
+	f[3] = r3.Sub(r1) // top
+	f[4] = r3.Add(r2) // near
+	f[5] = r3.Sub(r2) // far
+
+	for i, p := range f {
+		if l := p.Vec3().Len(); l > 0 {
+			f[i] = p.Mul(1 / l)
+		}
+	}
+	return
+}
+
+// rejects reports whether the sphere is entirely outside of the frustum. Spheres crossing a plane are kept,
+// they're clipped per triangle later on.
+func (f *frustum) rejects(s mesh.Sphere) bool {
+	for _, p := range f {
+		if p.Vec3().Dot(s.Center)+p.W() < -s.Radius {
+			return true
+		}
+	}
+	return false
+}
+
+// Visible reports whether any of the sphere `s` in world space might be in view, as PushMesh rejects meshes by
+// their bounds. It's for culling things before going to the trouble of making their meshes.
+func (c *Context) Visible(s mesh.Sphere) bool {
+	frustum := frustum_from(c.proj_matrix.Mul4(c.view_matrix))
+	return !frustum.rejects(s)
+}
@@ -0,0 +1,127 @@
+package pipeline
+
+import "github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+
+// Lines are lines in world space which are as many pixels wide however far away they are, every two Points being
+// one line. They go through the pipeline as two triangles each facing the screen, so they're sorted among meshes
+// and hidden by what's in front of them, unlike the lines debugdraw draws over a frame.
+type Lines struct {
+	Points []vec3
+	// Width is how wide the lines are, in pixels.
+	Width float
+
+	id uint32
+}
+
+// ID is the Origin.Mesh of the lines' triangles, from the same counter as mesh IDs. Their Origin.Index is the
+// index of the line.
+func (l *Lines) ID() uint32 {
+	if l.id == 0 {
+		l.id = mesh.NewID()
+	}
+	return l.id
+}
+
+// Sprites are points in world space drawn as squares Size pixels across facing the screen, each with the whole
+// texture over it, like Lines sorted among meshes.
+type Sprites struct {
+	Points []vec3
+	// Size is how wide and high the squares are, in pixels.
+	Size float
+
+	id uint32
+}
+
+// ID is the Origin.Mesh of the sprites' triangles, from the same counter as mesh IDs. Their Origin.Index is the
+// index of the point.
+func (s *Sprites) ID() uint32 {
+	if s.id == 0 {
+		s.id = mesh.NewID()
+	}
+	return s.id
+}
+
+// PushLines pushes the lines of `l`. Their texcoords go along each line in u, from 0 at its first point to 1 at its
+// second, and across it in v. What's behind the eye is cut off. They're never culled and left out of AutoNearFar;
+// in Stats and captures they count as a mesh.
+func (ctx *Context) PushLines(l *Lines) {
+	ctx.stats.Meshes++
+	cull := ctx.Cull
+	ctx.Cull = CullNone
+	defer func() { ctx.Cull = cull }()
+
+	projection_view_matrix := ctx.fitted_projection().Mul4(ctx.view_matrix)
+	reverse_z := ctx.reverse_z()
+	id := l.ID()
+
+	for i := 0; i+1 < len(l.Points); i += 2 {
+		a := projection_view_matrix.Mul4x1(l.Points[i].Vec4(1))
+		b := projection_view_matrix.Mul4x1(l.Points[i+1].Vec4(1))
+		ua, ub := float(0), float(1)
+		// which way is across the line is only known in front of the eye
+		switch {
+		case a.W() < near_w && b.W() < near_w:
+			continue
+		case a.W() < near_w:
+			ua = (near_w - a.W()) / (b.W() - a.W())
+			a = a.Add(b.Sub(a).Mul(ua))
+		case b.W() < near_w:
+			t := (near_w - b.W()) / (a.W() - b.W())
+			b = b.Add(a.Sub(b).Mul(t))
+			ub = 1 - t
+		}
+
+		d := ctx.clip_to_pixels(b).Sub(ctx.clip_to_pixels(a))
+		if d.Len() == 0 {
+			// seen end on, it's a square
+			d = vec2{1, 0}
+		}
+		across := vec2{-d.Y(), d.X()}.Normalize().Mul(l.Width / 2)
+
+		ctx.origin = Origin{Mesh: id, Index: int32(i / 2)}
+		a0 := Vertex{Position: ctx.offset(a, across.Mul(-1)), Texcoord: vec2{ua, 0}}
+		a1 := Vertex{Position: ctx.offset(a, across), Texcoord: vec2{ua, 1}}
+		b0 := Vertex{Position: ctx.offset(b, across.Mul(-1)), Texcoord: vec2{ub, 0}}
+		b1 := Vertex{Position: ctx.offset(b, across), Texcoord: vec2{ub, 1}}
+		ctx.submit(a0, b0, b1, reverse_z)
+		ctx.submit(a0, b1, a1, reverse_z)
+	}
+}
+
+// PushSprites pushes the sprites of `s`, the top left of the texture at their top left on screen. Points behind the
+// eye are left out. Like lines they're never culled, left out of AutoNearFar and count as a mesh.
+func (ctx *Context) PushSprites(s *Sprites) {
+	ctx.stats.Meshes++
+	cull := ctx.Cull
+	ctx.Cull = CullNone
+	defer func() { ctx.Cull = cull }()
+
+	projection_view_matrix := ctx.fitted_projection().Mul4(ctx.view_matrix)
+	reverse_z := ctx.reverse_z()
+	id := s.ID()
+	h := s.Size / 2
+
+	for i, p := range s.Points {
+		c := projection_view_matrix.Mul4x1(p.Vec4(1))
+		if c.W() < near_w {
+			continue
+		}
+		ctx.origin = Origin{Mesh: id, Index: int32(i)}
+		top_left := Vertex{Position: ctx.offset(c, vec2{-h, -h}), Texcoord: vec2{0, 0}}
+		top_right := Vertex{Position: ctx.offset(c, vec2{h, -h}), Texcoord: vec2{1, 0}}
+		bottom_right := Vertex{Position: ctx.offset(c, vec2{h, h}), Texcoord: vec2{1, 1}}
+		bottom_left := Vertex{Position: ctx.offset(c, vec2{-h, h}), Texcoord: vec2{0, 1}}
+		ctx.submit(top_left, top_right, bottom_right, reverse_z)
+		ctx.submit(top_left, bottom_right, bottom_left, reverse_z)
+	}
+}
+
+// offset moves the clip space point `c` by `pixels` on screen, at the same depth.
+func (ctx *Context) offset(c vec4, pixels vec2) vec4 {
+	x := pixels.X() / ctx.viewport.w_2
+	y := pixels.Y() / ctx.viewport.h_2
+	if ctx.FlipY {
+		y = -y
+	}
+	return vec4{c.X() + x*c.W(), c.Y() + y*c.W(), c.Z(), c.W()}
+}
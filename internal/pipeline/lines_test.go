@@ -0,0 +1,70 @@
+package pipeline
+
+import "testing"
+
+// spread is the smallest and largest x and y of the triangles' vertices.
+func spread(triangles []Triangle) (lo, hi vec2) {
+	lo, hi = vec2{1e9, 1e9}, vec2{-1e9, -1e9}
+	for _, t := range triangles {
+		for _, v := range [3]Vertex{t.V1, t.V2, t.V3} {
+			for i := range 2 {
+				lo[i], hi[i] = min(lo[i], v.Position[i]), max(hi[i], v.Position[i])
+			}
+		}
+	}
+	return lo, hi
+}
+
+func TestLinesAreWidthPixelsWide(t *testing.T) {
+	c := project_context()
+
+	c.PushLines(&Lines{Points: []vec3{{-1, 0, 0}, {1, 0, 0}}, Width: 4})
+	if n := len(c.Triangles()); n != 2 {
+		t.Fatalf("a line is %d triangles, want 2", n)
+	}
+	if lo, hi := spread(c.Triangles()); lo.Y() != 98 || hi.Y() != 102 {
+		t.Fatalf("a line across the middle covers y %v to %v, want 98 to 102", lo.Y(), hi.Y())
+	}
+	c.Reset()
+
+	// a line going away from the eye is as wide at its far end as at its near one
+	c.PushLines(&Lines{Points: []vec3{{0, -1, 0}, {0, -1, -50}}, Width: 4})
+	for _, tri := range c.Triangles() {
+		for _, v := range [3]Vertex{tri.V1, tri.V2, tri.V3} {
+			if x := v.Position.X(); x != 198 && x != 202 {
+				t.Fatalf("a line going away has a corner at x %v, want 198 or 202", x)
+			}
+		}
+	}
+}
+
+func TestLinesBehindTheEyeAreCutOff(t *testing.T) {
+	c := project_context()
+
+	c.PushLines(&Lines{Points: []vec3{{0, 0, 6}, {1, 0, 7}, {-1, 0, 0}, {1, 0, 0}}, Width: 2})
+	for _, tri := range c.Triangles() {
+		if tri.Index != 1 {
+			t.Fatalf("line %d behind the eye was drawn", tri.Index)
+		}
+	}
+	if len(c.Triangles()) != 2 {
+		t.Fatalf("%d triangles were pushed, want the 2 of the line in front", len(c.Triangles()))
+	}
+}
+
+func TestSprites(t *testing.T) {
+	c := project_context()
+
+	s := &Sprites{Points: []vec3{{0, 0, 6}, {0, 0, 0}}, Size: 8}
+	c.PushSprites(s)
+	triangles := c.Triangles()
+	if len(triangles) != 2 || triangles[0].Mesh != s.ID() || triangles[0].Index != 1 {
+		t.Fatalf("the sprite in front of the eye is %d triangles, want 2", len(triangles))
+	}
+	if lo, hi := spread(triangles); lo != (vec2{196, 96}) || hi != (vec2{204, 104}) {
+		t.Fatalf("the sprite covers %v to %v, want 8 pixels around the middle", lo, hi)
+	}
+	if v := triangles[0].V1; v.Position.Vec2() != (vec2{196, 96}) || v.Texcoord != (vec2{}) {
+		t.Fatalf("the top left of the texture is at %v", v.Position)
+	}
+}
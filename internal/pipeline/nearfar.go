@@ -0,0 +1,119 @@
+package pipeline
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+// perspective are the parameters of the last SetPerspective, kept so the projection can be rebuilt with
+// fitted near and far planes.
+type perspective struct {
+	fovy, aspect, near, far float
+}
+
+// near_far tracks the depth range of the scene for AutoNearFar.
+type near_far struct {
+	// near and far are the fitted planes, used while valid
+	near, far float
+	valid     bool
+
+	// scene_near and scene_far are the depth range of the bounding spheres pushed this frame, valid once
+	// `meshes` isn't 0
+	scene_near, scene_far float
+	meshes                int
+	// unbounded is set when a mesh without bounds was pushed, its depth range is unknown
+	unbounded bool
+}
+
+// SetPerspective sets a perspective projection, like SetProjection(mgl32.Perspective(...)) does, but lets
+// AutoNearFar tighten the near and far planes.
+func (c *Context) SetPerspective(fovy, aspect, near, far float) {
+	c.perspective = &perspective{fovy, aspect, near, far}
+	c.proj_matrix = mgl32.Perspective(fovy, aspect, near, far)
+}
+
+// SetProjection replaces the projection with an arbitrary matrix, AutoNearFar has no effect until the next
+// SetPerspective.
+func (c *Context) SetProjection(m mat4) {
+	c.perspective = nil
+	c.proj_matrix = m
+}
+
+// NearFar returns the near and far planes the next frame is projected with, and whether they were fitted.
+func (c *Context) NearFar() (near, far float, fitted bool) {
+	if c.perspective == nil {
+		return 0, 0, false
+	}
+	if c.AutoNearFar && c.near_far.valid {
+		return c.near_far.near, c.near_far.far, true
+	}
+	return c.perspective.near, c.perspective.far, false
+}
+
+// fitted_projection returns the projection triangles are transformed with. Fitting is based on the previous
+// frame, since the scene bounds are only known once every mesh has been pushed. Meshes rarely move far within a
+// frame and the fitted planes are padded, see fit_near_far.
+func (c *Context) fitted_projection() mat4 {
+	p := c.perspective
+	if p == nil {
+		return c.proj_matrix
+	}
+
+	near, far := p.near, p.far
+	if c.AutoNearFar && c.near_far.valid {
+		near, far = c.near_far.near, c.near_far.far
+	}
+
+	proj := mgl32.Perspective(p.fovy, p.aspect, near, far)
+	if c.ReverseZ {
+		// depth = near * (far - d) / (d * (far - near)), 1 at the near plane and 0 at the far one
+		proj[10] = near / (far - near)
+		proj[14] = near * far / (far - near)
+	}
+	return proj
+}
+
+func (c *Context) reverse_z() bool {
+	return c.ReverseZ && c.perspective != nil
+}
+
+// include_near_far extends the depth range of the frame by the bounds of a mesh which wasn't rejected.
+func (c *Context) include_near_far(m *mesh.Mesh) {
+	if !m.Bounded() {
+		c.near_far.unbounded = true
+		return
+	}
+	// the camera looks down -Z in view space
+	depth := -c.view_matrix.Mul4x1(m.Sphere.Center.Vec4(1)).Z()
+	near, far := depth-m.Sphere.Radius, depth+m.Sphere.Radius
+
+	nf := &c.near_far
+	if nf.meshes == 0 {
+		nf.scene_near, nf.scene_far = near, far
+	} else {
+		nf.scene_near = min(nf.scene_near, near)
+		nf.scene_far = max(nf.scene_far, far)
+	}
+	nf.meshes++
+}
+
+// fit_near_far fits the planes for the next frame to the depth range of this one, never beyond the planes
+// given to SetPerspective. It runs in Reset.
+func (c *Context) fit_near_far() {
+	nf := &c.near_far
+	p := c.perspective
+
+	nf.valid = false
+	if p != nil && !nf.unbounded && nf.meshes > 0 {
+		// a little slack keeps geometry which moved since the last frame from being clipped
+		const padding = 0.05
+		span := nf.scene_far - nf.scene_near
+		nf.near = max(nf.scene_near-span*padding, p.near)
+		nf.far = min(nf.scene_far+span*padding, p.far)
+		nf.valid = nf.near < nf.far
+	}
+
+	nf.meshes = 0
+	nf.unbounded = false
+}
@@ -0,0 +1,23 @@
+package pipeline
+
+// Pick returns the triangle drawn on top at (x, y) in pixels, and false when there's none. With the painter's
+// algorithm that's the last triangle covering the point, so call it after Sort and before Reset. Its Origin says
+// which mesh and mesh triangle is under the point.
+func (c *Context) Pick(x, y float) (Triangle, bool) {
+	for i := len(c.triangles) - 1; i >= 0; i-- {
+		if covers(&c.triangles[i], x, y) {
+			return c.triangles[i], true
+		}
+	}
+	return Triangle{}, false
+}
+
+// covers reports whether the point is inside the triangle or on its edges. Screen space winding depends on the
+// projection, so either is accepted.
+func covers(t *Triangle, x, y float) bool {
+	p1, p2, p3 := t.V1.Position, t.V2.Position, t.V3.Position
+	e1 := (p2.X()-p1.X())*(y-p1.Y()) - (p2.Y()-p1.Y())*(x-p1.X())
+	e2 := (p3.X()-p2.X())*(y-p2.Y()) - (p3.Y()-p2.Y())*(x-p2.X())
+	e3 := (p1.X()-p3.X())*(y-p3.Y()) - (p1.Y()-p3.Y())*(x-p3.X())
+	return (e1 >= 0 && e2 >= 0 && e3 >= 0) || (e1 <= 0 && e2 <= 0 && e3 <= 0)
+}
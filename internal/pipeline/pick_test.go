@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+// box_frame pushes a box seen from above and in front, with the usual OpenGL conventions.
+func box_frame(c *Context, box *mesh.Mesh) {
+	c.FlipY = true
+	c.SetViewport(0, 0, 400, 400)
+	c.SetPerspective(mgl32.DegToRad(60), 1, 0.1, 100)
+	c.SetView(mgl32.LookAtV(vec3{0, 3, 5}, vec3{}, vec3{0, 1, 0}))
+	c.PushMesh(box)
+	c.Sort()
+}
+
+func TestPickFindsTopmostTriangle(t *testing.T) {
+	box := mesh.Box(vec3{2, 2, 2})
+	c := &Context{}
+	box_frame(c, box)
+
+	// the top (+y) face is triangles 6 and 7 and the front (+z) face 10 and 11
+	faces := map[int32]string{6: "top", 7: "top", 10: "front", 11: "front"}
+
+	tri, ok := c.Pick(200, 150)
+	if !ok || tri.Mesh != box.ID() || faces[tri.Index] != "top" {
+		t.Fatalf("picked %v %v above the center, want the top", ok, faces[tri.Index])
+	}
+	tri, ok = c.Pick(200, 260)
+	if !ok || faces[tri.Index] != "front" {
+		t.Fatalf("picked %v %v below the center, want the front", ok, faces[tri.Index])
+	}
+	if _, ok := c.Pick(5, 5); ok {
+		t.Fatal("picked something in the corner")
+	}
+}
+
+func TestFlipYKeepsOutwardFaces(t *testing.T) {
+	box := mesh.Box(vec3{2, 2, 2})
+	c := &Context{}
+	box_frame(c, box)
+
+	// only the top and front faces are visible from above and in front
+	for _, tri := range c.Triangles() {
+		switch tri.Index {
+		case 6, 7, 10, 11:
+		default:
+			t.Fatalf("triangle %d of a hidden face survived culling", tri.Index)
+		}
+	}
+	if n := len(c.Triangles()); n != 4 {
+		t.Fatalf("got %d triangles, want 4", n)
+	}
+}
+
+func TestCullModes(t *testing.T) {
+	box := mesh.Box(vec3{2, 2, 2})
+	// from above and in front only the top and front face the camera, front-face culling keeps the other four
+	front := &Context{Cull: CullFront}
+	box_frame(front, box)
+	for _, tri := range front.Triangles() {
+		switch tri.Index {
+		case 6, 7, 10, 11:
+			t.Fatalf("triangle %d faces the camera and survived front-face culling", tri.Index)
+		}
+	}
+	if n := len(front.Triangles()); n != 8 {
+		t.Fatalf("front-face culling drew %d triangles, want 8", n)
+	}
+	both := &Context{Cull: CullNone}
+	box_frame(both, box)
+	if n := len(both.Triangles()); n != len(box.Triangles) {
+		t.Fatalf("drew %d triangles without culling, want %d", n, len(box.Triangles))
+	}
+}
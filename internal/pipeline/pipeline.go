@@ -0,0 +1,424 @@
+// Package pipeline is the CPU half of the playground's software vertex pipeline. It transforms meshes into
+// clip space, clips and culls their triangles, maps what is left to screen space and depth sorts the result
+// for the painter's algorithm. Turning the resulting triangles into draw calls is left to the render package.
+package pipeline
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/memory"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
+	mat4  = mgl32.Mat4
+)
+
+type Vertex struct {
+	Position vec4
+	Texcoord vec2
+	// Color is the light baked into the corner of the mesh, see mesh.Mesh.Colors. It's black for meshes without.
+	Color vec3
+}
+
+// Origin identifies the mesh triangle a pipeline triangle came from. Clipping can turn one mesh triangle into
+// several, they all share the same origin.
+type Origin struct {
+	// Mesh is the ID of the mesh, see mesh.Mesh.ID.
+	Mesh uint32
+	// Index is the index of the triangle within the mesh.
+	Index int32
+	// Material is the material of the triangle, see mesh.Triangle.
+	Material uint16
+}
+
+// Triangle is a triangle which survived the pipeline. The X and Y of each position are in pixels, where pixel
+// (i, j) covers [i, i+1) x [j, j+1) and is sampled at its center (i+0.5, j+0.5). They're snapped to
+// 1/subpixel_steps of a pixel. Z is the depth in NDC (from 1 to 0 with ReverseZ) and W is retained from clip
+// space so texturing can be perspective corrected.
+type Triangle struct {
+	V1, V2, V3 Vertex
+	Distance   float
+
+	Origin
+
+	// source is the index of the Submission this triangle came from, only valid while capturing
+	source int32
+}
+
+type viewport struct {
+	x   int
+	y   int
+	w   int
+	h   int
+	w_2 float
+	h_2 float
+}
+
+// subpixel_steps is the precision screen positions are snapped to. Vertices shared by neighbouring triangles,
+// including the ones the clipper creates on both sides of an edge, then land on exactly the same position and
+// the edge is neither left open nor covered twice.
+const subpixel_steps = 16
+
+// Stats are statistics about a frame which went through the pipeline.
+type Stats struct {
+	Meshes    int
+	Triangles int
+	// Rejected is the number of meshes whose bounding sphere was outside the view, their points were never transformed.
+	Rejected int
+	// MeshBytes is the memory of the meshes pushed, see mesh.Mesh.Bytes.
+	MeshBytes int
+}
+
+// buffers counts the memory of the buffers of every context.
+var buffers = memory.NewCounter("pipeline buffers")
+
+// Cull is which way a triangle faces to be left out.
+type Cull int
+
+const (
+	CullBack Cull = iota
+	CullFront
+	CullNone
+)
+
+type Context struct {
+	view_matrix mat4
+	proj_matrix mat4
+	// viewport is used to convert normalized device coordinates to screen coordinates
+	viewport viewport
+
+	// Stats are the statistics of the last frame, they're updated by Reset.
+	Stats Stats
+	stats Stats
+
+	// SortBuckets is the number of depth buckets Sort quantizes distances into, more buckets order triangles
+	// which are close together more precisely. 0 uses a sensible default.
+	SortBuckets int
+	// ExactSort makes Sort compare distances exactly instead of bucketing them. It's slower for large frames.
+	ExactSort bool
+
+	// AutoNearFar fits the near and far planes of a SetPerspective projection to the bounds of the meshes in
+	// view, which spreads depth precision over what is actually visible.
+	AutoNearFar bool
+
+	// ReverseZ maps depth from 1 at the near plane to 0 at the far plane, instead of -1 to 1. Floats are densest
+	// around 0, which then lines up with the far plane where the perspective divide squeezes depth the most.
+	// It only applies to projections set with SetPerspective.
+	ReverseZ bool
+
+	// PolygonOffset pulls every triangle pushed while it's set towards the camera in the depth sort, by that
+	// much NDC depth. Coplanar geometry drawn over other geometry, such as decals, selection highlights or a grid
+	// over terrain, then always sorts in front instead of flickering. Set it before PushMesh and back to 0 after.
+	// The bucket sort only sees offsets larger than a bucket, so pushing such geometry last is still a good idea.
+	PolygonOffset float
+
+	// Cull is which triangles are left out by the way they face, the back faces unless it's set otherwise. Like
+	// PolygonOffset it applies to the meshes pushed while it's set.
+	Cull Cull
+
+	// FlipY maps NDC +Y to the top of the viewport, as OpenGL does. Without it +Y is the bottom, which the first
+	// demos compensate for by accident: a field of view of 30 passed to mgl32.Perspective as radians turns the
+	// picture upside down (and mirrors it). Culling happens in NDC, so counter-clockwise triangles face the camera
+	// either way.
+	FlipY bool
+
+	// GuardBand clips triangles against sides of the view volume GuardBand times as far out as the view's own, so
+	// triangles which only poke out of the view a little go to the rasterizer whole, which cuts them to the target
+	// for free, and only the ones reaching far out are clipped. Near and far are clipped as ever. Up to 1 clips to
+	// the view.
+	GuardBand float
+
+	// Serial transforms every mesh on the calling goroutine, even ones large enough to be split across the job
+	// pool, for measuring what the pool is worth.
+	Serial bool
+
+	perspective *perspective
+	near_far    near_far
+
+	capture       *Capture // the capture being recorded this frame
+	last_capture  *Capture // the last completed capture
+	capture_armed bool     // a capture should begin with the next frame
+	source        int32    // the submission index of the triangle being pushed
+	origin        Origin   // the origin of the triangle being pushed
+
+	// the following are not required to be stored here,
+	// they serve as buffers to reduce overall allocations.
+
+	clip_space_points []vec4
+	clip_soa          clip_points
+	triangles         []Triangle
+	sorted            []Triangle
+	sort_counts       []int32
+	// memory counts the buffers above on buffers, in the same order
+	memory [8]memory.Buffer
+}
+
+func (c *Context) guard_band() float {
+	return max(c.GuardBand, 1)
+}
+
+func (c *Context) SetViewport(x, y, w, h int) {
+	c.viewport.x = x
+	c.viewport.y = y
+	c.viewport.w = w
+	c.viewport.h = h
+	c.viewport.w_2 = float(w) / 2
+	c.viewport.h_2 = float(h) / 2
+}
+
+func (c *Context) Viewport() (x, y, w, h int) {
+	return c.viewport.x, c.viewport.y, c.viewport.w, c.viewport.h
+}
+
+func (c *Context) SetView(m mat4) {
+	c.view_matrix = m
+}
+
+func (c *Context) ProjectionMatrix() mat4 {
+	return c.proj_matrix
+}
+
+func (c *Context) ViewMatrix() mat4 {
+	return c.view_matrix
+}
+
+func (c *Context) clip_to_ndc(src vec4) (ndc vec4) {
+	inv_w := 1.0 / src.W()
+	ndc = vec4{
+		src.X() * inv_w,
+		src.Y() * inv_w,
+		src.Z() * inv_w,
+		src.W(), //  retain W for later
+	}
+	return
+}
+
+// ndc_to_screen maps -1 and 1 to the outer edges of the viewport, not to the centers of its outer pixels.
+func (c *Context) ndc_to_screen(src vec4) vec4 {
+	w_2 := c.viewport.w_2
+	h_2 := c.viewport.h_2
+	y := src.Y()
+	if c.FlipY {
+		y = -y
+	}
+	return vec4{
+		snap(w_2*src.X() + w_2 + float(c.viewport.x)),
+		snap(h_2*y + h_2 + float(c.viewport.y)),
+		src.Z(),
+		src.W(),
+	}
+}
+
+// snap rounds a screen coordinate to the sub-pixel grid.
+func snap(f float) float {
+	return float(math.Round(float64(f)*subpixel_steps)) / subpixel_steps
+}
+
+func (ctx *Context) PushMesh(mesh *mesh.Mesh) {
+	ctx.stats.Meshes++
+	ctx.stats.MeshBytes += mesh.Bytes()
+
+	// meshes are rejected against the projection as it was set, fitted planes only ever make it tighter
+	if mesh.Bounded() {
+		frustum := frustum_from(ctx.proj_matrix.Mul4(ctx.view_matrix))
+		if frustum.rejects(mesh.Sphere) {
+			ctx.stats.Rejected++
+			return
+		}
+	}
+
+	if ctx.AutoNearFar {
+		ctx.include_near_far(mesh)
+	}
+
+	// save us some calculations by doing this here instead of per point
+	projection_view_matrix := ctx.fitted_projection().Mul4(ctx.view_matrix)
+
+	// transform all the mesh points into clip space
+	if mesh.SoA != nil {
+		transform_soa(projection_view_matrix, mesh.SoA, &ctx.clip_soa, ctx.Serial)
+		ctx.clip_space_points = ctx.clip_soa.append_vec4(ctx.clip_space_points[:0])
+	} else {
+		ctx.clip_space_points = transform_aos(projection_view_matrix, mesh.Points, ctx.clip_space_points[:0])
+	}
+
+	mesh_id := mesh.ID()
+	reverse_z := ctx.reverse_z()
+
+	for i, triangle := range mesh.Triangles {
+		ctx.origin = Origin{
+			Mesh:     mesh_id,
+			Index:    int32(i),
+			Material: triangle.Material,
+		}
+
+		v1 := Vertex{
+			Position: ctx.clip_space_points[triangle.P1],
+			Texcoord: mesh.Texcoords[triangle.T1],
+		}
+		v2 := Vertex{
+			Position: ctx.clip_space_points[triangle.P2],
+			Texcoord: mesh.Texcoords[triangle.T2],
+		}
+		v3 := Vertex{
+			Position: ctx.clip_space_points[triangle.P3],
+			Texcoord: mesh.Texcoords[triangle.T3],
+		}
+
+		if len(mesh.Colors) == 3*len(mesh.Triangles) {
+			v1.Color, v2.Color, v3.Color = mesh.Colors[3*i], mesh.Colors[3*i+1], mesh.Colors[3*i+2]
+		}
+
+		ctx.submit(v1, v2, v3, reverse_z)
+	}
+}
+
+// submit clips the triangle in clip space with ctx.origin as its origin, if it has to be, and pushes what's left.
+func (ctx *Context) submit(v1, v2, v3 Vertex, reverse_z bool) {
+	g := ctx.guard_band()
+	clip := clip_out_of_bounds(v1.Position, reverse_z, g) || clip_out_of_bounds(v2.Position, reverse_z, g) || clip_out_of_bounds(v3.Position, reverse_z, g)
+
+	if ctx.capture != nil {
+		ctx.source = ctx.capture.submit(ctx.stats.Meshes-1, ctx.origin, v1, v2, v3, clip)
+	}
+
+	if clip {
+		ctx.clip_triangle_and_push(v1, v2, v3)
+	} else {
+		ctx.capture_clipped(v1, v2, v3)
+		ctx.push_triangle(v1, v2, v3)
+	}
+}
+
+func (c *Context) clip_triangle_and_push(v1, v2, v3 Vertex) {
+	planes := clip_planes
+	if c.reverse_z() {
+		planes = clip_planes_reverse_z
+	}
+	if g := c.guard_band(); g > 1 {
+		planes = guard_band_planes(planes, g)
+	}
+	polygon := polygons.Get()
+	defer polygons.Put(polygon)
+	points := sutherland_hodgman_3d(v1.Position, v2.Position, v3.Position, planes[:], polygon)
+
+	p1 := v1.Position.Vec3()
+	p2 := v2.Position.Vec3()
+	p3 := v3.Position.Vec3()
+
+	for i := 2; i < len(points); i++ {
+		b1 := barycentric(p1, p2, p3, points[0].Vec3())
+		b2 := barycentric(p1, p2, p3, points[i-1].Vec3())
+		b3 := barycentric(p1, p2, p3, points[i].Vec3())
+
+		c1 := interpolate_vertex(v1, v2, v3, b1)
+		c2 := interpolate_vertex(v1, v2, v3, b2)
+		c3 := interpolate_vertex(v1, v2, v3, b3)
+
+		c.capture_clipped(c1, c2, c3)
+		c.push_triangle(c1, c2, c3)
+	}
+}
+
+func (c *Context) push_triangle(v1, v2, v3 Vertex) {
+	ndc1 := c.clip_to_ndc(v1.Position)
+	ndc2 := c.clip_to_ndc(v2.Position)
+	ndc3 := c.clip_to_ndc(v3.Position)
+
+	// back-face culling, counter-clockwise triangles face the camera
+	area := (ndc2.X()-ndc1.X())*(ndc3.Y()-ndc1.Y()) - (ndc3.X()-ndc1.X())*(ndc2.Y()-ndc1.Y())
+	switch c.Cull {
+	case CullBack:
+		if area <= 0 {
+			return
+		}
+	case CullFront:
+		if area >= 0 {
+			return
+		}
+	}
+
+	v1.Position = c.ndc_to_screen(ndc1)
+	v2.Position = c.ndc_to_screen(ndc2)
+	v3.Position = c.ndc_to_screen(ndc3)
+
+	distance := (v1.Position.Z() + v2.Position.Z() + v3.Position.Z()) / 3
+	// nearer is smaller, or larger with reverse-Z
+	if c.reverse_z() {
+		distance += c.PolygonOffset
+	} else {
+		distance -= c.PolygonOffset
+	}
+
+	c.triangles = append(c.triangles, Triangle{
+		V1:       v1,
+		V2:       v2,
+		V3:       v3,
+		Distance: distance,
+		Origin:   c.origin,
+		source:   c.source,
+	})
+}
+
+// Sort sorts the triangles back to front, by bucketing their distance unless ExactSort is set.
+func (ctx *Context) Sort() {
+	if ctx.capture != nil {
+		ctx.capture.Unsorted = ctx.capture.record(ctx.capture.Unsorted, ctx.triangles)
+	}
+
+	if ctx.ExactSort {
+		sort_back_to_front(ctx.triangles, ctx.reverse_z())
+	} else {
+		buckets := ctx.SortBuckets
+		if buckets <= 0 {
+			buckets = default_sort_buckets
+		}
+		ctx.sorted, ctx.sort_counts = bucket_sort(ctx.triangles, ctx.sorted, buckets, ctx.reverse_z(), ctx.sort_counts)
+		ctx.triangles, ctx.sorted = ctx.sorted, ctx.triangles
+	}
+
+	if ctx.capture != nil {
+		ctx.capture.Sorted = ctx.capture.record(ctx.capture.Sorted, ctx.triangles)
+	}
+}
+
+// Triangles returns every triangle pushed this frame. The slice is only valid until Reset is called.
+func (ctx *Context) Triangles() []Triangle {
+	return ctx.triangles
+}
+
+// Reset ends the frame: statistics are published, a capture in progress is completed and the buffers
+// are cleared for the next frame.
+func (ctx *Context) Reset() {
+	ctx.stats.Triangles = len(ctx.triangles)
+	ctx.Stats = ctx.stats
+	ctx.stats = Stats{}
+
+	ctx.finish_capture()
+	ctx.fit_near_far()
+
+	ctx.clip_space_points = ctx.clip_space_points[:0]
+	ctx.triangles = ctx.triangles[:0]
+	ctx.count_buffers()
+}
+
+// count_buffers counts how far the buffers grew on buffers.
+func (ctx *Context) count_buffers() {
+	m := &ctx.memory
+	m[0].Track(buffers, cap(ctx.clip_space_points), unsafe.Sizeof(vec4{}))
+	m[1].Track(buffers, cap(ctx.clip_soa.x), 4)
+	m[2].Track(buffers, cap(ctx.clip_soa.y), 4)
+	m[3].Track(buffers, cap(ctx.clip_soa.z), 4)
+	m[4].Track(buffers, cap(ctx.clip_soa.w), 4)
+	m[5].Track(buffers, cap(ctx.triangles), unsafe.Sizeof(Triangle{}))
+	m[6].Track(buffers, cap(ctx.sorted), unsafe.Sizeof(Triangle{}))
+	m[7].Track(buffers, cap(ctx.sort_counts), 4)
+}
@@ -0,0 +1,42 @@
+package pipeline
+
+// near_w is the smallest clip space W kept when projecting, points closer to the eye are cut off.
+const near_w = 1e-3
+
+// Project returns where the world space point `p` ends up on screen, in pixels, with the current view,
+// projection and viewport. It's false for points behind the eye. Meant for debug overlays, the result is not
+// snapped like triangle vertices are.
+func (c *Context) Project(p vec3) (vec2, bool) {
+	clip := c.proj_matrix.Mul4(c.view_matrix).Mul4x1(p.Vec4(1))
+	if clip.W() < near_w {
+		return vec2{}, false
+	}
+	return c.clip_to_pixels(clip), true
+}
+
+// ProjectLine projects the world space segment from `a` to `b`, cutting off the part behind the eye. It's false
+// when nothing is left.
+func (c *Context) ProjectLine(a, b vec3) (vec2, vec2, bool) {
+	m := c.proj_matrix.Mul4(c.view_matrix)
+	ca, cb := m.Mul4x1(a.Vec4(1)), m.Mul4x1(b.Vec4(1))
+	switch {
+	case ca.W() < near_w && cb.W() < near_w:
+		return vec2{}, vec2{}, false
+	case ca.W() < near_w:
+		ca = ca.Add(cb.Sub(ca).Mul((near_w - ca.W()) / (cb.W() - ca.W())))
+	case cb.W() < near_w:
+		cb = cb.Add(ca.Sub(cb).Mul((near_w - cb.W()) / (ca.W() - cb.W())))
+	}
+	return c.clip_to_pixels(ca), c.clip_to_pixels(cb), true
+}
+
+func (c *Context) clip_to_pixels(clip vec4) vec2 {
+	x, y := clip.X()/clip.W(), clip.Y()/clip.W()
+	if c.FlipY {
+		y = -y
+	}
+	return vec2{
+		c.viewport.w_2*x + c.viewport.w_2 + float(c.viewport.x),
+		c.viewport.h_2*y + c.viewport.h_2 + float(c.viewport.y),
+	}
+}
@@ -0,0 +1,65 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+func project_context() *Context {
+	c := &Context{FlipY: true}
+	c.SetViewport(0, 0, 400, 200)
+	c.SetPerspective(mgl32.DegToRad(90), 2, 0.1, 100)
+	c.SetView(mgl32.LookAtV(vec3{0, 0, 5}, vec3{}, vec3{0, 1, 0}))
+	return c
+}
+
+func TestProject(t *testing.T) {
+	c := project_context()
+
+	p, ok := c.Project(vec3{})
+	if !ok || p != (vec2{200, 100}) {
+		t.Fatalf("the point looked at is at %v %v, want the middle of the viewport", p, ok)
+	}
+	// 45 degrees up is the top edge
+	if p, ok := c.Project(vec3{0, 5, 0}); !ok || mgl32.Abs(p.Y()) > 1e-3 {
+		t.Fatalf("the top edge projects to %v %v", p, ok)
+	}
+	if _, ok := c.Project(vec3{0, 0, 6}); ok {
+		t.Fatal("a point behind the eye was projected")
+	}
+}
+
+func TestProjectLineCutsOffBehindTheEye(t *testing.T) {
+	c := project_context()
+
+	a, b, ok := c.ProjectLine(vec3{}, vec3{1, 0, 0})
+	if !ok || a != (vec2{200, 100}) || b.X() <= 200 {
+		t.Fatalf("a line in front of the eye projects to %v %v %v", a, b, ok)
+	}
+	if _, _, ok := c.ProjectLine(vec3{0, 0, 6}, vec3{1, 0, 7}); ok {
+		t.Fatal("a line behind the eye was projected")
+	}
+	// from in front to behind, the end behind is moved to just in front of the eye, far off to the side
+	a, b, ok = c.ProjectLine(vec3{0, 0, 0}, vec3{1, 0, 10})
+	if !ok || a != (vec2{200, 100}) || b.X() < 1000 {
+		t.Fatalf("a line through the eye plane projects to %v %v %v", a, b, ok)
+	}
+}
+
+func TestVisible(t *testing.T) {
+	c := project_context()
+
+	if !c.Visible(mesh.Sphere{Radius: 1}) {
+		t.Fatal("a sphere in the middle of the view isn't visible")
+	}
+	if c.Visible(mesh.Sphere{Center: vec3{0, 0, 8}, Radius: 1}) {
+		t.Fatal("a sphere behind the eye is visible")
+	}
+	// off to the side, but reaching into the view
+	if !c.Visible(mesh.Sphere{Center: vec3{0, 6, 0}, Radius: 1}) {
+		t.Fatal("a sphere poking into the top of the view isn't visible")
+	}
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"cmp"
+	"slices"
+)
+
+// default_sort_buckets is the number of depth buckets used when Context.SortBuckets is 0.
+const default_sort_buckets = 1 << 12
+
+// sort_back_to_front orders triangles by descending distance, or ascending when `reverse_z` is set since depth then
+// goes from 1 at the near plane to 0 at the far plane. Equal distances keep their submission order.
+func sort_back_to_front(triangles []Triangle, reverse_z bool) {
+	if reverse_z {
+		slices.SortStableFunc(triangles, func(a, b Triangle) int {
+			return cmp.Compare(a.Distance, b.Distance)
+		})
+		return
+	}
+	slices.SortStableFunc(triangles, func(a, b Triangle) int {
+		return cmp.Compare(b.Distance, a.Distance)
+	})
+}
+
+// bucket_sort orders `src` back to front into `dst` with a counting sort on the distance quantized into
+// `buckets` steps between the nearest and farthest triangle. It's linear in the number of triangles, but
+// triangles closer together than a bucket stay in submission order. `counts` is scratch space.
+func bucket_sort(src, dst []Triangle, buckets int, reverse_z bool, counts []int32) ([]Triangle, []int32) {
+	dst = slices.Grow(dst[:0], len(src))[:len(src)]
+	if len(src) == 0 {
+		return dst, counts
+	}
+
+	lo, hi := src[0].Distance, src[0].Distance
+	for _, t := range src[1:] {
+		lo = min(lo, t.Distance)
+		hi = max(hi, t.Distance)
+	}
+
+	scale := float(0)
+	if hi > lo {
+		scale = float(buckets-1) / (hi - lo)
+	}
+
+	// the farthest triangles go into the first bucket
+	key := func(t *Triangle) int {
+		k := int((t.Distance - lo) * scale)
+		if !reverse_z {
+			k = buckets - 1 - k
+		}
+		return min(max(k, 0), buckets-1)
+	}
+
+	counts = slices.Grow(counts[:0], buckets+1)[:buckets+1]
+	clear(counts)
+	for i := range src {
+		counts[key(&src[i])+1]++
+	}
+	for i := 1; i <= buckets; i++ {
+		counts[i] += counts[i-1]
+	}
+	for i := range src {
+		k := key(&src[i])
+		dst[counts[k]] = src[i]
+		counts[k]++
+	}
+	return dst, counts
+}
@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func random_triangles(n int) []Triangle {
+	rng := rand.New(rand.NewSource(1))
+	triangles := make([]Triangle, n)
+	for i := range triangles {
+		triangles[i].Distance = rng.Float32()*2 - 1
+		triangles[i].source = int32(i)
+	}
+	return triangles
+}
+
+func TestBucketSortOrder(t *testing.T) {
+	src := random_triangles(10000)
+	// duplicates must keep their submission order
+	src = append(src, src[:100]...)
+
+	const buckets = 1 << 16
+	dst, _ := bucket_sort(src, nil, buckets, false, nil)
+
+	if len(dst) != len(src) {
+		t.Fatalf("got %d triangles, want %d", len(dst), len(src))
+	}
+
+	step := float(2.0 / buckets)
+	for i := 1; i < len(dst); i++ {
+		if dst[i].Distance > dst[i-1].Distance+step {
+			t.Fatalf("triangle %d at %f is behind triangle %d at %f", i, dst[i].Distance, i-1, dst[i-1].Distance)
+		}
+	}
+}
+
+func TestExactSortIsStable(t *testing.T) {
+	triangles := []Triangle{
+		{Distance: 0.5, source: 0},
+		{Distance: 0.9, source: 1},
+		{Distance: 0.5, source: 2},
+		{Distance: 0.1, source: 3},
+		{Distance: 0.5, source: 4},
+	}
+	sort_back_to_front(triangles, false)
+
+	var got []int32
+	for _, t := range triangles {
+		got = append(got, t.source)
+	}
+	if want := []int32{1, 0, 2, 4, 3}; !slices.Equal(got, want) {
+		t.Fatalf("got order %v, want %v", got, want)
+	}
+}
+
+const benchmark_triangles = 1 << 16
+
+func BenchmarkSortExact(b *testing.B) {
+	src := random_triangles(benchmark_triangles)
+	triangles := make([]Triangle, len(src))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(triangles, src)
+		sort_back_to_front(triangles, false)
+	}
+}
+
+func BenchmarkSortBuckets(b *testing.B) {
+	src := random_triangles(benchmark_triangles)
+	var dst []Triangle
+	var counts []int32
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst, counts = bucket_sort(src, dst, default_sort_buckets, false, counts)
+	}
+}
@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/jobs"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+// parallel_points is how many points a mesh needs before transform_soa splits it across the job pool, and how
+// many points each job transforms. Below that the overhead of the jobs outweighs the work.
+const parallel_points = 1 << 14
+
+// transform_aos transforms every point by `m`, appending the results to `dst`.
+func transform_aos(m mat4, points []vec3, dst []vec4) []vec4 {
+	for _, point := range points {
+		dst = append(dst, m.Mul4x1(point.Vec4(1)))
+	}
+	return dst
+}
+
+// clip_points are clip space positions in a structure-of-arrays layout.
+type clip_points struct {
+	x, y, z, w []float
+}
+
+func (c *clip_points) resize(n int) {
+	if cap(c.x) < n {
+		c.x = make([]float, n)
+		c.y = make([]float, n)
+		c.z = make([]float, n)
+		c.w = make([]float, n)
+	}
+	c.x = c.x[:n]
+	c.y = c.y[:n]
+	c.z = c.z[:n]
+	c.w = c.w[:n]
+}
+
+// transform_soa transforms every point by `m` into `dst`, in parallel for large meshes unless `serial`.
+func transform_soa(m mat4, src *mesh.Points, dst *clip_points, serial bool) {
+	n := src.Len()
+	dst.resize(n)
+
+	if serial {
+		transform_soa_range(m, src, dst, 0, n)
+		return
+	}
+	jobs.Default.ParallelFor(n, parallel_points, func(start, end int) {
+		transform_soa_range(m, src, dst, start, end)
+	})
+}
+
+// transform_soa_range transforms the points in [start, end). The slices are resliced to the same length up
+// front so the compiler can drop the bounds checks inside the loop.
+func transform_soa_range(m mat4, src *mesh.Points, dst *clip_points, start, end int) {
+	xs, ys, zs := src.X[start:end], src.Y[start:end], src.Z[start:end]
+	n := len(xs)
+	ys, zs = ys[:n], zs[:n]
+	ox, oy, oz, ow := dst.x[start:end][:n], dst.y[start:end][:n], dst.z[start:end][:n], dst.w[start:end][:n]
+
+	// mgl32 matrices are column major
+	m00, m01, m02, m03 := m[0], m[4], m[8], m[12]
+	m10, m11, m12, m13 := m[1], m[5], m[9], m[13]
+	m20, m21, m22, m23 := m[2], m[6], m[10], m[14]
+	m30, m31, m32, m33 := m[3], m[7], m[11], m[15]
+
+	for i := range xs {
+		x, y, z := xs[i], ys[i], zs[i]
+		ox[i] = m00*x + m01*y + m02*z + m03
+		oy[i] = m10*x + m11*y + m12*z + m13
+		oz[i] = m20*x + m21*y + m22*z + m23
+		ow[i] = m30*x + m31*y + m32*z + m33
+	}
+}
+
+// append_vec4 interleaves the points back into vectors, which is what the rest of the pipeline works with.
+func (c *clip_points) append_vec4(dst []vec4) []vec4 {
+	for i := range c.x {
+		dst = append(dst, vec4{c.x[i], c.y[i], c.z[i], c.w[i]})
+	}
+	return dst
+}
@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+const benchmark_points = 1 << 16
+
+func random_points(n int) []vec3 {
+	rng := rand.New(rand.NewSource(1))
+	points := make([]vec3, n)
+	for i := range points {
+		points[i] = vec3{rng.Float32()*20 - 10, rng.Float32()*20 - 10, rng.Float32()*20 - 10}
+	}
+	return points
+}
+
+func benchmark_matrix() mat4 {
+	proj := mgl32.Perspective(mgl32.DegToRad(60), 4.0/3.0, 0.1, 100)
+	view := mgl32.LookAtV(vec3{0, 7, 19}, vec3{}, vec3{0, 1, 0})
+	return proj.Mul4(view)
+}
+
+func TestTransformSoAMatchesAoS(t *testing.T) {
+	points := random_points(1000)
+	m := benchmark_matrix()
+
+	aos := transform_aos(m, points, nil)
+
+	var soa clip_points
+	transform_soa(m, mesh.NewPoints(points), &soa, false)
+
+	for i, want := range aos {
+		got := vec4{soa.x[i], soa.y[i], soa.z[i], soa.w[i]}
+		if !got.ApproxEqualThreshold(want, 1e-4) {
+			t.Fatalf("point %d: soa %v, aos %v", i, got, want)
+		}
+	}
+}
+
+func BenchmarkTransformAoS(b *testing.B) {
+	points := random_points(benchmark_points)
+	m := benchmark_matrix()
+	dst := make([]vec4, 0, len(points))
+
+	b.SetBytes(int64(len(points) * 12))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst = transform_aos(m, points, dst[:0])
+	}
+}
+
+func BenchmarkTransformSoA(b *testing.B) {
+	points := mesh.NewPoints(random_points(benchmark_points))
+	m := benchmark_matrix()
+	var dst clip_points
+
+	b.SetBytes(int64(points.Len() * 12))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		transform_soa(m, points, &dst, false)
+	}
+}
+
+// the pipeline benchmarks include the interleaving the SoA path needs before clipping
+func benchmark_push_mesh(b *testing.B, soa bool) {
+	const n = 128
+	m := &mesh.Mesh{Texcoords: []vec2{{}}}
+	for z := 0; z < n; z++ {
+		for x := 0; x < n; x++ {
+			m.Points = append(m.Points, vec3{float(x)/n*10 - 5, 0, float(z)/n*10 - 5})
+		}
+	}
+	for z := 0; z < n-1; z++ {
+		for x := 0; x < n-1; x++ {
+			i := uint16(z*n + x)
+			m.Triangles = append(m.Triangles,
+				mesh.Triangle{P1: i + n, P2: i + 1, P3: i},
+				mesh.Triangle{P1: i + n + 1, P2: i + 1, P3: i + n},
+			)
+		}
+	}
+	if soa {
+		m.StoreSoA()
+	}
+
+	var ctx Context
+	ctx.SetViewport(0, 0, 800, 600)
+	ctx.SetProjection(mgl32.Perspective(mgl32.DegToRad(60), 4.0/3.0, 0.1, 100))
+	ctx.SetView(mgl32.LookAtV(vec3{0, 7, 19}, vec3{}, vec3{0, 1, 0}))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx.PushMesh(m)
+		ctx.Reset()
+	}
+}
+
+func BenchmarkPushMeshAoS(b *testing.B) {
+	benchmark_push_mesh(b, false)
+}
+
+func BenchmarkPushMeshSoA(b *testing.B) {
+	benchmark_push_mesh(b, true)
+}
@@ -0,0 +1,60 @@
+// Package pool keeps what a hot loop needs for a moment, like the scratch slices of a function, to be used again
+// instead of allocated every time and left for the garbage collector. A Pool hands out values made by its make
+// function and takes them back through its reset hook, which readies them for the next Get and lets go of what
+// they point at. Pools are safe to use from several goroutines at once, unlike scratch kept in a global.
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Pool is a pool of values of T.
+type Pool[T any] struct {
+	pool  sync.Pool
+	reset func(x *T)
+	// made counts the values make made, when Get found the pool empty
+	made atomic.Int64
+}
+
+// New makes a pool of the values of `make`. `reset` readies a value put back for the next Get, it may be nil for
+// values which need nothing done.
+func New[T any](make func() *T, reset func(x *T)) *Pool[T] {
+	p := &Pool[T]{reset: reset}
+	p.pool.New = func() any {
+		p.made.Add(1)
+		return make()
+	}
+	return p
+}
+
+// Get takes a value from the pool, or makes one when there's none.
+func (p *Pool[T]) Get() *T {
+	return p.pool.Get().(*T)
+}
+
+// Put gives `x` back to the pool once it's reset. It mustn't be used after.
+func (p *Pool[T]) Put(x *T) {
+	if p.reset != nil {
+		p.reset(x)
+	}
+	p.pool.Put(x)
+}
+
+// Made is how many values the pool made, which stops going up once it holds enough for the loop using it. The
+// garbage collector may empty a pool, so it never quite stops.
+func (p *Pool[T]) Made() int64 {
+	return p.made.Load()
+}
+
+// Slices makes a pool of slices of E with room for `capacity` elements to begin with. They're put back empty,
+// with their elements zeroed so they don't keep anything alive.
+func Slices[E any](capacity int) *Pool[[]E] {
+	return New(func() *[]E {
+		s := make([]E, 0, capacity)
+		return &s
+	}, func(s *[]E) {
+		clear((*s)[:cap(*s)])
+		*s = (*s)[:0]
+	})
+}
@@ -0,0 +1,71 @@
+package pool
+
+import "testing"
+
+type scratch struct {
+	values []int
+	owner  *int
+}
+
+func TestPutResets(t *testing.T) {
+	resets := 0
+	p := New(func() *scratch { return &scratch{} }, func(s *scratch) {
+		resets++
+		s.values, s.owner = s.values[:0], nil
+	})
+
+	s := p.Get()
+	if s == nil || p.Made() != 1 {
+		t.Fatalf("got %v, made %d, want a value made for it", s, p.Made())
+	}
+	s.values = append(s.values, 1, 2, 3)
+	s.owner = new(int)
+	p.Put(s)
+	if resets != 1 || len(s.values) != 0 || s.owner != nil {
+		t.Fatalf("put back as %+v after %d resets, want it reset once", s, resets)
+	}
+
+	// whatever comes out next, it's made fresh or reset
+	if again := p.Get(); len(again.values) != 0 || again.owner != nil {
+		t.Fatalf("got %+v, want an empty value", again)
+	}
+}
+
+func TestNilReset(t *testing.T) {
+	p := New(func() *int { return new(int) }, nil)
+	x := p.Get()
+	*x = 4
+	p.Put(x)
+}
+
+func TestSlicesComeBackEmpty(t *testing.T) {
+	p := Slices[*int](8)
+	s := p.Get()
+	if len(*s) != 0 || cap(*s) != 8 {
+		t.Fatalf("got a slice of %d with room for %d, want an empty one with room for 8", len(*s), cap(*s))
+	}
+	*s = append(*s, new(int), new(int))
+	kept := *s
+	p.Put(s)
+	if len(*s) != 0 {
+		t.Fatalf("put back a slice of %d, want it empty", len(*s))
+	}
+	// the elements are zeroed so the pool doesn't keep what they point at alive
+	if kept[0] != nil || kept[1] != nil {
+		t.Fatalf("the elements of a slice put back are %v, want them zeroed", kept)
+	}
+}
+
+func TestReuseAllocatesNothing(t *testing.T) {
+	p := Slices[int](16)
+	// warmed up, the pool hands back what was put in
+	p.Put(p.Get())
+	allocs := testing.AllocsPerRun(100, func() {
+		s := p.Get()
+		*s = append(*s, 1, 2, 3)
+		p.Put(s)
+	})
+	if allocs > 0.1 {
+		t.Fatalf("%v allocations getting and putting a slice, want none", allocs)
+	}
+}
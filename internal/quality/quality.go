@@ -0,0 +1,161 @@
+// Package quality holds a frame rate by trading looks for speed. A Governor watches how long frames take and
+// steps through tiers of settings, the render scale, the anti-aliasing and how many particles there are, down
+// when frames are too slow and back up when there's time to spare. It waits for a frame rate to last before
+// stepping, and a tier it had to step down from again is tried less and less often, so it settles instead of
+// flickering between two tiers.
+package quality
+
+import "math"
+
+type float = float32
+
+// Tier is a set of settings.
+type Tier struct {
+	Name string
+	// Scale is how much of the width and height of the screen the frame is drawn at before it's scaled up to it.
+	Scale float
+	// AA is the anti-aliasing, as a render.AA.
+	AA int
+	// Particles is how many of the particles, instances, blades of grass and the like are drawn, from 0 to 1.
+	Particles float
+}
+
+// Tiers are the tiers NewGovernor steps through, from the cheapest to the best looking. Their AA is off, fxaa,
+// ebiten's and 2x in turn.
+var Tiers = []Tier{
+	{Name: "lowest", Scale: 0.5, AA: 0, Particles: 0.25},
+	{Name: "low", Scale: 0.75, AA: 4, Particles: 0.5},
+	{Name: "medium", Scale: 1, AA: 4, Particles: 0.75},
+	{Name: "high", Scale: 1, AA: 1, Particles: 1},
+	{Name: "ultra", Scale: 1, AA: 2, Particles: 1},
+}
+
+// max_backoff is how many times as long as Up a tier which keeps failing is waited for at most, as a power of 2.
+const max_backoff = 4
+
+// Governor picks the tier to draw frames at.
+type Governor struct {
+	Tiers []Tier
+	// Target is the frame rate to hold, in frames a second. With vsync on it has to be below the refresh rate, or
+	// frames never look fast enough to step up.
+	Target float
+	// Slack is how much slower than the target frames may be before the governor steps down, and Headroom how
+	// much faster they have to be before it steps up, as fractions of the time of a frame at the target.
+	Slack    float
+	Headroom float
+	// Down and Up are how many seconds frames have to be too slow, or fast enough, before stepping.
+	Down float
+	Up   float
+	// Settle is how many seconds of frames are ignored after a step, the first frames of a tier pay for the
+	// targets it resizes.
+	Settle float
+
+	tier int
+	// average is the frame time, smoothed
+	average float
+	// slow and fast are how long frames have been too slow or fast enough
+	slow, fast float
+	settle     float
+	// climbed is whether the last step was up, failed is how often stepping up to a tier had to be undone
+	climbed bool
+	failed  []int
+}
+
+// NewGovernor makes a governor holding `target` frames a second with Tiers, starting at the best looking.
+func NewGovernor(target float) *Governor {
+	return &Governor{
+		Tiers:    Tiers,
+		Target:   target,
+		Slack:    0.1,
+		Headroom: 0.3,
+		Down:     0.5,
+		Up:       3,
+		Settle:   0.5,
+		tier:     len(Tiers) - 1,
+	}
+}
+
+// Frame tells the governor the last frame took `dt` seconds, and returns true when it changed the tier.
+func (g *Governor) Frame(dt float) bool {
+	if dt <= 0 || len(g.Tiers) == 0 {
+		return false
+	}
+	if g.settle > 0 {
+		g.settle -= dt
+		return false
+	}
+	if g.average == 0 {
+		g.average = dt
+	} else {
+		g.average += (dt - g.average) * 0.1
+	}
+
+	budget := 1 / g.Target
+	switch {
+	case g.average > budget*(1+g.Slack):
+		g.slow += dt
+		g.fast = 0
+	case g.average < budget*(1-g.Headroom):
+		g.fast += dt
+		g.slow = 0
+	default:
+		g.slow, g.fast = 0, 0
+	}
+
+	if g.slow >= g.Down && g.tier > 0 {
+		if g.climbed {
+			g.failures()[g.tier]++
+		}
+		g.step(g.tier-1, false)
+		return true
+	}
+	if g.tier < len(g.Tiers)-1 && g.fast >= g.Up*g.backoff(g.tier+1) {
+		g.step(g.tier+1, true)
+		return true
+	}
+	return false
+}
+
+func (g *Governor) failures() []int {
+	if len(g.failed) != len(g.Tiers) {
+		g.failed = make([]int, len(g.Tiers))
+	}
+	return g.failed
+}
+
+// backoff is how many times as long as Up stepping up to `tier` is waited for
+func (g *Governor) backoff(tier int) float {
+	return float(math.Pow(2, float64(min(g.failures()[tier], max_backoff))))
+}
+
+func (g *Governor) step(tier int, up bool) {
+	g.tier = tier
+	g.climbed = up
+	g.slow, g.fast = 0, 0
+	g.average = 0
+	g.settle = g.Settle
+}
+
+// Tier is the tier to draw the next frame at.
+func (g *Governor) Tier() Tier {
+	return g.Tiers[g.tier]
+}
+
+// Level is the index of the tier in Tiers.
+func (g *Governor) Level() int {
+	return g.tier
+}
+
+// SetLevel picks the tier at `level` in Tiers, forgetting which tiers failed, for when the scene changes.
+func (g *Governor) SetLevel(level int) {
+	g.step(min(max(level, 0), len(g.Tiers)-1), false)
+	clear(g.failures())
+}
+
+// FPS is the frame rate the governor goes by, smoothed, 0 right after a step.
+func (g *Governor) FPS() float {
+	if g.average == 0 {
+		return 0
+	}
+	return 1 / g.average
+}
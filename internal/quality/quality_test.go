@@ -0,0 +1,72 @@
+package quality
+
+import "testing"
+
+// run feeds `g` frames of `dt` seconds for `seconds`, and returns the levels it stepped to.
+func run(g *Governor, dt, seconds float) []int {
+	var steps []int
+	for t := float(0); t < seconds; t += dt {
+		if g.Frame(dt) {
+			steps = append(steps, g.Level())
+		}
+	}
+	return steps
+}
+
+func TestStepsDownWhenSlow(t *testing.T) {
+	g := NewGovernor(60)
+	top := g.Level()
+	// 40 frames a second, too slow for every tier
+	steps := run(g, 1.0/40, 10)
+	if len(steps) != top || g.Level() != 0 {
+		t.Fatalf("stepped %v, want every tier down to 0", steps)
+	}
+}
+
+func TestHoldsWithinTheBand(t *testing.T) {
+	g := NewGovernor(60)
+	g.SetLevel(2)
+	// a little slower than the target, within the slack, and not fast enough to step up
+	if steps := run(g, 1.0/57, 30); len(steps) != 0 {
+		t.Fatalf("stepped %v at 57 frames a second", steps)
+	}
+}
+
+func TestStepsUpWhenFast(t *testing.T) {
+	g := NewGovernor(60)
+	g.SetLevel(0)
+	steps := run(g, 1.0/120, 3.9)
+	if len(steps) != 1 || steps[0] != 1 {
+		t.Fatalf("stepped %v in 3.9 seconds at 120 frames a second, want up once", steps)
+	}
+}
+
+func TestBacksOffFromFailingTiers(t *testing.T) {
+	g := NewGovernor(60)
+	g.SetLevel(0)
+	// fast enough at 0, too slow at 1
+	frame := func() float {
+		if g.Level() == 0 {
+			return 1.0 / 120
+		}
+		return 1.0 / 30
+	}
+	var ups []float
+	now := float(0)
+	for now < 60 {
+		dt := frame()
+		level := g.Level()
+		if g.Frame(dt) && g.Level() > level {
+			ups = append(ups, now)
+		}
+		now += dt
+	}
+	if len(ups) < 3 {
+		t.Fatalf("tried tier 1 at %v, want a few tries", ups)
+	}
+	for i := 2; i < len(ups); i++ {
+		if ups[i]-ups[i-1] <= ups[i-1]-ups[i-2] {
+			t.Fatalf("tried tier 1 at %v, the waits between tries should grow", ups)
+		}
+	}
+}
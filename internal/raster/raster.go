@@ -0,0 +1,265 @@
+// Package raster is an experimental software rasterizer for the triangles produced by the pipeline package. It
+// exists to compare rasterizing on the CPU against ebiten's DrawTriangles.
+//
+// The target is split into square tiles. Triangles are first binned into every tile their bounds touch, then
+// the tiles are rasterized in parallel. Each tile draws its bin in submission order, so the painter's algorithm
+// still holds without any synchronization between workers: no two workers ever write the same pixel.
+package raster
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/jobs"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+)
+
+// tile_size is the width and height of a tile in pixels.
+const tile_size = 32
+
+// tiles_per_job is how many tiles a single job rasterizes, a row of a 800 pixel wide target.
+const tiles_per_job = 25
+
+type Rasterizer struct {
+	width  int
+	height int
+
+	// Pixels is the RGBA target, premultiplied like ebiten expects.
+	Pixels []byte
+
+	tiles_x int
+	tiles_y int
+	// bins holds the indices of the triangles touching each tile, in draw order
+	bins [][]int32
+
+	// setups are the per-triangle values shared by every tile the triangle touches
+	setups []setup
+
+	// near and far are the depths drawn white and black by DrawDepth
+	near, far float
+}
+
+func New(width, height int) *Rasterizer {
+	r := &Rasterizer{}
+	r.Resize(width, height)
+	return r
+}
+
+func (r *Rasterizer) Size() (width, height int) {
+	return r.width, r.height
+}
+
+// Resize changes the size of the target, discarding its contents.
+func (r *Rasterizer) Resize(width, height int) {
+	if width == r.width && height == r.height {
+		return
+	}
+	r.width = width
+	r.height = height
+	r.Pixels = make([]byte, width*height*4)
+	r.tiles_x = (width + tile_size - 1) / tile_size
+	r.tiles_y = (height + tile_size - 1) / tile_size
+	r.bins = make([][]int32, r.tiles_x*r.tiles_y)
+}
+
+func (r *Rasterizer) Clear(c color.RGBA) {
+	for i := 0; i < len(r.Pixels); i += 4 {
+		r.Pixels[i+0] = c.R
+		r.Pixels[i+1] = c.G
+		r.Pixels[i+2] = c.B
+		r.Pixels[i+3] = c.A
+	}
+}
+
+// setup is a triangle prepared for rasterization. Attributes are divided by W so they can be interpolated
+// linearly in screen space and divided again per pixel.
+type setup struct {
+	x1, y1, x2, y2, x3, y3 float
+	inv_area               float
+	inv_w1, inv_w2, inv_w3 float
+	uv1, uv2, uv3          vec2 // uv / w
+	z1, z2, z3             float
+
+	min_x, min_y, max_x, max_y int
+}
+
+// Draw rasterizes `triangles` in order, sampling `texture` with nearest filtering.
+func (r *Rasterizer) Draw(triangles []pipeline.Triangle, texture *image.RGBA) {
+	r.draw(triangles, texture)
+}
+
+// DrawDepth rasterizes `triangles` in order as grayscale depth, white at `near` and black at `far`.
+func (r *Rasterizer) DrawDepth(triangles []pipeline.Triangle, near, far float) {
+	r.near, r.far = near, far
+	r.draw(triangles, nil)
+}
+
+// draw rasterizes with `texture`, or depth when it's nil.
+func (r *Rasterizer) draw(triangles []pipeline.Triangle, texture *image.RGBA) {
+	for i := range r.bins {
+		r.bins[i] = r.bins[i][:0]
+	}
+	r.setups = r.setups[:0]
+
+	for _, t := range triangles {
+		s, ok := r.setup(t)
+		if !ok {
+			continue
+		}
+		index := int32(len(r.setups))
+		r.setups = append(r.setups, s)
+
+		for ty := s.min_y / tile_size; ty <= s.max_y/tile_size; ty++ {
+			for tx := s.min_x / tile_size; tx <= s.max_x/tile_size; tx++ {
+				bin := &r.bins[ty*r.tiles_x+tx]
+				*bin = append(*bin, index)
+			}
+		}
+	}
+
+	jobs.Default.ParallelFor(len(r.bins), tiles_per_job, func(start, end int) {
+		for tile := start; tile < end; tile++ {
+			r.draw_tile(tile, texture)
+		}
+	})
+}
+
+func (r *Rasterizer) setup(t pipeline.Triangle) (s setup, ok bool) {
+	p1, p2, p3 := t.V1.Position, t.V2.Position, t.V3.Position
+
+	s.x1, s.y1 = p1.X(), p1.Y()
+	s.x2, s.y2 = p2.X(), p2.Y()
+	s.x3, s.y3 = p3.X(), p3.Y()
+
+	area := (s.x2-s.x1)*(s.y3-s.y1) - (s.y2-s.y1)*(s.x3-s.x1)
+	if area == 0 {
+		return s, false
+	}
+
+	// the winding in screen space depends on the projection, make it consistent so the edge functions are
+	// positive inside
+	if area < 0 {
+		s.x2, s.x3 = s.x3, s.x2
+		s.y2, s.y3 = s.y3, s.y2
+		t.V2, t.V3 = t.V3, t.V2
+		area = -area
+	}
+	s.inv_area = 1 / area
+
+	s.inv_w1 = 1 / t.V1.Position.W()
+	s.inv_w2 = 1 / t.V2.Position.W()
+	s.inv_w3 = 1 / t.V3.Position.W()
+	s.uv1 = t.V1.Texcoord.Mul(s.inv_w1)
+	s.uv2 = t.V2.Texcoord.Mul(s.inv_w2)
+	s.uv3 = t.V3.Texcoord.Mul(s.inv_w3)
+	s.z1, s.z2, s.z3 = t.V1.Position.Z(), t.V2.Position.Z(), t.V3.Position.Z()
+
+	s.min_x = max(int(min(s.x1, s.x2, s.x3)), 0)
+	s.min_y = max(int(min(s.y1, s.y2, s.y3)), 0)
+	s.max_x = min(int(max(s.x1, s.x2, s.x3)), r.width-1)
+	s.max_y = min(int(max(s.y1, s.y2, s.y3)), r.height-1)
+
+	return s, s.min_x <= s.max_x && s.min_y <= s.max_y
+}
+
+// edge is twice the signed area of the triangle (ax, ay), (bx, by), (px, py).
+func edge(ax, ay, bx, by, px, py float) float {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+// top_left reports whether the edge from a to b is a top or left edge, which own the pixels exactly on them.
+// Without the rule, pixels on an edge shared by two triangles would be drawn twice or not at all.
+func top_left(ax, ay, bx, by float) bool {
+	return (ay == by && bx < ax) || by > ay
+}
+
+func (r *Rasterizer) draw_tile(tile int, texture *image.RGBA) {
+	bin := r.bins[tile]
+	if len(bin) == 0 {
+		return
+	}
+
+	tile_min_x := (tile % r.tiles_x) * tile_size
+	tile_min_y := (tile / r.tiles_x) * tile_size
+	tile_max_x := min(tile_min_x+tile_size, r.width) - 1
+	tile_max_y := min(tile_min_y+tile_size, r.height) - 1
+
+	var tex_w, tex_h int
+	if texture != nil {
+		tex_w = texture.Rect.Dx()
+		tex_h = texture.Rect.Dy()
+	}
+
+	depth_scale := float(0)
+	if r.near != r.far {
+		depth_scale = 1 / (r.near - r.far)
+	}
+
+	for _, index := range bin {
+		s := &r.setups[index]
+
+		min_x := max(s.min_x, tile_min_x)
+		min_y := max(s.min_y, tile_min_y)
+		max_x := min(s.max_x, tile_max_x)
+		max_y := min(s.max_y, tile_max_y)
+
+		tl1 := top_left(s.x2, s.y2, s.x3, s.y3)
+		tl2 := top_left(s.x3, s.y3, s.x1, s.y1)
+		tl3 := top_left(s.x1, s.y1, s.x2, s.y2)
+
+		for y := min_y; y <= max_y; y++ {
+			py := float(y) + 0.5
+			row := y * r.width * 4
+
+			for x := min_x; x <= max_x; x++ {
+				px := float(x) + 0.5
+
+				w1 := edge(s.x2, s.y2, s.x3, s.y3, px, py)
+				w2 := edge(s.x3, s.y3, s.x1, s.y1, px, py)
+				w3 := edge(s.x1, s.y1, s.x2, s.y2, px, py)
+
+				if w1 < 0 || w2 < 0 || w3 < 0 {
+					continue
+				}
+				if (w1 == 0 && !tl1) || (w2 == 0 && !tl2) || (w3 == 0 && !tl3) {
+					continue
+				}
+
+				w1 *= s.inv_area
+				w2 *= s.inv_area
+				w3 *= s.inv_area
+
+				dst := row + x*4
+
+				if texture == nil {
+					// NDC depth is linear in screen space, it doesn't need perspective correction
+					z := w1*s.z1 + w2*s.z2 + w3*s.z3
+					gray := uint8(min(max((z-r.far)*depth_scale, 0), 1) * 255)
+					r.Pixels[dst+0] = gray
+					r.Pixels[dst+1] = gray
+					r.Pixels[dst+2] = gray
+					r.Pixels[dst+3] = 255
+					continue
+				}
+
+				// perspective correction, see the texture shader in the render package
+				inv_w := w1*s.inv_w1 + w2*s.inv_w2 + w3*s.inv_w3
+				u := (w1*s.uv1[0] + w2*s.uv2[0] + w3*s.uv3[0]) / inv_w
+				v := (w1*s.uv1[1] + w2*s.uv2[1] + w3*s.uv3[1]) / inv_w
+
+				tx := min(max(int(u*float(tex_w)), 0), tex_w-1)
+				ty := min(max(int(v*float(tex_h)), 0), tex_h-1)
+
+				src := texture.PixOffset(texture.Rect.Min.X+tx, texture.Rect.Min.Y+ty)
+				copy(r.Pixels[dst:dst+4], texture.Pix[src:src+4])
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pip"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/targets"
+)
+
+// AA is a way of smoothing the edges of a frame, each with its own look and cost.
+type AA int
+
+const (
+	// AAOff draws the frame as it is, jagged edges and all.
+	AAOff AA = iota
+	// AAEbiten has ebiten smooth the edges of every draw call, see Renderer.AntiAlias. Only the edges of triangles
+	// are smoothed, not those within textures or shaders.
+	AAEbiten
+	// AA2x draws the frame at twice the width and height and scales it down, averaging 4 samples for every pixel.
+	// It smooths everything, at four times the fill rate.
+	AA2x
+	// AA4x draws the frame at four times the width and height, 16 samples for every pixel.
+	AA4x
+	// AAFXAA blurs the finished frame along the edges it finds in its brightness, a single pass at the size of the
+	// screen. It's the cheapest of them and smooths everything, but softens text and fine detail too.
+	AAFXAA
+	AACount
+)
+
+func (a AA) String() string {
+	switch a {
+	case AAOff:
+		return "off"
+	case AAEbiten:
+		return "ebiten"
+	case AA2x:
+		return "2x"
+	case AA4x:
+		return "4x"
+	case AAFXAA:
+		return "fxaa"
+	}
+	return fmt.Sprintf("aa(%d)", int(a))
+}
+
+// Scale is how many times wider and taller than the screen the frame is drawn.
+func (a AA) Scale() int {
+	switch a {
+	case AA2x:
+		return 2
+	case AA4x:
+		return 4
+	}
+	return 1
+}
+
+// fxaa_shader is the FXAA of Timothy Lottes in its smallest form: every pixel is blurred along the edge through
+// it, found from the brightness of its corners, and left alone when the blur would bring in colors which are
+// brighter or darker than any around it.
+var fxaa_shader = `
+//kage:unit pixels
+package main
+
+const ReduceMin = 1.0 / 128
+const ReduceMul = 1.0 / 8
+const SpanMax = 8.0
+
+func luma(c vec4) float {
+	return dot(c.rgb, vec3(0.299, 0.587, 0.114))
+}
+
+// at blends the 4 texels around p, Kage only samples the nearest one. p is kept within the image, outside of it
+// is transparent and would darken the borders.
+func at(p vec2) vec4 {
+	origin := imageSrc0Origin()
+	p = clamp(p, origin+0.5, origin+imageSrc0Size()-0.5) - 0.5
+	f := fract(p)
+	p = floor(p) + 0.5
+	top := mix(imageSrc0At(p), imageSrc0At(p+vec2(1, 0)), f.x)
+	bottom := mix(imageSrc0At(p+vec2(0, 1)), imageSrc0At(p+vec2(1, 1)), f.x)
+	return mix(top, bottom, f.y)
+}
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	nw := luma(at(src + vec2(-1, -1)))
+	ne := luma(at(src + vec2(1, -1)))
+	sw := luma(at(src + vec2(-1, 1)))
+	se := luma(at(src + vec2(1, 1)))
+	m := luma(imageSrc0At(src))
+	lowest := min(m, min(min(nw, ne), min(sw, se)))
+	highest := max(m, max(max(nw, ne), max(sw, se)))
+
+	// the edge runs across the gradient of the corners, short edges are stretched out to SpanMax pixels
+	dir := vec2(-((nw + ne) - (sw + se)), (nw+sw)-(ne+se))
+	reduce := max((nw+ne+sw+se)*0.25*ReduceMul, ReduceMin)
+	dir = clamp(dir/(min(abs(dir.x), abs(dir.y))+reduce), vec2(-SpanMax), vec2(SpanMax))
+
+	near := 0.5 * (at(src+dir*(1.0/3-0.5)) + at(src+dir*(2.0/3-0.5)))
+	far := 0.5*near + 0.25*(at(src-dir*0.5)+at(src+dir*0.5))
+	l := luma(far)
+	if l < lowest || l > highest {
+		return near
+	}
+	return far
+}
+`
+
+// AntiAliaser smooths frames by any AA, it keeps the shader and borrows the images it needs from targets.Default.
+type AntiAliaser struct {
+	fxaa *ebiten.Shader
+}
+
+func NewAntiAliaser() (*AntiAliaser, error) {
+	shader, err := ebiten.NewShader([]byte(fxaa_shader))
+	if err != nil {
+		return nil, err
+	}
+	return &AntiAliaser{fxaa: shader}, nil
+}
+
+// Draw has `draw` draw a frame onto `screen`, smoothed by `mode`. The image `draw` is given is the size of the
+// screen times mode.Scale(), the viewport of the pipeline has to be set to it. `draw` is told the mode too, for
+// setting Renderer.AntiAlias.
+func (a *AntiAliaser) Draw(screen *ebiten.Image, mode AA, draw func(target *ebiten.Image, mode AA)) {
+	bounds := screen.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	at_screen := ebiten.GeoM{}
+	at_screen.Translate(float64(bounds.Min.X), float64(bounds.Min.Y))
+
+	switch mode {
+	case AA2x, AA4x:
+		frame := targets.Default.Get(w*mode.Scale(), h*mode.Scale())
+		defer targets.Default.Put(frame)
+		draw(frame, mode)
+		pip.Show("antialias", frame)
+
+		// halving with linear filtering averages every 2×2 texels exactly, 4x is halved twice
+		if mode == AA4x {
+			half := targets.Default.Get(w*2, h*2)
+			defer targets.Default.Put(half)
+			halve(half, frame, ebiten.GeoM{})
+			frame = half
+		}
+		halve(screen, frame, at_screen)
+
+	case AAFXAA:
+		frame := targets.Default.Get(w, h)
+		defer targets.Default.Put(frame)
+		draw(frame, mode)
+		pip.Show("antialias", frame)
+		screen.DrawRectShader(w, h, a.fxaa, &ebiten.DrawRectShaderOptions{
+			GeoM:   at_screen,
+			Images: [4]*ebiten.Image{frame},
+		})
+
+	default:
+		draw(screen, mode)
+	}
+}
+
+func halve(dst, src *ebiten.Image, geom ebiten.GeoM) {
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(0.5, 0.5)
+	op.GeoM.Concat(geom)
+	dst.DrawImage(src, op)
+}
+
+// Compare draws the frame twice for telling the modes apart: without smoothing left of `split`, a fraction of the
+// width of the screen, and by `mode` right of it, with a line in between.
+func (a *AntiAliaser) Compare(screen *ebiten.Image, mode AA, split float, draw func(target *ebiten.Image, mode AA)) {
+	bounds := screen.Bounds()
+	x := bounds.Min.X + int(float(bounds.Dx())*min(max(split, 0), 1))
+
+	a.Draw(screen, mode, draw)
+
+	off := targets.Default.Get(bounds.Dx(), bounds.Dy())
+	defer targets.Default.Put(off)
+	a.Draw(off, AAOff, draw)
+	left := off.SubImage(image.Rect(0, 0, x-bounds.Min.X, bounds.Dy())).(*ebiten.Image)
+	op := &ebiten.DrawImageOptions{Blend: ebiten.BlendCopy}
+	op.GeoM.Translate(float64(bounds.Min.X), float64(bounds.Min.Y))
+	screen.DrawImage(left, op)
+
+	vector.StrokeLine(screen, float(x), float(bounds.Min.Y), float(x), float(bounds.Max.Y), 1, color.White, false)
+}
@@ -0,0 +1,143 @@
+package render
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// batch_lookback is how many batches back Add looks for one to join, which keeps adding a draw from costing
+// more the more there are.
+const batch_lookback = 16
+
+// Batch collects draws and issues them in as few draw calls as it can. A frame drawn in depth order switches
+// between materials all the time, a call for each run of triangles, and most of those runs don't overlap the ones
+// in between them and the last run drawn the same way. Add joins such a run onto that earlier batch, which is
+// drawn the same as drawing it in order: the painter's algorithm only cares about the order of triangles which
+// cover the same pixels.
+//
+// Draws are the same when their target, shader, textures, blending and anti-aliasing are, and their materials
+// are the same or neither has uniforms. A draw onto another target is never moved across, something might be
+// drawing what it drew.
+type Batch struct {
+	Stats BatchStats
+
+	batches []batch
+	// merged is where the triangles of a batch added to more than once are put together to be drawn
+	merged []pipeline.Triangle
+}
+
+// BatchStats are what was drawn since Reset.
+type BatchStats struct {
+	// Draws are the draws added, and DrawCalls the batches they were drawn in.
+	Draws     int
+	DrawCalls int
+}
+
+type batch_state struct {
+	target     *ebiten.Image
+	shader     *ebiten.Shader
+	images     [4]*ebiten.Image
+	blend      ebiten.Blend
+	anti_alias *bool
+	reflective bool
+	// uniforms is the material when it has uniforms, which aren't compared
+	uniforms *Material
+}
+
+type batch struct {
+	state    batch_state
+	material *Material
+	// draws are the triangles of the draws in the batch, in the order they were added
+	draws [][]pipeline.Triangle
+	// min and max bound the triangles on the target
+	min, max vec2
+}
+
+func state_of(target *ebiten.Image, m *Material) batch_state {
+	s := batch_state{
+		target:     target,
+		shader:     m.Shader,
+		images:     m.Images,
+		blend:      m.Blend,
+		anti_alias: m.AntiAlias,
+		reflective: m.Reflective,
+	}
+	if len(m.Uniforms) > 0 {
+		s.uniforms = m
+	}
+	return s
+}
+
+// triangle_bounds are the bounds of `triangles` on the target
+func triangle_bounds(triangles []pipeline.Triangle) (lo, hi vec2) {
+	lo, hi = triangles[0].V1.Position.Vec2(), triangles[0].V1.Position.Vec2()
+	for _, t := range triangles {
+		for _, v := range [3]pipeline.Vertex{t.V1, t.V2, t.V3} {
+			lo = vec2{min(lo.X(), v.Position.X()), min(lo.Y(), v.Position.Y())}
+			hi = vec2{max(hi.X(), v.Position.X()), max(hi.Y(), v.Position.Y())}
+		}
+	}
+	return lo, hi
+}
+
+func (b *batch) overlaps(lo, hi vec2) bool {
+	return lo.X() < b.max.X() && b.min.X() < hi.X() && lo.Y() < b.max.Y() && b.min.Y() < hi.Y()
+}
+
+// Add draws `triangles` onto `target` with `m` when the batch is flushed, after what was added before and covers
+// the same pixels. The triangles have to stay as they are until then.
+func (b *Batch) Add(target *ebiten.Image, m *Material, triangles []pipeline.Triangle) {
+	if len(triangles) == 0 {
+		return
+	}
+	b.Stats.Draws++
+	state := state_of(target, m)
+	lo, hi := triangle_bounds(triangles)
+	for i := len(b.batches) - 1; i >= max(len(b.batches)-batch_lookback, 0); i-- {
+		earlier := &b.batches[i]
+		if earlier.state == state {
+			earlier.draws = append(earlier.draws, triangles)
+			earlier.min = vec2{min(earlier.min.X(), lo.X()), min(earlier.min.Y(), lo.Y())}
+			earlier.max = vec2{max(earlier.max.X(), hi.X()), max(earlier.max.Y(), hi.Y())}
+			return
+		}
+		if earlier.state.target != target || earlier.overlaps(lo, hi) {
+			break
+		}
+	}
+	// the batches are kept from frame to frame, so are their lists of draws
+	n := len(b.batches)
+	if n < cap(b.batches) {
+		b.batches = b.batches[:n+1]
+	} else {
+		b.batches = append(b.batches, batch{})
+	}
+	next := &b.batches[n]
+	*next = batch{state: state, material: m, draws: append(next.draws[:0], triangles), min: lo, max: hi}
+}
+
+// Flush draws what was added with `r`, a draw call for every batch, and starts over.
+func (b *Batch) Flush(r *Renderer) {
+	for i := range b.batches {
+		batch := &b.batches[i]
+		triangles := batch.draws[0]
+		if len(batch.draws) > 1 {
+			b.merged = b.merged[:0]
+			for _, draw := range batch.draws {
+				b.merged = append(b.merged, draw...)
+			}
+			triangles = b.merged
+		}
+		r.DrawMaterial(batch.state.target, batch.material, triangles)
+		// the draws are let go of so the batch doesn't keep the triangles they point at
+		clear(batch.draws)
+	}
+	b.Stats.DrawCalls += len(b.batches)
+	b.batches = b.batches[:0]
+}
+
+// Reset zeroes Stats, call it at the start of a frame so they add up the flushes of one frame.
+func (b *Batch) Reset() {
+	b.Stats = BatchStats{}
+}
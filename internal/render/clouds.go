@@ -0,0 +1,77 @@
+package render
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/kage"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// cloud_shader draws the puffs of package clouds: a round blob whose edge noise eats away, lighter on top, which
+// fades out where the scene is close behind it. The distance to the scene is image 0, see DrawDistance.
+var cloud_shader = `
+//kage:unit pixels
+package main
+
+#define FBM_OCTAVES 3
+#include "texture.kage"
+#include "noise.kage"
+#include "pack.kage"
+
+// Light is the color of the tops of the puffs and Shade that of their bottoms, Opacity scales their density.
+// Softness is how far in front of the scene a puff is drawn whole, Far is the far of the distance image. Time in
+// seconds churns the noise.
+var Light vec3
+var Shade vec3
+var Opacity float
+var Softness float
+var Far float
+var Time float
+
+func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
+	// the tags of the texture coordinates are the seed and the density of the puff, see clouds.Field.Mesh
+	uv := texture_uv(src, rgba)
+	cell := tags(uv)
+	p := untag(uv)
+	density := cell.y / 15
+
+	n := fbm(p*3 + cell.x*vec2(17.1, 5.3) + Time*0.05)
+	r := length(p*2-1) + (n-0.5)*0.6
+	alpha := (1 - smoothstep(0.2, 1, r)) * density * Opacity
+
+	scene := imageSrc0At(dst.xy + imageSrc0Origin())
+	behind := unpack_depth(scene.rg)*Far - 1/rgba.a
+	alpha *= clamp(behind/Softness, 0, 1)
+
+	rgb := mix(Light, Shade, p.y) * surface_light(rgba)
+	return vec4(rgb*alpha, alpha)
+}
+`
+
+// NewCloudMaterial makes a material for the puffs of package clouds, which fade out where they meet the scene in
+// `distance`, an image of the size of the target drawn by DrawDistance up to "Far". Set "Time" in its uniforms
+// every frame, and "Light", "Shade", "Opacity" and "Softness", in world units, to taste.
+func NewCloudMaterial(distance *ebiten.Image, far float) (*Material, error) {
+	src, err := kage.PreprocessSource("clouds.kage", cloud_shader, nil)
+	if err != nil {
+		return nil, err
+	}
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Material{
+		Shader: shader,
+		Images: [4]*ebiten.Image{distance},
+		Uniforms: map[string]any{
+			"Light":    []float32{1, 1, 1},
+			"Shade":    []float32{0.6, 0.65, 0.75},
+			"Opacity":  float32(1),
+			"Softness": float32(0.5),
+			"Far":      far,
+			"Time":     float32(0),
+		},
+		Blend: ebiten.BlendSourceOver,
+		Cull:  pipeline.CullNone,
+	}, nil
+}
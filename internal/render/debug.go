@@ -0,0 +1,141 @@
+package render
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+// Debug is a way of drawing triangles which shows something about them instead of their material, for finding
+// what's wrong with an imported model.
+type Debug int
+
+const (
+	DebugOff Debug = iota
+	// DebugUVCheck draws the UV check pattern of texgen, seams and stretched or flipped texcoords show.
+	DebugUVCheck
+	// DebugNormals colors each triangle by the direction it faces in world space, x, y and z as red, green and blue.
+	DebugNormals
+	// DebugDepth shades from white at the nearest of the triangles drawn to black at the farthest.
+	DebugDepth
+	// DebugTexcoords colors by u in red and v in green.
+	DebugTexcoords
+	// DebugTriangles colors each triangle of a mesh differently.
+	DebugTriangles
+	DebugCount
+)
+
+var debug_names = [DebugCount]string{"off", "uv_check", "normals", "depth", "texcoords", "triangles"}
+
+// String is the name of the mode, like "normals".
+func (d Debug) String() string {
+	if d >= 0 && d < DebugCount {
+		return debug_names[d]
+	}
+	return "unknown"
+}
+
+// DrawDebug draws `triangles` the way `mode` shows them. DebugNormals needs the meshes the triangles came from, with
+// their points in world space, which `meshes` returns by ID; triangles whose mesh it doesn't know are gray.
+func (r *Renderer) DrawDebug(target *ebiten.Image, triangles []pipeline.Triangle, mode Debug, meshes func(id uint32) *mesh.Mesh) {
+	switch mode {
+	case DebugOff:
+		return
+	case DebugUVCheck:
+		r.DrawTriangles(target, missing_texture(), triangles)
+		return
+	}
+
+	near, far := float(0), float(0)
+	if mode == DebugDepth {
+		near, far = depth_range(triangles)
+	}
+	flush := func() {
+		target.DrawTriangles(r.vertices, r.indices, white_image, nil)
+		r.vertices = r.vertices[:0]
+		r.indices = r.indices[:0]
+	}
+	for _, t := range triangles {
+		if len(r.vertices)+3 > ebiten.MaxVertexCount {
+			flush()
+		}
+		first := uint16(len(r.vertices))
+		for _, v := range [3]pipeline.Vertex{t.V1, t.V2, t.V3} {
+			var c vec3
+			switch mode {
+			case DebugNormals:
+				c = face_normal(t.Origin, meshes).Mul(0.5).Add(vec3{0.5, 0.5, 0.5})
+			case DebugDepth:
+				c = gray(1 - (v.Position.W()-near)/max(far-near, 1e-6))
+			case DebugTexcoords:
+				c = vec3{v.Texcoord.X(), v.Texcoord.Y(), 0}
+			case DebugTriangles:
+				c = triangle_color(t.Mesh, t.Index)
+			}
+			r.vertices = append(r.vertices, ebiten.Vertex{
+				DstX:   v.Position.X(),
+				DstY:   v.Position.Y(),
+				ColorR: c.X(),
+				ColorG: c.Y(),
+				ColorB: c.Z(),
+				ColorA: 1,
+			})
+		}
+		r.indices = append(r.indices, first, first+1, first+2)
+	}
+	if len(r.indices) > 0 {
+		flush()
+	}
+}
+
+// depth_range is the view depth of the nearest and the farthest vertex of `triangles`.
+func depth_range(triangles []pipeline.Triangle) (near, far float) {
+	if len(triangles) == 0 {
+		return 0, 0
+	}
+	near, far = triangles[0].V1.Position.W(), triangles[0].V1.Position.W()
+	for _, t := range triangles {
+		for _, v := range [3]pipeline.Vertex{t.V1, t.V2, t.V3} {
+			near, far = min(near, v.Position.W()), max(far, v.Position.W())
+		}
+	}
+	return near, far
+}
+
+func face_normal(o pipeline.Origin, meshes func(id uint32) *mesh.Mesh) vec3 {
+	var m *mesh.Mesh
+	if meshes != nil {
+		m = meshes(o.Mesh)
+	}
+	if m == nil || int(o.Index) >= len(m.Triangles) {
+		return vec3{}
+	}
+	t := m.Triangles[o.Index]
+	p1, p2, p3 := m.Points[t.P1], m.Points[t.P2], m.Points[t.P3]
+	n := p2.Sub(p1).Cross(p3.Sub(p1))
+	if n.Len() == 0 {
+		return vec3{}
+	}
+	return n.Normalize()
+}
+
+func gray(v float) vec3 {
+	return vec3{v, v, v}
+}
+
+// triangle_color is a color picked by hashing the triangle, neighbours are rarely alike.
+func triangle_color(id uint32, index int32) vec3 {
+	h := (id*0x9e3779b9 ^ uint32(index)) * 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return vec3{float(h&0xff) / 255, float(h>>8&0xff) / 255, float(h>>16&0xff) / 255}.Mul(0.75).Add(gray(0.25))
+}
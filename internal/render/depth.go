@@ -0,0 +1,78 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// DepthRange returns the nearest and farthest depth of the vertices of `triangles`. Stretching the depth view
+// over this range instead of the whole clip volume makes precision problems visible.
+func DepthRange(triangles []pipeline.Triangle, reverse_z bool) (near, far float32) {
+	if len(triangles) == 0 {
+		return 0, 0
+	}
+	lo := triangles[0].V1.Position.Z()
+	hi := lo
+	for _, t := range triangles {
+		for _, z := range [3]float32{t.V1.Position.Z(), t.V2.Position.Z(), t.V3.Position.Z()} {
+			lo = min(lo, z)
+			hi = max(hi, z)
+		}
+	}
+	if reverse_z {
+		return hi, lo
+	}
+	return lo, hi
+}
+
+var white_image = func() *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.White)
+	// the center pixel, so sampling never bleeds into the transparent atlas around it
+	return img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+}()
+
+func depth_gray(z, near, far float32) float32 {
+	if near == far {
+		return 1
+	}
+	return min(max((z-far)/(near-far), 0), 1)
+}
+
+// DrawDepth draws `triangles` as grayscale depth, white at `near` and black at `far`. Depth is linear in
+// screen space, so plain vertex colors interpolate it correctly.
+func (r *Renderer) DrawDepth(target *ebiten.Image, triangles []pipeline.Triangle, near, far float32) {
+	for _, triangle := range triangles {
+		for _, v := range [3]pipeline.Vertex{triangle.V1, triangle.V2, triangle.V3} {
+			gray := depth_gray(v.Position.Z(), near, far)
+			r.vertices = append(r.vertices, ebiten.Vertex{
+				SrcX:   1.5,
+				SrcY:   1.5,
+				DstX:   v.Position.X(),
+				DstY:   v.Position.Y(),
+				ColorR: gray,
+				ColorG: gray,
+				ColorB: gray,
+				ColorA: 1,
+			})
+		}
+
+		first_index := uint16(len(r.indices))
+		r.indices = append(r.indices, first_index, first_index+1, first_index+2)
+	}
+
+	target.DrawTriangles(r.vertices, r.indices, white_image, nil)
+
+	r.vertices = r.vertices[:0]
+	r.indices = r.indices[:0]
+}
+
+func (r *SoftwareRenderer) DrawDepth(target *ebiten.Image, triangles []pipeline.Triangle, near, far float32) {
+	r.draw(target, func() {
+		r.raster.DrawDepth(triangles, near, far)
+	})
+}
@@ -0,0 +1,38 @@
+package render
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// distance_shader writes how far the pixel is from the eye, W over Far, in 16 bits in red and green, see
+// pack.Depth.
+var distance_shader = `
+//kage:unit pixels
+package main
+
+#include "pack.kage"
+
+var Far float
+
+func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
+	return vec4(pack_depth(1/rgba.a/Far), 0, 1)
+}
+`
+
+// DrawDistance draws how far `triangles` are from the eye onto `target`, as far as `far` away, for effects
+// which fade out where they meet the scene, like the clouds of NewCloudMaterial. Triangles are drawn over what's
+// there in the order they're given, so sorted triangles leave the nearest distance. Fill the target with white
+// first, which is as far as it goes.
+func (r *Renderer) DrawDistance(target *ebiten.Image, triangles []pipeline.Triangle, far float) {
+	r.draw(target, r.distance, &ebiten.DrawTrianglesShaderOptions{
+		Uniforms: map[string]any{"Far": far},
+		Blend:    ebiten.BlendCopy,
+	}, triangles, unlit)
+}
+
+// unlit is white for every corner
+func unlit(pipeline.Triangle) [3]vec3 {
+	return [3]vec3{{1, 1, 1}, {1, 1, 1}, {1, 1, 1}}
+}
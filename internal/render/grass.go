@@ -0,0 +1,63 @@
+package render
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/kage"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// grass_shader draws the blades of package grass: a blade tapering from its root to its tip, darker at the root
+// where the grass shades itself, in one of eight shades of green.
+var grass_shader = `
+//kage:unit pixels
+package main
+
+#include "texture.kage"
+#include "pack.kage"
+
+// Root and Tip are the colors at either end of a blade, Variation is how much lighter or darker the shades of
+// blades are, from 0 for every blade the same. Light is the light on the field, like the sun and the sky.
+var Root vec3
+var Tip vec3
+var Variation float
+var Light vec3
+
+func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
+	// the tag of u is the shade of the blade, see grass.Patch
+	uv := texture_uv(src, rgba)
+	shade := tags(uv).x
+	across := abs(untag(uv).x-0.5) * 2
+	width := pow(clamp(uv.y, 0, 1), 0.6)
+	alpha := 1 - smoothstep(width-0.15, width, across)
+	if alpha <= 0 {
+		discard()
+	}
+	rgb := mix(Tip, Root, uv.y) * (1 + (shade/7-0.5)*Variation) * Light * surface_light(rgba)
+	return vec4(rgb*alpha, alpha)
+}
+`
+
+// NewGrassMaterial makes a material for the blades of package grass, drawn from both sides. Set "Light" in its
+// uniforms when the light changes, and "Root", "Tip" and "Variation" to taste.
+func NewGrassMaterial() (*Material, error) {
+	src, err := kage.PreprocessSource("grass.kage", grass_shader, nil)
+	if err != nil {
+		return nil, err
+	}
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Material{
+		Shader: shader,
+		Uniforms: map[string]any{
+			"Root":      []float32{0.12, 0.25, 0.08},
+			"Tip":       []float32{0.55, 0.75, 0.3},
+			"Variation": float32(0.4),
+			"Light":     []float32{1, 1, 1},
+		},
+		Blend: ebiten.BlendSourceOver,
+		Cull:  pipeline.CullNone,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// IDColor is the color mesh `id` is drawn with by DrawIDs, its low 24 bits spread over red, green and blue.
+// Mesh IDs start at 1, so the black of a cleared image is no mesh.
+func IDColor(id uint32) (r, g, b float32) {
+	return float32(id>>16&0xff) / 255, float32(id>>8&0xff) / 255, float32(id&0xff) / 255
+}
+
+// DrawIDs draws `triangles` flat, each in the IDColor of its mesh. Read it back with IDAt to find the mesh under
+// a pixel. The colors must arrive unchanged, so the target should be cleared first and not be filtered or scaled
+// before reading it.
+func (r *Renderer) DrawIDs(target *ebiten.Image, triangles []pipeline.Triangle) {
+	flush := func() {
+		target.DrawTriangles(r.vertices, r.indices, white_image, &ebiten.DrawTrianglesOptions{Blend: ebiten.BlendCopy})
+		r.vertices = r.vertices[:0]
+		r.indices = r.indices[:0]
+	}
+
+	for _, triangle := range triangles {
+		if len(r.vertices)+3 > ebiten.MaxVertexCount {
+			flush()
+		}
+		red, green, blue := IDColor(triangle.Mesh)
+		first_index := uint16(len(r.vertices))
+		for _, v := range [3]pipeline.Vertex{triangle.V1, triangle.V2, triangle.V3} {
+			r.vertices = append(r.vertices, ebiten.Vertex{
+				SrcX:   1.5,
+				SrcY:   1.5,
+				DstX:   v.Position.X(),
+				DstY:   v.Position.Y(),
+				ColorR: red,
+				ColorG: green,
+				ColorB: blue,
+				ColorA: 1,
+			})
+		}
+		r.indices = append(r.indices, first_index, first_index+1, first_index+2)
+	}
+	if len(r.indices) > 0 {
+		flush()
+	}
+}
+
+// IDAt reads the mesh ID DrawIDs left at (x, y), 0 when there's none. It waits for the GPU to finish drawing, so read
+// as few pixels as possible.
+func IDAt(img *ebiten.Image, x, y int) uint32 {
+	if !(image.Point{x, y}.In(img.Bounds())) {
+		return 0
+	}
+	var pixel [4]byte
+	img.SubImage(image.Rect(x, y, x+1, y+1)).(*ebiten.Image).ReadPixels(pixel[:])
+	return uint32(pixel[0])<<16 | uint32(pixel[1])<<8 | uint32(pixel[2])
+}
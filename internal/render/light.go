@@ -0,0 +1,87 @@
+package render
+
+import (
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+type LightKind int
+
+const (
+	// AmbientLight reaches every surface the same, from everywhere.
+	AmbientLight LightKind = iota
+	// DirectionalLight shines along a direction from infinitely far away, like the sun.
+	DirectionalLight
+	// PointLight shines from a point out to its range.
+	PointLight
+)
+
+// Light is a light as the renderer sees it, in world space.
+type Light struct {
+	Kind LightKind
+	// Color is the color of the light times its intensity.
+	Color vec3
+	// Position is where a point light is and Direction where a directional light shines towards, of length 1.
+	Position  vec3
+	Direction vec3
+	// Range is how far a point light reaches, it fades out to nothing there.
+	Range float
+}
+
+// Lighting lights the triangles a Renderer draws. It's worked out once for every triangle, from the middle and the
+// normal of the triangle of the mesh it came from, with the points in world space, which Meshes returns by ID.
+// Triangles of meshes it doesn't know aren't lit. Materials with their own shaders get the light from
+// surface_light of texture.kage, see package kage.
+type Lighting struct {
+	Lights []Light
+	Meshes func(id uint32) *mesh.Mesh
+	// Baked takes the light of meshes with colors from their corners instead, as package bake left it, blended
+	// across the triangles. Meshes without colors are lit by Lights still.
+	Baked bool
+}
+
+// At is the light reaching the point `p` of a surface facing `n`.
+func (l *Lighting) At(p, n vec3) vec3 {
+	var sum vec3
+	for _, light := range l.Lights {
+		switch light.Kind {
+		case AmbientLight:
+			sum = sum.Add(light.Color)
+		case DirectionalLight:
+			sum = sum.Add(light.Color.Mul(max(-n.Dot(light.Direction), 0)))
+		case PointLight:
+			to := light.Position.Sub(p)
+			d := to.Len()
+			if d == 0 || d >= light.Range {
+				continue
+			}
+			// inverse square would never reach zero, this falls to it at the range
+			falloff := 1 - (d/light.Range)*(d/light.Range)
+			sum = sum.Add(light.Color.Mul(max(n.Dot(to.Mul(1/d)), 0) * falloff * falloff))
+		}
+	}
+	return sum
+}
+
+// of is the light reaching the corners of `t`.
+func (l *Lighting) of(t pipeline.Triangle) [3]vec3 {
+	if l.Baked {
+		if m := l.Meshes(t.Mesh); m != nil && len(m.Colors) == 3*len(m.Triangles) {
+			return [3]vec3{t.V1.Color, t.V2.Color, t.V3.Color}
+		}
+	}
+	light := l.triangle(t.Origin)
+	return [3]vec3{light, light, light}
+}
+
+// triangle is the light reaching the mesh triangle `o`.
+func (l *Lighting) triangle(o pipeline.Origin) vec3 {
+	n := face_normal(o, l.Meshes)
+	if n == (vec3{}) {
+		return vec3{1, 1, 1}
+	}
+	m := l.Meshes(o.Mesh)
+	t := m.Triangles[o.Index]
+	center := m.Points[t.P1].Add(m.Points[t.P2]).Add(m.Points[t.P3]).Mul(1.0 / 3)
+	return l.At(center, n)
+}
@@ -0,0 +1,231 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"maps"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/kage"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/material"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/memory"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/texgen"
+)
+
+var logger = logging.Tag("render")
+
+// Material is a material file made ready to draw with, see DrawMaterial. Its shader gets the texture coordinates
+// and 1/W the way the textured shader does, so it starts from a copy of it.
+type Material struct {
+	// Shader is nil for the textured shader.
+	Shader   *ebiten.Shader
+	Images   [4]*ebiten.Image
+	Uniforms map[string]any
+	Blend    ebiten.Blend
+	// Cull is for the pipeline, set it on the context while pushing the meshes drawn with the material.
+	Cull pipeline.Cull
+	// AntiAlias overrides Renderer.AntiAlias for the material when it's not nil.
+	AntiAlias *bool
+	// Reflective materials are drawn with the nearest probe of Renderer.Reflections, see Probe.
+	Reflective bool
+
+	// source is the source of the shader when it's shared by Materials
+	source string
+	// texture_bytes is the memory of the textures build loaded, counted on textures
+	texture_bytes int64
+}
+
+// textures counts the memory of the textures of the materials built from files.
+var textures = memory.NewCounter("textures")
+
+// multiply_blend multiplies the destination by the source
+var multiply_blend = ebiten.Blend{
+	BlendFactorSourceRGB:        ebiten.BlendFactorZero,
+	BlendFactorSourceAlpha:      ebiten.BlendFactorZero,
+	BlendFactorDestinationRGB:   ebiten.BlendFactorSourceColor,
+	BlendFactorDestinationAlpha: ebiten.BlendFactorOne,
+	BlendOperationRGB:           ebiten.BlendOperationAdd,
+	BlendOperationAlpha:         ebiten.BlendOperationAdd,
+}
+
+var blends = map[string]ebiten.Blend{
+	material.Alpha:    ebiten.BlendSourceOver,
+	material.Add:      ebiten.BlendLighter,
+	material.Multiply: multiply_blend,
+	material.Opaque:   ebiten.BlendCopy,
+}
+
+var culls = map[string]pipeline.Cull{
+	material.Back:  pipeline.CullBack,
+	material.Front: pipeline.CullFront,
+	material.None:  pipeline.CullNone,
+}
+
+// NewMaterial compiles the shader of the material of `a`, its includes resolved and its features on, and loads its
+// textures, through the asset so they're watched as well. A material without textures samples white, and its
+// textures which are missing are the UV check pattern of texgen.
+func NewMaterial(a *material.Asset) (*Material, error) {
+	return build(a, func(_ string, src []byte) (*ebiten.Shader, error) { return ebiten.NewShader(src) })
+}
+
+// missing_texture is the UV check pattern, drawn for textures which are missing. It's made when first needed.
+var missing_texture = sync.OnceValue(func() *ebiten.Image {
+	return ebiten.NewImageFromImage(texgen.UVCheck(256))
+})
+
+// build builds the material of `a`, its shader with `compile`. The shader is compiled last, nothing fails after.
+func build(a *material.Asset, compile func(variant string, src []byte) (*ebiten.Shader, error)) (*Material, error) {
+	m := a.Material
+	if m == nil {
+		return nil, a.Err
+	}
+	built := &Material{Blend: blends[m.Blend], Cull: culls[m.Cull], AntiAlias: m.AntiAlias, Reflective: m.Reflective}
+	built.Images[0] = white_image
+	for i, path := range m.Textures {
+		src, err := a.Read(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// a texture which isn't there yet shows as such, rather than taking the whole material
+			logger.Warnf("material %s: no texture %s", a.Name, path)
+			built.Images[i] = missing_texture()
+			continue
+		}
+		if err != nil {
+			built.Deallocate()
+			return nil, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(src))
+		if err != nil {
+			built.Deallocate()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		built.Images[i] = ebiten.NewImageFromImage(img)
+		size := img.Bounds().Size()
+		built.texture_bytes += int64(size.X*size.Y) * 4
+		textures.Add(int64(size.X*size.Y) * 4)
+	}
+	if len(m.Uniforms) > 0 {
+		built.Uniforms = make(map[string]any, len(m.Uniforms))
+		for name, v := range m.Uniforms {
+			if len(v) == 1 {
+				built.Uniforms[name] = v[0]
+			} else {
+				built.Uniforms[name] = v
+			}
+		}
+	}
+	if m.Shader != "" {
+		defines := kage.Features(m.Features)
+		maps.Copy(defines, m.Defines)
+		src, err := kage.Preprocess(m.Shader, a.Read, defines)
+		if err == nil {
+			built.Shader, err = compile(kage.Variant(m.Shader, m.Features), src)
+		}
+		if err != nil {
+			built.Deallocate()
+			return nil, fmt.Errorf("%s: %w", m.Shader, err)
+		}
+	}
+	return built, nil
+}
+
+// Deallocate frees the shader and textures of the material, the shader unless it's shared by Materials.
+func (m *Material) Deallocate() {
+	if m.Shader != nil && m.source == "" {
+		m.Shader.Deallocate()
+	}
+	for _, img := range m.Images {
+		if img != nil && img != white_image && img != missing_texture() {
+			img.Deallocate()
+		}
+	}
+	textures.Add(-m.texture_bytes)
+	m.texture_bytes = 0
+}
+
+// Materials are the materials of a library made ready to draw with, made again when they reload. Their shaders
+// are compiled once for every variant, materials whose shaders preprocess to the same source share it.
+type Materials struct {
+	Library *material.Library
+
+	built   map[*material.Asset]*built_material
+	shaders map[string]*variant
+}
+
+type built_material struct {
+	material *Material
+	version  int
+}
+
+// variant is a compiled shader and how many materials use it
+type variant struct {
+	shader *ebiten.Shader
+	users  int
+}
+
+// Get returns the material called `name`, nil while it never loaded or built. A material which reloaded but
+// doesn't build is logged and the last one which did is kept.
+func (ms *Materials) Get(name string) *Material {
+	a := ms.Library.Get(name)
+	if ms.built == nil {
+		ms.built = make(map[*material.Asset]*built_material)
+		ms.shaders = make(map[string]*variant)
+	}
+	b := ms.built[a]
+	if b == nil {
+		b = &built_material{}
+		ms.built[a] = b
+	}
+	if b.version != a.Version {
+		b.version = a.Version
+		var source string
+		m, err := build(a, func(name string, src []byte) (*ebiten.Shader, error) {
+			source = string(src)
+			return ms.compile(name, source)
+		})
+		if err != nil {
+			logger.Warnf("material %s: %v", name, err)
+		} else {
+			m.source = source
+			if b.material != nil {
+				ms.release(b.material)
+			}
+			b.material = m
+		}
+	}
+	return b.material
+}
+
+// compile returns the shader of `source`, compiling it when no material uses it yet.
+func (ms *Materials) compile(name, source string) (*ebiten.Shader, error) {
+	if v, ok := ms.shaders[source]; ok {
+		v.users++
+		return v.shader, nil
+	}
+	shader, err := ebiten.NewShader([]byte(source))
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("compiled %s", name)
+	ms.shaders[source] = &variant{shader: shader, users: 1}
+	return shader, nil
+}
+
+// release deallocates `m`, and its shader when no other material uses it.
+func (ms *Materials) release(m *Material) {
+	m.Deallocate()
+	if v, ok := ms.shaders[m.source]; ok && m.source != "" {
+		if v.users--; v.users == 0 {
+			v.shader.Deallocate()
+			delete(ms.shaders, m.source)
+		}
+	}
+}
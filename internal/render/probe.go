@@ -0,0 +1,131 @@
+package render
+
+import (
+	"image"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pack"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pip"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// cube_faces are the directions the faces of a cubemap look along and which way is up on them, in the order of
+// the atlas, the top row left to right and then the bottom one. probe.kage samples them the same way.
+var cube_faces = [6]struct{ forward, up vec3 }{
+	{vec3{1, 0, 0}, vec3{0, 1, 0}},
+	{vec3{-1, 0, 0}, vec3{0, 1, 0}},
+	{vec3{0, 1, 0}, vec3{0, 0, 1}},
+	{vec3{0, -1, 0}, vec3{0, 0, -1}},
+	{vec3{0, 0, 1}, vec3{0, 1, 0}},
+	{vec3{0, 0, -1}, vec3{0, 1, 0}},
+}
+
+// Probe is a reflection probe: the scene as seen from a point, in every direction, which reflective materials
+// near it reflect. ebiten has no cubemaps, so the six faces are kept side by side in one image, three across and
+// two down. The probe is only as up to date as its last Capture, which draws the scene six times.
+type Probe struct {
+	Position vec3
+	// Radius is how far from the probe meshes reflect it, by the middle of their bounds.
+	Radius  float
+	Cubemap *ebiten.Image
+
+	size    int
+	context *pipeline.Context
+}
+
+// NewProbe makes a probe whose faces are `size` pixels wide.
+func NewProbe(size int) *Probe {
+	return &Probe{
+		Cubemap: ebiten.NewImage(3*size, 2*size),
+		size:    size,
+		context: &pipeline.Context{FlipY: true},
+	}
+}
+
+// Size is the width of a face in pixels.
+func (p *Probe) Size() int {
+	return p.size
+}
+
+// Capture draws the cubemap from Position, a face at a time: `draw` is given the face cleared and a context
+// looking out of it through a field of view of 90 degrees, with its viewport set to the face. It pushes the scene
+// and draws its triangles, less whatever shouldn't be in the reflection, like the mesh reflecting it. The context
+// is reset after every face.
+func (p *Probe) Capture(draw func(target *ebiten.Image, c *pipeline.Context)) {
+	for i, face := range cube_faces {
+		at := image.Pt(i%3, i/3).Mul(p.size)
+		bounds := image.Rectangle{Min: at, Max: at.Add(image.Pt(p.size, p.size))}
+		target := p.Cubemap.SubImage(bounds).(*ebiten.Image)
+		target.Clear()
+		c := p.context
+		c.SetViewport(at.X, at.Y, p.size, p.size)
+		c.SetPerspective(math.Pi/2, 1, 0.05, 100)
+		c.SetView(mgl32.LookAtV(p.Position, p.Position.Add(face.forward), face.up))
+		draw(target, c)
+		c.Reset()
+	}
+	pip.Show("probe", p.Cubemap)
+}
+
+// Deallocate frees the cubemap.
+func (p *Probe) Deallocate() {
+	p.Cubemap.Deallocate()
+}
+
+// Reflections are the probes the reflective materials a Renderer draws reflect. Every mesh reflects the probe
+// nearest to it, of those it's within the radius of, and reflects nothing when there's none.
+type Reflections struct {
+	Probes []*Probe
+	// Meshes returns the meshes by ID, with their points in world space and their bounds computed.
+	Meshes func(id uint32) *mesh.Mesh
+	// Context is the context the triangles went through, the view rays of the pixels come from its camera.
+	Context *pipeline.Context
+}
+
+// nearest is the probe the mesh `id` reflects, nil for none.
+func (r *Reflections) nearest(id uint32) *Probe {
+	m := r.Meshes(id)
+	if m == nil {
+		return nil
+	}
+	var nearest *Probe
+	closest := float(math.MaxFloat32)
+	for _, p := range r.Probes {
+		if d := p.Position.Sub(m.Sphere.Center).Len(); d <= p.Radius && d < closest {
+			nearest, closest = p, d
+		}
+	}
+	return nearest
+}
+
+// uniforms are the uniforms of probe.kage for `p`, which can be nil, added to `uniforms`.
+func (r *Reflections) uniforms(p *Probe, uniforms map[string]any) map[string]any {
+	c := r.Context
+	x, y, w, h := c.Viewport()
+	out := make(map[string]any, len(uniforms)+4)
+	for k, v := range uniforms {
+		out[k] = v
+	}
+	inverse := c.ProjectionMatrix().Mul4(c.ViewMatrix()).Inv()
+	out["ProbeInvViewProjection"] = inverse[:]
+	out["ProbeViewport"] = []float32{float(x), float(y), float(w), float(h)}
+	out["ProbeFlipY"] = float(0)
+	if c.FlipY {
+		out["ProbeFlipY"] = float(1)
+	}
+	out["ProbeFace"] = float(0)
+	if p != nil {
+		out["ProbeFace"] = float(p.size)
+	}
+	return out
+}
+
+// normals are the normals of the triangle `t` as probe.kage reads them.
+func (r *Reflections) normals(t pipeline.Triangle) [3]vec3 {
+	n := pack.Normal(face_normal(t.Origin, r.Meshes))
+	return [3]vec3{n, n, n}
+}
@@ -0,0 +1,218 @@
+// Package render turns the triangles produced by the pipeline package into ebiten draw calls.
+package render
+
+import (
+	"unsafe"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/kage"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/memory"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// texture_shader maps a texture onto each triangle with perspective correction. The pipeline stores UV/W in
+// SrcX/SrcY and 1/W in the alpha channel so the shader can undo the division per pixel. The red, green and blue of
+// the color are the light reaching the corner, see Lighting, divided by W the same way.
+var texture_shader = `
+//kage:unit pixels
+package main
+
+func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
+	src_origin := imageSrc0Origin()
+
+	// atlas -> texture space
+	texel := src - src_origin
+
+	// perspective divide (W is stored in rgba.a)
+	texel /= rgba.a
+
+	// scale uv to pixels
+	texel *= imageSrc0Size()
+
+	// move back to atlas space
+	texel += src_origin
+
+	return imageSrc0At(texel) * vec4(rgba.rgb/rgba.a, 1)
+}
+`
+
+type Renderer struct {
+	// AntiAlias has ebiten smooth the edges of the triangles. It draws them into an offscreen image first, which
+	// costs every draw call about three times the vertex processing. NewRenderer turns it on, see AA for the
+	// other ways of smoothing a frame.
+	AntiAlias bool
+	// Lighting lights the triangles drawn, nil leaves them as bright as their textures.
+	Lighting *Lighting
+	// Reflections are the probes reflective materials reflect, nil has them reflect nothing.
+	Reflections *Reflections
+
+	shader   *ebiten.Shader
+	distance *ebiten.Shader
+
+	// the following are not required to be stored here,
+	// they serve as buffers to reduce overall allocations.
+
+	vertices []ebiten.Vertex
+	indices  []uint16
+	// memory counts them on buffers
+	memory [2]memory.Buffer
+}
+
+// buffers counts the memory of the buffers of every renderer.
+var buffers = memory.NewCounter("render buffers")
+
+func NewRenderer() (*Renderer, error) {
+	shader, err := ebiten.NewShader([]byte(texture_shader))
+	if err != nil {
+		return nil, err
+	}
+	src, err := kage.PreprocessSource("distance.kage", distance_shader, nil)
+	if err != nil {
+		return nil, err
+	}
+	distance, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{AntiAlias: true, shader: shader, distance: distance}, nil
+}
+
+// DrawTriangles draws `triangles` with `texture`, in as many draw calls as MaxVertexCount requires.
+func (r *Renderer) DrawTriangles(target, texture *ebiten.Image, triangles []pipeline.Triangle) {
+	r.draw(target, r.shader, &ebiten.DrawTrianglesShaderOptions{
+		Images: [4]*ebiten.Image{
+			texture,
+		},
+		AntiAlias: r.AntiAlias,
+	}, triangles, r.light)
+}
+
+// DrawMaterial draws `triangles` with the shader, textures, uniforms and blending of `m`. Its culling applies
+// when the triangles are pushed, not here.
+func (r *Renderer) DrawMaterial(target *ebiten.Image, m *Material, triangles []pipeline.Triangle) {
+	shader := m.Shader
+	if shader == nil {
+		shader = r.shader
+	}
+	anti_alias := r.AntiAlias
+	if m.AntiAlias != nil {
+		anti_alias = *m.AntiAlias
+	}
+	options := &ebiten.DrawTrianglesShaderOptions{
+		Images:    m.Images,
+		Uniforms:  m.Uniforms,
+		Blend:     m.Blend,
+		AntiAlias: anti_alias,
+	}
+	if m.Reflective {
+		r.draw_reflective(target, shader, options, triangles)
+		return
+	}
+	r.draw(target, shader, options, triangles, r.light)
+}
+
+// no_reflections have every mesh reflect nothing
+var no_reflections = &Reflections{Meshes: func(uint32) *mesh.Mesh { return nil }, Context: &pipeline.Context{}}
+
+// draw_reflective draws `triangles` with the probes they reflect, a draw call for each run of them which reflects
+// the same one. Without Reflections they reflect nothing.
+func (r *Renderer) draw_reflective(target *ebiten.Image, shader *ebiten.Shader, options *ebiten.DrawTrianglesShaderOptions, triangles []pipeline.Triangle) {
+	reflections := r.Reflections
+	if reflections == nil {
+		reflections = no_reflections
+	}
+	uniforms := options.Uniforms
+	for len(triangles) > 0 {
+		probe := reflections.nearest(triangles[0].Mesh)
+		n := 1
+		for n < len(triangles) && (triangles[n].Mesh == triangles[n-1].Mesh || reflections.nearest(triangles[n].Mesh) == probe) {
+			n++
+		}
+		options.Images = [4]*ebiten.Image{white_image}
+		if probe != nil {
+			options.Images[0] = probe.Cubemap
+		}
+		options.Uniforms = reflections.uniforms(probe, uniforms)
+		r.draw(target, shader, options, triangles[:n], reflections.normals)
+		triangles = triangles[n:]
+	}
+}
+
+// light is the light reaching the corners of `t`, white without Lighting.
+func (r *Renderer) light(t pipeline.Triangle) [3]vec3 {
+	if r.Lighting == nil {
+		return unlit(t)
+	}
+	return r.Lighting.of(t)
+}
+
+// draw draws `triangles` with the colors of their corners from `colors`, divided by W like their texture
+// coordinates.
+func (r *Renderer) draw(target *ebiten.Image, shader *ebiten.Shader, options *ebiten.DrawTrianglesShaderOptions, triangles []pipeline.Triangle, colors func(t pipeline.Triangle) [3]vec3) {
+	flush := func() {
+		target.DrawTrianglesShader(r.vertices, r.indices, shader, options)
+
+		// reset buffers
+		r.vertices = r.vertices[:0]
+		r.indices = r.indices[:0]
+	}
+
+	for _, triangle := range triangles {
+		if len(r.vertices)+3 > ebiten.MaxVertexCount {
+			flush()
+		}
+
+		v1 := triangle.V1
+		v2 := triangle.V2
+		v3 := triangle.V3
+
+		inv_w1 := 1.0 / v1.Position.W()
+		inv_w2 := 1.0 / v2.Position.W()
+		inv_w3 := 1.0 / v3.Position.W()
+
+		light := colors(triangle)
+
+		r.vertices = append(r.vertices,
+			ebiten.Vertex{
+				SrcX:   v1.Texcoord.X() * inv_w1,
+				SrcY:   v1.Texcoord.Y() * inv_w1,
+				DstX:   v1.Position.X(),
+				DstY:   v1.Position.Y(),
+				ColorR: light[0].X() * inv_w1,
+				ColorG: light[0].Y() * inv_w1,
+				ColorB: light[0].Z() * inv_w1,
+				ColorA: inv_w1,
+			},
+			ebiten.Vertex{
+				SrcX:   v2.Texcoord.X() * inv_w2,
+				SrcY:   v2.Texcoord.Y() * inv_w2,
+				DstX:   v2.Position.X(),
+				DstY:   v2.Position.Y(),
+				ColorR: light[1].X() * inv_w2,
+				ColorG: light[1].Y() * inv_w2,
+				ColorB: light[1].Z() * inv_w2,
+				ColorA: inv_w2,
+			},
+			ebiten.Vertex{
+				SrcX:   v3.Texcoord.X() * inv_w3,
+				SrcY:   v3.Texcoord.Y() * inv_w3,
+				DstX:   v3.Position.X(),
+				DstY:   v3.Position.Y(),
+				ColorR: light[2].X() * inv_w3,
+				ColorG: light[2].Y() * inv_w3,
+				ColorB: light[2].Z() * inv_w3,
+				ColorA: inv_w3,
+			},
+		)
+
+		first_index := uint16(len(r.indices))
+		r.indices = append(r.indices, first_index, first_index+1, first_index+2)
+	}
+	if len(r.indices) > 0 {
+		flush()
+	}
+	r.memory[0].Track(buffers, cap(r.vertices), unsafe.Sizeof(ebiten.Vertex{}))
+	r.memory[1].Track(buffers, cap(r.indices), 2)
+}
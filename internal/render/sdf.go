@@ -0,0 +1,51 @@
+package render
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/kage"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sdf"
+)
+
+// sdf_shader draws the labels of sdf.Atlas.Mesh from the fields of the atlas.
+var sdf_shader = `
+//kage:unit pixels
+package main
+
+#include "texture.kage"
+#include "sdf.kage"
+
+// The style, see sdf.Style.
+var Color vec4
+var Outline float
+var OutlineColor vec4
+var Glow float
+var GlowColor vec4
+var Softness float
+
+func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
+	d := sdf_at(texture_uv(src, rgba)*imageSrc0Size() + imageSrc0Origin())
+	return sdf_shade(d, 0.5*fwidth(d), Color, Outline, OutlineColor, Glow, GlowColor, Softness)
+}
+`
+
+// NewSDFMaterial makes a material for labels made by sdf.Atlas.Mesh, drawing them from `texture`, the image of the
+// atlas, in `style`. It's seen from both sides. Its uniforms are those of sdf.Style.Uniforms, to change the style.
+func NewSDFMaterial(texture *ebiten.Image, style *sdf.Style) (*Material, error) {
+	src, err := kage.PreprocessSource("sdf_label.kage", sdf_shader, nil)
+	if err != nil {
+		return nil, err
+	}
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Material{
+		Shader:   shader,
+		Images:   [4]*ebiten.Image{texture},
+		Uniforms: style.Uniforms(),
+		Blend:    ebiten.BlendSourceOver,
+		Cull:     pipeline.CullNone,
+	}, nil
+}
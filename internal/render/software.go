@@ -0,0 +1,75 @@
+package render
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/invalidate"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/raster"
+)
+
+// TriangleRenderer draws the triangles of a frame, either with the GPU or the software rasterizer.
+type TriangleRenderer interface {
+	DrawTriangles(target, texture *ebiten.Image, triangles []pipeline.Triangle)
+	DrawDepth(target *ebiten.Image, triangles []pipeline.Triangle, near, far float32)
+}
+
+// SoftwareRenderer rasterizes on the CPU with the raster package and uploads the result with WritePixels.
+// Triangles are drawn over whatever is already in the target, just like Renderer.
+type SoftwareRenderer struct {
+	raster *raster.Rasterizer
+	image  *ebiten.Image
+
+	// textures caches the pixels of every texture drawn, reading them back from the GPU every frame would
+	// cost more than the rasterization itself. Textures are assumed not to change once drawn.
+	textures map[*ebiten.Image]*image.RGBA
+}
+
+func NewSoftwareRenderer() *SoftwareRenderer {
+	r := &SoftwareRenderer{
+		raster:   raster.New(0, 0),
+		textures: make(map[*ebiten.Image]*image.RGBA),
+	}
+	// textures that did change are read back again after a purge
+	invalidate.Register("software_textures", func() { clear(r.textures) })
+	return r
+}
+
+func (r *SoftwareRenderer) texture(texture *ebiten.Image) *image.RGBA {
+	pixels, ok := r.textures[texture]
+	if !ok {
+		bounds := texture.Bounds()
+		pixels = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		texture.ReadPixels(pixels.Pix)
+		r.textures[texture] = pixels
+	}
+	return pixels
+}
+
+func (r *SoftwareRenderer) DrawTriangles(target, texture *ebiten.Image, triangles []pipeline.Triangle) {
+	r.draw(target, func() {
+		r.raster.Draw(triangles, r.texture(texture))
+	})
+}
+
+// draw runs `rasterize` on a cleared target of the right size and draws the result over `target`.
+func (r *SoftwareRenderer) draw(target *ebiten.Image, rasterize func()) {
+	bounds := target.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if rw, rh := r.raster.Size(); rw != w || rh != h || r.image == nil {
+		r.raster.Resize(w, h)
+		r.image = ebiten.NewImage(w, h)
+	}
+
+	// cleared to transparent so only the triangles cover the target
+	clear(r.raster.Pixels)
+	rasterize()
+	r.image.WritePixels(r.raster.Pixels)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(bounds.Min.X), float64(bounds.Min.Y))
+	target.DrawImage(r.image, op)
+}
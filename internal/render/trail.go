@@ -0,0 +1,61 @@
+package render
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/kage"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pipeline"
+)
+
+// trail_shader draws the ribbons of package trail: the texture scrolls along them, and they fade towards their
+// tail and their edges.
+var trail_shader = `
+//kage:unit pixels
+package main
+
+#include "texture.kage"
+
+// Tint is multiplied in, premultiplied. Repeat is how often the texture repeats along the ribbon and Scroll how
+// many times a second it moves along it, Time is in seconds. Fade is how much of the tail fades out, from 0 to 1.
+var Tint vec4
+var Repeat float
+var Scroll float
+var Time float
+var Fade float
+
+func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
+	uv := texture_uv(src, rgba)
+	along := fract(uv.x*Repeat - Time*Scroll)
+	color := imageSrc0At(vec2(along, uv.y)*imageSrc0Size() + imageSrc0Origin())
+	fade := 1 - smoothstep(1-Fade, 1, uv.x)
+	edge := 1 - abs(uv.y*2-1)
+	return color * Tint * fade * edge
+}
+`
+
+// NewTrailMaterial makes a material for the ribbons of package trail, which adds `texture` onto what's behind,
+// scrolling along the ribbon, and fades it out towards the tail and the edges. Set "Time" in its uniforms every
+// frame, and "Tint", "Repeat", "Scroll" and "Fade" to taste.
+func NewTrailMaterial(texture *ebiten.Image) (*Material, error) {
+	src, err := kage.PreprocessSource("trail.kage", trail_shader, nil)
+	if err != nil {
+		return nil, err
+	}
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Material{
+		Shader: shader,
+		Images: [4]*ebiten.Image{texture},
+		Uniforms: map[string]any{
+			"Tint":   []float32{1, 1, 1, 1},
+			"Repeat": float32(1),
+			"Scroll": float32(0),
+			"Time":   float32(0),
+			"Fade":   float32(0.5),
+		},
+		Blend: ebiten.BlendLighter,
+		Cull:  pipeline.CullNone,
+	}, nil
+}
@@ -0,0 +1,161 @@
+// Package richtext parses the markdown-lite markup of help screens and about panels and wraps it into lines. It
+// doesn't draw anything, the ui package does, given a way to measure text.
+//
+// The markup:
+//
+//	# Heading         a line starting with # is bold
+//	**bold**          bold text
+//	{#ff8800 text}    colored text, the color in hex
+//	[text](target)    a link, clicking it hands target to the widget's callback
+//	\*                a backslash takes the next character literally
+//
+// A blank line starts a new paragraph, single line breaks are spaces, as in markdown. Markers which don't close
+// are kept as they were typed.
+package richtext
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Style is how a span is drawn.
+type Style struct {
+	Bold bool
+	// Color is the color of colored spans, Colored is false for the default one.
+	Color   color.RGBA
+	Colored bool
+	// Link is the target of a link, empty for everything else.
+	Link string
+}
+
+// Span is a piece of text in one style.
+type Span struct {
+	Text  string
+	Style Style
+}
+
+// Paragraph is a block of spans wrapped together.
+type Paragraph []Span
+
+// Parse parses `markup` into paragraphs.
+func Parse(markup string) []Paragraph {
+	var paragraphs []Paragraph
+	var block []string
+	flush := func() {
+		if len(block) > 0 {
+			paragraphs = append(paragraphs, parse_inline(strings.Join(block, " "), Style{}))
+			block = block[:0]
+		}
+	}
+	for _, line := range strings.Split(markup, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "# "):
+			flush()
+			paragraphs = append(paragraphs, parse_inline(strings.TrimSpace(line[2:]), Style{Bold: true}))
+		default:
+			block = append(block, line)
+		}
+	}
+	flush()
+	return paragraphs
+}
+
+// parse_inline parses the spans of `s`, all of them at least `style`.
+func parse_inline(s string, style Style) Paragraph {
+	var spans Paragraph
+	var text strings.Builder
+	emit := func() {
+		if text.Len() > 0 {
+			spans = append(spans, Span{Text: text.String(), Style: style})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			text.WriteByte(s[i])
+
+		case strings.HasPrefix(s[i:], "**"):
+			end := closing(s, i+2, "**")
+			if end < 0 {
+				text.WriteString("**")
+				i++
+				continue
+			}
+			emit()
+			inner := style
+			inner.Bold = true
+			spans = append(spans, parse_inline(s[i+2:end], inner)...)
+			i = end + 1
+
+		case c == '{' && strings.HasPrefix(s[i+1:], "#"):
+			end := closing(s, i+1, "}")
+			hex, body, ok := strings.Cut(s[i+2:max(end, i+2)], " ")
+			clr, valid := parse_color(hex)
+			if end < 0 || !ok || !valid {
+				text.WriteByte(c)
+				continue
+			}
+			emit()
+			inner := style
+			inner.Color, inner.Colored = clr, true
+			spans = append(spans, parse_inline(body, inner)...)
+			i = end
+
+		case c == '[':
+			end := closing(s, i+1, "]")
+			if end < 0 || !strings.HasPrefix(s[end+1:], "(") {
+				text.WriteByte(c)
+				continue
+			}
+			target_end := closing(s, end+2, ")")
+			if target_end < 0 {
+				text.WriteByte(c)
+				continue
+			}
+			emit()
+			inner := style
+			inner.Link = s[end+2 : target_end]
+			spans = append(spans, parse_inline(s[i+1:end], inner)...)
+			i = target_end
+
+		default:
+			text.WriteByte(c)
+		}
+	}
+	emit()
+	return spans
+}
+
+// closing returns the index of `marker` in `s` from `start` on, skipping escaped characters, or -1.
+func closing(s string, start int, marker string) int {
+	for i := start; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], marker) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parse_color parses rrggbb.
+func parse_color(hex string) (color.RGBA, bool) {
+	if len(hex) != 6 {
+		return color.RGBA{}, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, true
+}
@@ -0,0 +1,80 @@
+package richtext
+
+import (
+	"image/color"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParse(t *testing.T) {
+	orange := color.RGBA{255, 136, 0, 255}
+	got := Parse("# About\n\nSome **bold** and {#ff8800 orange **loud**} text,\nsee [the help](help) \\*here\\*.\n\n[not a link] {#zz x}")
+	want := []Paragraph{
+		{{Text: "About", Style: Style{Bold: true}}},
+		{
+			{Text: "Some "},
+			{Text: "bold", Style: Style{Bold: true}},
+			{Text: " and "},
+			{Text: "orange ", Style: Style{Color: orange, Colored: true}},
+			{Text: "loud", Style: Style{Bold: true, Color: orange, Colored: true}},
+			{Text: " text, see "},
+			{Text: "the help", Style: Style{Link: "help"}},
+			{Text: " *here*."},
+		},
+		{{Text: "[not a link] {#zz x}"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parsed\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestUnclosedMarkersStay(t *testing.T) {
+	got := Parse("2 ** 3 and [x](y")
+	if len(got) != 1 || len(got[0]) != 1 || got[0][0].Text != "2 ** 3 and [x](y" {
+		t.Fatalf("parsed %+v", got)
+	}
+}
+
+// monospace measures a character as 1 wide
+func monospace(text string, style Style) float64 {
+	return float64(utf8.RuneCountInString(text))
+}
+
+func texts(lines []Line) []string {
+	var out []string
+	for _, l := range lines {
+		s := ""
+		for _, r := range l.Runs {
+			s += r.Text
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestWrap(t *testing.T) {
+	lines := Wrap(Parse("the quick **brown** fox jumps over the lazy dog\n\nsecond"), 10, monospace)
+	want := []string{"the quick", "brown fox", "jumps over", "the lazy", "dog", "", "second"}
+	if got := texts(lines); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrapped into %q, want %q", got, want)
+	}
+	for _, l := range lines {
+		if l.Width > 10 {
+			t.Fatalf("%+v is wider than the lines", l)
+		}
+	}
+	// the bold word starts its line and the rest of the line follows it
+	if runs := lines[1].Runs; len(runs) != 2 || !runs[0].Style.Bold || runs[1].X != 5 || runs[1].Text != " fox" {
+		t.Fatalf("the second line is %+v", runs)
+	}
+}
+
+func TestWrapBreaksLongWords(t *testing.T) {
+	got := texts(Wrap(Parse("ab イミディエイトモード"), 4, monospace))
+	// the small ィ is a character of its own
+	want := []string{"ab", "イミディ", "エイトモ", "ード"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrapped into %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,129 @@
+package richtext
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Run is a piece of a line in one style, `X` from the start of the line.
+type Run struct {
+	Text  string
+	Style Style
+	X     float64
+	Width float64
+}
+
+// Line is a wrapped line. Paragraphs are apart by an empty line.
+type Line struct {
+	Runs  []Run
+	Width float64
+}
+
+// Measure returns how wide `text` is in `style`.
+type Measure func(text string, style Style) float64
+
+// Wrap breaks `paragraphs` into lines no wider than `width`, between words. Words wider than a line are broken
+// between characters, which is also how text without spaces such as Japanese wraps.
+func Wrap(paragraphs []Paragraph, width float64, measure Measure) []Line {
+	var lines []Line
+	for i, p := range paragraphs {
+		if i > 0 {
+			lines = append(lines, Line{})
+		}
+		lines = wrap_paragraph(p, width, measure, lines)
+	}
+	return lines
+}
+
+// word is a word and the space before it, the space is left out at the start of a line.
+type word struct {
+	text  string
+	style Style
+	space bool
+}
+
+func wrap_paragraph(p Paragraph, width float64, measure Measure, lines []Line) []Line {
+	var words []word
+	space := false
+	for _, span := range p {
+		text := span.Text
+		for text != "" {
+			if text[0] == ' ' {
+				space = true
+				text = text[1:]
+				continue
+			}
+			n := strings.IndexByte(text, ' ')
+			if n < 0 {
+				n = len(text)
+			}
+			words = append(words, word{text: text[:n], style: span.Style, space: space && len(words) > 0})
+			space = false
+			text = text[n:]
+		}
+	}
+
+	var line Line
+	put := func(w word) {
+		text := w.text
+		if w.space && len(line.Runs) > 0 {
+			text = " " + text
+		}
+		// words of the same style join up with the run before, so links and colors are one piece each
+		if n := len(line.Runs); n > 0 && line.Runs[n-1].Style == w.style {
+			last := &line.Runs[n-1]
+			last.Text += text
+			last.Width = measure(last.Text, last.Style)
+		} else {
+			line.Runs = append(line.Runs, Run{Text: text, Style: w.style, X: line.Width, Width: measure(text, w.style)})
+		}
+		last := line.Runs[len(line.Runs)-1]
+		line.Width = last.X + last.Width
+	}
+	next_line := func() {
+		lines = append(lines, line)
+		line = Line{}
+	}
+
+	for _, w := range words {
+		needed := measure(w.text, w.style)
+		if w.space && len(line.Runs) > 0 {
+			needed += measure(" ", w.style)
+		}
+		if line.Width+needed <= width || len(line.Runs) == 0 && needed <= width {
+			put(w)
+			continue
+		}
+		if len(line.Runs) > 0 {
+			next_line()
+			w.space = false
+		}
+		// a word too long for a line of its own is broken up
+		for measure(w.text, w.style) > width {
+			n := fit(w.text, width, func(s string) float64 { return measure(s, w.style) })
+			put(word{text: w.text[:n], style: w.style})
+			next_line()
+			w.text = w.text[n:]
+		}
+		if w.text != "" {
+			put(w)
+		}
+	}
+	if len(line.Runs) > 0 {
+		next_line()
+	}
+	return lines
+}
+
+// fit returns how many bytes of `text` fit in `width`, at least one character.
+func fit(text string, width float64, measure func(string) float64) int {
+	n := 0
+	for n < len(text) {
+		_, size := utf8.DecodeRuneInString(text[n:])
+		if n > 0 && measure(text[:n+size]) > width {
+			break
+		}
+		n += size
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package scene
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/noise"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/textmesh"
+)
+
+// BuildMesh builds the mesh of a recipe, a shape and its sizes separated by spaces, in the node's own space:
+//
+//	box 2 1 1            a box 2 wide, 1 high and 1 deep
+//	plane 20             a floor 20 across
+//	sphere 1 12 16       a sphere of radius 1 with 12 rings of 16 segments
+//	terrain 20 64 2 0.2  simplex fBm hills 20 across in 64 cells a side, 2 high, 0.2 hills per unit
+//	obj models/a.obj     a Wavefront OBJ file
+//	obj models/a.obj 45  the same smoothed with edges bent more than 45 degrees hard, in place of its own normals
+//	text 0.5 0.1 Hello   Hello in letters 0.5 high and 0.1 deep, centered, flat when 0 deep
+func BuildMesh(recipe string) (*mesh.Mesh, error) {
+	fields := strings.Fields(recipe)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty mesh recipe")
+	}
+	shape, args := fields[0], fields[1:]
+	if shape == "obj" {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("mesh %q: obj takes a path and maybe a crease angle", recipe)
+		}
+		src, err := os.ReadFile(args[0])
+		if err != nil {
+			return nil, err
+		}
+		m, err := mesh.LoadOBJ(src)
+		if err != nil || len(args) == 1 {
+			return m, err
+		}
+		crease, err := strconv.ParseFloat(args[1], 32)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %q: %w", recipe, err)
+		}
+		m.ComputeNormals(mgl32.DegToRad(float(crease)))
+		m.ComputeTangents()
+		return m, nil
+	}
+
+	if shape == "text" {
+		return text_mesh(recipe, args)
+	}
+
+	numbers := make([]float, len(args))
+	for i, arg := range args {
+		f, err := strconv.ParseFloat(arg, 32)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %q: %w", recipe, err)
+		}
+		numbers[i] = float(f)
+	}
+	want := map[string]int{"box": 3, "plane": 1, "sphere": 3, "terrain": 4}
+	n, ok := want[shape]
+	if !ok {
+		return nil, fmt.Errorf("mesh %q: unknown shape %s", recipe, shape)
+	}
+	if len(numbers) != n {
+		return nil, fmt.Errorf("mesh %q: %s takes %d numbers", recipe, shape, n)
+	}
+	switch shape {
+	case "box":
+		return mesh.Box(vec3{numbers[0], numbers[1], numbers[2]}), nil
+	case "plane":
+		return mesh.Plane(numbers[0]), nil
+	case "terrain":
+		height, frequency := numbers[2], numbers[3]
+		return mesh.Heightfield(numbers[0], int(numbers[1]), func(x, z float) float {
+			return height * noise.FBM(noise.Simplex, x*frequency, z*frequency)
+		}), nil
+	default:
+		return mesh.UVSphere(numbers[0], int(numbers[1]), int(numbers[2])), nil
+	}
+}
+
+func text_mesh(recipe string, args []string) (*mesh.Mesh, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("mesh %q: text takes a size, a depth and the text", recipe)
+	}
+	var numbers [2]float
+	for i := range numbers {
+		f, err := strconv.ParseFloat(args[i], 32)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %q: %w", recipe, err)
+		}
+		numbers[i] = float(f)
+	}
+	return textmesh.Default().Mesh(strings.Join(args[2:], " "), textmesh.Options{Size: numbers[0], Depth: numbers[1], Center: true})
+}
@@ -0,0 +1,353 @@
+// Package scene is a graph of named nodes with transforms, the meshes and materials they reference, lights and a
+// camera, saved to and loaded from JSON files so arranged scenes survive restarts. Meshes are referenced by
+// recipe, see BuildMesh, and materials by name into the scene's own table. Subtrees used more than once are kept as
+// prefabs, which nodes instance.
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
+	mat4  = mgl32.Mat4
+)
+
+// version is written into every file, files of a later version don't load
+const version = 1
+
+// Scene is what a scene file holds.
+type Scene struct {
+	Version   int                  `json:"version"`
+	Camera    Camera               `json:"camera"`
+	Materials map[string]*Material `json:"materials,omitempty"`
+	// Prefabs are subtrees by name, which nodes instance as many times as they like.
+	Prefabs map[string]*Node `json:"prefabs,omitempty"`
+	Nodes   []*Node          `json:"nodes"`
+}
+
+// Camera is where the scene is looked at from, Fov is vertical and in degrees.
+type Camera struct {
+	Position vec3  `json:"position"`
+	Target   vec3  `json:"target"`
+	Fov      float `json:"fov"`
+}
+
+// Material is a set of named colors and numbers for a shader, the demo drawing the scene decides what they mean,
+// or the name of a material file (see package material) to draw with instead.
+type Material struct {
+	Texture string          `json:"texture,omitempty"`
+	Params  map[string]vec4 `json:"params,omitempty"`
+	File    string          `json:"file,omitempty"`
+}
+
+// Light kinds.
+const (
+	Directional = "directional"
+	Point       = "point"
+	// Ambient lights everything the same wherever its node is.
+	Ambient = "ambient"
+)
+
+// Light lights the scene from its node, along the node's -z when directional.
+type Light struct {
+	Kind      string `json:"kind"`
+	Color     vec3   `json:"color"`
+	Intensity float  `json:"intensity"`
+	// Range is how far a point light reaches.
+	Range float `json:"range,omitempty"`
+}
+
+// Probe captures the scene around its node into a cubemap, which reflective materials near it reflect. See
+// render.Probe.
+type Probe struct {
+	// Radius is how far from the node meshes reflect the probe.
+	Radius float `json:"radius"`
+	// Size is the width of a face of the cubemap in pixels.
+	Size int `json:"size"`
+}
+
+// Transform places a node relative to its parent. Rotation is in degrees about x, y and z, applied in that order.
+type Transform struct {
+	Position vec3 `json:"position"`
+	Rotation vec3 `json:"rotation"`
+	Scale    vec3 `json:"scale"`
+}
+
+// Identity is the transform which leaves a node where its parent is.
+var Identity = Transform{Scale: vec3{1, 1, 1}}
+
+// Matrix is the transform as a matrix, scaling, then rotating, then translating.
+func (t Transform) Matrix() mat4 {
+	return matrix(t.Position, t.quat(), t.Scale)
+}
+
+// Rotation is the rotation of `q` in the angles of Transform.Rotation, for nodes turned by something which works
+// in quaternions, like the bodies of package physics.
+func Rotation(q mgl32.Quat) vec3 {
+	// the matrix is that of x times y times z, whose third column starts with the sine of y
+	m := q.Normalize().Mat4()
+	sin_y := mgl32.Clamp(m.At(0, 2), -1, 1)
+	var x, y, z float64
+	y = math.Asin(float64(sin_y))
+	if math.Abs(float64(sin_y)) < 0.9999 {
+		x = math.Atan2(float64(-m.At(1, 2)), float64(m.At(2, 2)))
+		z = math.Atan2(float64(-m.At(0, 1)), float64(m.At(0, 0)))
+	} else {
+		// y is a quarter turn and x and z turn about the same axis, so x takes all of it
+		x = math.Atan2(float64(m.At(2, 1)), float64(m.At(1, 1)))
+	}
+	return vec3{mgl32.RadToDeg(float(x)), mgl32.RadToDeg(float(y)), mgl32.RadToDeg(float(z))}
+}
+
+func (t Transform) quat() mgl32.Quat {
+	r := t.Rotation
+	return mgl32.AnglesToQuat(mgl32.DegToRad(r[0]), mgl32.DegToRad(r[1]), mgl32.DegToRad(r[2]), mgl32.XYZ)
+}
+
+func matrix(position vec3, rotation mgl32.Quat, scale vec3) mat4 {
+	return mgl32.Translate3D(position.Elem()).Mul4(rotation.Mat4()).Mul4(mgl32.Scale3D(scale.Elem()))
+}
+
+// Between is the matrix of the transform `t` of the way from `a` to `b`, turning the shorter way round between
+// their rotations however many turns their angles are apart.
+func Between(a, b Transform, t float) mat4 {
+	lerp := func(a, b vec3) vec3 { return a.Add(b.Sub(a).Mul(t)) }
+	qa, qb := a.quat(), b.quat()
+	if qa.Dot(qb) < 0 {
+		qb = qb.Scale(-1)
+	}
+	return matrix(lerp(a.Position, b.Position), mgl32.QuatNlerp(qa, qb, t), lerp(a.Scale, b.Scale))
+}
+
+// Node is a thing in the scene. Everything but the transform and the children is optional.
+type Node struct {
+	Name      string    `json:"name"`
+	Transform Transform `json:"transform"`
+	// Mesh is the recipe of the node's mesh, see BuildMesh, and Material the name of its material.
+	Mesh     string  `json:"mesh,omitempty"`
+	Material string  `json:"material,omitempty"`
+	Light    *Light  `json:"light,omitempty"`
+	Probe    *Probe  `json:"probe,omitempty"`
+	Children []*Node `json:"children,omitempty"`
+	// Prefab is the name of the prefab the node is an instance of, the prefab is then placed under the node after
+	// its children, and Overrides changes the nodes of the prefab for this instance only, by their path.
+	Prefab    string              `json:"prefab,omitempty"`
+	Overrides map[string]Override `json:"overrides,omitempty"`
+
+	// instance is the prefab as this node instances it, taken from the prefab on every walk
+	instance []*Node
+	hidden   bool
+	// previous is the transform at the last Tick, if there was one since the node was made
+	previous Transform
+	ticked   bool
+}
+
+// Override changes a node of a prefab in one instance. The node is named by its path from the root of the prefab,
+// the names below the root separated by slashes, the root itself being "". Empty fields leave the prefab's.
+type Override struct {
+	Transform *Transform `json:"transform,omitempty"`
+	Mesh      string     `json:"mesh,omitempty"`
+	Material  string     `json:"material,omitempty"`
+	// Hidden leaves the node and its children out of the instance.
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// Clone returns a deep copy of the node and its children.
+func (n *Node) Clone() *Node {
+	c := &Node{
+		Name:      n.Name,
+		Transform: n.Transform,
+		Mesh:      n.Mesh,
+		Material:  n.Material,
+		Prefab:    n.Prefab,
+		Overrides: maps.Clone(n.Overrides),
+	}
+	if n.Light != nil {
+		light := *n.Light
+		c.Light = &light
+	}
+	if n.Probe != nil {
+		probe := *n.Probe
+		c.Probe = &probe
+	}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, child.Clone())
+	}
+	return c
+}
+
+// Instance returns a node called `name` instancing the prefab `prefab`, where its parent is.
+func Instance(name, prefab string) *Node {
+	return &Node{Name: name, Transform: Identity, Prefab: prefab}
+}
+
+// MakePrefab turns `n` into the prefab `name` and `n` into its first instance, which stays where `n` was.
+func (s *Scene) MakePrefab(n *Node, name string) {
+	root := n.Clone()
+	root.Transform = Identity
+	if s.Prefabs == nil {
+		s.Prefabs = make(map[string]*Node)
+	}
+	s.Prefabs[name] = root
+	*n = Node{Name: n.Name, Transform: n.Transform, Prefab: name}
+}
+
+// max_nesting is how deep prefabs instance prefabs, which keeps one which instances itself from going forever
+const max_nesting = 8
+
+// instance brings the prefab `n` instances up to date and returns its root, nil when `n` isn't an instance or its
+// prefab is missing. The nodes of the instance are kept from walk to walk, so they're the same nodes every time.
+func (s *Scene) instance(n *Node, depth int) []*Node {
+	if n.Prefab == "" || depth >= max_nesting {
+		return nil
+	}
+	prefab := s.Prefabs[n.Prefab]
+	if prefab == nil {
+		return nil
+	}
+	if n.instance == nil {
+		n.instance = []*Node{{}}
+	}
+	n.instance[0].sync(prefab, "", n.Overrides)
+	return n.instance
+}
+
+// sync makes the node a copy of the prefab's node `src` at `path`, with the override of the path.
+func (n *Node) sync(src *Node, path string, overrides map[string]Override) {
+	n.Name, n.Transform, n.Mesh, n.Material, n.Light, n.Probe = src.Name, src.Transform, src.Mesh, src.Material, src.Light, src.Probe
+	n.Prefab, n.Overrides, n.hidden = src.Prefab, src.Overrides, false
+	if o, ok := overrides[path]; ok {
+		if o.Transform != nil {
+			n.Transform = *o.Transform
+		}
+		if o.Mesh != "" {
+			n.Mesh = o.Mesh
+		}
+		if o.Material != "" {
+			n.Material = o.Material
+		}
+		n.hidden = o.Hidden
+	}
+	for len(n.Children) < len(src.Children) {
+		n.Children = append(n.Children, &Node{})
+	}
+	n.Children = n.Children[:len(src.Children)]
+	for i, child := range src.Children {
+		n.Children[i].sync(child, strings.TrimPrefix(path+"/"+child.Name, "/"), overrides)
+	}
+}
+
+// New returns an empty scene looked at from a little way back along +z.
+func New() *Scene {
+	return &Scene{
+		Version:   version,
+		Camera:    Camera{Position: vec3{0, 0, 3}, Fov: 45},
+		Materials: make(map[string]*Material),
+	}
+}
+
+// Walk calls `fn` with every node and its transform in world space, parents before their children. The nodes of
+// the prefabs instanced are walked as well, as they are in each instance: they're copies taken from the prefab
+// on every walk, so editing the prefab edits every instance, and editing them does nothing lasting.
+func (s *Scene) Walk(fn func(n *Node, world mat4)) {
+	s.walk(func(n *Node) mat4 { return n.Transform.Matrix() }, fn)
+}
+
+// Tick remembers where every node is. Call it every tick before moving any, then WalkBetween can draw them on
+// their way from there to where the tick moved them, smoothly when frames come faster than ticks.
+func (s *Scene) Tick() {
+	s.Walk(func(n *Node, _ mat4) {
+		n.previous, n.ticked = n.Transform, true
+	})
+}
+
+// WalkBetween is Walk with every node `t` of the way from where it was at the last Tick, at 0, to where it is, at
+// 1. Nodes made since the last Tick are where they are.
+func (s *Scene) WalkBetween(t float, fn func(n *Node, world mat4)) {
+	s.walk(func(n *Node) mat4 {
+		if !n.ticked {
+			return n.Transform.Matrix()
+		}
+		return Between(n.previous, n.Transform, t)
+	}, fn)
+}
+
+// walk walks the nodes with `local` the transform of a node relative to its parent.
+func (s *Scene) walk(local func(n *Node) mat4, fn func(n *Node, world mat4)) {
+	var walk func(nodes []*Node, parent mat4, depth int)
+	walk = func(nodes []*Node, parent mat4, depth int) {
+		for _, n := range nodes {
+			if n.hidden {
+				continue
+			}
+			world := parent.Mul4(local(n))
+			fn(n, world)
+			walk(n.Children, world, depth)
+			walk(s.instance(n, depth), world, depth+1)
+		}
+	}
+	walk(s.Nodes, mgl32.Ident4(), 0)
+}
+
+// Find returns the first node called `name`, depth first, or nil.
+func (s *Scene) Find(name string) *Node {
+	var found *Node
+	s.Walk(func(n *Node, _ mat4) {
+		if found == nil && n.Name == name {
+			found = n
+		}
+	})
+	return found
+}
+
+// Save writes the scene to `path`, making its directory when there's none.
+func (s *Scene) Save(path string) error {
+	s.Version = version
+	src, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, src, 0o644)
+}
+
+// Load reads a scene from `path`.
+func Load(path string) (*Scene, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Decode(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Decode reads a scene from the JSON of a file.
+func Decode(src []byte) (*Scene, error) {
+	s := New()
+	if err := json.Unmarshal(src, s); err != nil {
+		return nil, fmt.Errorf("bad scene: %w", err)
+	}
+	if s.Version > version {
+		return nil, fmt.Errorf("scene is version %d, newer than %d", s.Version, version)
+	}
+	if s.Materials == nil {
+		s.Materials = make(map[string]*Material)
+	}
+	return s, nil
+}
@@ -0,0 +1,190 @@
+package scene
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func example() *Scene {
+	s := New()
+	s.Camera = Camera{Position: vec3{0, 2, 5}, Target: vec3{0, 1, 0}, Fov: 60}
+	s.Materials["checker"] = &Material{Params: map[string]vec4{"light": {1, 1, 1, 1}}}
+	table := &Node{Name: "table", Transform: Identity, Mesh: "box 2 0.1 1", Material: "checker"}
+	table.Transform.Position = vec3{1, 0, 0}
+	table.Children = []*Node{{Name: "cup", Transform: Identity, Mesh: "sphere 0.1 6 8"}}
+	table.Children[0].Transform.Position = vec3{0, 1, 0}
+	sun := &Node{Name: "sun", Transform: Identity, Light: &Light{Kind: Directional, Color: vec3{1, 1, 0.9}, Intensity: 1}}
+	mirror := &Node{Name: "mirror", Transform: Identity, Mesh: "sphere 0.2 12 16", Probe: &Probe{Radius: 1, Size: 32}}
+	s.Nodes = []*Node{table, sun, mirror}
+	return s
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenes", "a.json")
+	s := example()
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(s)
+	got, _ := json.Marshal(loaded)
+	if string(got) != string(want) {
+		t.Fatalf("loaded %s, saved %s", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("a scene from a later version loaded")
+	}
+}
+
+func TestWalkComposesTransforms(t *testing.T) {
+	s := example()
+	s.Find("table").Transform.Rotation = vec3{0, 90, 0}
+	var cup mgl32.Mat4
+	s.Walk(func(n *Node, world mgl32.Mat4) {
+		if n.Name == "cup" {
+			cup = world
+		}
+	})
+	// the cup sits a unit above the table, which is a unit along x, turning the table doesn't move it
+	if p := mgl32.TransformCoordinate(vec3{}, cup); !p.ApproxEqualThreshold(vec3{1, 1, 0}, 1e-5) {
+		t.Fatalf("the cup is at %v", p)
+	}
+	if s.Find("nothing") != nil {
+		t.Fatal("found a node which isn't there")
+	}
+}
+
+func TestPrefabInstances(t *testing.T) {
+	s := New()
+	chair := &Node{Name: "chair", Transform: Identity, Mesh: "box 1 1 1"}
+	chair.Children = []*Node{{Name: "back", Transform: Identity, Mesh: "box 1 1 0.1"}}
+	chair.Transform.Position = vec3{5, 0, 0}
+	s.Nodes = []*Node{chair, Instance("other", "chair")}
+	s.MakePrefab(chair, "chair")
+	s.Nodes[1].Transform.Position = vec3{0, 0, 2}
+	s.Nodes[1].Overrides = map[string]Override{"back": {Mesh: "box 1 2 0.1"}}
+
+	backs := func() (meshes []string, at []vec3) {
+		s.Walk(func(n *Node, world mgl32.Mat4) {
+			if n.Name == "back" {
+				meshes = append(meshes, n.Mesh)
+				at = append(at, mgl32.TransformCoordinate(vec3{}, world))
+			}
+		})
+		return meshes, at
+	}
+	meshes, at := backs()
+	if len(meshes) != 2 || meshes[0] != "box 1 1 0.1" || meshes[1] != "box 1 2 0.1" {
+		t.Fatalf("the backs of the chairs are %v", meshes)
+	}
+	if !at[0].ApproxEqual(vec3{5, 0, 0}) || !at[1].ApproxEqual(vec3{0, 0, 2}) {
+		t.Fatalf("the backs of the chairs are at %v", at)
+	}
+
+	// editing the prefab edits both, but not what the override changed
+	s.Prefabs["chair"].Children[0].Mesh = "box 2 2 0.1"
+	s.Prefabs["chair"].Children[0].Transform.Position = vec3{0, 1, 0}
+	meshes, at = backs()
+	if meshes[0] != "box 2 2 0.1" || meshes[1] != "box 1 2 0.1" || !at[1].ApproxEqual(vec3{0, 1, 2}) {
+		t.Fatalf("after editing the prefab the backs are %v at %v", meshes, at)
+	}
+
+	s.Nodes[1].Overrides["back"] = Override{Hidden: true}
+	if meshes, _ = backs(); len(meshes) != 1 {
+		t.Fatalf("a hidden back was walked, %v", meshes)
+	}
+
+	// a prefab instancing itself stops somewhere
+	s.Prefabs["chair"].Children = append(s.Prefabs["chair"].Children, Instance("again", "chair"))
+	count := 0
+	s.Walk(func(*Node, mgl32.Mat4) { count++ })
+	if count > 1000 {
+		t.Fatalf("walked %d nodes", count)
+	}
+}
+
+func TestBuildMesh(t *testing.T) {
+	for _, recipe := range []string{"box 1 2 3", "plane 10", "sphere 1 4 6", "terrain 20 16 2 0.2", "text 1 0.2 Hi there"} {
+		if m, err := BuildMesh(recipe); err != nil || len(m.Triangles) == 0 {
+			t.Errorf("%q built %v: %v", recipe, m, err)
+		}
+	}
+	for _, recipe := range []string{"", "box 1 2", "cone 1", "plane x", "obj", "text 1 Hi"} {
+		if _, err := BuildMesh(recipe); err == nil {
+			t.Errorf("%q built a mesh", recipe)
+		}
+	}
+}
+
+func TestWalkBetweenTicks(t *testing.T) {
+	s := example()
+	cup := s.Find("cup")
+	at := func(fn func(func(n *Node, world mgl32.Mat4))) vec3 {
+		var p vec3
+		fn(func(n *Node, world mgl32.Mat4) {
+			if n == cup {
+				p = mgl32.TransformCoordinate(vec3{}, world)
+			}
+		})
+		return p
+	}
+	between := func(f float) func(func(n *Node, world mgl32.Mat4)) {
+		return func(fn func(n *Node, world mgl32.Mat4)) { s.WalkBetween(f, fn) }
+	}
+
+	// nothing was ticked yet, so everything is where it is
+	if p := at(between(0)); p.Sub(vec3{1, 1, 0}).Len() > 1e-5 {
+		t.Fatalf("the cup is at %v before any tick", p)
+	}
+
+	s.Tick()
+	table := s.Find("table")
+	table.Transform.Position = vec3{3, 0, 0}
+	// a full turn and a quarter is a quarter turn the short way round
+	table.Transform.Rotation = vec3{450, 0, 0}
+	cup.Transform.Position = vec3{0, 2, 0}
+
+	if p := at(between(0)); p.Sub(vec3{1, 1, 0}).Len() > 1e-5 {
+		t.Fatalf("the cup is at %v at the last tick, want where it was", p)
+	}
+	if p, want := at(between(1)), at(s.Walk); p.Sub(want).Len() > 1e-5 {
+		t.Fatalf("the cup is at %v all the way to now, want %v", p, want)
+	}
+	// half way the table is at 2 and an eighth of a turn about x, the cup 1.5 above it along the turned y
+	half := float(math.Sqrt2 / 2)
+	if p := at(between(0.5)); p.Sub(vec3{2, 1.5 * half, 1.5 * half}).Len() > 1e-4 {
+		t.Fatalf("the cup is at %v half way", p)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	for _, q := range []mgl32.Quat{
+		mgl32.QuatIdent(),
+		mgl32.QuatRotate(0.15, vec3{0, 1, 0}),
+		mgl32.QuatRotate(2.5, vec3{1, 2, 3}.Normalize()),
+		mgl32.QuatRotate(-1, vec3{-3, 0.5, 1}.Normalize()),
+		mgl32.QuatRotate(math.Pi/2, vec3{0, 1, 0}).Mul(mgl32.QuatRotate(0.4, vec3{1, 0, 0})),
+		// y a quarter turn, where x and z turn about the same axis
+		mgl32.QuatRotate(0.4, vec3{1, 0, 0}).Mul(mgl32.QuatRotate(math.Pi/2, vec3{0, 1, 0})).Mul(mgl32.QuatRotate(0.3, vec3{0, 0, 1})),
+	} {
+		got, want := Transform{Rotation: Rotation(q), Scale: vec3{1, 1, 1}}.Matrix(), q.Mat4()
+		for i := range got {
+			if math.Abs(float64(got[i]-want[i])) > 1e-4 {
+				t.Errorf("%v turned into the angles %v, which are\n%v", q, Rotation(q), got)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package sdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+// atlas_width is how wide atlases are, glyphs are packed in rows across it
+const atlas_width = 1024
+
+// Atlas is the fields of the glyphs of a font, packed into one image.
+type Atlas struct {
+	// Image holds the fields, the glyphs are white on black with their edges at Edge.
+	Image *image.Alpha
+	// Size is how many pixels an em of the fields is, and Spread how many pixels they reach either side of an edge.
+	Size, Spread float
+	// Ascent and Descent are how far the font reaches above and below the baseline and LineHeight how far apart
+	// its lines are, in ems.
+	Ascent, Descent, LineHeight float
+	Glyphs                      map[rune]Glyph
+
+	// kerning moves glyphs closer or apart after others, in ems, for the pairs the font has it for
+	kerning map[[2]rune]float
+}
+
+// Glyph is where a glyph is in an atlas.
+type Glyph struct {
+	// Rect is its field in the image, empty for glyphs with nothing to draw, like spaces.
+	Rect image.Rectangle
+	// Offset is from where the glyph is on the baseline to the top left of Rect, in ems with y down.
+	Offset vec2
+	// Advance is how far the glyph moves the next along, in ems.
+	Advance float
+}
+
+// Latin1 are the printable characters of ASCII and Latin-1, what Default has.
+var Latin1 = func() string {
+	var b strings.Builder
+	for r := rune(0x20); r <= 0xff; r++ {
+		if r < 0x7f || r >= 0xa0 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}()
+
+// Default is Go Regular's Latin1 at 48 pixels an em and 8 pixels of spread, enough for outlines and glows of about a
+// sixth of an em. It's generated the first time it's asked for.
+var Default = sync.OnceValue(func() *Atlas {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		panic(err)
+	}
+	a, err := Generate(f, Latin1, 48, 8)
+	if err != nil {
+		panic(err)
+	}
+	return a
+})
+
+// Generate rasterizes the glyphs of `runes` in `f` at `size` pixels an em and makes their fields, reaching `spread`
+// pixels either side of their edges. Larger sizes keep sharper corners, larger spreads allow wider outlines and
+// glows, both make larger atlases. Characters the font doesn't have are left out.
+func Generate(f *sfnt.Font, runes string, size, spread float) (*Atlas, error) {
+	var b sfnt.Buffer
+	ppem := fixed.Int26_6(math.Round(float64(size) * 64))
+	em := func(x fixed.Int26_6) float { return float(x) / float(ppem) }
+	metrics, err := f.Metrics(&b, ppem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	a := &Atlas{
+		Size:       size,
+		Spread:     spread,
+		Ascent:     em(metrics.Ascent),
+		Descent:    em(metrics.Descent),
+		LineHeight: em(metrics.Height),
+		Glyphs:     make(map[rune]Glyph),
+		kerning:    make(map[[2]rune]float),
+	}
+
+	// the fields, each `pad` pixels larger than its glyph all round so they fade out before the edge
+	pad := int(math.Ceil(float64(spread))) + 1
+	indices := make(map[rune]sfnt.GlyphIndex)
+	fields := make(map[rune]*image.Alpha)
+	for _, r := range runes {
+		if _, ok := indices[r]; ok {
+			continue
+		}
+		index, err := f.GlyphIndex(&b, r)
+		if err != nil || index == 0 {
+			continue
+		}
+		indices[r] = index
+		advance, err := f.GlyphAdvance(&b, index, ppem, font.HintingNone)
+		if err != nil {
+			return nil, fmt.Errorf("glyph of %q: %w", r, err)
+		}
+		segments, err := f.LoadGlyph(&b, index, ppem, nil)
+		if err != nil {
+			return nil, fmt.Errorf("glyph of %q: %w", r, err)
+		}
+		g := Glyph{Advance: em(advance)}
+		if len(segments) > 0 {
+			mask, top_left := rasterize(segments, pad)
+			fields[r] = Field(mask, spread)
+			g.Offset = vec2{float(top_left.X) / size, float(top_left.Y) / size}
+			g.Rect = mask.Bounds()
+		}
+		a.Glyphs[r] = g
+	}
+
+	// packed in rows, tallest first so the rows waste little
+	order := make([]rune, 0, len(fields))
+	for r := range fields {
+		order = append(order, r)
+	}
+	slices.SortFunc(order, func(p, q rune) int {
+		if d := fields[q].Bounds().Dy() - fields[p].Bounds().Dy(); d != 0 {
+			return d
+		}
+		return int(p - q)
+	})
+	x, y, row := 0, 0, 0
+	for _, r := range order {
+		size := fields[r].Bounds().Size()
+		if x+size.X > atlas_width {
+			x, y, row = 0, y+row, 0
+		}
+		g := a.Glyphs[r]
+		g.Rect = image.Rectangle{image.Pt(x, y), image.Pt(x, y).Add(size)}
+		a.Glyphs[r] = g
+		x += size.X
+		row = max(row, size.Y)
+	}
+	a.Image = image.NewAlpha(image.Rect(0, 0, atlas_width, max(y+row, 1)))
+	for _, r := range order {
+		field := fields[r]
+		draw.Draw(a.Image, a.Glyphs[r].Rect, field, field.Bounds().Min, draw.Src)
+	}
+
+	for p, i := range indices {
+		for q, j := range indices {
+			if k, err := f.Kern(&b, i, j, ppem, font.HintingNone); err == nil && k != 0 {
+				a.kerning[[2]rune{p, q}] = em(k)
+			}
+		}
+	}
+	return a, nil
+}
+
+// rasterize draws the outline of a glyph, in pixels from where it is on the baseline, into a mask `pad` pixels
+// larger all round, and returns it and where its top left is from the baseline.
+func rasterize(segments sfnt.Segments, pad int) (*image.Alpha, image.Point) {
+	bounds := segments.Bounds()
+	lo := image.Pt(bounds.Min.X.Floor()-pad, bounds.Min.Y.Floor()-pad)
+	hi := image.Pt(bounds.Max.X.Ceil()+pad, bounds.Max.Y.Ceil()+pad)
+	point := func(p fixed.Point26_6) (float, float) {
+		return float(p.X)/64 - float(lo.X), float(p.Y)/64 - float(lo.Y)
+	}
+	r := vector.NewRasterizer(hi.X-lo.X, hi.Y-lo.Y)
+	started := false
+	for _, s := range segments {
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			// the rasterizer doesn't close the contour before on its own
+			if started {
+				r.ClosePath()
+			}
+			started = true
+			r.MoveTo(point(s.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			r.LineTo(point(s.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			bx, by := point(s.Args[0])
+			cx, cy := point(s.Args[1])
+			r.QuadTo(bx, by, cx, cy)
+		case sfnt.SegmentOpCubeTo:
+			bx, by := point(s.Args[0])
+			cx, cy := point(s.Args[1])
+			dx, dy := point(s.Args[2])
+			r.CubeTo(bx, by, cx, cy, dx, dy)
+		}
+	}
+	r.ClosePath()
+	mask := image.NewAlpha(image.Rect(0, 0, hi.X-lo.X, hi.Y-lo.Y))
+	r.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	return mask, lo
+}
+
+// glyph is the glyph of `r`, or of a question mark for characters the atlas doesn't have.
+func (a *Atlas) glyph(r rune) (Glyph, bool) {
+	if g, ok := a.Glyphs[r]; ok {
+		return g, true
+	}
+	g, ok := a.Glyphs['?']
+	return g, ok
+}
+
+// Kern is how much closer `q` goes after `p` than their advance, in ems, usually negative.
+func (a *Atlas) Kern(p, q rune) float {
+	return a.kerning[[2]rune{p, q}]
+}
+
+// Quad is a glyph laid out, a rectangle on screen and the field drawn on it.
+type Quad struct {
+	Min, Max vec2
+	Src      image.Rectangle
+}
+
+// Layout lays `s` out at `size` pixels an em, the top left of its first line at 0, 0, appending a quad of every
+// glyph to `dst`. Lines are split by newlines.
+func (a *Atlas) Layout(s string, size float, dst []Quad) []Quad {
+	scale := size / a.Size
+	y := a.Ascent * size
+	for _, line := range strings.Split(s, "\n") {
+		var x float
+		previous := rune(-1)
+		for _, r := range line {
+			g, ok := a.glyph(r)
+			if !ok {
+				continue
+			}
+			x += a.Kern(previous, r) * size
+			previous = r
+			if !g.Rect.Empty() {
+				at := vec2{x, y}.Add(g.Offset.Mul(size))
+				dst = append(dst, Quad{
+					Min: at,
+					Max: at.Add(vec2{float(g.Rect.Dx()), float(g.Rect.Dy())}.Mul(scale)),
+					Src: g.Rect,
+				})
+			}
+			x += g.Advance * size
+		}
+		y += a.LineHeight * size
+	}
+	return dst
+}
+
+// Measure is how wide the widest line of `s` is and how tall its lines are, at `size` pixels an em.
+func (a *Atlas) Measure(s string, size float) vec2 {
+	var width float
+	lines := strings.Split(s, "\n")
+	for _, line := range lines {
+		var x float
+		previous := rune(-1)
+		for _, r := range line {
+			if g, ok := a.glyph(r); ok {
+				x += a.Kern(previous, r)*size + g.Advance*size
+				previous = r
+			}
+		}
+		width = max(width, x)
+	}
+	return vec2{width, float(len(lines)) * a.LineHeight * size}
+}
+
+// Mesh makes a label of `s` for a 3D scene, a quad for every glyph, facing +z and reading along +x with y up, an em
+// being `size` tall. Its top left is at the origin, or its middle when `center`. The texture coordinates are into
+// the atlas' image, the mesh is drawn with the fields.
+func (a *Atlas) Mesh(s string, size float, center bool) *mesh.Mesh {
+	quads := a.Layout(s, size, nil)
+	var offset vec2
+	if center {
+		offset = a.Measure(s, size).Mul(0.5)
+	}
+	w, h := float(a.Image.Bounds().Dx()), float(a.Image.Bounds().Dy())
+	m := &mesh.Mesh{}
+	for _, q := range quads {
+		if len(m.Points)+4 > math.MaxUint16 {
+			break
+		}
+		base := uint16(len(m.Points))
+		corner := func(x, y float) vec3 {
+			return vec3{x - offset[0], offset[1] - y, 0}
+		}
+		m.Points = append(m.Points,
+			corner(q.Min[0], q.Min[1]), corner(q.Max[0], q.Min[1]),
+			corner(q.Min[0], q.Max[1]), corner(q.Max[0], q.Max[1]),
+		)
+		r := q.Src
+		m.Texcoords = append(m.Texcoords,
+			vec2{float(r.Min.X) / w, float(r.Min.Y) / h}, vec2{float(r.Max.X) / w, float(r.Min.Y) / h},
+			vec2{float(r.Min.X) / w, float(r.Max.Y) / h}, vec2{float(r.Max.X) / w, float(r.Max.Y) / h},
+		)
+		for _, t := range [2][3]uint16{{0, 2, 1}, {1, 2, 3}} {
+			p1, p2, p3 := base+t[0], base+t[1], base+t[2]
+			m.Triangles = append(m.Triangles, mesh.Triangle{P1: p1, P2: p2, P3: p3, T1: p1, T2: p2, T3: p3})
+		}
+	}
+	m.ComputeBounds()
+	return m
+}
+
+// atlas_json is an atlas as Save writes it, the image being a PNG next to it.
+type atlas_json struct {
+	Image      string         `json:"image"`
+	Size       float          `json:"size"`
+	Spread     float          `json:"spread"`
+	Ascent     float          `json:"ascent"`
+	Descent    float          `json:"descent"`
+	LineHeight float          `json:"line_height"`
+	Glyphs     []glyph_json   `json:"glyphs"`
+	Kerning    []kerning_json `json:"kerning,omitempty"`
+}
+
+type glyph_json struct {
+	Rune    rune  `json:"rune"`
+	X       int   `json:"x"`
+	Y       int   `json:"y"`
+	Width   int   `json:"width"`
+	Height  int   `json:"height"`
+	OffsetX float `json:"offset_x"`
+	OffsetY float `json:"offset_y"`
+	Advance float `json:"advance"`
+}
+
+type kerning_json struct {
+	First  rune  `json:"first"`
+	Second rune  `json:"second"`
+	Kern   float `json:"kern"`
+}
+
+// Save writes the atlas to the JSON file `name` and its image to a PNG of the same name next to it, for Load.
+func (a *Atlas) Save(name string) error {
+	image_name := strings.TrimSuffix(name, filepath.Ext(name)) + ".png"
+	j := atlas_json{
+		Image:      filepath.Base(image_name),
+		Size:       a.Size,
+		Spread:     a.Spread,
+		Ascent:     a.Ascent,
+		Descent:    a.Descent,
+		LineHeight: a.LineHeight,
+	}
+	for r, g := range a.Glyphs {
+		j.Glyphs = append(j.Glyphs, glyph_json{
+			Rune: r, X: g.Rect.Min.X, Y: g.Rect.Min.Y, Width: g.Rect.Dx(), Height: g.Rect.Dy(),
+			OffsetX: g.Offset[0], OffsetY: g.Offset[1], Advance: g.Advance,
+		})
+	}
+	slices.SortFunc(j.Glyphs, func(p, q glyph_json) int { return int(p.Rune - q.Rune) })
+	for pair, k := range a.kerning {
+		j.Kerning = append(j.Kerning, kerning_json{pair[0], pair[1], k})
+	}
+	slices.SortFunc(j.Kerning, func(p, q kerning_json) int {
+		if p.First != q.First {
+			return int(p.First - q.First)
+		}
+		return int(p.Second - q.Second)
+	})
+
+	src, err := json.MarshalIndent(j, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(name, src, 0o644); err != nil {
+		return err
+	}
+	// as gray, which image viewers show as the fields rather than as white with the fields for opacity
+	gray := &image.Gray{Pix: a.Image.Pix, Stride: a.Image.Stride, Rect: a.Image.Rect}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		return err
+	}
+	return os.WriteFile(image_name, buf.Bytes(), 0o644)
+}
+
+// Load loads the atlas Save wrote to `name` in `fsys`, and its image next to it.
+func Load(fsys fs.FS, name string) (*Atlas, error) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var j atlas_json
+	if err := json.Unmarshal(src, &j); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if j.Size <= 0 || j.Spread <= 0 {
+		return nil, fmt.Errorf("%s: a size of %v and a spread of %v", name, j.Size, j.Spread)
+	}
+
+	image_name := path.Join(path.Dir(name), j.Image)
+	f, err := fsys.Open(image_name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", image_name, err)
+	}
+	field := image.NewAlpha(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	for y := range field.Rect.Dy() {
+		for x := range field.Rect.Dx() {
+			// the field is the brightness, whichever kind of image it was saved as
+			r, _, _, _ := img.At(img.Bounds().Min.X+x, img.Bounds().Min.Y+y).RGBA()
+			field.Pix[y*field.Stride+x] = uint8(r >> 8)
+		}
+	}
+
+	a := &Atlas{
+		Image:      field,
+		Size:       j.Size,
+		Spread:     j.Spread,
+		Ascent:     j.Ascent,
+		Descent:    j.Descent,
+		LineHeight: j.LineHeight,
+		Glyphs:     make(map[rune]Glyph, len(j.Glyphs)),
+		kerning:    make(map[[2]rune]float, len(j.Kerning)),
+	}
+	for _, g := range j.Glyphs {
+		rect := image.Rect(g.X, g.Y, g.X+g.Width, g.Y+g.Height)
+		if !rect.Empty() && !rect.In(field.Rect) {
+			return nil, fmt.Errorf("%s: glyph %q at %v is outside the image", name, g.Rune, rect)
+		}
+		a.Glyphs[g.Rune] = Glyph{Rect: rect, Offset: vec2{g.OffsetX, g.OffsetY}, Advance: g.Advance}
+	}
+	for _, k := range j.Kerning {
+		a.kerning[[2]rune{k.First, k.Second}] = k.Kern
+	}
+	return a, nil
+}
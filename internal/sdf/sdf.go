@@ -0,0 +1,206 @@
+// Package sdf makes signed distance fields of glyphs, for text which stays sharp however large it's drawn, turned or
+// seen in 3D, with outlines and glows for free. A field keeps how far each pixel is from the edge of the shape
+// instead of how much of it the shape covers, and a shader finds the edge again between the pixels at any scale.
+//
+// An Atlas is the fields of the glyphs of a font packed into one image, made when a program starts by Generate, or
+// ahead of time by cmd/sdfgen and loaded by Load. Layout lays text out in quads of it and Mesh makes labels of it
+// for the pipeline. The functions of the shaders drawing it are in sdf.kage, which shaders include as "sdf.kage",
+// and Shade is their twin in Go.
+package sdf
+
+import (
+	_ "embed"
+	"image"
+	"math"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
+)
+
+// Kage is the source of the Kage half of the package.
+//
+//go:embed sdf.kage
+var Kage string
+
+// Edge is the value of a field on the edge of its shape, larger inside.
+const Edge = 0.5
+
+// Field is the signed distance field of `mask`, the coverage of a shape. The edge is at 0.5, and the field goes to
+// 1 `spread` pixels inside the shape and to 0 `spread` pixels outside, which is as far as outlines and glows drawn
+// from it can reach.
+func Field(mask *image.Alpha, spread float) *image.Alpha {
+	bounds := mask.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	to_inside := make([]float64, w*h)
+	to_outside := make([]float64, w*h)
+	for y := range h {
+		for x := range w {
+			i := y*w + x
+			if mask.AlphaAt(bounds.Min.X+x, bounds.Min.Y+y).A >= 128 {
+				to_inside[i], to_outside[i] = 0, math.Inf(1)
+			} else {
+				to_inside[i], to_outside[i] = math.Inf(1), 0
+			}
+		}
+	}
+	var t transform
+	t.squared(to_inside, w, h)
+	t.squared(to_outside, w, h)
+
+	field := image.NewAlpha(bounds)
+	for y := range h {
+		for x := range w {
+			i := y*w + x
+			// the distance from the edge in pixels, negative inside: from the middle of a pixel half way to the
+			// nearest one on the other side, or by its coverage for pixels the edge crosses
+			var d float64
+			a := float64(mask.AlphaAt(bounds.Min.X+x, bounds.Min.Y+y).A) / 255
+			switch {
+			case a > 0 && a < 1:
+				d = 0.5 - a
+			case to_inside[i] > 0:
+				d = math.Sqrt(to_inside[i]) - 0.5
+			default:
+				d = 0.5 - math.Sqrt(to_outside[i])
+			}
+			v := Edge - d/(2*float64(spread))
+			field.Pix[y*field.Stride+x] = uint8(math.Round(255 * min(max(v, 0), 1)))
+		}
+	}
+	return field
+}
+
+// transform is the squared Euclidean distance transform of Felzenszwalb and Huttenlocher, and its buffers: a pass
+// along every column, then one along every row, each finding the lower envelope of the parabolas of the samples.
+type transform struct {
+	f, d []float64
+	v    []int
+	z    []float64
+}
+
+// squared replaces every value of `grid`, 0 at the pixels distances are taken to and infinite elsewhere, by the
+// squared distance to the nearest of them.
+func (t *transform) squared(grid []float64, w, h int) {
+	n := max(w, h)
+	if len(t.f) < n {
+		t.f, t.d, t.v, t.z = make([]float64, n), make([]float64, n), make([]int, n), make([]float64, n+1)
+	}
+	for x := range w {
+		for y := range h {
+			t.f[y] = grid[y*w+x]
+		}
+		t.line(h)
+		for y := range h {
+			grid[y*w+x] = t.d[y]
+		}
+	}
+	for y := range h {
+		copy(t.f, grid[y*w:(y+1)*w])
+		t.line(w)
+		copy(grid[y*w:(y+1)*w], t.d[:w])
+	}
+}
+
+// line transforms the first `n` of t.f into t.d.
+func (t *transform) line(n int) {
+	f, v, z := t.f, t.v, t.z
+	k := 0
+	v[0] = 0
+	z[0], z[1] = math.Inf(-1), math.Inf(1)
+	for q := 1; q < n; q++ {
+		if math.IsInf(f[q], 1) {
+			continue
+		}
+		if math.IsInf(f[v[k]], 1) {
+			// nothing before reaches anywhere, the first parabola is this one
+			v[k] = q
+			continue
+		}
+		var s float64
+		for {
+			r := v[k]
+			s = ((f[q] + float64(q*q)) - (f[r] + float64(r*r))) / float64(2*q-2*r)
+			if s > z[k] || k == 0 {
+				break
+			}
+			k--
+		}
+		if s <= z[k] {
+			// it's lower than the only parabola left everywhere
+			v[k] = q
+			continue
+		}
+		k++
+		v[k] = q
+		z[k], z[k+1] = s, math.Inf(1)
+	}
+	k = 0
+	for q := range n {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		r := v[k]
+		t.d[q] = float64((q-r)*(q-r)) + f[r]
+	}
+}
+
+// Style is how text is drawn from a field. Widths are in units of the field, which runs from 0 to 1 over twice its
+// spread, see Field.
+type Style struct {
+	// Color is the color of the glyphs, premultiplied like the others.
+	Color vec4
+	// Outline is how far out from the edge an outline of OutlineColor reaches.
+	Outline      float
+	OutlineColor vec4
+	// Glow is how far out beyond the outline a glow of GlowColor fades.
+	Glow      float
+	GlowColor vec4
+	// Softness blurs the edges.
+	Softness float
+}
+
+// Shade is the color of a pixel `d` into the field, in `style`. `smooth` is how much of the field the edges blur
+// over, half of how much it changes from a pixel to the next keeps them sharp. sdf_shade in Kage.
+func Shade(d, smooth float, style *Style) vec4 {
+	smooth += style.Softness
+	color := style.Color.Mul(smoothstep(Edge-smooth, Edge+smooth, d))
+	if style.Outline > 0 {
+		o := smoothstep(Edge-style.Outline-smooth, Edge-style.Outline+smooth, d)
+		color = color.Add(style.OutlineColor.Mul(o * (1 - color[3])))
+	}
+	if style.Glow > 0 {
+		g := smoothstep(Edge-style.Outline-style.Glow, Edge-style.Outline, d)
+		color = color.Add(style.GlowColor.Mul(g * g * (1 - color[3])))
+	}
+	return color
+}
+
+// Uniforms are the uniforms of the style for the shaders drawing text, which name them as the fields are named.
+func (s *Style) Uniforms() map[string]any {
+	return map[string]any{
+		"Color":        slices.Clone(s.Color[:]),
+		"Outline":      s.Outline,
+		"OutlineColor": slices.Clone(s.OutlineColor[:]),
+		"Glow":         s.Glow,
+		"GlowColor":    slices.Clone(s.GlowColor[:]),
+		"Softness":     s.Softness,
+	}
+}
+
+func smoothstep(lo, hi, x float) float {
+	if lo == hi {
+		if x < lo {
+			return 0
+		}
+		return 1
+	}
+	t := min(max((x-lo)/(hi-lo), 0), 1)
+	return t * t * (3 - 2*t)
+}
@@ -0,0 +1,178 @@
+package sdf
+
+import (
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// disc is a mask of a disc `radius` across with its middle at `c`, antialiased by sampling every pixel 16 times.
+func disc(size int, c vec2, radius float) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, size, size))
+	for y := range size {
+		for x := range size {
+			n := 0
+			for s := range 16 {
+				p := vec2{float(x) + (float(s%4)+0.5)/4, float(y) + (float(s/4)+0.5)/4}
+				if p.Sub(c).Len() < radius {
+					n++
+				}
+			}
+			mask.Pix[y*mask.Stride+x] = uint8(min(n*16, 255))
+		}
+	}
+	return mask
+}
+
+func TestField(t *testing.T) {
+	const spread = 6
+	c, radius := vec2{32, 32}, float(14)
+	field := Field(disc(64, c, radius), spread)
+	for y := range 64 {
+		for x := range 64 {
+			// how far the middle of the pixel is outside the disc, and what the field should be there
+			d := vec2{float(x) + 0.5, float(y) + 0.5}.Sub(c).Len() - radius
+			want := min(max(Edge-d/(2*spread), 0), 1)
+			got := float(field.AlphaAt(x, y).A) / 255
+			if math.Abs(float64(got-want)) > 0.06 {
+				t.Fatalf("the field %v from the edge is %v, not %v", d, got, want)
+			}
+		}
+	}
+}
+
+func TestShade(t *testing.T) {
+	style := &Style{
+		Color:        vec4{1, 1, 1, 1},
+		Outline:      0.1,
+		OutlineColor: vec4{0, 0, 1, 1},
+		Glow:         0.2,
+		GlowColor:    vec4{0.5, 0, 0, 0.5},
+	}
+	const smooth = 0.01
+	for _, tc := range []struct {
+		d    float
+		want vec4
+	}{
+		{0.9, vec4{1, 1, 1, 1}},
+		{Edge, vec4{0.5, 0.5, 1, 1}},
+		{0.45, vec4{0, 0, 1, 1}},
+		{0.1, vec4{}},
+	} {
+		if got := Shade(tc.d, smooth, style); !got.ApproxEqualThreshold(tc.want, 1e-3) {
+			t.Errorf("%v into the field is %v, not %v", tc.d, got, tc.want)
+		}
+	}
+	// the glow fades out from the outline to nothing
+	last := float(1)
+	for d := float(0.39); d > 0.2; d -= 0.02 {
+		a := Shade(d, smooth, style)[3]
+		if a <= 0 || a >= last {
+			t.Errorf("the glow %v into the field is %v, after %v", d, a, last)
+		}
+		last = a
+	}
+	if a := Shade(0.19, smooth, style)[3]; a != 0 {
+		t.Errorf("the glow reaches past its width, %v", a)
+	}
+}
+
+func TestAtlas(t *testing.T) {
+	a := Default()
+	for _, r := range Latin1 {
+		g, ok := a.Glyphs[r]
+		if !ok {
+			t.Fatalf("%q is missing", r)
+		}
+		if (r == ' ' || r == 0xa0) != g.Rect.Empty() || g.Advance <= 0 {
+			t.Errorf("%q is at %v and %v ems wide", r, g.Rect, g.Advance)
+		}
+	}
+
+	// the fields of the glyphs don't overlap, and they're inside with their edges in them
+	rects := make([]image.Rectangle, 0, len(a.Glyphs))
+	for r, g := range a.Glyphs {
+		if g.Rect.Empty() {
+			continue
+		}
+		for _, other := range rects {
+			if g.Rect.Overlaps(other) {
+				t.Fatalf("%q at %v overlaps %v", r, g.Rect, other)
+			}
+		}
+		rects = append(rects, g.Rect)
+		var inside, outside bool
+		for y := g.Rect.Min.Y; y < g.Rect.Max.Y; y++ {
+			for x := g.Rect.Min.X; x < g.Rect.Max.X; x++ {
+				v := a.Image.AlphaAt(x, y).A
+				inside, outside = inside || v > 128, outside || v < 128
+			}
+		}
+		if !inside || !outside || a.Image.AlphaAt(g.Rect.Min.X, g.Rect.Min.Y).A != 0 {
+			t.Errorf("the field of %q doesn't cross its edge within its padding", r)
+		}
+	}
+
+	// a line of text is as wide as its advances and kerning, at any size
+	for _, size := range []float{12, 48, 200} {
+		width := a.Measure("AVo", size)[0]
+		want := (a.Glyphs['A'].Advance + a.Kern('A', 'V') + a.Glyphs['V'].Advance + a.Kern('V', 'o') + a.Glyphs['o'].Advance) * size
+		if math.Abs(float64(width-want)) > 1e-3 {
+			t.Errorf("AVo is %v wide at %v, not %v", width, size, want)
+		}
+	}
+
+	// the quads of glyphs sit on the lines, the second line a line height further down
+	quads := a.Layout("x\nx", 100, nil)
+	if len(quads) != 2 {
+		t.Fatalf("%d quads", len(quads))
+	}
+	if dy := quads[1].Min[1] - quads[0].Min[1]; math.Abs(float64(dy-100*a.LineHeight)) > 1e-3 {
+		t.Errorf("the lines are %v apart", dy)
+	}
+	x := a.Glyphs['x']
+	if size := quads[0].Max.Sub(quads[0].Min); math.Abs(float64(size[0]-float(x.Rect.Dx())*100/a.Size)) > 1e-3 {
+		t.Errorf("an x is %v large at 100 pixels an em", size)
+	}
+	// the bottom of the x is on the baseline, the padding of its field below it
+	pad := float(math.Ceil(float64(a.Spread)) + 1)
+	if bottom := quads[0].Max[1] - pad*100/a.Size; math.Abs(float64(bottom-100*a.Ascent)) > float64(100/a.Size) {
+		t.Errorf("an x ends at %v, the baseline is at %v", bottom, 100*a.Ascent)
+	}
+
+	m := a.Mesh("Hi there", 1, true)
+	if len(m.Triangles) != 2*7 || len(m.Points) != 4*7 {
+		t.Errorf("a label has %d triangles and %d points", len(m.Triangles), len(m.Points))
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	a := Default()
+	dir := t.TempDir()
+	if err := a.Save(filepath.Join(dir, "font.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "font.png")); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(os.DirFS(dir), "font.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Size != a.Size || loaded.Spread != a.Spread || loaded.LineHeight != a.LineHeight || len(loaded.Glyphs) != len(a.Glyphs) {
+		t.Errorf("%v %v %v %d glyphs were loaded", loaded.Size, loaded.Spread, loaded.LineHeight, len(loaded.Glyphs))
+	}
+	for r, g := range a.Glyphs {
+		if loaded.Glyphs[r] != g {
+			t.Errorf("%q was loaded as %+v, not %+v", r, loaded.Glyphs[r], g)
+		}
+	}
+	if loaded.Kern('A', 'V') != a.Kern('A', 'V') {
+		t.Errorf("A and V are kerned %v, not %v", loaded.Kern('A', 'V'), a.Kern('A', 'V'))
+	}
+	if string(loaded.Image.Pix) != string(a.Image.Pix) {
+		t.Error("the image isn't as it was saved")
+	}
+}
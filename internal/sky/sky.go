@@ -0,0 +1,194 @@
+// Package sky draws a procedural sky behind a scene: a gradient towards the horizon, a sun disc, and stars at night,
+// all driven by the time of day. It takes the place of a static skybox, so it fills the target before anything else
+// is drawn, and lighting can follow the sun it draws through SunDirection and SunColor.
+package sky
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/kage"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+	mat4  = mgl32.Mat4
+)
+
+var (
+	// not persisted, it changes every tick while the day advances
+	time_of_day = cvar.Float("sky_time", 10, 0, "time of day in hours, 6 is sunrise and 18 sunset").Range(0, 24)
+	day_speed   = cvar.Float("sky_speed", 0, 0, "hours the sky advances per second, 0 stops time").Range(-24, 24)
+)
+
+// sky_shader turns every pixel back into a view ray with the inverse view-projection matrix, so it lines up with
+// whatever projection the scene uses.
+var sky_shader = `
+//kage:unit pixels
+package main
+
+var InvViewProjection mat4
+// Viewport is x, y, width and height in pixels of the target.
+var Viewport vec4
+var Sun vec3
+var Zenith vec3
+var Horizon vec3
+var SunColor vec3
+// Night is 0 during the day and 1 when the sun is well below the horizon.
+var Night float
+
+func unproject(ndc vec2, z float) vec3 {
+	p := InvViewProjection * vec4(ndc, z, 1)
+	return p.xyz / p.w
+}
+
+#include "noise.kage"
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	// the pipeline maps NDC to pixels without flipping Y
+	ndc := (dst.xy-Viewport.xy)/Viewport.zw*2 - 1
+	dir := normalize(unproject(ndc, 1) - unproject(ndc, -1))
+
+	up := max(dir.y, 0)
+	rgb := mix(Horizon, Zenith, pow(up, 0.5))
+
+	// a darker ground below the horizon
+	if dir.y < 0 {
+		rgb = mix(Horizon, Horizon*0.3, min(-dir.y*4, 1))
+	}
+
+	// the sun disc with a glow around it
+	d := dot(dir, Sun)
+	rgb += SunColor * (smoothstep(0.9995, 0.9998, d) + pow(max(d, 0), 64)*0.3)
+
+	// stars are fixed cells of the sphere of directions, a few of which are lit
+	if Night > 0 && dir.y > 0 {
+		cell := floor(dir * 300)
+		star := smoothstep(0.997, 1, hash3(cell))
+		twinkle := 0.5 + 0.5*hash3(cell+vec3(1))
+		rgb += vec3(star * twinkle * Night * min(dir.y*8, 1))
+	}
+
+	return vec4(rgb, 1)
+}
+`
+
+type Sky struct {
+	shader *ebiten.Shader
+}
+
+func New() (*Sky, error) {
+	src, err := kage.PreprocessSource("sky.kage", sky_shader, nil)
+	if err != nil {
+		return nil, err
+	}
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Sky{shader: shader}, nil
+}
+
+// Update advances the time of day by one tick.
+func (s *Sky) Update() {
+	speed := day_speed.Float()
+	if speed == 0 {
+		return
+	}
+	hours := math.Mod(time_of_day.Float()+speed/float64(ebiten.TPS()), 24)
+	if hours < 0 {
+		hours += 24
+	}
+	time_of_day.SetFloat(hours)
+}
+
+// Time returns the time of day in hours.
+func (s *Sky) Time() float {
+	return time_of_day.Float32()
+}
+
+// SunDirection returns the unit vector pointing towards the sun. It rises in +X, peaks at noon slightly tilted
+// towards -Z so it casts shadows at noon too, and sets in -X.
+func (s *Sky) SunDirection() vec3 {
+	angle := (float64(time_of_day.Float()) - 6) / 24 * 2 * math.Pi
+	return vec3{
+		float(math.Cos(angle)),
+		float(math.Sin(angle)),
+		-0.35,
+	}.Normalize()
+}
+
+// daylight is 1 while the sun is well above the horizon and 0 once it's well below.
+func (s *Sky) daylight() float {
+	return smoothstep(-0.15, 0.15, s.SunDirection().Y())
+}
+
+// SunColor is the color of sunlight, white at noon, orange towards the horizon and black at night.
+func (s *Sky) SunColor() vec3 {
+	elevation := s.SunDirection().Y()
+	warm := vec3{1, 0.55, 0.25}
+	white := vec3{1, 0.97, 0.9}
+	return lerp(warm, white, smoothstep(0, 0.4, elevation)).Mul(s.daylight())
+}
+
+// Ambient is the color of light from the rest of the sky, for lighting which has nothing better.
+func (s *Sky) Ambient() vec3 {
+	night := vec3{0.02, 0.03, 0.06}
+	day := vec3{0.35, 0.45, 0.6}
+	return lerp(night, day, s.daylight())
+}
+
+// Draw fills `target` with the sky seen through `view_projection`, the matrix the scene is drawn with.
+func (s *Sky) Draw(target *ebiten.Image, view_projection mat4) {
+	bounds := target.Bounds()
+	daylight := s.daylight()
+	sunset := 1 - smoothstep(0, 0.35, abs(s.SunDirection().Y()))
+
+	zenith := lerp(vec3{0.01, 0.01, 0.04}, vec3{0.2, 0.4, 0.8}, daylight)
+	horizon := lerp(vec3{0.04, 0.05, 0.1}, vec3{0.65, 0.75, 0.9}, daylight)
+	horizon = lerp(horizon, vec3{0.95, 0.5, 0.25}, sunset*0.8)
+
+	inv := view_projection.Inv()
+	sun := s.SunDirection()
+	sun_color := s.SunColor()
+
+	x0, y0 := float(bounds.Min.X), float(bounds.Min.Y)
+	x1, y1 := float(bounds.Max.X), float(bounds.Max.Y)
+	vertices := []ebiten.Vertex{
+		{DstX: x0, DstY: y0, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		{DstX: x1, DstY: y0, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		{DstX: x0, DstY: y1, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+		{DstX: x1, DstY: y1, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1},
+	}
+	target.DrawTrianglesShader(vertices, []uint16{0, 1, 2, 1, 3, 2}, s.shader, &ebiten.DrawTrianglesShaderOptions{
+		Uniforms: map[string]any{
+			"InvViewProjection": inv[:],
+			"Viewport":          []float32{x0, y0, x1 - x0, y1 - y0},
+			"Sun":               sun[:],
+			"Zenith":            zenith[:],
+			"Horizon":           horizon[:],
+			"SunColor":          sun_color[:],
+			"Night":             1 - daylight,
+		},
+	})
+}
+
+func lerp(a, b vec3, t float) vec3 {
+	return a.Add(b.Sub(a).Mul(t))
+}
+
+func smoothstep(edge0, edge1, x float) float {
+	t := min(max((x-edge0)/(edge1-edge0), 0), 1)
+	return t * t * (3 - 2*t)
+}
+
+func abs(x float) float {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
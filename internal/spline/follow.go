@@ -0,0 +1,83 @@
+package spline
+
+// Mode is what a Follower does at the end of its path.
+type Mode int
+
+const (
+	// Once stops at the end.
+	Once Mode = iota
+	// Loop starts over, closed paths simply go round.
+	Loop
+	// PingPong turns around at either end.
+	PingPong
+)
+
+// Follower moves along a path at a steady speed. Anything with a position can follow one: read Position and
+// Forward after every Update.
+type Follower struct {
+	Path *Path
+	// Speed is in units per second.
+	Speed float
+	Mode  Mode
+	// Distance is how far along the path the follower is.
+	Distance float
+
+	backwards bool
+}
+
+// NewFollower returns a follower at the start of `path`.
+func NewFollower(path *Path, speed float, mode Mode) *Follower {
+	return &Follower{Path: path, Speed: speed, Mode: mode}
+}
+
+// Update moves the follower `dt` seconds further.
+func (f *Follower) Update(dt float) {
+	step := f.Speed * dt
+	if f.backwards {
+		step = -step
+	}
+	f.Distance += step
+
+	length := f.Path.Length()
+	if f.Path.Curve.Closed() && f.Mode != PingPong {
+		// Path wraps it, but keep the number small
+		f.Distance = f.Path.wrap(f.Distance)
+		return
+	}
+	switch f.Mode {
+	case Once:
+		f.Distance = min(max(f.Distance, 0), length)
+	case Loop:
+		if f.Distance > length {
+			f.Distance -= length
+		}
+		f.Distance = min(max(f.Distance, 0), length)
+	case PingPong:
+		if f.Distance > length {
+			f.Distance, f.backwards = 2*length-f.Distance, true
+		}
+		if f.Distance < 0 {
+			f.Distance, f.backwards = -f.Distance, false
+		}
+		f.Distance = min(max(f.Distance, 0), length)
+	}
+}
+
+// Done reports whether a follower moving Once reached the end.
+func (f *Follower) Done() bool {
+	return f.Mode == Once && !f.Path.Curve.Closed() && f.Distance >= f.Path.Length()
+}
+
+// Position is where the follower is.
+func (f *Follower) Position() vec3 {
+	return f.Path.At(f.Distance)
+}
+
+// Forward is the direction the follower is moving in.
+func (f *Follower) Forward() vec3 {
+	d := f.Path.Direction(f.Distance)
+	if f.backwards {
+		return d.Mul(-1)
+	}
+	return d
+}
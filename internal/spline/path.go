@@ -0,0 +1,92 @@
+package spline
+
+import (
+	"math"
+	"sort"
+)
+
+// samples_per_segment is how finely a Path measures its curve. Lengths are off by a fraction of a percent on
+// curves bent as tight as a Catmull-Rom corner.
+const samples_per_segment = 32
+
+// Path is a curve measured by arc length, so points on it can be found by their distance from the start.
+type Path struct {
+	Curve Curve
+	// distances[i] is the length of the curve up to t = i / samples_per_segment
+	distances []float
+}
+
+// NewPath measures `curve`.
+func NewPath(curve Curve) *Path {
+	p := &Path{Curve: curve}
+	p.Measure()
+	return p
+}
+
+// Measure measures the curve again, after its points changed.
+func (p *Path) Measure() {
+	n := p.Curve.Segments() * samples_per_segment
+	p.distances = append(p.distances[:0], 0)
+	previous := p.Curve.Point(0)
+	var total float
+	for i := 1; i <= n; i++ {
+		point := p.Curve.Point(float(i) / samples_per_segment)
+		total += point.Sub(previous).Len()
+		p.distances = append(p.distances, total)
+		previous = point
+	}
+}
+
+// Length is the length of the curve.
+func (p *Path) Length() float {
+	return p.distances[len(p.distances)-1]
+}
+
+// wrap brings `distance` onto the path, around closed paths and clamped to the ends of open ones.
+func (p *Path) wrap(distance float) float {
+	length := p.Length()
+	if length == 0 {
+		return 0
+	}
+	if p.Curve.Closed() {
+		distance = float(math.Mod(float64(distance), float64(length)))
+		if distance < 0 {
+			distance += length
+		}
+		return distance
+	}
+	return min(max(distance, 0), length)
+}
+
+// T returns the curve parameter `distance` along the path.
+func (p *Path) T(distance float) float {
+	distance = p.wrap(distance)
+	// the first sample further along
+	i := sort.Search(len(p.distances), func(i int) bool { return p.distances[i] >= distance })
+	if i == 0 {
+		return 0
+	}
+	if i == len(p.distances) {
+		return float(len(p.distances)-1) / samples_per_segment
+	}
+	before, after := p.distances[i-1], p.distances[i]
+	f := float(0)
+	if after > before {
+		f = (distance - before) / (after - before)
+	}
+	return (float(i-1) + f) / samples_per_segment
+}
+
+// At returns the point `distance` along the path.
+func (p *Path) At(distance float) vec3 {
+	return p.Curve.Point(p.T(distance))
+}
+
+// Direction returns the direction of the path `distance` along it, zero where it has none.
+func (p *Path) Direction(distance float) vec3 {
+	d := p.Curve.Derivative(p.T(distance))
+	if d.Len() == 0 {
+		return d
+	}
+	return d.Normalize()
+}
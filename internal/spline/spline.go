@@ -0,0 +1,158 @@
+// Package spline has smooth curves through authored points and the arc length bookkeeping to move along them at
+// a steady speed.
+//
+// Curves are parameterized by t from 0 to their number of segments, segment i covering [i, i+1], and move faster
+// where points are far apart. Path measures a curve so positions can be asked for by distance along it instead,
+// which is what anything following a path wants.
+package spline
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+// Curve is a piecewise curve.
+type Curve interface {
+	// Segments is the number of pieces, t goes from 0 to Segments.
+	Segments() int
+	Point(t float) vec3
+	// Derivative is the direction and speed the curve is traced in at t.
+	Derivative(t float) vec3
+	// Closed reports whether the end joins up with the start.
+	Closed() bool
+}
+
+// segment splits `t` into the segment it's on and the position within it, clamped to the curve.
+func segment(t float, segments int) (int, float) {
+	if segments <= 0 {
+		return 0, 0
+	}
+	if t <= 0 {
+		return 0, 0
+	}
+	if t >= float(segments) {
+		return segments - 1, 1
+	}
+	i := int(t)
+	return i, t - float(i)
+}
+
+// Bezier is a chain of cubic Bézier curves. Points holds the start, then two control points and an end per
+// segment, the end of each segment being the start of the next: p0 c c p1 c c p2 and so on. The curve only
+// passes through every third point, the control points pull it towards themselves.
+type Bezier struct {
+	Points []vec3
+}
+
+func (b *Bezier) Segments() int {
+	return max(len(b.Points)-1, 0) / 3
+}
+
+func (b *Bezier) Point(t float) vec3 {
+	if b.Segments() == 0 {
+		return first(b.Points)
+	}
+	i, u := segment(t, b.Segments())
+	p := b.Points[i*3 : i*3+4]
+	v := 1 - u
+	return p[0].Mul(v * v * v).
+		Add(p[1].Mul(3 * v * v * u)).
+		Add(p[2].Mul(3 * v * u * u)).
+		Add(p[3].Mul(u * u * u))
+}
+
+func (b *Bezier) Derivative(t float) vec3 {
+	if b.Segments() == 0 {
+		return vec3{}
+	}
+	i, u := segment(t, b.Segments())
+	p := b.Points[i*3 : i*3+4]
+	v := 1 - u
+	return p[1].Sub(p[0]).Mul(3 * v * v).
+		Add(p[2].Sub(p[1]).Mul(6 * v * u)).
+		Add(p[3].Sub(p[2]).Mul(3 * u * u))
+}
+
+func (b *Bezier) Closed() bool {
+	return b.Segments() > 0 && b.Points[0] == b.Points[b.Segments()*3]
+}
+
+// CatmullRom passes through every one of its points, bending through each one in the direction from the point
+// before it to the one after. That makes it the easy one to author, just place points. The ends of an open curve
+// have no neighbor and are given a mirrored one.
+type CatmullRom struct {
+	Points []vec3
+	// Closed curves go from the last point back to the first.
+	Loop bool
+}
+
+func (c *CatmullRom) Segments() int {
+	n := len(c.Points)
+	if n < 2 {
+		return 0
+	}
+	if c.Loop {
+		return n
+	}
+	return n - 1
+}
+
+// control returns the four points around segment `i`.
+func (c *CatmullRom) control(i int) (p0, p1, p2, p3 vec3) {
+	n := len(c.Points)
+	at := func(j int) vec3 {
+		if c.Loop {
+			return c.Points[(j%n+n)%n]
+		}
+		// mirrored past the ends, so the ends keep going the way they were
+		switch {
+		case j < 0:
+			return c.Points[0].Mul(2).Sub(c.Points[1])
+		case j >= n:
+			return c.Points[n-1].Mul(2).Sub(c.Points[n-2])
+		}
+		return c.Points[j]
+	}
+	return at(i - 1), at(i), at(i + 1), at(i + 2)
+}
+
+func (c *CatmullRom) Point(t float) vec3 {
+	if c.Segments() == 0 {
+		return first(c.Points)
+	}
+	i, u := segment(t, c.Segments())
+	p0, p1, p2, p3 := c.control(i)
+	u2, u3 := u*u, u*u*u
+	return p1.Mul(2).
+		Add(p2.Sub(p0).Mul(u)).
+		Add(p0.Mul(2).Sub(p1.Mul(5)).Add(p2.Mul(4)).Sub(p3).Mul(u2)).
+		Add(p1.Mul(3).Sub(p0).Sub(p2.Mul(3)).Add(p3).Mul(u3)).
+		Mul(0.5)
+}
+
+func (c *CatmullRom) Derivative(t float) vec3 {
+	if c.Segments() == 0 {
+		return vec3{}
+	}
+	i, u := segment(t, c.Segments())
+	p0, p1, p2, p3 := c.control(i)
+	return p2.Sub(p0).
+		Add(p0.Mul(2).Sub(p1.Mul(5)).Add(p2.Mul(4)).Sub(p3).Mul(2 * u)).
+		Add(p1.Mul(3).Sub(p0).Sub(p2.Mul(3)).Add(p3).Mul(3 * u * u)).
+		Mul(0.5)
+}
+
+func (c *CatmullRom) Closed() bool {
+	return c.Loop && c.Segments() > 0
+}
+
+func first(points []vec3) vec3 {
+	if len(points) == 0 {
+		return vec3{}
+	}
+	return points[0]
+}
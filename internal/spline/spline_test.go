@@ -0,0 +1,127 @@
+package spline
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func near(a, b vec3, epsilon float) bool {
+	return a.Sub(b).Len() <= epsilon
+}
+
+func TestCatmullRomPassesThroughItsPoints(t *testing.T) {
+	points := []vec3{{0, 0, 0}, {2, 1, 0}, {4, 0, 1}, {6, 2, 3}}
+	for _, loop := range []bool{false, true} {
+		c := &CatmullRom{Points: points, Loop: loop}
+		for i, p := range points {
+			if got := c.Point(float(i)); !near(got, p, 1e-5) {
+				t.Fatalf("loop %v: point %d is %v, the curve passes through %v", loop, i, p, got)
+			}
+		}
+		if loop && !near(c.Point(float(c.Segments())), points[0], 1e-5) {
+			t.Fatal("a closed curve doesn't end where it started")
+		}
+	}
+}
+
+func TestBezierEndsAndControls(t *testing.T) {
+	b := &Bezier{Points: []vec3{{0, 0, 0}, {0, 1, 0}, {1, 1, 0}, {1, 0, 0}, {1, -1, 0}, {2, -1, 0}, {2, 0, 0}}}
+	if b.Segments() != 2 {
+		t.Fatalf("%d segments, want 2", b.Segments())
+	}
+	for i, want := range []vec3{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}} {
+		if got := b.Point(float(i)); !near(got, want, 1e-6) {
+			t.Fatalf("t = %d is %v, want %v", i, got, want)
+		}
+	}
+	// leaving towards the first control point, three times as fast as it is far
+	if d := b.Derivative(0); !near(d, vec3{0, 3, 0}, 1e-6) {
+		t.Fatalf("the derivative at the start is %v", d)
+	}
+}
+
+func TestDerivativesMatchPoints(t *testing.T) {
+	curves := []Curve{
+		&CatmullRom{Points: []vec3{{0, 0, 0}, {2, 1, 0}, {4, 0, 1}, {6, 2, 3}}},
+		&Bezier{Points: []vec3{{0, 0, 0}, {0, 1, 0}, {1, 1, 0}, {1, 0, 0}}},
+	}
+	const h = 1e-2
+	for _, c := range curves {
+		for _, t0 := range []float{0.3, 0.5, 0.9} {
+			numeric := c.Point(t0 + h).Sub(c.Point(t0 - h)).Mul(1 / (2 * h))
+			if d := c.Derivative(t0); !near(d, numeric, 1e-2) {
+				t.Fatalf("%T: the derivative at %v is %v, the points say %v", c, t0, d, numeric)
+			}
+		}
+	}
+}
+
+func TestPathMeasuresArcLength(t *testing.T) {
+	// a straight line with its control points bunched at one end moves unevenly in t, but not by distance
+	b := &Bezier{Points: []vec3{{0, 0, 0}, {0.1, 0, 0}, {0.2, 0, 0}, {10, 0, 0}}}
+	p := NewPath(b)
+	if l := p.Length(); math.Abs(float64(l-10)) > 1e-3 {
+		t.Fatalf("the line is %v long, want 10", l)
+	}
+	for _, d := range []float{0, 2.5, 5, 7.5, 10} {
+		if got := p.At(d); !near(got, vec3{d, 0, 0}, 0.01) {
+			t.Fatalf("%v along the line is %v", d, got)
+		}
+	}
+
+	// a closed Catmull-Rom through a square's corners is a rounded square, longer than the square and shorter
+	// than the circle through the corners
+	r := float(5)
+	c := &CatmullRom{Points: []vec3{{r, 0, 0}, {0, 0, r}, {-r, 0, 0}, {0, 0, -r}}, Loop: true}
+	p = NewPath(c)
+	if l := float64(p.Length()); l < 4*math.Sqrt2*5 || l > 2*math.Pi*5 {
+		t.Fatalf("the loop is %v long, not between the square and the circle", l)
+	}
+	if !near(p.At(p.Length()+1), p.At(1), 1e-4) {
+		t.Fatal("distances don't wrap around a closed path")
+	}
+}
+
+func TestFollowerModes(t *testing.T) {
+	line := NewPath(&Bezier{Points: []vec3{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}, {3, 0, 0}}})
+
+	f := NewFollower(line, 1, Once)
+	for i := 0; i < 50; i++ {
+		f.Update(0.1)
+	}
+	if !f.Done() || !near(f.Position(), vec3{3, 0, 0}, 1e-4) {
+		t.Fatalf("following once ended at %v, done %v", f.Position(), f.Done())
+	}
+
+	f = NewFollower(line, 1, PingPong)
+	for i := 0; i < 40; i++ {
+		f.Update(0.1)
+	}
+	// 4 units: 3 there and 1 back
+	if !near(f.Position(), vec3{2, 0, 0}, 1e-3) || !near(f.Forward(), vec3{-1, 0, 0}, 1e-4) {
+		t.Fatalf("ping pong is at %v going %v, want 2 going back", f.Position(), f.Forward())
+	}
+
+	f = NewFollower(line, 1, Loop)
+	for i := 0; i < 40; i++ {
+		f.Update(0.1)
+	}
+	if !near(f.Position(), vec3{1, 0, 0}, 1e-3) || f.Done() {
+		t.Fatalf("looping is at %v, want 1", f.Position())
+	}
+}
+
+func TestFollowerSpeedIsSteady(t *testing.T) {
+	c := &CatmullRom{Points: []vec3{{0, 0, 0}, {3, 0, 0}, {6, 0, 3}, {9, 2, 6}}}
+	f := NewFollower(NewPath(c), 2, Once)
+	previous := f.Position()
+	for i := 0; i < 20; i++ {
+		f.Update(0.1)
+		if step := f.Position().Sub(previous).Len(); mgl32.Abs(step-0.2) > 0.01 {
+			t.Fatalf("step %d moved %v, want 0.2", i, step)
+		}
+		previous = f.Position()
+	}
+}
@@ -0,0 +1,54 @@
+package sprite
+
+import (
+	"fmt"
+	"image"
+	_ "image/png"
+	"io/fs"
+	"path"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/spritesheet"
+)
+
+// Sheet is a sprite sheet and its image.
+type Sheet struct {
+	*spritesheet.Sheet
+	Image *ebiten.Image
+}
+
+// LoadSheet loads the sheet described by the JSON at `name` in `fsys`, and its image next to it.
+func LoadSheet(fsys fs.FS, name string) (*Sheet, error) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	s, err := spritesheet.Decode(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	image_name := path.Join(path.Dir(name), s.Image)
+	f, err := fsys.Open(image_name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", image_name, err)
+	}
+	if s.Width == 0 {
+		s.Width, s.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	}
+	return &Sheet{Sheet: s, Image: ebiten.NewImageFromImage(img)}, nil
+}
+
+// Sprite is frame `frame` of the sheet placed by `geom` as if it were untrimmed, its top left at 0, 0.
+func (s *Sheet) Sprite(frame int, geom ebiten.GeoM) Sprite {
+	f := &s.Frames[frame]
+	var at ebiten.GeoM
+	at.Translate(float64(f.Offset.X), float64(f.Offset.Y))
+	at.Concat(geom)
+	return Sprite{Image: s.Image, Src: f.Rect, GeoM: at}
+}
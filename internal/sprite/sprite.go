@@ -0,0 +1,115 @@
+// Package sprite draws lots of sprites, rectangles cut out of images, in few draw calls. A Batch collects them and
+// draws each run of sprites of the same image onto the same target with a single DrawTriangles, so sprites cut
+// from one sheet, like the tiles of a tileset, cost a draw call together instead of one each. A Sheet is a sprite
+// sheet of package spritesheet with its image, which makes sprites of its frames.
+package sprite
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// max_sprites is how many sprites a draw call takes, the vertices of more don't fit the uint16 indices
+const max_sprites = (math.MaxUint16 + 1) / 4
+
+// Sprite is a rectangle of an image and where to draw it.
+type Sprite struct {
+	Image *ebiten.Image
+	// Src is the rectangle of Image drawn, in its coordinates. The zero rectangle is the whole image.
+	Src image.Rectangle
+	// GeoM places the rectangle, which is drawn with its top left at 0, 0 and the size of Src, or of Src turned
+	// sideways when it's flipped diagonally.
+	GeoM       ebiten.GeoM
+	ColorScale ebiten.ColorScale
+	// FlipX and FlipY mirror the rectangle, FlipDiagonal swaps its x and y before. The three make the rotations
+	// by quarter turns.
+	FlipX, FlipY, FlipDiagonal bool
+}
+
+// Stats count what a batch drew since its stats were last reset.
+type Stats struct {
+	Sprites, DrawCalls int
+}
+
+// Batch collects sprites to draw. The zero value is ready to use.
+type Batch struct {
+	// Filter samples the images, nearest by default, which keeps pixel art crisp.
+	Filter ebiten.Filter
+
+	target, image *ebiten.Image
+	vertices      []ebiten.Vertex
+	indices       []uint16
+	stats         Stats
+}
+
+// Add adds `s` to draw onto `target`. Sprites are drawn in the order they're added: a sprite of another image or
+// onto another target than the last one draws those before it first.
+func (b *Batch) Add(target *ebiten.Image, s *Sprite) {
+	if target != b.target || s.Image != b.image || len(b.vertices) == max_sprites*4 {
+		b.Flush()
+		b.target, b.image = target, s.Image
+	}
+	src := s.Src
+	if src.Empty() {
+		src = s.Image.Bounds()
+	}
+	w, h := float64(src.Dx()), float64(src.Dy())
+	if s.FlipDiagonal {
+		w, h = h, w
+	}
+	r, g, bl, a := s.ColorScale.R(), s.ColorScale.G(), s.ColorScale.B(), s.ColorScale.A()
+
+	base := uint16(len(b.vertices))
+	for corner := range 4 {
+		u, v := float64(corner&1), float64(corner>>1)
+		x, y := s.GeoM.Apply(u*w, v*h)
+		// where the corner samples the image, the flips undone in the opposite order they're applied
+		su, sv := u, v
+		if s.FlipY {
+			sv = 1 - sv
+		}
+		if s.FlipX {
+			su = 1 - su
+		}
+		if s.FlipDiagonal {
+			su, sv = sv, su
+		}
+		b.vertices = append(b.vertices, ebiten.Vertex{
+			DstX:   float32(x),
+			DstY:   float32(y),
+			SrcX:   float32(src.Min.X) + float32(su)*float32(src.Dx()),
+			SrcY:   float32(src.Min.Y) + float32(sv)*float32(src.Dy()),
+			ColorR: r,
+			ColorG: g,
+			ColorB: bl,
+			ColorA: a,
+		})
+	}
+	b.indices = append(b.indices, base, base+1, base+2, base+1, base+3, base+2)
+	b.stats.Sprites++
+}
+
+// Flush draws the sprites added so far. It's called by Add as needed, and has to be called once they're all added.
+func (b *Batch) Flush() {
+	if len(b.indices) > 0 {
+		op := &ebiten.DrawTrianglesOptions{
+			Filter:         b.Filter,
+			ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha,
+		}
+		b.target.DrawTriangles(b.vertices, b.indices, b.image, op)
+		b.stats.DrawCalls++
+	}
+	b.vertices, b.indices = b.vertices[:0], b.indices[:0]
+	b.target, b.image = nil, nil
+}
+
+// Stats are what was drawn since the last ResetStats.
+func (b *Batch) Stats() Stats {
+	return b.stats
+}
+
+func (b *Batch) ResetStats() {
+	b.stats = Stats{}
+}
@@ -0,0 +1,90 @@
+package spritesheet
+
+import (
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+// max_billboards is how many billboards a mesh holds, the points of more don't fit the uint16 indices
+const max_billboards = 0x10000 / 4
+
+// Billboards makes a mesh of sprites standing in a 3D scene, impostors of things too many or too far to model:
+// quads textured with frames of a sheet which turn about the vertical to face the camera, made again in place
+// every frame. The texture coordinates are into the whole image of the sheet, so the mesh is drawn with it.
+type Billboards struct {
+	Sheet *Sheet
+
+	mesh mesh.Mesh
+}
+
+// Reset empties the mesh, for the billboards of the next frame.
+func (b *Billboards) Reset() {
+	m := &b.mesh
+	m.Points = m.Points[:0]
+	m.Texcoords = m.Texcoords[:0]
+	m.Triangles = m.Triangles[:0]
+}
+
+// Len is how many billboards the mesh has.
+func (b *Billboards) Len() int {
+	return len(b.mesh.Points) / 4
+}
+
+// Add adds a billboard of frame `frame` of the sheet standing on `at`, seen from `eye`. Its untrimmed size is
+// `height` tall, the bottom middle of which is on `at`, and `flip` mirrors it. Billboards past as many as fit
+// 16 bit indices are dropped.
+func (b *Billboards) Add(at vec3, height float, frame int, eye vec3, flip bool) {
+	if b.Len() == max_billboards {
+		return
+	}
+	f := &b.Sheet.Frames[frame]
+	// the way to the right of the camera, in the ground plane
+	to_eye := vec3{eye[0] - at[0], 0, eye[2] - at[2]}
+	if to_eye.Len() < 1e-6 {
+		to_eye = vec3{0, 0, 1}
+	}
+	right := vec3{0, 1, 0}.Cross(to_eye).Normalize()
+
+	scale := height / float(f.Size.Y)
+	// the corners of the trimmed rectangle in the untrimmed one, from its bottom middle
+	left := (float(f.Offset.X) - float(f.Size.X)/2) * scale
+	right_edge := left + float(f.Rect.Dx())*scale
+	top := (float(f.Size.Y) - float(f.Offset.Y)) * scale
+	bottom := top - float(f.Rect.Dy())*scale
+	if flip {
+		left, right_edge = -left, -right_edge
+	}
+
+	m := &b.mesh
+	base := uint16(len(m.Points))
+	corner := func(x, y float) vec3 {
+		return at.Add(right.Mul(x)).Add(vec3{0, y, 0})
+	}
+	m.Points = append(m.Points,
+		corner(left, top), corner(right_edge, top),
+		corner(left, bottom), corner(right_edge, bottom),
+	)
+	w, h := float(b.Sheet.Width), float(b.Sheet.Height)
+	r := f.Rect
+	m.Texcoords = append(m.Texcoords,
+		vec2{float(r.Min.X) / w, float(r.Min.Y) / h}, vec2{float(r.Max.X) / w, float(r.Min.Y) / h},
+		vec2{float(r.Min.X) / w, float(r.Max.Y) / h}, vec2{float(r.Max.X) / w, float(r.Max.Y) / h},
+	)
+	// wound to face the eye, which a flip turns around
+	quad := [2][3]uint16{{0, 2, 1}, {1, 2, 3}}
+	if flip {
+		quad = [2][3]uint16{{0, 1, 2}, {1, 3, 2}}
+	}
+	for _, q := range quad {
+		a, b, c := base+q[0], base+q[1], base+q[2]
+		m.Triangles = append(m.Triangles, mesh.Triangle{P1: a, P2: b, P3: c, T1: a, T2: b, T3: c})
+	}
+}
+
+// Mesh is the mesh of the billboards added since the last Reset, nil when there are none. It's the billboards' own.
+func (b *Billboards) Mesh() *mesh.Mesh {
+	if len(b.mesh.Triangles) == 0 {
+		return nil
+	}
+	b.mesh.ComputeBounds()
+	return &b.mesh
+}
@@ -0,0 +1,141 @@
+package spritesheet
+
+import (
+	"time"
+)
+
+// Direction is the order a clip plays its frames in.
+type Direction int
+
+const (
+	Forward Direction = iota
+	Reverse
+	// PingPong plays the frames forward and then back, without showing the last and the first twice.
+	PingPong
+)
+
+// Clip is an animation of frames of a sheet.
+type Clip struct {
+	Name string
+	// Frames are indices into the frames of the sheet.
+	Frames    []int
+	Direction Direction
+	// Repeat is how many times the clip plays before it stops on its last frame, 0 loops it forever.
+	Repeat int
+	// Events are fired when the clip shows a frame, by the index of the frame in Frames.
+	Events map[int][]string
+}
+
+// On fires `event` whenever the clip shows its frame `i`.
+func (c *Clip) On(i int, event string) *Clip {
+	if c.Events == nil {
+		c.Events = make(map[int][]string)
+	}
+	c.Events[i] = append(c.Events[i], event)
+	return c
+}
+
+// length is how many frames a play of the clip shows.
+func (c *Clip) length() int {
+	if c.Direction == PingPong && len(c.Frames) > 2 {
+		return 2*len(c.Frames) - 2
+	}
+	return len(c.Frames)
+}
+
+// at is the index into Frames of the frame shown `step` frames into a play.
+func (c *Clip) at(step int) int {
+	switch c.Direction {
+	case Reverse:
+		return len(c.Frames) - 1 - step
+	case PingPong:
+		if step >= len(c.Frames) {
+			return 2*len(c.Frames) - 2 - step
+		}
+	}
+	return step
+}
+
+// Player plays clips of a sheet. The zero value plays nothing.
+type Player struct {
+	Sheet *Sheet
+
+	clip *Clip
+	// step is how many frames into the play, plays how many plays are done
+	step, plays int
+	elapsed     time.Duration
+	// entered is set when the frame shown hasn't fired its events yet
+	entered bool
+	done    bool
+}
+
+// Play starts playing `c` from its start, unless it's already playing, so it can be called every tick with the
+// clip that should play.
+func (p *Player) Play(c *Clip) {
+	if c != p.clip {
+		p.Restart(c)
+	}
+}
+
+// Restart starts playing `c` from its start.
+func (p *Player) Restart(c *Clip) {
+	p.clip = c
+	p.step, p.plays, p.elapsed = 0, 0, 0
+	p.entered = true
+	p.done = c == nil || len(c.Frames) == 0
+}
+
+// Clip is the clip playing, nil when there's none.
+func (p *Player) Clip() *Clip {
+	return p.clip
+}
+
+// Done reports whether the clip played as many times as it repeats, it stays on its last frame.
+func (p *Player) Done() bool {
+	return p.done
+}
+
+// Frame is the index of the frame shown into the frames of the sheet, -1 when nothing plays.
+func (p *Player) Frame() int {
+	if p.clip == nil || len(p.clip.Frames) == 0 {
+		return -1
+	}
+	return p.clip.Frames[p.clip.at(p.step)]
+}
+
+// Update plays the clip on by `dt`, and appends the events of the frames it showed to `events`, the first frame's
+// too on the first update after the clip started.
+func (p *Player) Update(dt time.Duration, events []string) []string {
+	if p.clip == nil || len(p.clip.Frames) == 0 {
+		return events
+	}
+	c := p.clip
+	if p.entered {
+		p.entered = false
+		events = append(events, c.Events[c.at(p.step)]...)
+	}
+	if p.done {
+		return events
+	}
+	p.elapsed += dt
+	length := c.length()
+	for {
+		// a frame without a duration would never let the loop end
+		duration := max(p.Sheet.Frames[p.Frame()].Duration, time.Millisecond)
+		if p.elapsed < duration {
+			break
+		}
+		p.elapsed -= duration
+		p.step++
+		if p.step == length {
+			p.plays++
+			if c.Repeat > 0 && p.plays >= c.Repeat {
+				p.step, p.elapsed, p.done = length-1, 0, true
+				break
+			}
+			p.step = 0
+		}
+		events = append(events, c.Events[c.at(p.step)]...)
+	}
+	return events
+}
@@ -0,0 +1,205 @@
+// Package spritesheet reads sprite sheets, images holding the frames of animated sprites, either cut into a grid or
+// described by the JSON that Aseprite and TexturePacker export. Frames are grouped into clips, which a Player
+// plays, firing the events of the frames it reaches, like a footstep on the frame a foot lands. Package sprite
+// draws the frames in 2D, and Billboards makes meshes of them facing the camera, for impostors in 3D.
+package spritesheet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+// DefaultDuration is how long a frame is shown when the sheet doesn't say.
+const DefaultDuration = 100 * time.Millisecond
+
+// Sheet is the frames of an image and the clips they make.
+type Sheet struct {
+	// Image is the path of the image as the JSON has it, relative to the JSON, empty for a grid.
+	Image         string
+	Width, Height int
+	Frames        []Frame
+	Clips         map[string]*Clip
+}
+
+// Frame is a rectangle of the image.
+type Frame struct {
+	Name     string
+	Rect     image.Rectangle
+	Duration time.Duration
+	// Size is the size of the sprite before it was trimmed of its transparent border, and Offset where Rect goes
+	// in it. Untrimmed frames have an offset of 0 and the size of Rect.
+	Size   image.Point
+	Offset image.Point
+}
+
+// Grid cuts an image of `width` by `height` into frames of `frame_width` by `frame_height`, row by row, with
+// `margin` pixels around them and `spacing` between them, each shown for `duration`.
+func Grid(width, height, frame_width, frame_height, margin, spacing int, duration time.Duration) *Sheet {
+	s := &Sheet{Width: width, Height: height, Clips: make(map[string]*Clip)}
+	if duration <= 0 {
+		duration = DefaultDuration
+	}
+	for y := margin; y+frame_height <= height-margin; y += frame_height + spacing {
+		for x := margin; x+frame_width <= width-margin; x += frame_width + spacing {
+			s.Frames = append(s.Frames, Frame{
+				Name:     fmt.Sprint(len(s.Frames)),
+				Rect:     image.Rect(x, y, x+frame_width, y+frame_height),
+				Duration: duration,
+				Size:     image.Pt(frame_width, frame_height),
+			})
+		}
+	}
+	return s
+}
+
+// AddClip adds a clip of the frames from `from` to `to`, both included, which plays forward and loops.
+func (s *Sheet) AddClip(name string, from, to int) *Clip {
+	c := &Clip{Name: name}
+	for i := from; i <= to; i++ {
+		c.Frames = append(c.Frames, i)
+	}
+	s.Clips[name] = c
+	return c
+}
+
+// Find is the index of the frame named `name`, -1 when there's none.
+func (s *Sheet) Find(name string) int {
+	for i, f := range s.Frames {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// The JSON of Aseprite's and TexturePacker's sheets, which are the same as far as they go.
+
+type json_rect struct {
+	X, Y, W, H int
+}
+
+type json_frame struct {
+	Filename         string    `json:"filename"`
+	Frame            json_rect `json:"frame"`
+	Rotated          bool      `json:"rotated"`
+	SpriteSourceSize json_rect `json:"spriteSourceSize"`
+	SourceSize       struct {
+		W, H int
+	} `json:"sourceSize"`
+	// Duration is in milliseconds, Aseprite writes it and TexturePacker doesn't
+	Duration int `json:"duration"`
+}
+
+type json_sheet struct {
+	// Frames is an array of frames, or an object of them by name
+	Frames json.RawMessage `json:"frames"`
+	Meta   struct {
+		Image string `json:"image"`
+		Size  struct {
+			W, H int
+		} `json:"size"`
+		FrameTags []struct {
+			Name      string `json:"name"`
+			From      int    `json:"from"`
+			To        int    `json:"to"`
+			Direction string `json:"direction"`
+			Repeat    string `json:"repeat"`
+		} `json:"frameTags"`
+	} `json:"meta"`
+}
+
+// Decode reads a sheet from the JSON of Aseprite or TexturePacker, with its frames in an array or in an object by
+// name. The tags of Aseprite become clips.
+func Decode(src []byte) (*Sheet, error) {
+	var j json_sheet
+	if err := json.Unmarshal(src, &j); err != nil {
+		return nil, fmt.Errorf("bad sheet: %w", err)
+	}
+	frames, err := decode_frames(j.Frames)
+	if err != nil {
+		return nil, fmt.Errorf("bad sheet: %w", err)
+	}
+	s := &Sheet{Image: j.Meta.Image, Width: j.Meta.Size.W, Height: j.Meta.Size.H, Clips: make(map[string]*Clip)}
+	for _, f := range frames {
+		if f.Rotated {
+			return nil, fmt.Errorf("frame %q is rotated, which isn't supported", f.Filename)
+		}
+		frame := Frame{
+			Name:     f.Filename,
+			Rect:     image.Rect(f.Frame.X, f.Frame.Y, f.Frame.X+f.Frame.W, f.Frame.Y+f.Frame.H),
+			Duration: time.Duration(f.Duration) * time.Millisecond,
+			Size:     image.Pt(f.SourceSize.W, f.SourceSize.H),
+			Offset:   image.Pt(f.SpriteSourceSize.X, f.SpriteSourceSize.Y),
+		}
+		if frame.Duration <= 0 {
+			frame.Duration = DefaultDuration
+		}
+		if frame.Size == (image.Point{}) {
+			frame.Size = frame.Rect.Size()
+		}
+		s.Frames = append(s.Frames, frame)
+	}
+	for _, tag := range j.Meta.FrameTags {
+		if tag.From < 0 || tag.To >= len(s.Frames) || tag.From > tag.To {
+			return nil, fmt.Errorf("tag %q is of frames %d to %d of %d", tag.Name, tag.From, tag.To, len(s.Frames))
+		}
+		c := s.AddClip(tag.Name, tag.From, tag.To)
+		switch tag.Direction {
+		case "", "forward":
+		case "reverse":
+			c.Direction = Reverse
+		case "pingpong":
+			c.Direction = PingPong
+		default:
+			return nil, fmt.Errorf("tag %q plays %s, which isn't supported", tag.Name, tag.Direction)
+		}
+		if tag.Repeat != "" {
+			if _, err := fmt.Sscan(tag.Repeat, &c.Repeat); err != nil {
+				return nil, fmt.Errorf("tag %q repeats %q times", tag.Name, tag.Repeat)
+			}
+		}
+	}
+	return s, nil
+}
+
+// decode_frames reads the frames of a sheet, in the order of the file also when they're an object.
+func decode_frames(src json.RawMessage) ([]json_frame, error) {
+	var frames []json_frame
+	src = bytes.TrimSpace(src)
+	if len(src) == 0 {
+		return nil, errors.New("there are no frames")
+	}
+	if src[0] == '[' {
+		err := json.Unmarshal(src, &frames)
+		return frames, err
+	}
+	d := json.NewDecoder(bytes.NewReader(src))
+	if _, err := d.Token(); err != nil {
+		return nil, err
+	}
+	for d.More() {
+		key, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		var f json_frame
+		if err := d.Decode(&f); err != nil {
+			return nil, err
+		}
+		f.Filename, _ = key.(string)
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
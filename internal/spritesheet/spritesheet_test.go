@@ -0,0 +1,203 @@
+package spritesheet
+
+import (
+	"image"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGrid(t *testing.T) {
+	// two rows of three, with a margin of 1 and 2 between
+	s := Grid(1+3*16+2*2+1, 1+2*16+2+1, 16, 16, 1, 2, 0)
+	if len(s.Frames) != 6 {
+		t.Fatalf("%d frames", len(s.Frames))
+	}
+	if f := s.Frames[4]; f.Rect != image.Rect(19, 19, 35, 35) || f.Duration != DefaultDuration || f.Size != image.Pt(16, 16) {
+		t.Errorf("frame 4 is %+v", f)
+	}
+	if c := s.AddClip("walk", 2, 4); !slices.Equal(c.Frames, []int{2, 3, 4}) || s.Clips["walk"] != c {
+		t.Errorf("the clip is %+v", c)
+	}
+}
+
+// aseprite is a sheet as Aseprite exports it, its frames by name in an order which isn't sorted.
+const aseprite = `{
+ "frames": {
+  "hero 2.aseprite": {"frame": {"x": 32, "y": 0, "w": 16, "h": 16}, "rotated": false, "trimmed": false,
+   "spriteSourceSize": {"x": 0, "y": 0, "w": 16, "h": 16}, "sourceSize": {"w": 16, "h": 16}, "duration": 200},
+  "hero 0.aseprite": {"frame": {"x": 0, "y": 0, "w": 16, "h": 16}, "sourceSize": {"w": 16, "h": 16}, "duration": 100},
+  "hero 1.aseprite": {"frame": {"x": 16, "y": 0, "w": 10, "h": 12}, "trimmed": true,
+   "spriteSourceSize": {"x": 3, "y": 4, "w": 10, "h": 12}, "sourceSize": {"w": 16, "h": 16}, "duration": 100}
+ },
+ "meta": {
+  "image": "hero.png", "size": {"w": 48, "h": 16},
+  "frameTags": [
+   {"name": "all", "from": 0, "to": 2, "direction": "pingpong"},
+   {"name": "back", "from": 1, "to": 2, "direction": "reverse", "repeat": "2"}
+  ]
+ }
+}`
+
+// texturepacker is a sheet as TexturePacker exports it, its frames in an array and no durations.
+const texturepacker = `{
+ "frames": [
+  {"filename": "a.png", "frame": {"x": 0, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}},
+  {"filename": "b.png", "frame": {"x": 8, "y": 0, "w": 8, "h": 8}}
+ ],
+ "meta": {"image": "sheet.png", "size": {"w": 16, "h": 8}}
+}`
+
+func TestDecode(t *testing.T) {
+	s, err := Decode([]byte(aseprite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Image != "hero.png" || s.Width != 48 || len(s.Frames) != 3 {
+		t.Fatalf("sheet is %+v", s)
+	}
+	if s.Find("hero 2.aseprite") != 0 || s.Find("hero 1.aseprite") != 2 || s.Find("nobody") != -1 {
+		t.Errorf("frames aren't in the order of the file: %+v", s.Frames)
+	}
+	if f := s.Frames[2]; f.Offset != image.Pt(3, 4) || f.Size != image.Pt(16, 16) || f.Rect.Dx() != 10 {
+		t.Errorf("the trimmed frame is %+v", f)
+	}
+	if f := s.Frames[0]; f.Duration != 200*time.Millisecond {
+		t.Errorf("the first frame is shown for %v", f.Duration)
+	}
+	if c := s.Clips["all"]; c == nil || c.Direction != PingPong || c.Repeat != 0 || len(c.Frames) != 3 {
+		t.Errorf("all is %+v", c)
+	}
+	if c := s.Clips["back"]; c == nil || c.Direction != Reverse || c.Repeat != 2 {
+		t.Errorf("back is %+v", c)
+	}
+
+	s, err = Decode([]byte(texturepacker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Frames) != 2 || s.Frames[1].Name != "b.png" || s.Frames[1].Duration != DefaultDuration || s.Frames[1].Size != image.Pt(8, 8) {
+		t.Errorf("frames are %+v", s.Frames)
+	}
+
+	for _, bad := range []string{
+		`{"frames": [{"filename": "a", "rotated": true}]}`,
+		`{"frames": [], "meta": {"frameTags": [{"name": "a", "from": 0, "to": 1}]}}`,
+		`{"frames": []`,
+	} {
+		if _, err := Decode([]byte(bad)); err == nil {
+			t.Errorf("%s decoded", bad)
+		}
+	}
+}
+
+// play updates `p` frame by frame for `n` frames of 100ms, and is the frames it showed and the events it fired.
+func play(p *Player, n int) (frames []int, events []string) {
+	for range n {
+		events = p.Update(100*time.Millisecond, events)
+		frames = append(frames, p.Frame())
+	}
+	return frames, events
+}
+
+func TestPlayer(t *testing.T) {
+	s := Grid(64, 16, 16, 16, 0, 0, 100*time.Millisecond)
+	walk := s.AddClip("walk", 0, 3).On(0, "step").On(2, "step")
+	bounce := s.AddClip("bounce", 0, 2)
+	bounce.Direction = PingPong
+	once := s.AddClip("once", 1, 3)
+	once.Direction, once.Repeat = Reverse, 1
+	once.On(2, "end")
+
+	var p Player
+	p.Sheet = s
+	if p.Frame() != -1 {
+		t.Errorf("nothing playing shows %d", p.Frame())
+	}
+
+	p.Play(walk)
+	frames, events := play(&p, 6)
+	// the first update fires the events of the first frame and then moves on from it
+	if !slices.Equal(frames, []int{1, 2, 3, 0, 1, 2}) || !slices.Equal(events, []string{"step", "step", "step", "step"}) {
+		t.Errorf("walk showed %v and fired %v", frames, events)
+	}
+	p.Play(walk)
+	if p.Frame() != 2 {
+		t.Errorf("playing the clip playing restarted it")
+	}
+
+	p.Play(bounce)
+	if frames, _ := play(&p, 6); !slices.Equal(frames, []int{1, 2, 1, 0, 1, 2}) {
+		t.Errorf("bounce showed %v", frames)
+	}
+
+	p.Play(once)
+	frames, events = play(&p, 4)
+	if !slices.Equal(frames, []int{2, 1, 1, 1}) || !slices.Equal(events, []string{"end"}) || !p.Done() {
+		t.Errorf("once showed %v and fired %v, done: %v", frames, events, p.Done())
+	}
+	p.Restart(once)
+	if p.Done() || p.Frame() != 3 {
+		t.Errorf("restarting left it done: %v on %d", p.Done(), p.Frame())
+	}
+
+	// an update longer than the clip goes through it
+	p.Restart(walk)
+	if events := p.Update(time.Second, nil); len(events) != 6 || p.Frame() != 2 {
+		t.Errorf("a second of walk fired %v and ended on %d", events, p.Frame())
+	}
+}
+
+func TestBillboardsFaceTheEye(t *testing.T) {
+	s, err := Decode([]byte(aseprite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Billboards{Sheet: s}
+	if b.Mesh() != nil {
+		t.Fatal("no billboards made a mesh")
+	}
+	for _, eye := range []vec3{{0, 1, 5}, {5, 3, 0}, {-3, 0, -4}} {
+		for _, flip := range []bool{false, true} {
+			b.Reset()
+			b.Add(vec3{1, 0, 1}, 2, 0, eye, flip)
+			m := b.Mesh()
+			for _, tri := range m.Triangles {
+				a, c, d := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+				if c.Sub(a).Cross(d.Sub(a)).Dot(eye.Sub(a)) <= 0 {
+					t.Errorf("seen from %v flipped %v a triangle faces away", eye, flip)
+				}
+			}
+			// it stands on its bottom middle, 2 tall
+			lo, hi := m.Points[0], m.Points[0]
+			for _, p := range m.Points {
+				lo, hi = vec3{min(lo[0], p[0]), min(lo[1], p[1]), min(lo[2], p[2])}, vec3{max(hi[0], p[0]), max(hi[1], p[1]), max(hi[2], p[2])}
+			}
+			if lo[1] != 0 || hi[1] != 2 || abs(lo[0]+hi[0]-2) > 1e-5 || abs(lo[2]+hi[2]-2) > 1e-5 {
+				t.Errorf("seen from %v the billboard spans %v to %v", eye, lo, hi)
+			}
+		}
+	}
+
+	// the trimmed frame covers the part of the untrimmed one it came from, which starts 3 pixels in and 4 down
+	b.Reset()
+	b.Add(vec3{}, 16, 2, vec3{0, 0, 10}, false)
+	b.Add(vec3{}, 16, 1, vec3{0, 0, 10}, false)
+	m := b.Mesh()
+	if b.Len() != 2 || m.Points[0] != (vec3{-5, 12, 0}) || m.Points[3] != (vec3{5, 0, 0}) {
+		t.Errorf("the trimmed frame is at %v", m.Points[:4])
+	}
+	if m.Texcoords[0] != (vec2{16.0 / 48, 0}) || m.Texcoords[3] != (vec2{26.0 / 48, 12.0 / 16}) {
+		t.Errorf("the texcoords of the trimmed frame are %v", m.Texcoords[:4])
+	}
+	if m.Points[4] != (vec3{-8, 16, 0}) || m.Texcoords[7] != (vec2{16.0 / 48, 1}) {
+		t.Errorf("the untrimmed frame is at %v, %v", m.Points[4:], m.Texcoords[4:])
+	}
+}
+
+func abs(x float) float {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
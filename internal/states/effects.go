@@ -0,0 +1,87 @@
+package states
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// fade_shader fades the frame left out to Color and the frame entered in from it.
+var fade_shader = `
+//kage:unit pixels
+package main
+
+var Progress float
+var Color vec4
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	if Progress < 0.5 {
+		return mix(imageSrc0At(src), Color, Progress*2)
+	}
+	return mix(Color, imageSrc1At(src), Progress*2-1)
+}
+`
+
+// wipe_shader uncovers the frame entered behind an edge sweeping across the screen along Direction, Softness
+// pixels wide.
+var wipe_shader = `
+//kage:unit pixels
+package main
+
+var Progress float
+var Direction vec2
+var Softness float
+var Size vec2
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	// how far along the sweep the pixel is, from 0 where it starts to 1 where it ends
+	span := abs(Direction.x)*Size.x + abs(Direction.y)*Size.y
+	along := (dot(dst.xy-Size/2, Direction) + span/2) / span
+	soft := Softness / span
+	edge := Progress * (1 + soft)
+	return mix(imageSrc1At(src), imageSrc0At(src), smoothstep(edge-soft, edge, along))
+}
+`
+
+// Effect draws a transition, blending from the frame of the states before it into the frame of those after.
+type Effect struct {
+	shader *ebiten.Shader
+	// Uniforms are those of the effect's shader, besides Progress.
+	Uniforms map[string]any
+}
+
+// NewFade makes an effect which fades out to `c` and back in from it.
+func NewFade(c color.Color) (*Effect, error) {
+	shader, err := ebiten.NewShader([]byte(fade_shader))
+	if err != nil {
+		return nil, err
+	}
+	r, g, b, a := c.RGBA()
+	return &Effect{shader: shader, Uniforms: map[string]any{
+		"Color": []float32{float(r) / 0xffff, float(g) / 0xffff, float(b) / 0xffff, float(a) / 0xffff},
+	}}, nil
+}
+
+// NewWipe makes an effect which sweeps the new frame in along `direction`, say {1, 0} from left to right, its
+// edge `softness` pixels wide.
+func NewWipe(direction vec2, softness float) (*Effect, error) {
+	shader, err := ebiten.NewShader([]byte(wipe_shader))
+	if err != nil {
+		return nil, err
+	}
+	direction = direction.Normalize()
+	return &Effect{shader: shader, Uniforms: map[string]any{
+		"Direction": []float32{direction.X(), direction.Y()},
+		"Softness":  max(softness, 1),
+	}}, nil
+}
+
+// draw draws the transition from `from` to `to`, which are the size of `dst`, `progress` of the way through.
+func (e *Effect) draw(dst, from, to *ebiten.Image, progress float) {
+	size := dst.Bounds().Size()
+	e.Uniforms["Progress"] = progress
+	e.Uniforms["Size"] = []float32{float(size.X), float(size.Y)}
+	op := &ebiten.DrawRectShaderOptions{Uniforms: e.Uniforms}
+	op.Images[0], op.Images[1] = from, to
+	dst.DrawRectShader(size.X, size.Y, e.shader, op)
+}
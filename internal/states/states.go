@@ -0,0 +1,182 @@
+// Package states runs the screens of a demo, a menu, the demo itself, a pause menu over it, as a stack of states.
+// Only the state on top updates, states push the next one or pop themselves to go back, and the change can be
+// drawn as a transition: an effect of a Kage shader blending from the frame of the stack before it into the frame
+// of the stack after, like a fade or a wipe. A Machine is an ebiten.Game, so it runs inside app like any demo.
+package states
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/targets"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+)
+
+// State is a screen of the demo.
+type State interface {
+	// Update is called every tick while the state is on top and no transition is running.
+	Update(m *Machine) error
+	Draw(screen *ebiten.Image)
+}
+
+// Enterer is implemented by states which want to know when they're pushed.
+type Enterer interface {
+	Enter(m *Machine)
+}
+
+// Leaver is implemented by states which want to know when they're popped, or switched for another.
+type Leaver interface {
+	Leave(m *Machine)
+}
+
+// Overlay is implemented by states drawn over the state under them, like a pause menu over the demo it paused.
+// The state under still draws, but doesn't update.
+type Overlay interface {
+	Overlay() bool
+}
+
+// Transition is how a change of states is drawn. The zero value cuts straight to the new state.
+type Transition struct {
+	Effect  *Effect
+	Seconds float
+}
+
+// Machine is a stack of states.
+type Machine struct {
+	Width, Height int
+
+	states []State
+	// from is the stack as it was before the change being transitioned from, which is drawn until it's done
+	from       []State
+	transition Transition
+	elapsed    float
+}
+
+// New makes a machine laid out at `width` by `height` with `first` on its stack.
+func New(width, height int, first State) *Machine {
+	m := &Machine{Width: width, Height: height}
+	m.Push(first, Transition{})
+	return m
+}
+
+// Top is the state on top, or nil once the last was popped.
+func (m *Machine) Top() State {
+	if len(m.states) == 0 {
+		return nil
+	}
+	return m.states[len(m.states)-1]
+}
+
+// Len is how many states are on the stack.
+func (m *Machine) Len() int {
+	return len(m.states)
+}
+
+// Transitioning reports whether a transition is running, states don't update until it's done.
+func (m *Machine) Transitioning() bool {
+	return m.from != nil
+}
+
+// begin starts `t` from the stack as it is, before it's changed.
+func (m *Machine) begin(t Transition) {
+	if t.Effect == nil || t.Seconds <= 0 {
+		m.from = nil
+		return
+	}
+	// a change during a transition starts over from the stack it was heading to
+	m.from = append(m.from[:0:0], m.states...)
+	m.transition = t
+	m.elapsed = 0
+}
+
+// Push puts `s` on top of the stack.
+func (m *Machine) Push(s State, t Transition) {
+	m.begin(t)
+	m.states = append(m.states, s)
+	if e, ok := s.(Enterer); ok {
+		e.Enter(m)
+	}
+}
+
+// Pop takes the state on top off the stack, and the demo ends once the last one is. A state popped is still drawn
+// while the transition away from it runs.
+func (m *Machine) Pop(t Transition) {
+	if len(m.states) == 0 {
+		return
+	}
+	m.begin(t)
+	top := m.states[len(m.states)-1]
+	m.states[len(m.states)-1] = nil
+	m.states = m.states[:len(m.states)-1]
+	if l, ok := top.(Leaver); ok {
+		l.Leave(m)
+	}
+}
+
+// Switch takes the state on top off the stack and puts `s` in its place.
+func (m *Machine) Switch(s State, t Transition) {
+	m.begin(t)
+	if n := len(m.states); n > 0 {
+		top := m.states[n-1]
+		m.states = m.states[:n-1]
+		if l, ok := top.(Leaver); ok {
+			l.Leave(m)
+		}
+	}
+	m.states = append(m.states, s)
+	if e, ok := s.(Enterer); ok {
+		e.Enter(m)
+	}
+}
+
+func (m *Machine) Layout(outside_width, outside_height int) (int, int) {
+	return m.Width, m.Height
+}
+
+func (m *Machine) Update() error {
+	if m.from != nil {
+		m.elapsed += 1 / float(ebiten.TPS())
+		if m.elapsed >= m.transition.Seconds {
+			m.from = nil
+		}
+		return nil
+	}
+	top := m.Top()
+	if top == nil {
+		return ebiten.Termination
+	}
+	return top.Update(m)
+}
+
+func (m *Machine) Draw(screen *ebiten.Image) {
+	if m.from == nil {
+		draw(screen, m.states)
+		return
+	}
+	size := screen.Bounds().Size()
+	from, to := targets.Default.Get(size.X, size.Y), targets.Default.Get(size.X, size.Y)
+	draw(from, m.from)
+	draw(to, m.states)
+	progress := min(m.elapsed/m.transition.Seconds, 1)
+	m.transition.Effect.draw(screen, from, to, progress)
+	targets.Default.Put(from)
+	targets.Default.Put(to)
+}
+
+// draw draws the top of `states` onto `screen`, from the highest one which isn't an overlay up.
+func draw(screen *ebiten.Image, states []State) {
+	bottom := len(states) - 1
+	for bottom > 0 {
+		if o, ok := states[bottom].(Overlay); !ok || !o.Overlay() {
+			break
+		}
+		bottom--
+	}
+	for _, s := range states[max(bottom, 0):] {
+		s.Draw(screen)
+	}
+}
@@ -0,0 +1,395 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecscene"
+)
+
+// paint is a fill or stroke, a color or nothing.
+type paint struct {
+	color vec4
+	set   bool
+}
+
+// style is the paint of an element, what it inherits and what it sets.
+type style struct {
+	fill, stroke                 paint
+	fill_opacity, stroke_opacity float
+	fill_rule                    vecscene.FillRule
+	stroke_width                 float
+	join                         vecscene.Join
+	cap                          vecscene.Cap
+	miter_limit                  float
+	// color is what currentColor paints with
+	color vec4
+	// opacity isn't inherited, it fades the element and everything in it
+	opacity float
+}
+
+// default_style is what the root inherits: filled black and not stroked.
+func default_style() style {
+	return style{
+		fill:           paint{color: vec4{0, 0, 0, 1}, set: true},
+		fill_opacity:   1,
+		stroke_opacity: 1,
+		stroke_width:   1,
+		miter_limit:    4,
+		color:          vec4{0, 0, 0, 1},
+		opacity:        1,
+	}
+}
+
+// apply is the style of an element with the attributes `a` which inherits `s`. Values which don't parse are
+// ignored, as SVG has it, and "inherit" is what's inherited anyway.
+func (s style) apply(a attrs) style {
+	s.opacity = 1
+	if v, ok := a["color"]; ok {
+		if c, err := parse_color(v); err == nil {
+			s.color = c
+		}
+	}
+	if v, ok := a["fill"]; ok {
+		if p, err := s.paint(v); err == nil {
+			s.fill = p
+		}
+	}
+	if v, ok := a["stroke"]; ok {
+		if p, err := s.paint(v); err == nil {
+			s.stroke = p
+		}
+	}
+	number := func(name string, dst *float) {
+		if v, ok := a[name]; ok {
+			if n, err := parse_number(v); err == nil {
+				*dst = n
+			}
+		}
+	}
+	number("fill-opacity", &s.fill_opacity)
+	number("stroke-opacity", &s.stroke_opacity)
+	number("opacity", &s.opacity)
+	number("stroke-miterlimit", &s.miter_limit)
+	if v, ok := a["stroke-width"]; ok {
+		if w, err := parse_length(v); err == nil && w >= 0 {
+			s.stroke_width = w
+		}
+	}
+	switch a["fill-rule"] {
+	case "nonzero":
+		s.fill_rule = vecscene.NonZero
+	case "evenodd":
+		s.fill_rule = vecscene.EvenOdd
+	}
+	switch a["stroke-linejoin"] {
+	case "miter", "miter-clip", "arcs":
+		s.join = vecscene.MiterJoin
+	case "round":
+		s.join = vecscene.RoundJoin
+	case "bevel":
+		s.join = vecscene.BevelJoin
+	}
+	switch a["stroke-linecap"] {
+	case "butt":
+		s.cap = vecscene.ButtCap
+	case "round":
+		s.cap = vecscene.RoundCap
+	case "square":
+		s.cap = vecscene.SquareCap
+	}
+	return s
+}
+
+// paint parses a fill or stroke: a color, currentColor, none, or a reference to a paint server, which draws in the
+// color after it or nothing.
+func (s style) paint(v string) (paint, error) {
+	switch v {
+	case "none":
+		return paint{}, nil
+	case "currentColor":
+		return paint{color: s.color, set: true}, nil
+	case "inherit":
+		return paint{}, errors.New("inherited")
+	}
+	if strings.HasPrefix(v, "url(") {
+		_, fallback, ok := strings.Cut(v, ")")
+		if fallback = strings.TrimSpace(fallback); !ok || fallback == "" {
+			return paint{}, nil
+		}
+		return s.paint(fallback)
+	}
+	c, err := parse_color(v)
+	if err != nil {
+		return paint{}, err
+	}
+	return paint{color: c, set: true}, nil
+}
+
+// named are the colors of CSS's basic keywords and a few more, the ones which turn up in icons.
+var named = map[string][3]uint8{
+	"black": {0, 0, 0}, "silver": {192, 192, 192}, "gray": {128, 128, 128}, "grey": {128, 128, 128},
+	"white": {255, 255, 255}, "maroon": {128, 0, 0}, "red": {255, 0, 0}, "purple": {128, 0, 128},
+	"fuchsia": {255, 0, 255}, "magenta": {255, 0, 255}, "green": {0, 128, 0}, "lime": {0, 255, 0},
+	"olive": {128, 128, 0}, "yellow": {255, 255, 0}, "navy": {0, 0, 128}, "blue": {0, 0, 255},
+	"teal": {0, 128, 128}, "aqua": {0, 255, 255}, "cyan": {0, 255, 255}, "orange": {255, 165, 0},
+	"gold": {255, 215, 0}, "pink": {255, 192, 203}, "brown": {165, 42, 42}, "darkgray": {169, 169, 169},
+	"darkgrey": {169, 169, 169}, "lightgray": {211, 211, 211}, "lightgrey": {211, 211, 211},
+	"dimgray": {105, 105, 105}, "dimgrey": {105, 105, 105}, "whitesmoke": {245, 245, 245},
+	"crimson": {220, 20, 60}, "tomato": {255, 99, 71}, "coral": {255, 127, 80}, "salmon": {250, 128, 114},
+	"skyblue": {135, 206, 235}, "steelblue": {70, 130, 180}, "royalblue": {65, 105, 225},
+	"dodgerblue": {30, 144, 255}, "darkblue": {0, 0, 139}, "darkgreen": {0, 100, 0},
+	"forestgreen": {34, 139, 34}, "seagreen": {46, 139, 87}, "indigo": {75, 0, 130}, "violet": {238, 130, 238},
+	"tan": {210, 180, 140}, "beige": {245, 245, 220}, "ivory": {255, 255, 240}, "khaki": {240, 230, 140},
+}
+
+// parse_color parses a color: #rgb, #rgba, #rrggbb, #rrggbbaa, rgb() or rgba() of numbers or percentages, a named
+// color or transparent. It's not premultiplied.
+func parse_color(v string) (vec4, error) {
+	v = strings.TrimSpace(v)
+	lower := strings.ToLower(v)
+	if lower == "transparent" {
+		return vec4{}, nil
+	}
+	if c, ok := named[lower]; ok {
+		return vec4{float(c[0]) / 255, float(c[1]) / 255, float(c[2]) / 255, 1}, nil
+	}
+	if hex, ok := strings.CutPrefix(v, "#"); ok {
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return vec4{}, fmt.Errorf("bad color %q", v)
+		}
+		nibble := func(shift int) float {
+			return float(n>>shift&0xf) * 17 / 255
+		}
+		byte_ := func(shift int) float {
+			return float(n>>shift&0xff) / 255
+		}
+		switch len(hex) {
+		case 3:
+			return vec4{nibble(8), nibble(4), nibble(0), 1}, nil
+		case 4:
+			return vec4{nibble(12), nibble(8), nibble(4), nibble(0)}, nil
+		case 6:
+			return vec4{byte_(16), byte_(8), byte_(0), 1}, nil
+		case 8:
+			return vec4{byte_(24), byte_(16), byte_(8), byte_(0)}, nil
+		}
+		return vec4{}, fmt.Errorf("bad color %q", v)
+	}
+	if args, ok := strings.CutPrefix(lower, "rgb"); ok {
+		args = strings.TrimPrefix(args, "a")
+		args, ok = strings.CutPrefix(strings.TrimSpace(args), "(")
+		args, ok2 := strings.CutSuffix(args, ")")
+		if !ok || !ok2 {
+			return vec4{}, fmt.Errorf("bad color %q", v)
+		}
+		// the modern syntax separates the alpha with a slash
+		fields := strings.FieldsFunc(args, func(r rune) bool {
+			return r == ',' || r == '/' || r == ' ' || r == '\t'
+		})
+		if len(fields) != 3 && len(fields) != 4 {
+			return vec4{}, fmt.Errorf("bad color %q", v)
+		}
+		c := vec4{0, 0, 0, 1}
+		for i, f := range fields {
+			percent := strings.HasSuffix(f, "%")
+			n, err := strconv.ParseFloat(strings.TrimSuffix(f, "%"), 32)
+			if err != nil {
+				return vec4{}, fmt.Errorf("bad color %q", v)
+			}
+			switch {
+			case percent:
+				n /= 100
+			case i < 3:
+				n /= 255
+			}
+			c[i] = float(min(max(n, 0), 1))
+		}
+		return c, nil
+	}
+	return vec4{}, fmt.Errorf("unknown color %q", v)
+}
+
+// units are how many pixels a unit of length is.
+var units = map[string]float{
+	"": 1, "px": 1, "pt": 4.0 / 3, "pc": 16, "in": 96, "cm": 96 / 2.54, "mm": 96 / 25.4, "em": 16, "ex": 8,
+}
+
+// parse_length parses a length in pixels. Percentages aren't, they're of a viewport this doesn't have.
+func parse_length(v string) (float, error) {
+	sc := scanner{s: strings.TrimSpace(v)}
+	n, err := sc.number()
+	if err != nil {
+		return 0, err
+	}
+	unit, ok := units[strings.TrimSpace(sc.s[sc.i:])]
+	if !ok {
+		return 0, fmt.Errorf("bad length %q", v)
+	}
+	return n * unit, nil
+}
+
+// parse_number parses a number, or a percentage as a fraction, what opacities may be.
+func parse_number(v string) (float, error) {
+	v = strings.TrimSpace(v)
+	if p, ok := strings.CutSuffix(v, "%"); ok {
+		n, err := strconv.ParseFloat(p, 32)
+		return float(n / 100), err
+	}
+	n, err := strconv.ParseFloat(v, 32)
+	return float(n), err
+}
+
+// parse_numbers parses a list of numbers, separated by spaces, commas or nothing where the next starts with a sign
+// or a second decimal point.
+func parse_numbers(v string) ([]float, error) {
+	var numbers []float
+	sc := scanner{s: v}
+	for sc.skip(); !sc.done(); sc.skip() {
+		n, err := sc.number()
+		if err != nil {
+			return numbers, err
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// parse_transform parses a list of transforms, the matrix doing the last first.
+func parse_transform(v string) (mat3, error) {
+	m := mgl32.Ident3()
+	rest := strings.TrimSpace(v)
+	for rest != "" {
+		name, args, ok := strings.Cut(rest, "(")
+		if !ok {
+			return m, fmt.Errorf("bad transform %q", v)
+		}
+		args, rest, ok = strings.Cut(args, ")")
+		if !ok {
+			return m, fmt.Errorf("bad transform %q", v)
+		}
+		rest = strings.TrimLeft(rest, " \t\r\n,")
+		n, err := parse_numbers(args)
+		if err != nil {
+			return m, fmt.Errorf("bad transform %q", v)
+		}
+		arity := func(counts ...int) bool {
+			for _, c := range counts {
+				if len(n) == c {
+					return true
+				}
+			}
+			return false
+		}
+		radians := func(degrees float) float {
+			return degrees * math.Pi / 180
+		}
+		var t mat3
+		switch name = strings.TrimSpace(name); {
+		case name == "matrix" && arity(6):
+			t = mat3{n[0], n[1], 0, n[2], n[3], 0, n[4], n[5], 1}
+		case name == "translate" && arity(1, 2):
+			n = append(n, 0)
+			t = mgl32.Translate2D(n[0], n[1])
+		case name == "scale" && arity(1, 2):
+			n = append(n, n[0])
+			t = mgl32.Scale2D(n[0], n[1])
+		case name == "rotate" && arity(1, 3):
+			t = mgl32.HomogRotate2D(radians(n[0]))
+			if len(n) == 3 {
+				t = mgl32.Translate2D(n[1], n[2]).Mul3(t).Mul3(mgl32.Translate2D(-n[1], -n[2]))
+			}
+		case name == "skewX" && arity(1):
+			t = mgl32.Ident3()
+			t[3] = float(math.Tan(float64(radians(n[0]))))
+		case name == "skewY" && arity(1):
+			t = mgl32.Ident3()
+			t[1] = float(math.Tan(float64(radians(n[0]))))
+		default:
+			return m, fmt.Errorf("bad transform %q", v)
+		}
+		m = m.Mul3(t)
+	}
+	return m, nil
+}
+
+// scanner reads the numbers and flags of attribute values.
+type scanner struct {
+	s string
+	i int
+}
+
+func (sc *scanner) done() bool {
+	return sc.i >= len(sc.s)
+}
+
+// skip skips spaces and a comma between them.
+func (sc *scanner) skip() {
+	comma := false
+	for !sc.done() {
+		switch c := sc.s[sc.i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		case c == ',' && !comma:
+			comma = true
+		default:
+			return
+		}
+		sc.i++
+	}
+}
+
+// number reads a number, stopping where the next one starts.
+func (sc *scanner) number() (float, error) {
+	from := sc.i
+	digits := func() int {
+		n := 0
+		for !sc.done() && sc.s[sc.i] >= '0' && sc.s[sc.i] <= '9' {
+			sc.i++
+			n++
+		}
+		return n
+	}
+	if !sc.done() && (sc.s[sc.i] == '+' || sc.s[sc.i] == '-') {
+		sc.i++
+	}
+	n := digits()
+	if !sc.done() && sc.s[sc.i] == '.' {
+		sc.i++
+		n += digits()
+	}
+	if n == 0 {
+		sc.i = from
+		return 0, fmt.Errorf("expected a number at %q", sc.s[from:])
+	}
+	// an exponent, but not the e of a unit like em or ex
+	if !sc.done() && (sc.s[sc.i] == 'e' || sc.s[sc.i] == 'E') {
+		at := sc.i
+		sc.i++
+		if !sc.done() && (sc.s[sc.i] == '+' || sc.s[sc.i] == '-') {
+			sc.i++
+		}
+		if digits() == 0 {
+			sc.i = at
+		}
+	}
+	f, err := strconv.ParseFloat(sc.s[from:sc.i], 32)
+	if err != nil {
+		return 0, err
+	}
+	return float(f), nil
+}
+
+// flag reads a flag of an arc, a 0 or 1 which needn't be separated from what follows.
+func (sc *scanner) flag() (bool, error) {
+	if sc.done() || (sc.s[sc.i] != '0' && sc.s[sc.i] != '1') {
+		return false, fmt.Errorf("expected a flag at %q", sc.s[sc.i:])
+	}
+	sc.i++
+	return sc.s[sc.i-1] == '1', nil
+}
@@ -0,0 +1,106 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecscene"
+)
+
+// Mesh makes a flat mesh of the image facing +z, a pixel of it a unit, centered on the origin with y up. Curves are
+// flattened to within `tolerance` pixels. Every fill and stroke is `layer` in front of the one painted before it,
+// so they sort in the order they're painted, and its triangles have a material for its color: the colors,
+// premultiplied, are returned in the order of the mesh's Materials, which are named after them. Texture
+// coordinates map the image onto a texture, 0, 0 its top left. What doesn't fit 16 bit indices is left out.
+func (img *Image) Mesh(tolerance, layer float) (*mesh.Mesh, []vec4) {
+	b := &mesh_builder{
+		m:         &mesh.Mesh{},
+		tolerance: max(tolerance, 1e-3),
+		layer:     layer,
+		size:      vec2{img.Width, img.Height},
+	}
+	b.node(img.Root, mgl32.Ident3(), 1)
+	b.m.ComputeBounds()
+	return b.m, b.colors
+}
+
+type mesh_builder struct {
+	m         *mesh.Mesh
+	colors    []vec4
+	tolerance float
+	layer     float
+	size      vec2
+	// painted counts the fills and strokes so far
+	painted int
+
+	lines     []vecscene.Polyline
+	triangles vecscene.Triangles
+}
+
+func (b *mesh_builder) node(n *vecscene.Node, parent mat3, opacity float) {
+	if n.Hidden {
+		return
+	}
+	m := parent.Mul3(n.Transform)
+	opacity *= n.Opacity
+	if n.Path != nil && (n.Fill != nil || n.Stroke != nil) {
+		scale := max(vec2{m[0], m[1]}.Len(), vec2{m[3], m[4]}.Len(), 1e-6)
+		tolerance := b.tolerance / scale
+		b.lines = n.Path.Flatten(tolerance, b.lines[:0])
+		if n.Fill != nil {
+			b.triangles.Reset()
+			vecscene.Fill(b.lines, n.Fill.Rule, &b.triangles)
+			b.add(&m, n.Fill.Color.Mul(opacity))
+		}
+		if n.Stroke != nil {
+			b.triangles.Reset()
+			vecscene.Stroke(b.lines, &n.Stroke.StrokeStyle, tolerance, &b.triangles)
+			b.add(&m, n.Stroke.Color.Mul(opacity))
+		}
+	}
+	for _, c := range n.Children {
+		b.node(c, m, opacity)
+	}
+}
+
+// add adds the triangles built, placed by `m`, in `color`.
+func (b *mesh_builder) add(m *mat3, color vec4) {
+	t := &b.triangles
+	if len(t.Indices) == 0 || color[3] <= 0 || len(b.m.Points)+len(t.Points) > math.MaxUint16+1 {
+		return
+	}
+	material := slices.Index(b.colors, color)
+	if material < 0 {
+		material = len(b.colors)
+		b.colors = append(b.colors, color)
+		a := color[3]
+		b.m.Materials = append(b.m.Materials, fmt.Sprintf("#%02x%02x%02x%02x",
+			uint8(color[0]/a*255+0.5), uint8(color[1]/a*255+0.5), uint8(color[2]/a*255+0.5), uint8(a*255+0.5)))
+	}
+
+	z := float(b.painted) * b.layer
+	b.painted++
+	base := len(b.m.Points)
+	for _, p := range t.Points {
+		x, y := m[0]*p[0]+m[3]*p[1]+m[6], m[1]*p[0]+m[4]*p[1]+m[7]
+		b.m.Points = append(b.m.Points, vec3{x - b.size[0]/2, b.size[1]/2 - y, z})
+		b.m.Texcoords = append(b.m.Texcoords, vec2{x / b.size[0], y / b.size[1]})
+	}
+	for i := 0; i < len(t.Indices); i += 3 {
+		p1, p2, p3 := uint16(base)+t.Indices[i], uint16(base)+t.Indices[i+1], uint16(base)+t.Indices[i+2]
+		// counter-clockwise seen from +z
+		a, c, d := b.m.Points[p1], b.m.Points[p2], b.m.Points[p3]
+		if (c[0]-a[0])*(d[1]-a[1])-(c[1]-a[1])*(d[0]-a[0]) < 0 {
+			p2, p3 = p3, p2
+		}
+		b.m.Triangles = append(b.m.Triangles, mesh.Triangle{
+			P1: p1, P2: p2, P3: p3,
+			T1: p1, T2: p2, T3: p3,
+			Material: uint16(material),
+		})
+	}
+}
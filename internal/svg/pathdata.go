@@ -0,0 +1,146 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecscene"
+)
+
+// parse_path adds the path data `d` to `p`: moves, lines, cubic and quadratic curves and their smooth forms, arcs
+// and closes, absolute in capitals and relative in lower case. On an error what came before it is kept.
+func parse_path(d string, p *vecscene.Path) error {
+	sc := scanner{s: d}
+	// command is the one being repeated and previous the last one done, in capitals
+	var command, previous byte
+	// at is where the path is and start where its subpath started, control the last control point of the last
+	// curve, which the smooth curves reflect
+	var at, start, control vec2
+	for sc.skip(); !sc.done(); sc.skip() {
+		if c := sc.s[sc.i]; (c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') && c != 'e' && c != 'E' {
+			command = c
+			sc.i++
+			sc.skip()
+		} else if command == 0 {
+			return fmt.Errorf("expected a command at %q", sc.s[sc.i:])
+		}
+		var origin vec2
+		if command >= 'a' {
+			origin = at
+		}
+		var args [6]float
+		read := func(n int) error {
+			for i := range n {
+				if i > 0 {
+					sc.skip()
+				}
+				v, err := sc.number()
+				if err != nil {
+					return err
+				}
+				args[i] = v
+			}
+			return nil
+		}
+		point := func(i int) vec2 {
+			return vec2{args[i], args[i+1]}.Add(origin)
+		}
+		// reflected is the last control point mirrored about where the path is, if the last command was one of
+		// `curves`, else where the path is
+		reflected := func(curves string) vec2 {
+			for i := range len(curves) {
+				if previous == curves[i] {
+					return at.Mul(2).Sub(control)
+				}
+			}
+			return at
+		}
+
+		upper := command &^ 0x20
+		switch upper {
+		case 'M':
+			if err := read(2); err != nil {
+				return err
+			}
+			at = point(0)
+			start = at
+			p.MoveTo(at[0], at[1])
+			// pairs after a move are lines, L and l coming right before M and m
+			command--
+		case 'L':
+			if err := read(2); err != nil {
+				return err
+			}
+			at = point(0)
+			p.LineTo(at[0], at[1])
+		case 'H':
+			if err := read(1); err != nil {
+				return err
+			}
+			at[0] = args[0] + origin[0]
+			p.LineTo(at[0], at[1])
+		case 'V':
+			if err := read(1); err != nil {
+				return err
+			}
+			at[1] = args[0] + origin[1]
+			p.LineTo(at[0], at[1])
+		case 'C':
+			if err := read(6); err != nil {
+				return err
+			}
+			c1, c2 := point(0), point(2)
+			at, control = point(4), c2
+			p.CubeTo(c1[0], c1[1], c2[0], c2[1], at[0], at[1])
+		case 'S':
+			if err := read(4); err != nil {
+				return err
+			}
+			c1, c2 := reflected("CS"), point(0)
+			at, control = point(2), c2
+			p.CubeTo(c1[0], c1[1], c2[0], c2[1], at[0], at[1])
+		case 'Q':
+			if err := read(4); err != nil {
+				return err
+			}
+			control, at = point(0), point(2)
+			p.QuadTo(control[0], control[1], at[0], at[1])
+		case 'T':
+			if err := read(2); err != nil {
+				return err
+			}
+			control, at = reflected("QT"), point(0)
+			p.QuadTo(control[0], control[1], at[0], at[1])
+		case 'A':
+			if err := read(3); err != nil {
+				return err
+			}
+			rx, ry, rotation := args[0], args[1], args[2]*math.Pi/180
+			sc.skip()
+			large, err := sc.flag()
+			if err != nil {
+				return err
+			}
+			sc.skip()
+			sweep, err := sc.flag()
+			if err != nil {
+				return err
+			}
+			sc.skip()
+			if err := read(2); err != nil {
+				return err
+			}
+			at = point(0)
+			p.ArcTo(rx, ry, rotation, large, sweep, at[0], at[1])
+		case 'Z':
+			p.Close()
+			at = start
+			// a close takes nothing, repeating it would never end
+			command = 0
+		default:
+			return fmt.Errorf("unknown path command %q", command)
+		}
+		previous = upper
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+// Package svg imports the part of SVG icons and logos are drawn with: paths, rectangles, circles, ellipses, lines,
+// polylines and polygons, filled and stroked in solid colors, in groups and nested documents with transforms and
+// opacity. Paint comes from presentation attributes and from style attributes, and is inherited down the tree.
+// What else there is, like gradients, patterns, text, clipping, masks, <use> and stylesheets, is skipped: a paint
+// referencing a gradient draws in its fallback color, or not at all.
+//
+// A document becomes a tree of package vecscene nodes, named after the ids of its elements, to draw as a scene, or
+// a flat mesh for the pipeline with Mesh.
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecscene"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
+	mat3  = mgl32.Mat3
+)
+
+// Image is an SVG document.
+type Image struct {
+	// Width and Height are its size in pixels, those of its view box when it doesn't say.
+	Width, Height float
+	// ViewBox is the rectangle of user space shown, x, y, width and height.
+	ViewBox [4]float
+	// Root draws the document from 0, 0 to Width, Height, its view box fitted into that keeping its aspect.
+	Root *vecscene.Node
+}
+
+// Scene makes a scene drawing the image. The scene shares the nodes of the image, changing them changes it.
+func (img *Image) Scene() *vecscene.Scene {
+	s := vecscene.New()
+	s.Root.Add(img.Root)
+	return s
+}
+
+// Load reads and decodes the SVG file `name`.
+func Load(fsys fs.FS, name string) (*Image, error) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	img, err := Decode(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return img, nil
+}
+
+// Decode decodes an SVG document.
+func Decode(src []byte) (*Image, error) {
+	d := xml.NewDecoder(bytes.NewReader(src))
+	// documents often declare entities or encodings the decoder would choke on and which don't matter here
+	d.Strict = false
+	d.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) {
+		return r, nil
+	}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, errors.New("no <svg> element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Local != "svg" {
+				return nil, fmt.Errorf("the document is a <%s>, not an <svg>", start.Name.Local)
+			}
+			p := &parser{decoder: d}
+			img := &Image{}
+			img.Root, img.Width, img.Height, img.ViewBox, err = p.svg(start, default_style())
+			if err != nil {
+				return nil, err
+			}
+			return img, nil
+		}
+	}
+}
+
+type parser struct {
+	decoder *xml.Decoder
+}
+
+// attrs are the attributes of an element, those of its style attribute over the rest.
+type attrs map[string]string
+
+func attributes(start xml.StartElement) attrs {
+	a := make(attrs, len(start.Attr))
+	for _, attr := range start.Attr {
+		// namespaced attributes, like those of inkscape:, aren't SVG's, but xlink:href is
+		if attr.Name.Space == "" || attr.Name.Local == "href" {
+			a[attr.Name.Local] = strings.TrimSpace(attr.Value)
+		}
+	}
+	if style, ok := a["style"]; ok {
+		for _, decl := range strings.Split(style, ";") {
+			name, value, ok := strings.Cut(decl, ":")
+			if ok {
+				value, _, _ = strings.Cut(value, "!important")
+				a[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return a
+}
+
+// svg parses an <svg> element: the node drawing it at its size, its size and its view box.
+func (p *parser) svg(start xml.StartElement, inherited style) (n *vecscene.Node, w, h float, view [4]float, err error) {
+	a := attributes(start)
+	st := inherited.apply(a)
+	has_view := false
+	if v, ok := a["viewBox"]; ok {
+		numbers, err := parse_numbers(v)
+		if err != nil || len(numbers) != 4 {
+			return nil, 0, 0, view, fmt.Errorf("bad viewBox %q", v)
+		}
+		copy(view[:], numbers)
+		has_view = view[2] > 0 && view[3] > 0
+	}
+	// sizes in percent are of a viewport this doesn't have, they're left to the view box
+	size := func(name string) float {
+		if v, ok := a[name]; ok && !strings.HasSuffix(v, "%") {
+			l, err := parse_length(v)
+			if err == nil && l > 0 {
+				return l
+			}
+		}
+		return 0
+	}
+	w, h = size("width"), size("height")
+	switch {
+	case !has_view && (w == 0 || h == 0):
+		w, h = max(w, 300), max(h, 150)
+		view = [4]float{0, 0, w, h}
+	case !has_view:
+		view = [4]float{0, 0, w, h}
+	case w == 0 && h == 0:
+		w, h = view[2], view[3]
+	case w == 0:
+		w = h * view[2] / view[3]
+	case h == 0:
+		h = w * view[3] / view[2]
+	}
+
+	n = vecscene.NewNode(a["id"], nil)
+	// the view box is fitted in and centered, as preserveAspectRatio's default has it
+	scale := min(w/view[2], h/view[3])
+	n.Transform = mgl32.Translate2D((w-view[2]*scale)/2-view[0]*scale, (h-view[3]*scale)/2-view[1]*scale).
+		Mul3(mgl32.Scale2D(scale, scale))
+	n.Opacity = st.opacity
+	if err := p.children(n, st); err != nil {
+		return nil, 0, 0, view, err
+	}
+	return n, w, h, view, nil
+}
+
+// children parses the elements in the one just started, up to its end, adding what they draw to `parent`.
+func (p *parser) children(parent *vecscene.Node, st style) error {
+	for {
+		tok, err := p.decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return errors.New("the document ends early")
+			}
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			n, err := p.element(tok, st)
+			if err != nil {
+				return fmt.Errorf("<%s>: %w", tok.Name.Local, err)
+			}
+			if n != nil {
+				parent.Add(n)
+			}
+		}
+	}
+}
+
+// element parses an element and what's in it, the node drawing it or nil.
+func (p *parser) element(start xml.StartElement, inherited style) (*vecscene.Node, error) {
+	a := attributes(start)
+	if a["display"] == "none" {
+		return nil, p.decoder.Skip()
+	}
+
+	var path vecscene.Path
+	switch start.Name.Local {
+	case "svg":
+		n, _, _, _, err := p.svg(start, inherited)
+		if err != nil {
+			return nil, err
+		}
+		// a nested document is placed at its x, y, and should be clipped to its size, which isn't
+		n.Transform = mgl32.Translate2D(a.length("x"), a.length("y")).Mul3(n.Transform)
+		return n, nil
+	case "g", "a", "switch":
+		st := inherited.apply(a)
+		n, err := node(a, st, nil)
+		if err != nil {
+			return nil, err
+		}
+		return n, p.children(n, st)
+	case "path":
+		// bad path data draws what it had up to the error, as SVG has it
+		_ = parse_path(a["d"], &path)
+	case "rect":
+		rect(&path, a.length("x"), a.length("y"), a.length("width"), a.length("height"), a.optional_length("rx"), a.optional_length("ry"))
+	case "circle":
+		if r := a.length("r"); r > 0 {
+			path.Circle(a.length("cx"), a.length("cy"), r)
+		}
+	case "ellipse":
+		rx, ry := a.optional_length("rx"), a.optional_length("ry")
+		// either radius alone is both, as SVG 2 has it
+		if rx < 0 {
+			rx = ry
+		}
+		if ry < 0 {
+			ry = rx
+		}
+		if rx > 0 && ry > 0 {
+			path.Ellipse(a.length("cx"), a.length("cy"), rx, ry)
+		}
+	case "line":
+		path.MoveTo(a.length("x1"), a.length("y1"))
+		path.LineTo(a.length("x2"), a.length("y2"))
+	case "polyline", "polygon":
+		// and so do bad points
+		points, _ := parse_numbers(a["points"])
+		for i := 0; i+1 < len(points); i += 2 {
+			if i == 0 {
+				path.MoveTo(points[i], points[i+1])
+			} else {
+				path.LineTo(points[i], points[i+1])
+			}
+		}
+		if start.Name.Local == "polygon" && len(points) >= 2 {
+			path.Close()
+		}
+	default:
+		// what isn't drawn, or isn't supported, is skipped with everything in it
+		return nil, p.decoder.Skip()
+	}
+	// a shape has nothing in it worth parsing
+	if err := p.decoder.Skip(); err != nil {
+		return nil, err
+	}
+	if path.Empty() {
+		return nil, nil
+	}
+	return node(a, inherited.apply(a), &path)
+}
+
+// node makes the node of an element, drawing `path` if it's a shape.
+func node(a attrs, st style, path *vecscene.Path) (*vecscene.Node, error) {
+	n := vecscene.NewNode(a["id"], nil)
+	if v, ok := a["transform"]; ok {
+		m, err := parse_transform(v)
+		if err != nil {
+			return nil, err
+		}
+		n.Transform = m
+	}
+	n.Opacity = st.opacity
+	if a["visibility"] == "hidden" || a["visibility"] == "collapse" {
+		n.Hidden = true
+	}
+	if path == nil {
+		return n, nil
+	}
+	n.Path = path
+	if st.fill.set {
+		n.Fill = &vecscene.FillPaint{Color: premultiply(st.fill.color, st.fill_opacity), Rule: st.fill_rule}
+	}
+	if st.stroke.set && st.stroke_width > 0 {
+		n.Stroke = &vecscene.StrokePaint{
+			Color: premultiply(st.stroke.color, st.stroke_opacity),
+			StrokeStyle: vecscene.StrokeStyle{
+				Width:      st.stroke_width,
+				Join:       st.join,
+				Cap:        st.cap,
+				MiterLimit: st.miter_limit,
+			},
+		}
+	}
+	return n, nil
+}
+
+// rect adds a rectangle with its corners rounded by `rx` and `ry`, each missing one the other, as SVG has it.
+func rect(p *vecscene.Path, x, y, w, h, rx, ry float) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	if rx < 0 {
+		rx = ry
+	}
+	if ry < 0 {
+		ry = rx
+	}
+	rx, ry = min(max(rx, 0), w/2), min(max(ry, 0), h/2)
+	if rx == 0 || ry == 0 {
+		p.Rect(x, y, w, h)
+		return
+	}
+	p.MoveTo(x+rx, y)
+	p.LineTo(x+w-rx, y)
+	p.ArcTo(rx, ry, 0, false, true, x+w, y+ry)
+	p.LineTo(x+w, y+h-ry)
+	p.ArcTo(rx, ry, 0, false, true, x+w-rx, y+h)
+	p.LineTo(x+rx, y+h)
+	p.ArcTo(rx, ry, 0, false, true, x, y+h-ry)
+	p.LineTo(x, y+ry)
+	p.ArcTo(rx, ry, 0, false, true, x+rx, y)
+	p.Close()
+}
+
+// length is the attribute `name` as a length, 0 when it's missing or bad.
+func (a attrs) length(name string) float {
+	l, _ := parse_length(a[name])
+	return l
+}
+
+// optional_length is the attribute `name` as a length, -1 when it's missing, bad or "auto".
+func (a attrs) optional_length(name string) float {
+	v, ok := a[name]
+	if !ok {
+		return -1
+	}
+	l, err := parse_length(v)
+	if err != nil {
+		return -1
+	}
+	return l
+}
+
+func premultiply(c vec4, opacity float) vec4 {
+	a := c[3] * min(max(opacity, 0), 1)
+	return vec4{c[0] * a, c[1] * a, c[2] * a, a}
+}
@@ -0,0 +1,295 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecscene"
+)
+
+// filled is the area `p` encloses by the non-zero rule.
+func filled(p *vecscene.Path) float {
+	var t vecscene.Triangles
+	vecscene.Fill(p.Flatten(0.01, nil), vecscene.NonZero, &t)
+	var a float
+	for i := 0; i < len(t.Indices); i += 3 {
+		p, q, r := t.Points[t.Indices[i]], t.Points[t.Indices[i+1]], t.Points[t.Indices[i+2]]
+		a += float(math.Abs(float64((q[0]-p[0])*(r[1]-p[1])-(q[1]-p[1])*(r[0]-p[0])))) / 2
+	}
+	return a
+}
+
+func near(a, b, tolerance float) bool {
+	return math.Abs(float64(a-b)) <= float64(tolerance)
+}
+
+func TestParsePath(t *testing.T) {
+	for _, tc := range []struct {
+		d    string
+		area float
+		// end is where the path ends up
+		end vec2
+	}{
+		{"M10 10h10v10H10z", 100, vec2{10, 10}},
+		{"m10,10 l10,0 0,10 -10,0z", 100, vec2{10, 10}},
+		// numbers run together where a sign or a second point starts the next
+		{"M0 0L10-0 10-10.5.5-10.5z", (10 + 9.5) / 2 * 10.5, vec2{0, 0}},
+		{"M0,0 1e1,0 10,1E1", 50, vec2{10, 10}},
+		// a half circle of radius 5 by an arc with its flags run together
+		{"M0 0a5 5 0 0110 0z", math.Pi * 25 / 2, vec2{0, 0}},
+		{"M0 0A5 5 0 1 0 10 0Z", math.Pi * 25 / 2, vec2{0, 0}},
+		// a smooth cubic reflects the control point of the one before, making the same curve mirrored
+		{"M0 0C0 10 10 10 10 0S20 -10 20 0", 0, vec2{20, 0}},
+		{"M0 0Q5 10 10 0T20 0", 0, vec2{20, 0}},
+		{"M0 0Q5 10 10 0t10 0z", 0, vec2{0, 0}},
+	} {
+		var p vecscene.Path
+		if err := parse_path(tc.d, &p); err != nil {
+			t.Errorf("%q: %v", tc.d, err)
+			continue
+		}
+		if a := filled(&p); tc.area > 0 && !near(a, tc.area, 0.1) {
+			t.Errorf("%q encloses %v, not %v", tc.d, a, tc.area)
+		}
+		if x, y := p.Current(); !near(x, tc.end[0], 1e-4) || !near(y, tc.end[1], 1e-4) {
+			t.Errorf("%q ends at %v, %v", tc.d, x, y)
+		}
+	}
+
+	// the curves before and after the smooth ones are mirror images, enclosing as much above the line as below
+	for _, d := range []string{"M0 0C0 10 10 10 10 0S20 -10 20 0", "M0 0Q5 10 10 0T20 0"} {
+		var p vecscene.Path
+		parse_path(d, &p)
+		var above, below float
+		for _, q := range p.Flatten(0.01, nil)[0].Points {
+			above, below = max(above, q[1]), min(below, q[1])
+		}
+		if !near(above, -below, 1e-3) || above == 0 {
+			t.Errorf("%q reaches %v above and %v below", d, above, below)
+		}
+	}
+
+	// bad path data keeps what came before the error
+	var p vecscene.Path
+	if err := parse_path("M0 0L10 0L10 10L0 10zL5 x", &p); err == nil {
+		t.Errorf("bad path data parsed")
+	}
+	if a := filled(&p); !near(a, 100, 1e-3) {
+		t.Errorf("the square before the error encloses %v", a)
+	}
+	for _, d := range []string{"10 10", "M10", "M0 0 A1 1 0 2 0 1 1", "M0 0 X"} {
+		var p vecscene.Path
+		if err := parse_path(d, &p); err == nil {
+			t.Errorf("%q parsed", d)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want vec4
+	}{
+		{"#fff", vec4{1, 1, 1, 1}},
+		{"#F00", vec4{1, 0, 0, 1}},
+		{"#00ff0080", vec4{0, 1, 0, 128.0 / 255}},
+		{"#0000ff", vec4{0, 0, 1, 1}},
+		{"rgb(255, 0, 0)", vec4{1, 0, 0, 1}},
+		{"rgba(0,0,255,.5)", vec4{0, 0, 1, 0.5}},
+		{"rgb(100%, 50%, 0%)", vec4{1, 0.5, 0, 1}},
+		{"rgb(0 255 0 / 25%)", vec4{0, 1, 0, 0.25}},
+		{"Red", vec4{1, 0, 0, 1}},
+		{"transparent", vec4{}},
+	} {
+		c, err := parse_color(tc.s)
+		if err != nil {
+			t.Errorf("%q: %v", tc.s, err)
+			continue
+		}
+		for i := range 4 {
+			if !near(c[i], tc.want[i], 1e-3) {
+				t.Errorf("%q is %v, not %v", tc.s, c, tc.want)
+				break
+			}
+		}
+	}
+	for _, s := range []string{"#12345", "#ggg", "rgb(1,2)", "chartreusy", "rgb(1,2,3"} {
+		if _, err := parse_color(s); err == nil {
+			t.Errorf("%q parsed", s)
+		}
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		from, to vec2
+	}{
+		{"translate(10,20) scale(2)", vec2{1, 1}, vec2{12, 22}},
+		{"scale(2, 3)", vec2{1, 1}, vec2{2, 3}},
+		{"translate(5)", vec2{1, 1}, vec2{6, 1}},
+		{"rotate(90 10 10)", vec2{20, 10}, vec2{10, 20}},
+		{"rotate(-90)", vec2{1, 0}, vec2{0, -1}},
+		{"matrix(1 0 0 1 3 4)", vec2{1, 1}, vec2{4, 5}},
+		{"skewX(45)", vec2{0, 1}, vec2{1, 1}},
+		{"skewY(45)", vec2{1, 0}, vec2{1, 1}},
+		{" translate(1 1),rotate(180) ", vec2{1, 0}, vec2{0, 1}},
+	} {
+		m, err := parse_transform(tc.s)
+		if err != nil {
+			t.Errorf("%q: %v", tc.s, err)
+			continue
+		}
+		if got := m.Mul3x1(tc.from.Vec3(1)).Vec2(); got.Sub(tc.to).Len() > 1e-5 {
+			t.Errorf("%q takes %v to %v, not %v", tc.s, tc.from, got, tc.to)
+		}
+	}
+	for _, s := range []string{"translate(1,2,3)", "spin(4)", "scale(1"} {
+		if _, err := parse_transform(s); err == nil {
+			t.Errorf("%q parsed", s)
+		}
+	}
+}
+
+const document = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- made in a design tool -->
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+	width="200" height="100" viewBox="0 0 20 20">
+	<defs>
+		<linearGradient id="fade"><stop offset="0" stop-color="#fff"/></linearGradient>
+		<rect id="template" width="5" height="5"/>
+	</defs>
+	<title>A test</title>
+	<g id="layer" fill="#f00" stroke="blue" stroke-width="2" inkscape:label="Layer 1" transform="translate(1 2)">
+		<rect id="box" x="1" y="1" width="8" height="8" rx="2" style="stroke:none;fill-opacity:0.5"/>
+		<circle id="dot" cx="5" cy="5" r="3" fill="url(#fade) #00ff00" opacity="0.5"/>
+		<path id="gradient" d="M0 0h4v4z" fill="url(#fade)" stroke="none"/>
+		<polygon id="tri" points="0,0 10,0 5,5" stroke-linejoin="round" stroke-linecap="square"/>
+		<line id="rule" x1="0" y1="12" x2="20" y2="12" fill-rule="evenodd"/>
+		<rect id="gone" width="10" height="10" display="none"/>
+		<text x="0" y="0">words <tspan>more</tspan></text>
+	</g>
+	<ellipse id="oval" cx="10" cy="15" rx="4" fill="currentColor" color="rgb(0,0,255)"/>
+	<svg id="inner" x="10" y="0" width="10" height="10" viewBox="0 0 1 1"><rect width="1" height="1"/></svg>
+</svg>`
+
+func TestDecode(t *testing.T) {
+	img, err := Decode([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 200 || img.Height != 100 || img.ViewBox != [4]float{0, 0, 20, 20} {
+		t.Errorf("the image is %vx%v viewing %v", img.Width, img.Height, img.ViewBox)
+	}
+	// the view box is fitted into the height and centered along the width
+	if at := img.Root.Transform.Mul3x1(vec2{20, 20}.Vec3(1)).Vec2(); !at.ApproxEqual(vec2{150, 100}) {
+		t.Errorf("the corner of the view box is at %v", at)
+	}
+
+	for _, name := range []string{"template", "fade", "gone"} {
+		if img.Root.Find(name) != nil {
+			t.Errorf("%q was drawn", name)
+		}
+	}
+	// a gradient without a fallback paints nothing
+	if g := img.Root.Find("gradient"); g == nil || g.Fill != nil || g.Stroke != nil {
+		t.Errorf("the gradient is %+v", g)
+	}
+	layer := img.Root.Find("layer")
+	if layer == nil || layer.Transform != mgl32.Translate2D(1, 2) {
+		t.Fatalf("the layer is %+v", layer)
+	}
+
+	red, blue, green := vec4{1, 0, 0, 1}, vec4{0, 0, 1, 1}, vec4{0, 1, 0, 1}
+	box := img.Root.Find("box")
+	if box.Fill == nil || box.Fill.Color != red.Mul(0.5) || box.Stroke != nil {
+		t.Errorf("the box is filled %+v and stroked %+v", box.Fill, box.Stroke)
+	}
+	if a := filled(box.Path); !near(a, 64-4*(4-math.Pi), 0.1) {
+		t.Errorf("the box with its corners rounded covers %v", a)
+	}
+	dot := img.Root.Find("dot")
+	if dot.Fill.Color != green || dot.Opacity != 0.5 || dot.Stroke.Color != blue || dot.Stroke.Width != 2 {
+		t.Errorf("the dot is filled %+v and stroked %+v at %v", dot.Fill, dot.Stroke, dot.Opacity)
+	}
+	tri := img.Root.Find("tri")
+	if tri.Fill.Color != red || tri.Stroke.Join != vecscene.RoundJoin || tri.Stroke.Cap != vecscene.SquareCap {
+		t.Errorf("the triangle is filled %+v and stroked %+v", tri.Fill, tri.Stroke)
+	}
+	if rule := img.Root.Find("rule"); rule.Fill.Rule != vecscene.EvenOdd || rule.Opacity != 1 {
+		t.Errorf("the rule is filled %+v at %v", rule.Fill, rule.Opacity)
+	}
+	if oval := img.Root.Find("oval"); oval.Fill.Color != blue || !near(filled(oval.Path), math.Pi*16, 0.2) {
+		t.Errorf("the oval is filled %+v, covering %v", oval.Fill, filled(oval.Path))
+	}
+	inner := img.Root.Find("inner")
+	if at := inner.Transform.Mul3x1(vec2{1, 1}.Vec3(1)).Vec2(); !at.ApproxEqual(vec2{20, 10}) {
+		t.Errorf("the inner document's corner is at %v", at)
+	}
+
+	// the scene draws it all
+	s := img.Scene()
+	s.Build(mgl32.Ident3())
+	if s.Stats.Tessellated != 6 {
+		t.Errorf("the scene drew %+v", s.Stats)
+	}
+
+	for _, bad := range []string{
+		"",
+		`<html></html>`,
+		`<svg viewBox="0 0 1"></svg>`,
+		`<svg><g transform="spin(1)"/></svg>`,
+		`<svg><g>`,
+	} {
+		if _, err := Decode([]byte(bad)); err == nil {
+			t.Errorf("%q decoded", bad)
+		}
+	}
+}
+
+func TestMesh(t *testing.T) {
+	img, err := Decode([]byte(`<svg width="20" height="10">
+		<rect width="20" height="10" fill="#ff0000"/>
+		<circle cx="5" cy="5" r="4" fill="#0000ff" stroke="#ff0000"/>
+		<rect width="1" height="1" fill="none"/>
+	</svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, colors := img.Mesh(0.01, 0.1)
+	if len(colors) != 2 || colors[0] != (vec4{1, 0, 0, 1}) || colors[1] != (vec4{0, 0, 1, 1}) ||
+		len(m.Materials) != 2 || m.Materials[0] != "#ff0000ff" {
+		t.Fatalf("the mesh has the colors %v and materials %v", colors, m.Materials)
+	}
+	areas := make([]float, 2)
+	// the layers, their z by what's painted
+	layers := map[float]bool{}
+	for _, tri := range m.Triangles {
+		a, b, c := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+		area := ((b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])) / 2
+		if area < 0 {
+			t.Fatalf("a triangle faces -z")
+		}
+		areas[tri.Material] += area
+		layers[a[2]] = true
+		if uv := m.Texcoords[tri.T1]; uv[0] < 0 || uv[0] > 1 || uv[1] < 0 || uv[1] > 1 {
+			t.Errorf("texture coordinates %v", uv)
+		}
+	}
+	// the red is the rect and the stroke, a ring 1 wide around the circle whose joins overlap it a little
+	if want := float(200 + 2*math.Pi*4); !near(areas[0], want, 1) {
+		t.Errorf("the red covers %v, not %v", areas[0], want)
+	}
+	if want := float(math.Pi * 16); !near(areas[1], want, 0.2) {
+		t.Errorf("the blue covers %v, not %v", areas[1], want)
+	}
+	if len(layers) != 3 || !layers[0] || !layers[0.2] {
+		t.Errorf("the layers are %v", layers)
+	}
+	// centered on the origin with y up
+	if m.Sphere.Center.Vec2().Len() > 1e-4 {
+		t.Errorf("the mesh is centered on %v", m.Sphere.Center)
+	}
+}
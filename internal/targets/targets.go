@@ -0,0 +1,180 @@
+// Package targets pools offscreen images, so passes that need a scratch image every frame borrow one instead of
+// allocating it. Images are keyed by size and whether they're unmanaged, and images nobody asked for in a while are
+// given back to ebiten.
+package targets
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/invalidate"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/memory"
+)
+
+// Key describes an image of the pool. Images are only handed out for the same key they were created with.
+type Key struct {
+	Width, Height int
+	// Unmanaged images skip ebiten's atlas, see ebiten.NewImageOptions.
+	Unmanaged bool
+}
+
+func (k Key) String() string {
+	if k.Unmanaged {
+		return fmt.Sprintf("%dx%d unmanaged", k.Width, k.Height)
+	}
+	return fmt.Sprintf("%dx%d", k.Width, k.Height)
+}
+
+type idle struct {
+	image *ebiten.Image
+	// frame is the frame the image was put back in
+	frame int
+}
+
+// Stats count what the pool did. Hits and Misses are since the pool was created or Reset.
+type Stats struct {
+	Hits   int
+	Misses int
+	// InUse is the number of images handed out and not put back.
+	InUse int
+	// Free is the number of images waiting in the pool.
+	Free int
+	// Freed is the number of images deallocated, after sitting idle or by Purge.
+	Freed int
+}
+
+type Pool struct {
+	// MaxIdle is how many frames an image may sit in the pool before it's deallocated. Sizes that stop being asked
+	// for, like the old window size after a resize, don't hold on to memory forever.
+	MaxIdle int
+
+	free   map[Key][]idle
+	in_use map[*ebiten.Image]Key
+	frame  int
+	stats  Stats
+}
+
+func NewPool() *Pool {
+	return &Pool{
+		MaxIdle: 60,
+		free:    make(map[Key][]idle),
+		in_use:  make(map[*ebiten.Image]Key),
+	}
+}
+
+// Default is the pool shared by every subsystem, the app framework ends its frames.
+var Default = NewPool()
+
+func init() {
+	invalidate.Register("targets", func() { Default.Purge() })
+	memory.Gauge("render targets", Default.Bytes)
+}
+
+// Get returns a cleared image of `width` by `height`. Put it back once done with it, at the latest at the end of
+// the frame.
+func (p *Pool) Get(width, height int) *ebiten.Image {
+	return p.GetKey(Key{Width: width, Height: height})
+}
+
+// GetKey returns a cleared image for `key`.
+func (p *Pool) GetKey(key Key) *ebiten.Image {
+	var img *ebiten.Image
+	if free := p.free[key]; len(free) > 0 {
+		// the most recently used one, so the others age out when fewer are needed
+		img = free[len(free)-1].image
+		p.free[key] = free[:len(free)-1]
+		img.Clear()
+		p.stats.Hits++
+		p.stats.Free--
+	} else {
+		img = ebiten.NewImageWithOptions(image.Rect(0, 0, key.Width, key.Height), &ebiten.NewImageOptions{
+			Unmanaged: key.Unmanaged,
+		})
+		p.stats.Misses++
+	}
+	p.in_use[img] = key
+	p.stats.InUse++
+	return img
+}
+
+// Put gives an image from Get back. Images which didn't come from the pool are ignored.
+func (p *Pool) Put(img *ebiten.Image) {
+	key, ok := p.in_use[img]
+	if !ok {
+		return
+	}
+	delete(p.in_use, img)
+	p.free[key] = append(p.free[key], idle{image: img, frame: p.frame})
+	p.stats.InUse--
+	p.stats.Free++
+}
+
+// EndFrame ages the images in the pool and deallocates the ones idle for more than MaxIdle frames.
+func (p *Pool) EndFrame() {
+	p.frame++
+	for key, free := range p.free {
+		// images are put back in order, so the oldest are at the front
+		n := 0
+		for n < len(free) && p.frame-free[n].frame > p.MaxIdle {
+			free[n].image.Deallocate()
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		p.stats.Free -= n
+		p.stats.Freed += n
+		if n == len(free) {
+			delete(p.free, key)
+		} else {
+			p.free[key] = append(free[:0], free[n:]...)
+		}
+	}
+}
+
+// Purge deallocates every image waiting in the pool. Images in use are left alone, they're purged once put back
+// and idle for long enough.
+func (p *Pool) Purge() {
+	for key, free := range p.free {
+		for _, f := range free {
+			f.image.Deallocate()
+		}
+		p.stats.Freed += len(free)
+		delete(p.free, key)
+	}
+	p.stats.Free = 0
+}
+
+// Bytes is the memory of the images of the pool, those in use and those waiting, at 4 bytes a pixel.
+func (p *Pool) Bytes() int64 {
+	var pixels int64
+	for _, key := range p.in_use {
+		pixels += int64(key.Width * key.Height)
+	}
+	for key, free := range p.free {
+		pixels += int64(key.Width*key.Height) * int64(len(free))
+	}
+	return pixels * 4
+}
+
+func (p *Pool) Stats() Stats {
+	return p.stats
+}
+
+// Reset zeroes the hit and miss counters.
+func (p *Pool) Reset() {
+	p.stats.Hits = 0
+	p.stats.Misses = 0
+	p.stats.Freed = 0
+}
+
+// Keys returns the keys of the images waiting in the pool with their counts, for display.
+func (p *Pool) Keys() map[Key]int {
+	keys := make(map[Key]int, len(p.free))
+	for key, free := range p.free {
+		keys[key] = len(free)
+	}
+	return keys
+}
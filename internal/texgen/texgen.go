@@ -0,0 +1,128 @@
+// Package texgen generates textures on the CPU: checkerboards, a UV check pattern, gradients, noise and normal
+// maps from heights. Demos use them where they'd otherwise need an image file, and as the texture of anything
+// whose own is missing. They're plain images, ebiten.NewImageFromImage makes them drawable.
+package texgen
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+type float = float32
+
+// Checker is a `size` by `size` checkerboard of `cells` cells a side, starting with `a` in the top left.
+func Checker(size, cells int, a, b color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	cells = max(cells, 1)
+	for y := range size {
+		for x := range size {
+			if (x*cells/size+y*cells/size)%2 == 0 {
+				img.Set(x, y, a)
+			} else {
+				img.Set(x, y, b)
+			}
+		}
+	}
+	return img
+}
+
+// UVCheck is a pattern for checking how a texture maps onto a model: an 8 by 8 checkerboard whose cells are tinted
+// red along u and green along v and labelled from A1 in the top left to H8, so stretching, seams and flips show.
+func UVCheck(size int) *image.RGBA {
+	const cells = 8
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := range size {
+		for x := range size {
+			cx, cy := x*cells/size, y*cells/size
+			shade := float(0.55)
+			if (cx+cy)%2 == 0 {
+				shade = 0.85
+			}
+			u, v := (float(cx)+0.5)/cells, (float(cy)+0.5)/cells
+			img.Set(x, y, color.RGBA{
+				R: uint8(255 * shade * (0.4 + 0.6*u)),
+				G: uint8(255 * shade * (0.4 + 0.6*v)),
+				B: uint8(255 * shade * 0.5),
+				A: 255,
+			})
+		}
+	}
+	// labels only fit in cells large enough for them
+	face := basicfont.Face7x13
+	if size/cells < 2*face.Width+4 {
+		return img
+	}
+	d := font.Drawer{Dst: img, Src: image.Black, Face: face}
+	for cy := range cells {
+		for cx := range cells {
+			d.Dot = fixed.P(cx*size/cells+3, cy*size/cells+face.Ascent+2)
+			d.DrawString(fmt.Sprintf("%c%d", 'A'+cx, cy+1))
+		}
+	}
+	return img
+}
+
+// Gradient is a `w` by `h` image fading from `from` to `to`, left to right or top to bottom when `vertical`.
+func Gradient(w, h int, from, to color.Color, vertical bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	a, b := color.RGBAModel.Convert(from).(color.RGBA), color.RGBAModel.Convert(to).(color.RGBA)
+	lerp := func(x, y uint8, t float) uint8 { return uint8(float(x) + (float(y)-float(x))*t + 0.5) }
+	for y := range h {
+		for x := range w {
+			t := float(x) / float(max(w-1, 1))
+			if vertical {
+				t = float(y) / float(max(h-1, 1))
+			}
+			img.SetRGBA(x, y, color.RGBA{lerp(a.R, b.R, t), lerp(a.G, b.G, t), lerp(a.B, b.B, t), lerp(a.A, b.A, t)})
+		}
+	}
+	return img
+}
+
+// Noise is a `size` by `size` gray image of `noise`, from package noise or otherwise, taken `scale` units across
+// the image. Values from 0 to 1 are black to white, anything outside is clamped; pass noise from -1 to 1 through
+// something like `0.5 + 0.5*n`.
+func Noise(size int, scale float, noise func(x, y float) float) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := range size {
+		for x := range size {
+			n := noise(float(x)*scale/float(size), float(y)*scale/float(size))
+			img.SetGray(x, y, color.Gray{uint8(min(max(n, 0), 1)*255 + 0.5)})
+		}
+	}
+	return img
+}
+
+// NormalMap turns the heights in `height`, its brightness, into a tangent space normal map, x to the right and y
+// up the image, encoded the usual way from 0 to 1 in red, green and blue. `strength` is how steep a step from
+// black to white between neighbouring pixels is. The edges wrap, so tiling heights give a tiling map.
+func NormalMap(height image.Image, strength float) *image.RGBA {
+	b := height.Bounds()
+	w, h := b.Dx(), b.Dy()
+	gray := image.NewGray(image.Rect(0, 0, w, h))
+	draw.Draw(gray, gray.Bounds(), height, b.Min, draw.Src)
+	at := func(x, y int) float {
+		return float(gray.GrayAt((x+w)%w, (y+h)%h).Y) / 255
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			// central differences, y is down the image and the normal's y up it
+			dx := (at(x+1, y) - at(x-1, y)) * strength / 2
+			dy := (at(x, y-1) - at(x, y+1)) * strength / 2
+			nx, ny, nz := -dx, -dy, float(1)
+			l := float(math.Sqrt(float64(nx*nx + ny*ny + nz*nz)))
+			encode := func(c float) uint8 { return uint8((c/l*0.5+0.5)*255 + 0.5) }
+			img.SetRGBA(x, y, color.RGBA{encode(nx), encode(ny), encode(nz), 255})
+		}
+	}
+	return img
+}
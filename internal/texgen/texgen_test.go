@@ -0,0 +1,55 @@
+package texgen
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestChecker(t *testing.T) {
+	img := Checker(8, 2, color.White, color.Black)
+	for _, test := range []struct {
+		x, y int
+		want color.Gray
+	}{{0, 0, color.Gray{255}}, {3, 3, color.Gray{255}}, {4, 0, color.Gray{0}}, {0, 4, color.Gray{0}}, {7, 7, color.Gray{255}}} {
+		if got := color.GrayModel.Convert(img.At(test.x, test.y)); got != test.want {
+			t.Errorf("the checker at %d,%d is %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestUVCheckTints(t *testing.T) {
+	img := UVCheck(256)
+	// the top right is redder than the top left, the bottom left greener
+	r0, g0, _, _ := img.At(1, 1).RGBA()
+	r1, _, _, _ := img.At(255, 1).RGBA()
+	_, g2, _, _ := img.At(1, 255).RGBA()
+	if r1 <= r0 || g2 <= g0 {
+		t.Fatalf("the corners don't tint along u and v: %v %v %v", img.At(1, 1), img.At(255, 1), img.At(1, 255))
+	}
+}
+
+func TestGradient(t *testing.T) {
+	img := Gradient(3, 2, color.Black, color.White, false)
+	if img.RGBAAt(0, 1).R != 0 || img.RGBAAt(1, 1).R != 128 || img.RGBAAt(2, 0).R != 255 {
+		t.Fatalf("a gradient of three pixels is %v", img.Pix)
+	}
+}
+
+func TestNormalMap(t *testing.T) {
+	// a ramp rising to the right tilts the normals to the left, and flat ground points straight out
+	ramp := image.NewGray(image.Rect(0, 0, 8, 8))
+	for y := range 8 {
+		for x := range 8 {
+			ramp.SetGray(x, y, color.Gray{uint8(x * 30)})
+		}
+	}
+	n := NormalMap(ramp, 4).RGBAAt(3, 3)
+	if n.R >= 128 || n.G != 128 || n.B >= 255 {
+		t.Fatalf("the normal of a ramp rising right is %v", n)
+	}
+	flat := NormalMap(image.NewGray(image.Rect(0, 0, 4, 4)), 4).RGBAAt(1, 1)
+	if flat != (color.RGBA{128, 128, 255, 255}) {
+		t.Fatalf("the normal of flat ground is %v", flat)
+	}
+}
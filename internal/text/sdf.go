@@ -0,0 +1,146 @@
+package text
+
+import (
+	"fmt"
+	"image/color"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/kage"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sdf"
+)
+
+// sdf_shader draws glyphs from their fields, see package sdf.
+var sdf_shader = `
+//kage:unit pixels
+package main
+
+#include "sdf.kage"
+
+// The style, see sdf.Style.
+var Color vec4
+var Outline float
+var OutlineColor vec4
+var Glow float
+var GlowColor vec4
+var Softness float
+
+func Fragment(dst vec4, src vec2, rgba vec4) vec4 {
+	d := sdf_at(src)
+	// half of how much the field changes across a pixel blurs the edge over about one
+	return sdf_shade(d, 0.5*fwidth(d), Color, Outline, OutlineColor, Glow, GlowColor, Softness)
+}
+`
+
+var sdf_compiled *ebiten.Shader
+
+// SDF draws text from the signed distance fields of an atlas, sharp at any size and however it's turned, with
+// outlines and glows. Small text at a fixed size is sharper drawn with a Font.
+type SDF struct {
+	*sdf.Atlas
+
+	image    *ebiten.Image
+	quads    []sdf.Quad
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+// NewSDF makes the image of `a` and, the first time, compiles the shader.
+func NewSDF(a *sdf.Atlas) (*SDF, error) {
+	if sdf_compiled == nil {
+		src, err := kage.PreprocessSource("sdf_text.kage", sdf_shader, nil)
+		if err != nil {
+			return nil, err
+		}
+		shader, err := ebiten.NewShader(src)
+		if err != nil {
+			return nil, fmt.Errorf("sdf_text.kage: %w", err)
+		}
+		sdf_compiled = shader
+	}
+	return &SDF{Atlas: a, image: ebiten.NewImageFromImage(a.Image)}, nil
+}
+
+// SDFSans is Go Regular from the fields of sdf.Default, made the first time it's asked for.
+var SDFSans = sync.OnceValue(func() *SDF {
+	s, err := NewSDF(sdf.Default())
+	if err != nil {
+		panic(err)
+	}
+	return s
+})
+
+// SDFStyle is how SDF text is drawn, its widths in pixels of the text at the size it's drawn, before its GeoM.
+type SDFStyle struct {
+	Color color.Color
+	// Outline is how wide an outline of OutlineColor is, out from the glyphs, 0 for none.
+	Outline      float64
+	OutlineColor color.Color
+	// Glow is how far a glow of GlowColor fades out beyond the outline, 0 for none.
+	Glow      float64
+	GlowColor color.Color
+	// Softness blurs the edges by as many pixels, for shadows.
+	Softness float64
+}
+
+// Image is the atlas' image, to draw meshes of it, see sdf.Atlas.Mesh.
+func (s *SDF) Image() *ebiten.Image {
+	return s.image
+}
+
+// Field is `style` at `size` pixels an em in units of the field, for the shaders. Widths are cut to what the field
+// reaches.
+func (s *SDF) Field(style *SDFStyle, size float64) sdf.Style {
+	// a pixel at `size` is that much of the field
+	pixel := float32(float64(s.Size) / size / float64(2*s.Spread))
+	premultiplied := func(c color.Color) [4]float32 {
+		if c == nil {
+			return [4]float32{}
+		}
+		r, g, b, a := c.RGBA()
+		return [4]float32{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff, float32(a) / 0xffff}
+	}
+	clr := style.Color
+	if clr == nil {
+		clr = color.White
+	}
+	outline := min(float32(style.Outline)*pixel, sdf.Edge)
+	return sdf.Style{
+		Color:        premultiplied(clr),
+		Outline:      outline,
+		OutlineColor: premultiplied(style.OutlineColor),
+		Glow:         min(float32(style.Glow)*pixel, sdf.Edge-outline),
+		GlowColor:    premultiplied(style.GlowColor),
+		Softness:     float32(style.Softness) * pixel,
+	}
+}
+
+// Draw draws `str` at `size` pixels an em in `style`, the top left of its first line at 0, 0 placed by `geom`. Lines
+// are split by newlines.
+func (s *SDF) Draw(dst *ebiten.Image, str string, size float64, geom ebiten.GeoM, style *SDFStyle) {
+	s.quads = s.Layout(str, float32(size), s.quads[:0])
+	if len(s.quads) == 0 {
+		return
+	}
+	s.vertices, s.indices = s.vertices[:0], s.indices[:0]
+	for _, q := range s.quads[:min(len(s.quads), 0x10000/4)] {
+		base := uint16(len(s.vertices))
+		corners := [4][2]float32{{q.Min[0], q.Min[1]}, {q.Max[0], q.Min[1]}, {q.Min[0], q.Max[1]}, {q.Max[0], q.Max[1]}}
+		srcs := [4][2]int{{q.Src.Min.X, q.Src.Min.Y}, {q.Src.Max.X, q.Src.Min.Y}, {q.Src.Min.X, q.Src.Max.Y}, {q.Src.Max.X, q.Src.Max.Y}}
+		for i, c := range corners {
+			x, y := geom.Apply(float64(c[0]), float64(c[1]))
+			s.vertices = append(s.vertices, ebiten.Vertex{
+				DstX: float32(x), DstY: float32(y),
+				SrcX: float32(srcs[i][0]), SrcY: float32(srcs[i][1]),
+				ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1,
+			})
+		}
+		s.indices = append(s.indices, base, base+1, base+2, base+1, base+3, base+2)
+	}
+	field := s.Field(style, size)
+	dst.DrawTrianglesShader(s.vertices, s.indices, sdf_compiled, &ebiten.DrawTrianglesShaderOptions{
+		Uniforms: field.Uniforms(),
+		Images:   [4]*ebiten.Image{s.image},
+	})
+}
@@ -0,0 +1,178 @@
+// Package text draws strings with real fonts instead of ebiten's debug font, which only has ASCII. Text is shaped
+// by go-text, so accents, Cyrillic, Greek, Arabic and the rest come out right as long as a font has them.
+//
+// A Font is a chain of font files tried in order for each character: the Go fonts first, then the files given
+// with -fonts, then the fonts of the usual systems which cover what the Go fonts don't, such as Chinese and
+// Japanese. Text falls back to the first font which has a character instead of showing a box.
+package text
+
+import (
+	"bytes"
+	"flag"
+	"image/color"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/jobs"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+)
+
+var logger = logging.Tag("text")
+
+var font_paths = flag.String("fonts", "", "comma separated font files to fall back on after the Go fonts")
+
+// system_fonts are fallbacks looked for where they usually are, the ones which exist are used.
+var system_fonts = []string{
+	// windows
+	"C:/Windows/Fonts/segoeui.ttf",
+	"C:/Windows/Fonts/msyh.ttc",
+	"C:/Windows/Fonts/YuGothR.ttc",
+	"C:/Windows/Fonts/malgun.ttf",
+	// macOS
+	"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+	"/System/Library/Fonts/Hiragino Sans GB.ttc",
+	// linux
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/truetype/noto/NotoSansArabic-Regular.ttf",
+}
+
+// Face is a font at a size, what text is measured and drawn with.
+type Face = text.Face
+
+// Font is a chain of font files, the first one having a character draws it.
+type Font struct {
+	sources []*text.GoTextFaceSource
+	// faces are the faces handed out, by size
+	faces map[float64]text.Face
+}
+
+func NewFont(sources ...*text.GoTextFaceSource) *Font {
+	return &Font{sources: sources, faces: make(map[float64]text.Face)}
+}
+
+// Fallback adds `sources` to the end of the chain.
+func (f *Font) Fallback(sources ...*text.GoTextFaceSource) {
+	f.sources = append(f.sources, sources...)
+	clear(f.faces)
+}
+
+// Face returns the font at `size`, in pixels per em.
+func (f *Font) Face(size float64) text.Face {
+	if face, ok := f.faces[size]; ok {
+		return face
+	}
+	faces := make([]text.Face, len(f.sources))
+	for i, source := range f.sources {
+		faces[i] = &text.GoTextFace{Source: source, Size: size}
+	}
+	var face text.Face = faces[0]
+	if len(faces) > 1 {
+		multi, err := text.NewMultiFace(faces...)
+		if err != nil {
+			panic(err)
+		}
+		face = multi
+	}
+	f.faces[size] = face
+	return face
+}
+
+var (
+	// Mono is Go Mono and its fallbacks, what the UI is drawn with. Characters are 0.6 of the size wide, at size
+	// 10 that's the 6 pixels the debug font had.
+	Mono = NewFont(must(text.NewGoTextFaceSource(bytes.NewReader(gomono.TTF))))
+	// MonoBold is as wide as Mono, bold text doesn't push the rest of a line along.
+	MonoBold = NewFont(must(text.NewGoTextFaceSource(bytes.NewReader(gomonobold.TTF))))
+	// Sans is Go Regular and its fallbacks, for text which doesn't need to line up.
+	Sans = NewFont(must(text.NewGoTextFaceSource(bytes.NewReader(goregular.TTF))))
+)
+
+func must(source *text.GoTextFaceSource, err error) *text.GoTextFaceSource {
+	if err != nil {
+		panic(err)
+	}
+	return source
+}
+
+// Load loads the font file at `path`, every font of it when it's a collection (.ttc).
+func Load(path string) ([]*text.GoTextFaceSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(path[max(len(path)-4, 0):], ".ttc") {
+		return text.NewGoTextFaceSourcesFromCollection(bytes.NewReader(data))
+	}
+	source, err := text.NewGoTextFaceSource(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return []*text.GoTextFaceSource{source}, nil
+}
+
+// LoadFallbacks loads the -fonts and the system fonts in the background and adds them to the fonts above once
+// they're loaded. Until then text falls back to boxes. The app framework calls it when it starts.
+func LoadFallbacks() {
+	var paths []string
+	if *font_paths != "" {
+		paths = strings.Split(*font_paths, ",")
+	}
+	for _, path := range system_fonts {
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	var sources []*text.GoTextFaceSource
+	jobs.Default.SubmitThen(jobs.Low, func() {
+		for _, path := range paths {
+			s, err := Load(path)
+			if err != nil {
+				logger.Warnf("could not load font %s: %v", path, err)
+				continue
+			}
+			// only the first font of a collection, the others are weights and styles of the same characters
+			sources = append(sources, s[0])
+		}
+	}, func() {
+		for _, f := range []*Font{Mono, MonoBold, Sans} {
+			f.Fallback(sources...)
+		}
+		logger.Debugf("%d fallback fonts loaded", len(sources))
+	})
+}
+
+// Draw draws `s` in `face` with the top left of its first line at `x`, `y` and lines `line_height` apart. Every
+// line is aligned on its own by `align_x`, 0 for left through 1 for right, within `width`.
+func Draw(dst *ebiten.Image, s string, face text.Face, x, y, width, line_height, align_x float64, clr color.Color) {
+	// lines are centered in their height, like the debug font sits in its 16 pixels
+	m := face.Metrics()
+	y += (line_height - (m.HAscent + m.HDescent)) / 2
+	for _, line := range strings.Split(s, "\n") {
+		opts := &text.DrawOptions{}
+		opts.GeoM.Translate(x+(width-text.Advance(line, face))*align_x, y)
+		opts.ColorScale.ScaleWithColor(clr)
+		text.Draw(dst, line, face, opts)
+		y += line_height
+	}
+}
+
+// Width is the width of the widest line of `s`.
+func Width(s string, face text.Face) float64 {
+	var width float64
+	for _, line := range strings.Split(s, "\n") {
+		width = max(width, text.Advance(line, face))
+	}
+	return width
+}
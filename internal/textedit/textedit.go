@@ -0,0 +1,168 @@
+// Package textedit edits a line of text the way text fields do: a cursor which moves by characters and words, a
+// selection between the cursor and an anchor, and typing, deleting, cutting and pasting over it. It knows nothing
+// of keys or the screen, the UI maps keys to these calls.
+package textedit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Line is a line of text being edited. Cursor and Anchor are byte offsets into Text, always at the start of a
+// character. The selection is the text between them, nothing when they're equal.
+type Line struct {
+	Text   string
+	Cursor int
+	Anchor int
+}
+
+// Set replaces the text and puts the cursor at its end.
+func (l *Line) Set(text string) {
+	l.Text = text
+	l.Cursor, l.Anchor = len(text), len(text)
+}
+
+// Selection returns the start and end of the selection.
+func (l *Line) Selection() (start, end int) {
+	return min(l.Cursor, l.Anchor), max(l.Cursor, l.Anchor)
+}
+
+// Selected returns the selected text.
+func (l *Line) Selected() string {
+	start, end := l.Selection()
+	return l.Text[start:end]
+}
+
+// SelectAll selects the whole line.
+func (l *Line) SelectAll() {
+	l.Anchor, l.Cursor = 0, len(l.Text)
+}
+
+// Insert replaces the selection with `s`, leaving the cursor after it. Line breaks become spaces.
+func (l *Line) Insert(s string) {
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return ' '
+		}
+		if r == '\r' || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, s)
+	start, end := l.Selection()
+	l.Text = l.Text[:start] + s + l.Text[end:]
+	l.Cursor = start + len(s)
+	l.Anchor = l.Cursor
+}
+
+// Cut removes the selection and returns it.
+func (l *Line) Cut() string {
+	s := l.Selected()
+	l.Insert("")
+	return s
+}
+
+// Backspace deletes the selection, or the character before the cursor, or the word before it when `word` is set.
+func (l *Line) Backspace(word bool) {
+	if l.Cursor == l.Anchor {
+		l.Anchor = l.left(l.Cursor, word)
+	}
+	l.Insert("")
+}
+
+// Delete deletes the selection, or the character after the cursor, or the word after it when `word` is set.
+func (l *Line) Delete(word bool) {
+	if l.Cursor == l.Anchor {
+		l.Anchor = l.right(l.Cursor, word)
+	}
+	l.Insert("")
+}
+
+// Left moves the cursor a character left, or a word when `word` is set. Without `selecting` it collapses a
+// selection to its start instead.
+func (l *Line) Left(word, selecting bool) {
+	if !selecting && l.Cursor != l.Anchor {
+		l.Cursor, _ = l.Selection()
+	} else {
+		l.Cursor = l.left(l.Cursor, word)
+	}
+	l.settle(selecting)
+}
+
+// Right moves the cursor a character right, or a word when `word` is set. Without `selecting` it collapses a
+// selection to its end instead.
+func (l *Line) Right(word, selecting bool) {
+	if !selecting && l.Cursor != l.Anchor {
+		_, l.Cursor = l.Selection()
+	} else {
+		l.Cursor = l.right(l.Cursor, word)
+	}
+	l.settle(selecting)
+}
+
+// Home moves the cursor to the start of the line.
+func (l *Line) Home(selecting bool) {
+	l.Cursor = 0
+	l.settle(selecting)
+}
+
+// End moves the cursor to the end of the line.
+func (l *Line) End(selecting bool) {
+	l.Cursor = len(l.Text)
+	l.settle(selecting)
+}
+
+// settle drops the anchor at the cursor unless the selection is being extended.
+func (l *Line) settle(selecting bool) {
+	if !selecting {
+		l.Anchor = l.Cursor
+	}
+}
+
+// left is the offset of the character, or the start of the word, before `i`.
+func (l *Line) left(i int, word bool) int {
+	if !word {
+		_, size := utf8.DecodeLastRuneInString(l.Text[:i])
+		return i - size
+	}
+	// skip the spaces before the word, then the word
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(l.Text[:i])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		i -= size
+	}
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(l.Text[:i])
+		if unicode.IsSpace(r) {
+			break
+		}
+		i -= size
+	}
+	return i
+}
+
+// right is the offset after the character, or the end of the word, after `i`.
+func (l *Line) right(i int, word bool) int {
+	if !word {
+		_, size := utf8.DecodeRuneInString(l.Text[i:])
+		return i + size
+	}
+	for i < len(l.Text) {
+		r, size := utf8.DecodeRuneInString(l.Text[i:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		i += size
+	}
+	for i < len(l.Text) {
+		r, size := utf8.DecodeRuneInString(l.Text[i:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
@@ -0,0 +1,71 @@
+package textedit
+
+import "testing"
+
+func TestTyping(t *testing.T) {
+	var l Line
+	l.Insert("héllo wörld")
+	l.Left(false, false)
+	l.Backspace(false)
+	if l.Text != "héllo wörd" || l.Cursor != len("héllo wör") {
+		t.Fatalf("%q with the cursor at %d", l.Text, l.Cursor)
+	}
+	l.Home(false)
+	l.Right(false, false)
+	l.Delete(false)
+	if l.Text != "hllo wörd" {
+		t.Fatalf("deleting after h left %q", l.Text)
+	}
+	l.Insert("\tä\r\n")
+	if l.Text != "h ä llo wörd" {
+		t.Fatalf("inserting a tab and a line break left %q", l.Text)
+	}
+}
+
+func TestWords(t *testing.T) {
+	var l Line
+	l.Set("set  r_fov 90")
+	l.Left(true, false)
+	if l.Cursor != len("set  r_fov ") {
+		t.Fatalf("a word left is %d", l.Cursor)
+	}
+	l.Left(true, false)
+	l.Left(true, false)
+	if l.Cursor != 0 {
+		t.Fatalf("three words left is %d", l.Cursor)
+	}
+	l.Right(true, false)
+	if l.Cursor != len("set") {
+		t.Fatalf("a word right is %d", l.Cursor)
+	}
+	l.End(false)
+	l.Backspace(true)
+	if l.Text != "set  r_fov " {
+		t.Fatalf("deleting a word left %q", l.Text)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	var l Line
+	l.Set("copy this text")
+	l.Left(true, false)
+	l.Left(true, true)
+	if got := l.Selected(); got != "this " {
+		t.Fatalf("selected %q", got)
+	}
+	// moving without shift collapses the selection to the side moved to
+	l.Right(false, false)
+	if l.Cursor != len("copy this ") || l.Selected() != "" {
+		t.Fatalf("collapsing right put the cursor at %d", l.Cursor)
+	}
+
+	l.Home(true)
+	if cut := l.Cut(); cut != "copy this " || l.Text != "text" {
+		t.Fatalf("cut %q and left %q", cut, l.Text)
+	}
+	l.SelectAll()
+	l.Insert("pasted")
+	if l.Text != "pasted" || l.Cursor != 6 || l.Anchor != 6 {
+		t.Fatalf("pasting over everything left %+v", l)
+	}
+}
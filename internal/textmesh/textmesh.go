@@ -0,0 +1,297 @@
+// Package textmesh makes meshes of text from the outlines of a font's glyphs, flat or extruded, for titles which
+// are geometry in a 3D scene rather than an image drawn over it. The outlines are curves; they're flattened into
+// points, split into outlines and holes and handed to mesh.Fill or mesh.Extrude.
+package textmesh
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+)
+
+// Font is a TrueType or OpenType font.
+type Font struct {
+	font *sfnt.Font
+	// shapes are the shapes of glyphs made so far, in ems, by glyph and tolerance
+	shapes map[glyph][]mesh.Shape
+	lock   sync.Mutex
+}
+
+type glyph struct {
+	index     sfnt.GlyphIndex
+	tolerance float
+}
+
+// Parse reads a font file, a .ttf or .otf.
+func Parse(src []byte) (*Font, error) {
+	f, err := sfnt.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Font{font: f, shapes: make(map[glyph][]mesh.Shape)}, nil
+}
+
+// Default is Go Regular.
+var Default = sync.OnceValue(func() *Font {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		panic(err)
+	}
+	return f
+})
+
+// Options are how text is made into a mesh.
+type Options struct {
+	// Size is the height of an em, about from the bottom of a g to the top of an h. 0 is 1.
+	Size float
+	// Depth is how deep the letters are extruded, along z from -Depth/2 to Depth/2. 0 leaves them flat at z = 0.
+	Depth float
+	// Tolerance is how far the flattened curves may stray from the real ones, in ems. 0 is 1/100.
+	Tolerance float
+	// Center puts the middle of the text at the origin, otherwise the first line's baseline starts there.
+	Center bool
+}
+
+// Mesh makes a mesh of `s`, facing +z and reading along +x with y up. Lines are split by newlines. Characters
+// the font doesn't have are left out.
+func (f *Font) Mesh(s string, o Options) (*mesh.Mesh, error) {
+	if o.Size == 0 {
+		o.Size = 1
+	}
+	if o.Tolerance == 0 {
+		o.Tolerance = 0.01
+	}
+
+	var b sfnt.Buffer
+	ppem := fixed.Int26_6(f.font.UnitsPerEm()) << 6
+	em := func(x fixed.Int26_6) float { return float(x) / float(ppem) }
+	metrics, err := f.font.Metrics(&b, ppem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []*mesh.Mesh
+	var y float
+	for _, line := range strings.Split(s, "\n") {
+		var x float
+		previous := sfnt.GlyphIndex(0)
+		for _, r := range line {
+			index, err := f.font.GlyphIndex(&b, r)
+			if err != nil || index == 0 {
+				continue
+			}
+			if previous != 0 {
+				if kern, err := f.font.Kern(&b, previous, index, ppem, font.HintingNone); err == nil {
+					x += em(kern)
+				}
+			}
+			previous = index
+			shapes, err := f.glyph(&b, index, ppem, o.Tolerance)
+			if err != nil {
+				return nil, fmt.Errorf("glyph of %q: %w", r, err)
+			}
+			for _, shape := range shapes {
+				shape = place(shape, x, y, o.Size)
+				if o.Depth > 0 {
+					parts = append(parts, mesh.Extrude(shape, o.Depth))
+				} else {
+					parts = append(parts, mesh.Fill(shape))
+				}
+			}
+			advance, err := f.font.GlyphAdvance(&b, index, ppem, font.HintingNone)
+			if err != nil {
+				return nil, err
+			}
+			x += em(advance)
+		}
+		y -= em(metrics.Height)
+	}
+
+	m, err := mesh.Merge(parts...)
+	if err != nil {
+		return nil, err
+	}
+	if o.Center && m.Bounded() {
+		lo, hi := m.Points[0], m.Points[0]
+		for _, p := range m.Points {
+			for i := range p {
+				lo[i], hi[i] = min(lo[i], p[i]), max(hi[i], p[i])
+			}
+		}
+		middle := lo.Add(hi).Mul(0.5)
+		m.Transform(mgl32.Translate3D(-middle.X(), -middle.Y(), -middle.Z()))
+	}
+	return m, nil
+}
+
+// place moves and scales a glyph's shape from ems at the origin to where it's drawn.
+func place(s mesh.Shape, x, y, size float) mesh.Shape {
+	move := func(ring []vec2) []vec2 {
+		out := make([]vec2, len(ring))
+		for i, p := range ring {
+			out[i] = vec2{x + p.X(), y + p.Y()}.Mul(size)
+		}
+		return out
+	}
+	placed := mesh.Shape{Outline: move(s.Outline)}
+	for _, h := range s.Holes {
+		placed.Holes = append(placed.Holes, move(h))
+	}
+	return placed
+}
+
+// glyph is the shapes of a glyph in ems, flattened to `tolerance`.
+func (f *Font) glyph(b *sfnt.Buffer, index sfnt.GlyphIndex, ppem fixed.Int26_6, tolerance float) ([]mesh.Shape, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	key := glyph{index, tolerance}
+	if shapes, ok := f.shapes[key]; ok {
+		return shapes, nil
+	}
+	segments, err := f.font.LoadGlyph(b, index, ppem, nil)
+	if err != nil {
+		return nil, err
+	}
+	shapes := Shapes(flatten(segments, ppem, tolerance))
+	f.shapes[key] = shapes
+	return shapes, nil
+}
+
+// flatten turns the curves of a glyph's outline into rings of points, in ems with y up.
+func flatten(segments sfnt.Segments, ppem fixed.Int26_6, tolerance float) [][]vec2 {
+	point := func(p fixed.Point26_6) vec2 {
+		return vec2{float(p.X) / float(ppem), -float(p.Y) / float(ppem)}
+	}
+	// steps is how many lines a curve is cut into to stay within tolerance, `bend` is how far its control points
+	// are from being in a line
+	steps := func(bend float) int {
+		return min(max(int(math.Ceil(math.Sqrt(float64(bend/(4*tolerance))))), 1), 32)
+	}
+
+	var rings [][]vec2
+	var ring []vec2
+	end := func() {
+		if len(ring) > 0 && ring[0] == ring[len(ring)-1] {
+			ring = ring[:len(ring)-1]
+		}
+		if len(ring) >= 3 {
+			rings = append(rings, ring)
+		}
+		ring = nil
+	}
+	add := func(p vec2) {
+		if len(ring) == 0 || ring[len(ring)-1] != p {
+			ring = append(ring, p)
+		}
+	}
+	for _, s := range segments {
+		var from vec2
+		if len(ring) > 0 {
+			from = ring[len(ring)-1]
+		}
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			end()
+			add(point(s.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			add(point(s.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			c, to := point(s.Args[0]), point(s.Args[1])
+			n := steps(from.Sub(c.Mul(2)).Add(to).Len())
+			for i := 1; i <= n; i++ {
+				t := float(i) / float(n)
+				add(from.Mul((1 - t) * (1 - t)).Add(c.Mul(2 * (1 - t) * t)).Add(to.Mul(t * t)))
+			}
+		case sfnt.SegmentOpCubeTo:
+			c1, c2, to := point(s.Args[0]), point(s.Args[1]), point(s.Args[2])
+			n := steps(1.5 * max(from.Sub(c1.Mul(2)).Add(c2).Len(), c1.Sub(c2.Mul(2)).Add(to).Len()))
+			for i := 1; i <= n; i++ {
+				t := float(i) / float(n)
+				u := 1 - t
+				add(from.Mul(u * u * u).Add(c1.Mul(3 * u * u * t)).Add(c2.Mul(3 * u * t * t)).Add(to.Mul(t * t * t)))
+			}
+		}
+	}
+	end()
+	return rings
+}
+
+// Shapes sorts the rings of an outline, such as a glyph's, into shapes with holes by which rings are inside which:
+// a ring inside an even number of others is an outline, one inside an odd number a hole in the smallest outline
+// around it. That works whichever way round the font winds its rings, TrueType and CFF fonts disagree.
+func Shapes(rings [][]vec2) []mesh.Shape {
+	inside := make([][]int, len(rings))
+	for i, r := range rings {
+		for j, other := range rings {
+			if i != j && contains(other, r[0]) {
+				inside[i] = append(inside[i], j)
+			}
+		}
+	}
+	area := func(r []vec2) float {
+		var a float
+		for i := range r {
+			p, q := r[i], r[(i+1)%len(r)]
+			a += p.X()*q.Y() - q.X()*p.Y()
+		}
+		return float(math.Abs(float64(a))) / 2
+	}
+
+	var shapes []mesh.Shape
+	outline := make(map[int]int)
+	for i, r := range rings {
+		if len(inside[i])%2 == 0 {
+			outline[i] = len(shapes)
+			shapes = append(shapes, mesh.Shape{Outline: r})
+		}
+	}
+	for i, r := range rings {
+		if len(inside[i])%2 == 0 {
+			continue
+		}
+		// the hole belongs to the smallest outline around it, which is the one one level up
+		parents := slices.DeleteFunc(slices.Clone(inside[i]), func(j int) bool { return len(inside[j]) != len(inside[i])-1 })
+		if len(parents) == 0 {
+			continue
+		}
+		parent := slices.MinFunc(parents, func(a, b int) int {
+			switch {
+			case area(rings[a]) < area(rings[b]):
+				return -1
+			case area(rings[a]) > area(rings[b]):
+				return 1
+			}
+			return 0
+		})
+		shape := &shapes[outline[parent]]
+		shape.Holes = append(shape.Holes, r)
+	}
+	return shapes
+}
+
+// contains reports whether `p` is inside the ring, by counting how often a line from it to the right crosses it.
+func contains(ring []vec2, p vec2) bool {
+	in := false
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		if (a.Y() > p.Y()) != (b.Y() > p.Y()) && p.X() < a.X()+(p.Y()-a.Y())*(b.X()-a.X())/(b.Y()-a.Y()) {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,67 @@
+package textmesh
+
+import (
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func shapes_of(t *testing.T, r rune) int {
+	t.Helper()
+	f := Default()
+	var b sfnt.Buffer
+	index, err := f.font.GlyphIndex(&b, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shapes, err := f.glyph(&b, index, fixed.Int26_6(f.font.UnitsPerEm())<<6, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holes := 0
+	for _, s := range shapes {
+		holes += len(s.Holes)
+	}
+	return len(shapes)*10 + holes
+}
+
+func TestGlyphShapes(t *testing.T) {
+	// tens are outlines, ones holes
+	for _, test := range []struct {
+		r    rune
+		want int
+	}{{'l', 10}, {'o', 11}, {'i', 20}, {'B', 12}, {'%', 32}} {
+		if got := shapes_of(t, test.r); got != test.want {
+			t.Errorf("%q has %d outlines and %d holes, want %d and %d", test.r, got/10, got%10, test.want/10, test.want%10)
+		}
+	}
+}
+
+func TestTextMesh(t *testing.T) {
+	flat, err := Default().Mesh("Hi o", Options{Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tri := range flat.Triangles {
+		p1, p2, p3 := flat.Points[tri.P1], flat.Points[tri.P2], flat.Points[tri.P3]
+		if n := p2.Sub(p1).Cross(p3.Sub(p1)); n.Z() <= 0 {
+			t.Fatalf("triangle %d of flat text faces %v", i, n)
+		}
+	}
+
+	solid, err := Default().Mesh("Hi o\nHi", Options{Size: 2, Depth: 0.5, Center: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var volume float
+	for _, tri := range solid.Triangles {
+		volume += solid.Points[tri.P1].Dot(solid.Points[tri.P2].Cross(solid.Points[tri.P3])) / 6
+	}
+	if volume <= 0 {
+		t.Fatalf("extruded text has a volume of %v", volume)
+	}
+	if c := solid.Sphere.Center; c.Len() > 1e-3 {
+		t.Fatalf("centered text is centered on %v", c)
+	}
+}
@@ -0,0 +1,71 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// decode_data reads the `count` tiles of a tile layer from its data as Tiled writes it: comma separated numbers,
+// or base64 of little endian uint32s, maybe compressed.
+func decode_data(encoding, compression, data string, count int) ([]GID, error) {
+	switch encoding {
+	case "csv":
+		if compression != "" {
+			return nil, fmt.Errorf("csv data compressed with %s", compression)
+		}
+		return decode_csv(data, count)
+	case "base64":
+		return decode_base64(compression, data, count)
+	}
+	return nil, fmt.Errorf("unknown encoding %q of layer data", encoding)
+}
+
+func decode_csv(data string, count int) ([]GID, error) {
+	fields := strings.Split(strings.TrimSpace(data), ",")
+	if len(fields) != count {
+		return nil, fmt.Errorf("layer has %d tiles, not %d", len(fields), count)
+	}
+	tiles := make([]GID, count)
+	for i, field := range fields {
+		gid, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad tile %d: %w", i, err)
+		}
+		tiles[i] = GID(gid)
+	}
+	return tiles, nil
+}
+
+func decode_base64(compression, data string, count int) ([]GID, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("bad base64 layer data: %w", err)
+	}
+	var r io.Reader
+	switch compression {
+	case "":
+		r = bytes.NewReader(raw)
+	case "zlib":
+		if r, err = zlib.NewReader(bytes.NewReader(raw)); err != nil {
+			return nil, fmt.Errorf("bad zlib layer data: %w", err)
+		}
+	case "gzip":
+		if r, err = gzip.NewReader(bytes.NewReader(raw)); err != nil {
+			return nil, fmt.Errorf("bad gzip layer data: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("layer data compressed with %s, which isn't supported", compression)
+	}
+	tiles := make([]GID, count)
+	if err := binary.Read(r, binary.LittleEndian, tiles); err != nil {
+		return nil, fmt.Errorf("layer data is short of %d tiles: %w", count, err)
+	}
+	return tiles, nil
+}
@@ -0,0 +1,293 @@
+package tiled
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The JSON of .tmj and .tsj files, see https://doc.mapeditor.org/en/stable/reference/json-map-format/.
+
+type json_map struct {
+	Orientation string          `json:"orientation"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	TileWidth   int             `json:"tilewidth"`
+	TileHeight  int             `json:"tileheight"`
+	Infinite    bool            `json:"infinite"`
+	Properties  json_properties `json:"properties"`
+	Tilesets    []json_tileset  `json:"tilesets"`
+	Layers      []json_layer    `json:"layers"`
+}
+
+type json_tileset struct {
+	FirstGID    uint32 `json:"firstgid"`
+	Source      string `json:"source"`
+	Name        string `json:"name"`
+	TileWidth   int    `json:"tilewidth"`
+	TileHeight  int    `json:"tileheight"`
+	TileCount   int    `json:"tilecount"`
+	Columns     int    `json:"columns"`
+	Spacing     int    `json:"spacing"`
+	Margin      int    `json:"margin"`
+	Image       string `json:"image"`
+	ImageWidth  int    `json:"imagewidth"`
+	ImageHeight int    `json:"imageheight"`
+	TileOffset  struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"tileoffset"`
+	Tiles []struct {
+		ID         uint32          `json:"id"`
+		Type       string          `json:"type"`
+		Class      string          `json:"class"`
+		Properties json_properties `json:"properties"`
+		Animation  []struct {
+			TileID   uint32 `json:"tileid"`
+			Duration int    `json:"duration"`
+		} `json:"animation"`
+	} `json:"tiles"`
+}
+
+type json_layer struct {
+	Type       string          `json:"type"`
+	ID         int             `json:"id"`
+	Name       string          `json:"name"`
+	Visible    *bool           `json:"visible"`
+	Opacity    *float          `json:"opacity"`
+	OffsetX    float           `json:"offsetx"`
+	OffsetY    float           `json:"offsety"`
+	Width      int             `json:"width"`
+	Height     int             `json:"height"`
+	Properties json_properties `json:"properties"`
+	// Data is an array of GIDs, or a string of base64 with Encoding "base64"
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Chunks      json.RawMessage `json:"chunks"`
+	Objects     []json_object   `json:"objects"`
+	Layers      []json_layer    `json:"layers"`
+}
+
+type json_object struct {
+	ID         int             `json:"id"`
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Class      string          `json:"class"`
+	X          float           `json:"x"`
+	Y          float           `json:"y"`
+	Width      float           `json:"width"`
+	Height     float           `json:"height"`
+	Rotation   float           `json:"rotation"`
+	GID        GID             `json:"gid"`
+	Visible    *bool           `json:"visible"`
+	Properties json_properties `json:"properties"`
+	Ellipse    bool            `json:"ellipse"`
+	Point      bool            `json:"point"`
+	Polygon    []struct {
+		X, Y float
+	} `json:"polygon"`
+	Polyline []struct {
+		X, Y float
+	} `json:"polyline"`
+}
+
+type json_properties []struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+func (p json_properties) convert() Properties {
+	if len(p) == 0 {
+		return nil
+	}
+	props := make(Properties, len(p))
+	for _, prop := range p {
+		props[prop.Name] = fmt.Sprint(prop.Value)
+	}
+	return props
+}
+
+func (l *loader) decode_json(src []byte) (*Map, error) {
+	var j json_map
+	if err := json.Unmarshal(src, &j); err != nil {
+		return nil, fmt.Errorf("bad map: %w", err)
+	}
+	if j.Infinite {
+		return nil, errors.New("infinite maps aren't supported")
+	}
+	if err := check_size("the map", j.Width, j.Height); err != nil {
+		return nil, err
+	}
+	m := &Map{
+		Orientation: j.Orientation,
+		Width:       j.Width,
+		Height:      j.Height,
+		TileWidth:   j.TileWidth,
+		TileHeight:  j.TileHeight,
+		Properties:  j.Properties.convert(),
+	}
+	for _, t := range j.Tilesets {
+		var ts *Tileset
+		var err error
+		if t.Source != "" {
+			ts, err = l.tileset(t.Source, t.FirstGID)
+		} else {
+			ts, err = l.convert_tsj(&t, t.FirstGID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.Tilesets = append(m.Tilesets, ts)
+	}
+	for _, layer := range j.Layers {
+		if err := m.add_json_layer(&layer, root_group); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (l *loader) decode_tsj(src []byte, first_gid uint32) (*Tileset, error) {
+	var j json_tileset
+	if err := json.Unmarshal(src, &j); err != nil {
+		return nil, fmt.Errorf("bad tileset: %w", err)
+	}
+	return l.convert_tsj(&j, first_gid)
+}
+
+func (l *loader) convert_tsj(j *json_tileset, first_gid uint32) (*Tileset, error) {
+	ts := &Tileset{
+		FirstGID:    first_gid,
+		Name:        j.Name,
+		TileWidth:   j.TileWidth,
+		TileHeight:  j.TileHeight,
+		TileCount:   j.TileCount,
+		Columns:     j.Columns,
+		Spacing:     j.Spacing,
+		Margin:      j.Margin,
+		Image:       j.Image,
+		ImageWidth:  j.ImageWidth,
+		ImageHeight: j.ImageHeight,
+		OffsetX:     j.TileOffset.X,
+		OffsetY:     j.TileOffset.Y,
+		Tiles:       make(map[uint32]*Tile),
+	}
+	for _, t := range j.Tiles {
+		tile := &Tile{Type: t.Type, Properties: t.Properties.convert()}
+		if tile.Type == "" {
+			tile.Type = t.Class
+		}
+		for _, f := range t.Animation {
+			tile.Animation = append(tile.Animation, Frame{f.TileID, time.Duration(f.Duration) * time.Millisecond})
+		}
+		ts.Tiles[t.ID] = tile
+	}
+	if err := l.check(ts); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (m *Map) add_json_layer(j *json_layer, parent group) error {
+	visible := j.Visible == nil || *j.Visible
+	opacity := float(1)
+	if j.Opacity != nil {
+		opacity = *j.Opacity
+	}
+	g := parent.nest(visible, opacity, j.OffsetX, j.OffsetY)
+	layer := &Layer{
+		ID:         j.ID,
+		Name:       j.Name,
+		Visible:    g.visible,
+		Opacity:    g.opacity,
+		OffsetX:    g.offset_x,
+		OffsetY:    g.offset_y,
+		Properties: j.Properties.convert(),
+	}
+
+	switch j.Type {
+	case "tilelayer":
+		layer.Kind = TileLayer
+		layer.Width, layer.Height = j.Width, j.Height
+		if len(j.Chunks) > 0 {
+			return errors.New("infinite maps aren't supported")
+		}
+		if err := check_size(fmt.Sprintf("layer %q", j.Name), j.Width, j.Height); err != nil {
+			return err
+		}
+		count := j.Width * j.Height
+		if j.Encoding == "base64" {
+			var data string
+			if err := json.Unmarshal(j.Data, &data); err != nil {
+				return fmt.Errorf("layer %q: bad data: %w", j.Name, err)
+			}
+			tiles, err := decode_data(j.Encoding, j.Compression, data, count)
+			if err != nil {
+				return fmt.Errorf("layer %q: %w", j.Name, err)
+			}
+			layer.Tiles = tiles
+		} else {
+			if err := json.Unmarshal(j.Data, &layer.Tiles); err != nil {
+				return fmt.Errorf("layer %q: bad data: %w", j.Name, err)
+			}
+			if len(layer.Tiles) != count {
+				return fmt.Errorf("layer %q has %d tiles, not %d", j.Name, len(layer.Tiles), count)
+			}
+		}
+	case "objectgroup":
+		layer.Kind = ObjectLayer
+		for _, o := range j.Objects {
+			layer.Objects = append(layer.Objects, o.convert())
+		}
+	case "group":
+		for _, child := range j.Layers {
+			if err := m.add_json_layer(&child, g); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		// image layers
+		return nil
+	}
+	m.Layers = append(m.Layers, layer)
+	return nil
+}
+
+func (j *json_object) convert() *Object {
+	o := &Object{
+		ID:         j.ID,
+		Name:       j.Name,
+		Type:       j.Type,
+		X:          j.X,
+		Y:          j.Y,
+		Width:      j.Width,
+		Height:     j.Height,
+		Rotation:   j.Rotation,
+		GID:        j.GID,
+		Visible:    j.Visible == nil || *j.Visible,
+		Properties: j.Properties.convert(),
+	}
+	if o.Type == "" {
+		o.Type = j.Class
+	}
+	switch {
+	case j.Ellipse:
+		o.Shape = Ellipse
+	case j.Point:
+		o.Shape = Point
+	case j.Polygon != nil:
+		o.Shape = Polygon
+		for _, p := range j.Polygon {
+			o.Points = append(o.Points, vec2{p.X, p.Y})
+		}
+	case j.Polyline != nil:
+		o.Shape = Polyline
+		for _, p := range j.Polyline {
+			o.Points = append(o.Points, vec2{p.X, p.Y})
+		}
+	}
+	return o
+}
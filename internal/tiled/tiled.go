@@ -0,0 +1,314 @@
+// Package tiled loads maps made with the Tiled editor (mapeditor.org), from its XML format (.tmx) or its JSON one
+// (.tmj or .json), with their tilesets embedded or in files of their own (.tsx, .tsj). Tile layers and object
+// layers load, and groups of layers are flattened into the layers under them, which carry the offset, opacity and
+// visibility of their groups. Image layers, infinite maps and tilesets of one image per tile don't load.
+//
+// Package tilemap draws what this loads.
+package tiled
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+)
+
+// GID is a tile of a map: the ID of the tile over all the tilesets of the map, counting from 1 since 0 is no tile,
+// with how the tile is flipped in its highest bits.
+type GID uint32
+
+// Flips of a GID.
+const (
+	FlipHorizontal GID = 0x80000000
+	FlipVertical   GID = 0x40000000
+	// FlipDiagonal swaps the x and y of the tile, which with the other two flips makes rotations by 90 degrees.
+	FlipDiagonal GID = 0x20000000
+	// rotated_hexagonal rotates tiles of hexagonal maps, which don't draw, it's masked off
+	rotated_hexagonal GID = 0x10000000
+
+	all_flips = FlipHorizontal | FlipVertical | FlipDiagonal | rotated_hexagonal
+)
+
+// ID is the tile without its flips.
+func (g GID) ID() uint32 {
+	return uint32(g &^ all_flips)
+}
+
+// Flipped reports whether the tile is flipped by `flip`.
+func (g GID) Flipped(flip GID) bool {
+	return g&flip != 0
+}
+
+// Orientations of a map.
+const (
+	Orthogonal = "orthogonal"
+	Isometric  = "isometric"
+	Staggered  = "staggered"
+	Hexagonal  = "hexagonal"
+)
+
+// Properties are the custom properties of a map, layer, tile or object, by name. Values are as Tiled writes them,
+// colors as #aarrggbb, booleans as true or false.
+type Properties map[string]string
+
+// Map is what a map file holds.
+type Map struct {
+	Orientation string
+	// Width and Height are in tiles, TileWidth and TileHeight the size of a tile of the grid in pixels. Tiles of
+	// tilesets can be larger, they stick out of the top and right of their cell.
+	Width, Height         int
+	TileWidth, TileHeight int
+	// Tilesets are in the order of their FirstGID.
+	Tilesets   []*Tileset
+	Layers     []*Layer
+	Properties Properties
+}
+
+// Tileset is an image cut into a grid of tiles.
+type Tileset struct {
+	// FirstGID is the GID of the first tile of the set in the map, the others follow.
+	FirstGID              uint32
+	Name                  string
+	TileWidth, TileHeight int
+	TileCount, Columns    int
+	// Spacing is the pixels between tiles in the image, Margin those around them all.
+	Spacing, Margin int
+	// Image is the path of the image in the file system the map was loaded from.
+	Image                   string
+	ImageWidth, ImageHeight int
+	// OffsetX and OffsetY move every tile of the set when it's drawn, in pixels.
+	OffsetX, OffsetY int
+	// Tiles are the tiles with more to them than their place in the image, by their ID in the set.
+	Tiles map[uint32]*Tile
+}
+
+// Tile is a tile of a tileset with a type, properties or an animation.
+type Tile struct {
+	Type       string
+	Properties Properties
+	// Animation is the tiles shown in turn in place of this one, in a loop.
+	Animation []Frame
+}
+
+// Frame is a tile of an animation, by its ID in the tileset, and how long it's shown.
+type Frame struct {
+	TileID   uint32
+	Duration time.Duration
+}
+
+// LayerKind is what a layer holds.
+type LayerKind int
+
+const (
+	TileLayer LayerKind = iota
+	ObjectLayer
+)
+
+// Layer is a layer of the map, of tiles or of objects.
+type Layer struct {
+	Kind LayerKind
+	ID   int
+	Name string
+	// Visible, Opacity and the offsets take those of the groups the layer was in into account.
+	Visible          bool
+	Opacity          float
+	OffsetX, OffsetY float
+	Properties       Properties
+
+	// Width, Height and Tiles are those of a tile layer, its tiles row by row from the top left, 0 being no tile.
+	Width, Height int
+	Tiles         []GID
+
+	// Objects are those of an object layer.
+	Objects []*Object
+}
+
+// At is the tile at column `x` and row `y` of a tile layer, 0 outside of it.
+func (l *Layer) At(x, y int) GID {
+	if x < 0 || y < 0 || x >= l.Width || y >= l.Height {
+		return 0
+	}
+	return l.Tiles[y*l.Width+x]
+}
+
+// Shape is the shape of an object.
+type Shape int
+
+const (
+	Rectangle Shape = iota
+	Ellipse
+	Point
+	Polygon
+	Polyline
+)
+
+// Object is something placed on an object layer: a shape, or a tile when it has a GID.
+type Object struct {
+	ID         int
+	Name, Type string
+	// X and Y are the top left of the object in pixels, or the bottom left of a tile object on an orthogonal map.
+	X, Y, Width, Height float
+	// Rotation is in degrees clockwise around X and Y.
+	Rotation float
+	GID      GID
+	Visible  bool
+	Shape    Shape
+	// Points are those of a polygon or polyline, relative to X and Y.
+	Points     []vec2
+	Properties Properties
+}
+
+// Tileset is the tileset `gid` is a tile of, and the ID of the tile in it. It's nil for no tile or a GID past the
+// tilesets of the map.
+func (m *Map) Tileset(gid GID) (*Tileset, uint32) {
+	id := gid.ID()
+	if id == 0 {
+		return nil, 0
+	}
+	for i := len(m.Tilesets) - 1; i >= 0; i-- {
+		ts := m.Tilesets[i]
+		if id >= ts.FirstGID {
+			if id-ts.FirstGID >= uint32(ts.TileCount) {
+				return nil, 0
+			}
+			return ts, id - ts.FirstGID
+		}
+	}
+	return nil, 0
+}
+
+// Rect is where tile `id` of the set is in its image.
+func (ts *Tileset) Rect(id uint32) image.Rectangle {
+	column, row := int(id)%ts.Columns, int(id)/ts.Columns
+	x := ts.Margin + column*(ts.TileWidth+ts.Spacing)
+	y := ts.Margin + row*(ts.TileHeight+ts.Spacing)
+	return image.Rect(x, y, x+ts.TileWidth, y+ts.TileHeight)
+}
+
+// Animate is the tile shown in place of tile `id` of the set `elapsed` into its animation, `id` itself when it
+// isn't animated.
+func (ts *Tileset) Animate(id uint32, elapsed time.Duration) uint32 {
+	tile := ts.Tiles[id]
+	if tile == nil || len(tile.Animation) == 0 {
+		return id
+	}
+	var loop time.Duration
+	for _, f := range tile.Animation {
+		loop += f.Duration
+	}
+	if loop <= 0 {
+		return tile.Animation[0].TileID
+	}
+	elapsed %= loop
+	if elapsed < 0 {
+		elapsed += loop
+	}
+	for _, f := range tile.Animation {
+		if elapsed < f.Duration {
+			return f.TileID
+		}
+		elapsed -= f.Duration
+	}
+	return tile.Animation[len(tile.Animation)-1].TileID
+}
+
+// Load loads the map at `name` in `fsys`, and the tilesets it references relative to it. Files ending in .tmx are
+// read as XML, any other as JSON.
+func Load(fsys fs.FS, name string) (*Map, error) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	l := &loader{fsys: fsys, dir: path.Dir(name)}
+	var m *Map
+	if strings.EqualFold(path.Ext(name), ".tmx") {
+		m, err = l.decode_tmx(src)
+	} else {
+		m, err = l.decode_json(src)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// loader resolves the files a map references, relative to the directory of the map.
+type loader struct {
+	fsys fs.FS
+	dir  string
+}
+
+// tileset loads the tileset file at `source`, relative to the map.
+func (l *loader) tileset(source string, first_gid uint32) (*Tileset, error) {
+	name := path.Join(l.dir, source)
+	src, err := fs.ReadFile(l.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	// the images of a tileset file are relative to it
+	sub := &loader{fsys: l.fsys, dir: path.Dir(name)}
+	var ts *Tileset
+	if strings.EqualFold(path.Ext(name), ".tsx") {
+		ts, err = sub.decode_tsx(src, first_gid)
+	} else {
+		ts, err = sub.decode_tsj(src, first_gid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ts, nil
+}
+
+// max_tiles is the most tiles a map or a layer may have, 4096x4096, past which the file is taken to be broken rather
+// than allocated for.
+const max_tiles = 4096 * 4096
+
+// check_size makes sure `what`, a map or a layer, is `width` by `height` tiles a map could be.
+func check_size(what string, width, height int) error {
+	if width <= 0 || height <= 0 || width > max_tiles/height {
+		return fmt.Errorf("%s is %dx%d tiles", what, width, height)
+	}
+	return nil
+}
+
+// check fills in what a tileset left out and makes sure the map can be drawn with it.
+func (l *loader) check(ts *Tileset) error {
+	if ts.Image == "" {
+		return fmt.Errorf("tileset %q has an image per tile, which isn't supported", ts.Name)
+	}
+	if ts.TileWidth <= 0 || ts.TileHeight <= 0 {
+		return fmt.Errorf("tileset %q has tiles of %dx%d", ts.Name, ts.TileWidth, ts.TileHeight)
+	}
+	ts.Image = path.Join(l.dir, ts.Image)
+	if ts.Columns <= 0 {
+		ts.Columns = max((ts.ImageWidth-2*ts.Margin+ts.Spacing)/(ts.TileWidth+ts.Spacing), 1)
+	}
+	if ts.TileCount <= 0 {
+		rows := (ts.ImageHeight - 2*ts.Margin + ts.Spacing) / (ts.TileHeight + ts.Spacing)
+		ts.TileCount = ts.Columns * max(rows, 1)
+	}
+	return nil
+}
+
+// group is what the groups a layer is in add up to.
+type group struct {
+	visible            bool
+	opacity            float
+	offset_x, offset_y float
+}
+
+var root_group = group{visible: true, opacity: 1}
+
+// nest is the group a layer of `g` makes for the layers under it.
+func (g group) nest(visible bool, opacity, offset_x, offset_y float) group {
+	return group{g.visible && visible, g.opacity * opacity, g.offset_x + offset_x, g.offset_y + offset_y}
+}
@@ -0,0 +1,260 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"io"
+	"path"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// encode is `tiles` as base64 layer data, compressed by `compress` unless it's nil.
+func encode(t *testing.T, tiles []GID, compress func(io.Writer) io.WriteCloser) string {
+	var b bytes.Buffer
+	var w io.Writer = &b
+	var c io.WriteCloser
+	if compress != nil {
+		c = compress(&b)
+		w = c
+	}
+	if err := binary.Write(w, binary.LittleEndian, tiles); err != nil {
+		t.Fatal(err)
+	}
+	if c != nil {
+		c.Close()
+	}
+	return base64.StdEncoding.EncodeToString(b.Bytes())
+}
+
+var ground = []GID{1, 2, 3, 4, 5, 6}
+
+// decoration has a tile of the second tileset flipped both ways
+var decoration = []GID{0, 0, 17 | FlipHorizontal | FlipVertical, 0, 0, 0}
+
+const tsx = `<?xml version="1.0" encoding="UTF-8"?>
+<tileset name="terrain" tilewidth="16" tileheight="16" tilecount="16" columns="4" spacing="2" margin="1">
+ <image source="images/terrain.png" width="74" height="74"/>
+ <tile id="3" type="water">
+  <properties><property name="speed" type="float" value="1.5"/></properties>
+  <animation>
+   <frame tileid="3" duration="100"/>
+   <frame tileid="7" duration="300"/>
+  </animation>
+ </tile>
+</tileset>
+`
+
+func tmx(t *testing.T) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" renderorder="right-down" width="3" height="2" tilewidth="16" tileheight="16" infinite="0">
+ <properties><property name="music" value="forest"/></properties>
+ <tileset firstgid="1" source="tilesets/terrain.tsx"/>
+ <tileset firstgid="17" name="props" tilewidth="16" tileheight="32" tilecount="4" columns="4">
+  <tileoffset x="0" y="2"/>
+  <image source="props.png" width="64" height="32"/>
+ </tileset>
+ <layer id="1" name="ground" width="3" height="2">
+  <data encoding="csv">
+1,2,3,
+4,5,6
+</data>
+ </layer>
+ <group id="2" name="above" offsetx="4" opacity="0.5">
+  <layer id="3" name="decoration" width="3" height="2" offsety="2" opacity="0.5">
+   <data encoding="base64" compression="zlib">` + encode(t, decoration, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }) + `</data>
+  </layer>
+  <objectgroup id="4" name="things" visible="0">
+   <object id="1" name="chest" type="loot" gid="18" x="16" y="32" width="16" height="32"/>
+   <object id="2" name="spawn" x="8" y="8"><point/></object>
+   <object id="3" name="pond" x="0" y="0" width="10" height="6"><ellipse/></object>
+   <object id="4" name="fence" x="1" y="2" rotation="90"><polygon points="0,0 10,0 10,5.5"/></object>
+  </objectgroup>
+ </group>
+ <imagelayer id="5" name="backdrop"><image source="sky.png"/></imagelayer>
+</map>
+`
+}
+
+func json_map_source(t *testing.T) string {
+	return `{
+ "orientation": "orthogonal", "width": 3, "height": 2, "tilewidth": 16, "tileheight": 16, "infinite": false,
+ "properties": [{"name": "music", "type": "string", "value": "forest"}],
+ "tilesets": [
+  {"firstgid": 1, "source": "tilesets/terrain.tsj"},
+  {"firstgid": 17, "name": "props", "tilewidth": 16, "tileheight": 32, "tilecount": 4, "columns": 4,
+   "tileoffset": {"x": 0, "y": 2}, "image": "props.png", "imagewidth": 64, "imageheight": 32}
+ ],
+ "layers": [
+  {"type": "tilelayer", "id": 1, "name": "ground", "width": 3, "height": 2, "data": [1, 2, 3, 4, 5, 6]},
+  {"type": "group", "id": 2, "name": "above", "offsetx": 4, "opacity": 0.5, "layers": [
+   {"type": "tilelayer", "id": 3, "name": "decoration", "width": 3, "height": 2, "offsety": 2, "opacity": 0.5,
+    "encoding": "base64", "compression": "gzip", "data": "` + encode(t, decoration, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }) + `"},
+   {"type": "objectgroup", "id": 4, "name": "things", "visible": false, "objects": [
+    {"id": 1, "name": "chest", "type": "loot", "gid": 18, "x": 16, "y": 32, "width": 16, "height": 32},
+    {"id": 2, "name": "spawn", "x": 8, "y": 8, "point": true},
+    {"id": 3, "name": "pond", "x": 0, "y": 0, "width": 10, "height": 6, "ellipse": true},
+    {"id": 4, "name": "fence", "x": 1, "y": 2, "rotation": 90, "polygon": [{"x": 0, "y": 0}, {"x": 10, "y": 0}, {"x": 10, "y": 5.5}]}
+   ]}
+  ]},
+  {"type": "imagelayer", "id": 5, "name": "backdrop", "image": "sky.png"}
+ ]
+}`
+}
+
+const tsj = `{
+ "name": "terrain", "tilewidth": 16, "tileheight": 16, "tilecount": 16, "columns": 4, "spacing": 2, "margin": 1,
+ "image": "images/terrain.png", "imagewidth": 74, "imageheight": 74,
+ "tiles": [{"id": 3, "type": "water", "properties": [{"name": "speed", "type": "float", "value": 1.5}],
+  "animation": [{"tileid": 3, "duration": 100}, {"tileid": 7, "duration": 300}]}]
+}`
+
+// check checks `m` is the map of tmx and json_map_source, which are the same map in either format, loaded from `dir`.
+func check(t *testing.T, m *Map, dir string) {
+	t.Helper()
+	if m.Orientation != Orthogonal || m.Width != 3 || m.Height != 2 || m.TileWidth != 16 || m.Properties["music"] != "forest" {
+		t.Fatalf("map is %+v", m)
+	}
+
+	if len(m.Tilesets) != 2 {
+		t.Fatalf("%d tilesets", len(m.Tilesets))
+	}
+	terrain, props := m.Tilesets[0], m.Tilesets[1]
+	// images are relative to the root of the file system, and those of tileset files were relative to them
+	if terrain.Image != path.Join(dir, "tilesets/images/terrain.png") || props.Image != path.Join(dir, "props.png") {
+		t.Errorf("images are %s and %s, relative to their files", terrain.Image, props.Image)
+	}
+	if water := terrain.Tiles[3]; water == nil || water.Type != "water" || water.Properties["speed"] != "1.5" ||
+		len(water.Animation) != 2 || water.Animation[1] != (Frame{7, 300 * time.Millisecond}) {
+		t.Errorf("the water tile is %+v", terrain.Tiles[3])
+	}
+	if props.OffsetY != 2 || props.TileHeight != 32 {
+		t.Errorf("props are %+v", props)
+	}
+
+	if len(m.Layers) != 3 {
+		t.Fatalf("%d layers, the group should be flattened and the image layer skipped", len(m.Layers))
+	}
+	g, d, o := m.Layers[0], m.Layers[1], m.Layers[2]
+	if g.Kind != TileLayer || !slices.Equal(g.Tiles, ground) || g.At(2, 1) != 6 || g.At(3, 0) != 0 {
+		t.Errorf("ground is %+v", g)
+	}
+	if !slices.Equal(d.Tiles, decoration) || d.OffsetX != 4 || d.OffsetY != 2 || d.Opacity != 0.25 || !d.Visible {
+		t.Errorf("decoration is %+v, in a group offset by 4 at half opacity", d)
+	}
+	if o.Kind != ObjectLayer || o.Visible || len(o.Objects) != 4 {
+		t.Fatalf("things are %+v", o)
+	}
+	chest, spawn, pond, fence := o.Objects[0], o.Objects[1], o.Objects[2], o.Objects[3]
+	if chest.GID != 18 || chest.Type != "loot" || chest.Shape != Rectangle || chest.Y != 32 || !chest.Visible {
+		t.Errorf("chest is %+v", chest)
+	}
+	if spawn.Shape != Point || pond.Shape != Ellipse || pond.Width != 10 {
+		t.Errorf("spawn is %+v and pond %+v", spawn, pond)
+	}
+	if fence.Shape != Polygon || fence.Rotation != 90 || len(fence.Points) != 3 || fence.Points[2] != (vec2{10, 5.5}) {
+		t.Errorf("fence is %+v", fence)
+	}
+}
+
+func TestLoadTMX(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/a.tmx":                {Data: []byte(tmx(t))},
+		"maps/tilesets/terrain.tsx": {Data: []byte(tsx)},
+	}
+	m, err := Load(fsys, "maps/a.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, m, "maps")
+}
+
+func TestLoadJSON(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.tmj":                {Data: []byte(json_map_source(t))},
+		"tilesets/terrain.tsj": {Data: []byte(tsj)},
+	}
+	m, err := Load(fsys, "a.tmj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, m, ".")
+}
+
+func TestLoadErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"short.tmx":       `<map width="2" height="1" tilewidth="8" tileheight="8"><layer name="a" width="2" height="1"><data encoding="csv">1</data></layer></map>`,
+		"infinite.tmx":    `<map infinite="1"></map>`,
+		"negative.tmx":    `<map width="2" height="1" tilewidth="8" tileheight="8"><layer name="a" width="-2" height="1"><data encoding="base64">AAAAAA==</data></layer></map>`,
+		"huge.tmx":        `<map width="2" height="1" tilewidth="8" tileheight="8"><layer name="a" width="1000000" height="1000000"><data encoding="base64">AAAAAA==</data></layer></map>`,
+		"overflow.json":   `{"width": 1, "height": 1, "layers": [{"type": "tilelayer", "width": 4294967296, "height": 4294967296, "encoding": "base64", "data": "AAAAAA=="}]}`,
+		"empty.json":      `{"width": 0, "height": 0, "layers": []}`,
+		"zstd.json":       `{"width": 1, "height": 1, "layers": [{"type": "tilelayer", "width": 1, "height": 1, "encoding": "base64", "compression": "zstd", "data": "AAAAAA=="}]}`,
+		"missing.json":    `{"width": 1, "height": 1, "tilesets": [{"firstgid": 1, "source": "nowhere.tsj"}]}`,
+		"collection.json": `{"width": 1, "height": 1, "tilesets": [{"firstgid": 1, "name": "images", "tilewidth": 8, "tileheight": 8, "tiles": [{"id": 0, "image": "a.png"}]}]}`,
+	} {
+		if _, err := Load(fstest.MapFS{name: {Data: []byte(src)}}, name); err == nil {
+			t.Errorf("%s loaded", name)
+		}
+	}
+}
+
+func TestBase64(t *testing.T) {
+	tiles, err := decode_data("base64", "", encode(t, ground, nil), len(ground))
+	if err != nil || !slices.Equal(tiles, ground) {
+		t.Fatalf("decoded %v, %v", tiles, err)
+	}
+	if _, err := decode_data("base64", "", encode(t, ground, nil), len(ground)+1); err == nil {
+		t.Fatal("data short of a tile decoded")
+	}
+}
+
+func TestTileset(t *testing.T) {
+	m := &Map{Tilesets: []*Tileset{
+		{FirstGID: 1, TileCount: 16, Columns: 4, TileWidth: 16, TileHeight: 16, Spacing: 2, Margin: 1},
+		{FirstGID: 17, TileCount: 4, Columns: 4, TileWidth: 16, TileHeight: 32},
+	}}
+	for _, c := range []struct {
+		gid GID
+		set int
+		id  uint32
+	}{{0, -1, 0}, {1, 0, 0}, {16, 0, 15}, {17, 1, 0}, {20 | FlipDiagonal, 1, 3}, {21, -1, 0}} {
+		ts, id := m.Tileset(c.gid)
+		if (c.set < 0 && ts != nil) || (c.set >= 0 && (ts != m.Tilesets[c.set] || id != c.id)) {
+			t.Errorf("gid %x is tile %d of %v", uint32(c.gid), id, ts)
+		}
+	}
+
+	// the sixth tile is in the second row and column, past the margin and the spacing between
+	if r := m.Tilesets[0].Rect(5); r != image.Rect(19, 19, 35, 35) {
+		t.Errorf("tile 5 is at %v", r)
+	}
+
+	g := 7 | FlipHorizontal | FlipDiagonal
+	if g.ID() != 7 || !g.Flipped(FlipHorizontal) || g.Flipped(FlipVertical) || !g.Flipped(FlipDiagonal) {
+		t.Errorf("%x has the wrong flips", uint32(g))
+	}
+}
+
+func TestAnimate(t *testing.T) {
+	ts := &Tileset{Tiles: map[uint32]*Tile{
+		3: {Animation: []Frame{{3, 100 * time.Millisecond}, {7, 300 * time.Millisecond}}},
+	}}
+	for _, c := range []struct {
+		elapsed time.Duration
+		want    uint32
+	}{{0, 3}, {99 * time.Millisecond, 3}, {100 * time.Millisecond, 7}, {399 * time.Millisecond, 7}, {400 * time.Millisecond, 3}, {-50 * time.Millisecond, 7}} {
+		if got := ts.Animate(3, c.elapsed); got != c.want {
+			t.Errorf("%v into the animation shows %d, not %d", c.elapsed, got, c.want)
+		}
+	}
+	if got := ts.Animate(2, time.Second); got != 2 {
+		t.Errorf("a still tile shows %d", got)
+	}
+}
@@ -0,0 +1,336 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The XML of .tmx and .tsx files, see https://doc.mapeditor.org/en/stable/reference/tmx-map-format/.
+
+type tmx_map struct {
+	Orientation string         `xml:"orientation,attr"`
+	Width       int            `xml:"width,attr"`
+	Height      int            `xml:"height,attr"`
+	TileWidth   int            `xml:"tilewidth,attr"`
+	TileHeight  int            `xml:"tileheight,attr"`
+	Infinite    int            `xml:"infinite,attr"`
+	Properties  tmx_properties `xml:"properties"`
+	Tilesets    []tmx_tileset  `xml:"tileset"`
+	// Layers are the tile layers, object groups and groups in their order in the file
+	Layers []tmx_layer `xml:",any"`
+}
+
+type tmx_tileset struct {
+	FirstGID   uint32 `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr"`
+	Name       string `xml:"name,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
+	Spacing    int    `xml:"spacing,attr"`
+	Margin     int    `xml:"margin,attr"`
+	Image      struct {
+		Source string `xml:"source,attr"`
+		Width  int    `xml:"width,attr"`
+		Height int    `xml:"height,attr"`
+	} `xml:"image"`
+	TileOffset struct {
+		X int `xml:"x,attr"`
+		Y int `xml:"y,attr"`
+	} `xml:"tileoffset"`
+	Tiles []struct {
+		ID         uint32         `xml:"id,attr"`
+		Type       string         `xml:"type,attr"`
+		Class      string         `xml:"class,attr"`
+		Properties tmx_properties `xml:"properties"`
+		Animation  []struct {
+			TileID   uint32 `xml:"tileid,attr"`
+			Duration int    `xml:"duration,attr"`
+		} `xml:"animation>frame"`
+	} `xml:"tile"`
+}
+
+type tmx_layer struct {
+	XMLName    xml.Name
+	ID         int            `xml:"id,attr"`
+	Name       string         `xml:"name,attr"`
+	Visible    *int           `xml:"visible,attr"`
+	Opacity    *float         `xml:"opacity,attr"`
+	OffsetX    float          `xml:"offsetx,attr"`
+	OffsetY    float          `xml:"offsety,attr"`
+	Width      int            `xml:"width,attr"`
+	Height     int            `xml:"height,attr"`
+	Properties tmx_properties `xml:"properties"`
+	Data       *struct {
+		Encoding    string `xml:"encoding,attr"`
+		Compression string `xml:"compression,attr"`
+		Text        string `xml:",chardata"`
+		Tiles       []struct {
+			GID GID `xml:"gid,attr"`
+		} `xml:"tile"`
+		Chunks []struct{} `xml:"chunk"`
+	} `xml:"data"`
+	Objects []tmx_object `xml:"object"`
+	// Layers are those of a group
+	Layers []tmx_layer `xml:",any"`
+}
+
+type tmx_object struct {
+	ID         int            `xml:"id,attr"`
+	Name       string         `xml:"name,attr"`
+	Type       string         `xml:"type,attr"`
+	Class      string         `xml:"class,attr"`
+	X          float          `xml:"x,attr"`
+	Y          float          `xml:"y,attr"`
+	Width      float          `xml:"width,attr"`
+	Height     float          `xml:"height,attr"`
+	Rotation   float          `xml:"rotation,attr"`
+	GID        GID            `xml:"gid,attr"`
+	Visible    *int           `xml:"visible,attr"`
+	Properties tmx_properties `xml:"properties"`
+	Ellipse    *struct{}      `xml:"ellipse"`
+	Point      *struct{}      `xml:"point"`
+	Polygon    *tmx_points    `xml:"polygon"`
+	Polyline   *tmx_points    `xml:"polyline"`
+}
+
+type tmx_points struct {
+	Points string `xml:"points,attr"`
+}
+
+type tmx_properties struct {
+	Properties []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+		// Text is the value of a string with more than one line
+		Text string `xml:",chardata"`
+	} `xml:"property"`
+}
+
+func (p tmx_properties) convert() Properties {
+	if len(p.Properties) == 0 {
+		return nil
+	}
+	props := make(Properties, len(p.Properties))
+	for _, prop := range p.Properties {
+		if prop.Value == "" {
+			prop.Value = prop.Text
+		}
+		props[prop.Name] = prop.Value
+	}
+	return props
+}
+
+func (l *loader) decode_tmx(src []byte) (*Map, error) {
+	var x tmx_map
+	if err := xml.Unmarshal(src, &x); err != nil {
+		return nil, fmt.Errorf("bad map: %w", err)
+	}
+	if x.Infinite != 0 {
+		return nil, errors.New("infinite maps aren't supported")
+	}
+	if err := check_size("the map", x.Width, x.Height); err != nil {
+		return nil, err
+	}
+	m := &Map{
+		Orientation: x.Orientation,
+		Width:       x.Width,
+		Height:      x.Height,
+		TileWidth:   x.TileWidth,
+		TileHeight:  x.TileHeight,
+		Properties:  x.Properties.convert(),
+	}
+	for _, t := range x.Tilesets {
+		var ts *Tileset
+		var err error
+		if t.Source != "" {
+			ts, err = l.tileset(t.Source, t.FirstGID)
+		} else {
+			ts, err = l.convert_tsx(&t, t.FirstGID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.Tilesets = append(m.Tilesets, ts)
+	}
+	for _, layer := range x.Layers {
+		if err := m.add_tmx_layer(&layer, root_group); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (l *loader) decode_tsx(src []byte, first_gid uint32) (*Tileset, error) {
+	var x tmx_tileset
+	if err := xml.Unmarshal(src, &x); err != nil {
+		return nil, fmt.Errorf("bad tileset: %w", err)
+	}
+	return l.convert_tsx(&x, first_gid)
+}
+
+func (l *loader) convert_tsx(x *tmx_tileset, first_gid uint32) (*Tileset, error) {
+	ts := &Tileset{
+		FirstGID:    first_gid,
+		Name:        x.Name,
+		TileWidth:   x.TileWidth,
+		TileHeight:  x.TileHeight,
+		TileCount:   x.TileCount,
+		Columns:     x.Columns,
+		Spacing:     x.Spacing,
+		Margin:      x.Margin,
+		Image:       x.Image.Source,
+		ImageWidth:  x.Image.Width,
+		ImageHeight: x.Image.Height,
+		OffsetX:     x.TileOffset.X,
+		OffsetY:     x.TileOffset.Y,
+		Tiles:       make(map[uint32]*Tile),
+	}
+	for _, t := range x.Tiles {
+		tile := &Tile{Type: t.Type, Properties: t.Properties.convert()}
+		if tile.Type == "" {
+			tile.Type = t.Class
+		}
+		for _, f := range t.Animation {
+			tile.Animation = append(tile.Animation, Frame{f.TileID, time.Duration(f.Duration) * time.Millisecond})
+		}
+		ts.Tiles[t.ID] = tile
+	}
+	if err := l.check(ts); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (m *Map) add_tmx_layer(x *tmx_layer, parent group) error {
+	visible := x.Visible == nil || *x.Visible != 0
+	opacity := float(1)
+	if x.Opacity != nil {
+		opacity = *x.Opacity
+	}
+	g := parent.nest(visible, opacity, x.OffsetX, x.OffsetY)
+	layer := &Layer{
+		ID:         x.ID,
+		Name:       x.Name,
+		Visible:    g.visible,
+		Opacity:    g.opacity,
+		OffsetX:    g.offset_x,
+		OffsetY:    g.offset_y,
+		Properties: x.Properties.convert(),
+	}
+
+	switch x.XMLName.Local {
+	case "layer":
+		layer.Kind = TileLayer
+		layer.Width, layer.Height = x.Width, x.Height
+		if x.Data == nil {
+			return fmt.Errorf("layer %q has no data", x.Name)
+		}
+		if len(x.Data.Chunks) > 0 {
+			return errors.New("infinite maps aren't supported")
+		}
+		if err := check_size(fmt.Sprintf("layer %q", x.Name), x.Width, x.Height); err != nil {
+			return err
+		}
+		count := x.Width * x.Height
+		if x.Data.Encoding == "" {
+			// a tile element per tile, the oldest format
+			if len(x.Data.Tiles) != count {
+				return fmt.Errorf("layer %q has %d tiles, not %d", x.Name, len(x.Data.Tiles), count)
+			}
+			layer.Tiles = make([]GID, count)
+			for i, t := range x.Data.Tiles {
+				layer.Tiles[i] = t.GID
+			}
+		} else {
+			tiles, err := decode_data(x.Data.Encoding, x.Data.Compression, x.Data.Text, count)
+			if err != nil {
+				return fmt.Errorf("layer %q: %w", x.Name, err)
+			}
+			layer.Tiles = tiles
+		}
+	case "objectgroup":
+		layer.Kind = ObjectLayer
+		for _, o := range x.Objects {
+			object, err := o.convert()
+			if err != nil {
+				return fmt.Errorf("layer %q: %w", x.Name, err)
+			}
+			layer.Objects = append(layer.Objects, object)
+		}
+	case "group":
+		for _, child := range x.Layers {
+			if err := m.add_tmx_layer(&child, g); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		// image layers and whatever else isn't a layer, like the editor's settings
+		return nil
+	}
+	m.Layers = append(m.Layers, layer)
+	return nil
+}
+
+func (x *tmx_object) convert() (*Object, error) {
+	o := &Object{
+		ID:         x.ID,
+		Name:       x.Name,
+		Type:       x.Type,
+		X:          x.X,
+		Y:          x.Y,
+		Width:      x.Width,
+		Height:     x.Height,
+		Rotation:   x.Rotation,
+		GID:        x.GID,
+		Visible:    x.Visible == nil || *x.Visible != 0,
+		Properties: x.Properties.convert(),
+	}
+	if o.Type == "" {
+		o.Type = x.Class
+	}
+	var err error
+	switch {
+	case x.Ellipse != nil:
+		o.Shape = Ellipse
+	case x.Point != nil:
+		o.Shape = Point
+	case x.Polygon != nil:
+		o.Shape = Polygon
+		o.Points, err = parse_points(x.Polygon.Points)
+	case x.Polyline != nil:
+		o.Shape = Polyline
+		o.Points, err = parse_points(x.Polyline.Points)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("object %d: %w", x.ID, err)
+	}
+	return o, nil
+}
+
+// parse_points reads the points of a polygon, "x,y" pairs separated by spaces.
+func parse_points(s string) ([]vec2, error) {
+	var points []vec2
+	for _, pair := range strings.Fields(s) {
+		xs, ys, ok := strings.Cut(pair, ",")
+		if !ok {
+			return nil, fmt.Errorf("bad point %q", pair)
+		}
+		x, err := strconv.ParseFloat(xs, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad point %q", pair)
+		}
+		y, err := strconv.ParseFloat(ys, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad point %q", pair)
+		}
+		points = append(points, vec2{float(x), float(y)})
+	}
+	return points, nil
+}
@@ -0,0 +1,304 @@
+// Package tilemap draws maps loaded by package tiled. Tiles go through a sprite.Batch, so a layer costs a draw call
+// per tileset it uses however many tiles it has, and only the tiles inside the target are added to it. Animated
+// tiles show the frame of the time they're drawn at, flipped tiles are flipped, and object layers draw the objects
+// which are tiles. The shapes of the other objects can be outlined, to see where they are.
+//
+// Orthogonal and isometric maps are drawn, tiles in the right-down order whatever order the map asks for. Isometric
+// maps go through an iso.Projection, their tiles standing on the bottom of their diamond and their objects placed
+// as Tiled places them, measured in tile heights along both axes of the grid.
+package tilemap
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/iso"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/memory"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/sprite"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/tiled"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+var tilesets = memory.NewCounter("tilesets")
+
+// ellipse_segments is how many lines the outline of an ellipse is made of
+const ellipse_segments = 24
+
+// Renderer draws a map.
+type Renderer struct {
+	Map   *tiled.Map
+	Batch sprite.Batch
+	// Projection places the cells of an isometric map. The top corner of cell 0, 0 is as many half tiles in as the
+	// map is tall, so the map starts at 0, 0 as it does in Tiled.
+	Projection iso.Projection
+
+	images map[*tiled.Tileset]*ebiten.Image
+	bytes  int64
+	// reach is how many cells past the target tiles are still added, for those larger than the grid or offset
+	reach_x, reach_y int
+	sprite           sprite.Sprite
+	outline          []vec2
+}
+
+// New loads the images of the tilesets of `m` from `fsys`, which the map was loaded from.
+func New(m *tiled.Map, fsys fs.FS) (*Renderer, error) {
+	if m.Orientation != tiled.Orthogonal && m.Orientation != tiled.Isometric {
+		return nil, fmt.Errorf("%s maps aren't drawn, only orthogonal and isometric ones", m.Orientation)
+	}
+	r := &Renderer{Map: m, images: make(map[*tiled.Tileset]*ebiten.Image)}
+	tw, th := float(m.TileWidth), float(m.TileHeight)
+	r.Projection = iso.Projection{TileWidth: tw, TileHeight: th, Elevation: th, Origin: vec2{float(m.Height) * tw / 2, 0}}
+	for _, ts := range m.Tilesets {
+		f, err := fsys.Open(ts.Image)
+		if err != nil {
+			r.Deallocate()
+			return nil, err
+		}
+		img, _, err := image.Decode(f)
+		f.Close()
+		if err != nil {
+			r.Deallocate()
+			return nil, fmt.Errorf("%s: %w", ts.Image, err)
+		}
+		r.images[ts] = ebiten.NewImageFromImage(img)
+		size := img.Bounds().Size()
+		r.bytes += int64(size.X*size.Y) * 4
+
+		reach_x := ts.TileWidth - m.TileWidth + abs(ts.OffsetX)
+		reach_y := ts.TileHeight - m.TileHeight + abs(ts.OffsetY)
+		r.reach_x = max(r.reach_x, (reach_x+m.TileWidth-1)/m.TileWidth)
+		r.reach_y = max(r.reach_y, (reach_y+m.TileHeight-1)/m.TileHeight)
+	}
+	if m.Orientation == tiled.Isometric {
+		// going a tile across or up on screen goes a cell along both axes of the grid
+		r.reach_x += r.reach_y
+		r.reach_y = r.reach_x
+	}
+	tilesets.Add(r.bytes)
+	return r, nil
+}
+
+// Deallocate frees the images of the tilesets, the renderer can't draw anymore after.
+func (r *Renderer) Deallocate() {
+	for _, img := range r.images {
+		img.Deallocate()
+	}
+	clear(r.images)
+	tilesets.Add(-r.bytes)
+	r.bytes = 0
+}
+
+// Draw draws the visible layers of the map onto `dst`, moved onto it by `view` from the pixels of the map, with its
+// animations `elapsed` in.
+func (r *Renderer) Draw(dst *ebiten.Image, view ebiten.GeoM, elapsed time.Duration) {
+	for _, l := range r.Map.Layers {
+		if l.Visible {
+			r.DrawLayer(dst, l, view, elapsed)
+		}
+	}
+}
+
+// DrawLayer draws layer `l` whether it's visible or not.
+func (r *Renderer) DrawLayer(dst *ebiten.Image, l *tiled.Layer, view ebiten.GeoM, elapsed time.Duration) {
+	var scale ebiten.ColorScale
+	scale.ScaleAlpha(l.Opacity)
+	switch l.Kind {
+	case tiled.TileLayer:
+		r.draw_tiles(dst, l, view, elapsed, scale)
+	case tiled.ObjectLayer:
+		r.draw_objects(dst, l, view, elapsed, scale)
+	}
+	r.Batch.Flush()
+}
+
+// visible is the columns and rows of the cells of layer `l` which might show on `dst`.
+func (r *Renderer) visible(dst *ebiten.Image, l *tiled.Layer, view ebiten.GeoM) image.Rectangle {
+	all := image.Rect(0, 0, l.Width, l.Height)
+	if !view.IsInvertible() {
+		return all
+	}
+	inverse := view
+	inverse.Invert()
+	bounds := dst.Bounds()
+	lo, hi := vec2{math.MaxFloat32, math.MaxFloat32}, vec2{-math.MaxFloat32, -math.MaxFloat32}
+	for _, corner := range [4]image.Point{bounds.Min, {bounds.Max.X, bounds.Min.Y}, {bounds.Min.X, bounds.Max.Y}, bounds.Max} {
+		x, y := inverse.Apply(float64(corner.X), float64(corner.Y))
+		p := vec2{float(x) - l.OffsetX, float(y) - l.OffsetY}
+		if r.Map.Orientation == tiled.Isometric {
+			p = r.Projection.ToTile(p, 0)
+		} else {
+			p = vec2{p[0] / float(r.Map.TileWidth), p[1] / float(r.Map.TileHeight)}
+		}
+		lo, hi = vec2{min(lo[0], p[0]), min(lo[1], p[1])}, vec2{max(hi[0], p[0]), max(hi[1], p[1])}
+	}
+	cells := image.Rect(
+		int(floor(lo[0]))-r.reach_x, int(floor(lo[1]))-r.reach_y,
+		int(floor(hi[0]))+1+r.reach_x, int(floor(hi[1]))+1+r.reach_y,
+	)
+	return cells.Intersect(all)
+}
+
+func (r *Renderer) draw_tiles(dst *ebiten.Image, l *tiled.Layer, view ebiten.GeoM, elapsed time.Duration, scale ebiten.ColorScale) {
+	m := r.Map
+	cells := r.visible(dst, l, view)
+	for y := cells.Min.Y; y < cells.Max.Y; y++ {
+		for x := cells.Min.X; x < cells.Max.X; x++ {
+			gid := l.Tiles[y*l.Width+x]
+			ts, id := m.Tileset(gid)
+			if ts == nil {
+				continue
+			}
+			// tiles sit on the bottom left of their cell, and stick out of its top and right when they're larger,
+			// the cell of an isometric map being the rectangle around its diamond
+			left, bottom := float(x*m.TileWidth), float((y+1)*m.TileHeight)
+			if m.Orientation == tiled.Isometric {
+				top := r.Projection.ToScreen(vec3{float(x), float(y), 0})
+				left, bottom = top[0]-float(m.TileWidth)/2, top[1]+float(m.TileHeight)
+			}
+			var at ebiten.GeoM
+			at.Translate(
+				float64(left+float(ts.OffsetX)+l.OffsetX),
+				float64(bottom+float(ts.OffsetY-ts.TileHeight)+l.OffsetY),
+			)
+			at.Concat(view)
+			r.add(dst, ts, ts.Animate(id, elapsed), gid, at, scale)
+		}
+	}
+}
+
+func (r *Renderer) draw_objects(dst *ebiten.Image, l *tiled.Layer, view ebiten.GeoM, elapsed time.Duration, scale ebiten.ColorScale) {
+	for _, o := range l.Objects {
+		if !o.Visible || o.GID == 0 {
+			continue
+		}
+		ts, id := r.Map.Tileset(o.GID)
+		if ts == nil {
+			continue
+		}
+		// tile objects are stretched over their size and turn around their bottom left, their bottom middle on
+		// isometric maps
+		var at ebiten.GeoM
+		w, h := float64(ts.TileWidth), float64(ts.TileHeight)
+		if o.GID.Flipped(tiled.FlipDiagonal) {
+			w, h = h, w
+		}
+		if o.Width > 0 && o.Height > 0 {
+			at.Scale(float64(o.Width)/w, float64(o.Height)/h)
+			w, h = float64(o.Width), float64(o.Height)
+		}
+		at.Translate(float64(ts.OffsetX), float64(ts.OffsetY)-h)
+		if r.Map.Orientation == tiled.Isometric {
+			at.Translate(-w/2, 0)
+		}
+		at.Rotate(float64(mgl32.DegToRad(o.Rotation)))
+		p := r.Pixels(vec2{o.X, o.Y})
+		at.Translate(float64(p[0]+l.OffsetX), float64(p[1]+l.OffsetY))
+		at.Concat(view)
+		r.add(dst, ts, ts.Animate(id, elapsed), o.GID, at, scale)
+	}
+}
+
+// add adds tile `id` of `ts` to the batch, flipped as `gid` is.
+func (r *Renderer) add(dst *ebiten.Image, ts *tiled.Tileset, id uint32, gid tiled.GID, at ebiten.GeoM, scale ebiten.ColorScale) {
+	s := &r.sprite
+	*s = sprite.Sprite{
+		Image:        r.images[ts],
+		Src:          ts.Rect(id),
+		GeoM:         at,
+		ColorScale:   scale,
+		FlipX:        gid.Flipped(tiled.FlipHorizontal),
+		FlipY:        gid.Flipped(tiled.FlipVertical),
+		FlipDiagonal: gid.Flipped(tiled.FlipDiagonal),
+	}
+	r.Batch.Add(dst, s)
+}
+
+// Outline outlines the shapes of the objects of layer `l` with `clr`, and marks its points.
+func (r *Renderer) Outline(dst *ebiten.Image, l *tiled.Layer, view ebiten.GeoM, clr color.Color) {
+	for _, o := range l.Objects {
+		if !o.Visible || o.GID != 0 {
+			continue
+		}
+		points := r.outline[:0]
+		closed := true
+		switch o.Shape {
+		case tiled.Rectangle:
+			points = append(points, vec2{0, 0}, vec2{o.Width, 0}, vec2{o.Width, o.Height}, vec2{0, o.Height})
+		case tiled.Ellipse:
+			for i := range ellipse_segments {
+				angle := float64(i) / ellipse_segments * 2 * math.Pi
+				points = append(points, vec2{
+					o.Width / 2 * (1 + float(math.Cos(angle))),
+					o.Height / 2 * (1 + float(math.Sin(angle))),
+				})
+			}
+		case tiled.Polygon:
+			points = append(points, o.Points...)
+		case tiled.Polyline:
+			points = append(points, o.Points...)
+			closed = false
+		case tiled.Point:
+			p := r.Pixels(vec2{o.X, o.Y})
+			x, y := view.Apply(float64(p[0]+l.OffsetX), float64(p[1]+l.OffsetY))
+			vector.StrokeCircle(dst, float(x), float(y), 4, 1, clr, true)
+			continue
+		}
+
+		r.outline = points
+
+		// the points turn around the object's position before they go to the pixels of the map, so the shapes of
+		// an isometric map lie on its grid
+		var at ebiten.GeoM
+		at.Rotate(float64(mgl32.DegToRad(o.Rotation)))
+		at.Translate(float64(o.X), float64(o.Y))
+		for i, p := range points {
+			x, y := at.Apply(float64(p[0]), float64(p[1]))
+			p = r.Pixels(vec2{float(x), float(y)})
+			x, y = view.Apply(float64(p[0]+l.OffsetX), float64(p[1]+l.OffsetY))
+			points[i] = vec2{float(x), float(y)}
+		}
+		for i := range points {
+			if i == len(points)-1 && !closed {
+				break
+			}
+			a, b := points[i], points[(i+1)%len(points)]
+			vector.StrokeLine(dst, a[0], a[1], b[0], b[1], 1, clr, true)
+		}
+	}
+}
+
+// Pixels is where point `p` of an object is in the pixels of the map. They're the same on an orthogonal map, an
+// isometric one measures both axes of the grid in tile heights.
+func (r *Renderer) Pixels(p vec2) vec2 {
+	if r.Map.Orientation != tiled.Isometric {
+		return p
+	}
+	th := float(r.Map.TileHeight)
+	return r.Projection.ToScreen(vec3{p[0] / th, p[1] / th, 0})
+}
+
+func floor(x float) float {
+	return float(math.Floor(float64(x)))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
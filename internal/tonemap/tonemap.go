@@ -0,0 +1,109 @@
+// Package tonemap maps the light of a frame, which can be brighter than a screen shows, into what it shows:
+// exposure scales the light, an operator rolls off what's above 1 rather than clipping it, and the result is encoded
+// to sRGB. It's the last post pass of a frame lit in linear light, see package gamma.
+//
+// Images are 8 bits a channel and can't hold more than 1, so an HDR frame keeps its light divided by Options.Range
+// to leave room above it, at the cost of that many times fewer levels below.
+package tonemap
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type float = float32
+
+type Operator int
+
+const (
+	// None clips what's above 1, the way the frame looks without tone mapping.
+	None Operator = iota
+	// Reinhard divides every channel by one more than itself, which never reaches white and greys out the
+	// brightest colors a little.
+	Reinhard
+	// ACES is the curve of the filmic ACES transform as fitted by Krzysztof Narkowicz: darks a little deeper,
+	// highlights which saturate to white.
+	ACES
+	OperatorCount
+)
+
+func (o Operator) String() string {
+	switch o {
+	case None:
+		return "none"
+	case Reinhard:
+		return "reinhard"
+	case ACES:
+		return "aces"
+	}
+	return fmt.Sprintf("operator(%d)", int(o))
+}
+
+// shader scales the light of image 0 by Scale, maps it by Operator and encodes it to sRGB.
+var shader = `
+//kage:unit pixels
+package main
+
+var Scale float
+var Operator float
+
+func aces(x vec3) vec3 {
+	return clamp((x*(2.51*x+0.03))/(x*(2.43*x+0.59)+0.14), 0, 1)
+}
+
+func to_srgb(c vec3) vec3 {
+	return mix(c*12.92, 1.055*pow(c, vec3(1/2.4))-0.055, step(0.0031308, c))
+}
+
+func Fragment(dst vec4, src vec2, color vec4) vec4 {
+	c := imageSrc0At(src)
+	if c.a == 0 {
+		return c
+	}
+	rgb := c.rgb / c.a * Scale
+	if Operator > 1.5 {
+		rgb = aces(rgb)
+	} else if Operator > 0.5 {
+		rgb = rgb / (1 + rgb)
+	}
+	return vec4(to_srgb(clamp(rgb, 0, 1))*c.a, c.a)
+}
+`
+
+// Options describe how a frame is mapped.
+type Options struct {
+	Operator Operator
+	// Exposure brightens the frame by 2 to its power, in stops.
+	Exposure float
+	// Range is the light a value of 1 in the frame stands for, 1 for a frame which isn't divided.
+	Range float
+}
+
+type Mapper struct {
+	shader *ebiten.Shader
+}
+
+func New() (*Mapper, error) {
+	s, err := ebiten.NewShader([]byte(shader))
+	if err != nil {
+		return nil, err
+	}
+	return &Mapper{shader: s}, nil
+}
+
+// Draw draws the linear frame `src` onto `dst`, mapped by `opts` and encoded to sRGB.
+func (m *Mapper) Draw(dst, src *ebiten.Image, opts Options) {
+	scale := max(opts.Range, 1) * float(math.Exp2(float64(opts.Exposure)))
+	size := src.Bounds().Size()
+	op := &ebiten.DrawRectShaderOptions{
+		Uniforms: map[string]any{
+			"Scale":    scale,
+			"Operator": float(opts.Operator),
+		},
+		Images: [4]*ebiten.Image{src},
+	}
+	op.GeoM.Translate(float64(dst.Bounds().Min.X), float64(dst.Bounds().Min.Y))
+	dst.DrawRectShader(size.X, size.Y, m.shader, op)
+}
@@ -0,0 +1,120 @@
+// Package trail makes ribbons which follow something moving, the streak behind a sword or a projectile or the
+// path a camera took. A Trail remembers where the thing was over the last few seconds and makes a mesh of a
+// ribbon through those points which turns to face the camera, made again in place every frame.
+package trail
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/curve"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+)
+
+type point struct {
+	at   vec3
+	time float
+}
+
+// Trail is the history of something moving.
+type Trail struct {
+	// Width is how wide the ribbon is.
+	Width float
+	// Shape scales Width along the ribbon, from 0 at the newest point to 1 at the oldest. Nil keeps it as wide all
+	// along, a curve falling to 0 makes it taper.
+	Shape *curve.Curve
+	// Lifetime is how many seconds a point is remembered.
+	Lifetime float
+	// Spacing is how far apart the points are at least, closer ones move the newest point along instead of
+	// adding one.
+	Spacing float
+
+	// points are from the oldest to the newest
+	points []point
+	now    float
+	mesh   mesh.Mesh
+}
+
+// Add records that the thing is at `at` at `now` seconds, the same clock Update is given.
+func (t *Trail) Add(at vec3, now float) {
+	t.now = max(t.now, now)
+	n := len(t.points)
+	if n >= 2 && t.points[n-2].at.Sub(at).Len() < t.Spacing {
+		t.points[n-1] = point{at, now}
+		return
+	}
+	t.points = append(t.points, point{at, now})
+}
+
+// Update forgets the points older than Lifetime at `now`.
+func (t *Trail) Update(now float) {
+	t.now = now
+	old := 0
+	for old < len(t.points) && now-t.points[old].time > t.Lifetime {
+		old++
+	}
+	t.points = append(t.points[:0], t.points[old:]...)
+}
+
+// Clear forgets every point, for when the thing jumps somewhere else.
+func (t *Trail) Clear() {
+	t.points = t.points[:0]
+}
+
+// Len is how many points the trail has.
+func (t *Trail) Len() int {
+	return len(t.points)
+}
+
+// Mesh is the mesh of the ribbon seen from `eye`, nil while the trail has fewer than two points. u runs along it
+// from 0 at the newest point to 1 at a point Lifetime old, which a shader can fade by, and v across it from 0 to 1.
+// It's the trail's own, made again in place by every call.
+func (t *Trail) Mesh(eye vec3) *mesh.Mesh {
+	if len(t.points) < 2 {
+		return nil
+	}
+	m := &t.mesh
+	m.Points = m.Points[:0]
+	m.Texcoords = m.Texcoords[:0]
+	m.Triangles = m.Triangles[:0]
+
+	// the newest points are at the end, and the ribbon can only be so long with 16 bit indices
+	points := t.points[max(len(t.points)-0x8000, 0):]
+	var side vec3
+	for i, p := range points {
+		along := points[min(i+1, len(points)-1)].at.Sub(points[max(i-1, 0)].at)
+		// across the ribbon is across both the way it goes and the way to the eye, so it faces the eye; where
+		// that's undefined it keeps the way it went across the last point
+		if s := along.Cross(eye.Sub(p.at)); s.Len() > 1e-9 {
+			side = s.Normalize()
+		}
+		age := min(max((t.now-p.time)/max(t.Lifetime, 1e-6), 0), 1)
+		width := t.Width
+		if t.Shape != nil {
+			width *= t.Shape.Evaluate(age)
+		}
+		half := side.Mul(width / 2)
+		m.Points = append(m.Points, p.at.Sub(half), p.at.Add(half))
+		m.Texcoords = append(m.Texcoords, vec2{age, 0}, vec2{age, 1})
+	}
+	for i := range len(points) - 1 {
+		a, b := uint16(2*i), uint16(2*i+1)
+		c, d := a+2, b+2
+		quad := [2][3]uint16{{a, b, c}, {b, d, c}}
+		// wound to face the eye, which is a matter of which way the trail went
+		pa, pb, pc := m.Points[a], m.Points[b], m.Points[c]
+		if pb.Sub(pa).Cross(pc.Sub(pa)).Dot(eye.Sub(pa)) < 0 {
+			quad = [2][3]uint16{{a, c, b}, {b, c, d}}
+		}
+		for _, q := range quad {
+			m.Triangles = append(m.Triangles, mesh.Triangle{P1: q[0], P2: q[1], P3: q[2], T1: q[0], T2: q[1], T3: q[2]})
+		}
+	}
+	m.ComputeBounds()
+	return m
+}
@@ -0,0 +1,63 @@
+package trail
+
+import (
+	"testing"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/curve"
+)
+
+func TestRibbonFacesEye(t *testing.T) {
+	tr := &Trail{Width: 0.2, Lifetime: 1, Spacing: 0.1}
+	for i := range 11 {
+		tr.Add(vec3{float(i) * 0.1, 0, 0}, float(i)*0.05)
+	}
+	for _, eye := range []vec3{{0.5, 0, 5}, {0.5, 0, -5}, {0.5, 3, 0}} {
+		m := tr.Mesh(eye)
+		if len(m.Triangles) != 20 {
+			t.Fatalf("a trail of 11 points has %d triangles", len(m.Triangles))
+		}
+		for i, tri := range m.Triangles {
+			p1, p2, p3 := m.Points[tri.P1], m.Points[tri.P2], m.Points[tri.P3]
+			if p2.Sub(p1).Cross(p3.Sub(p1)).Dot(eye.Sub(p1)) <= 0 {
+				t.Fatalf("triangle %d turns away from the eye at %v", i, eye)
+			}
+		}
+		if w := m.Points[1].Sub(m.Points[0]).Len(); w < 0.2-1e-5 || w > 0.2+1e-5 {
+			t.Fatalf("the ribbon is %v wide", w)
+		}
+	}
+}
+
+func TestPointsExpire(t *testing.T) {
+	tr := &Trail{Width: 1, Lifetime: 0.5, Spacing: 0.5}
+	tr.Add(vec3{0, 0, 0}, 0)
+	tr.Add(vec3{0.2, 0, 0}, 0.2)
+	// too close to the point before the newest, so the newest moves
+	tr.Add(vec3{0.3, 0, 0}, 0.3)
+	if tr.Len() != 2 {
+		t.Fatalf("the trail has %d points, want 2", tr.Len())
+	}
+	tr.Add(vec3{2, 0, 0}, 0.4)
+	tr.Update(0.6)
+	if tr.Len() != 2 {
+		t.Fatalf("after the first point is 0.6 old the trail has %d points", tr.Len())
+	}
+	tr.Update(2)
+	if tr.Len() != 0 || tr.Mesh(vec3{0, 0, 1}) != nil {
+		t.Fatalf("a trail whose points all expired has %d", tr.Len())
+	}
+}
+
+func TestTaper(t *testing.T) {
+	tr := &Trail{Width: 1, Lifetime: 1, Shape: &curve.Curve{Keys: []curve.Key{{T: 0, V: 1}, {T: 1, V: 0}}}}
+	tr.Add(vec3{0, 0, 0}, 0)
+	tr.Add(vec3{1, 0, 0}, 1)
+	m := tr.Mesh(vec3{0, 0, 5})
+	// the oldest point is a Lifetime old and has no width, the newest all of it
+	if old, new := m.Points[1].Sub(m.Points[0]).Len(), m.Points[3].Sub(m.Points[2]).Len(); old > 1e-5 || new < 1-1e-5 {
+		t.Fatalf("the tapered ribbon is %v wide at its tail and %v at its head", old, new)
+	}
+	if n := testing.AllocsPerRun(10, func() { tr.Mesh(vec3{0, 0, 5}) }); n != 0 {
+		t.Fatalf("making the ribbon again allocates %v times", n)
+	}
+}
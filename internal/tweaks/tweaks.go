@@ -0,0 +1,138 @@
+// Package tweaks is the tweak panel, which lists every cvar and nudges numbers and flips bools with the mouse.
+// Colors are only shown, they're set from the console.
+package tweaks
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/ui"
+)
+
+const (
+	panel_width  = 320
+	row_height   = 16
+	name_width   = 168
+	value_width  = 96
+	button_width = (panel_width - name_width - value_width) / 2
+)
+
+type Panel struct {
+	ui *ui.Context
+
+	open   bool
+	scroll int
+}
+
+func New() *Panel {
+	return &Panel{
+		ui: ui.NewContext(),
+	}
+}
+
+func (p *Panel) Toggle() {
+	p.open = !p.open
+}
+
+func (p *Panel) Open() bool {
+	return p.open
+}
+
+func (p *Panel) Draw(screen *ebiten.Image) {
+	if !p.open {
+		return
+	}
+
+	bounds := screen.Bounds()
+	panel := image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Min.X+panel_width, bounds.Max.Y)
+
+	vars := cvar.All()
+	visible_rows := panel.Dy() / row_height
+
+	if ui.CursorWithin(panel) {
+		_, wheel := input.Wheel()
+		p.scroll -= int(wheel) * 3
+	}
+	p.scroll = min(max(p.scroll, 0), max(len(vars)-visible_rows, 0))
+
+	p.ui.StartFrame(screen)
+	p.ui.Push(panel.Min.X, panel.Min.Y, panel.Dx(), panel.Dy(), nil)
+	p.ui.Panel()
+
+	if len(vars) == 0 {
+		p.ui.Label("no cvars", 0.5, 0.5)
+	}
+
+	y := panel.Min.Y
+	for _, v := range vars[p.scroll:min(p.scroll+visible_rows, len(vars))] {
+		p.row(screen, v, panel.Min.X, y)
+		y += row_height
+	}
+
+	p.ui.Pop()
+	p.ui.EndFrame()
+}
+
+func (p *Panel) row(screen *ebiten.Image, v *cvar.Var, x, y int) {
+	p.ui.Push(x+4, y, name_width-4, row_height, nil)
+	p.ui.Label(v.Name(), 0, 0.5)
+	p.ui.Pop()
+
+	x += name_width
+	p.ui.Push(x, y, value_width, row_height, nil)
+	p.ui.Label(format(v), 1, 0.5)
+	p.ui.Pop()
+
+	x += value_width
+	switch v.Kind() {
+	case cvar.KindBool:
+		p.ui.Push(x+2, y, button_width*2-4, row_height, nil)
+		p.ui.Button(ui.ButtonArgs{
+			Text:     "toggle",
+			AlignX:   0.5,
+			AlignY:   0.5,
+			Selected: v.Bool(),
+			Behavior: ui.ButtonBehavior{
+				OnActivate: func() {
+					v.SetBool(!v.Bool())
+				},
+			},
+		})
+		p.ui.Pop()
+
+	case cvar.KindColor:
+		vector.DrawFilledRect(screen, float32(x+2), float32(y+2), button_width*2-4, row_height-4, v.Color(), false)
+
+	default:
+		p.ui.Push(x+2, y, button_width*2-4, row_height, &ui.GridLayout{Columns: 2, Rows: 1})
+		for _, sign := range []float64{-1, 1} {
+			text := "-"
+			if sign > 0 {
+				text = "+"
+			}
+			p.ui.Button(ui.ButtonArgs{
+				Text:   text,
+				AlignX: 0.5,
+				AlignY: 0.5,
+				Behavior: ui.ButtonBehavior{
+					OnActivate: func() {
+						v.SetFloat(v.Float() + sign*v.Step())
+					},
+				},
+			})
+		}
+		p.ui.Pop()
+	}
+}
+
+func format(v *cvar.Var) string {
+	if v.Kind() == cvar.KindFloat {
+		return fmt.Sprintf("%.4g", v.Float())
+	}
+	return v.String()
+}
@@ -0,0 +1,134 @@
+// Package tween interpolates values over a number of ticks with easing, so demos can animate things without
+// writing the interpolation inline. Tweens count ticks rather than time, like everything else driven from Update,
+// which keeps recorded sessions replaying identically.
+package tween
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+// Ease maps linear progress from 0 to 1 onto eased progress. It starts at 0 and ends at 1, it may overshoot in
+// between.
+type Ease func(t float) float
+
+func Linear(t float) float { return t }
+
+func InQuad(t float) float  { return t * t }
+func OutQuad(t float) float { return 1 - (1-t)*(1-t) }
+func InOutQuad(t float) float {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - 2*(1-t)*(1-t)
+}
+
+func InCubic(t float) float  { return t * t * t }
+func OutCubic(t float) float { return 1 - (1-t)*(1-t)*(1-t) }
+func InOutCubic(t float) float {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	return 1 - 4*(1-t)*(1-t)*(1-t)
+}
+
+// SmoothStep eases in and out more gently than InOutCubic.
+func SmoothStep(t float) float { return t * t * (3 - 2*t) }
+
+// OutBack overshoots the end a little and settles back.
+func OutBack(t float) float {
+	const c1 = 1.70158
+	const c3 = c1 + 1
+	u := t - 1
+	return 1 + c3*u*u*u + c1*u*u
+}
+
+var eases = map[string]Ease{
+	"linear":       Linear,
+	"in_quad":      InQuad,
+	"out_quad":     OutQuad,
+	"in_out_quad":  InOutQuad,
+	"in_cubic":     InCubic,
+	"out_cubic":    OutCubic,
+	"in_out_cubic": InOutCubic,
+	"smoothstep":   SmoothStep,
+	"out_back":     OutBack,
+}
+
+// ByName returns the ease called `name`, such as "in_out_cubic", for console commands and config files.
+func ByName(name string) (Ease, bool) {
+	e, ok := eases[name]
+	return e, ok
+}
+
+// Names returns the names ByName knows, sorted.
+func Names() []string {
+	names := make([]string, 0, len(eases))
+	for name := range eases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tween is the progress of an animation lasting Ticks ticks. The zero value is done.
+type Tween struct {
+	Ticks int
+	Ease  Ease
+	tick  int
+}
+
+// New returns a tween lasting `ticks` ticks, Linear when `ease` is nil.
+func New(ticks int, ease Ease) Tween {
+	if ease == nil {
+		ease = Linear
+	}
+	return Tween{Ticks: max(ticks, 1), Ease: ease}
+}
+
+// Step advances the tween by a tick and returns the eased progress.
+func (t *Tween) Step() float {
+	if t.tick < t.Ticks {
+		t.tick++
+	}
+	return t.Progress()
+}
+
+// Progress is the eased progress, 0 before the first Step and 1 once done.
+func (t *Tween) Progress() float {
+	if t.tick >= t.Ticks {
+		// exactly the end, whatever the ease does with rounding
+		return 1
+	}
+	return t.Ease(float(t.tick) / float(t.Ticks))
+}
+
+// Tick is how many ticks the tween has run for.
+func (t *Tween) Tick() int {
+	return t.tick
+}
+
+func (t *Tween) Done() bool {
+	return t.tick >= t.Ticks
+}
+
+func Lerp(a, b, t float) float {
+	return a + (b-a)*t
+}
+
+func LerpVec3(a, b vec3, t float) vec3 {
+	return a.Add(b.Sub(a).Mul(t))
+}
+
+// LerpAngle interpolates between two angles in radians the short way around.
+func LerpAngle(a, b, t float) float {
+	d := float(math.Remainder(float64(b-a), 2*math.Pi))
+	return a + d*t
+}
@@ -0,0 +1,45 @@
+package tween
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEasesStartAndEnd(t *testing.T) {
+	for _, name := range Names() {
+		ease, _ := ByName(name)
+		if start, end := ease(0), ease(1); math.Abs(float64(start)) > 1e-6 || math.Abs(float64(end-1)) > 1e-6 {
+			t.Errorf("%s goes from %v to %v", name, start, end)
+		}
+	}
+}
+
+func TestTweenEndsExactly(t *testing.T) {
+	tw := New(3, OutBack)
+	if tw.Done() || tw.Progress() != 0 {
+		t.Fatal("a new tween already made progress")
+	}
+	for i := 0; i < 3; i++ {
+		tw.Step()
+	}
+	if !tw.Done() || tw.Progress() != 1 {
+		t.Fatalf("after 3 of 3 ticks the tween is at %v", tw.Progress())
+	}
+	if tw.Step() != 1 {
+		t.Fatal("stepping a finished tween moved it")
+	}
+
+	var zero Tween
+	if !zero.Done() || zero.Progress() != 1 {
+		t.Fatal("the zero tween isn't done")
+	}
+}
+
+func TestLerpAngleTakesTheShortWay(t *testing.T) {
+	// from just below +pi to just above -pi is a small step across the seam
+	a, b := float(math.Pi-0.1), float(-math.Pi+0.1)
+	mid := LerpAngle(a, b, 0.5)
+	if d := math.Abs(math.Remainder(float64(mid)-math.Pi, 2*math.Pi)); d > 1e-5 {
+		t.Fatalf("halfway is %v, not pi", mid)
+	}
+}
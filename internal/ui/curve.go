@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/curve"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+)
+
+const (
+	// curve_padding keeps the keys at the edges of a curve editor inside it, where they can be grabbed
+	curve_padding = 8
+	// key_reach is how close to a key or a handle a press has to be to grab it
+	key_reach = 6
+	// handle_length is how far from its key a tangent handle is drawn
+	handle_length = 30
+)
+
+const (
+	drag_none = iota
+	drag_key
+	drag_in
+	drag_out
+)
+
+// CurveEdit is the state of a curve editor, kept by the caller from frame to frame.
+type CurveEdit struct {
+	Curve *curve.Curve
+	// Min and Max are the values shown from the bottom to the top of the editor, 0 and 1 when they're equal. Time
+	// goes from 0 to 1 from left to right.
+	Min, Max float32
+
+	// selected is the key whose tangent handles are shown, when picked
+	selected int
+	picked   bool
+	// dragging is what's being dragged, one of the drag_ constants
+	dragging int
+	// changed is set when a press changed the curve, at the end of the frame
+	changed bool
+}
+
+// curve_view maps between a curve and the area it's drawn in.
+type curve_view struct {
+	plot   image.Rectangle
+	lo, hi float32
+}
+
+func (v curve_view) point(t, value float32) (x, y float32) {
+	x = float32(v.plot.Min.X) + t*float32(v.plot.Dx())
+	y = float32(v.plot.Max.Y) - (value-v.lo)/(v.hi-v.lo)*float32(v.plot.Dy())
+	return
+}
+
+func (v curve_view) at(p image.Point) (t, value float32) {
+	t = float32(p.X-v.plot.Min.X) / float32(max(v.plot.Dx(), 1))
+	value = v.lo + float32(v.plot.Max.Y-p.Y)/float32(max(v.plot.Dy(), 1))*(v.hi-v.lo)
+	return min(max(t, 0), 1), min(max(value, v.lo), v.hi)
+}
+
+// handle is where the tangent handle of `slope` is drawn, from the key at `x`, `y`, towards `side`: 1 for the
+// slope leaving the key, -1 for the one arriving.
+func (v curve_view) handle(x, y, slope, side float32) (float32, float32) {
+	dx := float32(v.plot.Dx())
+	dy := -slope * float32(v.plot.Dy()) / (v.hi - v.lo)
+	length := float32(math.Hypot(float64(dx), float64(dy)))
+	if length == 0 {
+		return x, y
+	}
+	return x + side*dx/length*handle_length, y + side*dy/length*handle_length
+}
+
+// Curve declares an editor of `e.Curve` in the next area of the layout. Pressing a key selects it and drags it,
+// pressing anywhere else adds a key there, and the handles of the selected key drag its slopes, both together so
+// the curve stays smooth, or with shift held only the one side to make a corner. Delete or backspace with the
+// cursor over the editor removes the selected key, down to two. It reports whether the curve changed.
+func (ctx *Context) Curve(e *CurveEdit) (changed bool) {
+	uid := ctx.uid(1)
+	dst := ctx.Next()
+	bounds := dst.Bounds()
+	c := e.Curve
+	if c == nil || bounds.Dx() <= curve_padding*2 || bounds.Dy() <= curve_padding*2 {
+		return false
+	}
+	view := curve_view{plot: bounds.Inset(curve_padding), lo: e.Min, hi: e.Max}
+	if view.lo == view.hi {
+		view.lo, view.hi = 0, 1
+	}
+
+	changed, e.changed = e.changed, false
+	if e.selected >= len(c.Keys) {
+		// the caller took keys away
+		e.picked, e.dragging = false, drag_none
+	}
+	if e.dragging != drag_none {
+		if input.MousePressed(ebiten.MouseButtonLeft) {
+			e.drag(view, ctx.cursor())
+			changed = true
+		} else {
+			e.dragging = drag_none
+		}
+	}
+	if e.picked && len(c.Keys) > 2 && ctx.hover_uid == uid && deleted() {
+		c.Remove(e.selected)
+		e.picked, e.dragging = false, drag_none
+		changed = true
+	}
+
+	dst.Fill(ctx.Theme.ButtonPressed)
+	DrawBorder(dst, 0, 1, ctx.Theme.Border)
+	for i := 1; i < 4; i++ {
+		x, y := view.point(float32(i)/4, view.lo+(view.hi-view.lo)*float32(i)/4)
+		vector.StrokeLine(dst, x, float32(view.plot.Min.Y), x, float32(view.plot.Max.Y), 1, ctx.Theme.Button, false)
+		vector.StrokeLine(dst, float32(view.plot.Min.X), y, float32(view.plot.Max.X), y, 1, ctx.Theme.Button, false)
+	}
+
+	// a line every couple of pixels is as smooth as the curve gets on screen
+	px, py := view.point(0, c.Evaluate(0))
+	for x := view.plot.Min.X + 2; x <= view.plot.Max.X+1; x += 2 {
+		t := min(float32(x-view.plot.Min.X)/float32(view.plot.Dx()), 1)
+		nx, ny := view.point(t, c.Evaluate(t))
+		vector.StrokeLine(dst, px, py, nx, ny, 1.5, ctx.Theme.Text, true)
+		px, py = nx, ny
+	}
+
+	for i, k := range c.Keys {
+		x, y := view.point(k.T, k.V)
+		clr := ctx.Theme.Highlight
+		if e.picked && i == e.selected {
+			clr = ctx.Theme.Link
+			for _, side := range []float32{-1, 1} {
+				slope := k.Out
+				if side < 0 {
+					slope = k.In
+				}
+				hx, hy := view.handle(x, y, slope, side)
+				vector.StrokeLine(dst, x, y, hx, hy, 1, ctx.Theme.Link, true)
+				vector.DrawFilledCircle(dst, hx, hy, 3, ctx.Theme.LinkHovered, true)
+			}
+		}
+		vector.DrawFilledRect(dst, x-3, y-3, 6, 6, clr, false)
+	}
+
+	origin := ctx.origin
+	ctx.push_trigger(uid, bounds, ButtonBehavior{
+		OnPress: func(btn ebiten.MouseButton) {
+			e.press(view, image.Pt(input.CursorPosition()).Sub(origin))
+		},
+	})
+	return changed
+}
+
+// press grabs the handle or the key at `p`, or adds a key there.
+func (e *CurveEdit) press(view curve_view, p image.Point) {
+	c := e.Curve
+	near := func(x, y float32) bool {
+		dx, dy := x-float32(p.X), y-float32(p.Y)
+		return dx*dx+dy*dy <= key_reach*key_reach
+	}
+	if e.picked && e.selected < len(c.Keys) {
+		k := c.Keys[e.selected]
+		x, y := view.point(k.T, k.V)
+		if near(view.handle(x, y, k.Out, 1)) {
+			e.dragging = drag_out
+			return
+		}
+		if near(view.handle(x, y, k.In, -1)) {
+			e.dragging = drag_in
+			return
+		}
+	}
+	for i, k := range c.Keys {
+		if near(view.point(k.T, k.V)) {
+			e.selected, e.picked, e.dragging = i, true, drag_key
+			return
+		}
+	}
+	t, v := view.at(p)
+	e.selected, e.picked, e.dragging = c.Add(t, v), true, drag_key
+	e.changed = true
+}
+
+// drag moves what's being dragged to the cursor at `p`.
+func (e *CurveEdit) drag(view curve_view, p image.Point) {
+	c := e.Curve
+	if e.dragging == drag_key {
+		t, v := view.at(p)
+		c.Move(e.selected, t, v)
+		return
+	}
+
+	k := &c.Keys[e.selected]
+	x, y := view.point(k.T, k.V)
+	dx, dy := float32(p.X)-x, float32(p.Y)-y
+	// a handle stays on its own side of the key
+	if e.dragging == drag_out {
+		dx = max(dx, 1)
+	} else {
+		dx = min(dx, -1)
+	}
+	slope := (-dy / float32(view.plot.Dy()) * (view.hi - view.lo)) / (dx / float32(view.plot.Dx()))
+	switch {
+	case input.KeyPressed(ebiten.KeyShift) && e.dragging == drag_out:
+		k.Out = slope
+	case input.KeyPressed(ebiten.KeyShift):
+		k.In = slope
+	default:
+		k.In, k.Out = slope, slope
+	}
+}
+
+// deleted reports whether delete or backspace was pressed since the last frame.
+func deleted() bool {
+	input_mu.Lock()
+	defer input_mu.Unlock()
+	for _, e := range edits {
+		if e.has_key && (e.key == ebiten.KeyDelete || e.key == ebiten.KeyBackspace) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/dock"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/text"
+)
+
+const (
+	tab_height = 20
+	// tab_padding is the room around the title of a tab
+	tab_padding = 16
+	// splitter_width is the gap between the halves of a split, dragged to move it
+	splitter_width = 4
+	// drag_distance is how far a tab has to be dragged before its window comes loose
+	drag_distance = 6
+	// min_ratio keeps both halves of a split from being dragged shut
+	min_ratio = 0.1
+)
+
+var drop_preview = color.RGBA{60, 120, 200, 96}
+
+// Dock is the state of a dock space widget, kept by the caller from frame to frame.
+type Dock struct {
+	// Space is the arrangement of the windows.
+	Space *dock.Space
+	// Name is what the arrangement is saved as when it changes, it isn't saved when empty.
+	Name string
+
+	// pressed is the window whose tab is held, press where it was pressed
+	pressed string
+	press   image.Point
+	// dragging is the window being dragged to a new place
+	dragging string
+	// resizing is the split whose splitter is held, span its rectangle
+	resizing *dock.Node
+	span     image.Rectangle
+}
+
+// NewDock returns the dock widget of the space saved as `name`, or `fallback` when none was saved yet. It holds
+// exactly `windows`, see dock.Space.Fit.
+func NewDock(name string, fallback *dock.Space, windows ...string) *Dock {
+	space, err := dock.Load(name, fallback, windows)
+	if err != nil {
+		logger.Warnf("could not load dock space %s: %v", name, err)
+	}
+	return &Dock{Space: space, Name: name}
+}
+
+// Save saves the arrangement, the widget does when windows are moved, callers do after changing Space themselves.
+func (d *Dock) Save() {
+	if d.Name == "" {
+		return
+	}
+	if err := d.Space.Save(d.Name); err != nil {
+		logger.Warnf("could not save dock space %s: %v", d.Name, err)
+	}
+}
+
+// DockSpace declares the windows of `d` in the next area of the layout. Every leaf of the space gets a bar with a
+// tab per window, titled by `title`, and `window` is called with the name of the shown one to declare its widgets,
+// which fill the area below the bar. Clicking a tab shows its window, dragging it docks the window into the tabs
+// or against an edge of whichever leaf it's dropped on, and dragging the gap between leaves resizes them.
+func (ctx *Context) DockSpace(d *Dock, title func(window string) string, window func(name string)) {
+	area := ctx.Next().Bounds()
+	cursor := ctx.cursor()
+	held := input.MousePressed(ebiten.MouseButtonLeft)
+
+	// drops change the tree before anything is declared, so the triggers of this frame don't hold nodes that went away
+	ctx.dock_drag(d, area, cursor, held)
+
+	areas, splitters := d.Space.Layout(area, splitter_width)
+	layout := ctx.layout
+	for _, a := range areas {
+		ctx.dock_leaf(d, a, title, window)
+	}
+	for _, s := range splitters {
+		uid := ctx.uid(0)
+		clr := ctx.Theme.ButtonPressed
+		if d.resizing == s.Node || ctx.hover_uid == uid {
+			clr = ctx.Theme.ButtonHovered
+		}
+		vector.DrawFilledRect(ctx.layers[len(ctx.layers)-1], float32(s.Rect.Min.X), float32(s.Rect.Min.Y), float32(s.Rect.Dx()), float32(s.Rect.Dy()), clr, false)
+		ctx.push_trigger(uid, s.Rect, ButtonBehavior{
+			OnPress: func(btn ebiten.MouseButton) {
+				d.resizing, d.span = s.Node, s.Span
+			},
+		})
+	}
+
+	if d.dragging != "" {
+		dst := ctx.layers[len(ctx.layers)-1]
+		for _, a := range areas {
+			if cursor.In(a.Rect) {
+				p := dock.Preview(a.Rect, dock.Zone(a.Rect, cursor))
+				vector.DrawFilledRect(dst, float32(p.Min.X), float32(p.Min.Y), float32(p.Dx()), float32(p.Dy()), drop_preview, false)
+			}
+		}
+		name := title(d.dragging)
+		tab := image.Rect(cursor.X, cursor.Y, cursor.X+tab_width(name), cursor.Y+tab_height)
+		ctx.Push(tab.Min.X, tab.Min.Y, tab.Dx(), tab.Dy(), nil)
+		ctx.Next().Fill(ctx.Theme.ButtonHovered)
+		DrawString(ctx.Next(), name, 0.5, 0.5)
+		ctx.Pop()
+	}
+	ctx.layout = layout
+}
+
+// dock_drag carries on with the drags of tabs and splitters started last frame.
+func (ctx *Context) dock_drag(d *Dock, area image.Rectangle, cursor image.Point, held bool) {
+	if d.resizing != nil {
+		if held {
+			var ratio float64
+			if d.resizing.Vertical {
+				ratio = float64(cursor.Y-d.span.Min.Y) / float64(max(d.span.Dy()-splitter_width, 1))
+			} else {
+				ratio = float64(cursor.X-d.span.Min.X) / float64(max(d.span.Dx()-splitter_width, 1))
+			}
+			d.resizing.Ratio = min(max(ratio, min_ratio), 1-min_ratio)
+		} else {
+			d.resizing = nil
+			d.Save()
+		}
+	}
+
+	if d.pressed != "" && d.dragging == "" && held {
+		delta := cursor.Sub(d.press)
+		if delta.X*delta.X+delta.Y*delta.Y > drag_distance*drag_distance {
+			d.dragging = d.pressed
+		}
+	}
+	if held {
+		return
+	}
+	if d.dragging != "" {
+		areas, _ := d.Space.Layout(area, splitter_width)
+		for _, a := range areas {
+			if cursor.In(a.Rect) {
+				d.Space.Dock(d.dragging, a.Node, dock.Zone(a.Rect, cursor))
+				d.Save()
+			}
+		}
+	}
+	d.pressed, d.dragging = "", ""
+}
+
+// dock_leaf declares the tab bar of a leaf and its shown window.
+func (ctx *Context) dock_leaf(d *Dock, a dock.Area, title func(window string) string, window func(name string)) {
+	leaf := a.Node
+	bar := image.Rect(a.Rect.Min.X, a.Rect.Min.Y, a.Rect.Max.X, min(a.Rect.Min.Y+tab_height, a.Rect.Max.Y))
+	ctx.Push(bar.Min.X, bar.Min.Y, bar.Dx(), bar.Dy(), nil)
+	ctx.Next().Fill(ctx.Theme.Panel)
+	ctx.Pop()
+
+	x := bar.Min.X
+	shown := leaf.Shown()
+	origin := ctx.origin
+	for i, w := range leaf.Windows {
+		name := title(w)
+		width := min(tab_width(name), bar.Max.X-x)
+		if width <= 0 {
+			break
+		}
+		ctx.Push(x, bar.Min.Y, width, bar.Dy(), nil)
+		ctx.Button(ButtonArgs{
+			Text:     name,
+			AlignX:   0.5,
+			AlignY:   0.5,
+			Selected: w == shown,
+			Behavior: ButtonBehavior{
+				Mode: ActivateOnClick,
+				OnPress: func(btn ebiten.MouseButton) {
+					d.pressed = w
+					d.press = image.Pt(input.CursorPosition()).Sub(origin)
+				},
+				OnActivate: func() {
+					if leaf.Active != i {
+						leaf.Active = i
+						d.Save()
+					}
+				},
+			},
+		})
+		ctx.Pop()
+		x += width
+	}
+
+	if shown == "" || bar.Max.Y >= a.Rect.Max.Y {
+		return
+	}
+	ctx.Push(a.Rect.Min.X, bar.Max.Y, a.Rect.Dx(), a.Rect.Max.Y-bar.Max.Y, nil)
+	window(shown)
+	ctx.Pop()
+}
+
+func tab_width(title string) int {
+	return int(text.Width(title, text.Mono.Face(font_size))) + tab_padding
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/text"
+)
+
+func CursorWithin(rect image.Rectangle) bool {
+	cx, cy := input.CursorPosition()
+	return cx >= rect.Min.X && cy >= rect.Min.Y && cx < rect.Max.X && cy < rect.Max.Y
+}
+
+func DrawBorder(dst *ebiten.Image, inset, width float32, clr color.Color) {
+	bounds := dst.Bounds()
+	inset += width / 2
+	x := float32(bounds.Min.X) + inset
+	y := float32(bounds.Min.Y) + inset
+	w := float32(bounds.Dx()) - inset*2
+	h := float32(bounds.Dy()) - inset*2
+	vector.StrokeRect(dst, x, y, w, h, width, clr, false)
+}
+
+// font_size and line_height keep the look of the debug font the UI used to be drawn with, 6 pixels per character
+// and 16 per line.
+const (
+	font_size   = 10
+	line_height = 16
+)
+
+// DrawString draws `s` within the bounds of `dst`. The alignment ranges from 0 (left/top) to 1 (right/bottom).
+func DrawString(dst *ebiten.Image, s string, align_x, align_y float32) {
+	bounds := dst.Bounds()
+	n_lines := strings.Count(s, "\n") + 1
+	y := float64(bounds.Min.Y) + float64(bounds.Dy()-n_lines*line_height)*float64(align_y)
+	text.Draw(dst, s, text.Mono.Face(font_size), float64(bounds.Min.X), y, float64(bounds.Dx()), line_height, float64(align_x), color.White)
+}
+
+// DrawHeading draws `s` within the bounds of `dst` like DrawString, but `size` pixels an em from the fields of
+// text.SDFSans, so it's sharp at any size, in `style`. Its lines are aligned together rather than each on its own.
+func DrawHeading(dst *ebiten.Image, s string, size float64, style *text.SDFStyle, align_x, align_y float32) {
+	font := text.SDFSans()
+	bounds := dst.Bounds()
+	extent := font.Measure(s, float32(size))
+	var geom ebiten.GeoM
+	geom.Translate(
+		float64(bounds.Min.X)+(float64(bounds.Dx())-float64(extent[0]))*float64(align_x),
+		float64(bounds.Min.Y)+(float64(bounds.Dy())-float64(extent[1]))*float64(align_y),
+	)
+	font.Draw(dst, s, size, geom, style)
+}
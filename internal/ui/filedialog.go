@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"errors"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileDialog is the state of a file picker, kept by the caller from frame to frame.
+type FileDialog struct {
+	// Dir is the directory listed, Ext the extension of the files listed, like ".json", every file when empty.
+	Dir, Ext string
+	// Name is the field with the name of the file, picking one in the list puts its name there.
+	Name TextInput
+
+	list List
+	// entries are the names in Dir, directories ending in a slash, listed is the Dir they're of
+	entries []string
+	listed  string
+	stale   bool
+	err     error
+}
+
+// Refresh lists the directory again, after a file was saved into it.
+func (d *FileDialog) Refresh() {
+	d.stale = true
+}
+
+// Path is the path of the file named in the field, Ext added when it's missing, empty without a name.
+func (d *FileDialog) Path() string {
+	name := strings.TrimSpace(d.Name.Text)
+	if name == "" {
+		return ""
+	}
+	if d.Ext != "" && !strings.HasSuffix(name, d.Ext) {
+		name += d.Ext
+	}
+	return filepath.Join(d.Dir, name)
+}
+
+func (d *FileDialog) read() {
+	d.entries, d.err = d.entries[:0], nil
+	if abs, err := filepath.Abs(d.Dir); err == nil && filepath.Dir(abs) != abs {
+		d.entries = append(d.entries, "../")
+	}
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// a directory which isn't there yet is empty, it's made when saving into it
+		d.err = err
+	}
+	var files []string
+	for _, e := range entries {
+		switch {
+		case strings.HasPrefix(e.Name(), "."):
+		case e.IsDir():
+			d.entries = append(d.entries, e.Name()+"/")
+		case d.Ext == "" || strings.HasSuffix(e.Name(), d.Ext):
+			files = append(files, e.Name())
+		}
+	}
+	slices.Sort(files)
+	d.entries = append(d.entries, files...)
+	d.listed, d.stale = d.Dir, false
+}
+
+// FileDialog declares a file picker in the next area of the layout: the directories and files in Dir, and below
+// them the field with the name of the file. Clicking a file puts its name in the field, clicking a directory goes
+// into it. It reports whether enter was pressed in the field. The directory is listed when it changes or after
+// Refresh, not every frame.
+func (ctx *Context) FileDialog(d *FileDialog) (submitted bool) {
+	if d.Dir == "" {
+		d.Dir = "."
+	}
+	if d.stale || d.listed != d.Dir {
+		d.read()
+	}
+
+	area := ctx.Next().Bounds()
+	field := Size{Min: image.Pt(0, 20), Preferred: image.Pt(0, 20)}
+	layout := ctx.layout
+	ctx.Push(area.Min.X, area.Min.Y, area.Dx(), area.Dy(), &StackLayout{Vertical: true, Gap: 4, Sizes: []Size{{Stretch: 1}, field}})
+	if d.err != nil {
+		ctx.Label(d.err.Error(), 0, 0)
+	} else {
+		ctx.List(&d.list, len(d.entries), 20, func(i int) {
+			name := d.entries[i]
+			ctx.Button(ButtonArgs{
+				Text:     name,
+				AlignX:   0.02,
+				AlignY:   0.5,
+				Selected: name == d.Name.Text,
+				// picked as pressed, a layer the dialog is in draws again while the mouse is held
+				Behavior: ButtonBehavior{Mode: ActivateOnClick, OnActivate: func() { d.pick(name) }},
+			})
+		})
+	}
+	submitted = ctx.TextInput(&d.Name)
+	ctx.Pop()
+	ctx.layout = layout
+	return submitted
+}
+
+// pick goes into the directory `name`, or names the file `name`.
+func (d *FileDialog) pick(name string) {
+	dir, ok := strings.CutSuffix(name, "/")
+	if !ok {
+		d.Name.Set(name)
+		return
+	}
+	d.Dir = filepath.Join(d.Dir, dir)
+	d.list.Top = 0
+}
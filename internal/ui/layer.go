@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/invalidate"
+)
+
+var (
+	cache_layers = cvar.Bool("ui_cache", true, 0, "keeps windows that didn't change in offscreen layers instead of drawing them again")
+	show_redraws = cvar.Bool("ui_show_redraws", false, 0, "outlines the layers drawn again this frame")
+)
+
+var redraw_outline = color.RGBA{255, 64, 64, 255}
+
+// layer_generation goes up when layers are purged, every layer redraws into a new image
+var layer_generation = 1
+
+func init() {
+	invalidate.Register("ui_layers", func() { layer_generation++ })
+}
+
+// Layer is a window drawn into an offscreen image of its own, kept by the caller from frame to frame. As long as
+// nothing the window shows changed, the image is drawn instead of declaring the widgets again, and their triggers
+// are replayed from the frame they were declared so they still react to the mouse.
+//
+// A layer is damaged, and draws again, when
+//   - it moved or changed size,
+//   - the key given to Context.Layer differs from the last one,
+//   - the mouse moved, clicked or scrolled over it, or anything was typed or an input method is open, a text
+//     input in it might be focused,
+//   - the widget hovered or pressed in it changed, or one of its widgets is held down,
+//   - or Damage was called.
+//
+// Anything else the window shows has to go in the key, like the language its text is in. Layers don't nest.
+type Layer struct {
+	image *ebiten.Image
+	// generation is the layer_generation the image was drawn in, zero before it ever was
+	generation int
+	damaged    bool
+
+	rect   image.Rectangle
+	key    any
+	cursor image.Point
+	// hover and press are the hovered and pressed widgets when they're in this layer
+	hover, press uid_t
+
+	recording layer_recording
+}
+
+// layer_recording is what declaring the widgets of a layer did besides drawing, what's replayed when it's cached.
+type layer_recording struct {
+	// triggers are relative to the layer
+	triggers []trigger_t
+	uids     []uid_t
+	// pcs are the bases of the uids in the order they were taken, starts how often each was taken before the
+	// layer. Uids taken at the same place outside of the layer shift the ones inside of it, which then draw again.
+	pcs    []uintptr
+	starts map[uintptr]uint64
+}
+
+// Damage makes the layer draw again next frame.
+func (l *Layer) Damage() {
+	l.damaged = true
+}
+
+// Layer declares the widgets `draw` declares in the next area of the layout, through `l`. Those widgets see the
+// area as all there is, from 0, 0. `key` is compared with ==.
+func (ctx *Context) Layer(l *Layer, key any, draw func()) {
+	if ctx.recording != nil {
+		panic("ui layers don't nest")
+	}
+	dst := ctx.Next()
+	rect := dst.Bounds()
+	if rect.Empty() {
+		return
+	}
+
+	cursor := image.Pt(input.CursorPosition())
+	hover, press := l.own(ctx.hover_uid), l.own(ctx.press_uid)
+	_, wheel := input.Wheel()
+	touched := cursor.In(rect) && (cursor != l.cursor || wheel != 0 ||
+		input.MousePressed(ebiten.MouseButtonLeft) || mouse_just_released(ebiten.MouseButtonLeft))
+	input_mu.Lock()
+	typed := len(edits) > 0 || ime_sessions > 0
+	input_mu.Unlock()
+
+	damaged := !cache_layers.Bool() || l.damaged || l.generation != layer_generation ||
+		rect != l.rect || key != l.key || hover != l.hover || press != l.press || press != uid_zero || touched || typed ||
+		!l.replayable(ctx)
+	l.cursor = cursor
+
+	if damaged {
+		ctx.draw_layer(l, rect, draw)
+		l.key, l.damaged = key, false
+		l.hover, l.press = l.own(ctx.hover_uid), l.own(ctx.press_uid)
+	} else {
+		ctx.replay_layer(l)
+	}
+
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(float64(rect.Min.X), float64(rect.Min.Y))
+	dst.DrawImage(l.image, opts)
+	if damaged && show_redraws.Bool() {
+		DrawBorder(dst, 0, 1, redraw_outline)
+	}
+}
+
+func (ctx *Context) draw_layer(l *Layer, rect image.Rectangle, draw func()) {
+	if l.image == nil || l.image.Bounds().Size() != rect.Size() || l.generation != layer_generation {
+		if l.image != nil {
+			l.image.Deallocate()
+		}
+		l.image = ebiten.NewImage(rect.Dx(), rect.Dy())
+		l.generation = layer_generation
+	}
+	l.image.Clear()
+	l.rect = rect
+
+	rec := &l.recording
+	rec.triggers, rec.uids, rec.pcs = rec.triggers[:0], rec.uids[:0], rec.pcs[:0]
+	if rec.starts == nil {
+		rec.starts = make(map[uintptr]uint64)
+	}
+	clear(rec.starts)
+
+	layout := ctx.layout
+	ctx.layers = append(ctx.layers, l.image)
+	ctx.layout = nil
+	ctx.origin = rect.Min
+	ctx.recording = rec
+	draw()
+	ctx.recording = nil
+	ctx.origin = image.Point{}
+	ctx.Pop()
+	ctx.layout = layout
+}
+
+// replay_layer takes the uids and pushes the triggers the widgets of the layer did when they were declared.
+func (ctx *Context) replay_layer(l *Layer) {
+	rec := &l.recording
+	for _, pc := range rec.pcs {
+		ctx.uid_base_occurences[pc]++
+	}
+	for _, uid := range rec.uids {
+		ctx.uid_frame[uid] = current_frame
+	}
+	for _, t := range rec.triggers {
+		ctx.push_trigger(t.uid, t.bounds.Add(l.rect.Min), t.ButtonBehavior)
+	}
+}
+
+// replayable reports whether the uids of the layer come out the same as when it was drawn.
+func (l *Layer) replayable(ctx *Context) bool {
+	for pc, start := range l.recording.starts {
+		if ctx.uid_base_occurences[pc] != start {
+			return false
+		}
+	}
+	return true
+}
+
+// own returns `uid` when it's a widget of the layer.
+func (l *Layer) own(uid uid_t) uid_t {
+	if uid != uid_zero && slices.Contains(l.recording.uids, uid) {
+		return uid
+	}
+	return uid_zero
+}
+
+// record notes a uid taken while a layer is drawn, at `pc` or, for keyed uids, 0.
+func (rec *layer_recording) record(pc uintptr, uid uid_t) {
+	rec.uids = append(rec.uids, uid)
+	if pc == 0 {
+		return
+	}
+	if _, ok := rec.starts[pc]; !ok {
+		rec.starts[pc] = uid.id
+	}
+	rec.pcs = append(rec.pcs, pc)
+}
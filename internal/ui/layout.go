@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"image"
+	"math"
+	"strings"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/flex"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/text"
+)
+
+type Layout interface {
+	Layout(src image.Rectangle) (dst image.Rectangle)
+}
+
+// GridLayout fills a grid row by row. Without Cells every cell is the same size, with them every column is as
+// wide and every row as high as their largest cell asks for, see StackLayout.
+type GridLayout struct {
+	Columns int
+	Rows    int
+	// Cells are the sizes of the widgets in the order they're declared, row by row.
+	Cells   []Size
+	widths  []int
+	heights []int
+	current int
+}
+
+func (l *GridLayout) Layout(src image.Rectangle) (dst image.Rectangle) {
+	if l.current == l.Rows*l.Columns {
+		return image.Rectangle{}
+	}
+	col := l.current % l.Columns
+	row := l.current / l.Columns
+	l.current++
+
+	if l.Cells == nil {
+		cell_width := src.Dx() / l.Columns
+		cell_height := src.Dy() / l.Rows
+		x := src.Min.X + (col * cell_width)
+		y := src.Min.Y + (row * cell_height)
+		w := cell_width
+		h := cell_height
+		return image.Rect(x, y, x+w, y+h)
+	}
+
+	if l.current == 1 {
+		l.solve(src.Size())
+	}
+	x, y := src.Min.X, src.Min.Y
+	for _, w := range l.widths[:col] {
+		x += w
+	}
+	for _, h := range l.heights[:row] {
+		y += h
+	}
+	return image.Rect(x, y, x+l.widths[col], y+l.heights[row]).Intersect(src)
+}
+
+func (l *GridLayout) solve(room image.Point) {
+	columns := make([]flex.Item, l.Columns)
+	rows := make([]flex.Item, l.Rows)
+	for i, cell := range l.Cells[:min(len(l.Cells), l.Columns*l.Rows)] {
+		col, row := i%l.Columns, i/l.Columns
+		columns[col] = flex.Largest(columns[col], cell.along(false))
+		rows[row] = flex.Largest(rows[row], cell.along(true))
+	}
+	l.widths = flex.Solve(columns, room.X, 0)
+	l.heights = flex.Solve(rows, room.Y, 0)
+}
+
+// RowLayout stacks rows of a fixed height from top to bottom, until it runs out of room.
+type RowLayout struct {
+	Height  int
+	current int
+}
+
+func (l *RowLayout) Layout(src image.Rectangle) (dst image.Rectangle) {
+	y := src.Min.Y + l.current*l.Height
+	if y+l.Height > src.Max.Y {
+		return image.Rectangle{}
+	}
+	l.current++
+	return image.Rect(src.Min.X, y, src.Max.X, y+l.Height)
+}
+
+// Size is what a widget asks of a layout: the least it can be drawn in without clipping, the size it would like
+// and its share of any room left over, see package flex.
+type Size struct {
+	Min, Preferred image.Point
+	Stretch        float64
+}
+
+func (s Size) along(vertical bool) flex.Item {
+	if vertical {
+		return flex.Item{Min: s.Min.Y, Preferred: s.Preferred.Y, Stretch: s.Stretch}
+	}
+	return flex.Item{Min: s.Min.X, Preferred: s.Preferred.X, Stretch: s.Stretch}
+}
+
+// button_padding is the room around the text of a button it would like, half of it is the least it needs.
+const button_padding = 8
+
+// TextSize is the size of a label of `s`, which has to fit whole.
+func TextSize(s string) Size {
+	lines := strings.Count(s, "\n") + 1
+	size := image.Pt(int(math.Ceil(text.Width(s, text.Mono.Face(font_size)))), lines*line_height)
+	return Size{Min: size, Preferred: size}
+}
+
+// ButtonSize is the size of a button labelled `s`.
+func ButtonSize(s string) Size {
+	size := TextSize(s)
+	size.Min = size.Min.Add(image.Pt(button_padding, 2))
+	size.Preferred = size.Preferred.Add(image.Pt(button_padding*2, 4))
+	return size
+}
+
+// StackLayout lines widgets up from left to right, or top to bottom when Vertical, sized by Sizes in the order
+// they're declared: the preferred sizes when they fit, shrunk towards the minimums when they don't, with the room
+// left over shared by stretch. Widgets fill the other direction.
+type StackLayout struct {
+	Vertical bool
+	Gap      int
+	Sizes    []Size
+	rects    []image.Rectangle
+	current  int
+}
+
+func (l *StackLayout) Layout(src image.Rectangle) (dst image.Rectangle) {
+	if l.current == 0 {
+		l.rects = l.rects[:0]
+		items := make([]flex.Item, len(l.Sizes))
+		for i, s := range l.Sizes {
+			items[i] = s.along(l.Vertical)
+		}
+		pos := src.Min
+		for _, size := range flex.Solve(items, axis(src.Size(), l.Vertical), l.Gap) {
+			r := image.Rectangle{Min: pos, Max: src.Max}
+			if l.Vertical {
+				r.Max.Y = pos.Y + size
+				pos.Y += size + l.Gap
+			} else {
+				r.Max.X = pos.X + size
+				pos.X += size + l.Gap
+			}
+			// widgets which overflow the stack are clipped, or left out when none of them fits
+			l.rects = append(l.rects, r.Intersect(src))
+		}
+	}
+	if l.current == len(l.rects) {
+		return image.Rectangle{}
+	}
+	l.current++
+	return l.rects[l.current-1]
+}
+
+// Measure is the size of the whole stack, for nesting it in other layouts.
+func (l *StackLayout) Measure() Size {
+	main := make([]flex.Item, len(l.Sizes))
+	across := make([]flex.Item, len(l.Sizes))
+	for i, s := range l.Sizes {
+		main[i] = s.along(l.Vertical)
+		across[i] = s.along(!l.Vertical)
+	}
+	line := flex.Measure(main, l.Gap)
+	widest := flex.Largest(across...)
+	if l.Vertical {
+		return Size{Min: image.Pt(widest.Min, line.Min), Preferred: image.Pt(widest.Preferred, line.Preferred), Stretch: line.Stretch}
+	}
+	return Size{Min: image.Pt(line.Min, widest.Min), Preferred: image.Pt(line.Preferred, widest.Preferred), Stretch: line.Stretch}
+}
+
+func axis(p image.Point, vertical bool) int {
+	if vertical {
+		return p.Y
+	}
+	return p.X
+}
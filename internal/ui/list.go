@@ -0,0 +1,143 @@
+package ui
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+)
+
+const (
+	scrollbar_width = 8
+	// min_thumb keeps the thumb of very long lists big enough to grab
+	min_thumb = 16
+	// wheel_rows is how far a notch of the mouse wheel scrolls
+	wheel_rows = 3
+)
+
+// List is the scroll state of a list widget, kept by the caller from frame to frame.
+type List struct {
+	// Top is the first row in view.
+	Top int
+	// Follow keeps the last row in view as rows are added, the way logs are read. Scrolling up stops following
+	// until the list is scrolled back to the end.
+	Follow bool
+
+	// visible is how many rows were in view last frame
+	visible int
+	// detached is set while a following list is scrolled away from the end
+	detached bool
+	// dragging is set while the thumb is held, grab is where it was grabbed
+	dragging bool
+	grab     int
+}
+
+// ScrollTo scrolls the least needed to bring row `i` into view, as many rows as were in view last frame.
+func (l *List) ScrollTo(i int) {
+	if i < l.Top {
+		l.Top = i
+	} else if i >= l.Top+l.visible {
+		l.Top = i - l.visible + 1
+	}
+}
+
+// ScrollToEnd scrolls to the last row, and a following list follows again.
+func (l *List) ScrollToEnd() {
+	l.Top = math.MaxInt
+	l.detached = false
+}
+
+// List declares a list of `count` rows `row_height` high in the next area of the layout, with a scroll bar when
+// they don't all fit. Only the rows in view exist: `row` is called with the index of each of them and declares
+// its widgets, which fill the row's area. A list of a million rows costs what the rows in view cost.
+func (ctx *Context) List(l *List, count, row_height int, row func(i int)) {
+	area := ctx.Next().Bounds()
+	visible := max(area.Dy()/row_height, 1)
+	last := max(count-visible, 0)
+	l.visible = visible
+
+	if l.Follow && !l.detached {
+		l.Top = last
+	}
+
+	rows := area
+	if last > 0 {
+		rows.Max.X -= scrollbar_width
+		ctx.scrollbar(l, image.Rect(rows.Max.X, area.Min.Y, area.Max.X, area.Max.Y), count, visible)
+	} else {
+		l.dragging = false
+	}
+
+	if ctx.cursor().In(area) {
+		_, wheel := input.Wheel()
+		l.Top -= int(wheel) * wheel_rows
+	}
+	l.Top = min(max(l.Top, 0), last)
+	l.detached = l.Top < last
+
+	// the rows are areas of their own, the layout the list was in carries on after it
+	layout := ctx.layout
+	for i := l.Top; i < count && i < l.Top+visible; i++ {
+		y := rows.Min.Y + (i-l.Top)*row_height
+		ctx.Push(rows.Min.X, y, rows.Dx(), row_height, nil)
+		row(i)
+		ctx.Pop()
+	}
+	ctx.layout = layout
+}
+
+// scrollbar declares the scroll bar of `l` in `track`. Pressing the track pages towards the cursor, dragging the
+// thumb scrolls along with it.
+func (ctx *Context) scrollbar(l *List, track image.Rectangle, count, visible int) {
+	dst := ctx.layers[len(ctx.layers)-1].SubImage(track).(*ebiten.Image)
+	last := count - visible
+	thumb_height := max(track.Dy()*visible/count, min_thumb)
+	travel := max(track.Dy()-thumb_height, 1)
+
+	cy := ctx.cursor().Y
+	if l.dragging {
+		if input.MousePressed(ebiten.MouseButtonLeft) {
+			l.Top = (cy - l.grab - track.Min.Y) * last / travel
+			l.Top = min(max(l.Top, 0), last)
+		} else {
+			l.dragging = false
+		}
+	}
+
+	thumb_y := track.Min.Y + travel*min(max(l.Top, 0), last)/last
+	thumb := image.Rect(track.Min.X, thumb_y, track.Max.X, thumb_y+thumb_height)
+
+	// the triggers go off at the end of the frame, outside of any layer
+	origin := ctx.origin
+	track_uid := ctx.uid(1)
+	thumb_uid := ctx.uid(1)
+
+	dst.Fill(ctx.Theme.ButtonPressed)
+	clr := ctx.Theme.Button
+	if l.dragging || ctx.hover_uid == thumb_uid {
+		clr = ctx.Theme.ButtonHovered
+	}
+	vector.DrawFilledRect(dst, float32(thumb.Min.X+1), float32(thumb.Min.Y), float32(thumb.Dx()-2), float32(thumb.Dy()), clr, false)
+
+	ctx.push_trigger(track_uid, track, ButtonBehavior{
+		Mode: ActivateOnClick,
+		OnActivate: func() {
+			_, cy := input.CursorPosition()
+			if cy-origin.Y < thumb.Min.Y {
+				l.Top -= visible
+			} else {
+				l.Top += visible
+			}
+		},
+	})
+	ctx.push_trigger(thumb_uid, thumb, ButtonBehavior{
+		OnPress: func(btn ebiten.MouseButton) {
+			_, cy := input.CursorPosition()
+			l.dragging = true
+			l.grab = cy - origin.Y - thumb.Min.Y
+		},
+	})
+}
@@ -0,0 +1,284 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/cvar"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+)
+
+const (
+	// default_step is what a number moves by per pixel dragged when no step is given, color_step for colors
+	default_step = 0.1
+	color_step   = 0.005
+	// fine_steps is how many times finer dragging is with shift held
+	fine_steps = 10
+)
+
+// number_field is the state of a field holding a number, which is dragged left and right to scrub it or clicked
+// to type it.
+type number_field struct {
+	input   TextInput
+	editing bool
+	// dragging is set while the field is held, moved once it was dragged far enough to scrub rather than click.
+	// grab_x is where the cursor was when scrubbing started, grab the value then.
+	dragging, moved bool
+	grab_x          int
+	grab            float64
+}
+
+// number_field declares a field showing `value` after `label` in the next area of the layout, and returns the
+// value it was scrubbed or typed to, kept between `lo` and `hi` unless they're equal. Typed text is evaluated as
+// an expression, see cvar.Eval, so 90/4 or pi/2 go too. Anything that doesn't evaluate leaves the value as it was.
+func (ctx *Context) number_field(f *number_field, label string, value, step, lo, hi float64) (float64, bool) {
+	uid := ctx.uid(0)
+	dst := ctx.Next()
+	bounds := dst.Bounds()
+	old := value
+	cursor := ctx.cursor()
+
+	if f.dragging {
+		if input.MousePressed(ebiten.MouseButtonLeft) {
+			dx := cursor.X - f.grab_x
+			if !f.moved && dx*dx > drag_distance*drag_distance {
+				// scrubbing starts where the drag came loose, so the value doesn't jump
+				f.moved, f.grab_x, dx = true, cursor.X, 0
+			}
+			if f.moved {
+				fine := 1.0
+				if input.KeyPressed(ebiten.KeyShift) {
+					fine = fine_steps
+				}
+				value = f.grab + float64(dx)*step/fine
+			}
+		} else {
+			f.dragging = false
+			if !f.moved {
+				f.editing = true
+				f.input.Focused = true
+				f.input.Set(format_number(value, step))
+				f.input.SelectAll()
+			}
+		}
+	}
+
+	if f.editing {
+		layout := ctx.layout
+		ctx.Push(bounds.Min.X, bounds.Min.Y, bounds.Dx(), bounds.Dy(), nil)
+		submitted := ctx.TextInput(&f.input)
+		ctx.Pop()
+		ctx.layout = layout
+		if submitted || !f.input.Focused {
+			f.editing, f.input.Focused = false, false
+			if v, err := cvar.Eval(f.input.Text); err == nil {
+				value = v
+			}
+		}
+		value = clamp(value, lo, hi)
+		return value, value != old
+	}
+	value = clamp(value, lo, hi)
+
+	dst.Fill(ctx.Theme.ButtonPressed)
+	if lo < hi {
+		// bounded numbers fill as much of the field as they are of their range
+		w := float32(bounds.Dx()) * float32((value-lo)/(hi-lo))
+		vector.DrawFilledRect(dst, float32(bounds.Min.X), float32(bounds.Min.Y), w, float32(bounds.Dy()), ctx.Theme.Button, false)
+	}
+	border := ctx.Theme.Border
+	if f.dragging || ctx.hover_uid == uid {
+		border = ctx.Theme.Highlight
+	}
+	DrawBorder(dst, 0, 1, border)
+
+	inner := dst.SubImage(bounds.Inset(1)).(*ebiten.Image)
+	if label != "" {
+		DrawString(inner.SubImage(image.Rect(bounds.Min.X+text_padding, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)).(*ebiten.Image), label, 0, 0.5)
+	}
+	DrawString(inner, format_number(value, step), 0.5, 0.5)
+
+	origin := ctx.origin
+	ctx.push_trigger(uid, bounds, ButtonBehavior{
+		OnPress: func(btn ebiten.MouseButton) {
+			cx, _ := input.CursorPosition()
+			f.dragging, f.moved = true, false
+			f.grab_x, f.grab = cx-origin.X, value
+		},
+	})
+	return value, value != old
+}
+
+// format_number shows `v` with as many decimals as `step` has.
+func format_number(v, step float64) string {
+	decimals := 0
+	if step > 0 && step < 1 {
+		decimals = int(math.Ceil(-math.Log10(step) - 1e-9))
+	}
+	if math.Abs(v) < math.Pow(10, -float64(decimals))/2 {
+		// no -0.00 for numbers that round to nothing
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if lo < hi {
+		return min(max(v, lo), hi)
+	}
+	return v
+}
+
+// Spinbox is the state of a number field with buttons to step it, kept by the caller from frame to frame.
+type Spinbox struct {
+	// Step is what the buttons add and take away, and what a pixel dragged moves the number by. Min and Max bound
+	// the number unless they're equal.
+	Step     float64
+	Min, Max float64
+
+	field number_field
+	// nudge is what the buttons added since the spinbox was last declared
+	nudge float64
+}
+
+// Spinbox declares a number field between a - and a + button in the next area of the layout. The number is
+// dragged left and right to scrub it, with shift held to go finer, or clicked and typed, enter or clicking
+// elsewhere taking what was typed. It reports whether `value` changed.
+func (ctx *Context) Spinbox(s *Spinbox, value *float64) (changed bool) {
+	step := s.Step
+	if step <= 0 {
+		step = default_step
+	}
+	old := *value
+	*value = clamp(*value+s.nudge, s.Min, s.Max)
+	s.nudge = 0
+
+	area := ctx.Next().Bounds()
+	button := Size{Min: image.Pt(area.Dy(), 0), Preferred: image.Pt(area.Dy(), 0)}
+	layout := ctx.layout
+	ctx.Push(area.Min.X, area.Min.Y, area.Dx(), area.Dy(), &StackLayout{Sizes: []Size{button, {Stretch: 1}, button}})
+	ctx.Button(ButtonArgs{
+		Text:   "-",
+		AlignX: 0.5,
+		AlignY: 0.5,
+		// the buttons go off as they're pressed, the frame after that the press damages any layer the spinbox is in
+		Behavior: ButtonBehavior{Mode: ActivateOnClick, OnActivate: func() { s.nudge -= step }},
+	})
+	*value, _ = ctx.number_field(&s.field, "", *value, step, s.Min, s.Max)
+	ctx.Button(ButtonArgs{
+		Text:     "+",
+		AlignX:   0.5,
+		AlignY:   0.5,
+		Behavior: ButtonBehavior{Mode: ActivateOnClick, OnActivate: func() { s.nudge += step }},
+	})
+	ctx.Pop()
+	ctx.layout = layout
+	return *value != old
+}
+
+// VectorEdit is the state of an editor of a vector of up to 4 components, kept by the caller from frame to frame.
+type VectorEdit struct {
+	// Step is what a pixel dragged moves a component by, Min and Max bound them unless they're equal.
+	Step     float64
+	Min, Max float64
+	// Color edits the components as red, green, blue and alpha from 0 to 1, with a swatch of the color.
+	Color bool
+	// Linked moves every component along with the one being edited, scaled by the same factor, like a uniform
+	// scale. The button at the end of the editor toggles it.
+	Linked bool
+
+	fields [4]number_field
+}
+
+// Vector declares a field per component of `v`, up to 4, side by side in the next area of the layout, each
+// edited like a Spinbox's. It reports whether `v` changed.
+func (ctx *Context) Vector(e *VectorEdit, v []float32) (changed bool) {
+	n := min(len(v), len(e.fields))
+	labels := "XYZW"
+	step, lo, hi := e.Step, e.Min, e.Max
+	if e.Color {
+		labels = "RGBA"
+		if lo == hi {
+			lo, hi = 0, 1
+		}
+		if step <= 0 {
+			step = color_step
+		}
+	}
+	if step <= 0 {
+		step = default_step
+	}
+
+	area := ctx.Next().Bounds()
+	square := Size{Min: image.Pt(area.Dy(), 0), Preferred: image.Pt(area.Dy(), 0)}
+	sizes := make([]Size, n, n+2)
+	for i := range sizes {
+		sizes[i] = Size{Min: image.Pt(line_height, 0), Stretch: 1}
+	}
+	if e.Color {
+		sizes = append(sizes, square)
+	}
+	sizes = append(sizes, square)
+
+	layout := ctx.layout
+	ctx.Push(area.Min.X, area.Min.Y, area.Dx(), area.Dy(), &StackLayout{Gap: 2, Sizes: sizes})
+	for i := range n {
+		next, moved := ctx.number_field(&e.fields[i], labels[i:i+1], float64(v[i]), step, lo, hi)
+		if !moved {
+			continue
+		}
+		changed = true
+		if e.Linked {
+			link(v[:n], i, next, lo, hi)
+		} else {
+			v[i] = float32(next)
+		}
+	}
+	if e.Color {
+		swatch := ctx.Next()
+		swatch.Fill(vector_color(v[:n]))
+		DrawBorder(swatch, 0, 1, ctx.Theme.Border)
+	}
+	ctx.Button(ButtonArgs{
+		Text:     "=",
+		AlignX:   0.5,
+		AlignY:   0.5,
+		Selected: e.Linked,
+		Behavior: ButtonBehavior{Mode: ActivateOnClick, OnActivate: func() { e.Linked = !e.Linked }},
+	})
+	ctx.Pop()
+	ctx.layout = layout
+	return changed
+}
+
+// link moves component `i` of `v` to `next` and the others along with it: scaled by the same factor, or moved by
+// the same amount while it's zero.
+func link(v []float32, i int, next, lo, hi float64) {
+	prev := float64(v[i])
+	for j := range v {
+		switch {
+		case j == i:
+			v[j] = float32(next)
+		case prev != 0:
+			v[j] = float32(clamp(float64(v[j])*next/prev, lo, hi))
+		default:
+			v[j] = float32(clamp(float64(v[j])+next-prev, lo, hi))
+		}
+	}
+}
+
+// vector_color is the color of components from 0 to 1, opaque without an alpha.
+func vector_color(v []float32) color.NRGBA {
+	channel := func(i int) uint8 {
+		if i >= len(v) {
+			return 255
+		}
+		return uint8(min(max(v[i], 0), 1)*255 + 0.5)
+	}
+	return color.NRGBA{channel(0), channel(1), channel(2), channel(3)}
+}
@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/richtext"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/text"
+)
+
+// RichText draws `markup` word wrapped into the next area of the layout, see package richtext for what it can do.
+// Clicking a link calls `on_link` with its target. Lines past the bottom of the area are cut off.
+func (ctx *Context) RichText(markup string, on_link func(target string)) {
+	dst := ctx.Next()
+	bounds := dst.Bounds()
+	theme := &ctx.Theme
+
+	lines := richtext.Wrap(richtext.Parse(markup), float64(bounds.Dx()), func(s string, style richtext.Style) float64 {
+		return text.Width(s, rich_face(style))
+	})
+
+	for i, line := range lines {
+		y := bounds.Min.Y + i*line_height
+		if y+line_height > bounds.Max.Y {
+			break
+		}
+		for _, run := range line.Runs {
+			x := float64(bounds.Min.X) + run.X
+			clr := theme.Text
+			if run.Style.Colored {
+				clr = run.Style.Color
+			}
+
+			if target := run.Style.Link; target != "" {
+				// every link is a widget of its own
+				uid := ctx.uid(0)
+				clr = theme.Link
+				if ctx.hover_uid == uid {
+					clr = theme.LinkHovered
+				}
+				underline := float32(y + line_height - 2)
+				vector.StrokeLine(dst, float32(x), underline, float32(x+run.Width), underline, 1, clr, false)
+				if on_link != nil {
+					rect := image.Rect(int(x), y, int(x+run.Width), y+line_height).Intersect(bounds)
+					ctx.push_trigger(uid, rect, ButtonBehavior{OnActivate: func() { on_link(target) }})
+				}
+			}
+
+			text.Draw(dst, run.Text, rich_face(run.Style), x, float64(y), 0, line_height, 0, clr)
+		}
+	}
+}
+
+func rich_face(style richtext.Style) text.Face {
+	if style.Bold {
+		return text.MonoBold.Face(font_size)
+	}
+	return text.Mono.Face(font_size)
+}
@@ -0,0 +1,299 @@
+package ui
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/exp/textinput"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/clipboard"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/text"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/textedit"
+)
+
+// text_padding is the room left of the text of a field
+const text_padding = 4
+
+// edit_keys are the keys which edit a line, see EditLine
+var edit_keys = []ebiten.Key{
+	ebiten.KeyBackspace, ebiten.KeyDelete, ebiten.KeyArrowLeft, ebiten.KeyArrowRight, ebiten.KeyHome, ebiten.KeyEnd,
+	ebiten.KeyA, ebiten.KeyC, ebiten.KeyX, ebiten.KeyV, ebiten.KeyEnter, ebiten.KeyNumpadEnter,
+}
+
+// edit is something typed in a tick, characters or a key with the modifiers held with it.
+type edit struct {
+	chars       string
+	key         ebiten.Key
+	ctrl, shift bool
+	has_key     bool
+}
+
+// edits are what was typed since the last frame, for the focused text input
+var edits []edit
+
+// poll_edits returns what was typed this tick.
+func poll_edits(dst []edit) []edit {
+	if chars := input.Chars(); chars != "" {
+		dst = append(dst, edit{chars: chars})
+	}
+	// command on macOS does what control does elsewhere
+	ctrl := input.KeyPressed(ebiten.KeyControl) || input.KeyPressed(ebiten.KeyMeta)
+	shift := input.KeyPressed(ebiten.KeyShift)
+	for _, key := range edit_keys {
+		if input.KeyJustPressed(key) {
+			dst = append(dst, edit{key: key, ctrl: ctrl, shift: shift, has_key: true})
+		}
+	}
+	return dst
+}
+
+// apply applies `e` to `l`, reporting whether it was enter. Pasting is only done once the clipboard was read, a
+// tick or so later.
+func (e edit) apply(l *textedit.Line) (submitted bool) {
+	if !e.has_key {
+		l.Insert(e.chars)
+		return false
+	}
+	switch e.key {
+	case ebiten.KeyBackspace:
+		l.Backspace(e.ctrl)
+	case ebiten.KeyDelete:
+		l.Delete(e.ctrl)
+	case ebiten.KeyArrowLeft:
+		l.Left(e.ctrl, e.shift)
+	case ebiten.KeyArrowRight:
+		l.Right(e.ctrl, e.shift)
+	case ebiten.KeyHome:
+		l.Home(e.shift)
+	case ebiten.KeyEnd:
+		l.End(e.shift)
+	case ebiten.KeyA:
+		if e.ctrl {
+			l.SelectAll()
+		}
+	case ebiten.KeyC:
+		if e.ctrl && l.Selected() != "" {
+			clipboard.Copy(l.Selected())
+		}
+	case ebiten.KeyX:
+		if e.ctrl && l.Selected() != "" {
+			clipboard.Copy(l.Cut())
+		}
+	case ebiten.KeyV:
+		if e.ctrl {
+			clipboard.Paste(l.Insert)
+		}
+	case ebiten.KeyEnter, ebiten.KeyNumpadEnter:
+		return true
+	}
+	return false
+}
+
+// EditLine applies the typing of this tick to `l`: characters, backspace and delete, the arrows, home and end to
+// move, with shift to select and control to go by words, and control with A, C, X and V to select everything,
+// copy, cut and paste through the system clipboard. It reports whether enter was pressed. Call it from Update,
+// every tick.
+func EditLine(l *textedit.Line) (submitted bool) {
+	for _, e := range poll_edits(nil) {
+		if e.apply(l) {
+			submitted = true
+		}
+	}
+	return
+}
+
+// TextInput is the state of a text field, kept by the caller from frame to frame.
+type TextInput struct {
+	textedit.Line
+	// Focused fields take what's typed. Clicking a field focuses it, clicking anywhere else lets go of it.
+	Focused bool
+
+	// scroll is how far the text is scrolled left to keep the cursor in view
+	scroll float64
+
+	// ime is the session of the platform's input method while the field is focused, nil where there's none.
+	// Typed text arrives through it instead of as characters, composing is the text being composed with it, such
+	// as kana on their way to becoming kanji, shown at the cursor until it's committed.
+	ime       chan textinput.State
+	ime_end   func()
+	composing textinput.State
+}
+
+// ime_sessions counts the fields with an input method session. Their text arrives whenever the input method
+// sends it, layers can't tell that it did, so they draw every frame while a session is open.
+var ime_sessions int
+
+// compose starts a session of the input method, with its candidate window at `x`, `y` on the screen, and takes
+// what it sent since last frame.
+func (t *TextInput) compose(x, y int) {
+	if t.ime == nil {
+		if t.ime, t.ime_end = textinput.Start(x, y); t.ime == nil {
+			return
+		}
+		ime_sessions++
+	}
+	for {
+		select {
+		case state, ok := <-t.ime:
+			if state.Error != nil {
+				logger.Warnf("input method: %v", state.Error)
+			}
+			if !ok || state.Error != nil {
+				// the session ended, another starts next frame
+				t.ime, t.ime_end, t.composing = nil, nil, textinput.State{}
+				ime_sessions--
+				return
+			}
+			if state.Committed {
+				t.Insert(state.Text)
+				t.composing = textinput.State{}
+			} else {
+				t.composing = state
+			}
+		default:
+			return
+		}
+	}
+}
+
+// end_compose ends the session of the input method, dropping whatever wasn't committed.
+func (t *TextInput) end_compose() {
+	if t.ime == nil {
+		return
+	}
+	t.ime_end()
+	t.ime, t.ime_end, t.composing = nil, nil, textinput.State{}
+	ime_sessions--
+}
+
+// TextInput declares a text field in the next area of the layout, which is edited like EditLine describes while
+// it's focused. It reports whether enter was pressed in it.
+func (ctx *Context) TextInput(t *TextInput) (submitted bool) {
+	uid := ctx.uid(1)
+	dst := ctx.Next()
+	bounds := dst.Bounds()
+
+	if t.Focused {
+		// the candidate window of the input method goes below the cursor
+		at := bounds.Min.Add(ctx.origin)
+		t.compose(at.X+text_padding+int(t.x(t.Cursor)-t.scroll), at.Y+bounds.Dy())
+
+		input_mu.Lock()
+		pending := edits
+		field_focused = true
+		input_mu.Unlock()
+		for _, e := range pending {
+			// while composing the keys are the input method's, and with one the characters come through it
+			if t.composing.Text != "" || t.ime != nil && !e.has_key {
+				continue
+			}
+			if e.apply(&t.Line) {
+				submitted = true
+			}
+		}
+		if mouse_just_pressed(ebiten.MouseButtonLeft) && !ctx.cursor().In(bounds) {
+			t.Focused = false
+		}
+	}
+	if !t.Focused {
+		t.end_compose()
+	}
+
+	dst.Fill(ctx.Theme.ButtonPressed)
+	border := ctx.Theme.Border
+	if t.Focused {
+		border = ctx.Theme.Highlight
+	}
+	DrawBorder(dst, 0, 1, border)
+
+	// keep the cursor in view, with a little room after it, or what's being composed at it
+	width := float64(bounds.Dx() - text_padding*2)
+	x := t.x(t.Cursor) + text_width(t.composing.Text)
+	t.scroll = min(max(t.scroll, x-width+text_padding), x)
+	t.scroll = max(t.scroll, 0)
+
+	origin := ctx.origin
+	field := dst.SubImage(bounds.Inset(1)).(*ebiten.Image)
+	draw_line(field, &t.Line, t.composing, float64(bounds.Min.X+text_padding)-t.scroll, bounds.Min.Y, bounds.Dy(), t.Focused, ctx.Theme)
+
+	ctx.push_trigger(uid, bounds, ButtonBehavior{
+		Mode: ActivateOnClick,
+		OnPress: func(btn ebiten.MouseButton) {
+			cx, _ := input.CursorPosition()
+			t.Focused = true
+			t.Cursor = t.offset(float64(cx-origin.X-bounds.Min.X-text_padding) + t.scroll)
+			t.Anchor = t.Cursor
+		},
+	})
+	return submitted
+}
+
+// DrawLine draws `l` starting at `x`, in a row at `y` which is `height` high, with its selection and, when
+// `focused`, its cursor.
+func DrawLine(dst *ebiten.Image, l *textedit.Line, x float64, y, height int, focused bool, theme Theme) {
+	draw_line(dst, l, textinput.State{}, x, y, height, focused, theme)
+}
+
+// draw_line draws `l` with `composing` at its cursor, underlined and the part the input method is working on
+// twice as thick, like input methods show it.
+func draw_line(dst *ebiten.Image, l *textedit.Line, composing textinput.State, x float64, y, height int, focused bool, theme Theme) {
+	s := l.Text
+	caret := l.Cursor
+	if composing.Text != "" {
+		s = l.Text[:l.Cursor] + composing.Text + l.Text[l.Cursor:]
+		caret = l.Cursor + composing.CompositionSelectionStartInBytes
+	} else if focused {
+		start, end := l.Selection()
+		if start != end {
+			sx := x + text_width(l.Text[:start])
+			vector.DrawFilledRect(dst, float32(sx), float32(y+2), float32(text_width(l.Text[start:end])), float32(height-4), theme.ButtonHovered, false)
+		}
+	}
+
+	ty := float64(y) + float64(height-line_height)/2
+	text.Draw(dst, s, text.Mono.Face(font_size), x, ty, 0, line_height, 0, theme.Text)
+
+	if composing.Text != "" {
+		underline := float32(y + height - 3)
+		start := x + text_width(l.Text[:l.Cursor])
+		vector.StrokeLine(dst, float32(start), underline, float32(start+text_width(composing.Text)), underline, 1, theme.Text, false)
+		from, to := composing.CompositionSelectionStartInBytes, composing.CompositionSelectionEndInBytes
+		if from < to && to <= len(composing.Text) {
+			a := start + text_width(composing.Text[:from])
+			b := start + text_width(composing.Text[:to])
+			vector.StrokeLine(dst, float32(a), underline, float32(b), underline, 2, theme.Text, false)
+		}
+	}
+	if focused {
+		cx := float32(x + text_width(s[:caret]))
+		vector.StrokeLine(dst, cx, float32(y+2), cx, float32(y+height-2), 1, theme.Text, false)
+	}
+}
+
+func text_width(s string) float64 {
+	return text.Width(s, text.Mono.Face(font_size))
+}
+
+// x is how far from the start of the text the character at byte `i` is.
+func (t *TextInput) x(i int) float64 {
+	return text_width(t.Text[:i])
+}
+
+// offset is the byte offset of the character boundary nearest to `x`.
+func (t *TextInput) offset(x float64) int {
+	prev_i, prev_x := 0, 0.0
+	for i := range t.Text {
+		if i == 0 {
+			continue
+		}
+		w := t.x(i)
+		if x < (prev_x+w)/2 {
+			return prev_i
+		}
+		prev_i, prev_x = i, w
+	}
+	if x < (prev_x+t.x(len(t.Text)))/2 {
+		return prev_i
+	}
+	return len(t.Text)
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"image/color"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/audio"
+)
+
+// Theme is how the widgets of a context look and sound.
+type Theme struct {
+	Button        color.RGBA
+	ButtonHovered color.RGBA
+	ButtonPressed color.RGBA
+	// Border is the edge of panels and the line just inside the edge of buttons, which is Highlight.
+	Border    color.RGBA
+	Highlight color.RGBA
+	Panel     color.RGBA
+	// Text is the color of rich text, Link the color of its links.
+	Text        color.RGBA
+	Link        color.RGBA
+	LinkHovered color.RGBA
+
+	Sounds ThemeSounds
+}
+
+// ThemeSounds are the audio events played when the cursor enters a widget, presses it and activates it. The events
+// are looked up in audio.Default, empty ones are silent.
+type ThemeSounds struct {
+	Hover    string
+	Press    string
+	Activate string
+}
+
+// DefaultTheme is the theme of new contexts, gray and silent as the tools built on it want to be.
+var DefaultTheme = Theme{
+	Button:        color.RGBA{80, 80, 80, 255},
+	ButtonHovered: color.RGBA{128, 128, 128, 255},
+	ButtonPressed: color.RGBA{60, 60, 60, 255},
+	Border:        color.RGBA{127, 127, 127, 255},
+	Highlight:     color.RGBA{196, 196, 196, 255},
+	Panel:         color.RGBA{40, 40, 40, 230},
+	Text:          color.RGBA{255, 255, 255, 255},
+	Link:          color.RGBA{110, 170, 255, 255},
+	LinkHovered:   color.RGBA{180, 215, 255, 255},
+}
+
+// play plays the audio event `name` of the theme.
+func play(name string) {
+	if name != "" {
+		audio.Default.Trigger(name, 1)
+	}
+}
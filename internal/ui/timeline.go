@@ -0,0 +1,231 @@
+package ui
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+)
+
+const (
+	// controls_width is the width of each of the buttons left of the track
+	controls_width = 24
+	// label_spacing is the least room between the labels of the ruler
+	label_spacing = 48
+	// max_zoom is how many times the track can be zoomed in on
+	max_zoom = 64
+	// zoom_step is how much a notch of the mouse wheel zooms
+	zoom_step = 1.25
+)
+
+// Timeline is the state of a timeline widget, kept by the caller from frame to frame. Time is in any unit the
+// caller likes, ticks or seconds, the ruler counts it.
+type Timeline struct {
+	// Time is where the playhead is, from 0 to Length.
+	Time, Length float64
+	// Playing moves the playhead along in Advance, and Loop takes it back to the start at the end instead of
+	// stopping there.
+	Playing, Loop bool
+	// Step is how far the frame buttons move the playhead, a frame of the animation.
+	Step float64
+	// Keys are the times of the keyframes, drawn as markers on the track. Scrubbing snaps to them unless shift is
+	// held, and the key buttons jump between them.
+	Keys []float64
+
+	// zoom is how many times longer the track is than the widget, start the time at its left edge
+	zoom  float64
+	start float64
+	// scrubbing is set while the track is held
+	scrubbing bool
+}
+
+// Advance moves the playhead by `dt` while playing. Call it from Update, so recordings replay the same.
+func (t *Timeline) Advance(dt float64) {
+	if !t.Playing || t.Length <= 0 {
+		return
+	}
+	t.Time += dt
+	if t.Time < t.Length {
+		return
+	}
+	if t.Loop {
+		t.Time = math.Mod(t.Time, t.Length)
+	} else {
+		t.Time, t.Playing = t.Length, false
+	}
+}
+
+// Seek moves the playhead to `time`, within the length.
+func (t *Timeline) Seek(time float64) {
+	t.Time = min(max(time, 0), t.Length)
+}
+
+// key returns the nearest key before the playhead when `dir` is -1, after it when 1, or the start or the end when
+// there's none.
+func (t *Timeline) key(dir float64) float64 {
+	best := 0.0
+	if dir > 0 {
+		best = t.Length
+	}
+	// keys the playhead is on don't count, or it would never get off of them
+	const epsilon = 1e-9
+	for _, k := range t.Keys {
+		if d := (k - t.Time) * dir; d > epsilon && d < (best-t.Time)*dir {
+			best = k
+		}
+	}
+	return best
+}
+
+// Timeline declares a timeline in the next area of the layout: buttons to jump to the previous key, step a frame
+// back, play or pause, step a frame on and jump to the next key, then a track with a ruler, the keys and the
+// playhead. Pressing the track scrubs the playhead along it, and the mouse wheel zooms in and out around the
+// cursor. The playhead is kept in view while it plays or is scrubbed.
+func (ctx *Context) Timeline(t *Timeline) {
+	area := ctx.Next().Bounds()
+	if t.zoom < 1 {
+		t.zoom = 1
+	}
+	step := t.Step
+	if step <= 0 {
+		step = t.Length / 100
+	}
+
+	play := ">"
+	if t.Playing {
+		play = "||"
+	}
+	controls := []struct {
+		text string
+		do   func()
+	}{
+		{"|<", func() { t.Seek(t.key(-1)) }},
+		{"<|", func() { t.Seek(t.Time - step) }},
+		{play, func() {
+			if !t.Playing && t.Time >= t.Length {
+				t.Time = 0
+			}
+			t.Playing = !t.Playing
+		}},
+		{"|>", func() { t.Seek(t.Time + step) }},
+		{">|", func() { t.Seek(t.key(1)) }},
+	}
+	layout := ctx.layout
+	ctx.Push(area.Min.X, area.Min.Y, len(controls)*controls_width, area.Dy(), &GridLayout{Columns: len(controls), Rows: 1})
+	for _, c := range controls {
+		ctx.Button(ButtonArgs{
+			Text:     c.text,
+			AlignX:   0.5,
+			AlignY:   0.5,
+			Behavior: ButtonBehavior{OnActivate: c.do},
+		})
+	}
+	ctx.Pop()
+	ctx.layout = layout
+
+	track := image.Rect(area.Min.X+len(controls)*controls_width+2, area.Min.Y, area.Max.X, area.Max.Y)
+	if track.Dx() <= 0 || t.Length <= 0 {
+		return
+	}
+	ctx.track(t, track)
+}
+
+// track declares the track of a timeline in `track`.
+func (ctx *Context) track(t *Timeline, track image.Rectangle) {
+	uid := ctx.uid(1)
+	cursor := ctx.cursor()
+	visible := t.Length / t.zoom
+	per_pixel := visible / float64(track.Dx())
+	time_at := func(x int) float64 {
+		return t.start + float64(x-track.Min.X)*per_pixel
+	}
+	x_of := func(time float64) float32 {
+		return float32(track.Min.X) + float32((time-t.start)/per_pixel)
+	}
+
+	if cursor.In(track) {
+		if _, wheel := input.Wheel(); wheel != 0 {
+			// the time under the cursor stays under it
+			at := time_at(cursor.X)
+			t.zoom = min(max(t.zoom*math.Pow(zoom_step, wheel), 1), max_zoom)
+			visible = t.Length / t.zoom
+			per_pixel = visible / float64(track.Dx())
+			t.start = at - float64(cursor.X-track.Min.X)*per_pixel
+		}
+	}
+	if t.scrubbing {
+		if input.MousePressed(ebiten.MouseButtonLeft) {
+			t.Seek(t.snap(time_at(cursor.X), per_pixel*key_reach))
+		} else {
+			t.scrubbing = false
+		}
+	}
+	if (t.Playing || t.scrubbing) && (t.Time < t.start || t.Time > t.start+visible) {
+		t.start = t.Time - visible/2
+	}
+	t.start = min(max(t.start, 0), t.Length-visible)
+
+	dst := ctx.layers[len(ctx.layers)-1].SubImage(track).(*ebiten.Image)
+	dst.Fill(ctx.Theme.ButtonPressed)
+
+	// a label every so many units of time, at least label_spacing apart
+	every := nice_interval(per_pixel * label_spacing)
+	for n := math.Ceil(t.start / every); n*every <= t.start+visible; n++ {
+		time := n * every
+		x := x_of(time)
+		vector.StrokeLine(dst, x, float32(track.Max.Y-6), x, float32(track.Max.Y), 1, ctx.Theme.Border, false)
+		label := dst.SubImage(image.Rect(int(x)+2, track.Min.Y, int(x)+label_spacing, track.Max.Y)).(*ebiten.Image)
+		DrawString(label, format_number(time, every), 0, 0)
+	}
+
+	mid := float32(track.Min.Y+track.Max.Y) / 2
+	for _, k := range t.Keys {
+		x := x_of(k)
+		vector.DrawFilledRect(dst, x-3, mid-3, 6, 6, ctx.Theme.Highlight, false)
+	}
+
+	x := x_of(t.Time)
+	vector.StrokeLine(dst, x, float32(track.Min.Y), x, float32(track.Max.Y), 2, ctx.Theme.Link, false)
+
+	border := ctx.Theme.Border
+	if ctx.hover_uid == uid || t.scrubbing {
+		border = ctx.Theme.Highlight
+	}
+	DrawBorder(dst, 0, 1, border)
+
+	ctx.push_trigger(uid, track, ButtonBehavior{
+		OnPress: func(btn ebiten.MouseButton) {
+			t.scrubbing, t.Playing = true, false
+		},
+	})
+}
+
+// snap returns the key within `reach` of `time`, or `time` when there's none or shift is held.
+func (t *Timeline) snap(time, reach float64) float64 {
+	if input.KeyPressed(ebiten.KeyShift) {
+		return time
+	}
+	for _, k := range t.Keys {
+		if math.Abs(k-time) <= reach {
+			return k
+		}
+	}
+	return time
+}
+
+// nice_interval is the smallest of 1, 2 or 5 times a power of ten which is at least `least`.
+func nice_interval(least float64) float64 {
+	if least <= 0 {
+		return 1
+	}
+	power := math.Pow(10, math.Floor(math.Log10(least)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*power >= least {
+			return m * power
+		}
+	}
+	return 10 * power
+}
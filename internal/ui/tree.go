@@ -0,0 +1,290 @@
+package ui
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"image"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/flex"
+)
+
+// Kind is what a node of a tree is.
+type Kind int
+
+const (
+	KindLabel Kind = iota
+	KindButton
+	KindPanel
+	KindStack
+	KindGrid
+	KindImmediate
+)
+
+// Node describes a widget, or a container of them, as data. A tree of nodes is built every frame like the
+// immediate mode widgets are declared, and Context.Tree diffs it against the last one: nodes that didn't change
+// keep their measurements and areas instead of working them out again. Build trees with the functions below.
+type Node struct {
+	Kind Kind
+	// Key tells the node apart from its siblings across frames, so its widget keeps being hovered and pressed
+	// when siblings come and go. Without one its index is used.
+	Key  string
+	Text string
+	// Selected draws a button held down.
+	Selected   bool
+	OnActivate func()
+
+	// Vertical, Gap and Columns lay out the children of stacks and grids.
+	Vertical bool
+	Gap      int
+	Columns  int
+	Children []*Node
+
+	// Size replaces what the node measures when it isn't zero, Stretch is added to it.
+	Size    Size
+	Stretch float64
+	// Build declares the immediate mode widgets of an Immediate node, in its area. It runs every frame.
+	Build func()
+
+	measured Size
+	rect     image.Rectangle
+	uid      uid_t
+}
+
+// Label is a line of text.
+func Label(text string) *Node {
+	return &Node{Kind: KindLabel, Text: text}
+}
+
+// Button is a button running `on_activate` when clicked.
+func Button(text string, on_activate func()) *Node {
+	return &Node{Kind: KindButton, Text: text, OnActivate: on_activate}
+}
+
+// Panel is a background with `child` inside of it.
+func Panel(child *Node) *Node {
+	return &Node{Kind: KindPanel, Children: []*Node{child}}
+}
+
+// HStack lines `children` up from left to right, VStack from top to bottom, see StackLayout.
+func HStack(gap int, children ...*Node) *Node {
+	return &Node{Kind: KindStack, Gap: gap, Children: children}
+}
+
+func VStack(gap int, children ...*Node) *Node {
+	return &Node{Kind: KindStack, Vertical: true, Gap: gap, Children: children}
+}
+
+// Grid fills `columns` columns with `children` row by row, see GridLayout.
+func Grid(columns int, children ...*Node) *Node {
+	return &Node{Kind: KindGrid, Columns: max(columns, 1), Children: children}
+}
+
+// Immediate is an area of `size` where `build` declares immediate mode widgets, the parts of a tree that change.
+func Immediate(size Size, build func()) *Node {
+	return &Node{Kind: KindImmediate, Size: size, Build: build}
+}
+
+// WithKey sets the key of the node and returns it.
+func (n *Node) WithKey(key string) *Node {
+	n.Key = key
+	return n
+}
+
+// WithStretch sets the stretch of the node and returns it.
+func (n *Node) WithStretch(stretch float64) *Node {
+	n.Stretch = stretch
+	return n
+}
+
+// WithSize sets the size the node asks for instead of what it measures and returns it.
+func (n *Node) WithSize(size Size) *Node {
+	n.Size = size
+	return n
+}
+
+// WithSelected sets whether a button is drawn held down and returns it.
+func (n *Node) WithSelected(selected bool) *Node {
+	n.Selected = selected
+	return n
+}
+
+// panel_padding is the room between a panel and what's in it
+const panel_padding = 4
+
+// Tree is the state of a declarative UI, kept by the caller from frame to frame.
+type Tree struct {
+	last *Node
+	area image.Rectangle
+	// seed sets the uids of this tree apart from other trees'
+	seed uint64
+	// Changed reports whether the last tree differed from the one before it, e.g. to damage a layer.
+	Changed bool
+}
+
+var (
+	uid_seed = maphash.MakeSeed()
+	trees    uint64
+)
+
+// Tree declares the widgets of `root` in the next area of the layout.
+func (ctx *Context) Tree(t *Tree, root *Node) {
+	if t.seed == 0 {
+		trees++
+		t.seed = trees
+	}
+	area := ctx.Next().Bounds()
+	same := same_tree(root, t.last)
+	t.Changed = !same
+	if same {
+		adopt(root, t.last, area == t.area)
+	}
+	if !same || area != t.area {
+		measure(root)
+		arrange(root, area)
+		root.uid = uid_t{base: t.seed}
+		key_uids(root, t.seed)
+	}
+	t.last, t.area = root, area
+
+	layout := ctx.layout
+	ctx.declare(root)
+	ctx.layout = layout
+}
+
+// same_tree reports whether `a` and `b` would measure and lay out the same. Callbacks aren't compared, they
+// change every frame and only run, the immediate parts run every frame anyway.
+func same_tree(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind || a.Key != b.Key || a.Text != b.Text || a.Selected != b.Selected ||
+		a.Vertical != b.Vertical || a.Gap != b.Gap || a.Columns != b.Columns ||
+		a.Size != b.Size || a.Stretch != b.Stretch || len(a.Children) != len(b.Children) {
+		return false
+	}
+	for i := range a.Children {
+		if !same_tree(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// adopt copies what was worked out for the nodes of `old` to the same nodes of `n`, their areas when they're
+// still valid.
+func adopt(n, old *Node, areas bool) {
+	n.measured, n.uid = old.measured, old.uid
+	if areas {
+		n.rect = old.rect
+	}
+	for i, c := range n.Children {
+		adopt(c, old.Children[i], areas)
+	}
+}
+
+func measure(n *Node) Size {
+	sizes := make([]Size, len(n.Children))
+	for i, c := range n.Children {
+		sizes[i] = measure(c)
+	}
+	switch n.Kind {
+	case KindLabel:
+		n.measured = TextSize(n.Text)
+	case KindButton:
+		n.measured = ButtonSize(n.Text)
+	case KindPanel:
+		if len(sizes) > 0 {
+			pad := image.Pt(panel_padding*2, panel_padding*2)
+			n.measured = Size{Min: sizes[0].Min.Add(pad), Preferred: sizes[0].Preferred.Add(pad), Stretch: sizes[0].Stretch}
+		}
+	case KindStack:
+		n.measured = (&StackLayout{Vertical: n.Vertical, Gap: n.Gap, Sizes: sizes}).Measure()
+	case KindGrid:
+		columns := make([]flex.Item, n.Columns)
+		rows := make([]flex.Item, (len(sizes)+n.Columns-1)/n.Columns)
+		for i, s := range sizes {
+			columns[i%n.Columns] = flex.Largest(columns[i%n.Columns], s.along(false))
+			rows[i/n.Columns] = flex.Largest(rows[i/n.Columns], s.along(true))
+		}
+		x, y := flex.Measure(columns, 0), flex.Measure(rows, 0)
+		n.measured = Size{Min: image.Pt(x.Min, y.Min), Preferred: image.Pt(x.Preferred, y.Preferred), Stretch: max(x.Stretch, y.Stretch)}
+	case KindImmediate:
+		n.measured = Size{}
+	}
+	if n.Size != (Size{}) {
+		n.measured = n.Size
+	}
+	n.measured.Stretch += n.Stretch
+	return n.measured
+}
+
+func arrange(n *Node, rect image.Rectangle) {
+	n.rect = rect
+	sizes := make([]Size, len(n.Children))
+	for i, c := range n.Children {
+		sizes[i] = c.measured
+	}
+	var layout Layout
+	switch n.Kind {
+	case KindPanel:
+		if len(n.Children) > 0 {
+			arrange(n.Children[0], rect.Inset(panel_padding))
+		}
+		return
+	case KindStack:
+		layout = &StackLayout{Vertical: n.Vertical, Gap: n.Gap, Sizes: sizes}
+	case KindGrid:
+		layout = &GridLayout{Columns: n.Columns, Rows: (len(sizes) + n.Columns - 1) / n.Columns, Cells: sizes}
+	default:
+		return
+	}
+	for _, c := range n.Children {
+		arrange(c, layout.Layout(rect))
+	}
+}
+
+// key_uids gives the widgets uids from the keys, or indices, on their way down the tree.
+func key_uids(n *Node, parent uint64) {
+	var h maphash.Hash
+	h.SetSeed(uid_seed)
+	for i, c := range n.Children {
+		h.Reset()
+		h.Write(binary.LittleEndian.AppendUint64(nil, parent))
+		if c.Key != "" {
+			h.WriteString(c.Key)
+		} else {
+			h.Write(binary.LittleEndian.AppendUint64(nil, uint64(i)))
+		}
+		c.uid = uid_t{base: h.Sum64()}
+		key_uids(c, c.uid.base)
+	}
+}
+
+func (ctx *Context) declare(n *Node) {
+	if n.rect.Empty() {
+		return
+	}
+	ctx.Push(n.rect.Min.X, n.rect.Min.Y, n.rect.Dx(), n.rect.Dy(), nil)
+	switch n.Kind {
+	case KindLabel:
+		ctx.Label(n.Text, 0, 0.5)
+	case KindButton:
+		ctx.button(ctx.keyed_uid(n.uid), ButtonArgs{
+			Text:     n.Text,
+			AlignX:   0.5,
+			AlignY:   0.5,
+			Selected: n.Selected,
+			Behavior: ButtonBehavior{OnActivate: n.OnActivate},
+		})
+	case KindPanel:
+		ctx.Panel()
+	case KindImmediate:
+		if n.Build != nil {
+			n.Build()
+		}
+	}
+	ctx.Pop()
+	for _, c := range n.Children {
+		ctx.declare(c)
+	}
+}
@@ -0,0 +1,433 @@
+// Package ui is an immediate mode UI experiment. Widgets are declared every frame by calling methods on a
+// Context, and the input for every widget is resolved at the end of the frame in draw order so the top-most
+// widget under the cursor wins.
+package ui
+
+import (
+	"image"
+	"runtime"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/input"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/logging"
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/pool"
+)
+
+var logger = logging.Tag("ui")
+
+var current_frame int
+
+// field_focused is set by text fields focused this frame, and field_typing when one was last frame, which keeps
+// the keyboard theirs, see input.Type
+var field_focused, field_typing bool
+
+// we need input state synchronized with the frame due to checking inputs at the end of a frame
+var (
+	input_mu       sync.Mutex
+	mouse_pressed  = make(map[ebiten.MouseButton]int)
+	mouse_released = make(map[ebiten.MouseButton]int)
+)
+
+// Update records the mouse input of the current tick so that it can be consumed by the next frame, and what's
+// typed into the text field focused, which has the keyboard to itself. It is called by the app framework every tick,
+// even while paused.
+func Update() {
+	input_mu.Lock()
+	defer input_mu.Unlock()
+
+	if field_typing {
+		input.Type()
+	}
+
+	if input.MouseJustPressed(ebiten.MouseButtonLeft) {
+		mouse_pressed[ebiten.MouseButtonLeft] = current_frame
+	}
+	if input.MouseJustReleased(ebiten.MouseButtonLeft) {
+		mouse_released[ebiten.MouseButtonLeft] = current_frame
+	}
+	edits = poll_edits(edits)
+}
+
+// NextFrame advances the frame counter. It is called by the app framework once every context has ended its frame.
+func NextFrame() {
+	current_frame++
+
+	input_mu.Lock()
+	edits = edits[:0]
+	field_typing, field_focused = field_focused, false
+	input_mu.Unlock()
+}
+
+func mouse_just_pressed(button ebiten.MouseButton) bool {
+	input_mu.Lock()
+	defer input_mu.Unlock()
+	return mouse_pressed[button] == current_frame
+}
+
+func mouse_just_released(button ebiten.MouseButton) bool {
+	input_mu.Lock()
+	defer input_mu.Unlock()
+	return mouse_released[button] == current_frame
+}
+
+// uid_t is a unique identifier that should remain consistent between frames.
+type uid_t struct {
+	// base is typically derived from a program counter, however it can be any deterministic value that stays the same between frames
+	base uint64
+	// id is typically a value starting at 0 and incrementing for each time a `base` is reused.
+	id uint64
+}
+
+var uid_zero uid_t
+
+type Context struct {
+	// Theme is how the widgets look and sound, a copy of DefaultTheme to begin with.
+	Theme Theme
+
+	// layers tracks the clipping of the context. The last entry is always the "top" or "active" clipping area.
+	layers []*ebiten.Image
+
+	// layout affects the returned *ebiten.Image of ctx.Next()
+	layout Layout
+
+	// triggers is a mapping of uid->trigger for behaviors that can happen with a delay...
+	// like pressing a button, dragging away, and then releasing
+	triggers map[uid_t]trigger_t
+
+	// uid_base_occurences is a mapping of program counters (PC) to the number of occurences on the current frame.
+	// This map gets cleared at the end of each frame
+	uid_base_occurences map[uintptr]uint64
+
+	// uid_frame is a mapping of UIDs to the cycle
+	uid_frame map[uid_t]int
+
+	// frame_triggers is a per-frame tracker of triggers used for testing input against. This list should always be populated
+	// by draw-order to ensure the top level trigger is properly detected.
+	frame_triggers []trigger_t
+
+	// hover_uid is a global state for which uid is hovered.
+	hover_uid uid_t
+
+	// press_uid is the global state for which trigger is pressed. Pressed as in: mouse is currently down, not released.
+	press_uid uid_t
+
+	// origin is where the layer being drawn is on the screen, its widgets draw relative to it. recording is what
+	// they do besides drawing, nil outside of layers.
+	origin    image.Point
+	recording *layer_recording
+}
+
+func NewContext() *Context {
+	return &Context{
+		Theme:               DefaultTheme,
+		triggers:            make(map[uid_t]trigger_t),
+		uid_base_occurences: make(map[uintptr]uint64),
+		uid_frame:           make(map[uid_t]int),
+	}
+}
+
+// StartFrame resets and initializes the context with a destination image
+func (ctx *Context) StartFrame(dst *ebiten.Image) {
+	clear(ctx.layers)                        // we're using 'clear' to avoid holding onto references
+	ctx.layers = append(ctx.layers[:0], dst) //
+}
+
+// EndFrame performs cleanup on per-frame state and runs logic to perform button inputs
+func (ctx *Context) EndFrame() {
+	clear(ctx.uid_base_occurences)
+
+	var hovered_trigger trigger_t
+	var cursor_over_trigger bool
+
+	for _, trigger := range ctx.frame_triggers {
+		if CursorWithin(trigger.bounds) {
+			hovered_trigger = trigger
+			cursor_over_trigger = true
+		}
+	}
+	ctx.frame_triggers = ctx.frame_triggers[:0]
+
+	if next_uid := hovered_trigger.uid; next_uid != ctx.hover_uid {
+		var prev trigger_t
+
+		if ctx.hover_uid != uid_zero {
+			prev = ctx.triggers[ctx.hover_uid]
+		}
+
+		next, ok := ctx.triggers[next_uid]
+
+		if !ok {
+			ctx.triggers[next_uid] = hovered_trigger
+			next = hovered_trigger
+		}
+
+		cx, cy := input.CursorPosition()
+
+		logger.Debugf("hover %+v -> %+v", prev.uid, next.uid)
+
+		if on_exit := prev.OnExit; on_exit != nil {
+			on_exit(cx, cy)
+		}
+
+		if on_enter := next.OnEnter; on_enter != nil {
+			on_enter(cx, cy)
+		}
+
+		if next_uid != uid_zero {
+			play(ctx.Theme.Sounds.Hover)
+		}
+
+		ctx.hover_uid = next_uid
+	}
+
+	if cursor_over_trigger {
+		trigger := ctx.triggers[hovered_trigger.uid]
+
+		if mouse_just_pressed(ebiten.MouseButtonLeft) {
+			if on_press := trigger.OnPress; on_press != nil {
+				on_press(ebiten.MouseButtonLeft)
+			}
+			play(ctx.Theme.Sounds.Press)
+
+			if trigger.Mode == ActivateOnClick {
+				if on_activate := trigger.OnActivate; on_activate != nil {
+					on_activate()
+				}
+				play(ctx.Theme.Sounds.Activate)
+			}
+
+			ctx.press_uid = hovered_trigger.uid
+		}
+	}
+
+	if mouse_just_released(ebiten.MouseButtonLeft) {
+		if trigger := ctx.triggers[ctx.press_uid]; trigger.uid != uid_zero {
+			if on_release := trigger.OnRelease; on_release != nil {
+				on_release(ebiten.MouseButtonLeft)
+			}
+
+			if trigger.Mode == ActivateOnRelease ||
+				trigger.Mode == ActivateOnClickRelease && CursorWithin(trigger.bounds) {
+				if on_activate := trigger.OnActivate; on_activate != nil {
+					on_activate()
+				}
+				play(ctx.Theme.Sounds.Activate)
+			}
+			ctx.press_uid = uid_zero
+		}
+	}
+
+	ctx.gc()
+}
+
+// stale_uid_frames is how many frames need to elapse before a uid is considered 'stale'
+const stale_uid_frames = 5
+
+// gc performs garbage collection on this ui context. This may not be entirely necessary
+// since the size of a trigger in memory is barely anything at all. However, if there were
+// hundreds of thousands then this might make a difference in memory usage over time.
+//
+// This will become more important when larger state is retained.
+func (ctx *Context) gc() {
+	stale_uids := stale_uid_slices.Get()
+	defer stale_uid_slices.Put(stale_uids)
+	for uid, frame := range ctx.uid_frame {
+		if current_frame-frame >= stale_uid_frames {
+			*stale_uids = append(*stale_uids, uid)
+		}
+	}
+	// delete references to the uid
+	for _, uid := range *stale_uids {
+		delete(ctx.uid_frame, uid)
+		delete(ctx.triggers, uid)
+	}
+}
+
+// stale_uid_slices are the lists of stale uids of gc, which runs every frame of every context.
+var stale_uid_slices = pool.Slices[uid_t](16)
+
+// Hovered reports whether the cursor is over any widget of this context. Demos use it to avoid
+// reacting to clicks that were meant for the UI.
+func (ctx *Context) Hovered() bool {
+	return ctx.hover_uid != uid_zero
+}
+
+func (ctx *Context) SetLayout(layout Layout) {
+	ctx.layout = layout
+}
+
+// Push pushes a subimage of the current image onto the layer stack, effectively making it our new working area.
+func (ctx *Context) Push(x, y, w, h int, layout Layout) {
+	if len(ctx.layers) == 0 {
+		panic("ui context not initialized")
+	}
+	top := ctx.layers[len(ctx.layers)-1]
+	ctx.layers = append(ctx.layers, top.SubImage(image.Rect(x, y, x+w, y+h)).(*ebiten.Image))
+	ctx.layout = layout
+}
+
+// push_trigger pushes a per-frame trigger for input for testing at the end of the current frame.
+func (ctx *Context) push_trigger(uid uid_t, bounds image.Rectangle, behavior ButtonBehavior) {
+	t := trigger_t{
+		ButtonBehavior: behavior,
+		uid:            uid,
+		bounds:         bounds,
+	}
+	if ctx.recording != nil {
+		ctx.recording.triggers = append(ctx.recording.triggers, t)
+	}
+	t.bounds = bounds.Add(ctx.origin)
+	ctx.frame_triggers = append(ctx.frame_triggers, t)
+}
+
+// cursor is the position of the cursor relative to the layer being drawn.
+func (ctx *Context) cursor() image.Point {
+	return image.Pt(input.CursorPosition()).Sub(ctx.origin)
+}
+
+// Pop pops the top subimage off the layer stack.
+func (ctx *Context) Pop() {
+	if len(ctx.layers) > 0 {
+		ctx.layers[len(ctx.layers)-1] = nil
+		ctx.layers = ctx.layers[:len(ctx.layers)-1]
+	}
+	ctx.layout = nil
+}
+
+// Next returns the working area of our context. If `ctx.layout` is not `nil`, then the next image will be
+// determined by that layout. Because this always works in the context of a subimage, a layout can never
+// escape the bounds it begins in.
+func (ctx *Context) Next() *ebiten.Image {
+	if len(ctx.layers) == 0 {
+		panic("ui context not initialized")
+	}
+
+	top := ctx.layers[len(ctx.layers)-1]
+
+	if l := ctx.layout; l != nil {
+		if bounds := l.Layout(top.Bounds()); !bounds.Empty() {
+			return top.SubImage(bounds).(*ebiten.Image)
+		}
+	}
+
+	return top
+}
+
+type trigger_t struct {
+	uid    uid_t
+	bounds image.Rectangle
+	ButtonBehavior
+}
+
+type ButtonMode int
+
+const (
+	ActivateOnClickRelease ButtonMode = iota
+	ActivateOnClick
+	ActivateOnRelease
+)
+
+type ButtonBehavior struct {
+	Mode       ButtonMode
+	OnEnter    func(x, y int)
+	OnPress    func(btn ebiten.MouseButton)
+	OnExit     func(x, y int)
+	OnActivate func()
+	OnRelease  func(btn ebiten.MouseButton)
+}
+
+type ButtonArgs struct {
+	Text string
+	// AlignX and AlignY position the text within the button, see DrawString.
+	AlignX, AlignY float32
+	// Selected draws the button as if it were held down, for toggles and list selections.
+	Selected bool
+	Behavior ButtonBehavior
+}
+
+func (ctx *Context) uid(skip int) (uid uid_t) {
+	var pcs [1]uintptr
+	runtime.Callers(2+skip, pcs[:])
+	pc := pcs[0]
+
+	uid = uid_t{
+		base: uint64(pc),
+		id:   ctx.uid_base_occurences[pc],
+	}
+
+	ctx.uid_frame[uid] = current_frame
+	ctx.uid_base_occurences[pc]++
+	if ctx.recording != nil {
+		ctx.recording.record(pc, uid)
+	}
+	return
+}
+
+// keyed_uid takes a uid which isn't derived from where it's taken, like the uids of a tree's widgets.
+func (ctx *Context) keyed_uid(uid uid_t) uid_t {
+	ctx.uid_frame[uid] = current_frame
+	if ctx.recording != nil {
+		ctx.recording.record(0, uid)
+	}
+	return uid
+}
+
+func (ctx *Context) Button(args ButtonArgs) {
+	ctx.button(ctx.uid(1), args)
+}
+
+func (ctx *Context) button(uid uid_t, args ButtonArgs) {
+	dst := ctx.Next()
+
+	theme := &ctx.Theme
+	if ctx.press_uid == uid || args.Selected {
+		dst.Fill(theme.ButtonPressed)
+	} else if ctx.hover_uid == uid {
+		dst.Fill(theme.ButtonHovered)
+	} else {
+		dst.Fill(theme.Button)
+	}
+
+	DrawBorder(dst, 1, 1, theme.Border)
+	DrawBorder(dst, 0, 1, theme.Highlight)
+
+	if args.Text != "" {
+		DrawString(dst, args.Text, args.AlignX, args.AlignY)
+	}
+
+	ctx.push_trigger(uid, dst.Bounds(), args.Behavior)
+}
+
+// Label draws text into the next area of the layout.
+func (ctx *Context) Label(text string, align_x, align_y float32) {
+	DrawString(ctx.Next(), text, align_x, align_y)
+}
+
+// Panel fills the next area of the layout with a background.
+func (ctx *Context) Panel() {
+	dst := ctx.Next()
+	dst.Fill(ctx.Theme.Panel)
+	DrawBorder(dst, 0, 1, ctx.Theme.Border)
+}
+
+// Image draws `img` into the next area of the layout, scaled to fit and centered. Render targets shown this way,
+// like a minimap, stay crisp as long as the area is a whole multiple of their size.
+func (ctx *Context) Image(img *ebiten.Image) {
+	dst := ctx.Next()
+	area := dst.Bounds()
+	size := img.Bounds().Size()
+	if size.X == 0 || size.Y == 0 {
+		return
+	}
+	scale := min(float64(area.Dx())/float64(size.X), float64(area.Dy())/float64(size.Y))
+
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(scale, scale)
+	opts.GeoM.Translate(
+		float64(area.Min.X)+(float64(area.Dx())-float64(size.X)*scale)/2,
+		float64(area.Min.Y)+(float64(area.Dy())-float64(size.Y)*scale)/2,
+	)
+	dst.DrawImage(img, opts)
+}
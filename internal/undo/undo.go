@@ -0,0 +1,143 @@
+// Package undo is a history of commands which can be undone and redone, for the demos which edit things like a
+// tool would. Edits made a frame at a time, such as dragging a field, merge into one command until the drag ends.
+package undo
+
+import "reflect"
+
+// Command is an edit which can be taken back.
+type Command interface {
+	Do()
+	Undo()
+}
+
+// Merger is a command which can take in the command after it, so a drag undoes in one go rather than a frame at
+// a time. Merge reports whether it did, `next` is then dropped.
+type Merger interface {
+	Merge(next Command) bool
+}
+
+// Stack is a history of commands. The zero value is an empty history without a limit.
+type Stack struct {
+	// Limit is how many commands are kept, the oldest are forgotten past it. Zero keeps them all.
+	Limit int
+
+	done   []Command
+	undone []Command
+	// sealed keeps the next command from merging into the last one
+	sealed bool
+}
+
+// Do runs `c` and records it.
+func (s *Stack) Do(c Command) {
+	c.Do()
+	s.Push(c)
+}
+
+// Push records `c` without running it, for edits which were made already, like by a widget. It merges into the
+// last command when it can and the stack wasn't sealed since, and whatever was undone can't be redone anymore.
+func (s *Stack) Push(c Command) {
+	s.undone = s.undone[:0]
+	if n := len(s.done); n > 0 && !s.sealed {
+		if m, ok := s.done[n-1].(Merger); ok && m.Merge(c) {
+			return
+		}
+	}
+	s.done = append(s.done, c)
+	s.sealed = false
+	if s.Limit > 0 && len(s.done) > s.Limit {
+		s.done = append(s.done[:0], s.done[len(s.done)-s.Limit:]...)
+	}
+}
+
+// Seal ends the edit being made, the next command doesn't merge into the last one. Call it when a drag ends.
+func (s *Stack) Seal() {
+	s.sealed = true
+}
+
+// Undo undoes the last command and reports whether there was one.
+func (s *Stack) Undo() bool {
+	n := len(s.done)
+	if n == 0 {
+		return false
+	}
+	c := s.done[n-1]
+	s.done = s.done[:n-1]
+	c.Undo()
+	s.undone = append(s.undone, c)
+	s.sealed = true
+	return true
+}
+
+// Redo does the last command undone again and reports whether there was one.
+func (s *Stack) Redo() bool {
+	n := len(s.undone)
+	if n == 0 {
+		return false
+	}
+	c := s.undone[n-1]
+	s.undone = s.undone[:n-1]
+	c.Do()
+	s.done = append(s.done, c)
+	s.sealed = true
+	return true
+}
+
+func (s *Stack) CanUndo() bool { return len(s.done) > 0 }
+func (s *Stack) CanRedo() bool { return len(s.undone) > 0 }
+
+// Clear forgets the whole history.
+func (s *Stack) Clear() {
+	clear(s.done)
+	clear(s.undone)
+	s.done, s.undone = s.done[:0], s.undone[:0]
+}
+
+// Set sets Target from From to To, and merges with the Sets of the same target after it.
+type Set[T any] struct {
+	Target   *T
+	From, To T
+}
+
+func (s *Set[T]) Do()   { *s.Target = s.To }
+func (s *Set[T]) Undo() { *s.Target = s.From }
+
+func (s *Set[T]) Merge(next Command) bool {
+	n, ok := next.(*Set[T])
+	if !ok || n.Target != s.Target {
+		return false
+	}
+	s.To = n.To
+	return true
+}
+
+// Func is a command made of two functions.
+type Func struct {
+	DoFunc, UndoFunc func()
+}
+
+func (f Func) Do()   { f.DoFunc() }
+func (f Func) Undo() { f.UndoFunc() }
+
+// SetKey sets Key of Map from From to To, and merges with the SetKeys of the same key after it.
+type SetKey[K comparable, V any] struct {
+	Map      map[K]V
+	Key      K
+	From, To V
+}
+
+func (s *SetKey[K, V]) Do()   { s.Map[s.Key] = s.To }
+func (s *SetKey[K, V]) Undo() { s.Map[s.Key] = s.From }
+
+func (s *SetKey[K, V]) Merge(next Command) bool {
+	n, ok := next.(*SetKey[K, V])
+	if !ok || n.Key != s.Key || !same_map(n.Map, s.Map) {
+		return false
+	}
+	s.To = n.To
+	return true
+}
+
+// same_map reports whether `a` and `b` are the same map, not just equal ones.
+func same_map[K comparable, V any](a, b map[K]V) bool {
+	return reflect.ValueOf(a).UnsafePointer() == reflect.ValueOf(b).UnsafePointer()
+}
@@ -0,0 +1,79 @@
+package undo
+
+import "testing"
+
+func TestUndoRedo(t *testing.T) {
+	var s Stack
+	x := 0
+	s.Do(&Set[int]{Target: &x, From: 0, To: 1})
+	s.Seal()
+	s.Do(&Set[int]{Target: &x, From: 1, To: 2})
+	if x != 2 || !s.CanUndo() || s.CanRedo() {
+		t.Fatalf("after two sets x is %d", x)
+	}
+	s.Undo()
+	s.Undo()
+	if x != 0 || s.CanUndo() || s.Undo() {
+		t.Fatalf("undoing both left x at %d", x)
+	}
+	s.Redo()
+	if x != 1 {
+		t.Fatalf("redoing the first left x at %d", x)
+	}
+	// a new command drops what could be redone
+	s.Do(&Set[int]{Target: &x, From: 1, To: 5})
+	if s.CanRedo() || s.Redo() {
+		t.Fatal("the second set could still be redone after a new one")
+	}
+}
+
+func TestDragsMerge(t *testing.T) {
+	var s Stack
+	x, y := 0, 0
+	for i := 1; i <= 10; i++ {
+		x = i
+		s.Push(&Set[int]{Target: &x, From: i - 1, To: i})
+	}
+	// another target doesn't merge
+	s.Do(&Set[int]{Target: &y, From: 0, To: 3})
+	s.Undo()
+	s.Undo()
+	if x != 0 || y != 0 || s.CanUndo() {
+		t.Fatalf("a drag of ten frames took more than one undo, x is %d, y is %d", x, y)
+	}
+
+	// keys of a map merge like targets
+	m, other := map[string]int{}, map[string]int{}
+	s.Do(&SetKey[string, int]{Map: m, Key: "a", From: 0, To: 1})
+	s.Do(&SetKey[string, int]{Map: m, Key: "a", From: 1, To: 2})
+	s.Do(&SetKey[string, int]{Map: other, Key: "a", From: 0, To: 1})
+	s.Undo()
+	s.Undo()
+	if m["a"] != 0 || other["a"] != 0 || s.CanUndo() {
+		t.Fatalf("undoing two edits of a map left %v and %v", m, other)
+	}
+
+	s.Clear()
+	s.Do(&Set[int]{Target: &x, From: 0, To: 1})
+	s.Seal()
+	s.Do(&Set[int]{Target: &x, From: 1, To: 2})
+	s.Undo()
+	if x != 1 {
+		t.Fatalf("sealing didn't end the first drag, x is %d", x)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	s := Stack{Limit: 3}
+	x := 0
+	for i := 1; i <= 5; i++ {
+		s.Do(Func{DoFunc: func() { x = i }, UndoFunc: func() { x = i - 1 }})
+	}
+	undone := 0
+	for s.Undo() {
+		undone++
+	}
+	if undone != 3 || x != 2 {
+		t.Fatalf("undid %d commands to %d, want 3 to 2", undone, x)
+	}
+}
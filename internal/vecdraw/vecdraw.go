@@ -0,0 +1,68 @@
+// Package vecdraw draws the retained vector scenes of package vecscene onto ebiten images, a draw call for each batch
+// of triangles they build.
+package vecdraw
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/vecscene"
+)
+
+var white_image = func() *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.White)
+	// the center pixel, so sampling never bleeds into the transparent atlas around it
+	return img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+}()
+
+// Drawer draws scenes. The zero value is ready to use.
+type Drawer struct {
+	// AntiAlias smooths the edges of the triangles, at the cost of drawing them onto an offscreen image first.
+	AntiAlias bool
+	// DrawCalls is how many draw calls the last Draw made.
+	DrawCalls int
+
+	vertices []ebiten.Vertex
+}
+
+// Draw builds `s` placed by `geom` and draws it onto `dst`.
+func (d *Drawer) Draw(dst *ebiten.Image, s *vecscene.Scene, geom ebiten.GeoM) {
+	d.DrawCalls = 0
+	op := &ebiten.DrawTrianglesOptions{
+		ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha,
+		AntiAlias:      d.AntiAlias,
+	}
+	for _, b := range s.Build(Mat3(geom)) {
+		d.vertices = d.vertices[:0]
+		for _, v := range b.Vertices {
+			d.vertices = append(d.vertices, ebiten.Vertex{
+				DstX:   v.Position[0],
+				DstY:   v.Position[1],
+				SrcX:   1.5,
+				SrcY:   1.5,
+				ColorR: v.Color[0],
+				ColorG: v.Color[1],
+				ColorB: v.Color[2],
+				ColorA: v.Color[3],
+			})
+		}
+		dst.DrawTriangles(d.vertices, b.Indices, white_image, op)
+		d.DrawCalls++
+	}
+}
+
+// Mat3 is `geom` as the affine transform a scene is built with.
+func Mat3(geom ebiten.GeoM) mgl32.Mat3 {
+	a, b, c, d := geom.Element(0, 0), geom.Element(0, 1), geom.Element(1, 0), geom.Element(1, 1)
+	tx, ty := geom.Element(0, 2), geom.Element(1, 2)
+	// column major, x' = a x + b y + tx
+	return mgl32.Mat3{
+		float32(a), float32(c), 0,
+		float32(b), float32(d), 0,
+		float32(tx), float32(ty), 1,
+	}
+}
@@ -0,0 +1,321 @@
+// Package vecscene is retained 2D vector graphics: paths of lines and curves, filled and stroked, in a tree of nodes
+// with transforms, for HUD art and illustrations drawn every frame. Fills and strokes are cut into triangles once
+// and kept, and cut again only when the path, its fill rule or stroke style, or the scale it's drawn at changes, so
+// a frame of a scene which merely moves costs transforming points. Package vecdraw draws scenes with ebiten.
+//
+// Paths go through the same steps ebiten's vector package takes every frame: they're flattened into polylines within
+// a tolerance, then Fill triangulates the areas they enclose and Stroke outlines them with joins and caps.
+package vecscene
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type (
+	float = float32
+	vec2  = mgl32.Vec2
+	vec3  = mgl32.Vec3
+	vec4  = mgl32.Vec4
+	mat3  = mgl32.Mat3
+)
+
+// verb is what a step of a path does with its points.
+type verb uint8
+
+const (
+	move verb = iota
+	line
+	quad
+	cube
+	close_path
+)
+
+// kappa puts the control points of the cubic of a quarter circle, it's off by less than 0.03%
+const kappa = 0.5522847498
+
+// Path is subpaths of lines and curves. Changing it after it's drawn is noticed by the nodes drawing it, which
+// triangulate it again.
+type Path struct {
+	verbs  []verb
+	points []vec2
+	// version counts the changes, nodes compare it with the one they triangulated
+	version uint32
+	// at is where the path is, start where its subpath started
+	at, start vec2
+}
+
+func (p *Path) add(v verb, points ...vec2) {
+	p.verbs = append(p.verbs, v)
+	p.points = append(p.points, points...)
+	p.version++
+	switch {
+	case v == move:
+		p.at, p.start = points[0], points[0]
+	case v == close_path:
+		p.at = p.start
+	default:
+		p.at = points[len(points)-1]
+	}
+}
+
+// Current is where the path is: the end of what was added last, where its subpath started after a Close, or 0, 0
+// for an empty path.
+func (p *Path) Current() (x, y float) {
+	return p.at[0], p.at[1]
+}
+
+// MoveTo starts a new subpath at `x`, `y`.
+func (p *Path) MoveTo(x, y float) {
+	p.add(move, vec2{x, y})
+}
+
+// LineTo adds a line to `x`, `y`.
+func (p *Path) LineTo(x, y float) {
+	p.add(line, vec2{x, y})
+}
+
+// QuadTo adds a quadratic Bézier curve through the control point `cx`, `cy` to `x`, `y`.
+func (p *Path) QuadTo(cx, cy, x, y float) {
+	p.add(quad, vec2{cx, cy}, vec2{x, y})
+}
+
+// CubeTo adds a cubic Bézier curve through the control points `c1` and `c2` to `x`, `y`.
+func (p *Path) CubeTo(c1x, c1y, c2x, c2y, x, y float) {
+	p.add(cube, vec2{c1x, c1y}, vec2{c2x, c2y}, vec2{x, y})
+}
+
+// Close joins the subpath back to where it started, strokes join there rather than end.
+func (p *Path) Close() {
+	p.add(close_path)
+}
+
+// Reset empties the path, to build it again.
+func (p *Path) Reset() {
+	p.verbs, p.points = p.verbs[:0], p.points[:0]
+	p.at, p.start = vec2{}, vec2{}
+	p.version++
+}
+
+// Empty reports whether the path has nothing in it.
+func (p *Path) Empty() bool {
+	return len(p.verbs) == 0
+}
+
+// Rect adds the rectangle from `x`, `y`, `w` by `h`, as a closed subpath.
+func (p *Path) Rect(x, y, w, h float) {
+	p.MoveTo(x, y)
+	p.LineTo(x+w, y)
+	p.LineTo(x+w, y+h)
+	p.LineTo(x, y+h)
+	p.Close()
+}
+
+// RoundedRect adds a rectangle with its corners rounded `r` in, as a closed subpath.
+func (p *Path) RoundedRect(x, y, w, h, r float) {
+	r = min(r, w/2, h/2)
+	if r <= 0 {
+		p.Rect(x, y, w, h)
+		return
+	}
+	k := r * (1 - kappa)
+	p.MoveTo(x+r, y)
+	p.LineTo(x+w-r, y)
+	p.CubeTo(x+w-k, y, x+w, y+k, x+w, y+r)
+	p.LineTo(x+w, y+h-r)
+	p.CubeTo(x+w, y+h-k, x+w-k, y+h, x+w-r, y+h)
+	p.LineTo(x+r, y+h)
+	p.CubeTo(x+k, y+h, x, y+h-k, x, y+h-r)
+	p.LineTo(x, y+r)
+	p.CubeTo(x, y+k, x+k, y, x+r, y)
+	p.Close()
+}
+
+// Ellipse adds the ellipse around `cx`, `cy` with the radii `rx` and `ry`, as a closed subpath of four cubics.
+func (p *Path) Ellipse(cx, cy, rx, ry float) {
+	kx, ky := rx*kappa, ry*kappa
+	p.MoveTo(cx+rx, cy)
+	p.CubeTo(cx+rx, cy+ky, cx+kx, cy+ry, cx, cy+ry)
+	p.CubeTo(cx-kx, cy+ry, cx-rx, cy+ky, cx-rx, cy)
+	p.CubeTo(cx-rx, cy-ky, cx-kx, cy-ry, cx, cy-ry)
+	p.CubeTo(cx+kx, cy-ry, cx+rx, cy-ky, cx+rx, cy)
+	p.Close()
+}
+
+// Circle adds the circle around `cx`, `cy` of radius `r`.
+func (p *Path) Circle(cx, cy, r float) {
+	p.Ellipse(cx, cy, r, r)
+}
+
+// Arc adds an arc of the circle around `cx`, `cy` of radius `r` from the angle `from` to `to`, in radians, as
+// cubics of at most a quarter turn. It's joined by a line from where the path was, or starts it.
+func (p *Path) Arc(cx, cy, r, from, to float) {
+	at := vec2{cx + r*cos(from), cy + r*sin(from)}
+	if len(p.verbs) == 0 || p.verbs[len(p.verbs)-1] == close_path {
+		p.MoveTo(at[0], at[1])
+	} else {
+		p.LineTo(at[0], at[1])
+	}
+	n := max(int(math.Ceil(math.Abs(float64(to-from))/(math.Pi/2)-1e-4)), 1)
+	step := (to - from) / float(n)
+	// the control points are along the tangents, 4/3 tan(θ/4) of the radius away
+	k := r * 4 / 3 * float(math.Tan(float64(step)/4))
+	for i := range n {
+		a, b := from+step*float(i), from+step*float(i+1)
+		p.CubeTo(
+			cx+r*cos(a)-k*sin(a), cy+r*sin(a)+k*cos(a),
+			cx+r*cos(b)+k*sin(b), cy+r*sin(b)-k*cos(b),
+			cx+r*cos(b), cy+r*sin(b),
+		)
+	}
+}
+
+// ArcTo adds an arc of an ellipse to `x`, `y` the way SVG's path data does: the ellipse has the radii `rx` and `ry`
+// and is turned by `rotation` radians, and of the four arcs between the points on two such ellipses `large` picks
+// one of more than half a turn and `sweep` one going the way angles grow. Radii too small to reach are scaled up
+// until they do, a zero one makes a line.
+func (p *Path) ArcTo(rx, ry, rotation float, large, sweep bool, x, y float) {
+	from, to := p.at, vec2{x, y}
+	if from == to {
+		return
+	}
+	rx, ry = abs(rx), abs(ry)
+	if rx == 0 || ry == 0 {
+		p.LineTo(x, y)
+		return
+	}
+	// the center, as in the implementation notes of the SVG specification, worked out in float64 since it
+	// subtracts nearly equal numbers for arcs of nearly half a turn
+	c, s := math.Cos(float64(rotation)), math.Sin(float64(rotation))
+	dx, dy := float64(from[0]-to[0])/2, float64(from[1]-to[1])/2
+	x1, y1 := c*dx+s*dy, -s*dx+c*dy
+	rx2, ry2 := float64(rx)*float64(rx), float64(ry)*float64(ry)
+	if l := x1*x1/rx2 + y1*y1/ry2; l > 1 {
+		rx2, ry2 = rx2*l, ry2*l
+	}
+	f := math.Sqrt(max(rx2*ry2-rx2*y1*y1-ry2*x1*x1, 0) / (rx2*y1*y1 + ry2*x1*x1))
+	if large == sweep {
+		f = -f
+	}
+	rxf, ryf := math.Sqrt(rx2), math.Sqrt(ry2)
+	cx1, cy1 := f*rxf*y1/ryf, -f*ryf*x1/rxf
+	cx := c*cx1 - s*cy1 + float64(from[0]+to[0])/2
+	cy := s*cx1 + c*cy1 + float64(from[1]+to[1])/2
+	angle := func(ux, uy float64) float64 {
+		return math.Atan2(uy, ux)
+	}
+	theta := angle((x1-cx1)/rxf, (y1-cy1)/ryf)
+	delta := angle((-x1-cx1)/rxf, (-y1-cy1)/ryf) - theta
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	// cubics of at most a quarter turn of the ellipse, worked out on the unit circle and then stretched and turned
+	n := max(int(math.Ceil(math.Abs(delta)/(math.Pi/2)-1e-4)), 1)
+	step := delta / float64(n)
+	k := 4.0 / 3 * math.Tan(step/4)
+	point := func(ux, uy float64) (float, float) {
+		ux, uy = ux*rxf, uy*ryf
+		return float(c*ux - s*uy + cx), float(s*ux + c*uy + cy)
+	}
+	for i := range n {
+		a, b := theta+step*float64(i), theta+step*float64(i+1)
+		c1x, c1y := point(math.Cos(a)-k*math.Sin(a), math.Sin(a)+k*math.Cos(a))
+		c2x, c2y := point(math.Cos(b)+k*math.Sin(b), math.Sin(b)-k*math.Cos(b))
+		ex, ey := point(math.Cos(b), math.Sin(b))
+		if i == n-1 {
+			// the arc ends exactly where it was asked to
+			ex, ey = x, y
+		}
+		p.CubeTo(c1x, c1y, c2x, c2y, ex, ey)
+	}
+}
+
+// Polyline is a path flattened, the points of a subpath joined by straight lines.
+type Polyline struct {
+	Points []vec2
+	Closed bool
+}
+
+// Flatten cuts the curves of the path into lines which stray from them by at most `tolerance`, appending a polyline
+// for each subpath to `dst`.
+func (p *Path) Flatten(tolerance float, dst []Polyline) []Polyline {
+	tolerance = max(tolerance, 1e-4)
+	// steps is how many lines a curve is cut into to stay within tolerance, `bend` is the most its second derivative
+	// reaches: a line along t in 1/n steps strays from it by at most bend/8n²
+	steps := func(bend float) int {
+		return min(max(int(math.Ceil(math.Sqrt(float64(bend/(8*tolerance))))), 1), 256)
+	}
+
+	var current *Polyline
+	var start vec2
+	last := func() vec2 {
+		return current.Points[len(current.Points)-1]
+	}
+	begin := func(at vec2) {
+		dst = append(dst, Polyline{Points: []vec2{at}})
+		current = &dst[len(dst)-1]
+		start = at
+	}
+	// open makes sure a subpath is open to add to: one without a move starts at `at`, and one after a closed one
+	// where that started
+	open := func(at vec2) {
+		switch {
+		case current == nil:
+			begin(at)
+		case current.Closed:
+			begin(start)
+		}
+	}
+	add := func(at vec2) {
+		if last() != at {
+			current.Points = append(current.Points, at)
+		}
+	}
+
+	i := 0
+	for _, v := range p.verbs {
+		switch v {
+		case move:
+			begin(p.points[i])
+			i++
+		case line:
+			open(p.points[i])
+			add(p.points[i])
+			i++
+		case quad:
+			open(p.points[i])
+			from, c, to := last(), p.points[i], p.points[i+1]
+			n := steps(2 * from.Sub(c.Mul(2)).Add(to).Len())
+			for s := 1; s <= n; s++ {
+				t := float(s) / float(n)
+				add(from.Mul((1 - t) * (1 - t)).Add(c.Mul(2 * (1 - t) * t)).Add(to.Mul(t * t)))
+			}
+			i += 2
+		case cube:
+			open(p.points[i])
+			from, c1, c2, to := last(), p.points[i], p.points[i+1], p.points[i+2]
+			n := steps(6 * max(from.Sub(c1.Mul(2)).Add(c2).Len(), c1.Sub(c2.Mul(2)).Add(to).Len()))
+			for s := 1; s <= n; s++ {
+				t := float(s) / float(n)
+				u := 1 - t
+				add(from.Mul(u * u * u).Add(c1.Mul(3 * u * u * t)).Add(c2.Mul(3 * u * t * t)).Add(to.Mul(t * t * t)))
+			}
+			i += 3
+		case close_path:
+			if current != nil && !current.Closed {
+				if len(current.Points) > 1 && last() == current.Points[0] {
+					current.Points = current.Points[:len(current.Points)-1]
+				}
+				current.Closed = true
+			}
+		}
+	}
+	return dst
+}
+
+func cos(a float) float { return float(math.Cos(float64(a))) }
+func sin(a float) float { return float(math.Sin(float64(a))) }
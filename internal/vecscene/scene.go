@@ -0,0 +1,232 @@
+package vecscene
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// FillPaint fills a node's path with a color.
+type FillPaint struct {
+	// Color is premultiplied.
+	Color vec4
+	Rule  FillRule
+}
+
+// StrokePaint outlines a node's path with a color.
+type StrokePaint struct {
+	// Color is premultiplied.
+	Color vec4
+	StrokeStyle
+}
+
+// Node is a part of a scene: a path filled or stroked or both, and children drawn after it, all placed by its
+// transform within its parent. Nodes are made by NewNode, a zero transform or opacity hides them.
+type Node struct {
+	Name string
+	// Transform is the affine transform from the node to its parent.
+	Transform mat3
+	// Hidden nodes aren't drawn, nor are their children.
+	Hidden bool
+	// Opacity fades the node and its children, 1 is opaque.
+	Opacity float
+
+	Path     *Path
+	Fill     *FillPaint
+	Stroke   *StrokePaint
+	Children []*Node
+
+	cache cache
+}
+
+// cache is the triangles of a node's path and what they were made of, kept until that changes.
+type cache struct {
+	path    *Path
+	version uint32
+	// level is the power of two of the tolerance the curves were flattened to
+	level           int
+	rule            FillRule
+	style           StrokeStyle
+	filled, stroked bool
+	lines           []Polyline
+	fill            Triangles
+	stroke          Triangles
+	valid           bool
+}
+
+// NewNode makes a node with `path`, an identity transform and full opacity.
+func NewNode(name string, path *Path) *Node {
+	return &Node{Name: name, Transform: mgl32.Ident3(), Opacity: 1, Path: path}
+}
+
+// Add adds `children` to the node, drawn after those it has, and returns the node.
+func (n *Node) Add(children ...*Node) *Node {
+	n.Children = append(n.Children, children...)
+	return n
+}
+
+// Find is the first node named `name` under the node, the node itself included, or nil.
+func (n *Node) Find(name string) *Node {
+	if n.Name == name {
+		return n
+	}
+	for _, c := range n.Children {
+		if found := c.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Invalidate throws the node's triangles away so they're made again the next time it's drawn.
+func (n *Node) Invalidate() {
+	n.cache.valid = false
+}
+
+// Scene is a tree of nodes, and the triangles of what they draw from the last Build.
+type Scene struct {
+	Root *Node
+	// Tolerance is how far the lines of flattened curves may stray from them, in pixels of what the scene is drawn
+	// onto. 0 is 0.25.
+	Tolerance float
+	// Stats are of the last Build.
+	Stats Stats
+
+	vertices []Vertex
+	indices  []uint16
+	batches  []Batch
+	points   []vec2
+	// vertex_from and index_from are where the batch being filled starts
+	vertex_from, index_from int
+}
+
+// Stats count what Build did.
+type Stats struct {
+	// Nodes were drawn, Tessellated of them cut their paths into triangles again and Cached reused them.
+	Nodes, Tessellated, Cached int
+	Triangles                  int
+}
+
+// Vertex is a corner of a triangle of a scene, where it's drawn and its premultiplied color.
+type Vertex struct {
+	Position vec2
+	Color    vec4
+}
+
+// Batch is triangles of a scene few enough for 16 bit indices.
+type Batch struct {
+	Vertices []Vertex
+	Indices  []uint16
+}
+
+// New makes a scene with an empty root.
+func New() *Scene {
+	return &Scene{Root: NewNode("", nil)}
+}
+
+// Build transforms the triangles of every visible node by `m` and the transforms of the nodes above it, colored by
+// their paints, and returns them in the order they're drawn: a node's fill, its stroke, then its children. Nodes
+// whose paths, fill rules and stroke styles are as they were, drawn at about the same scale, reuse the triangles
+// they were cut into. The batches are the scene's until the next Build.
+func (s *Scene) Build(m mat3) []Batch {
+	s.Stats = Stats{}
+	s.vertices, s.indices, s.batches = s.vertices[:0], s.indices[:0], s.batches[:0]
+	s.vertex_from, s.index_from = 0, 0
+	tolerance := s.Tolerance
+	if tolerance <= 0 {
+		tolerance = 0.25
+	}
+	if s.Root != nil {
+		s.build(s.Root, m, 1, tolerance)
+	}
+	s.flush()
+	return s.batches
+}
+
+// flush ends the batch of the triangles added since the last.
+func (s *Scene) flush() {
+	if len(s.indices) > s.index_from {
+		s.batches = append(s.batches, Batch{Vertices: s.vertices[s.vertex_from:], Indices: s.indices[s.index_from:]})
+	}
+	s.vertex_from, s.index_from = len(s.vertices), len(s.indices)
+}
+
+func (s *Scene) build(n *Node, parent mat3, opacity, tolerance float) {
+	if n.Hidden {
+		return
+	}
+	m := parent.Mul3(n.Transform)
+	opacity *= n.Opacity
+	s.Stats.Nodes++
+
+	if n.Path != nil && (n.Fill != nil || n.Stroke != nil) {
+		// curves are flattened finely enough for the scale they're drawn at, in steps of powers of two so a node
+		// which scales smoothly is only cut again every time it doubles or halves
+		scale := max(vec2{m[0], m[1]}.Len(), vec2{m[3], m[4]}.Len(), 1e-6)
+		level := int(math.Floor(math.Log2(float64(tolerance / scale))))
+		if n.tessellate(level) {
+			s.Stats.Tessellated++
+		} else {
+			s.Stats.Cached++
+		}
+	}
+	if n.Fill != nil && n.cache.valid {
+		s.add(&n.cache.fill, &m, n.Fill.Color.Mul(opacity))
+	}
+	if n.Stroke != nil && n.cache.valid {
+		s.add(&n.cache.stroke, &m, n.Stroke.Color.Mul(opacity))
+	}
+	for _, c := range n.Children {
+		s.build(c, m, opacity, tolerance)
+	}
+}
+
+// add adds triangles through `m` in `color`, in a batch of its own when the one being filled can't index them.
+func (s *Scene) add(t *Triangles, m *mat3, color vec4) {
+	if len(t.Indices) == 0 || color[3] <= 0 {
+		return
+	}
+	if len(s.vertices)-s.vertex_from+len(t.Points) > math.MaxUint16+1 {
+		s.flush()
+	}
+	base := uint16(len(s.vertices) - s.vertex_from)
+	s.points = transformed(m, t.Points, s.points[:0])
+	for _, p := range s.points {
+		s.vertices = append(s.vertices, Vertex{Position: p, Color: color})
+	}
+	for _, i := range t.Indices {
+		s.indices = append(s.indices, base+i)
+	}
+	s.Stats.Triangles += len(t.Indices) / 3
+}
+
+// tessellate cuts the node's path into triangles, unless they're as they'd come out, and reports whether it did.
+func (n *Node) tessellate(level int) bool {
+	c := &n.cache
+	var style StrokeStyle
+	if n.Stroke != nil {
+		style = n.Stroke.StrokeStyle
+	}
+	var rule FillRule
+	if n.Fill != nil {
+		rule = n.Fill.Rule
+	}
+	if c.valid && c.path == n.Path && c.version == n.Path.version && c.level == level &&
+		c.filled == (n.Fill != nil) && c.stroked == (n.Stroke != nil) && c.rule == rule && c.style == style {
+		return false
+	}
+
+	tolerance := float(math.Ldexp(1, level))
+	c.lines = n.Path.Flatten(tolerance, c.lines[:0])
+	c.fill.Reset()
+	c.stroke.Reset()
+	if n.Fill != nil {
+		Fill(c.lines, rule, &c.fill)
+	}
+	if n.Stroke != nil {
+		Stroke(c.lines, &style, tolerance, &c.stroke)
+	}
+	c.path, c.version, c.level, c.rule, c.style = n.Path, n.Path.version, level, rule, style
+	c.filled, c.stroked, c.valid = n.Fill != nil, n.Stroke != nil, true
+	return true
+}
@@ -0,0 +1,492 @@
+package vecscene
+
+import (
+	"math"
+	"slices"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+// Triangles are a path cut into triangles, indexing its points.
+type Triangles struct {
+	Points  []vec2
+	Indices []uint16
+}
+
+// Reset empties the triangles, keeping their memory.
+func (t *Triangles) Reset() {
+	t.Points, t.Indices = t.Points[:0], t.Indices[:0]
+}
+
+// fits reports whether `n` more points can be indexed.
+func (t *Triangles) fits(n int) bool {
+	return len(t.Points)+n <= math.MaxUint16+1
+}
+
+func (t *Triangles) triangle(a, b, c vec2) {
+	if !t.fits(3) {
+		return
+	}
+	base := uint16(len(t.Points))
+	t.Points = append(t.Points, a, b, c)
+	t.Indices = append(t.Indices, base, base+1, base+2)
+}
+
+func (t *Triangles) quad(a, b, c, d vec2) {
+	if !t.fits(4) {
+		return
+	}
+	base := uint16(len(t.Points))
+	t.Points = append(t.Points, a, b, c, d)
+	t.Indices = append(t.Indices, base, base+1, base+2, base+1, base+3, base+2)
+}
+
+// FillRule is which of the areas a path encloses are filled, by how many times it winds around them.
+type FillRule uint8
+
+const (
+	// NonZero fills what the path winds around at all, counting clockwise against counter-clockwise.
+	NonZero FillRule = iota
+	// EvenOdd fills what the path goes around an odd number of times, so a ring inside another cuts a hole
+	// whichever way round it goes.
+	EvenOdd
+)
+
+func (r FillRule) filled(winding int) bool {
+	if r == EvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// Fill appends the triangles of the area `lines` enclose by `rule` to `dst`, every polyline closed. Rings which
+// only nest are triangulated as outlines with holes, rings which cross or touch themselves or each other are cut
+// into trapezoids, more of them.
+func Fill(lines []Polyline, rule FillRule, dst *Triangles) {
+	var rings [][]vec2
+	for _, l := range lines {
+		// a ring crossing itself can wind both ways and add up to no area, it's only flat ones which enclose nothing
+		if len(l.Points) >= 3 && !flat(l.Points) {
+			rings = append(rings, l.Points)
+		}
+	}
+	if len(rings) == 0 {
+		return
+	}
+	if ys := crossings(rings); len(ys) > 0 {
+		sweep(rings, ys, rule, dst)
+		return
+	}
+
+	// how many times the path winds around the inside and the outside of each ring, which tells whether the ring
+	// bounds what's filled from the inside, an outline, from the outside, a hole, or nothing
+	inside := make([][]int, len(rings))
+	outlines := make([]bool, len(rings))
+	bounds := make([]bool, len(rings))
+	for i, r := range rings {
+		out := 0
+		for j, other := range rings {
+			if i != j && contains(other, r[0]) {
+				inside[i] = append(inside[i], j)
+				out += direction(other)
+			}
+		}
+		in := out + direction(r)
+		bounds[i] = rule.filled(in) != rule.filled(out)
+		outlines[i] = rule.filled(in)
+	}
+
+	var shapes []mesh.Shape
+	index := make(map[int]int)
+	for i, r := range rings {
+		if bounds[i] && outlines[i] {
+			index[i] = len(shapes)
+			shapes = append(shapes, mesh.Shape{Outline: r})
+		}
+	}
+	for i, r := range rings {
+		if !bounds[i] || outlines[i] {
+			continue
+		}
+		// the hole is in the smallest outline around it
+		parent := -1
+		for _, j := range inside[i] {
+			if _, ok := index[j]; ok && (parent < 0 || abs(area(rings[j])) < abs(area(rings[parent]))) {
+				parent = j
+			}
+		}
+		if parent >= 0 {
+			s := &shapes[index[parent]]
+			s.Holes = append(s.Holes, r)
+		}
+	}
+
+	for _, s := range shapes {
+		points := s.Points()
+		if !dst.fits(len(points)) {
+			return
+		}
+		base := len(dst.Points)
+		dst.Points = append(dst.Points, points...)
+		for _, t := range s.Triangulate() {
+			dst.Indices = append(dst.Indices, uint16(base+t[0]), uint16(base+t[1]), uint16(base+t[2]))
+		}
+	}
+}
+
+// flat reports whether the points of a ring all lie on one line, or in one place.
+func flat(ring []vec2) bool {
+	o := ring[0]
+	var d vec2
+	for _, p := range ring[1:] {
+		v := p.Sub(o)
+		if d == (vec2{}) {
+			d = v
+			continue
+		}
+		// the cross product is weighed against the lengths, so it means the same at every scale
+		if abs(d[0]*v[1]-d[1]*v[0]) > 1e-6*d.Len()*v.Len() {
+			return false
+		}
+	}
+	return true
+}
+
+// crossings are the heights where edges of the rings cross or touch, none when they only nest. Rings which touch
+// can't be told apart as nested by one of their points, which may be on the other.
+func crossings(rings [][]vec2) []float {
+	type box struct{ lo, hi vec2 }
+	boxes := make([]box, len(rings))
+	for i, r := range rings {
+		b := box{r[0], r[0]}
+		for _, p := range r {
+			b.lo, b.hi = vec2{min(b.lo[0], p[0]), min(b.lo[1], p[1])}, vec2{max(b.hi[0], p[0]), max(b.hi[1], p[1])}
+		}
+		boxes[i] = b
+	}
+	var ys []float
+	for i, r := range rings {
+		for j := i; j < len(rings); j++ {
+			if boxes[i].hi[0] < boxes[j].lo[0] || boxes[j].hi[0] < boxes[i].lo[0] ||
+				boxes[i].hi[1] < boxes[j].lo[1] || boxes[j].hi[1] < boxes[i].lo[1] {
+				continue
+			}
+			other := rings[j]
+			for k := range r {
+				a, b := r[k], r[(k+1)%len(r)]
+				if a == b {
+					continue
+				}
+				from := 0
+				if i == j {
+					// the edges next to one another in a ring share a point, which isn't a touch
+					from = k + 2
+				}
+				for l := from; l < len(other); l++ {
+					if i == j && k == 0 && l == len(r)-1 {
+						break
+					}
+					c, d := other[l], other[(l+1)%len(other)]
+					if y, ok := cross(a, b, c, d); ok {
+						ys = append(ys, y)
+					}
+				}
+			}
+		}
+	}
+	return ys
+}
+
+// cross is the height where the segments `a` `b` and `c` `d` cross or touch, if they do. Where they touch an end of
+// one is on the other.
+func cross(a, b, c, d vec2) (float, bool) {
+	side := func(p, q, r vec2) float {
+		return (q[0]-p[0])*(r[1]-p[1]) - (q[1]-p[1])*(r[0]-p[0])
+	}
+	// on reports whether `p`, on the line through `q` and `r`, is between them
+	on := func(p, q, r vec2) bool {
+		return min(q[0], r[0]) <= p[0] && p[0] <= max(q[0], r[0]) && min(q[1], r[1]) <= p[1] && p[1] <= max(q[1], r[1])
+	}
+	s1, s2 := side(a, b, c), side(a, b, d)
+	s3, s4 := side(c, d, a), side(c, d, b)
+	switch {
+	case s1*s2 < 0 && s3*s4 < 0:
+		t := s3 / (s3 - s4)
+		return a[1] + (b[1]-a[1])*t, true
+	case s1 == 0 && on(c, a, b):
+		return c[1], true
+	case s2 == 0 && on(d, a, b):
+		return d[1], true
+	case s3 == 0 && on(a, c, d):
+		return a[1], true
+	case s4 == 0 && on(b, c, d):
+		return b[1], true
+	}
+	return 0, false
+}
+
+// sweep appends the triangles of the area the rings enclose by `rule`, cut into slabs between every height a point
+// of them or a crossing `ys` is at. No edges cross within a slab, so its filled spans are trapezoids between the
+// edges where the winding, counted from the left, starts and stops filling.
+func sweep(rings [][]vec2, ys []float, rule FillRule, dst *Triangles) {
+	type edge struct {
+		top, bottom vec2
+		winding     int
+	}
+	var edges []edge
+	for _, r := range rings {
+		for i, a := range r {
+			b := r[(i+1)%len(r)]
+			ys = append(ys, a[1])
+			switch {
+			case a[1] < b[1]:
+				edges = append(edges, edge{a, b, 1})
+			case a[1] > b[1]:
+				edges = append(edges, edge{b, a, -1})
+			}
+		}
+	}
+	slices.Sort(ys)
+	ys = slices.Compact(ys)
+
+	x_at := func(e *edge, y float) float {
+		t := (y - e.top[1]) / (e.bottom[1] - e.top[1])
+		return e.top[0] + (e.bottom[0]-e.top[0])*min(max(t, 0), 1)
+	}
+	type span struct {
+		x float
+		e int
+	}
+	var active []span
+	for i := 1; i < len(ys); i++ {
+		y0, y1 := ys[i-1], ys[i]
+		if y1-y0 < 1e-6 {
+			continue
+		}
+		mid := (y0 + y1) / 2
+		active = active[:0]
+		for j := range edges {
+			if e := &edges[j]; e.top[1] < mid && mid < e.bottom[1] {
+				active = append(active, span{x_at(e, mid), j})
+			}
+		}
+		slices.SortFunc(active, func(a, b span) int {
+			switch {
+			case a.x < b.x:
+				return -1
+			case a.x > b.x:
+				return 1
+			}
+			return 0
+		})
+		winding := 0
+		for j, s := range active {
+			was := rule.filled(winding)
+			winding += edges[s.e].winding
+			if !was && rule.filled(winding) && j+1 < len(active) {
+				// the span goes on to the edge where filling stops
+				k, w := j+1, winding
+				for ; k < len(active); k++ {
+					w += edges[active[k].e].winding
+					if !rule.filled(w) {
+						break
+					}
+				}
+				if k == len(active) {
+					continue
+				}
+				l, r := &edges[s.e], &edges[active[k].e]
+				dst.quad(vec2{x_at(l, y0), y0}, vec2{x_at(r, y0), y0}, vec2{x_at(l, y1), y1}, vec2{x_at(r, y1), y1})
+			}
+		}
+	}
+}
+
+// area is the signed area of a ring, positive counter-clockwise with y up.
+func area(ring []vec2) float {
+	var a float
+	for i := range ring {
+		p, q := ring[i], ring[(i+1)%len(ring)]
+		a += p[0]*q[1] - q[0]*p[1]
+	}
+	return a / 2
+}
+
+// direction is +1 for a ring going counter-clockwise with y up and -1 for one going clockwise.
+func direction(ring []vec2) int {
+	if area(ring) < 0 {
+		return -1
+	}
+	return 1
+}
+
+// contains reports whether `p` is inside the ring, by counting how often a line from it to the right crosses it.
+func contains(ring []vec2, p vec2) bool {
+	in := false
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < a[0]+(p[1]-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+			in = !in
+		}
+	}
+	return in
+}
+
+func abs(x float) float {
+	return float(math.Abs(float64(x)))
+}
+
+// Join is how a stroke turns a corner.
+type Join uint8
+
+const (
+	// MiterJoin carries the edges on to where they meet, or bevels corners too sharp for the miter limit.
+	MiterJoin Join = iota
+	// RoundJoin rounds the corner off.
+	RoundJoin
+	// BevelJoin cuts the corner off straight.
+	BevelJoin
+)
+
+// Cap is how a stroke ends.
+type Cap uint8
+
+const (
+	// ButtCap ends the stroke square at its end.
+	ButtCap Cap = iota
+	// RoundCap ends it in a half circle.
+	RoundCap
+	// SquareCap ends it square half its width past its end.
+	SquareCap
+)
+
+// StrokeStyle is how a path is outlined.
+type StrokeStyle struct {
+	Width float
+	Join  Join
+	Cap   Cap
+	// MiterLimit is how many times the width a miter may reach out before it's beveled instead. 0 is 4, what SVG
+	// has.
+	MiterLimit float
+}
+
+// Stroke appends the triangles of a line `style.Width` wide along `lines` to `dst`, round joins and caps cut into
+// lines within `tolerance`. The triangles of its segments and joins overlap, so a translucent stroke is darker where
+// they do.
+func Stroke(lines []Polyline, style *StrokeStyle, tolerance float, dst *Triangles) {
+	if style.Width <= 0 {
+		return
+	}
+	hw := style.Width / 2
+	limit := style.MiterLimit
+	if limit <= 0 {
+		limit = 4
+	}
+	// the angle a round join or cap turns by between its points
+	step := float(2 * math.Acos(float64(max(1-max(tolerance, 1e-4)/hw, -1))))
+	step = max(step, math.Pi/64)
+
+	// fan adds the triangles of the part of a circle around `p` from the angle `from` turning `by`
+	fan := func(p vec2, from, by float) {
+		n := max(int(math.Ceil(float64(abs(by)/step))), 1)
+		prev := p.Add(vec2{cos(from), sin(from)}.Mul(hw))
+		for i := 1; i <= n; i++ {
+			a := from + by*float(i)/float(n)
+			next := p.Add(vec2{cos(a), sin(a)}.Mul(hw))
+			dst.triangle(p, prev, next)
+			prev = next
+		}
+	}
+
+	for _, l := range lines {
+		points := l.Points
+		closed := l.Closed && len(points) > 2
+		if len(points) == 1 || (len(points) == 2 && points[0] == points[1]) {
+			// a dot is only seen with caps which reach past it
+			p := points[0]
+			switch style.Cap {
+			case RoundCap:
+				fan(p, 0, 2*math.Pi)
+			case SquareCap:
+				dst.quad(p.Add(vec2{-hw, -hw}), p.Add(vec2{hw, -hw}), p.Add(vec2{-hw, hw}), p.Add(vec2{hw, hw}))
+			}
+			continue
+		}
+
+		n := len(points)
+		segments := n - 1
+		if closed {
+			segments = n
+		}
+		direction := func(i int) vec2 {
+			return points[(i+1)%n].Sub(points[i]).Normalize()
+		}
+		for i := range segments {
+			a, b := points[i], points[(i+1)%n]
+			d := direction(i)
+			normal := vec2{-d[1], d[0]}.Mul(hw)
+			if !closed && style.Cap == SquareCap {
+				if i == 0 {
+					a = a.Sub(d.Mul(hw))
+				}
+				if i == segments-1 {
+					b = b.Add(d.Mul(hw))
+				}
+			}
+			dst.quad(a.Add(normal), a.Sub(normal), b.Add(normal), b.Sub(normal))
+		}
+
+		// the joins, at every point of a closed polyline and every one but the ends of an open one
+		first, last := 1, n-1
+		if closed {
+			first, last = 0, n
+		}
+		for i := first; i < last; i++ {
+			p := points[i]
+			d0, d1 := direction((i+n-1)%n), direction(i)
+			turn := d0[0]*d1[1] - d0[1]*d1[0]
+			if abs(turn) < 1e-6 && d0.Dot(d1) > 0 {
+				continue
+			}
+			n0, n1 := vec2{-d0[1], d0[0]}, vec2{-d1[1], d1[0]}
+			// the corner's outside is on the right of a left turn and the left of a right turn
+			if turn > 0 {
+				n0, n1 = n0.Mul(-1), n1.Mul(-1)
+			}
+			o0, o1 := p.Add(n0.Mul(hw)), p.Add(n1.Mul(hw))
+			switch style.Join {
+			case RoundJoin:
+				from := float(math.Atan2(float64(n0[1]), float64(n0[0])))
+				by := float(math.Atan2(float64(n0[0]*n1[1]-n0[1]*n1[0]), float64(n0.Dot(n1))))
+				fan(p, from, by)
+			case MiterJoin:
+				// the miter is 1/cos of half the angle between the normals as long as the width
+				if c := 1 + n0.Dot(n1); c > 1e-6 && 2/float(math.Sqrt(float64(2*c))) <= limit {
+					m := p.Add(n0.Add(n1).Mul(hw / c))
+					dst.triangle(p, o0, m)
+					dst.triangle(p, m, o1)
+					continue
+				}
+				dst.triangle(p, o0, o1)
+			default:
+				dst.triangle(p, o0, o1)
+			}
+		}
+
+		if !closed && style.Cap == RoundCap {
+			d := direction(0)
+			fan(points[0], float(math.Atan2(float64(d[0]), float64(-d[1]))), math.Pi)
+			d = direction(n - 2)
+			fan(points[n-1], float(math.Atan2(float64(-d[0]), float64(d[1]))), math.Pi)
+		}
+	}
+}
+
+// transformed is `points` through the affine transform `m`.
+func transformed(m *mat3, points []vec2, dst []vec2) []vec2 {
+	dst = slices.Grow(dst, len(points))
+	for _, p := range points {
+		dst = append(dst, vec2{m[0]*p[0] + m[3]*p[1] + m[6], m[1]*p[0] + m[4]*p[1] + m[7]})
+	}
+	return dst
+}
@@ -0,0 +1,436 @@
+package vecscene
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// covered is the area of the triangles, overlaps counted twice.
+func covered(t *Triangles) float {
+	var a float
+	for i := 0; i < len(t.Indices); i += 3 {
+		p, q, r := t.Points[t.Indices[i]], t.Points[t.Indices[i+1]], t.Points[t.Indices[i+2]]
+		a += abs((q[0]-p[0])*(r[1]-p[1])-(q[1]-p[1])*(r[0]-p[0])) / 2
+	}
+	return a
+}
+
+func near(a, b, tolerance float) bool {
+	return abs(a-b) <= tolerance
+}
+
+func TestFlatten(t *testing.T) {
+	var p Path
+	p.Circle(10, 20, 50)
+	p.MoveTo(0, 0)
+	p.LineTo(5, 0)
+	p.QuadTo(10, 0, 10, 5)
+	lines := p.Flatten(0.1, nil)
+	if len(lines) != 2 || !lines[0].Closed || lines[1].Closed {
+		t.Fatalf("%d polylines, closed %v", len(lines), lines)
+	}
+	for _, q := range lines[0].Points {
+		if r := q.Sub(vec2{10, 20}).Len(); !near(r, 50, 0.1) {
+			t.Errorf("a point of the circle is %v from its middle", r)
+		}
+	}
+	// halfway along the lines between them too
+	pts := lines[0].Points
+	for i := range pts {
+		mid := pts[i].Add(pts[(i+1)%len(pts)]).Mul(0.5)
+		if r := mid.Sub(vec2{10, 20}).Len(); !near(r, 50, 0.1) {
+			t.Errorf("the circle is cut into lines straying %v from it", 50-r)
+		}
+	}
+	if end := lines[1].Points[len(lines[1].Points)-1]; end != (vec2{10, 5}) {
+		t.Errorf("the curve ends at %v", end)
+	}
+
+	// finer tolerances cut curves into more lines
+	if coarse, fine := len(p.Flatten(1, nil)[0].Points), len(p.Flatten(0.01, nil)[0].Points); coarse >= fine {
+		t.Errorf("%d points at a tolerance of 1, %d at 0.01", coarse, fine)
+	}
+
+	// an arc of a half turn goes around the circle
+	var arc Path
+	arc.Arc(0, 0, 10, 0, math.Pi)
+	line := arc.Flatten(0.01, nil)[0].Points
+	if first, last := line[0], line[len(line)-1]; first.Sub(vec2{10, 0}).Len() > 1e-4 || last.Sub(vec2{-10, 0}).Len() > 1e-4 {
+		t.Errorf("the arc goes from %v to %v", first, last)
+	}
+	for _, q := range line {
+		if r := q.Len(); !near(r, 10, 0.01) {
+			t.Errorf("a point of the arc is %v from its middle", r)
+		}
+	}
+}
+
+func TestArcTo(t *testing.T) {
+	// of the two circles of radius 10 through 0, 0 and 10, 10, the arcs going the way angles grow, clockwise with y
+	// down, are a quarter of the one around 0, 10 and three quarters of the one around 10, 0
+	for _, tc := range []struct {
+		large, sweep bool
+		center       vec2
+		// lowest is the least y the arc reaches
+		lowest float
+	}{
+		{false, true, vec2{0, 10}, 0},
+		{false, false, vec2{10, 0}, 0},
+		{true, true, vec2{10, 0}, -10},
+		{true, false, vec2{0, 10}, 0},
+	} {
+		var p Path
+		p.MoveTo(0, 0)
+		p.ArcTo(10, 10, 0, tc.large, tc.sweep, 10, 10)
+		line := p.Flatten(0.01, nil)[0].Points
+		if end := line[len(line)-1]; end != (vec2{10, 10}) {
+			t.Errorf("large %v, sweep %v ends at %v", tc.large, tc.sweep, end)
+		}
+		lowest := line[0][1]
+		for _, q := range line {
+			if r := q.Sub(tc.center).Len(); !near(r, 10, 0.01) {
+				t.Fatalf("large %v, sweep %v goes through %v, %v from %v", tc.large, tc.sweep, q, r, tc.center)
+			}
+			lowest = min(lowest, q[1])
+		}
+		if !near(lowest, tc.lowest, 0.01) {
+			t.Errorf("large %v, sweep %v reaches up to %v", tc.large, tc.sweep, lowest)
+		}
+	}
+
+	// a half circle, the only arc between the ends of a diameter, goes up or down by its radius
+	for _, sweep := range []bool{false, true} {
+		var p Path
+		p.MoveTo(0, 0)
+		p.ArcTo(10, 10, 0, false, sweep, 20, 0)
+		var far float
+		for _, q := range p.Flatten(0.01, nil)[0].Points {
+			if r := q.Sub(vec2{10, 0}).Len(); !near(r, 10, 0.01) {
+				t.Fatalf("a point of the half circle is %v from its middle", r)
+			}
+			if abs(q[1]) > abs(far) {
+				far = q[1]
+			}
+		}
+		// sweeping the way angles grow goes through -y from the left, with y down that's clockwise
+		if want := float(10); sweep {
+			if !near(far, -want, 0.01) {
+				t.Errorf("sweeping reaches %v", far)
+			}
+		} else if !near(far, want, 0.01) {
+			t.Errorf("not sweeping reaches %v", far)
+		}
+	}
+
+	// radii too small to reach are scaled up, an ellipse turned a quarter is as tall as it was wide
+	var p Path
+	p.MoveTo(0, 0)
+	p.ArcTo(1, 1, 0, false, true, 20, 0)
+	for _, q := range p.Flatten(0.01, nil)[0].Points {
+		if r := q.Sub(vec2{10, 0}).Len(); !near(r, 10, 0.01) {
+			t.Fatalf("a point of the scaled up arc is %v from its middle", r)
+		}
+	}
+	p.Reset()
+	p.MoveTo(0, -20)
+	p.ArcTo(20, 10, math.Pi/2, false, true, 0, 20)
+	for _, q := range p.Flatten(0.01, nil)[0].Points {
+		if e := q[0]*q[0]/100 + q[1]*q[1]/400; !near(e, 1, 0.01) {
+			t.Fatalf("a point of the turned ellipse %v is off it", q)
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	var p Path
+	p.Rect(0, 0, 10, 10)
+	p.Rect(2, 2, 4, 4)
+	for _, tc := range []struct {
+		rule FillRule
+		// whether the inner square goes the other way round
+		reverse bool
+		want    float
+	}{
+		// a ring inside another going the same way is filled by NonZero and a hole by EvenOdd
+		{NonZero, false, 100},
+		{EvenOdd, false, 84},
+		{NonZero, true, 84},
+		{EvenOdd, true, 84},
+	} {
+		q := p
+		if tc.reverse {
+			q = Path{}
+			q.Rect(0, 0, 10, 10)
+			q.MoveTo(2, 2)
+			q.LineTo(2, 6)
+			q.LineTo(6, 6)
+			q.LineTo(6, 2)
+			q.Close()
+		}
+		var tris Triangles
+		Fill(q.Flatten(0.1, nil), tc.rule, &tris)
+		if a := covered(&tris); !near(a, tc.want, 1e-3) {
+			t.Errorf("rule %d, reversed %v, covers %v, not %v", tc.rule, tc.reverse, a, tc.want)
+		}
+	}
+
+	// an island in a hole is filled again
+	p.Rect(3, 3, 2, 2)
+	var tris Triangles
+	Fill(p.Flatten(0.1, nil), EvenOdd, &tris)
+	if a := covered(&tris); !near(a, 88, 1e-3) {
+		t.Errorf("an island in a hole covers %v with the rest", a)
+	}
+
+	// squares overlapping by a quarter are joined by NonZero, and EvenOdd leaves out where they overlap
+	var overlap Path
+	overlap.Rect(0, 0, 10, 10)
+	overlap.Rect(5, 5, 10, 10)
+	for rule, want := range map[FillRule]float{NonZero: 175, EvenOdd: 150} {
+		var tris Triangles
+		Fill(overlap.Flatten(0.1, nil), rule, &tris)
+		if a := covered(&tris); !near(a, want, 1e-3) {
+			t.Errorf("overlapping squares by rule %d cover %v, not %v", rule, a, want)
+		}
+	}
+
+	// a bowtie winds as much one way as the other, adding up to no area, and is filled all the same
+	var bowtie Path
+	bowtie.MoveTo(0, 0)
+	bowtie.LineTo(10, 10)
+	bowtie.LineTo(10, 0)
+	bowtie.LineTo(0, 10)
+	bowtie.Close()
+	for _, rule := range []FillRule{NonZero, EvenOdd} {
+		var tris Triangles
+		Fill(bowtie.Flatten(0.1, nil), rule, &tris)
+		if a := covered(&tris); !near(a, 50, 1e-3) {
+			t.Errorf("the bowtie by rule %d covers %v, not 50", rule, a)
+		}
+	}
+
+	// a hole sharing a corner with its outline, the same way round and the other, which can't be told nested by the
+	// corner they share
+	for _, tc := range []struct {
+		rule    FillRule
+		reverse bool
+		want    float
+	}{
+		{NonZero, false, 100},
+		{EvenOdd, false, 89.5},
+		{NonZero, true, 89.5},
+		{EvenOdd, true, 89.5},
+	} {
+		var p Path
+		p.Rect(0, 0, 10, 10)
+		p.MoveTo(0, 0)
+		if tc.reverse {
+			p.LineTo(2, 5)
+			p.LineTo(5, 2)
+		} else {
+			p.LineTo(5, 2)
+			p.LineTo(2, 5)
+		}
+		p.Close()
+		var tris Triangles
+		Fill(p.Flatten(0.1, nil), tc.rule, &tris)
+		if a := covered(&tris); !near(a, tc.want, 1e-3) {
+			t.Errorf("a hole in the corner by rule %d, reversed %v, covers %v, not %v", tc.rule, tc.reverse, a, tc.want)
+		}
+	}
+
+	// a hole with a corner on an edge of its outline
+	var touching Path
+	touching.Rect(0, 0, 10, 10)
+	touching.MoveTo(5, 0)
+	touching.LineTo(3, 4)
+	touching.LineTo(7, 4)
+	touching.Close()
+	tris = Triangles{}
+	Fill(touching.Flatten(0.1, nil), EvenOdd, &tris)
+	if a := covered(&tris); !near(a, 92, 1e-3) {
+		t.Errorf("a hole touching an edge covers %v, not 92", a)
+	}
+
+	// rings on a line enclose nothing
+	var line Path
+	line.MoveTo(0, 0)
+	line.LineTo(5, 5)
+	line.LineTo(10, 10)
+	line.LineTo(5, 5)
+	line.Close()
+	tris = Triangles{}
+	Fill(line.Flatten(0.1, nil), NonZero, &tris)
+	if len(tris.Indices) > 0 {
+		t.Errorf("a flat ring has %d triangles", len(tris.Indices)/3)
+	}
+
+	// a star drawn in one stroke winds twice around its middle, filled by NonZero and not by EvenOdd
+	var star Path
+	for i := range 5 {
+		a := float64(i*2) / 5 * 2 * math.Pi
+		x, y := float(math.Cos(a)), float(math.Sin(a))
+		if i == 0 {
+			star.MoveTo(x, y)
+		} else {
+			star.LineTo(x, y)
+		}
+	}
+	star.Close()
+	// the middle is a pentagon whose corners are cos 72° / cos 36° out
+	inner := float(math.Cos(2*math.Pi/5) / math.Cos(math.Pi/5))
+	pentagon := 5 * inner * inner * float(math.Sin(2*math.Pi/5)) / 2
+	var nonzero, evenodd Triangles
+	Fill(star.Flatten(0.1, nil), NonZero, &nonzero)
+	Fill(star.Flatten(0.1, nil), EvenOdd, &evenodd)
+	if a, b := covered(&nonzero), covered(&evenodd); !near(a-b, pentagon, 1e-3) {
+		t.Errorf("the star covers %v by NonZero and %v by EvenOdd, not the %v of its middle apart", a, b, pentagon)
+	}
+}
+
+func TestStroke(t *testing.T) {
+	var p Path
+	p.MoveTo(0, 0)
+	p.LineTo(100, 0)
+	lines := p.Flatten(0.1, nil)
+	for _, tc := range []struct {
+		cap  Cap
+		want float
+	}{
+		{ButtCap, 1000},
+		{SquareCap, 1100},
+		{RoundCap, 1000 + math.Pi*25},
+	} {
+		var tris Triangles
+		Stroke(lines, &StrokeStyle{Width: 10, Cap: tc.cap}, 0.01, &tris)
+		if a := covered(&tris); !near(a, tc.want, 0.5) {
+			t.Errorf("cap %d covers %v, not %v", tc.cap, a, tc.want)
+		}
+	}
+
+	// a right angle gets a square corner mitered and half of one beveled, and the miter limit bevels sharp corners
+	p.LineTo(100, 100)
+	lines = p.Flatten(0.1, nil)
+	for _, tc := range []struct {
+		style StrokeStyle
+		join  float
+	}{
+		{StrokeStyle{Width: 10}, 25},
+		{StrokeStyle{Width: 10, Join: BevelJoin}, 12.5},
+		{StrokeStyle{Width: 10, Join: RoundJoin}, math.Pi * 25 / 4},
+		{StrokeStyle{Width: 10, MiterLimit: 1.2}, 12.5},
+	} {
+		var tris Triangles
+		Stroke(lines, &tc.style, 0.01, &tris)
+		if a := covered(&tris) - 2000; !near(a, tc.join, 0.1) {
+			t.Errorf("%+v joins with %v, not %v", tc.style, a, tc.join)
+		}
+	}
+}
+
+func TestSceneCache(t *testing.T) {
+	s := New()
+	var ring Path
+	ring.Circle(0, 0, 10)
+	var bar Path
+	bar.Rect(-1, -1, 2, 2)
+	dial := NewNode("dial", &ring)
+	dial.Fill = &FillPaint{Color: vec4{1, 1, 1, 1}}
+	dial.Stroke = &StrokePaint{Color: vec4{0, 0, 0, 1}, StrokeStyle: StrokeStyle{Width: 1}}
+	needle := NewNode("needle", &bar)
+	needle.Fill = &FillPaint{Color: vec4{1, 0, 0, 1}}
+	s.Root.Add(dial.Add(needle))
+
+	build := func(m mat3) Stats {
+		s.Build(m)
+		return s.Stats
+	}
+	if st := build(mgl32.Ident3()); st.Tessellated != 2 || st.Cached != 0 || st.Nodes != 3 {
+		t.Errorf("the first build %+v", st)
+	}
+	// turning and moving reuses the triangles
+	needle.Transform = mgl32.HomogRotate2D(1)
+	if st := build(mgl32.Translate2D(100, 50)); st.Tessellated != 0 || st.Cached != 2 {
+		t.Errorf("after a move %+v", st)
+	}
+	// and so does scaling a little, but not four times as large
+	if st := build(mgl32.Scale2D(0.8, 0.8)); st.Tessellated != 0 || st.Cached != 2 {
+		t.Errorf("scaled a little %+v", st)
+	}
+	if st := build(mgl32.Scale2D(4, 4)); st.Tessellated != 2 {
+		t.Errorf("scaled up four times %+v", st)
+	}
+	// changing a path cuts that path again
+	bar.LineTo(3, 3)
+	if st := build(mgl32.Scale2D(4, 4)); st.Tessellated != 1 || st.Cached != 1 {
+		t.Errorf("after a path changed %+v", st)
+	}
+	// and so does changing the stroke
+	dial.Stroke.Width = 2
+	if st := build(mgl32.Scale2D(4, 4)); st.Tessellated != 1 {
+		t.Errorf("after the stroke changed %+v", st)
+	}
+	// but not a color
+	dial.Fill.Color = vec4{0, 0, 1, 1}
+	if st := build(mgl32.Scale2D(4, 4)); st.Tessellated != 0 {
+		t.Errorf("after a color changed %+v", st)
+	}
+
+	// the needle is placed by its transform and the scene's
+	needle.Transform = mgl32.Translate2D(5, 0)
+	batches := s.Build(mgl32.Translate2D(100, 0))
+	var red []vec2
+	for _, v := range batches[0].Vertices {
+		if v.Color == (vec4{1, 0, 0, 1}) {
+			red = append(red, v.Position)
+		}
+	}
+	if len(red) == 0 || red[0][0] < 103.9 || red[0][0] > 106.1 {
+		t.Errorf("the needle is at %v", red)
+	}
+
+	// hidden nodes and their children aren't drawn, faded ones are fainter
+	dial.Opacity = 0.5
+	batches = s.Build(mgl32.Ident3())
+	for _, v := range batches[0].Vertices {
+		if v.Color[3] != 0.5 {
+			t.Fatalf("a vertex of the faded dial is %v", v.Color)
+		}
+	}
+	dial.Hidden = true
+	if batches := s.Build(mgl32.Ident3()); len(batches) != 0 {
+		t.Errorf("%d batches of a hidden dial", len(batches))
+	}
+}
+
+func TestBatches(t *testing.T) {
+	s := New()
+	var blob Path
+	blob.Circle(0, 0, 1000)
+	for range 500 {
+		n := NewNode("", &blob)
+		n.Fill = &FillPaint{Color: vec4{1, 1, 1, 1}}
+		s.Root.Add(n)
+	}
+	batches := s.Build(mgl32.Ident3())
+	if len(batches) < 2 {
+		t.Fatalf("%d batches", len(batches))
+	}
+	triangles := 0
+	for _, b := range batches {
+		if len(b.Vertices) > math.MaxUint16+1 {
+			t.Errorf("a batch of %d vertices", len(b.Vertices))
+		}
+		for _, i := range b.Indices {
+			if int(i) >= len(b.Vertices) {
+				t.Fatalf("index %d of %d vertices", i, len(b.Vertices))
+			}
+		}
+		triangles += len(b.Indices) / 3
+	}
+	if triangles != s.Stats.Triangles {
+		t.Errorf("%d triangles in the batches, %d built", triangles, s.Stats.Triangles)
+	}
+}
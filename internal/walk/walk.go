@@ -0,0 +1,120 @@
+// Package walk is a first person character controller: a capsule that falls, jumps, walks up steps and slides
+// along walls, colliding with the triangles of a World. Distances are in world units and time in seconds.
+//
+// Ground and walls are handled separately. Walkable triangles are only ever stood on: a few vertical probes find the
+// highest one under the capsule and the feet snap onto it, which is also how steps are climbed. Everything steeper
+// is a wall and pushes the capsule out, but only the part of the capsule above the step height, so low obstacles
+// become steps instead of walls.
+package walk
+
+type Controller struct {
+	// Position is the point between the feet.
+	Position vec3
+	Velocity vec3
+	Grounded bool
+
+	Radius float
+	Height float
+	// Eye is the height of the eyes above Position.
+	Eye float
+	// StepHeight is the tallest ledge walked up without jumping.
+	StepHeight float
+	Gravity    float
+	JumpSpeed  float
+	// Speed is the walking speed.
+	Speed float
+}
+
+// New returns a controller of roughly human size standing at `position`.
+func New(position vec3) *Controller {
+	return &Controller{
+		Position:   position,
+		Radius:     0.3,
+		Height:     1.8,
+		Eye:        1.65,
+		StepHeight: 0.4,
+		Gravity:    20,
+		JumpSpeed:  6.5,
+		Speed:      4,
+	}
+}
+
+// EyePosition is where a first person camera goes.
+func (c *Controller) EyePosition() vec3 {
+	return c.Position.Add(vec3{0, c.Eye, 0})
+}
+
+// wall_iterations is how often the capsule is pushed out of walls per update, corners need more than one.
+const wall_iterations = 4
+
+// Update advances the controller by `dt` seconds. `wish` is the direction to walk in, its length is the fraction of
+// Speed and its Y is ignored. `jump` jumps when standing on something.
+func (c *Controller) Update(w *World, wish vec3, jump bool, dt float) {
+	c.Velocity[0] = wish.X() * c.Speed
+	c.Velocity[2] = wish.Z() * c.Speed
+	if c.Grounded && jump {
+		c.Velocity[1] = c.JumpSpeed
+		c.Grounded = false
+	}
+	c.Velocity[1] -= c.Gravity * dt
+
+	// across, pushed out of walls
+	c.Position = c.Position.Add(vec3{c.Velocity.X() * dt, 0, c.Velocity.Z() * dt})
+	for i := 0; i < wall_iterations; i++ {
+		d := w.push(c.wall_segment())
+		if d.Len() == 0 {
+			break
+		}
+		// only across, ceilings are dealt with after moving up
+		d[1] = 0
+		c.Position = c.Position.Add(d)
+	}
+
+	// up and down, onto whatever is below
+	top := c.Position.Y() + c.StepHeight
+	c.Position[1] += c.Velocity.Y() * dt
+	if c.Velocity.Y() > 0 {
+		if d := w.push(c.wall_segment()); d.Y() < 0 {
+			// bumped the head
+			c.Position[1] += d.Y()
+			c.Velocity[1] = 0
+		}
+	}
+	bottom := c.Position.Y()
+	if c.Grounded && c.Velocity.Y() <= 0 {
+		// walking down stairs sticks to them instead of falling off every step
+		bottom -= c.StepHeight
+	}
+
+	ground, ok := c.probe(w, top, bottom)
+	if ok && c.Velocity.Y() <= 0 {
+		c.Position[1] = ground
+		c.Velocity[1] = 0
+		c.Grounded = true
+	} else {
+		c.Grounded = false
+	}
+}
+
+// wall_segment is the part of the capsule's axis walls collide with, its bottom sphere rests on the step height.
+func (c *Controller) wall_segment() (p1, p2 vec3, radius float) {
+	bottom := c.Position.Y() + c.StepHeight + c.Radius
+	top := c.Position.Y() + c.Height - c.Radius
+	return vec3{c.Position.X(), bottom, c.Position.Z()}, vec3{c.Position.X(), max(top, bottom), c.Position.Z()}, c.Radius
+}
+
+// probe returns the highest ground between `top` and `bottom` under the middle and the sides of the capsule.
+func (c *Controller) probe(w *World, top, bottom float) (float, bool) {
+	const side = 0.7
+	offsets := [5][2]float{{0, 0}, {side, 0}, {-side, 0}, {0, side}, {0, -side}}
+
+	best, found := bottom, false
+	for _, o := range offsets {
+		x := c.Position.X() + o[0]*c.Radius
+		z := c.Position.Z() + o[1]*c.Radius
+		if y, ok := w.ground(x, z, top, bottom); ok && y >= best {
+			best, found = y, true
+		}
+	}
+	return best, found
+}
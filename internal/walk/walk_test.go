@@ -0,0 +1,93 @@
+package walk
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+const dt = 1.0 / 60
+
+func box(size, at vec3) *mesh.Mesh {
+	m := mesh.Box(size)
+	m.Transform(mgl32.Translate3D(at.X(), at.Y(), at.Z()))
+	return m
+}
+
+func run(c *Controller, w *World, wish vec3, ticks int) {
+	for i := 0; i < ticks; i++ {
+		c.Update(w, wish, false, dt)
+	}
+}
+
+func TestFallsOntoTheFloor(t *testing.T) {
+	w := NewWorld(mesh.Plane(20))
+	c := New(vec3{0, 3, 0})
+	run(c, w, vec3{}, 120)
+
+	if !c.Grounded || c.Position.Y() != 0 {
+		t.Fatalf("after falling the controller is at %v, grounded %v", c.Position, c.Grounded)
+	}
+}
+
+func TestWallsStop(t *testing.T) {
+	// a 2 unit tall wall with its face at x = 2
+	w := NewWorld(mesh.Plane(20), box(vec3{1, 2, 4}, vec3{2.5, 1, 0}))
+	c := New(vec3{0, 0, 0})
+	run(c, w, vec3{1, 0, 0}, 120)
+
+	if x := c.Position.X(); x > 2-c.Radius+0.01 || x < 2-c.Radius-0.05 {
+		t.Fatalf("the controller walked to x = %v, the wall is at 2", x)
+	}
+	if !c.Grounded {
+		t.Fatal("walking into a wall left the ground")
+	}
+}
+
+func TestClimbsStepsButNotBoxes(t *testing.T) {
+	w := NewWorld(mesh.Plane(20), box(vec3{2, 0.3, 2}, vec3{2, 0.15, 0}))
+	c := New(vec3{0, 0, 0})
+	run(c, w, vec3{1, 0, 0}, 40)
+	if y := c.Position.Y(); y < 0.29 || y > 0.31 || c.Position.X() < 1.2 {
+		t.Fatalf("the controller didn't get onto the step, it's at %v", c.Position)
+	}
+
+	w = NewWorld(mesh.Plane(20), box(vec3{2, 1, 2}, vec3{2, 0.5, 0}))
+	c = New(vec3{0, 0, 0})
+	run(c, w, vec3{1, 0, 0}, 40)
+	if c.Position.Y() != 0 || c.Position.X() > 1 {
+		t.Fatalf("the controller got onto a box taller than a step, it's at %v", c.Position)
+	}
+}
+
+func TestJumpsAndLands(t *testing.T) {
+	w := NewWorld(mesh.Plane(20))
+	c := New(vec3{0, 0, 0})
+	run(c, w, vec3{}, 1)
+
+	c.Update(w, vec3{}, true, dt)
+	peak := float(0)
+	for i := 0; i < 120; i++ {
+		c.Update(w, vec3{}, false, dt)
+		peak = max(peak, c.Position.Y())
+	}
+	if peak < 0.8 || !c.Grounded || c.Position.Y() != 0 {
+		t.Fatalf("jumped %v high and ended at %v, grounded %v", peak, c.Position, c.Grounded)
+	}
+}
+
+func TestCeilingsStopJumps(t *testing.T) {
+	// a slab 2.2 above the floor, its underside is a ceiling
+	w := NewWorld(mesh.Plane(20), box(vec3{4, 0.2, 4}, vec3{0, 2.3, 0}))
+	c := New(vec3{0, 0, 0})
+	run(c, w, vec3{}, 1)
+	c.Update(w, vec3{}, true, dt)
+	for i := 0; i < 120; i++ {
+		c.Update(w, vec3{}, false, dt)
+		if top := c.Position.Y() + c.Height; top > 2.2+0.01 {
+			t.Fatalf("the head went through the ceiling to %v", top)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package walk
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/thedaneeffect/ebiten-kage-playground/internal/mesh"
+)
+
+type (
+	float = float32
+	vec3  = mgl32.Vec3
+)
+
+// walkable is the smallest Y of a normal that can be stood on, about 45 degrees of slope. Steeper triangles are
+// walls.
+const walkable = 0.7
+
+type triangle struct {
+	a, b, c vec3
+	normal  vec3
+	lo, hi  vec3
+}
+
+// World is the static geometry controllers collide with.
+type World struct {
+	triangles []triangle
+}
+
+// NewWorld collects the triangles of `meshes`, as they are now. Meshes moved later have to be added again.
+func NewWorld(meshes ...*mesh.Mesh) *World {
+	w := &World{}
+	for _, m := range meshes {
+		w.Add(m)
+	}
+	return w
+}
+
+// Add adds the triangles of `m`, they're expected to wind counter-clockwise seen from outside.
+func (w *World) Add(m *mesh.Mesh) {
+	for _, t := range m.Triangles {
+		a, b, c := m.Points[t.P1], m.Points[t.P2], m.Points[t.P3]
+		n := b.Sub(a).Cross(c.Sub(a))
+		if n.Len() == 0 {
+			continue
+		}
+		tri := triangle{a: a, b: b, c: c, normal: n.Normalize(), lo: a, hi: a}
+		for _, p := range [2]vec3{b, c} {
+			for i := range p {
+				tri.lo[i] = min(tri.lo[i], p[i])
+				tri.hi[i] = max(tri.hi[i], p[i])
+			}
+		}
+		w.triangles = append(w.triangles, tri)
+	}
+}
+
+// ground returns the height of the highest walkable surface below `top` and above `bottom` at (x, z).
+func (w *World) ground(x, z, top, bottom float) (float, bool) {
+	best, found := bottom, false
+	for i := range w.triangles {
+		t := &w.triangles[i]
+		if t.normal.Y() < walkable || x < t.lo.X() || x > t.hi.X() || z < t.lo.Z() || z > t.hi.Z() || t.lo.Y() > top || t.hi.Y() < bottom {
+			continue
+		}
+		y, ok := t.height_at(x, z)
+		if ok && y <= top && y >= best {
+			best, found = y, true
+		}
+	}
+	return best, found
+}
+
+// height_at intersects a vertical line at (x, z) with the triangle.
+func (t *triangle) height_at(x, z float) (float, bool) {
+	// barycentric coordinates in the XZ plane
+	v0x, v0z := t.b.X()-t.a.X(), t.b.Z()-t.a.Z()
+	v1x, v1z := t.c.X()-t.a.X(), t.c.Z()-t.a.Z()
+	v2x, v2z := x-t.a.X(), z-t.a.Z()
+	det := v0x*v1z - v1x*v0z
+	if det == 0 {
+		return 0, false
+	}
+	u := (v2x*v1z - v1x*v2z) / det
+	v := (v0x*v2z - v2x*v0z) / det
+	if u < 0 || v < 0 || u+v > 1 {
+		return 0, false
+	}
+	return t.a.Y() + u*(t.b.Y()-t.a.Y()) + v*(t.c.Y()-t.a.Y()), true
+}
+
+// push returns how far a capsule around the segment from `p1` up to `p2` with `radius` has to move to get out of
+// the walls it overlaps. Walkable triangles are left to ground.
+func (w *World) push(p1, p2 vec3, radius float) vec3 {
+	var total vec3
+	lo := vec3{min(p1.X(), p2.X()) - radius, min(p1.Y(), p2.Y()) - radius, min(p1.Z(), p2.Z()) - radius}
+	hi := vec3{max(p1.X(), p2.X()) + radius, max(p1.Y(), p2.Y()) + radius, max(p1.Z(), p2.Z()) + radius}
+	for i := range w.triangles {
+		t := &w.triangles[i]
+		if t.normal.Y() >= walkable || !overlaps(lo, hi, t.lo, t.hi) {
+			continue
+		}
+		offset := total
+		if d := t.capsule_push(p1.Add(offset), p2.Add(offset), radius); d.Len() > 0 {
+			total = total.Add(d)
+		}
+	}
+	return total
+}
+
+func overlaps(lo1, hi1, lo2, hi2 vec3) bool {
+	return lo1.X() <= hi2.X() && hi1.X() >= lo2.X() &&
+		lo1.Y() <= hi2.Y() && hi1.Y() >= lo2.Y() &&
+		lo1.Z() <= hi2.Z() && hi1.Z() >= lo2.Z()
+}
+
+// capsule_push finds the point of the segment nearest to the triangle and pushes the sphere around it out of
+// the triangle, only from its front so a capsule can't be pushed through a wall it's already halfway into.
+func (t *triangle) capsule_push(p1, p2 vec3, radius float) vec3 {
+	axis := p2.Sub(p1)
+	// where the segment's line crosses the triangle's plane, or any point of it when they're parallel
+	reference := t.a
+	if d := t.normal.Dot(axis); d != 0 {
+		s := t.normal.Dot(t.a.Sub(p1)) / d
+		reference = t.closest(p1.Add(axis.Mul(s)))
+	}
+	center := closest_on_segment(p1, p2, reference)
+
+	q := t.closest(center)
+	away := center.Sub(q)
+	dist := away.Len()
+	if dist >= radius || t.normal.Dot(center.Sub(t.a)) < 0 {
+		return vec3{}
+	}
+	if dist == 0 {
+		return t.normal.Mul(radius)
+	}
+	return away.Mul((radius - dist) / dist)
+}
+
+func closest_on_segment(a, b, p vec3) vec3 {
+	ab := b.Sub(a)
+	l := ab.Dot(ab)
+	if l == 0 {
+		return a
+	}
+	s := mgl32.Clamp(p.Sub(a).Dot(ab)/l, 0, 1)
+	return a.Add(ab.Mul(s))
+}
+
+// closest returns the point of the triangle nearest to `p`, from Ericson's Real-Time Collision Detection.
+func (t *triangle) closest(p vec3) vec3 {
+	a, b, c := t.a, t.b, t.c
+	ab, ac, ap := b.Sub(a), c.Sub(a), p.Sub(a)
+	d1, d2 := ab.Dot(ap), ac.Dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return a
+	}
+	bp := p.Sub(b)
+	d3, d4 := ab.Dot(bp), ac.Dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return b
+	}
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return a.Add(ab.Mul(d1 / (d1 - d3)))
+	}
+	cp := p.Sub(c)
+	d5, d6 := ab.Dot(cp), ac.Dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return c
+	}
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return a.Add(ac.Mul(d2 / (d2 - d6)))
+	}
+	va := d3*d6 - d5*d4
+	if va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		return b.Add(c.Sub(b).Mul((d4 - d3) / ((d4 - d3) + (d5 - d6))))
+	}
+	denom := 1 / (va + vb + vc)
+	v, w := vb*denom, vc*denom
+	return a.Add(ab.Mul(v)).Add(ac.Mul(w))
+}
@@ -0,0 +1,76 @@
+package watch
+
+import (
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tree watches every file under a directory whose name ends in one of Exts, like the Go sources of a module.
+// Hidden directories, like .git, are skipped.
+type Tree struct {
+	Root     string
+	Exts     []string
+	Interval time.Duration
+
+	checked time.Time
+	sum     uint64
+}
+
+// NewTree returns a watch on the files under `root` ending in `exts`, which considers them as they are now
+// unchanged.
+func NewTree(root string, exts ...string) *Tree {
+	t := &Tree{Root: root, Exts: exts, Interval: DefaultInterval}
+	t.sum = t.walk()
+	t.checked = time.Now()
+	return t
+}
+
+// walk sums up the names, modification times and sizes of the files, any of them changing changes the sum.
+func (t *Tree) walk() uint64 {
+	h := fnv.New64a()
+	filepath.WalkDir(t.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != t.Root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !slices.ContainsFunc(t.Exts, func(ext string) bool { return strings.HasSuffix(path, ext) }) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		h.Write([]byte(path))
+		h.Write([]byte(strconv.FormatInt(info.ModTime().UnixNano(), 10)))
+		h.Write([]byte(strconv.FormatInt(info.Size(), 10)))
+		return nil
+	})
+	return h.Sum64()
+}
+
+// Changed reports whether a file was modified, created or removed since the last time it reported a change. The
+// disk is only walked once per Interval.
+func (t *Tree) Changed() bool {
+	now := time.Now()
+	if now.Sub(t.checked) < t.Interval {
+		return false
+	}
+	t.checked = now
+
+	sum := t.walk()
+	if sum == t.sum {
+		return false
+	}
+	t.sum = sum
+	return true
+}
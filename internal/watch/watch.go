@@ -0,0 +1,62 @@
+// Package watch notices when files change on disk so assets can be reloaded while a demo runs. It polls instead of
+// using OS notifications, which keeps it dependency free and works the same everywhere; a handful of files checked
+// a few times a second costs nothing.
+package watch
+
+import (
+	"os"
+	"time"
+)
+
+// DefaultInterval is how often a File checks the disk.
+const DefaultInterval = 250 * time.Millisecond
+
+// File watches a single file. Editors often replace files instead of writing them in place, so it compares the
+// modification time and size rather than holding the file open.
+type File struct {
+	Path     string
+	Interval time.Duration
+
+	checked time.Time
+	mod     time.Time
+	size    int64
+	exists  bool
+}
+
+// New returns a watch on `path` which considers the file as it is now unchanged.
+func New(path string) *File {
+	f := &File{Path: path, Interval: DefaultInterval}
+	f.mod, f.size, f.exists = stat(path)
+	f.checked = time.Now()
+	return f
+}
+
+func stat(path string) (mod time.Time, size int64, exists bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return info.ModTime(), info.Size(), true
+}
+
+// Changed reports whether the file was modified, created or removed since the last time it reported a change. It's
+// cheap to call every tick, the disk is only checked once per Interval.
+func (f *File) Changed() bool {
+	now := time.Now()
+	if now.Sub(f.checked) < f.Interval {
+		return false
+	}
+	f.checked = now
+
+	mod, size, exists := stat(f.Path)
+	if mod.Equal(f.mod) && size == f.size && exists == f.exists {
+		return false
+	}
+	f.mod, f.size, f.exists = mod, size, exists
+	return true
+}
+
+// Exists reports whether the file existed when it was last checked.
+func (f *File) Exists() bool {
+	return f.exists
+}
@@ -0,0 +1,108 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lut.png")
+	f := New(path)
+	f.Interval = 0
+
+	if f.Changed() || f.Exists() {
+		t.Fatal("a missing file changed")
+	}
+
+	if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Changed() || !f.Exists() {
+		t.Fatal("creating the file wasn't noticed")
+	}
+	if f.Changed() {
+		t.Fatal("a change was reported twice")
+	}
+
+	// same size, so only the modification time tells
+	if err := os.WriteFile(path, []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Changed() {
+		t.Fatal("modifying the file wasn't noticed")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Changed() || f.Exists() {
+		t.Fatal("removing the file wasn't noticed")
+	}
+}
+
+func TestInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lut.png")
+	f := New(path)
+	f.Interval = time.Hour
+
+	if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if f.Changed() {
+		t.Fatal("the disk was checked before the interval passed")
+	}
+}
+
+func TestTree(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"cmd", ".git"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	main := filepath.Join(root, "cmd", "main.go")
+	if err := os.WriteFile(main, []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewTree(root, ".go")
+	tree.Interval = 0
+
+	if tree.Changed() {
+		t.Fatal("nothing was touched but the tree changed")
+	}
+
+	// files of other kinds, and anything in hidden directories, don't count
+	if err := os.WriteFile(filepath.Join(root, "cmd", "notes.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "hook.go"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Changed() {
+		t.Fatal("a file which isn't watched changed the tree")
+	}
+
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(main, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !tree.Changed() {
+		t.Fatal("modifying a source wasn't noticed")
+	}
+	if tree.Changed() {
+		t.Fatal("a change was reported twice")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "cmd", "more.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !tree.Changed() {
+		t.Fatal("a new source wasn't noticed")
+	}
+}